@@ -4,10 +4,18 @@ import (
 	"context"
 	"crypto/tls"
 	"idm/docs"
+	"idm/inner/accessrequest"
+	"idm/inner/audit"
+	"idm/inner/authz"
+	"idm/inner/certification"
 	"idm/inner/common"
 	"idm/inner/database"
+	"idm/inner/department"
 	"idm/inner/employee"
 	"idm/inner/info"
+	"idm/inner/jobs"
+	"idm/inner/permission"
+	"idm/inner/position"
 	"idm/inner/role"
 	"idm/inner/validator"
 	"idm/inner/web"
@@ -68,7 +76,19 @@ func main() {
 		logger.Fatal("failed to connect to database: %v", zap.Error(err))
 	}
 
-	server := build(db, cfg, logger)
+	server, scheduler, jobPool, expirer, closer := build(db, cfg, logger)
+
+	// запуск планировщика отложенных изменений сотрудников
+	scheduler.Start()
+
+	// запуск обработчиков фоновых заданий
+	jobPool.Start()
+
+	// запуск истечения просроченных запросов доступа
+	expirer.Start()
+
+	// запуск завершения кампаний пересмотра доступа по истечении срока
+	closer.Start()
 
 	// канал для получения системных сигналов
 	quit := make(chan os.Signal, 1)
@@ -101,11 +121,19 @@ func main() {
 	logger.Info("Shutting down server...")
 
 	// выполняем graceful shutdown
-	gracefulShutdown(server, db, logger)
+	gracefulShutdown(server, scheduler, jobPool, expirer, closer, db, logger)
 }
 
 // gracefulShutdown выполняет корректное завершение работы сервера
-func gracefulShutdown(server *web.Server, db *sqlx.DB, logger *common.Logger) {
+func gracefulShutdown(
+	server *web.Server,
+	scheduler *employee.Scheduler,
+	jobPool *jobs.Pool,
+	expirer *accessrequest.Expirer,
+	closer *certification.Closer,
+	db *sqlx.DB,
+	logger *common.Logger,
+) {
 	// контекст с таймаутом для shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -131,6 +159,18 @@ func gracefulShutdown(server *web.Server, db *sqlx.DB, logger *common.Logger) {
 		} else {
 			logger.Info("Server shutdown completed successfully")
 		}
+
+		// останавливаем планировщик, истечение запросов доступа и завершение кампаний пересмотра
+		// до закрытия соединения с базой данных
+		scheduler.Stop(ctx)
+		expirer.Stop(ctx)
+		closer.Stop(ctx)
+
+		// дожидаемся выполняемых фоновых заданий; не успевшие завершиться возвращаются в очередь,
+		// поэтому ожидание короче общего таймаута: нужно время прервать их до закрытия базы данных
+		jobCtx, jobCancel := context.WithTimeout(ctx, jobDrainTimeout)
+		defer jobCancel()
+		jobPool.Stop(jobCtx)
 	}()
 
 	// ожидается завершение shutdown или таймаута
@@ -142,8 +182,31 @@ func gracefulShutdown(server *web.Server, db *sqlx.DB, logger *common.Logger) {
 	}
 }
 
-// buil функция, конструирующая наш веб-сервер
-func build(database *sqlx.DB, cfg common.Config, logger *common.Logger) *web.Server {
+// период проверки наступивших отложенных изменений сотрудников
+const scheduledChangesInterval = time.Minute
+
+// число обработчиков фоновых заданий и период опроса очереди заданий
+const (
+	jobWorkers      = 2
+	jobPollInterval = 5 * time.Second
+)
+
+// сколько при остановке ждать завершения выполняемых фоновых заданий
+const jobDrainTimeout = 20 * time.Second
+
+// период проверки запросов доступа, не получивших решения в срок
+const accessRequestExpiryInterval = time.Hour
+
+// период проверки кампаний пересмотра доступа, срок которых истёк
+const certificationCloseInterval = time.Hour
+
+// buil функция, конструирующая наш веб-сервер, планировщик отложенных изменений, пул фоновых заданий
+// истечение запросов доступа и завершение кампаний пересмотра доступа
+func build(
+	database *sqlx.DB,
+	cfg common.Config,
+	logger *common.Logger,
+) (*web.Server, *employee.Scheduler, *jobs.Pool, *accessrequest.Expirer, *certification.Closer) {
 	// создаём веб-сервер
 	var server = web.NewServer(logger)
 
@@ -154,10 +217,32 @@ func build(database *sqlx.DB, cfg common.Config, logger *common.Logger) *web.Ser
 	server.App.Use(requestid.New())
 	server.App.Use(recover.New())
 	server.GroupApi.Use(web.AuthMiddleware(logger))
+	server.GroupApi.Use(web.ActorMiddleware())
+
+	// курсоры, подписанные общим ключом, остаются действительными после перезапуска
+	if cfg.CursorSecret != "" {
+		server.Cursors = common.NewCursorCodec(cfg.CursorSecret)
+	} else {
+		logger.Warn("CURSOR_SECRET is not set, pagination cursors will be invalidated on restart")
+	}
 
 	// создаём валидатор
 	var vld = validator.New()
 
+	// -------------------------
+	// Модуль audit
+	// -------------------------
+
+	// создаём репозиторий журнала аудита
+	var auditRepo = audit.NewAuditRepository(database)
+
+	// создаём сервис журнала; через него сервисы сотрудников и ролей записывают изменения
+	var auditService = audit.NewService(auditRepo, vld, logger)
+
+	// создаём контроллер журнала
+	var auditController = audit.NewController(server, auditService, logger)
+	auditController.RegisterRoutes()
+
 	// -------------------------
 	// Модуль role
 	// -------------------------
@@ -166,12 +251,66 @@ func build(database *sqlx.DB, cfg common.Config, logger *common.Logger) *web.Ser
 	var roleRepo = role.NewRoleRepository(database)
 
 	// Создаём сервис для ролей
-	var roleService = role.NewService(roleRepo, vld, logger)
+	var roleService = role.NewService(roleRepo, auditService, vld, logger)
 
 	// Создаём контроллер для ролей
 	var roleController = role.NewController(server, roleService, logger)
 	roleController.RegisterRoutes()
 
+	// -------------------------
+	// Модуль permission
+	// -------------------------
+
+	// создаём репозиторий разрешений
+	var permissionRepo = permission.NewPermissionRepository(database)
+
+	// создаём сервис разрешений; эффективные роли сотрудника он получает от сервиса ролей
+	var permissionService = permission.NewService(permissionRepo, roleService, vld, logger)
+
+	// создаём контроллер разрешений
+	var permissionController = permission.NewController(server, permissionService, logger)
+	permissionController.RegisterRoutes()
+
+	// -------------------------
+	// Модуль authz
+	// -------------------------
+
+	// создаём сервис проверки доступа; роли и разрешения вычисляются теми же сервисами,
+	// а решение принимается теми же функциями, что и в middleware web.RequireAnyRole
+	var authzService = authz.NewService(roleService, permissionService, vld, logger)
+
+	// создаём контроллер проверки доступа
+	var authzController = authz.NewController(server, authzService, logger)
+	authzController.RegisterRoutes()
+
+	// -------------------------
+	// Модуль department
+	// -------------------------
+
+	// создаём репозиторий отделов
+	var departmentRepo = department.NewDepartmentRepository(database)
+
+	// создаём сервис отделов
+	var departmentService = department.NewService(departmentRepo, auditService, vld, logger)
+
+	// создаём контроллер отделов
+	var departmentController = department.NewController(server, departmentService, logger)
+	departmentController.RegisterRoutes()
+
+	// -------------------------
+	// Модуль position
+	// -------------------------
+
+	// создаём репозиторий должностей
+	var positionRepo = position.NewPositionRepository(database)
+
+	// создаём сервис должностей
+	var positionService = position.NewService(positionRepo, auditService, vld, logger)
+
+	// создаём контроллер должностей
+	var positionController = position.NewController(server, positionService, logger)
+	positionController.RegisterRoutes()
+
 	// -------------------------
 	// Модуль employee
 	// -------------------------
@@ -180,12 +319,67 @@ func build(database *sqlx.DB, cfg common.Config, logger *common.Logger) *web.Ser
 	var employeeRepo = employee.NewEmployeeRepository(database)
 
 	// создаём сервис для сотрудников
-	var employeeService = employee.NewService(employeeRepo, vld, logger)
+	var employeeService = employee.NewService(employeeRepo, auditService, roleService, vld, logger)
 
 	// создаём контроллер для сотрудников
 	var employeeController = employee.NewController(server, employeeService, logger)
 	employeeController.RegisterRoutes()
 
+	// создаём планировщик, применяющий наступившие отложенные изменения; запускается в main
+	var scheduler = employee.NewScheduler(employeeService, scheduledChangesInterval, logger)
+
+	// -------------------------
+	// Модуль jobs
+	// -------------------------
+
+	// создаём репозиторий фоновых заданий
+	var jobRepo = jobs.NewJobRepository(database)
+
+	// создаём пул обработчиков заданий; запускается в main
+	var jobPool = jobs.NewPool(jobRepo, jobWorkers, jobPollInterval, logger)
+	jobs.RegisterHandlers(jobPool, employeeService, roleService)
+
+	// создаём сервис для заданий
+	var jobService = jobs.NewService(jobRepo, jobPool, vld, logger)
+
+	// создаём контроллер для заданий
+	var jobController = jobs.NewController(server, jobService, logger)
+	jobController.RegisterRoutes()
+
+	// -------------------------
+	// Модуль accessrequest
+	// -------------------------
+
+	// создаём репозиторий запросов доступа
+	var accessRequestRepo = accessrequest.NewAccessRequestRepository(database)
+
+	// создаём сервис для запросов доступа; роль по одобренному запросу назначает сервис сотрудников
+	var accessRequestService = accessrequest.NewService(accessRequestRepo, employeeService, vld, logger)
+
+	// создаём контроллер для запросов доступа
+	var accessRequestController = accessrequest.NewController(server, accessRequestService, logger)
+	accessRequestController.RegisterRoutes()
+
+	// создаём истечение просроченных запросов; запускается в main
+	var expirer = accessrequest.NewExpirer(accessRequestService, accessRequestExpiryInterval, logger)
+
+	// -------------------------
+	// Модуль certification
+	// -------------------------
+
+	// создаём репозиторий кампаний пересмотра доступа
+	var certificationRepo = certification.NewCertificationRepository(database)
+
+	// создаём сервис для кампаний пересмотра; отозванные роли снимает сервис сотрудников
+	var certificationService = certification.NewService(certificationRepo, employeeService, vld, logger)
+
+	// создаём контроллер для кампаний пересмотра
+	var certificationController = certification.NewController(server, certificationService, logger)
+	certificationController.RegisterRoutes()
+
+	// создаём завершение кампаний по истечении срока; запускается в main
+	var closer = certification.NewCloser(certificationService, certificationCloseInterval, logger)
+
 	// -------------------------
 	// Модуль info
 	// -------------------------
@@ -194,5 +388,5 @@ func build(database *sqlx.DB, cfg common.Config, logger *common.Logger) *web.Ser
 	var infoController = info.NewController(server, cfg, database, logger)
 	infoController.RegisterRoutes()
 
-	return server
+	return server, scheduler, jobPool, expirer, closer
 }
@@ -5,6 +5,7 @@ go 1.24.3
 require (
 	github.com/go-playground/validator/v10 v10.26.0
 	github.com/gofiber/contrib/fiberzap/v2 v2.1.6
+	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/icrowley/fake v0.0.0-20240710202011-f797eb4a99c0
 	github.com/swaggo/swag v1.16.4
 	go.uber.org/zap v1.27.0
@@ -18,7 +19,6 @@ require (
 	github.com/go-openapi/jsonreference v0.21.0 // indirect
 	github.com/go-openapi/spec v0.21.0 // indirect
 	github.com/go-openapi/swag v0.23.1 // indirect
-	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
@@ -0,0 +1,474 @@
+package accessrequest
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// допустимые значения фильтра status
+var statuses = []string{StatusPending, StatusApproved, StatusRejected, StatusCancelled, StatusExpired}
+
+type Controller struct {
+	server               *web.Server
+	accessRequestService Svc
+	logger               *common.Logger
+}
+
+// интерфейс сервиса accessrequest.Service
+type Svc interface {
+	Submit(ctx context.Context, request CreateRequest, admin bool) (Response, error)
+	FindById(ctx context.Context, id int64, admin bool) (Response, error)
+	FindMine(ctx context.Context, status string) ([]Response, error)
+	FindAll(ctx context.Context, status string) ([]Response, error)
+	FindPendingApprovals(ctx context.Context, admin bool) ([]Response, error)
+	Approve(ctx context.Context, request DecisionRequest, admin bool) (Response, error)
+	Reject(ctx context.Context, request CommentRequest, admin bool) (Response, error)
+	Comment(ctx context.Context, request CommentRequest, admin bool) (EventResponse, error)
+	Cancel(ctx context.Context, id int64, admin bool) (Response, error)
+	FindPolicies(ctx context.Context) ([]PolicyResponse, error)
+	SetPolicy(ctx context.Context, request PolicyRequest) (PolicyResponse, error)
+	DeletePolicy(ctx context.Context, roleId int64) error
+}
+
+func NewController(server *web.Server, accessRequestService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:               server,
+		accessRequestService: accessRequestService,
+		logger:               logger,
+	}
+}
+
+// функция для регистрации маршрутов
+func (c *Controller) RegisterRoutes() {
+	c.logger.Info("Registering access request routes")
+	// полный маршрут получится "/api/v1/access-requests"
+	// статические маршруты регистрируются раньше "/access-requests/:id", иначе он их перехватит
+	c.server.GroupApiV1User.Post("/access-requests", c.SubmitAccessRequest)
+	c.server.GroupApiV1User.Get("/access-requests", c.FindMyAccessRequests)
+	c.server.GroupApiV1User.Get("/access-requests/approvals", c.FindPendingApprovals)
+	c.server.GroupApiV1User.Get("/access-requests/:id", c.FindAccessRequestById)
+	c.server.GroupApiV1User.Post("/access-requests/:id/approve", c.ApproveAccessRequest)
+	c.server.GroupApiV1User.Post("/access-requests/:id/reject", c.RejectAccessRequest)
+	c.server.GroupApiV1User.Post("/access-requests/:id/comments", c.CommentAccessRequest)
+	c.server.GroupApiV1User.Post("/access-requests/:id/cancel", c.CancelAccessRequest)
+	// все запросы и настройки согласования доступны только администраторам: "/api/v1/admin/access-requests/..."
+	c.server.GroupApiV1Admin.Get("/access-requests", c.FindAllAccessRequests)
+	c.server.GroupApiV1Admin.Get("/access-requests/policies", c.FindPolicies)
+	c.server.GroupApiV1Admin.Put("/access-requests/policies/:roleId", c.SetPolicy)
+	c.server.GroupApiV1Admin.Delete("/access-requests/policies/:roleId", c.DeletePolicy)
+	c.logger.Info("Access request routes registered successfully")
+}
+
+// SubmitAccessRequest подаёт запрос роли
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Submit access request
+//	@Description	Request a role for yourself or, as a manager, for a direct report. The user is matched to an employee by the email of the token. The request passes the approval steps of the role (manager, admin by default); the role is assigned after the last step
+//	@Tags			access-requests
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		AccessRequestCreateRequest	true	"Role and justification"
+//	@Success		200		{object}	AccessRequestResponse		"Submitted request"
+//	@Failure		400		{object}	common.Response[any]		"Invalid request"
+//	@Failure		403		{object}	common.Response[any]		"Employee is not the user or their direct report"
+//	@Failure		409		{object}	common.Response[any]		"Role is already assigned or requested"
+//	@Failure		500		{object}	common.Response[any]		"Error when submitting the request"
+//	@Router			/access-requests [post]
+func (c *Controller) SubmitAccessRequest(ctx *fiber.Ctx) error {
+	c.logger.Info("Received submit access request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	response, err := c.accessRequestService.Submit(ctx.UserContext(), request, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindMyAccessRequests получает запросы пользователя
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List own access requests
+//	@Description	Requests submitted by the user and requests submitted for the user's employee, newest first
+//	@Tags			access-requests
+//	@Produce		json
+//	@Param			status	query		string					false	"Request status"	Enums(pending, approved, rejected, cancelled, expired)
+//	@Success		200		{array}		AccessRequestResponse	"Requests"
+//	@Failure		400		{object}	common.Response[any]	"Invalid status"
+//	@Failure		500		{object}	common.Response[any]	"Error when getting requests"
+//	@Router			/access-requests [get]
+func (c *Controller) FindMyAccessRequests(ctx *fiber.Ctx) error {
+	status := ctx.Query("status")
+	if status != "" && !slices.Contains(statuses, status) {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid status parameter")
+	}
+
+	response, err := c.accessRequestService.FindMine(ctx.UserContext(), status)
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindAllAccessRequests получает все запросы
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List all access requests
+//	@Description	All access requests, newest first
+//	@Tags			access-requests
+//	@Produce		json
+//	@Param			status	query		string					false	"Request status"	Enums(pending, approved, rejected, cancelled, expired)
+//	@Success		200		{array}		AccessRequestResponse	"Requests"
+//	@Failure		400		{object}	common.Response[any]	"Invalid status"
+//	@Failure		500		{object}	common.Response[any]	"Error when getting requests"
+//	@Router			/admin/access-requests [get]
+func (c *Controller) FindAllAccessRequests(ctx *fiber.Ctx) error {
+	status := ctx.Query("status")
+	if status != "" && !slices.Contains(statuses, status) {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid status parameter")
+	}
+
+	response, err := c.accessRequestService.FindAll(ctx.UserContext(), status)
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindPendingApprovals получает запросы, ожидающие решения пользователя
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List pending approvals
+//	@Description	Pending requests whose current step the user decides: the manager step of direct reports, the role_owner step of owned roles, and for administrators the admin step, the manager step of employees without a manager and the role_owner step of roles without an owner
+//	@Tags			access-requests
+//	@Produce		json
+//	@Success		200	{array}		AccessRequestResponse	"Requests awaiting decision"
+//	@Failure		500	{object}	common.Response[any]	"Error when getting requests"
+//	@Router			/access-requests/approvals [get]
+func (c *Controller) FindPendingApprovals(ctx *fiber.Ctx) error {
+	response, err := c.accessRequestService.FindPendingApprovals(ctx.UserContext(), web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindAccessRequestById получает запрос с историей
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get access request
+//	@Description	Request with its history of decisions and comments. Available to the requester, the employee, the employee's manager, the role owner and administrators
+//	@Tags			access-requests
+//	@Produce		json
+//	@Param			id	path		int						true	"Access request ID"
+//	@Success		200	{object}	AccessRequestResponse	"Request"
+//	@Failure		400	{object}	common.Response[any]	"Invalid access request ID"
+//	@Failure		404	{object}	common.Response[any]	"Access request not found"
+//	@Failure		500	{object}	common.Response[any]	"Error when getting the request"
+//	@Router			/access-requests/{id} [get]
+func (c *Controller) FindAccessRequestById(ctx *fiber.Ctx) error {
+	id, err := c.parseAccessRequestId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid access request ID format")
+	}
+
+	response, err := c.accessRequestService.FindById(ctx.UserContext(), id, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// ApproveAccessRequest одобряет текущий шаг запроса
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Approve access request step
+//	@Description	Approve the current step. After the last step the role is assigned to the employee. The requester and the employee cannot approve
+//	@Tags			access-requests
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"Access request ID"
+//	@Param			request	body		AccessRequestDecisionRequest	false	"Comment"
+//	@Success		200		{object}	AccessRequestResponse			"Request after the decision"
+//	@Failure		400		{object}	common.Response[any]			"Invalid request"
+//	@Failure		403		{object}	common.Response[any]			"User cannot decide the current step"
+//	@Failure		404		{object}	common.Response[any]			"Access request not found"
+//	@Failure		409		{object}	common.Response[any]			"Request is not pending or the role cannot be assigned"
+//	@Failure		500		{object}	common.Response[any]			"Error when approving the request"
+//	@Router			/access-requests/{id}/approve [post]
+func (c *Controller) ApproveAccessRequest(ctx *fiber.Ctx) error {
+	c.logger.Info("Received approve access request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseAccessRequestId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid access request ID format")
+	}
+	// комментарий к одобрению необязателен, тело может отсутствовать
+	var request DecisionRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	request.Id = id
+
+	response, err := c.accessRequestService.Approve(ctx.UserContext(), request, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// RejectAccessRequest отклоняет запрос
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Reject access request
+//	@Description	Reject the request at the current step. The comment with the reason is required
+//	@Tags			access-requests
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Access request ID"
+//	@Param			request	body		AccessRequestCommentRequest	true	"Reason"
+//	@Success		200		{object}	AccessRequestResponse		"Rejected request"
+//	@Failure		400		{object}	common.Response[any]		"Invalid request"
+//	@Failure		403		{object}	common.Response[any]		"User cannot decide the current step"
+//	@Failure		404		{object}	common.Response[any]		"Access request not found"
+//	@Failure		409		{object}	common.Response[any]		"Request is not pending"
+//	@Failure		500		{object}	common.Response[any]		"Error when rejecting the request"
+//	@Router			/access-requests/{id}/reject [post]
+func (c *Controller) RejectAccessRequest(ctx *fiber.Ctx) error {
+	c.logger.Info("Received reject access request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseAccessRequestId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid access request ID format")
+	}
+	var request CommentRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	request.Id = id
+
+	response, err := c.accessRequestService.Reject(ctx.UserContext(), request, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// CommentAccessRequest добавляет комментарий к запросу
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Comment access request
+//	@Description	Add a comment to the history of the request. Available to everyone who can view the request
+//	@Tags			access-requests
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Access request ID"
+//	@Param			request	body		AccessRequestCommentRequest	true	"Comment"
+//	@Success		200		{object}	AccessRequestEventResponse	"Added comment"
+//	@Failure		400		{object}	common.Response[any]		"Invalid request"
+//	@Failure		404		{object}	common.Response[any]		"Access request not found"
+//	@Failure		500		{object}	common.Response[any]		"Error when adding the comment"
+//	@Router			/access-requests/{id}/comments [post]
+func (c *Controller) CommentAccessRequest(ctx *fiber.Ctx) error {
+	id, err := c.parseAccessRequestId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid access request ID format")
+	}
+	var request CommentRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	request.Id = id
+
+	response, err := c.accessRequestService.Comment(ctx.UserContext(), request, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// CancelAccessRequest отменяет запрос
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Cancel access request
+//	@Description	Cancel a pending request. Available to the requester, the employee and administrators
+//	@Tags			access-requests
+//	@Produce		json
+//	@Param			id	path		int						true	"Access request ID"
+//	@Success		200	{object}	AccessRequestResponse	"Cancelled request"
+//	@Failure		400	{object}	common.Response[any]	"Invalid access request ID"
+//	@Failure		403	{object}	common.Response[any]	"User cannot cancel the request"
+//	@Failure		404	{object}	common.Response[any]	"Access request not found"
+//	@Failure		409	{object}	common.Response[any]	"Request is not pending"
+//	@Failure		500	{object}	common.Response[any]	"Error when cancelling the request"
+//	@Router			/access-requests/{id}/cancel [post]
+func (c *Controller) CancelAccessRequest(ctx *fiber.Ctx) error {
+	c.logger.Info("Received cancel access request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseAccessRequestId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid access request ID format")
+	}
+
+	response, err := c.accessRequestService.Cancel(ctx.UserContext(), id, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindPolicies получает настройки согласования ролей
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List approval policies
+//	@Description	Approval steps configured for roles. Requests for other roles pass the default steps manager, admin
+//	@Tags			access-requests
+//	@Produce		json
+//	@Success		200	{array}		AccessRequestPolicyResponse	"Policies"
+//	@Failure		500	{object}	common.Response[any]		"Error when getting policies"
+//	@Router			/admin/access-requests/policies [get]
+func (c *Controller) FindPolicies(ctx *fiber.Ctx) error {
+	response, err := c.accessRequestService.FindPolicies(ctx.UserContext())
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// SetPolicy задаёт шаги согласования роли
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Set approval policy
+//	@Description	Set the approval steps of a role in order. Pending requests keep the steps they were submitted with
+//	@Tags			access-requests
+//	@Accept			json
+//	@Produce		json
+//	@Param			roleId	path		int							true	"Role ID"
+//	@Param			request	body		AccessRequestPolicyRequest	true	"Approval steps"
+//	@Success		200		{object}	AccessRequestPolicyResponse	"Saved policy"
+//	@Failure		400		{object}	common.Response[any]		"Invalid request"
+//	@Failure		404		{object}	common.Response[any]		"Role not found"
+//	@Failure		500		{object}	common.Response[any]		"Error when saving the policy"
+//	@Router			/admin/access-requests/policies/{roleId} [put]
+func (c *Controller) SetPolicy(ctx *fiber.Ctx) error {
+	c.logger.Info("Received set access request policy",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	roleId, err := strconv.ParseInt(ctx.Params("roleId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+	var request PolicyRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	request.RoleId = roleId
+
+	response, err := c.accessRequestService.SetPolicy(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleAccessRequestError(ctx, err, roleId)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// DeletePolicy удаляет настройку согласования роли
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Delete approval policy
+//	@Description	Requests for the role pass the default steps again
+//	@Tags			access-requests
+//	@Produce		json
+//	@Param			roleId	path		int						true	"Role ID"
+//	@Success		200		{object}	common.Response[any]	"Policy deleted"
+//	@Failure		400		{object}	common.Response[any]	"Invalid role ID"
+//	@Failure		404		{object}	common.Response[any]	"Policy not found"
+//	@Failure		500		{object}	common.Response[any]	"Error when deleting the policy"
+//	@Router			/admin/access-requests/policies/{roleId} [delete]
+func (c *Controller) DeletePolicy(ctx *fiber.Ctx) error {
+	c.logger.Info("Received delete access request policy",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	roleId, err := strconv.ParseInt(ctx.Params("roleId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	if err := c.accessRequestService.DeletePolicy(ctx.UserContext(), roleId); err != nil {
+		return c.handleAccessRequestError(ctx, err, roleId)
+	}
+	return common.OkResponse(ctx, fiber.Map{"message": "Access request policy deleted successfully"})
+}
+
+func (c *Controller) parseAccessRequestId(ctx *fiber.Ctx) (int64, error) {
+	return strconv.ParseInt(ctx.Params("id"), 10, 64)
+}
+
+// обрабатывает ошибки сервиса запросов доступа; ошибки назначения роли при последнем одобрении
+// приходят от сервиса сотрудников и обрабатываются так же
+func (c *Controller) handleAccessRequestError(ctx *fiber.Ctx, err error, id int64) error {
+	var validationErr common.RequestValidationError
+	if errors.As(err, &validationErr) {
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message, validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+	}
+	var forbiddenErr common.ForbiddenError
+	if errors.As(err, &forbiddenErr) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, forbiddenErr.Message)
+	}
+	var notFoundErr common.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return common.ErrResponse(ctx, fiber.StatusNotFound, notFoundErr.Message)
+	}
+	var conflictErr common.ConflictError
+	if errors.As(err, &conflictErr) {
+		return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message)
+	}
+	c.logger.Error("Access request failed",
+		zap.Int64("id", id),
+		zap.Error(err),
+		zap.String("ip", ctx.IP()))
+	return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when processing the access request")
+}
@@ -0,0 +1,329 @@
+package accessrequest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock для сервиса
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Submit(ctx context.Context, request CreateRequest, admin bool) (Response, error) {
+	args := m.Called(request, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindById(ctx context.Context, id int64, admin bool) (Response, error) {
+	args := m.Called(id, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindMine(ctx context.Context, status string) ([]Response, error) {
+	args := m.Called(status)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (m *MockService) FindAll(ctx context.Context, status string) ([]Response, error) {
+	args := m.Called(status)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (m *MockService) FindPendingApprovals(ctx context.Context, admin bool) ([]Response, error) {
+	args := m.Called(admin)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (m *MockService) Approve(ctx context.Context, request DecisionRequest, admin bool) (Response, error) {
+	args := m.Called(request, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Reject(ctx context.Context, request CommentRequest, admin bool) (Response, error) {
+	args := m.Called(request, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Comment(ctx context.Context, request CommentRequest, admin bool) (EventResponse, error) {
+	args := m.Called(request, admin)
+	return args.Get(0).(EventResponse), args.Error(1)
+}
+
+func (m *MockService) Cancel(ctx context.Context, id int64, admin bool) (Response, error) {
+	args := m.Called(id, admin)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindPolicies(ctx context.Context) ([]PolicyResponse, error) {
+	args := m.Called()
+	return args.Get(0).([]PolicyResponse), args.Error(1)
+}
+
+func (m *MockService) SetPolicy(ctx context.Context, request PolicyRequest) (PolicyResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(PolicyResponse), args.Error(1)
+}
+
+func (m *MockService) DeletePolicy(ctx context.Context, roleId int64) error {
+	return m.Called(roleId).Error(0)
+}
+
+// Вспомогательная функция для создания Fiber app; токен с ролями roles подставляется вместо Keycloak
+func setupTestApp(roles ...string) (*fiber.App, *MockService) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{
+			RealmAccess:       web.RealmAccessClaims{Roles: roles},
+			PreferredUsername: "user",
+			Email:             "user@example.com",
+			RegisteredClaims:  jwt.RegisteredClaims{Subject: "sub-1"},
+		}})
+		return c.Next()
+	})
+	app.Use(web.ActorMiddleware())
+	mockService := &MockService{}
+
+	server := &web.Server{
+		GroupApiV1:      app.Group("/api/v1"),
+		GroupApiV1User:  app.Group("/api/v1"),
+		GroupApiV1Admin: app.Group("/api/v1/admin"),
+	}
+
+	controller := NewController(server, mockService, createTestLogger())
+	controller.RegisterRoutes()
+
+	return app, mockService
+}
+
+func TestController_SubmitAccessRequest(t *testing.T) {
+	t.Run("Submitted", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Submit", CreateRequest{RoleId: 3, Justification: "Need access"}, false).
+			Return(Response{Id: 10, Status: StatusPending, CurrentStepType: StepManager}, nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/access-requests",
+			bytes.NewBufferString(`{"role_id":3,"justification":"Need access"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var response common.Response[Response]
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		assert.Equal(t, int64(10), response.Data.Id)
+		assert.Equal(t, StepManager, response.Data.CurrentStepType)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Forbidden for an employee who is not a report", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Submit", mock.Anything, false).
+			Return(Response{}, common.ForbiddenError{Message: "a role can be requested only for yourself or your direct report"})
+
+		req := httptest.NewRequest("POST", "/api/v1/access-requests",
+			bytes.NewBufferString(`{"employee_id":9,"role_id":3,"justification":"Need access"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Duplicate request", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Submit", mock.Anything, false).
+			Return(Response{}, common.ConflictError{Message: "employee 7 already has a pending request for role 3"})
+
+		req := httptest.NewRequest("POST", "/api/v1/access-requests",
+			bytes.NewBufferString(`{"role_id":3,"justification":"Need access"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+}
+
+func TestController_FindMyAccessRequests(t *testing.T) {
+	t.Run("Filtered by status", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("FindMine", StatusPending).Return([]Response{{Id: 10}}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/access-requests?status=pending", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid status", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/access-requests?status=unknown", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "FindMine", mock.Anything)
+	})
+}
+
+func TestController_FindPendingApprovals(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmAdmin)
+	mockService.On("FindPendingApprovals", true).Return([]Response{{Id: 10}}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/access-requests/approvals", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_FindAccessRequestById_NotFound(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmUser)
+	mockService.On("FindById", int64(10), false).
+		Return(Response{}, common.NotFoundError{Message: "access request with id 10 not found"})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/access-requests/10", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestController_ApproveAccessRequest(t *testing.T) {
+	t.Run("Approved without body", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Approve", DecisionRequest{Id: 10}, false).
+			Return(Response{Id: 10, Status: StatusApproved}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/access-requests/10/approve", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Approved with comment", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Approve", DecisionRequest{Id: 10, Comment: "ok"}, false).
+			Return(Response{Id: 10, Status: StatusPending}, nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/access-requests/10/approve", bytes.NewBufferString(`{"comment":"ok"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("User cannot decide the step", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Approve", DecisionRequest{Id: 10}, false).
+			Return(Response{}, common.ForbiddenError{Message: "access request 10 awaits approval of step admin"})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/access-requests/10/approve", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/access-requests/abc/approve", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Approve", mock.Anything, mock.Anything)
+	})
+}
+
+func TestController_RejectAccessRequest_ValidationError(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmUser)
+	mockService.On("Reject", CommentRequest{Id: 10}, false).
+		Return(Response{}, common.RequestValidationError{Message: "Data validation error"})
+
+	req := httptest.NewRequest("POST", "/api/v1/access-requests/10/reject", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_CommentAccessRequest(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmUser)
+	mockService.On("Comment", CommentRequest{Id: 10, Comment: "Which period?"}, false).
+		Return(EventResponse{Id: 1, Action: ActionComment}, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/access-requests/10/comments", bytes.NewBufferString(`{"comment":"Which period?"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_CancelAccessRequest_Conflict(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmUser)
+	mockService.On("Cancel", int64(10), false).
+		Return(Response{}, common.ConflictError{Message: "access request 10 is already approved"})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/access-requests/10/cancel", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestController_Policies(t *testing.T) {
+	t.Run("Set policy", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		mockService.On("SetPolicy", PolicyRequest{RoleId: 3, Steps: []string{StepManager, StepAdmin}}).
+			Return(PolicyResponse{RoleId: 3, Steps: []string{StepManager, StepAdmin}}, nil)
+
+		req := httptest.NewRequest("PUT", "/api/v1/admin/access-requests/policies/3",
+			bytes.NewBufferString(`{"steps":["manager","admin"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Delete missing policy", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		mockService.On("DeletePolicy", int64(3)).
+			Return(common.NotFoundError{Message: "access request policy of role 3 not found"})
+
+		resp, err := app.Test(httptest.NewRequest("DELETE", "/api/v1/admin/access-requests/policies/3", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("List all requests", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		mockService.On("FindAll", "").Return([]Response{}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/admin/access-requests", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+}
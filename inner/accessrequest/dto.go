@@ -0,0 +1,216 @@
+package accessrequest
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// состояния запроса доступа
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+)
+
+// шаги согласования: руководитель сотрудника, владелец роли, администратор IDM
+const (
+	StepManager   = "manager"
+	StepRoleOwner = "role_owner"
+	StepAdmin     = "admin"
+)
+
+// события истории запроса
+const (
+	ActionSubmit  = "submit"
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionComment = "comment"
+	ActionCancel  = "cancel"
+	ActionExpire  = "expire"
+)
+
+// DefaultSteps шаги согласования ролей без собственной настройки
+var DefaultSteps = []string{StepManager, StepAdmin}
+
+type Entity struct {
+	Id         int64 `db:"id"`
+	EmployeeId int64 `db:"employee_id"`
+	// имя сотрудника, только для чтения
+	EmployeeName string `db:"employee_name"`
+	// руководитель сотрудника (согласующий шага manager), только для чтения
+	ManagerId *int64 `db:"manager_id"`
+	RoleId    int64  `db:"role_id"`
+	// название роли, только для чтения
+	RoleName string `db:"role_name"`
+	// владелец роли (согласующий шага role_owner), только для чтения
+	RoleOwnerId         *int64         `db:"role_owner_id"`
+	RequesterEmployeeId *int64         `db:"requester_employee_id"`
+	Justification       string         `db:"justification"`
+	ValidFrom           *time.Time     `db:"valid_from"`
+	ValidTo             *time.Time     `db:"valid_to"`
+	Steps               pq.StringArray `db:"steps"`
+	CurrentStep         int            `db:"current_step"`
+	Status              string         `db:"status"`
+	AssignmentId        *int64         `db:"assignment_id"`
+	CreatedBySub        *string        `db:"created_by_sub"`
+	CreatedByUsername   *string        `db:"created_by_username"`
+	ExpiresAt           time.Time      `db:"expires_at"`
+	CreatedAt           time.Time      `db:"created_at"`
+	UpdatedAt           time.Time      `db:"updated_at"`
+	DecidedAt           *time.Time     `db:"decided_at"`
+}
+
+// шаг согласования, ожидающий решения; пустая строка - запрос уже не рассматривается
+func (e *Entity) currentStepType() string {
+	if e.Status != StatusPending || e.CurrentStep >= len(e.Steps) {
+		return ""
+	}
+	return e.Steps[e.CurrentStep]
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:                  e.Id,
+		EmployeeId:          e.EmployeeId,
+		EmployeeName:        e.EmployeeName,
+		RoleId:              e.RoleId,
+		RoleName:            e.RoleName,
+		RequesterEmployeeId: e.RequesterEmployeeId,
+		RequestedBy:         e.CreatedByUsername,
+		Justification:       e.Justification,
+		ValidFrom:           e.ValidFrom,
+		ValidTo:             e.ValidTo,
+		Steps:               e.Steps,
+		CurrentStep:         e.CurrentStep,
+		CurrentStepType:     e.currentStepType(),
+		Status:              e.Status,
+		AssignmentId:        e.AssignmentId,
+		ExpiresAt:           e.ExpiresAt,
+		CreatedAt:           e.CreatedAt,
+		UpdatedAt:           e.UpdatedAt,
+		DecidedAt:           e.DecidedAt,
+	}
+}
+
+// Response запрос доступа. Events - история запроса, заполняется только при получении запроса по id
+type Response struct {
+	Id                  int64      `json:"id"`
+	EmployeeId          int64      `json:"employee_id"`
+	EmployeeName        string     `json:"employee_name"`
+	RoleId              int64      `json:"role_id"`
+	RoleName            string     `json:"role_name"`
+	RequesterEmployeeId *int64     `json:"requester_employee_id"`
+	RequestedBy         *string    `json:"requested_by"`
+	Justification       string     `json:"justification"`
+	ValidFrom           *time.Time `json:"valid_from,omitempty"`
+	ValidTo             *time.Time `json:"valid_to,omitempty"`
+	Steps               []string   `json:"steps"`
+	CurrentStep         int        `json:"current_step"`
+	// шаг, ожидающий решения (manager, role_owner или admin); пусто, если решение принято
+	CurrentStepType string          `json:"current_step_type,omitempty"`
+	Status          string          `json:"status"`
+	AssignmentId    *int64          `json:"assignment_id,omitempty"`
+	ExpiresAt       time.Time       `json:"expires_at"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	DecidedAt       *time.Time      `json:"decided_at,omitempty"`
+	Events          []EventResponse `json:"events,omitempty"`
+} // @name AccessRequestResponse
+
+type EventEntity struct {
+	Id              int64     `db:"id"`
+	RequestId       int64     `db:"request_id"`
+	Action          string    `db:"action"`
+	Step            *string   `db:"step"`
+	Comment         *string   `db:"comment"`
+	ActorEmployeeId *int64    `db:"actor_employee_id"`
+	ActorSub        *string   `db:"actor_sub"`
+	ActorUsername   *string   `db:"actor_username"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+func (e *EventEntity) toResponse() EventResponse {
+	return EventResponse{
+		Id:              e.Id,
+		Action:          e.Action,
+		Step:            e.Step,
+		Comment:         e.Comment,
+		ActorEmployeeId: e.ActorEmployeeId,
+		ActorUsername:   e.ActorUsername,
+		CreatedAt:       e.CreatedAt,
+	}
+}
+
+// EventResponse событие истории запроса доступа
+type EventResponse struct {
+	Id              int64     `json:"id"`
+	Action          string    `json:"action"`
+	Step            *string   `json:"step,omitempty"`
+	Comment         *string   `json:"comment,omitempty"`
+	ActorEmployeeId *int64    `json:"actor_employee_id,omitempty"`
+	ActorUsername   *string   `json:"actor_username"`
+	CreatedAt       time.Time `json:"created_at"`
+} // @name AccessRequestEventResponse
+
+type PolicyEntity struct {
+	RoleId    int64          `db:"role_id"`
+	Steps     pq.StringArray `db:"steps"`
+	CreatedAt time.Time      `db:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at"`
+}
+
+func (e *PolicyEntity) toResponse() PolicyResponse {
+	return PolicyResponse{
+		RoleId:    e.RoleId,
+		Steps:     e.Steps,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+// PolicyResponse шаги согласования запросов роли
+type PolicyResponse struct {
+	RoleId    int64     `json:"role_id"`
+	Steps     []string  `json:"steps"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+} // @name AccessRequestPolicyResponse
+
+// сотрудник, для которого запрашивается роль
+type employeeEntity struct {
+	Id        int64  `db:"id"`
+	Status    string `db:"status"`
+	ManagerId *int64 `db:"manager_id"`
+}
+
+// CreateRequest запрос роли. Без employee_id роль запрашивается для себя;
+// руководитель может запросить роль для своего непосредственного подчинённого.
+// ValidFrom и ValidTo - период будущего назначения, как у назначения роли администратором
+type CreateRequest struct {
+	EmployeeId    int64      `json:"employee_id" validate:"omitempty,min=1" example:"7"`
+	RoleId        int64      `json:"role_id" validate:"required,min=1" example:"3"`
+	Justification string     `json:"justification" validate:"required,min=5,max=1000" example:"Need access to payment reports for Q3 closing"`
+	ValidFrom     *time.Time `json:"valid_from,omitempty" example:"2025-07-01T00:00:00Z"`
+	ValidTo       *time.Time `json:"valid_to,omitempty" example:"2025-12-31T23:59:59Z"`
+} // @name AccessRequestCreateRequest
+
+// DecisionRequest решение согласующего по текущему шагу; комментарий к одобрению необязателен
+type DecisionRequest struct {
+	Id      int64  `json:"-"`
+	Comment string `json:"comment" validate:"max=1000" example:"Approved for the Q3 closing period"`
+} // @name AccessRequestDecisionRequest
+
+// CommentRequest комментарий к запросу; при отклонении запроса комментарий обязателен
+type CommentRequest struct {
+	Id      int64  `json:"-"`
+	Comment string `json:"comment" validate:"required,max=1000" example:"Please specify the reporting period"`
+} // @name AccessRequestCommentRequest
+
+// PolicyRequest шаги согласования запросов роли в порядке прохождения
+type PolicyRequest struct {
+	RoleId int64    `json:"-"`
+	Steps  []string `json:"steps" validate:"required,min=1,max=5,dive,oneof=manager role_owner admin" example:"manager,role_owner"`
+} // @name AccessRequestPolicyRequest
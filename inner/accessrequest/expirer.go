@@ -0,0 +1,86 @@
+package accessrequest
+
+import (
+	"context"
+	"idm/inner/common"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// интерфейс истечения просроченных запросов (реализуется accessrequest.Service)
+type StaleExpirer interface {
+	ExpireStale(ctx context.Context) (int, error)
+}
+
+// Expirer фоновая горутина, периодически переводящая в expired запросы, не получившие решения в срок
+type Expirer struct {
+	expirer  StaleExpirer
+	interval time.Duration
+	logger   *common.Logger
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// функция-конструктор
+func NewExpirer(expirer StaleExpirer, interval time.Duration, logger *common.Logger) *Expirer {
+	return &Expirer{
+		expirer:  expirer,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start запускает проверку; первая проверка выполняется сразу
+func (e *Expirer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.wg.Add(1)
+	go e.run(ctx)
+	e.logger.Info("Access request expirer started", zap.Duration("interval", e.interval))
+}
+
+// Stop останавливает проверку и ждёт завершения текущего прохода или отмены ctx
+func (e *Expirer) Stop(ctx context.Context) {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		e.logger.Info("Access request expirer stopped")
+	case <-ctx.Done():
+		e.logger.Warn("Access request expirer stop timeout exceeded")
+	}
+}
+
+func (e *Expirer) run(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// один проход; ошибки только логируются, запросы истекут на следующем проходе
+func (e *Expirer) tick(ctx context.Context) {
+	if _, err := e.expirer.ExpireStale(ctx); err != nil && ctx.Err() == nil {
+		e.logger.Error("Failed to expire stale access requests", zap.Error(err))
+	}
+}
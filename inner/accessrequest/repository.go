@@ -0,0 +1,207 @@
+package accessrequest
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+// запрос с именами сотрудника и роли, руководителем сотрудника и владельцем роли
+const selectRequest = `SELECT ar.*, e.name AS employee_name, e.manager_id, r.name AS role_name,
+	r.owner_id AS role_owner_id
+	FROM access_request ar
+	JOIN employee e ON e.id = ar.employee_id
+	JOIN role r ON r.id = ar.role_id`
+
+// шаг запроса, ожидающий решения (массивы в postgres нумеруются с единицы)
+const currentStepColumn = `ar.steps[ar.current_step + 1]`
+
+func NewAccessRequestRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// Транзакционные методы
+func (r *Repository) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// Найти действующего сотрудника по адресу почты пользователя; адреса сравниваются без учёта регистра
+func (r *Repository) FindEmployeeIdByEmail(ctx context.Context, email string) (id int64, err error) {
+	err = r.db.GetContext(ctx, &id,
+		`SELECT id FROM employee
+		WHERE lower(email) = lower($1) AND deleted_at IS NULL AND status <> 'terminated'
+		ORDER BY id LIMIT 1`,
+		email)
+	return id, err
+}
+
+func (r *Repository) FindEmployeeTx(ctx context.Context, tx *sqlx.Tx, id int64) (employee employeeEntity, err error) {
+	err = tx.GetContext(ctx, &employee,
+		"SELECT id, status, manager_id FROM employee WHERE id = $1 AND deleted_at IS NULL", id)
+	return employee, err
+}
+
+// Проверить существование роли
+func (r *Repository) RoleExistsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "SELECT exists(SELECT 1 FROM role WHERE id = $1 AND deleted_at IS NULL)", roleId)
+	return isExists, err
+}
+
+// Проверить, назначена ли роль сотруднику сейчас
+func (r *Repository) HasActiveAssignmentTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists,
+		`SELECT exists(
+			SELECT 1 FROM employee_role er
+			WHERE er.employee_id = $1 AND er.role_id = $2
+				AND er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+		)`,
+		employeeId, roleId)
+	return isExists, err
+}
+
+// Найти шаги согласования роли; sql.ErrNoRows - настройки нет, используются шаги по умолчанию
+func (r *Repository) FindPolicyStepsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (steps pq.StringArray, err error) {
+	err = tx.GetContext(ctx, &steps, "SELECT steps FROM access_request_policy WHERE role_id = $1", roleId)
+	return steps, err
+}
+
+// Создать запрос. Если у сотрудника уже есть рассматриваемый запрос этой роли,
+// запрос не создаётся и возвращается sql.ErrNoRows
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, request Entity) (id int64, err error) {
+	err = tx.GetContext(ctx, &id,
+		`INSERT INTO access_request (employee_id, role_id, requester_employee_id, justification, valid_from, valid_to,
+			steps, current_step, created_by_sub, created_by_username, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (employee_id, role_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id`,
+		request.EmployeeId, request.RoleId, request.RequesterEmployeeId, request.Justification,
+		request.ValidFrom, request.ValidTo, request.Steps, request.CurrentStep,
+		request.CreatedBySub, request.CreatedByUsername, request.ExpiresAt)
+	return id, err
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (request Entity, err error) {
+	err = r.db.GetContext(ctx, &request, selectRequest+" WHERE ar.id = $1", id)
+	return request, err
+}
+
+// Найти запрос по id и заблокировать его строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (request Entity, err error) {
+	err = tx.GetContext(ctx, &request, selectRequest+" WHERE ar.id = $1 FOR UPDATE OF ar", id)
+	return request, err
+}
+
+// Сохранить продвижение запроса по шагам и решение
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, request Entity) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE access_request SET current_step = $2, status = $3, assignment_id = $4, expires_at = $5, decided_at = $6
+		WHERE id = $1`,
+		request.Id, request.CurrentStep, request.Status, request.AssignmentId, request.ExpiresAt, request.DecidedAt)
+	return err
+}
+
+// Записать событие в историю запроса
+func (r *Repository) AddEventTx(ctx context.Context, tx *sqlx.Tx, event EventEntity) (created EventEntity, err error) {
+	err = tx.GetContext(ctx, &created,
+		`INSERT INTO access_request_event (request_id, action, step, comment, actor_employee_id, actor_sub, actor_username)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *`,
+		event.RequestId, event.Action, event.Step, event.Comment, event.ActorEmployeeId, event.ActorSub, event.ActorUsername)
+	return created, err
+}
+
+func (r *Repository) FindEvents(ctx context.Context, requestId int64) ([]EventEntity, error) {
+	var events []EventEntity
+	err := r.db.SelectContext(ctx, &events,
+		"SELECT * FROM access_request_event WHERE request_id = $1 ORDER BY id", requestId)
+	return events, err
+}
+
+// Найти запросы пользователя: поданные им (по JWT sub) и поданные для его сотрудника.
+// employeeId = 0 - пользователь не сопоставлен с сотрудником, status = "" - все состояния
+func (r *Repository) FindMine(ctx context.Context, sub string, employeeId int64, status string) ([]Entity, error) {
+	var requests []Entity
+	err := r.db.SelectContext(ctx, &requests,
+		selectRequest+` WHERE (ar.created_by_sub = $1 OR ar.employee_id = $2) AND ($3 = '' OR ar.status = $3)
+		ORDER BY ar.id DESC`,
+		sub, employeeId, status)
+	return requests, err
+}
+
+// Найти все запросы; status = "" - все состояния
+func (r *Repository) FindAll(ctx context.Context, status string) ([]Entity, error) {
+	var requests []Entity
+	err := r.db.SelectContext(ctx, &requests,
+		selectRequest+" WHERE ($1 = '' OR ar.status = $1) ORDER BY ar.id DESC", status)
+	return requests, err
+}
+
+// Найти запросы, ожидающие решения пользователя: шаг manager - у руководителя сотрудника,
+// шаг role_owner - у владельца роли, шаг admin, шаг manager сотрудника без руководителя
+// и шаг role_owner роли без владельца (или если владелец и есть сотрудник) - у администраторов.
+// Свои запросы и запросы для себя не согласуются, поэтому не возвращаются
+func (r *Repository) FindPendingApprovals(ctx context.Context, sub string, employeeId int64, admin bool) ([]Entity, error) {
+	var requests []Entity
+	err := r.db.SelectContext(ctx, &requests,
+		selectRequest+` WHERE ar.status = 'pending'
+			AND ar.created_by_sub IS DISTINCT FROM $1 AND ar.employee_id <> $2
+			AND (
+				(`+currentStepColumn+` = 'manager' AND e.manager_id = $2)
+				OR (`+currentStepColumn+` = 'role_owner' AND r.owner_id = $2)
+				OR ($3 AND (
+					`+currentStepColumn+` = 'admin'
+					OR (`+currentStepColumn+` = 'manager' AND e.manager_id IS NULL)
+					OR (`+currentStepColumn+` = 'role_owner' AND (r.owner_id IS NULL OR r.owner_id = ar.employee_id))
+				))
+			)
+		ORDER BY ar.id`,
+		sub, employeeId, admin)
+	return requests, err
+}
+
+// Перевести в состояние expired рассматриваемые запросы, срок которых истёк, и записать это в их историю.
+// Возвращает id истёкших запросов
+func (r *Repository) ExpireStale(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	err := r.db.SelectContext(ctx, &ids,
+		`WITH expired AS (
+			UPDATE access_request ar SET status = 'expired', decided_at = now()
+			WHERE ar.status = 'pending' AND ar.expires_at <= now()
+			RETURNING ar.id, `+currentStepColumn+` AS step
+		)
+		INSERT INTO access_request_event (request_id, action, step)
+		SELECT id, 'expire', step FROM expired
+		RETURNING request_id`)
+	return ids, err
+}
+
+func (r *Repository) FindPolicies(ctx context.Context) ([]PolicyEntity, error) {
+	var policies []PolicyEntity
+	err := r.db.SelectContext(ctx, &policies, "SELECT * FROM access_request_policy ORDER BY role_id")
+	return policies, err
+}
+
+// Создать или заменить шаги согласования роли
+func (r *Repository) SavePolicyTx(ctx context.Context, tx *sqlx.Tx, roleId int64, steps []string) (policy PolicyEntity, err error) {
+	err = tx.GetContext(ctx, &policy,
+		`INSERT INTO access_request_policy (role_id, steps) VALUES ($1, $2)
+		ON CONFLICT (role_id) DO UPDATE SET steps = EXCLUDED.steps
+		RETURNING *`,
+		roleId, pq.Array(steps))
+	return policy, err
+}
+
+// Удалить настройку согласования роли; false - настройки не было
+func (r *Repository) DeletePolicy(ctx context.Context, roleId int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM access_request_policy WHERE role_id = $1", roleId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
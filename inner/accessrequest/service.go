@@ -0,0 +1,817 @@
+package accessrequest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/validator"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// запрос без решения по текущему шагу дольше requestTTL истекает
+const requestTTL = 14 * 24 * time.Hour
+
+type Service struct {
+	repo      Repo
+	assigner  Assigner
+	validator Validator
+	logger    *common.Logger
+}
+
+type Repo interface {
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindEmployeeIdByEmail(ctx context.Context, email string) (int64, error)
+	FindEmployeeTx(ctx context.Context, tx *sqlx.Tx, id int64) (employeeEntity, error)
+	RoleExistsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (bool, error)
+	HasActiveAssignmentTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) (bool, error)
+	FindPolicyStepsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (pq.StringArray, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, request Entity) (int64, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, request Entity) error
+	AddEventTx(ctx context.Context, tx *sqlx.Tx, event EventEntity) (EventEntity, error)
+	FindEvents(ctx context.Context, requestId int64) ([]EventEntity, error)
+	FindMine(ctx context.Context, sub string, employeeId int64, status string) ([]Entity, error)
+	FindAll(ctx context.Context, status string) ([]Entity, error)
+	FindPendingApprovals(ctx context.Context, sub string, employeeId int64, admin bool) ([]Entity, error)
+	ExpireStale(ctx context.Context) ([]int64, error)
+	FindPolicies(ctx context.Context) ([]PolicyEntity, error)
+	SavePolicyTx(ctx context.Context, tx *sqlx.Tx, roleId int64, steps []string) (PolicyEntity, error)
+	DeletePolicy(ctx context.Context, roleId int64) (bool, error)
+}
+
+// интерфейс назначения ролей (реализуется employee.Service): одобренный запрос
+// назначает роль так же, как администратор, с проверками и записью в журнал аудита
+type Assigner interface {
+	AssignRole(ctx context.Context, request employee.AssignRoleRequest) (employee.RoleAssignmentResponse, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// пользователь, выполняющий действие с запросом
+type caller struct {
+	sub      string
+	username string
+	// сотрудник пользователя (сопоставляется по адресу почты); 0 - не найден
+	employeeId int64
+	admin      bool
+}
+
+// функция-конструктор
+func NewService(repo Repo, assigner Assigner, validator Validator, logger *common.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		assigner:  assigner,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// Метод для подачи запроса роли. Шаги согласования берутся из настройки роли на момент подачи;
+// если запрос подаёт руководитель сотрудника, начальные шаги manager считаются пройденными
+func (svc *Service) Submit(ctx context.Context, request CreateRequest, admin bool) (Response, error) {
+	svc.logger.Info("Submitting access request",
+		zap.Int64("employee_id", request.EmployeeId),
+		zap.Int64("role_id", request.RoleId))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+	validFrom := time.Now()
+	if request.ValidFrom != nil {
+		validFrom = *request.ValidFrom
+	}
+	if request.ValidTo != nil && !request.ValidTo.After(validFrom) {
+		return Response{}, common.RequestValidationError{Message: "valid_to must be after valid_from"}
+	}
+
+	c, err := svc.resolveCaller(ctx, admin)
+	if err != nil {
+		return Response{}, err
+	}
+	if request.EmployeeId == 0 {
+		if c.employeeId == 0 {
+			return Response{}, common.RequestValidationError{
+				Message: "your account is not linked to an employee, employee_id is required",
+			}
+		}
+		request.EmployeeId = c.employeeId
+	}
+
+	id, approved, err := svc.create(ctx, request, c)
+	if err != nil {
+		return Response{}, err
+	}
+	// назначение создаётся отдельной транзакцией: сервис сотрудников блокирует сотрудника,
+	// на которого уже ссылается созданный запрос
+	if approved {
+		if err := svc.complete(ctx, id, c); err != nil {
+			return Response{}, err
+		}
+	}
+
+	svc.logger.Info("Access request submitted",
+		zap.Int64("id", id),
+		zap.Int64("employee_id", request.EmployeeId),
+		zap.Int64("role_id", request.RoleId))
+	return svc.findResponse(ctx, id)
+}
+
+// создаёт запрос; approved = true - все шаги пройдены при подаче
+func (svc *Service) create(ctx context.Context, request CreateRequest, c caller) (id int64, approved bool, err error) {
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for access request", zap.Error(err))
+		return 0, false, fmt.Errorf("error submit access request: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	target, err := svc.repo.FindEmployeeTx(ctx, tx, request.EmployeeId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, common.RequestValidationError{
+				Message: fmt.Sprintf("employee with id %d does not exist", request.EmployeeId),
+			}
+		}
+		svc.logger.Error("Failed to find employee", zap.Int64("employee_id", request.EmployeeId), zap.Error(err))
+		return 0, false, fmt.Errorf("error finding employee with id %d: %w", request.EmployeeId, err)
+	}
+	if target.Status == employee.StatusTerminated {
+		return 0, false, common.ConflictError{
+			Message: fmt.Sprintf("employee %d is terminated", request.EmployeeId),
+		}
+	}
+	requestedByManager := c.employeeId != 0 && target.ManagerId != nil && *target.ManagerId == c.employeeId
+	if request.EmployeeId != c.employeeId && !requestedByManager && !c.admin {
+		return 0, false, common.ForbiddenError{
+			Message: "a role can be requested only for yourself or your direct report",
+		}
+	}
+
+	isExist, err := svc.repo.RoleExistsTx(ctx, tx, request.RoleId)
+	if err != nil {
+		svc.logger.Error("Failed to check role existence", zap.Int64("role_id", request.RoleId), zap.Error(err))
+		return 0, false, fmt.Errorf("error finding role with id %d: %w", request.RoleId, err)
+	}
+	if !isExist {
+		return 0, false, common.RequestValidationError{
+			Message: fmt.Sprintf("role with id %d does not exist", request.RoleId),
+		}
+	}
+
+	assigned, err := svc.repo.HasActiveAssignmentTx(ctx, tx, request.EmployeeId, request.RoleId)
+	if err != nil {
+		svc.logger.Error("Failed to check role assignment",
+			zap.Int64("employee_id", request.EmployeeId),
+			zap.Int64("role_id", request.RoleId),
+			zap.Error(err))
+		return 0, false, fmt.Errorf("error checking role assignments of employee %d: %w", request.EmployeeId, err)
+	}
+	if assigned {
+		return 0, false, common.ConflictError{
+			Message: fmt.Sprintf("role %d is already assigned to employee %d", request.RoleId, request.EmployeeId),
+		}
+	}
+
+	steps, err := svc.repo.FindPolicyStepsTx(ctx, tx, request.RoleId)
+	if errors.Is(err, sql.ErrNoRows) {
+		steps, err = DefaultSteps, nil
+	}
+	if err != nil {
+		svc.logger.Error("Failed to find access request policy", zap.Int64("role_id", request.RoleId), zap.Error(err))
+		return 0, false, fmt.Errorf("error finding approval steps of role %d: %w", request.RoleId, err)
+	}
+
+	// запрос руководителя уже выражает его согласие
+	currentStep := 0
+	if requestedByManager {
+		for currentStep < len(steps) && steps[currentStep] == StepManager {
+			currentStep++
+		}
+	}
+
+	entity := Entity{
+		EmployeeId:        request.EmployeeId,
+		RoleId:            request.RoleId,
+		Justification:     request.Justification,
+		ValidFrom:         request.ValidFrom,
+		ValidTo:           request.ValidTo,
+		Steps:             steps,
+		CurrentStep:       currentStep,
+		CreatedBySub:      nullable(c.sub),
+		CreatedByUsername: nullable(c.username),
+		ExpiresAt:         time.Now().Add(requestTTL),
+	}
+	if c.employeeId != 0 {
+		entity.RequesterEmployeeId = &c.employeeId
+	}
+	id, err = svc.repo.CreateTx(ctx, tx, entity)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, common.ConflictError{
+				Message: fmt.Sprintf("employee %d already has a pending request for role %d", request.EmployeeId, request.RoleId),
+			}
+		}
+		svc.logger.Error("Failed to create access request",
+			zap.Int64("employee_id", request.EmployeeId),
+			zap.Int64("role_id", request.RoleId),
+			zap.Error(err))
+		return 0, false, fmt.Errorf("error creating access request: %w", err)
+	}
+
+	if err = svc.addEvent(ctx, tx, id, ActionSubmit, "", request.Justification, c); err != nil {
+		return 0, false, err
+	}
+	for _, step := range steps[:currentStep] {
+		if err = svc.addEvent(ctx, tx, id, ActionApprove, step, "submitted by the employee's manager", c); err != nil {
+			return 0, false, err
+		}
+	}
+	return id, currentStep == len(steps), nil
+}
+
+// завершает запрос, все шаги которого пройдены при подаче
+func (svc *Service) complete(ctx context.Context, id int64, c caller) (err error) {
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for access request completion", zap.Int64("id", id), zap.Error(err))
+		return fmt.Errorf("error complete access request: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	entity, err := svc.findForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if entity.Status != StatusPending || entity.CurrentStep < len(entity.Steps) {
+		return nil
+	}
+	if err = svc.approveFinal(ctx, &entity); err != nil {
+		return err
+	}
+	return svc.update(ctx, tx, entity)
+}
+
+// Метод для одобрения текущего шага. После последнего шага сотруднику назначается роль;
+// если назначить роль нельзя (например, она уже назначена), запрос остаётся на последнем шаге
+func (svc *Service) Approve(ctx context.Context, request DecisionRequest, admin bool) (Response, error) {
+	svc.logger.Info("Approving access request", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+	err := svc.decide(ctx, request.Id, admin, func(tx *sqlx.Tx, entity *Entity, c caller) error {
+		if err := svc.addEvent(ctx, tx, entity.Id, ActionApprove, entity.currentStepType(), request.Comment, c); err != nil {
+			return err
+		}
+		entity.CurrentStep++
+		if entity.CurrentStep < len(entity.Steps) {
+			// срок рассмотрения отсчитывается заново для каждого шага
+			entity.ExpiresAt = time.Now().Add(requestTTL)
+			return nil
+		}
+		return svc.approveFinal(ctx, entity)
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Access request approved", zap.Int64("id", request.Id))
+	return svc.findResponse(ctx, request.Id)
+}
+
+// Метод для отклонения запроса согласующим текущего шага; причина отклонения обязательна
+func (svc *Service) Reject(ctx context.Context, request CommentRequest, admin bool) (Response, error) {
+	svc.logger.Info("Rejecting access request", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+	err := svc.decide(ctx, request.Id, admin, func(tx *sqlx.Tx, entity *Entity, c caller) error {
+		if err := svc.addEvent(ctx, tx, entity.Id, ActionReject, entity.currentStepType(), request.Comment, c); err != nil {
+			return err
+		}
+		now := time.Now()
+		entity.Status, entity.DecidedAt = StatusRejected, &now
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Access request rejected", zap.Int64("id", request.Id))
+	return svc.findResponse(ctx, request.Id)
+}
+
+// решение по текущему шагу: запрос блокируется, проверяется право пользователя на решение,
+// apply изменяет запрос, после чего он сохраняется
+func (svc *Service) decide(
+	ctx context.Context,
+	id int64,
+	admin bool,
+	apply func(tx *sqlx.Tx, entity *Entity, c caller) error,
+) (err error) {
+	c, err := svc.resolveCaller(ctx, admin)
+	if err != nil {
+		return err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for access request decision", zap.Int64("id", id), zap.Error(err))
+		return fmt.Errorf("error decide access request: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	entity, err := svc.findForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if !canView(entity, c) {
+		return notFound(id)
+	}
+	if err = checkDecision(entity, c); err != nil {
+		svc.logger.Warn("Access request decision rejected",
+			zap.Int64("id", id),
+			zap.String("step", entity.currentStepType()),
+			zap.Error(err))
+		return err
+	}
+
+	if err = apply(tx, &entity, c); err != nil {
+		return err
+	}
+	return svc.update(ctx, tx, entity)
+}
+
+// назначает роль по одобренному запросу от имени одобрившего последний шаг
+func (svc *Service) approveFinal(ctx context.Context, entity *Entity) error {
+	assignment, err := svc.assigner.AssignRole(ctx, employee.AssignRoleRequest{
+		EmployeeId: entity.EmployeeId,
+		RoleId:     entity.RoleId,
+		ValidFrom:  entity.ValidFrom,
+		ValidTo:    entity.ValidTo,
+	})
+	if err != nil {
+		svc.logger.Warn("Failed to assign role for approved access request",
+			zap.Int64("id", entity.Id),
+			zap.Int64("employee_id", entity.EmployeeId),
+			zap.Int64("role_id", entity.RoleId),
+			zap.Error(err))
+		return err
+	}
+
+	now := time.Now()
+	entity.Status, entity.AssignmentId, entity.DecidedAt = StatusApproved, &assignment.Id, &now
+	return nil
+}
+
+// Метод для отмены рассматриваемого запроса подавшим его пользователем или сотрудником, для которого он подан
+func (svc *Service) Cancel(ctx context.Context, id int64, admin bool) (response Response, err error) {
+	svc.logger.Info("Cancelling access request", zap.Int64("id", id))
+
+	c, err := svc.resolveCaller(ctx, admin)
+	if err != nil {
+		return Response{}, err
+	}
+
+	err = func() (err error) {
+		tx, err := svc.repo.BeginTransaction(ctx)
+		if err != nil {
+			svc.logger.Error("Failed to begin transaction for access request cancellation", zap.Int64("id", id), zap.Error(err))
+			return fmt.Errorf("error cancel access request: error creating transaction: %w", err)
+		}
+		defer func() {
+			err = svc.finishTransaction(tx, err, id)
+		}()
+
+		entity, err := svc.findForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !canView(entity, c) {
+			return notFound(id)
+		}
+		isRequester := c.sub != "" && valueOf(entity.CreatedBySub) == c.sub
+		isEmployee := c.employeeId != 0 && c.employeeId == entity.EmployeeId
+		if !isRequester && !isEmployee && !c.admin {
+			return common.ForbiddenError{Message: "only the requester can cancel an access request"}
+		}
+		if entity.Status != StatusPending {
+			return alreadyDecided(entity)
+		}
+
+		if err = svc.addEvent(ctx, tx, id, ActionCancel, entity.currentStepType(), "", c); err != nil {
+			return err
+		}
+		now := time.Now()
+		entity.Status, entity.DecidedAt = StatusCancelled, &now
+		return svc.update(ctx, tx, entity)
+	}()
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Access request cancelled", zap.Int64("id", id))
+	return svc.findResponse(ctx, id)
+}
+
+// Метод для добавления комментария к запросу; комментировать может любой, кому запрос доступен
+func (svc *Service) Comment(ctx context.Context, request CommentRequest, admin bool) (response EventResponse, err error) {
+	svc.logger.Info("Commenting access request", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request); err != nil {
+		return EventResponse{}, err
+	}
+	c, err := svc.resolveCaller(ctx, admin)
+	if err != nil {
+		return EventResponse{}, err
+	}
+
+	entity, err := svc.find(ctx, request.Id)
+	if err != nil {
+		return EventResponse{}, err
+	}
+	if !canView(entity, c) {
+		return EventResponse{}, notFound(request.Id)
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for access request comment", zap.Int64("id", request.Id), zap.Error(err))
+		return EventResponse{}, fmt.Errorf("error comment access request: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, request.Id)
+	}()
+
+	event, err := svc.repo.AddEventTx(ctx, tx, svc.event(request.Id, ActionComment, "", request.Comment, c))
+	if err != nil {
+		svc.logger.Error("Failed to save access request comment", zap.Int64("id", request.Id), zap.Error(err))
+		return EventResponse{}, fmt.Errorf("error saving comment of access request %d: %w", request.Id, err)
+	}
+	return event.toResponse(), nil
+}
+
+// Метод для получения запроса с историей. Запрос доступен подавшему его пользователю,
+// сотруднику, для которого он подан, руководителю сотрудника, владельцу роли и администраторам
+func (svc *Service) FindById(ctx context.Context, id int64, admin bool) (Response, error) {
+	svc.logger.Debug("Finding access request by ID", zap.Int64("id", id))
+
+	c, err := svc.resolveCaller(ctx, admin)
+	if err != nil {
+		return Response{}, err
+	}
+	entity, err := svc.find(ctx, id)
+	if err != nil {
+		return Response{}, err
+	}
+	if !canView(entity, c) {
+		return Response{}, notFound(id)
+	}
+	return svc.withEvents(ctx, entity)
+}
+
+// Метод для получения запросов пользователя: поданных им и поданных для него
+func (svc *Service) FindMine(ctx context.Context, status string) ([]Response, error) {
+	svc.logger.Debug("Finding own access requests", zap.String("status", status))
+
+	c, err := svc.resolveCaller(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := svc.repo.FindMine(ctx, c.sub, c.employeeId, status)
+	if err != nil {
+		svc.logger.Error("Failed to find own access requests", zap.Error(err))
+		return nil, fmt.Errorf("error finding access requests: %w", err)
+	}
+	return toResponses(requests), nil
+}
+
+// Метод для получения всех запросов (для администраторов)
+func (svc *Service) FindAll(ctx context.Context, status string) ([]Response, error) {
+	svc.logger.Debug("Finding all access requests", zap.String("status", status))
+
+	requests, err := svc.repo.FindAll(ctx, status)
+	if err != nil {
+		svc.logger.Error("Failed to find access requests", zap.Error(err))
+		return nil, fmt.Errorf("error finding access requests: %w", err)
+	}
+	return toResponses(requests), nil
+}
+
+// Метод для получения запросов, ожидающих решения пользователя
+func (svc *Service) FindPendingApprovals(ctx context.Context, admin bool) ([]Response, error) {
+	svc.logger.Debug("Finding pending access request approvals")
+
+	c, err := svc.resolveCaller(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := svc.repo.FindPendingApprovals(ctx, c.sub, c.employeeId, c.admin)
+	if err != nil {
+		svc.logger.Error("Failed to find pending access request approvals", zap.Error(err))
+		return nil, fmt.Errorf("error finding pending approvals: %w", err)
+	}
+	return toResponses(requests), nil
+}
+
+// ExpireStale переводит в состояние expired запросы, не получившие решения в срок.
+// Вызывается периодически (см. Expirer)
+func (svc *Service) ExpireStale(ctx context.Context) (int, error) {
+	ids, err := svc.repo.ExpireStale(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring access requests: %w", err)
+	}
+	if len(ids) > 0 {
+		svc.logger.Info("Access requests expired", zap.Int64s("ids", ids))
+	}
+	return len(ids), nil
+}
+
+// Метод для получения настроек согласования ролей
+func (svc *Service) FindPolicies(ctx context.Context) ([]PolicyResponse, error) {
+	svc.logger.Debug("Finding access request policies")
+
+	policies, err := svc.repo.FindPolicies(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to find access request policies", zap.Error(err))
+		return nil, fmt.Errorf("error finding access request policies: %w", err)
+	}
+	responses := make([]PolicyResponse, len(policies))
+	for i, policy := range policies {
+		responses[i] = policy.toResponse()
+	}
+	return responses, nil
+}
+
+// Метод для задания шагов согласования роли; поданные запросы сохраняют прежние шаги
+func (svc *Service) SetPolicy(ctx context.Context, request PolicyRequest) (response PolicyResponse, err error) {
+	svc.logger.Info("Setting access request policy",
+		zap.Int64("role_id", request.RoleId),
+		zap.Strings("steps", request.Steps))
+
+	if err := svc.validateRequest(request); err != nil {
+		return PolicyResponse{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for access request policy", zap.Int64("role_id", request.RoleId), zap.Error(err))
+		return PolicyResponse{}, fmt.Errorf("error set access request policy: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, request.RoleId)
+	}()
+
+	isExist, err := svc.repo.RoleExistsTx(ctx, tx, request.RoleId)
+	if err != nil {
+		svc.logger.Error("Failed to check role existence", zap.Int64("role_id", request.RoleId), zap.Error(err))
+		return PolicyResponse{}, fmt.Errorf("error finding role with id %d: %w", request.RoleId, err)
+	}
+	if !isExist {
+		return PolicyResponse{}, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", request.RoleId)}
+	}
+
+	policy, err := svc.repo.SavePolicyTx(ctx, tx, request.RoleId, request.Steps)
+	if err != nil {
+		svc.logger.Error("Failed to save access request policy", zap.Int64("role_id", request.RoleId), zap.Error(err))
+		return PolicyResponse{}, fmt.Errorf("error saving access request policy of role %d: %w", request.RoleId, err)
+	}
+
+	svc.logger.Info("Access request policy set", zap.Int64("role_id", request.RoleId))
+	return policy.toResponse(), nil
+}
+
+// Метод для удаления настройки согласования роли: запросы роли снова проходят шаги по умолчанию
+func (svc *Service) DeletePolicy(ctx context.Context, roleId int64) error {
+	svc.logger.Info("Deleting access request policy", zap.Int64("role_id", roleId))
+
+	deleted, err := svc.repo.DeletePolicy(ctx, roleId)
+	if err != nil {
+		svc.logger.Error("Failed to delete access request policy", zap.Int64("role_id", roleId), zap.Error(err))
+		return fmt.Errorf("error deleting access request policy of role %d: %w", roleId, err)
+	}
+	if !deleted {
+		return common.NotFoundError{Message: fmt.Sprintf("access request policy of role %d not found", roleId)}
+	}
+	return nil
+}
+
+// определяет пользователя по инициатору запроса; сотрудник пользователя ищется по адресу почты
+func (svc *Service) resolveCaller(ctx context.Context, admin bool) (caller, error) {
+	actor := common.ActorFromContext(ctx)
+	c := caller{sub: actor.Subject, username: actor.Username, admin: admin}
+	if actor.Email == "" {
+		return c, nil
+	}
+
+	id, err := svc.repo.FindEmployeeIdByEmail(ctx, actor.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, nil
+	}
+	if err != nil {
+		svc.logger.Error("Failed to find employee of user", zap.String("username", actor.Username), zap.Error(err))
+		return caller{}, fmt.Errorf("error finding employee of user %s: %w", actor.Username, err)
+	}
+	c.employeeId = id
+	return c, nil
+}
+
+// запрос доступен подавшему его, сотруднику, для которого он подан, руководителю сотрудника,
+// владельцу роли и администраторам
+func canView(entity Entity, c caller) bool {
+	if c.admin || (c.sub != "" && valueOf(entity.CreatedBySub) == c.sub) {
+		return true
+	}
+	if c.employeeId == 0 {
+		return false
+	}
+	return c.employeeId == entity.EmployeeId || (entity.ManagerId != nil && *entity.ManagerId == c.employeeId) ||
+		(entity.RoleOwnerId != nil && *entity.RoleOwnerId == c.employeeId)
+}
+
+// проверяет, может ли пользователь принять решение по текущему шагу запроса.
+// Шаг manager решает руководитель сотрудника, шаг role_owner - владелец роли, шаг admin - администраторы;
+// администратор может решить любой шаг. Решение по своему запросу или запросу для себя не принимается
+func checkDecision(entity Entity, c caller) error {
+	if entity.Status != StatusPending {
+		return alreadyDecided(entity)
+	}
+	if (c.sub != "" && valueOf(entity.CreatedBySub) == c.sub) || (c.employeeId != 0 && c.employeeId == entity.EmployeeId) {
+		return common.ForbiddenError{Message: "you cannot decide on your own access request"}
+	}
+
+	step := entity.currentStepType()
+	isManager := c.employeeId != 0 && entity.ManagerId != nil && *entity.ManagerId == c.employeeId
+	isOwner := c.employeeId != 0 && entity.RoleOwnerId != nil && *entity.RoleOwnerId == c.employeeId
+	if c.admin || (step == StepManager && isManager) || (step == StepRoleOwner && isOwner) {
+		return nil
+	}
+	return common.ForbiddenError{
+		Message: fmt.Sprintf("access request %d awaits approval of step %s", entity.Id, step),
+	}
+}
+
+func (svc *Service) find(ctx context.Context, id int64) (Entity, error) {
+	entity, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Entity{}, notFound(id)
+		}
+		svc.logger.Error("Failed to find access request", zap.Int64("id", id), zap.Error(err))
+		return Entity{}, fmt.Errorf("error finding access request with id %d: %w", id, err)
+	}
+	return entity, nil
+}
+
+// находит запрос и блокирует его строку; NotFoundError, если запроса нет
+func (svc *Service) findForUpdate(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Entity{}, notFound(id)
+		}
+		svc.logger.Error("Failed to find access request", zap.Int64("id", id), zap.Error(err))
+		return Entity{}, fmt.Errorf("error finding access request with id %d: %w", id, err)
+	}
+	return entity, nil
+}
+
+// запрос с историей после изменения
+func (svc *Service) findResponse(ctx context.Context, id int64) (Response, error) {
+	entity, err := svc.find(ctx, id)
+	if err != nil {
+		return Response{}, err
+	}
+	return svc.withEvents(ctx, entity)
+}
+
+func (svc *Service) withEvents(ctx context.Context, entity Entity) (Response, error) {
+	events, err := svc.repo.FindEvents(ctx, entity.Id)
+	if err != nil {
+		svc.logger.Error("Failed to find access request events", zap.Int64("id", entity.Id), zap.Error(err))
+		return Response{}, fmt.Errorf("error finding history of access request %d: %w", entity.Id, err)
+	}
+	response := entity.toResponse()
+	response.Events = make([]EventResponse, len(events))
+	for i, event := range events {
+		response.Events[i] = event.toResponse()
+	}
+	return response, nil
+}
+
+func (svc *Service) update(ctx context.Context, tx *sqlx.Tx, entity Entity) error {
+	if err := svc.repo.UpdateTx(ctx, tx, entity); err != nil {
+		svc.logger.Error("Failed to update access request", zap.Int64("id", entity.Id), zap.Error(err))
+		return fmt.Errorf("error updating access request with id %d: %w", entity.Id, err)
+	}
+	return nil
+}
+
+func (svc *Service) addEvent(ctx context.Context, tx *sqlx.Tx, id int64, action, step, comment string, c caller) error {
+	if _, err := svc.repo.AddEventTx(ctx, tx, svc.event(id, action, step, comment, c)); err != nil {
+		svc.logger.Error("Failed to save access request event",
+			zap.Int64("id", id),
+			zap.String("action", action),
+			zap.Error(err))
+		return fmt.Errorf("error saving history of access request %d: %w", id, err)
+	}
+	return nil
+}
+
+func (svc *Service) event(id int64, action, step, comment string, c caller) EventEntity {
+	event := EventEntity{
+		RequestId:     id,
+		Action:        action,
+		Step:          nullable(step),
+		Comment:       nullable(comment),
+		ActorSub:      nullable(c.sub),
+		ActorUsername: nullable(c.username),
+	}
+	if c.employeeId != 0 {
+		event.ActorEmployeeId = &c.employeeId
+	}
+	return event
+}
+
+// валидация запросов сервиса
+func (svc *Service) validateRequest(request any) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Access request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return nil
+}
+
+func (svc *Service) finishTransaction(tx *sqlx.Tx, err error, id int64) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			svc.logger.Error("Failed to rollback transaction",
+				zap.Int64("id", id),
+				zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		svc.logger.Error("Failed to commit transaction",
+			zap.Int64("id", id),
+			zap.Error(commitErr))
+		return commitErr
+	}
+	return nil
+}
+
+func toResponses(requests []Entity) []Response {
+	responses := make([]Response, len(requests))
+	for i, request := range requests {
+		responses[i] = request.toResponse()
+	}
+	return responses
+}
+
+func notFound(id int64) error {
+	return common.NotFoundError{Message: fmt.Sprintf("access request with id %d not found", id)}
+}
+
+func alreadyDecided(entity Entity) error {
+	return common.ConflictError{Message: fmt.Sprintf("access request %d is already %s", entity.Id, entity.Status)}
+}
+
+func nullable(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
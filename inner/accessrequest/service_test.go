@@ -0,0 +1,730 @@
+package accessrequest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/validator"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// мок репозитория; транзакции открываются в sqlmock, ожидания Begin/Commit/Rollback задаются в тесте
+type MockRepo struct {
+	mock.Mock
+	db *sqlx.DB
+}
+
+func (m *MockRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	return m.db.Beginx()
+}
+
+func (m *MockRepo) FindEmployeeIdByEmail(ctx context.Context, email string) (int64, error) {
+	args := m.Called(email)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindEmployeeTx(ctx context.Context, tx *sqlx.Tx, id int64) (employeeEntity, error) {
+	args := m.Called(id)
+	return args.Get(0).(employeeEntity), args.Error(1)
+}
+
+func (m *MockRepo) RoleExistsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (bool, error) {
+	args := m.Called(roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) HasActiveAssignmentTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) (bool, error) {
+	args := m.Called(employeeId, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindPolicyStepsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (pq.StringArray, error) {
+	args := m.Called(roleId)
+	return args.Get(0).(pq.StringArray), args.Error(1)
+}
+
+func (m *MockRepo) CreateTx(ctx context.Context, tx *sqlx.Tx, request Entity) (int64, error) {
+	args := m.Called(request)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, request Entity) error {
+	return m.Called(request).Error(0)
+}
+
+func (m *MockRepo) AddEventTx(ctx context.Context, tx *sqlx.Tx, event EventEntity) (EventEntity, error) {
+	args := m.Called(event)
+	return args.Get(0).(EventEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindEvents(ctx context.Context, requestId int64) ([]EventEntity, error) {
+	args := m.Called(requestId)
+	return args.Get(0).([]EventEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindMine(ctx context.Context, sub string, employeeId int64, status string) ([]Entity, error) {
+	args := m.Called(sub, employeeId, status)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context, status string) ([]Entity, error) {
+	args := m.Called(status)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindPendingApprovals(ctx context.Context, sub string, employeeId int64, admin bool) ([]Entity, error) {
+	args := m.Called(sub, employeeId, admin)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) ExpireStale(ctx context.Context) ([]int64, error) {
+	args := m.Called()
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) FindPolicies(ctx context.Context) ([]PolicyEntity, error) {
+	args := m.Called()
+	return args.Get(0).([]PolicyEntity), args.Error(1)
+}
+
+func (m *MockRepo) SavePolicyTx(ctx context.Context, tx *sqlx.Tx, roleId int64, steps []string) (PolicyEntity, error) {
+	args := m.Called(roleId, steps)
+	return args.Get(0).(PolicyEntity), args.Error(1)
+}
+
+func (m *MockRepo) DeletePolicy(ctx context.Context, roleId int64) (bool, error) {
+	args := m.Called(roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockAssigner struct {
+	mock.Mock
+}
+
+func (m *MockAssigner) AssignRole(ctx context.Context, request employee.AssignRoleRequest) (employee.RoleAssignmentResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(employee.RoleAssignmentResponse), args.Error(1)
+}
+
+func createTestLogger() *common.Logger {
+	cfg := common.Config{
+		DbDriverName:   "postgres",
+		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
+		AppName:        "test_app",
+		AppVersion:     "1.0.0",
+		LogLevel:       "DEBUG",
+		LogDevelopMode: true,
+	}
+	return common.NewLogger(cfg)
+}
+
+func newTestService(t *testing.T) (*Service, *MockRepo, *MockAssigner, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	repo := &MockRepo{db: sqlx.NewDb(db, "postgres")}
+	assigner := new(MockAssigner)
+	return NewService(repo, assigner, validator.New(), createTestLogger()), repo, assigner, sqlMock
+}
+
+// контекст пользователя; сотрудник пользователя находится по адресу почты
+func actorContext(sub, email string) context.Context {
+	return common.WithActor(context.Background(), common.Actor{Subject: sub, Username: sub, Email: email})
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// ожидает сохранения события с указанным действием
+func expectEvent(repo *MockRepo, action, step string) {
+	repo.On("AddEventTx", mock.MatchedBy(func(event EventEntity) bool {
+		return event.Action == action && valueOf(event.Step) == step
+	})).Return(EventEntity{Action: action}, nil).Once()
+}
+
+// ожидает чтения запроса после изменения
+func expectResponse(repo *MockRepo, entity Entity) {
+	repo.On("FindById", entity.Id).Return(entity, nil)
+	repo.On("FindEvents", entity.Id).Return([]EventEntity{}, nil)
+}
+
+func TestService_Submit(t *testing.T) {
+	t.Run("Employee requests role for themselves with default steps", func(t *testing.T) {
+		svc, repo, assigner, sqlMock := newTestService(t)
+		ctx := actorContext("sub-7", "john@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeIdByEmail", "john@example.com").Return(int64(7), nil)
+		repo.On("FindEmployeeTx", int64(7)).Return(employeeEntity{Id: 7, Status: employee.StatusActive, ManagerId: ptr(int64(2))}, nil)
+		repo.On("RoleExistsTx", int64(3)).Return(true, nil)
+		repo.On("HasActiveAssignmentTx", int64(7), int64(3)).Return(false, nil)
+		repo.On("FindPolicyStepsTx", int64(3)).Return(pq.StringArray(nil), sql.ErrNoRows)
+		repo.On("CreateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.EmployeeId == 7 && entity.RoleId == 3 && entity.CurrentStep == 0 &&
+				assert.ObjectsAreEqual(pq.StringArray{StepManager, StepAdmin}, entity.Steps) &&
+				*entity.RequesterEmployeeId == 7 && *entity.CreatedBySub == "sub-7" && !entity.ExpiresAt.IsZero()
+		})).Return(int64(10), nil)
+		expectEvent(repo, ActionSubmit, "")
+		expectResponse(repo, Entity{Id: 10, EmployeeId: 7, Steps: pq.StringArray{StepManager, StepAdmin}, Status: StatusPending})
+
+		response, err := svc.Submit(ctx, CreateRequest{RoleId: 3, Justification: "Need access"}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), response.Id)
+		assert.Equal(t, StepManager, response.CurrentStepType)
+		assigner.AssertNotCalled(t, "AssignRole", mock.Anything)
+		repo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Request of the manager skips the manager steps", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindEmployeeTx", int64(7)).Return(employeeEntity{Id: 7, Status: employee.StatusActive, ManagerId: ptr(int64(2))}, nil)
+		repo.On("RoleExistsTx", int64(3)).Return(true, nil)
+		repo.On("HasActiveAssignmentTx", int64(7), int64(3)).Return(false, nil)
+		repo.On("FindPolicyStepsTx", int64(3)).Return(pq.StringArray{StepManager, StepRoleOwner}, nil)
+		repo.On("CreateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.EmployeeId == 7 && entity.CurrentStep == 1
+		})).Return(int64(10), nil)
+		expectEvent(repo, ActionSubmit, "")
+		expectEvent(repo, ActionApprove, StepManager)
+		expectResponse(repo, Entity{Id: 10, EmployeeId: 7, Steps: pq.StringArray{StepManager, StepRoleOwner}, CurrentStep: 1, Status: StatusPending})
+
+		response, err := svc.Submit(ctx, CreateRequest{EmployeeId: 7, RoleId: 3, Justification: "Needs access"}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, StepRoleOwner, response.CurrentStepType)
+		repo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Request approved at submission assigns the role", func(t *testing.T) {
+		svc, repo, assigner, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+		pending := Entity{Id: 10, EmployeeId: 7, RoleId: 3, Steps: pq.StringArray{StepManager}, CurrentStep: 1, Status: StatusPending}
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindEmployeeTx", int64(7)).Return(employeeEntity{Id: 7, Status: employee.StatusActive, ManagerId: ptr(int64(2))}, nil)
+		repo.On("RoleExistsTx", int64(3)).Return(true, nil)
+		repo.On("HasActiveAssignmentTx", int64(7), int64(3)).Return(false, nil)
+		repo.On("FindPolicyStepsTx", int64(3)).Return(pq.StringArray{StepManager}, nil)
+		repo.On("CreateTx", mock.Anything).Return(int64(10), nil)
+		expectEvent(repo, ActionSubmit, "")
+		expectEvent(repo, ActionApprove, StepManager)
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(pending, nil)
+		assigner.On("AssignRole", employee.AssignRoleRequest{EmployeeId: 7, RoleId: 3}).
+			Return(employee.RoleAssignmentResponse{Id: 55}, nil)
+		repo.On("UpdateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.Status == StatusApproved && *entity.AssignmentId == 55 && entity.DecidedAt != nil
+		})).Return(nil)
+		expectResponse(repo, Entity{Id: 10, Status: StatusApproved, AssignmentId: ptr(int64(55))})
+
+		response, err := svc.Submit(ctx, CreateRequest{EmployeeId: 7, RoleId: 3, Justification: "Needs access"}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusApproved, response.Status)
+		repo.AssertExpectations(t)
+		assigner.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Role cannot be requested for an employee who is not a report", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-5", "peer@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "peer@example.com").Return(int64(5), nil)
+		repo.On("FindEmployeeTx", int64(7)).Return(employeeEntity{Id: 7, Status: employee.StatusActive, ManagerId: ptr(int64(2))}, nil)
+
+		_, err := svc.Submit(ctx, CreateRequest{EmployeeId: 7, RoleId: 3, Justification: "Needs access"}, false)
+
+		assert.True(t, errors.As(err, &common.ForbiddenError{}))
+		repo.AssertNotCalled(t, "CreateTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Administrator requests role for any employee", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-admin", "")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeTx", int64(7)).Return(employeeEntity{Id: 7, Status: employee.StatusActive}, nil)
+		repo.On("RoleExistsTx", int64(3)).Return(true, nil)
+		repo.On("HasActiveAssignmentTx", int64(7), int64(3)).Return(false, nil)
+		repo.On("FindPolicyStepsTx", int64(3)).Return(pq.StringArray{StepAdmin}, nil)
+		repo.On("CreateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.RequesterEmployeeId == nil && entity.CurrentStep == 0
+		})).Return(int64(10), nil)
+		expectEvent(repo, ActionSubmit, "")
+		expectResponse(repo, Entity{Id: 10, Status: StatusPending})
+
+		_, err := svc.Submit(ctx, CreateRequest{EmployeeId: 7, RoleId: 3, Justification: "Needs access"}, true)
+
+		require.NoError(t, err)
+		repo.AssertNotCalled(t, "FindEmployeeIdByEmail", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("User without employee must specify employee_id", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-x", "nobody@example.com")
+		repo.On("FindEmployeeIdByEmail", "nobody@example.com").Return(int64(0), sql.ErrNoRows)
+
+		_, err := svc.Submit(ctx, CreateRequest{RoleId: 3, Justification: "Need access"}, false)
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Already assigned role is not requested", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-7", "john@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "john@example.com").Return(int64(7), nil)
+		repo.On("FindEmployeeTx", int64(7)).Return(employeeEntity{Id: 7, Status: employee.StatusActive}, nil)
+		repo.On("RoleExistsTx", int64(3)).Return(true, nil)
+		repo.On("HasActiveAssignmentTx", int64(7), int64(3)).Return(true, nil)
+
+		_, err := svc.Submit(ctx, CreateRequest{RoleId: 3, Justification: "Need access"}, false)
+
+		assert.True(t, errors.As(err, &common.ConflictError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Duplicate pending request is a conflict", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-7", "john@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "john@example.com").Return(int64(7), nil)
+		repo.On("FindEmployeeTx", int64(7)).Return(employeeEntity{Id: 7, Status: employee.StatusActive}, nil)
+		repo.On("RoleExistsTx", int64(3)).Return(true, nil)
+		repo.On("HasActiveAssignmentTx", int64(7), int64(3)).Return(false, nil)
+		repo.On("FindPolicyStepsTx", int64(3)).Return(pq.StringArray(nil), sql.ErrNoRows)
+		repo.On("CreateTx", mock.Anything).Return(int64(0), sql.ErrNoRows)
+
+		_, err := svc.Submit(ctx, CreateRequest{RoleId: 3, Justification: "Need access"}, false)
+
+		assert.True(t, errors.As(err, &common.ConflictError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Justification is required", func(t *testing.T) {
+		svc, _, _, sqlMock := newTestService(t)
+
+		_, err := svc.Submit(actorContext("sub-7", ""), CreateRequest{RoleId: 3}, false)
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Approve(t *testing.T) {
+	pending := func(step int, steps ...string) Entity {
+		return Entity{
+			Id:           10,
+			EmployeeId:   7,
+			ManagerId:    ptr(int64(2)),
+			RoleId:       3,
+			Steps:        steps,
+			CurrentStep:  step,
+			Status:       StatusPending,
+			CreatedBySub: ptr("sub-7"),
+		}
+	}
+
+	t.Run("Manager approves the manager step", func(t *testing.T) {
+		svc, repo, assigner, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(pending(0, StepManager, StepAdmin), nil)
+		expectEvent(repo, ActionApprove, StepManager)
+		repo.On("UpdateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.CurrentStep == 1 && entity.Status == StatusPending && !entity.ExpiresAt.IsZero()
+		})).Return(nil)
+		expectResponse(repo, pending(1, StepManager, StepAdmin))
+
+		response, err := svc.Approve(ctx, DecisionRequest{Id: 10}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, StepAdmin, response.CurrentStepType)
+		assigner.AssertNotCalled(t, "AssignRole", mock.Anything)
+		repo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Role owner approves the role_owner step", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-5", "owner@example.com")
+		owned := func(step int) Entity {
+			entity := pending(step, StepRoleOwner, StepAdmin)
+			entity.RoleOwnerId = ptr(int64(5))
+			return entity
+		}
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeIdByEmail", "owner@example.com").Return(int64(5), nil)
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(owned(0), nil)
+		expectEvent(repo, ActionApprove, StepRoleOwner)
+		repo.On("UpdateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.CurrentStep == 1 && entity.Status == StatusPending
+		})).Return(nil)
+		expectResponse(repo, owned(1))
+
+		response, err := svc.Approve(ctx, DecisionRequest{Id: 10}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, StepAdmin, response.CurrentStepType)
+		repo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Manager cannot approve the role_owner step", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+		entity := pending(0, StepRoleOwner)
+		entity.RoleOwnerId = ptr(int64(5))
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(entity, nil)
+
+		_, err := svc.Approve(ctx, DecisionRequest{Id: 10}, false)
+
+		assert.True(t, errors.As(err, &common.ForbiddenError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Role owner cannot approve own request at the role_owner step", func(t *testing.T) {
+		for name, entity := range map[string]Entity{
+			// владелец подал запрос для своего подчинённого
+			"Submitted by the owner": {
+				Id: 10, EmployeeId: 7, RoleId: 3, Steps: []string{StepRoleOwner}, Status: StatusPending,
+				CreatedBySub: ptr("sub-5"), RoleOwnerId: ptr(int64(5)),
+			},
+			// владелец запросил роль для себя
+			"Requested for the owner": {
+				Id: 10, EmployeeId: 5, RoleId: 3, Steps: []string{StepRoleOwner}, Status: StatusPending,
+				CreatedBySub: ptr("sub-5"), RoleOwnerId: ptr(int64(5)),
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				svc, repo, _, sqlMock := newTestService(t)
+				ctx := actorContext("sub-5", "owner@example.com")
+
+				sqlMock.ExpectBegin()
+				sqlMock.ExpectRollback()
+				repo.On("FindEmployeeIdByEmail", "owner@example.com").Return(int64(5), nil)
+				repo.On("FindByIdForUpdateTx", int64(10)).Return(entity, nil)
+
+				_, err := svc.Approve(ctx, DecisionRequest{Id: 10}, false)
+
+				assert.True(t, errors.As(err, &common.ForbiddenError{}))
+				repo.AssertNotCalled(t, "AddEventTx", mock.Anything)
+				assert.NoError(t, sqlMock.ExpectationsWereMet())
+			})
+		}
+	})
+
+	t.Run("Last approval assigns the role", func(t *testing.T) {
+		svc, repo, assigner, sqlMock := newTestService(t)
+		ctx := actorContext("sub-admin", "")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(pending(1, StepManager, StepAdmin), nil)
+		expectEvent(repo, ActionApprove, StepAdmin)
+		assigner.On("AssignRole", employee.AssignRoleRequest{EmployeeId: 7, RoleId: 3}).
+			Return(employee.RoleAssignmentResponse{Id: 55}, nil)
+		repo.On("UpdateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.CurrentStep == 2 && entity.Status == StatusApproved && *entity.AssignmentId == 55
+		})).Return(nil)
+		expectResponse(repo, Entity{Id: 10, Status: StatusApproved})
+
+		response, err := svc.Approve(ctx, DecisionRequest{Id: 10, Comment: "ok"}, true)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusApproved, response.Status)
+		repo.AssertExpectations(t)
+		assigner.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed assignment keeps the request pending", func(t *testing.T) {
+		svc, repo, assigner, sqlMock := newTestService(t)
+		ctx := actorContext("sub-admin", "")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(pending(0, StepAdmin), nil)
+		expectEvent(repo, ActionApprove, StepAdmin)
+		assigner.On("AssignRole", mock.Anything).
+			Return(employee.RoleAssignmentResponse{}, common.ConflictError{Message: "overlapping assignment"})
+
+		_, err := svc.Approve(ctx, DecisionRequest{Id: 10}, true)
+
+		assert.True(t, errors.As(err, &common.ConflictError{}))
+		repo.AssertNotCalled(t, "UpdateTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Administrator cannot approve own request", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-7", "")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(pending(1, StepManager, StepAdmin), nil)
+
+		_, err := svc.Approve(ctx, DecisionRequest{Id: 10}, true)
+
+		assert.True(t, errors.As(err, &common.ForbiddenError{}))
+		repo.AssertNotCalled(t, "AddEventTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Manager cannot approve the admin step", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(pending(1, StepManager, StepAdmin), nil)
+
+		_, err := svc.Approve(ctx, DecisionRequest{Id: 10}, false)
+
+		assert.True(t, errors.As(err, &common.ForbiddenError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Decided request cannot be approved", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		rejected := pending(0, StepAdmin)
+		rejected.Status = StatusRejected
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(rejected, nil)
+
+		_, err := svc.Approve(actorContext("sub-admin", ""), DecisionRequest{Id: 10}, true)
+
+		assert.True(t, errors.As(err, &common.ConflictError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unrelated user does not see the request", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-5", "peer@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "peer@example.com").Return(int64(5), nil)
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(pending(0, StepManager), nil)
+
+		_, err := svc.Approve(ctx, DecisionRequest{Id: 10}, false)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Reject(t *testing.T) {
+	t.Run("Manager rejects with a reason", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(Entity{
+			Id: 10, EmployeeId: 7, ManagerId: ptr(int64(2)), Steps: pq.StringArray{StepManager}, Status: StatusPending,
+		}, nil)
+		expectEvent(repo, ActionReject, StepManager)
+		repo.On("UpdateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.Status == StatusRejected && entity.DecidedAt != nil && entity.CurrentStep == 0
+		})).Return(nil)
+		expectResponse(repo, Entity{Id: 10, Status: StatusRejected})
+
+		response, err := svc.Reject(ctx, CommentRequest{Id: 10, Comment: "Not needed"}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusRejected, response.Status)
+		repo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Reason is required", func(t *testing.T) {
+		svc, _, _, sqlMock := newTestService(t)
+
+		_, err := svc.Reject(actorContext("sub-admin", ""), CommentRequest{Id: 10}, true)
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Cancel(t *testing.T) {
+	request := Entity{
+		Id: 10, EmployeeId: 7, ManagerId: ptr(int64(2)), Steps: pq.StringArray{StepManager},
+		Status: StatusPending, CreatedBySub: ptr("sub-7"),
+	}
+
+	t.Run("Requester cancels the request", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(request, nil)
+		expectEvent(repo, ActionCancel, StepManager)
+		repo.On("UpdateTx", mock.MatchedBy(func(entity Entity) bool {
+			return entity.Status == StatusCancelled
+		})).Return(nil)
+		expectResponse(repo, Entity{Id: 10, Status: StatusCancelled})
+
+		response, err := svc.Cancel(actorContext("sub-7", ""), 10, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusCancelled, response.Status)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Manager cannot cancel the request", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindByIdForUpdateTx", int64(10)).Return(request, nil)
+
+		_, err := svc.Cancel(ctx, 10, false)
+
+		assert.True(t, errors.As(err, &common.ForbiddenError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Comment(t *testing.T) {
+	t.Run("Manager comments the request", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindById", int64(10)).Return(Entity{Id: 10, EmployeeId: 7, ManagerId: ptr(int64(2))}, nil)
+		repo.On("AddEventTx", EventEntity{
+			RequestId:       10,
+			Action:          ActionComment,
+			Comment:         ptr("Which period?"),
+			ActorEmployeeId: ptr(int64(2)),
+			ActorSub:        ptr("sub-2"),
+			ActorUsername:   ptr("sub-2"),
+		}).Return(EventEntity{Id: 1, Action: ActionComment, Comment: ptr("Which period?")}, nil)
+
+		response, err := svc.Comment(ctx, CommentRequest{Id: 10, Comment: "Which period?"}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, ActionComment, response.Action)
+		repo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unrelated user cannot comment", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		repo.On("FindById", int64(10)).Return(Entity{Id: 10, EmployeeId: 7, CreatedBySub: ptr("sub-7")}, nil)
+
+		_, err := svc.Comment(actorContext("sub-5", ""), CommentRequest{Id: 10, Comment: "Hello"}, false)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Policies(t *testing.T) {
+	t.Run("Policy of a missing role is not saved", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("RoleExistsTx", int64(3)).Return(false, nil)
+
+		_, err := svc.SetPolicy(context.Background(), PolicyRequest{RoleId: 3, Steps: []string{StepAdmin}})
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+		repo.AssertNotCalled(t, "SavePolicyTx", mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown step is rejected", func(t *testing.T) {
+		svc, _, _, sqlMock := newTestService(t)
+
+		_, err := svc.SetPolicy(context.Background(), PolicyRequest{RoleId: 3, Steps: []string{"director"}})
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Missing policy is not deleted", func(t *testing.T) {
+		svc, repo, _, _ := newTestService(t)
+		repo.On("DeletePolicy", int64(3)).Return(false, nil)
+
+		err := svc.DeletePolicy(context.Background(), 3)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+	})
+}
+
+func TestService_ExpireStale(t *testing.T) {
+	svc, repo, _, _ := newTestService(t)
+	repo.On("ExpireStale").Return([]int64{4, 9}, nil)
+
+	expired, err := svc.ExpireStale(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, expired)
+}
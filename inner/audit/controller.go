@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server       *web.Server
+	auditService Svc
+	logger       *common.Logger
+}
+
+// интерфейс сервиса audit.Service
+type Svc interface {
+	FindWithFilter(ctx context.Context, request FilterRequest) (PageResponse, error)
+}
+
+func NewController(server *web.Server, auditService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:       server,
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// функция для регистрации маршрутов
+func (c *Controller) RegisterRoutes() {
+	c.logger.Info("Registering audit routes")
+	// полный маршрут получится "/api/v1/admin/audit"
+	// журнал доступен только администраторам
+	c.server.GroupApiV1Admin.Get("/audit", c.FindAuditEvents)
+	c.logger.Info("Audit routes registered successfully")
+}
+
+// FindAuditEvents получает журнал изменений с фильтрами и пагинацией
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get audit events
+//	@Description	Obtaining the change log of employees and roles, newest first
+//	@Tags			audit
+//	@Produce		json
+//	@Param			actor		query		string					false	"JWT sub or preferred username of the actor"
+//	@Param			entityType	query		string					false	"Entity type"				Enums(employee, role, department, position, sod_rule, sod_exception)
+//	@Param			entityId	query		int						false	"Entity ID"
+//	@Param			from		query		string					false	"Start of the period (RFC 3339)"	example("2025-06-01T00:00:00Z")
+//	@Param			to			query		string					false	"End of the period, exclusive (RFC 3339)"
+//	@Param			pageNumber	query		int						false	"Page number"				default(1)
+//	@Param			pageSize	query		int						false	"Number of items on page"	default(20)
+//	@Success		200			{object}	AuditPageResponse		"Audit events with pagination"
+//	@Failure		400			{object}	common.Response[any]	"Invalid filter"
+//	@Failure		500			{object}	common.Response[any]	"Error when getting audit events"
+//	@Router			/admin/audit [get]
+func (c *Controller) FindAuditEvents(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received audit events request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("query", ctx.OriginalURL()),
+		zap.String("ip", ctx.IP()))
+
+	request, err := parseFilterRequest(ctx)
+	if err != nil {
+		c.logger.Warn("Invalid audit filter",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	pageResponse, err := c.auditService.FindWithFilter(ctx.UserContext(), request)
+	if err != nil {
+		if errors.As(err, &common.RequestValidationError{}) {
+			var validationErr common.RequestValidationError
+			errors.As(err, &validationErr)
+
+			if validationErr.Data != nil {
+				return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+			}
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+		}
+		c.logger.Error("Failed to find audit events",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when getting audit events")
+	}
+
+	return common.OkResponse(ctx, pageResponse)
+}
+
+// разбирает фильтры журнала из query string
+func parseFilterRequest(ctx *fiber.Ctx) (FilterRequest, error) {
+	request := FilterRequest{
+		Actor:      ctx.Query("actor"),
+		EntityType: ctx.Query("entityType"),
+	}
+
+	var err error
+	if request.PageNumber, err = strconv.Atoi(ctx.Query("pageNumber", "1")); err != nil {
+		return FilterRequest{}, errors.New("invalid pageNumber parameter")
+	}
+	if request.PageSize, err = strconv.Atoi(ctx.Query("pageSize", "20")); err != nil {
+		return FilterRequest{}, errors.New("invalid pageSize parameter")
+	}
+	if entityId := ctx.Query("entityId"); entityId != "" {
+		if request.EntityId, err = strconv.ParseInt(entityId, 10, 64); err != nil {
+			return FilterRequest{}, errors.New("invalid entityId parameter")
+		}
+	}
+	if request.From, err = parseTimeParam(ctx, "from"); err != nil {
+		return FilterRequest{}, err
+	}
+	if request.To, err = parseTimeParam(ctx, "to"); err != nil {
+		return FilterRequest{}, err
+	}
+	return request, nil
+}
+
+func parseTimeParam(ctx *fiber.Ctx, name string) (*time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.New("invalid " + name + " parameter: expected RFC 3339 time")
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock для сервиса
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) FindWithFilter(ctx context.Context, request FilterRequest) (PageResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(PageResponse), args.Error(1)
+}
+
+// Вспомогательные функции для создания Fiber app
+func setupTestApp() (*fiber.App, *MockService) {
+	app := fiber.New()
+	mockService := &MockService{}
+
+	server := &web.Server{
+		GroupApiV1Admin: app.Group("/api/v1/admin"),
+	}
+
+	controller := NewController(server, mockService, createTestLogger())
+	controller.RegisterRoutes()
+
+	return app, mockService
+}
+
+func TestController_FindAuditEvents(t *testing.T) {
+	t.Run("Parses filters", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+		mockService.On("FindWithFilter", FilterRequest{
+			Actor:      "admin",
+			EntityType: EntityRole,
+			EntityId:   3,
+			From:       &from,
+			To:         &to,
+			PageNumber: 1,
+			PageSize:   20,
+		}).Return(PageResponse{Data: []Response{{Id: 1, Action: ActionDelete}}, PageNumber: 1, PageSize: 20, TotalCount: 1, TotalPages: 1}, nil)
+
+		req := httptest.NewRequest("GET",
+			"/api/v1/admin/audit?actor=admin&entityType=role&entityId=3&from=2025-06-01T00:00:00Z&to=2025-07-01T00:00:00Z", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var response common.Response[PageResponse]
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		assert.NoError(t, err)
+		assert.Len(t, response.Data.Data, 1)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid time", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		req := httptest.NewRequest("GET", "/api/v1/admin/audit?from=yesterday", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "FindWithFilter", mock.Anything)
+	})
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// действия, фиксируемые в журнале
+const (
+	ActionCreate     = "create"
+	ActionUpdate     = "update"
+	ActionDelete     = "delete"
+	ActionAssignRole = "assign_role"
+	ActionRevokeRole = "revoke_role"
+	ActionRestore    = "restore"
+	ActionPurge      = "purge"
+	ActionTransition = "transition"
+)
+
+// типы сущностей журнала
+const (
+	EntityEmployee   = "employee"
+	EntityRole       = "role"
+	EntityDepartment = "department"
+	EntityPosition   = "position"
+	// правило разделения обязанностей и исключение из него
+	EntitySodRule      = "sod_rule"
+	EntitySodException = "sod_exception"
+)
+
+type Entity struct {
+	Id            int64     `db:"id"`
+	ActorSub      *string   `db:"actor_sub"`
+	ActorUsername *string   `db:"actor_username"`
+	Action        string    `db:"action"`
+	EntityType    string    `db:"entity_type"`
+	EntityId      *int64    `db:"entity_id"`
+	Before        *string   `db:"before"`
+	After         *string   `db:"after"`
+	RequestId     *string   `db:"request_id"`
+	Ip            *string   `db:"ip"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:            e.Id,
+		ActorSub:      e.ActorSub,
+		ActorUsername: e.ActorUsername,
+		Action:        e.Action,
+		EntityType:    e.EntityType,
+		EntityId:      e.EntityId,
+		Before:        rawJson(e.Before),
+		After:         rawJson(e.After),
+		RequestId:     e.RequestId,
+		Ip:            e.Ip,
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+func rawJson(value *string) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	return json.RawMessage(*value)
+}
+
+type Response struct {
+	Id            int64           `json:"id"`
+	ActorSub      *string         `json:"actor_sub"`
+	ActorUsername *string         `json:"actor_username"`
+	Action        string          `json:"action"`
+	EntityType    string          `json:"entity_type"`
+	EntityId      *int64          `json:"entity_id"`
+	Before        json.RawMessage `json:"before,omitempty"`
+	After         json.RawMessage `json:"after,omitempty"`
+	RequestId     *string         `json:"request_id"`
+	Ip            *string         `json:"ip"`
+	CreatedAt     time.Time       `json:"created_at"`
+} // @name AuditEventResponse
+
+// Event изменение, которое сервис записывает в журнал.
+// Before и After - снимки сущности до и после изменения, nil - снимка нет
+type Event struct {
+	Action     string
+	EntityType string
+	EntityId   int64
+	Before     any
+	After      any
+}
+
+// FilterRequest структура запроса журнала с фильтрами.
+// Actor сравнивается и с JWT sub, и с preferred_username
+type FilterRequest struct {
+	Actor      string     `json:"actor" validate:"max=255"`
+	EntityType string     `json:"entityType" validate:"omitempty,oneof=employee role department position sod_rule sod_exception"`
+	EntityId   int64      `json:"entityId" validate:"min=0"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+	PageNumber int        `json:"pageNumber" validate:"min=1"`
+	PageSize   int        `json:"pageSize" validate:"min=1,max=100"`
+} // @name AuditFilterRequest
+
+// PageResponse структура для ответа с пагинацией
+type PageResponse struct {
+	Data       []Response `json:"data"`
+	PageNumber int        `json:"pageNumber"`
+	PageSize   int        `json:"pageSize"`
+	TotalCount int64      `json:"totalCount"`
+	TotalPages int        `json:"totalPages"`
+} // @name AuditPageResponse
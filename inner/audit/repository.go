@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// SaveTx записывает событие в транзакции изменения, чтобы журнал и данные не расходились
+func (r *Repository) SaveTx(ctx context.Context, tx *sqlx.Tx, event Entity) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_event
+			(actor_sub, actor_username, action, entity_type, entity_id, before, after, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb, $8, $9)`,
+		event.ActorSub, event.ActorUsername, event.Action, event.EntityType, event.EntityId,
+		event.Before, event.After, event.RequestId, event.Ip)
+	return err
+}
+
+func (r *Repository) FindWithFilter(ctx context.Context, filter FilterRequest, limit, offset int) ([]Entity, error) {
+	where, args := buildFilter(filter)
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(
+		"SELECT * FROM audit_event%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d",
+		where, len(args)-1, len(args))
+
+	var events []Entity
+	err := r.db.SelectContext(ctx, &events, query, args...)
+	return events, err
+}
+
+func (r *Repository) CountWithFilter(ctx context.Context, filter FilterRequest) (int64, error) {
+	where, args := buildFilter(filter)
+
+	var count int64
+	err := r.db.GetContext(ctx, &count, "SELECT COUNT(*) FROM audit_event"+where, args...)
+	return count, err
+}
+
+// собирает условие WHERE по фильтрам запроса; значения передаются только параметрами
+func buildFilter(filter FilterRequest) (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Actor != "" {
+		add("(actor_sub = $%[1]d OR actor_username = $%[1]d)", filter.Actor)
+	}
+	if filter.EntityType != "" {
+		add("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityId > 0 {
+		add("entity_id = $%d", filter.EntityId)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
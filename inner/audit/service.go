@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"idm/inner/common"
+	"idm/inner/validator"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo      Repo
+	validator Validator
+	logger    *common.Logger
+}
+
+type Repo interface {
+	SaveTx(ctx context.Context, tx *sqlx.Tx, event Entity) error
+	FindWithFilter(ctx context.Context, filter FilterRequest, limit, offset int) ([]Entity, error)
+	CountWithFilter(ctx context.Context, filter FilterRequest) (int64, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// функция-конструктор
+func NewService(repo Repo, validator Validator, logger *common.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// Record записывает событие в журнал в транзакции изменения.
+// Инициатор запроса берётся из контекста (см. web.ActorMiddleware)
+func (svc *Service) Record(ctx context.Context, tx *sqlx.Tx, event Event) error {
+	actor := common.ActorFromContext(ctx)
+
+	before, err := snapshot(event.Before)
+	if err != nil {
+		return fmt.Errorf("error recording audit event: %w", err)
+	}
+	after, err := snapshot(event.After)
+	if err != nil {
+		return fmt.Errorf("error recording audit event: %w", err)
+	}
+
+	entity := Entity{
+		ActorSub:      nullable(actor.Subject),
+		ActorUsername: nullable(actor.Username),
+		Action:        event.Action,
+		EntityType:    event.EntityType,
+		EntityId:      &event.EntityId,
+		Before:        before,
+		After:         after,
+		RequestId:     nullable(actor.RequestId),
+		Ip:            nullable(actor.Ip),
+	}
+	if err = svc.repo.SaveTx(ctx, tx, entity); err != nil {
+		svc.logger.Error("Failed to save audit event",
+			zap.String("action", event.Action),
+			zap.String("entity_type", event.EntityType),
+			zap.Int64("entity_id", event.EntityId),
+			zap.Error(err))
+		return fmt.Errorf("error recording audit event: %w", err)
+	}
+	return nil
+}
+
+// Метод для получения журнала с фильтрами и пагинацией
+func (svc *Service) FindWithFilter(ctx context.Context, request FilterRequest) (PageResponse, error) {
+	svc.logger.Debug("Finding audit events", zap.Any("filter", request))
+
+	if err := svc.validateRequest(request); err != nil {
+		return PageResponse{}, err
+	}
+
+	offset := (request.PageNumber - 1) * request.PageSize
+	entities, err := svc.repo.FindWithFilter(ctx, request, request.PageSize, offset)
+	if err != nil {
+		svc.logger.Error("Failed to find audit events", zap.Error(err))
+		return PageResponse{}, fmt.Errorf("error finding audit events: %w", err)
+	}
+	totalCount, err := svc.repo.CountWithFilter(ctx, request)
+	if err != nil {
+		svc.logger.Error("Failed to count audit events", zap.Error(err))
+		return PageResponse{}, fmt.Errorf("error counting audit events: %w", err)
+	}
+
+	responses := make([]Response, len(entities))
+	for i, entity := range entities {
+		responses[i] = entity.toResponse()
+	}
+
+	return PageResponse{
+		Data:       responses,
+		PageNumber: request.PageNumber,
+		PageSize:   request.PageSize,
+		TotalCount: totalCount,
+		TotalPages: int(math.Ceil(float64(totalCount) / float64(request.PageSize))),
+	}, nil
+}
+
+// валидация запроса журнала
+func (svc *Service) validateRequest(request FilterRequest) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Audit request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+
+	if request.From != nil && request.To != nil && !request.From.Before(*request.To) {
+		return common.RequestValidationError{Message: "from must be earlier than to"}
+	}
+	return nil
+}
+
+// сериализует снимок сущности в JSON; nil - снимка нет
+func snapshot(value any) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	result := string(data)
+	return &result, nil
+}
+
+func nullable(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
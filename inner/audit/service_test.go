@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/validator"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Объявляем структуру мок-репозитория
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, event Entity) error {
+	args := m.Called(tx, event)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindWithFilter(ctx context.Context, filter FilterRequest, limit, offset int) ([]Entity, error) {
+	args := m.Called(filter, limit, offset)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) CountWithFilter(ctx context.Context, filter FilterRequest) (int64, error) {
+	args := m.Called(filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// логгер для тестов
+func createTestLogger() *common.Logger {
+	cfg := common.Config{
+		DbDriverName:   "postgres",
+		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
+		AppName:        "test_app",
+		AppVersion:     "1.0.0",
+		LogLevel:       "DEBUG",
+		LogDevelopMode: true,
+	}
+	return common.NewLogger(cfg)
+}
+
+func newTestTx(t *testing.T) *sqlx.Tx {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	sqlMock.ExpectBegin()
+
+	tx, err := sqlx.NewDb(db, "postgres").Beginx()
+	assert.NoError(t, err)
+	return tx
+}
+
+func TestService_Record(t *testing.T) {
+	t.Run("Takes actor from context", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, validator.New(), createTestLogger())
+		tx := newTestTx(t)
+
+		var saved Entity
+		mockRepo.On("SaveTx", tx, mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(1).(Entity)
+		}).Return(nil)
+
+		ctx := common.WithActor(context.Background(), common.Actor{
+			Subject:   "8f14e45f",
+			Username:  "admin",
+			RequestId: "req-1",
+			Ip:        "10.0.0.1",
+		})
+		err := service.Record(ctx, tx, Event{
+			Action:     ActionUpdate,
+			EntityType: EntityRole,
+			EntityId:   5,
+			Before:     map[string]string{"name": "Guest"},
+			After:      map[string]string{"name": "Viewer"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "8f14e45f", *saved.ActorSub)
+		assert.Equal(t, "admin", *saved.ActorUsername)
+		assert.Equal(t, "req-1", *saved.RequestId)
+		assert.Equal(t, "10.0.0.1", *saved.Ip)
+		assert.Equal(t, int64(5), *saved.EntityId)
+		assert.JSONEq(t, `{"name":"Guest"}`, *saved.Before)
+		assert.JSONEq(t, `{"name":"Viewer"}`, *saved.After)
+	})
+
+	t.Run("Without actor and snapshots", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, validator.New(), createTestLogger())
+		tx := newTestTx(t)
+
+		var saved Entity
+		mockRepo.On("SaveTx", tx, mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(1).(Entity)
+		}).Return(nil)
+
+		err := service.Record(context.Background(), tx, Event{Action: ActionDelete, EntityType: EntityEmployee, EntityId: 1})
+
+		assert.NoError(t, err)
+		assert.Nil(t, saved.ActorSub)
+		assert.Nil(t, saved.Before)
+		assert.Nil(t, saved.After)
+	})
+
+	t.Run("Save error", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, validator.New(), createTestLogger())
+		tx := newTestTx(t)
+		mockRepo.On("SaveTx", tx, mock.Anything).Return(errors.New("db error"))
+
+		err := service.Record(context.Background(), tx, Event{Action: ActionCreate, EntityType: EntityRole, EntityId: 1})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestService_FindWithFilter(t *testing.T) {
+	t.Run("Pagination", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, validator.New(), createTestLogger())
+
+		request := FilterRequest{Actor: "admin", EntityType: EntityEmployee, PageNumber: 2, PageSize: 10}
+		mockRepo.On("FindWithFilter", request, 10, 10).Return([]Entity{{Id: 11, Action: ActionCreate}}, nil)
+		mockRepo.On("CountWithFilter", request).Return(int64(11), nil)
+
+		response, err := service.FindWithFilter(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Len(t, response.Data, 1)
+		assert.Equal(t, int64(11), response.TotalCount)
+		assert.Equal(t, 2, response.TotalPages)
+	})
+
+	t.Run("Invalid period", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, validator.New(), createTestLogger())
+
+		from := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+		to := from.Add(-time.Hour)
+		_, err := service.FindWithFilter(context.Background(), FilterRequest{From: &from, To: &to, PageNumber: 1, PageSize: 10})
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "FindWithFilter", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown entity type", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, validator.New(), createTestLogger())
+
+		_, err := service.FindWithFilter(context.Background(), FilterRequest{EntityType: "permission", PageNumber: 1, PageSize: 10})
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+	})
+}
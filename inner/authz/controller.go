@@ -0,0 +1,118 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server       *web.Server
+	authzService Svc
+	logger       *common.Logger
+}
+
+// интерфейс сервиса authz.Service
+type Svc interface {
+	Check(ctx context.Context, request CheckRequest) (CheckResponse, error)
+	CheckBatch(ctx context.Context, request BatchCheckRequest) (BatchCheckResponse, error)
+}
+
+func NewController(server *web.Server, authzService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:       server,
+		authzService: authzService,
+		logger:       logger,
+	}
+}
+
+// функция для регистрации маршрутов
+func (c *Controller) RegisterRoutes() {
+	c.logger.Info("Registering authz routes")
+	// полный маршрут получится "/api/v1/authz/check"
+	api := c.server.GroupApiV1
+	api.Post("/authz/check", c.Check)
+	api.Post("/authz/check/batch", c.CheckBatch)
+	c.logger.Info("Authz routes registered successfully")
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/authz/check".
+// Отказ в доступе - штатный ответ 200 с allowed = false
+func (c *Controller) Check(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received authz check request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request CheckRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse authz check request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	response, err := c.authzService.Check(ctx.Context(), request)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return common.OkResponse(ctx, response)
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/authz/check/batch"
+func (c *Controller) CheckBatch(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received authz batch check request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request BatchCheckRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse authz batch check request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	response, err := c.authzService.CheckBatch(ctx.Context(), request)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return common.OkResponse(ctx, response)
+}
+
+// преобразует ошибки сервиса в HTTP-ответ
+func (c *Controller) handleError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		c.logger.Warn("Authz request validation error",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+
+		var validationErr common.RequestValidationError
+		errors.As(err, &validationErr)
+
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Authz request target not found",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Employee not found")
+
+	default:
+		c.logger.Error("Authz request internal error",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when checking access")
+	}
+}
@@ -0,0 +1,111 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock для сервиса
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Check(ctx context.Context, request CheckRequest) (CheckResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(CheckResponse), args.Error(1)
+}
+
+func (m *MockService) CheckBatch(ctx context.Context, request BatchCheckRequest) (BatchCheckResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(BatchCheckResponse), args.Error(1)
+}
+
+// Вспомогательные функции для создания Fiber app
+func setupTestApp() (*fiber.App, *MockService) {
+	app := fiber.New()
+	mockService := &MockService{}
+
+	server := &web.Server{
+		GroupApiV1: app.Group("/api/v1"),
+	}
+
+	controller := NewController(server, mockService, createTestLogger())
+	controller.RegisterRoutes()
+
+	return app, mockService
+}
+
+func TestController_Check(t *testing.T) {
+	t.Run("Denied", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("Check", CheckRequest{EmployeeId: 7, Roles: []string{"Admin"}}).Return(CheckResponse{
+			EmployeeId: 7,
+			Decision:   web.Decision{Allowed: false, Reasons: []string{"none of required roles [Admin] held"}},
+		}, nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/authz/check",
+			bytes.NewReader([]byte(`{"employee_id":7,"roles":["Admin"]}`)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		// отказ в доступе - штатный ответ, а не ошибка запроса
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var response common.Response[CheckResponse]
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		assert.NoError(t, err)
+		assert.False(t, response.Data.Allowed)
+		assert.Equal(t, []string{"none of required roles [Admin] held"}, response.Data.Reasons)
+	})
+
+	t.Run("Unknown employee", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("Check", mock.Anything).
+			Return(CheckResponse{}, common.NotFoundError{Message: "employee with id 404 not found"})
+
+		req := httptest.NewRequest("POST", "/api/v1/authz/check",
+			bytes.NewReader([]byte(`{"employee_id":404,"roles":["Admin"]}`)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestController_CheckBatch(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("CheckBatch", mock.Anything).Return(BatchCheckResponse{Results: []CheckResponse{
+		{EmployeeId: 7, Decision: web.Decision{Allowed: true, Reasons: []string{"role Admin held via assigned role"}}},
+		{EmployeeId: 8, Decision: web.Decision{Allowed: false, Reasons: []string{"permission audit:read not granted"}}},
+	}}, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/authz/check/batch", bytes.NewReader([]byte(
+		`{"checks":[{"employee_id":7,"roles":["Admin"]},{"employee_id":8,"permissions":["audit:read"]}]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response common.Response[BatchCheckResponse]
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data.Results, 2)
+	assert.True(t, response.Data.Results[0].Allowed)
+	assert.False(t, response.Data.Results[1].Allowed)
+}
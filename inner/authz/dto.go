@@ -0,0 +1,28 @@
+package authz
+
+import "idm/inner/web"
+
+// CheckRequest запрос на проверку доступа сотрудника.
+// Roles - достаточно любой из ролей, Permissions - требуются все разрешения.
+// Если указаны оба списка, доступ разрешается только при выполнении обоих условий
+type CheckRequest struct {
+	EmployeeId  int64    `json:"employee_id" validate:"required,min=1" example:"1"`
+	Roles       []string `json:"roles,omitempty" validate:"required_without=Permissions,omitempty,min=1,dive,required" example:"Admin"`
+	Permissions []string `json:"permissions,omitempty" validate:"required_without=Roles,omitempty,min=1,dive,required" example:"employee:read"`
+}
+
+// CheckResponse решение по запросу на проверку доступа
+type CheckResponse struct {
+	EmployeeId int64 `json:"employee_id"`
+	web.Decision
+}
+
+// BatchCheckRequest пакетный запрос на проверку доступа
+type BatchCheckRequest struct {
+	Checks []CheckRequest `json:"checks" validate:"required,min=1,max=100,dive"`
+}
+
+// BatchCheckResponse решения в порядке проверок запроса
+type BatchCheckResponse struct {
+	Results []CheckResponse `json:"results"`
+}
@@ -0,0 +1,181 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"idm/inner/common"
+	"idm/inner/permission"
+	"idm/inner/role"
+	"idm/inner/validator"
+	"idm/inner/web"
+
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	roleService       RoleSvc
+	permissionService PermissionSvc
+	validator         Validator
+	logger            *common.Logger
+}
+
+// интерфейс сервиса ролей: эффективные роли сотрудника с учётом наследования
+type RoleSvc interface {
+	FindEffectiveRoles(ctx context.Context, employeeId int64) ([]role.EffectiveRoleResponse, error)
+}
+
+// интерфейс сервиса разрешений: эффективные разрешения сотрудника
+type PermissionSvc interface {
+	FindEffectivePermissions(ctx context.Context, employeeId int64) ([]permission.EffectivePermissionResponse, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// права сотрудника, по которым принимается решение
+type subject struct {
+	roles       []web.Grant
+	permissions []web.Grant
+}
+
+// функция-конструктор
+func NewService(roleService RoleSvc, permissionService PermissionSvc, validator Validator, logger *common.Logger) *Service {
+	return &Service{
+		roleService:       roleService,
+		permissionService: permissionService,
+		validator:         validator,
+		logger:            logger,
+	}
+}
+
+// Метод для проверки доступа сотрудника
+func (svc *Service) Check(ctx context.Context, request CheckRequest) (CheckResponse, error) {
+	svc.logger.Debug("Checking access", zap.Int64("employee_id", request.EmployeeId))
+
+	if err := svc.validateRequest(request); err != nil {
+		return CheckResponse{}, err
+	}
+
+	sub, err := svc.loadSubject(ctx, request.EmployeeId)
+	if err != nil {
+		return CheckResponse{}, err
+	}
+
+	response := evaluate(sub, request)
+	svc.logger.Debug("Access checked",
+		zap.Int64("employee_id", request.EmployeeId),
+		zap.Bool("allowed", response.Allowed),
+		zap.Strings("reasons", response.Reasons))
+	return response, nil
+}
+
+// Метод для пакетной проверки доступа.
+// Права каждого сотрудника загружаются один раз; для несуществующего сотрудника
+// возвращается отказ, а не ошибка всего запроса
+func (svc *Service) CheckBatch(ctx context.Context, request BatchCheckRequest) (BatchCheckResponse, error) {
+	svc.logger.Debug("Checking access in batch", zap.Int("count", len(request.Checks)))
+
+	if err := svc.validateRequest(request); err != nil {
+		return BatchCheckResponse{}, err
+	}
+
+	subjects := make(map[int64]*subject)
+	results := make([]CheckResponse, len(request.Checks))
+	for i, check := range request.Checks {
+		sub, ok := subjects[check.EmployeeId]
+		if !ok {
+			loaded, err := svc.loadSubject(ctx, check.EmployeeId)
+			if err != nil && !errors.As(err, &common.NotFoundError{}) {
+				return BatchCheckResponse{}, err
+			}
+			if err == nil {
+				sub = &loaded
+			}
+			subjects[check.EmployeeId] = sub
+		}
+
+		if sub == nil {
+			results[i] = CheckResponse{
+				EmployeeId: check.EmployeeId,
+				Decision: web.Decision{
+					Allowed: false,
+					Reasons: []string{fmt.Sprintf("employee with id %d not found", check.EmployeeId)},
+				},
+			}
+			continue
+		}
+		results[i] = evaluate(*sub, check)
+	}
+
+	return BatchCheckResponse{Results: results}, nil
+}
+
+// загружает эффективные роли и разрешения сотрудника
+func (svc *Service) loadSubject(ctx context.Context, employeeId int64) (subject, error) {
+	roles, err := svc.roleService.FindEffectiveRoles(ctx, employeeId)
+	if err != nil {
+		return subject{}, err
+	}
+	permissions, err := svc.permissionService.FindEffectivePermissions(ctx, employeeId)
+	if err != nil {
+		return subject{}, err
+	}
+
+	roleNames := make(map[int64]string, len(roles))
+	for _, r := range roles {
+		roleNames[r.Id] = r.Name
+	}
+
+	var sub subject
+	for _, r := range roles {
+		source := "assigned role"
+		if r.Inherited {
+			source = fmt.Sprintf("inheritance from role %s", roleNames[r.SourceRoleId])
+		}
+		sub.roles = append(sub.roles, web.Grant{Name: r.Name, Source: source})
+	}
+	for _, p := range permissions {
+		for _, roleId := range p.ViaRoleIds {
+			sub.permissions = append(sub.permissions, web.Grant{
+				Name:   p.Code,
+				Source: fmt.Sprintf("role %s", roleNames[roleId]),
+			})
+		}
+	}
+	return sub, nil
+}
+
+// принимает решение по правам сотрудника тем же кодом, что и middleware web.RequireAnyRole
+func evaluate(sub subject, request CheckRequest) CheckResponse {
+	var decisions []web.Decision
+	if len(request.Roles) > 0 {
+		decisions = append(decisions, web.EvaluateAnyRole(sub.roles, request.Roles))
+	}
+	if len(request.Permissions) > 0 {
+		decisions = append(decisions, web.EvaluatePermissions(sub.permissions, request.Permissions))
+	}
+	return CheckResponse{
+		EmployeeId: request.EmployeeId,
+		Decision:   web.AllOf(decisions...),
+	}
+}
+
+// валидация запросов к сервису проверки доступа
+func (svc *Service) validateRequest(request any) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Authorization request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return nil
+}
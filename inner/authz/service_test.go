@@ -0,0 +1,140 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/permission"
+	"idm/inner/role"
+	"idm/inner/validator"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRoleService struct {
+	mock.Mock
+}
+
+type MockPermissionService struct {
+	mock.Mock
+}
+
+func (m *MockRoleService) FindEffectiveRoles(ctx context.Context, employeeId int64) ([]role.EffectiveRoleResponse, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).([]role.EffectiveRoleResponse), args.Error(1)
+}
+
+func (m *MockPermissionService) FindEffectivePermissions(ctx context.Context, employeeId int64) ([]permission.EffectivePermissionResponse, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).([]permission.EffectivePermissionResponse), args.Error(1)
+}
+
+// логгер для тестов
+func createTestLogger() *common.Logger {
+	cfg := common.Config{
+		DbDriverName:   "postgres",
+		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
+		AppName:        "test_app",
+		AppVersion:     "1.0.0",
+		LogLevel:       "DEBUG",
+		LogDevelopMode: true,
+	}
+	return common.NewLogger(cfg)
+}
+
+func newTestService() (*Service, *MockRoleService, *MockPermissionService) {
+	mockRoleService := new(MockRoleService)
+	mockPermissionService := new(MockPermissionService)
+	service := NewService(mockRoleService, mockPermissionService, validator.New(), createTestLogger())
+	return service, mockRoleService, mockPermissionService
+}
+
+// сотруднику 7 назначена роль Guest(3), она наследует Admin(2)
+func mockEmployee(mockRoleService *MockRoleService, mockPermissionService *MockPermissionService) {
+	mockRoleService.On("FindEffectiveRoles", int64(7)).Return([]role.EffectiveRoleResponse{
+		{Response: role.Response{Id: 3, Name: "Guest"}},
+		{Response: role.Response{Id: 2, Name: "Admin"}, Inherited: true, Depth: 1, SourceRoleId: 3},
+	}, nil)
+	mockPermissionService.On("FindEffectivePermissions", int64(7)).Return([]permission.EffectivePermissionResponse{
+		{Response: permission.Response{Id: 10, Code: "employee:read"}, ViaRoleIds: []int64{2, 3}},
+		{Response: permission.Response{Id: 11, Code: "employee:write"}, Inherited: true, ViaRoleIds: []int64{2}},
+	}, nil)
+}
+
+func TestService_Check(t *testing.T) {
+	t.Run("Inherited role allows", func(t *testing.T) {
+		service, mockRoleService, mockPermissionService := newTestService()
+		mockEmployee(mockRoleService, mockPermissionService)
+
+		response, err := service.Check(context.Background(), CheckRequest{EmployeeId: 7, Roles: []string{"Admin"}})
+
+		assert.NoError(t, err)
+		assert.True(t, response.Allowed)
+		assert.Equal(t, []string{"role Admin held via inheritance from role Guest"}, response.Reasons)
+	})
+
+	t.Run("Missing permission denies", func(t *testing.T) {
+		service, mockRoleService, mockPermissionService := newTestService()
+		mockEmployee(mockRoleService, mockPermissionService)
+
+		response, err := service.Check(context.Background(), CheckRequest{
+			EmployeeId:  7,
+			Roles:       []string{"Guest"},
+			Permissions: []string{"employee:read", "audit:read"},
+		})
+
+		assert.NoError(t, err)
+		assert.False(t, response.Allowed)
+		assert.Equal(t, []string{
+			"role Guest held via assigned role",
+			"permission employee:read granted via role Admin, role Guest",
+			"permission audit:read not granted",
+		}, response.Reasons)
+	})
+
+	t.Run("Nothing to check", func(t *testing.T) {
+		service, mockRoleService, _ := newTestService()
+
+		_, err := service.Check(context.Background(), CheckRequest{EmployeeId: 7})
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRoleService.AssertNotCalled(t, "FindEffectiveRoles", mock.Anything)
+	})
+
+	t.Run("Unknown employee", func(t *testing.T) {
+		service, mockRoleService, _ := newTestService()
+		mockRoleService.On("FindEffectiveRoles", int64(404)).
+			Return([]role.EffectiveRoleResponse(nil), common.NotFoundError{Message: "employee with id 404 not found"})
+
+		_, err := service.Check(context.Background(), CheckRequest{EmployeeId: 404, Roles: []string{"Admin"}})
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+	})
+}
+
+func TestService_CheckBatch(t *testing.T) {
+	service, mockRoleService, mockPermissionService := newTestService()
+	mockEmployee(mockRoleService, mockPermissionService)
+	mockRoleService.On("FindEffectiveRoles", int64(404)).
+		Return([]role.EffectiveRoleResponse(nil), common.NotFoundError{Message: "employee with id 404 not found"})
+
+	response, err := service.CheckBatch(context.Background(), BatchCheckRequest{Checks: []CheckRequest{
+		{EmployeeId: 7, Permissions: []string{"employee:write"}},
+		{EmployeeId: 404, Roles: []string{"Admin"}},
+		{EmployeeId: 7, Roles: []string{"Root"}},
+	}})
+
+	assert.NoError(t, err)
+	assert.Len(t, response.Results, 3)
+	assert.True(t, response.Results[0].Allowed)
+	assert.False(t, response.Results[1].Allowed)
+	assert.Equal(t, []string{"employee with id 404 not found"}, response.Results[1].Reasons)
+	assert.False(t, response.Results[2].Allowed)
+	// права сотрудника загружаются один раз на весь пакет
+	mockRoleService.AssertNumberOfCalls(t, "FindEffectiveRoles", 2)
+	mockPermissionService.AssertNumberOfCalls(t, "FindEffectivePermissions", 1)
+}
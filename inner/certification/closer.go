@@ -0,0 +1,86 @@
+package certification
+
+import (
+	"context"
+	"idm/inner/common"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// интерфейс завершения кампаний с истёкшим сроком (реализуется certification.Service)
+type DueCloser interface {
+	CloseDue(ctx context.Context) (int, error)
+}
+
+// Closer фоновая горутина, периодически завершающая кампании, срок которых истёк
+type Closer struct {
+	closer   DueCloser
+	interval time.Duration
+	logger   *common.Logger
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// функция-конструктор
+func NewCloser(closer DueCloser, interval time.Duration, logger *common.Logger) *Closer {
+	return &Closer{
+		closer:   closer,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start запускает проверку; первая проверка выполняется сразу
+func (c *Closer) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.wg.Add(1)
+	go c.run(ctx)
+	c.logger.Info("Certification campaign closer started", zap.Duration("interval", c.interval))
+}
+
+// Stop останавливает проверку и ждёт завершения текущего прохода или отмены ctx
+func (c *Closer) Stop(ctx context.Context) {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		c.logger.Info("Certification campaign closer stopped")
+	case <-ctx.Done():
+		c.logger.Warn("Certification campaign closer stop timeout exceeded")
+	}
+}
+
+func (c *Closer) run(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// один проход; ошибки только логируются, кампании будут завершены на следующем проходе
+func (c *Closer) tick(ctx context.Context) {
+	if _, err := c.closer.CloseDue(ctx); err != nil && ctx.Err() == nil {
+		c.logger.Error("Failed to close due certification campaigns", zap.Error(err))
+	}
+}
@@ -0,0 +1,369 @@
+package certification
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// допустимые значения фильтров status и decision
+var (
+	statuses  = []string{StatusActive, StatusCompleted, StatusCancelled}
+	decisions = []string{DecisionPending, DecisionCertified, DecisionRevoked}
+)
+
+type Controller struct {
+	server               *web.Server
+	certificationService Svc
+	logger               *common.Logger
+}
+
+// интерфейс сервиса certification.Service
+type Svc interface {
+	Create(ctx context.Context, request CreateRequest) (CampaignResponse, error)
+	FindCampaigns(ctx context.Context, status string) ([]CampaignResponse, error)
+	FindCampaignById(ctx context.Context, id int64) (CampaignResponse, error)
+	Cancel(ctx context.Context, id int64) (CampaignResponse, error)
+	FindItems(ctx context.Context, campaignId int64, decision string) ([]ItemResponse, error)
+	FindReviews(ctx context.Context, decision string, admin bool) ([]ItemResponse, error)
+	Certify(ctx context.Context, request DecisionRequest, admin bool) (ItemResponse, error)
+	Revoke(ctx context.Context, request DecisionRequest, admin bool) (ItemResponse, error)
+	Summary(ctx context.Context, campaignId int64) (SummaryResponse, error)
+}
+
+func NewController(server *web.Server, certificationService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:               server,
+		certificationService: certificationService,
+		logger:               logger,
+	}
+}
+
+// функция для регистрации маршрутов
+func (c *Controller) RegisterRoutes() {
+	c.logger.Info("Registering certification routes")
+	// пересмотр назначений: "/api/v1/certifications/..."
+	c.server.GroupApiV1User.Get("/certifications/reviews", c.FindReviews)
+	c.server.GroupApiV1User.Post("/certifications/items/:id/certify", c.CertifyItem)
+	c.server.GroupApiV1User.Post("/certifications/items/:id/revoke", c.RevokeItem)
+	// кампании ведут администраторы: "/api/v1/admin/certifications/..."
+	c.server.GroupApiV1Admin.Post("/certifications", c.CreateCampaign)
+	c.server.GroupApiV1Admin.Get("/certifications", c.FindCampaigns)
+	c.server.GroupApiV1Admin.Get("/certifications/:id", c.FindCampaignById)
+	c.server.GroupApiV1Admin.Get("/certifications/:id/items", c.FindCampaignItems)
+	c.server.GroupApiV1Admin.Get("/certifications/:id/report", c.GetCampaignReport)
+	c.server.GroupApiV1Admin.Post("/certifications/:id/cancel", c.CancelCampaign)
+	c.logger.Info("Certification routes registered successfully")
+}
+
+// CreateCampaign запускает кампанию пересмотра доступа
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Create certification campaign
+//	@Description	Snapshot the active role assignments of the scope into review items. Without role_ids or department_ids the scope is not limited; departments include their sub-departments. Items are reviewed by the employee's manager (reviewer=manager) or by administrators; at the deadline unreviewed items are certified or revoked according to auto_action (revoke by default)
+//	@Tags			certifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CertificationCampaignCreateRequest	true	"Campaign scope and deadline"
+//	@Success		200		{object}	CertificationCampaignResponse		"Created campaign"
+//	@Failure		400		{object}	common.Response[any]				"Invalid request or empty scope"
+//	@Failure		500		{object}	common.Response[any]				"Error when creating the campaign"
+//	@Router			/admin/certifications [post]
+func (c *Controller) CreateCampaign(ctx *fiber.Ctx) error {
+	c.logger.Info("Received create certification campaign request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	response, err := c.certificationService.Create(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleCertificationError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindCampaigns получает кампании
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List certification campaigns
+//	@Description	Campaigns, newest first
+//	@Tags			certifications
+//	@Produce		json
+//	@Param			status	query		string							false	"Campaign status"	Enums(active, completed, cancelled)
+//	@Success		200		{array}		CertificationCampaignResponse	"Campaigns"
+//	@Failure		400		{object}	common.Response[any]			"Invalid status"
+//	@Failure		500		{object}	common.Response[any]			"Error when getting campaigns"
+//	@Router			/admin/certifications [get]
+func (c *Controller) FindCampaigns(ctx *fiber.Ctx) error {
+	status := ctx.Query("status")
+	if status != "" && !slices.Contains(statuses, status) {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid status parameter")
+	}
+
+	response, err := c.certificationService.FindCampaigns(ctx.UserContext(), status)
+	if err != nil {
+		return c.handleCertificationError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindCampaignById получает кампанию
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get certification campaign
+//	@Tags			certifications
+//	@Produce		json
+//	@Param			id	path		int								true	"Campaign ID"
+//	@Success		200	{object}	CertificationCampaignResponse	"Campaign"
+//	@Failure		400	{object}	common.Response[any]			"Invalid campaign ID"
+//	@Failure		404	{object}	common.Response[any]			"Campaign not found"
+//	@Failure		500	{object}	common.Response[any]			"Error when getting the campaign"
+//	@Router			/admin/certifications/{id} [get]
+func (c *Controller) FindCampaignById(ctx *fiber.Ctx) error {
+	id, err := c.parseId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid campaign ID format")
+	}
+
+	response, err := c.certificationService.FindCampaignById(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleCertificationError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindCampaignItems получает назначения кампании
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List certification campaign items
+//	@Tags			certifications
+//	@Produce		json
+//	@Param			id			path		int							true	"Campaign ID"
+//	@Param			decision	query		string						false	"Decision"	Enums(pending, certified, revoked)
+//	@Success		200			{array}		CertificationItemResponse	"Items"
+//	@Failure		400			{object}	common.Response[any]		"Invalid parameters"
+//	@Failure		404			{object}	common.Response[any]		"Campaign not found"
+//	@Failure		500			{object}	common.Response[any]		"Error when getting items"
+//	@Router			/admin/certifications/{id}/items [get]
+func (c *Controller) FindCampaignItems(ctx *fiber.Ctx) error {
+	id, err := c.parseId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid campaign ID format")
+	}
+	decision := ctx.Query("decision")
+	if decision != "" && !slices.Contains(decisions, decision) {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid decision parameter")
+	}
+
+	response, err := c.certificationService.FindItems(ctx.UserContext(), id, decision)
+	if err != nil {
+		return c.handleCertificationError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// GetCampaignReport получает итоги кампании
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get certification campaign report
+//	@Description	Numbers of pending, certified and revoked items, including automatic decisions at the deadline, and the progress of each reviewer
+//	@Tags			certifications
+//	@Produce		json
+//	@Param			id	path		int							true	"Campaign ID"
+//	@Success		200	{object}	CertificationSummaryResponse	"Campaign report"
+//	@Failure		400	{object}	common.Response[any]		"Invalid campaign ID"
+//	@Failure		404	{object}	common.Response[any]		"Campaign not found"
+//	@Failure		500	{object}	common.Response[any]		"Error when building the report"
+//	@Router			/admin/certifications/{id}/report [get]
+func (c *Controller) GetCampaignReport(ctx *fiber.Ctx) error {
+	id, err := c.parseId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid campaign ID format")
+	}
+
+	response, err := c.certificationService.Summary(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleCertificationError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// CancelCampaign отменяет кампанию
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Cancel certification campaign
+//	@Description	Decisions already made are kept, unreviewed assignments stay as they are
+//	@Tags			certifications
+//	@Produce		json
+//	@Param			id	path		int								true	"Campaign ID"
+//	@Success		200	{object}	CertificationCampaignResponse	"Cancelled campaign"
+//	@Failure		400	{object}	common.Response[any]			"Invalid campaign ID"
+//	@Failure		404	{object}	common.Response[any]			"Campaign not found"
+//	@Failure		409	{object}	common.Response[any]			"Campaign is not active"
+//	@Failure		500	{object}	common.Response[any]			"Error when cancelling the campaign"
+//	@Router			/admin/certifications/{id}/cancel [post]
+func (c *Controller) CancelCampaign(ctx *fiber.Ctx) error {
+	c.logger.Info("Received cancel certification campaign request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid campaign ID format")
+	}
+
+	response, err := c.certificationService.Cancel(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleCertificationError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// FindReviews получает назначения, которые пересматривает пользователь
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List own certification reviews
+//	@Description	Items of active campaigns reviewed by the user's employee, ordered by deadline. Administrators also get items without a reviewer
+//	@Tags			certifications
+//	@Produce		json
+//	@Param			decision	query		string						false	"Decision"	Enums(pending, certified, revoked)
+//	@Success		200			{array}		CertificationItemResponse	"Items"
+//	@Failure		400			{object}	common.Response[any]		"Invalid decision"
+//	@Failure		500			{object}	common.Response[any]		"Error when getting items"
+//	@Router			/certifications/reviews [get]
+func (c *Controller) FindReviews(ctx *fiber.Ctx) error {
+	decision := ctx.Query("decision")
+	if decision != "" && !slices.Contains(decisions, decision) {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid decision parameter")
+	}
+
+	response, err := c.certificationService.FindReviews(ctx.UserContext(), decision, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleCertificationError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// CertifyItem подтверждает назначение
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Certify role assignment
+//	@Description	Confirm that the employee still needs the role. Available to the reviewer of the item and administrators
+//	@Tags			certifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"Item ID"
+//	@Param			request	body		CertificationDecisionRequest	false	"Comment"
+//	@Success		200		{object}	CertificationItemResponse		"Reviewed item"
+//	@Failure		400		{object}	common.Response[any]			"Invalid request"
+//	@Failure		403		{object}	common.Response[any]			"Own role assignment"
+//	@Failure		404		{object}	common.Response[any]			"Item not found"
+//	@Failure		409		{object}	common.Response[any]			"Item is already reviewed or the campaign is closed"
+//	@Failure		500		{object}	common.Response[any]			"Error when saving the decision"
+//	@Router			/certifications/items/{id}/certify [post]
+func (c *Controller) CertifyItem(ctx *fiber.Ctx) error {
+	return c.review(ctx, c.certificationService.Certify)
+}
+
+// RevokeItem отзывает назначение
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Revoke role assignment
+//	@Description	Revoke the role from the employee immediately. The comment with the reason is required
+//	@Tags			certifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"Item ID"
+//	@Param			request	body		CertificationDecisionRequest	true	"Reason"
+//	@Success		200		{object}	CertificationItemResponse		"Reviewed item"
+//	@Failure		400		{object}	common.Response[any]			"Invalid request"
+//	@Failure		403		{object}	common.Response[any]			"Own role assignment"
+//	@Failure		404		{object}	common.Response[any]			"Item not found"
+//	@Failure		409		{object}	common.Response[any]			"Item is already reviewed or the campaign is closed"
+//	@Failure		500		{object}	common.Response[any]			"Error when saving the decision"
+//	@Router			/certifications/items/{id}/revoke [post]
+func (c *Controller) RevokeItem(ctx *fiber.Ctx) error {
+	return c.review(ctx, c.certificationService.Revoke)
+}
+
+func (c *Controller) review(
+	ctx *fiber.Ctx,
+	decide func(ctx context.Context, request DecisionRequest, admin bool) (ItemResponse, error),
+) error {
+	c.logger.Info("Received certification review request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid item ID format")
+	}
+	// комментарий к подтверждению необязателен, тело может отсутствовать
+	var request DecisionRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	request.ItemId = id
+
+	response, err := decide(ctx.UserContext(), request, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleCertificationError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+func (c *Controller) parseId(ctx *fiber.Ctx) (int64, error) {
+	return strconv.ParseInt(ctx.Params("id"), 10, 64)
+}
+
+// обрабатывает ошибки сервиса кампаний; ошибки отзыва роли приходят от сервиса сотрудников и обрабатываются так же
+func (c *Controller) handleCertificationError(ctx *fiber.Ctx, err error, id int64) error {
+	var validationErr common.RequestValidationError
+	if errors.As(err, &validationErr) {
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message, validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+	}
+	var forbiddenErr common.ForbiddenError
+	if errors.As(err, &forbiddenErr) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, forbiddenErr.Message)
+	}
+	var notFoundErr common.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return common.ErrResponse(ctx, fiber.StatusNotFound, notFoundErr.Message)
+	}
+	var conflictErr common.ConflictError
+	if errors.As(err, &conflictErr) {
+		return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message)
+	}
+	c.logger.Error("Certification request failed",
+		zap.Int64("id", id),
+		zap.Error(err),
+		zap.String("ip", ctx.IP()))
+	return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when processing the certification request")
+}
@@ -0,0 +1,273 @@
+package certification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock для сервиса
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Create(ctx context.Context, request CreateRequest) (CampaignResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(CampaignResponse), args.Error(1)
+}
+
+func (m *MockService) FindCampaigns(ctx context.Context, status string) ([]CampaignResponse, error) {
+	args := m.Called(status)
+	return args.Get(0).([]CampaignResponse), args.Error(1)
+}
+
+func (m *MockService) FindCampaignById(ctx context.Context, id int64) (CampaignResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).(CampaignResponse), args.Error(1)
+}
+
+func (m *MockService) Cancel(ctx context.Context, id int64) (CampaignResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).(CampaignResponse), args.Error(1)
+}
+
+func (m *MockService) FindItems(ctx context.Context, campaignId int64, decision string) ([]ItemResponse, error) {
+	args := m.Called(campaignId, decision)
+	return args.Get(0).([]ItemResponse), args.Error(1)
+}
+
+func (m *MockService) FindReviews(ctx context.Context, decision string, admin bool) ([]ItemResponse, error) {
+	args := m.Called(decision, admin)
+	return args.Get(0).([]ItemResponse), args.Error(1)
+}
+
+func (m *MockService) Certify(ctx context.Context, request DecisionRequest, admin bool) (ItemResponse, error) {
+	args := m.Called(request, admin)
+	return args.Get(0).(ItemResponse), args.Error(1)
+}
+
+func (m *MockService) Revoke(ctx context.Context, request DecisionRequest, admin bool) (ItemResponse, error) {
+	args := m.Called(request, admin)
+	return args.Get(0).(ItemResponse), args.Error(1)
+}
+
+func (m *MockService) Summary(ctx context.Context, campaignId int64) (SummaryResponse, error) {
+	args := m.Called(campaignId)
+	return args.Get(0).(SummaryResponse), args.Error(1)
+}
+
+// Вспомогательная функция для создания Fiber app; токен с ролями roles подставляется вместо Keycloak
+func setupTestApp(roles ...string) (*fiber.App, *MockService) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{
+			RealmAccess:       web.RealmAccessClaims{Roles: roles},
+			PreferredUsername: "user",
+			Email:             "user@example.com",
+			RegisteredClaims:  jwt.RegisteredClaims{Subject: "sub-1"},
+		}})
+		return c.Next()
+	})
+	app.Use(web.ActorMiddleware())
+	mockService := &MockService{}
+
+	server := &web.Server{
+		GroupApiV1:      app.Group("/api/v1"),
+		GroupApiV1User:  app.Group("/api/v1"),
+		GroupApiV1Admin: app.Group("/api/v1/admin"),
+	}
+
+	controller := NewController(server, mockService, createTestLogger())
+	controller.RegisterRoutes()
+
+	return app, mockService
+}
+
+func TestController_CreateCampaign(t *testing.T) {
+	t.Run("Created", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		deadline := time.Date(2025, 9, 30, 23, 59, 59, 0, time.UTC)
+		mockService.On("Create", CreateRequest{Name: "Q3 review", RoleIds: []int64{1, 2}, Deadline: deadline}).
+			Return(CampaignResponse{Id: 1, Status: StatusActive, ItemCount: 12}, nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/certifications",
+			bytes.NewBufferString(`{"name":"Q3 review","role_ids":[1,2],"deadline":"2025-09-30T23:59:59Z"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var response common.Response[CampaignResponse]
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		assert.Equal(t, 12, response.Data.ItemCount)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Empty scope", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		mockService.On("Create", mock.Anything).
+			Return(CampaignResponse{}, common.RequestValidationError{Message: "no active role assignments match the campaign scope"})
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/certifications",
+			bytes.NewBufferString(`{"name":"Q3 review","deadline":"2025-09-30T23:59:59Z"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Invalid body", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/certifications", bytes.NewBufferString(`{"name":`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestController_FindCampaigns(t *testing.T) {
+	t.Run("Filtered by status", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		mockService.On("FindCampaigns", StatusActive).Return([]CampaignResponse{{Id: 1}}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/admin/certifications?status=active", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid status", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/admin/certifications?status=unknown", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "FindCampaigns", mock.Anything)
+	})
+}
+
+func TestController_FindCampaignItems_NotFound(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmAdmin)
+	mockService.On("FindItems", int64(1), DecisionPending).
+		Return([]ItemResponse(nil), common.NotFoundError{Message: "certification campaign with id 1 not found"})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/admin/certifications/1/items?decision=pending", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestController_GetCampaignReport(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmAdmin)
+	mockService.On("Summary", int64(1)).
+		Return(SummaryResponse{Campaign: CampaignResponse{Id: 1}, Total: 8, Pending: 2, Progress: 75}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/admin/certifications/1/report", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response common.Response[SummaryResponse]
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, 75, response.Data.Progress)
+}
+
+func TestController_CancelCampaign_Conflict(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmAdmin)
+	mockService.On("Cancel", int64(1)).
+		Return(CampaignResponse{}, common.ConflictError{Message: "certification campaign 1 is already completed"})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/admin/certifications/1/cancel", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestController_FindReviews(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmUser)
+	mockService.On("FindReviews", "", false).Return([]ItemResponse{{Id: 5}}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/certifications/reviews", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_ReviewItem(t *testing.T) {
+	t.Run("Certified without body", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Certify", DecisionRequest{ItemId: 5}, false).
+			Return(ItemResponse{Id: 5, Decision: DecisionCertified}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/certifications/items/5/certify", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Revoked with reason", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		mockService.On("Revoke", DecisionRequest{ItemId: 5, Comment: "Moved to sales"}, true).
+			Return(ItemResponse{Id: 5, Decision: DecisionRevoked}, nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/certifications/items/5/revoke",
+			bytes.NewBufferString(`{"comment":"Moved to sales"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Own assignment", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Certify", DecisionRequest{ItemId: 5}, false).
+			Return(ItemResponse{}, common.ForbiddenError{Message: "you cannot review your own role assignment"})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/certifications/items/5/certify", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Campaign is closed", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("Certify", DecisionRequest{ItemId: 5}, false).
+			Return(ItemResponse{}, common.ConflictError{Message: "certification campaign 1 is completed"})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/certifications/items/5/certify", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/certifications/items/abc/revoke", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything)
+	})
+}
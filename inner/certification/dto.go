@@ -0,0 +1,212 @@
+package certification
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// состояния кампании
+const (
+	StatusActive    = "active"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+)
+
+// кто пересматривает назначения: руководитель сотрудника или владелец роли
+const (
+	ReviewerManager   = "manager"
+	ReviewerRoleOwner = "role_owner"
+)
+
+// решения по назначению; auto_action кампании - DecisionCertified или DecisionRevoked
+const (
+	DecisionPending   = "pending"
+	DecisionCertified = "certified"
+	DecisionRevoked   = "revoked"
+)
+
+// действия по непересмотренным к сроку назначениям
+const (
+	AutoActionCertify = "certify"
+	AutoActionRevoke  = "revoke"
+)
+
+type CampaignEntity struct {
+	Id                int64         `db:"id"`
+	Name              string        `db:"name"`
+	Description       *string       `db:"description"`
+	RoleIds           pq.Int64Array `db:"role_ids"`
+	DepartmentIds     pq.Int64Array `db:"department_ids"`
+	Reviewer          string        `db:"reviewer"`
+	AutoAction        string        `db:"auto_action"`
+	Deadline          time.Time     `db:"deadline"`
+	Status            string        `db:"status"`
+	CreatedBySub      *string       `db:"created_by_sub"`
+	CreatedByUsername *string       `db:"created_by_username"`
+	CreatedAt         time.Time     `db:"created_at"`
+	UpdatedAt         time.Time     `db:"updated_at"`
+	CompletedAt       *time.Time    `db:"completed_at"`
+	// число назначений кампании, только для чтения
+	ItemCount int `db:"item_count"`
+}
+
+func (e *CampaignEntity) toResponse() CampaignResponse {
+	return CampaignResponse{
+		Id:            e.Id,
+		Name:          e.Name,
+		Description:   e.Description,
+		RoleIds:       e.RoleIds,
+		DepartmentIds: e.DepartmentIds,
+		Reviewer:      e.Reviewer,
+		AutoAction:    e.AutoAction,
+		Deadline:      e.Deadline,
+		Status:        e.Status,
+		ItemCount:     e.ItemCount,
+		CreatedBy:     e.CreatedByUsername,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+		CompletedAt:   e.CompletedAt,
+	}
+}
+
+// CampaignResponse кампания пересмотра доступа
+type CampaignResponse struct {
+	Id            int64      `json:"id"`
+	Name          string     `json:"name"`
+	Description   *string    `json:"description,omitempty"`
+	RoleIds       []int64    `json:"role_ids"`
+	DepartmentIds []int64    `json:"department_ids"`
+	Reviewer      string     `json:"reviewer"`
+	AutoAction    string     `json:"auto_action"`
+	Deadline      time.Time  `json:"deadline"`
+	Status        string     `json:"status"`
+	ItemCount     int        `json:"item_count"`
+	CreatedBy     *string    `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+} // @name CertificationCampaignResponse
+
+type ItemEntity struct {
+	Id           int64  `db:"id"`
+	CampaignId   int64  `db:"campaign_id"`
+	AssignmentId *int64 `db:"assignment_id"`
+	EmployeeId   int64  `db:"employee_id"`
+	// имя сотрудника, только для чтения
+	EmployeeName string `db:"employee_name"`
+	RoleId       int64  `db:"role_id"`
+	// название роли, только для чтения
+	RoleName           string     `db:"role_name"`
+	ValidFrom          time.Time  `db:"valid_from"`
+	ValidTo            *time.Time `db:"valid_to"`
+	ReviewerEmployeeId *int64     `db:"reviewer_employee_id"`
+	// имя пересматривающего сотрудника, только для чтения
+	ReviewerName      *string    `db:"reviewer_name"`
+	Decision          string     `db:"decision"`
+	Auto              bool       `db:"auto"`
+	Comment           *string    `db:"comment"`
+	DecidedBySub      *string    `db:"decided_by_sub"`
+	DecidedByUsername *string    `db:"decided_by_username"`
+	DecidedAt         *time.Time `db:"decided_at"`
+	CreatedAt         time.Time  `db:"created_at"`
+	// состояние и срок кампании, только для чтения
+	CampaignStatus   string    `db:"campaign_status"`
+	CampaignDeadline time.Time `db:"campaign_deadline"`
+}
+
+func (e *ItemEntity) toResponse() ItemResponse {
+	return ItemResponse{
+		Id:                 e.Id,
+		CampaignId:         e.CampaignId,
+		AssignmentId:       e.AssignmentId,
+		EmployeeId:         e.EmployeeId,
+		EmployeeName:       e.EmployeeName,
+		RoleId:             e.RoleId,
+		RoleName:           e.RoleName,
+		ValidFrom:          e.ValidFrom,
+		ValidTo:            e.ValidTo,
+		ReviewerEmployeeId: e.ReviewerEmployeeId,
+		ReviewerName:       e.ReviewerName,
+		Decision:           e.Decision,
+		Auto:               e.Auto,
+		Comment:            e.Comment,
+		DecidedBy:          e.DecidedByUsername,
+		DecidedAt:          e.DecidedAt,
+		Deadline:           e.CampaignDeadline,
+	}
+}
+
+// ItemResponse назначение роли на пересмотре. Без reviewer_employee_id назначение пересматривают администраторы
+type ItemResponse struct {
+	Id                 int64      `json:"id"`
+	CampaignId         int64      `json:"campaign_id"`
+	AssignmentId       *int64     `json:"assignment_id"`
+	EmployeeId         int64      `json:"employee_id"`
+	EmployeeName       string     `json:"employee_name"`
+	RoleId             int64      `json:"role_id"`
+	RoleName           string     `json:"role_name"`
+	ValidFrom          time.Time  `json:"valid_from"`
+	ValidTo            *time.Time `json:"valid_to,omitempty"`
+	ReviewerEmployeeId *int64     `json:"reviewer_employee_id"`
+	ReviewerName       *string    `json:"reviewer_name"`
+	Decision           string     `json:"decision"`
+	// решение принято автоматически по истечении срока кампании
+	Auto      bool       `json:"auto"`
+	Comment   *string    `json:"comment,omitempty"`
+	DecidedBy *string    `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	Deadline  time.Time  `json:"deadline"`
+} // @name CertificationItemResponse
+
+// ReviewerSummary ход пересмотра у одного пересматривающего; без reviewer_employee_id - у администраторов
+type ReviewerSummary struct {
+	ReviewerEmployeeId *int64  `json:"reviewer_employee_id" db:"reviewer_employee_id"`
+	ReviewerName       *string `json:"reviewer_name" db:"reviewer_name"`
+	Total              int     `json:"total" db:"total"`
+	Pending            int     `json:"pending" db:"pending"`
+	Certified          int     `json:"certified" db:"certified"`
+	Revoked            int     `json:"revoked" db:"revoked"`
+} // @name CertificationReviewerSummary
+
+// SummaryResponse итоги кампании. AutoCertified и AutoRevoked входят в Certified и Revoked;
+// Progress - доля пересмотренных назначений в процентах
+type SummaryResponse struct {
+	Campaign      CampaignResponse  `json:"campaign"`
+	Total         int               `json:"total"`
+	Pending       int               `json:"pending"`
+	Certified     int               `json:"certified"`
+	Revoked       int               `json:"revoked"`
+	AutoCertified int               `json:"auto_certified"`
+	AutoRevoked   int               `json:"auto_revoked"`
+	Progress      int               `json:"progress"`
+	Reviewers     []ReviewerSummary `json:"reviewers"`
+} // @name CertificationSummaryResponse
+
+// счётчики решений кампании
+type decisionCounts struct {
+	Total         int `db:"total"`
+	Pending       int `db:"pending"`
+	Certified     int `db:"certified"`
+	Revoked       int `db:"revoked"`
+	AutoCertified int `db:"auto_certified"`
+	AutoRevoked   int `db:"auto_revoked"`
+}
+
+// CreateRequest запуск кампании: в неё попадают действующие на момент запуска назначения ролей RoleIds
+// сотрудникам отделов DepartmentIds (вместе с подотделами); пустой список не ограничивает область
+type CreateRequest struct {
+	Name          string    `json:"name" validate:"required,min=2,max=255" example:"Q3 2025 access review"`
+	Description   string    `json:"description" validate:"max=1000" example:"Quarterly review of finance roles"`
+	RoleIds       []int64   `json:"role_ids" validate:"max=1000,dive,min=1" example:"1,2"`
+	DepartmentIds []int64   `json:"department_ids" validate:"max=1000,dive,min=1" example:"3"`
+	Reviewer      string    `json:"reviewer" validate:"omitempty,oneof=manager role_owner" example:"manager"`
+	AutoAction    string    `json:"auto_action" validate:"omitempty,oneof=certify revoke" example:"revoke"`
+	Deadline      time.Time `json:"deadline" validate:"required" example:"2025-09-30T23:59:59Z"`
+} // @name CertificationCampaignCreateRequest
+
+// DecisionRequest решение по назначению; при отзыве комментарий обязателен
+type DecisionRequest struct {
+	ItemId  int64  `json:"-"`
+	Comment string `json:"comment" validate:"max=1000" example:"Still needed for payroll"`
+} // @name CertificationDecisionRequest
@@ -0,0 +1,213 @@
+package certification
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+// кампания с числом назначений
+const selectCampaign = `SELECT c.*,
+		(SELECT count(*) FROM certification_item i WHERE i.campaign_id = c.id) AS item_count
+	FROM certification_campaign c`
+
+// назначение с именами сотрудника, роли и пересматривающего и состоянием кампании
+const selectItem = `SELECT i.*, e.name AS employee_name, r.name AS role_name, rv.name AS reviewer_name,
+		c.status AS campaign_status, c.deadline AS campaign_deadline
+	FROM certification_item i
+	JOIN certification_campaign c ON c.id = i.campaign_id
+	JOIN employee e ON e.id = i.employee_id
+	JOIN role r ON r.id = i.role_id
+	LEFT JOIN employee rv ON rv.id = i.reviewer_employee_id`
+
+func NewCertificationRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+// Транзакционные методы
+func (r *Repository) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// Найти действующего сотрудника по адресу почты пользователя; адреса сравниваются без учёта регистра
+func (r *Repository) FindEmployeeIdByEmail(ctx context.Context, email string) (id int64, err error) {
+	err = r.db.GetContext(ctx, &id,
+		`SELECT id FROM employee
+		WHERE lower(email) = lower($1) AND deleted_at IS NULL AND status <> 'terminated'
+		ORDER BY id LIMIT 1`,
+		email)
+	return id, err
+}
+
+func (r *Repository) CreateCampaignTx(ctx context.Context, tx *sqlx.Tx, campaign CampaignEntity) (id int64, err error) {
+	err = tx.GetContext(ctx, &id,
+		`INSERT INTO certification_campaign (name, description, role_ids, department_ids, reviewer, auto_action, deadline,
+			created_by_sub, created_by_username)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`,
+		campaign.Name, campaign.Description, campaign.RoleIds, campaign.DepartmentIds, campaign.Reviewer,
+		campaign.AutoAction, campaign.Deadline, campaign.CreatedBySub, campaign.CreatedByUsername)
+	return id, err
+}
+
+// Зафиксировать в кампании действующие назначения ролей из её области. Пересматривающий - руководитель
+// сотрудника или владелец роли; назначения без руководителя, роли без владельца и назначения самому
+// владельцу пересматривают администраторы. Возвращает число зафиксированных назначений
+func (r *Repository) SnapshotItemsTx(ctx context.Context, tx *sqlx.Tx, campaign CampaignEntity) (int64, error) {
+	result, err := tx.ExecContext(ctx,
+		`WITH RECURSIVE departments AS (
+			SELECT id, ARRAY[id] AS path FROM department WHERE id = ANY ($3)
+			UNION ALL
+			SELECT d.id, s.path || d.id
+			FROM department d JOIN departments s ON d.parent_id = s.id
+			WHERE NOT d.id = ANY (s.path)
+		)
+		INSERT INTO certification_item (campaign_id, assignment_id, employee_id, role_id, valid_from, valid_to,
+			reviewer_employee_id)
+		SELECT $1, er.id, er.employee_id, er.role_id, er.valid_from, er.valid_to,
+			CASE $4 WHEN 'manager' THEN e.manager_id WHEN 'role_owner' THEN NULLIF(r.owner_id, e.id) END
+		FROM employee_role er
+		JOIN employee e ON e.id = er.employee_id AND e.deleted_at IS NULL
+		JOIN role r ON r.id = er.role_id AND r.deleted_at IS NULL
+		WHERE er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+			AND (cardinality($2::bigint[]) = 0 OR er.role_id = ANY ($2))
+			AND (cardinality($3::bigint[]) = 0 OR e.department_id IN (SELECT id FROM departments))
+		ORDER BY er.id`,
+		campaign.Id, campaign.RoleIds, campaign.DepartmentIds, campaign.Reviewer)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *Repository) FindCampaignById(ctx context.Context, id int64) (campaign CampaignEntity, err error) {
+	err = r.db.GetContext(ctx, &campaign, selectCampaign+" WHERE c.id = $1", id)
+	return campaign, err
+}
+
+// Найти кампании; status = "" - все состояния
+func (r *Repository) FindCampaigns(ctx context.Context, status string) ([]CampaignEntity, error) {
+	var campaigns []CampaignEntity
+	err := r.db.SelectContext(ctx, &campaigns,
+		selectCampaign+" WHERE ($1 = '' OR c.status = $1) ORDER BY c.id DESC", status)
+	return campaigns, err
+}
+
+// Отменить активную кампанию; false - кампании нет или она уже завершена
+func (r *Repository) CancelCampaign(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE certification_campaign SET status = 'cancelled', completed_at = now() WHERE id = $1 AND status = 'active'",
+		id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// Найти назначения кампании; decision = "" - все решения
+func (r *Repository) FindItems(ctx context.Context, campaignId int64, decision string) ([]ItemEntity, error) {
+	var items []ItemEntity
+	err := r.db.SelectContext(ctx, &items,
+		selectItem+" WHERE i.campaign_id = $1 AND ($2 = '' OR i.decision = $2) ORDER BY i.id",
+		campaignId, decision)
+	return items, err
+}
+
+// Найти назначения активных кампаний, которые пересматривает пользователь: назначенные его сотруднику,
+// а администраторам - и назначения без пересматривающего. Свои назначения не пересматриваются
+func (r *Repository) FindReviewItems(ctx context.Context, employeeId int64, admin bool, decision string) ([]ItemEntity, error) {
+	var items []ItemEntity
+	err := r.db.SelectContext(ctx, &items,
+		selectItem+` WHERE c.status = 'active' AND i.employee_id <> $1
+			AND (i.reviewer_employee_id = $1 OR ($2 AND i.reviewer_employee_id IS NULL))
+			AND ($3 = '' OR i.decision = $3)
+		ORDER BY c.deadline, i.id`,
+		employeeId, admin, decision)
+	return items, err
+}
+
+func (r *Repository) FindItemById(ctx context.Context, id int64) (item ItemEntity, err error) {
+	err = r.db.GetContext(ctx, &item, selectItem+" WHERE i.id = $1", id)
+	return item, err
+}
+
+// Найти назначение по id и заблокировать его строку до конца транзакции
+func (r *Repository) FindItemForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (item ItemEntity, err error) {
+	err = tx.GetContext(ctx, &item, selectItem+" WHERE i.id = $1 FOR UPDATE OF i", id)
+	return item, err
+}
+
+// Сохранить решение по назначению
+func (r *Repository) DecideItemTx(ctx context.Context, tx *sqlx.Tx, item ItemEntity) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE certification_item
+		SET decision = $2, auto = $3, comment = $4, decided_by_sub = $5, decided_by_username = $6, decided_at = now()
+		WHERE id = $1`,
+		item.Id, item.Decision, item.Auto, item.Comment, item.DecidedBySub, item.DecidedByUsername)
+	return err
+}
+
+func (r *Repository) CountDecisions(ctx context.Context, campaignId int64) (counts decisionCounts, err error) {
+	err = r.db.GetContext(ctx, &counts,
+		`SELECT count(*) AS total,
+			count(*) FILTER (WHERE decision = 'pending') AS pending,
+			count(*) FILTER (WHERE decision = 'certified') AS certified,
+			count(*) FILTER (WHERE decision = 'revoked') AS revoked,
+			count(*) FILTER (WHERE decision = 'certified' AND auto) AS auto_certified,
+			count(*) FILTER (WHERE decision = 'revoked' AND auto) AS auto_revoked
+		FROM certification_item WHERE campaign_id = $1`,
+		campaignId)
+	return counts, err
+}
+
+// Ход пересмотра по пересматривающим; сначала те, у кого больше непересмотренных назначений
+func (r *Repository) FindReviewerSummaries(ctx context.Context, campaignId int64) ([]ReviewerSummary, error) {
+	var summaries []ReviewerSummary
+	err := r.db.SelectContext(ctx, &summaries,
+		`SELECT i.reviewer_employee_id, rv.name AS reviewer_name,
+			count(*) AS total,
+			count(*) FILTER (WHERE i.decision = 'pending') AS pending,
+			count(*) FILTER (WHERE i.decision = 'certified') AS certified,
+			count(*) FILTER (WHERE i.decision = 'revoked') AS revoked
+		FROM certification_item i
+		LEFT JOIN employee rv ON rv.id = i.reviewer_employee_id
+		WHERE i.campaign_id = $1
+		GROUP BY i.reviewer_employee_id, rv.name
+		ORDER BY pending DESC, i.reviewer_employee_id NULLS LAST`,
+		campaignId)
+	return summaries, err
+}
+
+// Найти активные кампании, срок которых истёк
+func (r *Repository) FindDueCampaignIds(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	err := r.db.SelectContext(ctx, &ids,
+		"SELECT id FROM certification_campaign WHERE status = 'active' AND deadline <= now() ORDER BY deadline")
+	return ids, err
+}
+
+func (r *Repository) FindPendingItemIds(ctx context.Context, campaignId int64) ([]int64, error) {
+	var ids []int64
+	err := r.db.SelectContext(ctx, &ids,
+		"SELECT id FROM certification_item WHERE campaign_id = $1 AND decision = 'pending' ORDER BY id", campaignId)
+	return ids, err
+}
+
+// Завершить активную кампанию, если в ней не осталось непересмотренных назначений
+func (r *Repository) CompleteCampaign(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE certification_campaign SET status = 'completed', completed_at = now()
+		WHERE id = $1 AND status = 'active'
+			AND NOT EXISTS (SELECT 1 FROM certification_item WHERE campaign_id = $1 AND decision = 'pending')`,
+		id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
@@ -0,0 +1,523 @@
+package certification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/validator"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo      Repo
+	revoker   Revoker
+	validator Validator
+	logger    *common.Logger
+}
+
+type Repo interface {
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindEmployeeIdByEmail(ctx context.Context, email string) (int64, error)
+	CreateCampaignTx(ctx context.Context, tx *sqlx.Tx, campaign CampaignEntity) (int64, error)
+	SnapshotItemsTx(ctx context.Context, tx *sqlx.Tx, campaign CampaignEntity) (int64, error)
+	FindCampaignById(ctx context.Context, id int64) (CampaignEntity, error)
+	FindCampaigns(ctx context.Context, status string) ([]CampaignEntity, error)
+	CancelCampaign(ctx context.Context, id int64) (bool, error)
+	FindItems(ctx context.Context, campaignId int64, decision string) ([]ItemEntity, error)
+	FindReviewItems(ctx context.Context, employeeId int64, admin bool, decision string) ([]ItemEntity, error)
+	FindItemById(ctx context.Context, id int64) (ItemEntity, error)
+	FindItemForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (ItemEntity, error)
+	DecideItemTx(ctx context.Context, tx *sqlx.Tx, item ItemEntity) error
+	CountDecisions(ctx context.Context, campaignId int64) (decisionCounts, error)
+	FindReviewerSummaries(ctx context.Context, campaignId int64) ([]ReviewerSummary, error)
+	FindDueCampaignIds(ctx context.Context) ([]int64, error)
+	FindPendingItemIds(ctx context.Context, campaignId int64) ([]int64, error)
+	CompleteCampaign(ctx context.Context, id int64) (bool, error)
+}
+
+// интерфейс отзыва назначений (реализуется employee.Service): отзыв по итогам пересмотра
+// выполняется так же, как администратором, с записью в журнал аудита
+type Revoker interface {
+	RevokeRoleAssignment(ctx context.Context, employeeId, assignmentId int64) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// пользователь, пересматривающий назначения
+type caller struct {
+	sub      string
+	username string
+	// сотрудник пользователя (сопоставляется по адресу почты); 0 - не найден
+	employeeId int64
+	admin      bool
+}
+
+// функция-конструктор
+func NewService(repo Repo, revoker Revoker, validator Validator, logger *common.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		revoker:   revoker,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// Метод для запуска кампании: действующие назначения из области кампании фиксируются для пересмотра
+func (svc *Service) Create(ctx context.Context, request CreateRequest) (response CampaignResponse, err error) {
+	svc.logger.Info("Creating certification campaign",
+		zap.String("name", request.Name),
+		zap.Int64s("role_ids", request.RoleIds),
+		zap.Int64s("department_ids", request.DepartmentIds))
+
+	if err := svc.validateRequest(request); err != nil {
+		return CampaignResponse{}, err
+	}
+	if !request.Deadline.After(time.Now()) {
+		return CampaignResponse{}, common.RequestValidationError{Message: "deadline must be in the future"}
+	}
+
+	actor := common.ActorFromContext(ctx)
+	campaign := CampaignEntity{
+		Name:              request.Name,
+		Description:       nullable(request.Description),
+		RoleIds:           append([]int64{}, request.RoleIds...),
+		DepartmentIds:     append([]int64{}, request.DepartmentIds...),
+		Reviewer:          request.Reviewer,
+		AutoAction:        request.AutoAction,
+		Deadline:          request.Deadline,
+		CreatedBySub:      nullable(actor.Subject),
+		CreatedByUsername: nullable(actor.Username),
+	}
+	if campaign.Reviewer == "" {
+		campaign.Reviewer = ReviewerManager
+	}
+	// по умолчанию непересмотренный доступ отзывается
+	if campaign.AutoAction == "" {
+		campaign.AutoAction = AutoActionRevoke
+	}
+
+	err = func() (err error) {
+		tx, err := svc.repo.BeginTransaction(ctx)
+		if err != nil {
+			svc.logger.Error("Failed to begin transaction for certification campaign", zap.Error(err))
+			return fmt.Errorf("error create certification campaign: error creating transaction: %w", err)
+		}
+		defer func() {
+			err = svc.finishTransaction(tx, err, campaign.Id)
+		}()
+
+		campaign.Id, err = svc.repo.CreateCampaignTx(ctx, tx, campaign)
+		if err != nil {
+			svc.logger.Error("Failed to create certification campaign", zap.Error(err))
+			return fmt.Errorf("error creating certification campaign: %w", err)
+		}
+		items, err := svc.repo.SnapshotItemsTx(ctx, tx, campaign)
+		if err != nil {
+			svc.logger.Error("Failed to snapshot role assignments", zap.Int64("id", campaign.Id), zap.Error(err))
+			return fmt.Errorf("error snapshotting role assignments of campaign %d: %w", campaign.Id, err)
+		}
+		if items == 0 {
+			return common.RequestValidationError{Message: "no active role assignments match the campaign scope"}
+		}
+		svc.logger.Info("Certification campaign created", zap.Int64("id", campaign.Id), zap.Int64("items", items))
+		return nil
+	}()
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	return svc.FindCampaignById(ctx, campaign.Id)
+}
+
+// Метод для получения кампаний; status = "" - все состояния
+func (svc *Service) FindCampaigns(ctx context.Context, status string) ([]CampaignResponse, error) {
+	svc.logger.Debug("Finding certification campaigns", zap.String("status", status))
+
+	campaigns, err := svc.repo.FindCampaigns(ctx, status)
+	if err != nil {
+		svc.logger.Error("Failed to find certification campaigns", zap.Error(err))
+		return nil, fmt.Errorf("error finding certification campaigns: %w", err)
+	}
+	responses := make([]CampaignResponse, len(campaigns))
+	for i, campaign := range campaigns {
+		responses[i] = campaign.toResponse()
+	}
+	return responses, nil
+}
+
+func (svc *Service) FindCampaignById(ctx context.Context, id int64) (CampaignResponse, error) {
+	campaign, err := svc.findCampaign(ctx, id)
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	return campaign.toResponse(), nil
+}
+
+// Метод для отмены активной кампании; принятые решения сохраняются, непересмотренные назначения остаются как есть
+func (svc *Service) Cancel(ctx context.Context, id int64) (CampaignResponse, error) {
+	svc.logger.Info("Cancelling certification campaign", zap.Int64("id", id))
+
+	cancelled, err := svc.repo.CancelCampaign(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to cancel certification campaign", zap.Int64("id", id), zap.Error(err))
+		return CampaignResponse{}, fmt.Errorf("error cancelling certification campaign %d: %w", id, err)
+	}
+	campaign, err := svc.findCampaign(ctx, id)
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	if !cancelled {
+		return CampaignResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("certification campaign %d is already %s", id, campaign.Status),
+		}
+	}
+	return campaign.toResponse(), nil
+}
+
+// Метод для получения назначений кампании; decision = "" - все решения
+func (svc *Service) FindItems(ctx context.Context, campaignId int64, decision string) ([]ItemResponse, error) {
+	svc.logger.Debug("Finding certification items",
+		zap.Int64("campaign_id", campaignId),
+		zap.String("decision", decision))
+
+	if _, err := svc.findCampaign(ctx, campaignId); err != nil {
+		return nil, err
+	}
+	items, err := svc.repo.FindItems(ctx, campaignId, decision)
+	if err != nil {
+		svc.logger.Error("Failed to find certification items", zap.Int64("campaign_id", campaignId), zap.Error(err))
+		return nil, fmt.Errorf("error finding items of certification campaign %d: %w", campaignId, err)
+	}
+	return toItemResponses(items), nil
+}
+
+// Метод для получения назначений, которые пересматривает пользователь, в активных кампаниях
+func (svc *Service) FindReviews(ctx context.Context, decision string, admin bool) ([]ItemResponse, error) {
+	svc.logger.Debug("Finding certification reviews", zap.String("decision", decision))
+
+	c, err := svc.resolveCaller(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+	if c.employeeId == 0 && !c.admin {
+		return []ItemResponse{}, nil
+	}
+	items, err := svc.repo.FindReviewItems(ctx, c.employeeId, c.admin, decision)
+	if err != nil {
+		svc.logger.Error("Failed to find certification reviews", zap.Error(err))
+		return nil, fmt.Errorf("error finding certification reviews: %w", err)
+	}
+	return toItemResponses(items), nil
+}
+
+// Метод для подтверждения назначения
+func (svc *Service) Certify(ctx context.Context, request DecisionRequest, admin bool) (ItemResponse, error) {
+	return svc.review(ctx, request, DecisionCertified, admin)
+}
+
+// Метод для отзыва назначения: роль отзывается у сотрудника сразу; причина отзыва обязательна
+func (svc *Service) Revoke(ctx context.Context, request DecisionRequest, admin bool) (ItemResponse, error) {
+	if request.Comment == "" {
+		return ItemResponse{}, common.RequestValidationError{Message: "comment is required to revoke an assignment"}
+	}
+	return svc.review(ctx, request, DecisionRevoked, admin)
+}
+
+func (svc *Service) review(ctx context.Context, request DecisionRequest, decision string, admin bool) (ItemResponse, error) {
+	svc.logger.Info("Reviewing certification item",
+		zap.Int64("item_id", request.ItemId),
+		zap.String("decision", decision))
+
+	if err := svc.validateRequest(request); err != nil {
+		return ItemResponse{}, err
+	}
+	c, err := svc.resolveCaller(ctx, admin)
+	if err != nil {
+		return ItemResponse{}, err
+	}
+
+	if err := svc.decide(ctx, request.ItemId, decision, request.Comment, &c); err != nil {
+		return ItemResponse{}, err
+	}
+
+	svc.logger.Info("Certification item reviewed",
+		zap.Int64("item_id", request.ItemId),
+		zap.String("decision", decision))
+	item, err := svc.repo.FindItemById(ctx, request.ItemId)
+	if err != nil {
+		svc.logger.Error("Failed to find certification item", zap.Int64("item_id", request.ItemId), zap.Error(err))
+		return ItemResponse{}, fmt.Errorf("error finding certification item %d: %w", request.ItemId, err)
+	}
+	return item.toResponse(), nil
+}
+
+// решение по назначению. c = nil - автоматическое решение по истечении срока кампании.
+// Роль отзывается до сохранения решения в отдельной транзакции сервиса сотрудников:
+// если отозвать не удалось, решение не сохраняется
+func (svc *Service) decide(ctx context.Context, itemId int64, decision, comment string, c *caller) (err error) {
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for certification decision", zap.Int64("item_id", itemId), zap.Error(err))
+		return fmt.Errorf("error decide certification item: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, itemId)
+	}()
+
+	item, err := svc.repo.FindItemForUpdateTx(ctx, tx, itemId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return itemNotFound(itemId)
+		}
+		svc.logger.Error("Failed to find certification item", zap.Int64("item_id", itemId), zap.Error(err))
+		return fmt.Errorf("error finding certification item %d: %w", itemId, err)
+	}
+
+	if c == nil {
+		// назначение могли пересмотреть, пока кампания ждала завершения
+		if item.Decision != DecisionPending || item.CampaignStatus != StatusActive {
+			return nil
+		}
+	} else if err = checkReview(item, *c); err != nil {
+		svc.logger.Warn("Certification decision rejected", zap.Int64("item_id", itemId), zap.Error(err))
+		return err
+	}
+
+	if decision == DecisionRevoked && item.AssignmentId != nil {
+		err = svc.revoker.RevokeRoleAssignment(ctx, item.EmployeeId, *item.AssignmentId)
+		// назначение уже закончилось или удалено - отзывать нечего
+		if errors.As(err, &common.NotFoundError{}) {
+			err = nil
+		}
+		if err != nil {
+			svc.logger.Error("Failed to revoke role assignment",
+				zap.Int64("item_id", itemId),
+				zap.Int64("assignment_id", *item.AssignmentId),
+				zap.Error(err))
+			return err
+		}
+	}
+
+	item.Decision, item.Comment, item.Auto = decision, nullable(comment), c == nil
+	if c != nil {
+		item.DecidedBySub, item.DecidedByUsername = nullable(c.sub), nullable(c.username)
+	}
+	if err = svc.repo.DecideItemTx(ctx, tx, item); err != nil {
+		svc.logger.Error("Failed to save certification decision", zap.Int64("item_id", itemId), zap.Error(err))
+		return fmt.Errorf("error saving decision of certification item %d: %w", itemId, err)
+	}
+	return nil
+}
+
+// Назначение пересматривает пересматривающий сотрудник или администратор; назначения без
+// пересматривающего - администраторы. Недоступное назначение не раскрывается. Своё назначение
+// не пересматривается даже администратором
+func checkReview(item ItemEntity, c caller) error {
+	isReviewer := c.employeeId != 0 && item.ReviewerEmployeeId != nil && *item.ReviewerEmployeeId == c.employeeId
+	if !isReviewer && !c.admin {
+		return itemNotFound(item.Id)
+	}
+	if item.CampaignStatus != StatusActive {
+		return common.ConflictError{Message: fmt.Sprintf("certification campaign %d is %s", item.CampaignId, item.CampaignStatus)}
+	}
+	if !item.CampaignDeadline.After(time.Now()) {
+		return common.ConflictError{Message: fmt.Sprintf("deadline of certification campaign %d has passed", item.CampaignId)}
+	}
+	if item.Decision != DecisionPending {
+		return common.ConflictError{Message: fmt.Sprintf("certification item %d is already %s", item.Id, item.Decision)}
+	}
+	if c.employeeId != 0 && c.employeeId == item.EmployeeId {
+		return common.ForbiddenError{Message: "you cannot review your own role assignment"}
+	}
+	return nil
+}
+
+// Метод для получения итогов кампании
+func (svc *Service) Summary(ctx context.Context, campaignId int64) (SummaryResponse, error) {
+	svc.logger.Debug("Building certification campaign summary", zap.Int64("campaign_id", campaignId))
+
+	campaign, err := svc.findCampaign(ctx, campaignId)
+	if err != nil {
+		return SummaryResponse{}, err
+	}
+	counts, err := svc.repo.CountDecisions(ctx, campaignId)
+	if err != nil {
+		svc.logger.Error("Failed to count certification decisions", zap.Int64("campaign_id", campaignId), zap.Error(err))
+		return SummaryResponse{}, fmt.Errorf("error counting decisions of certification campaign %d: %w", campaignId, err)
+	}
+	reviewers, err := svc.repo.FindReviewerSummaries(ctx, campaignId)
+	if err != nil {
+		svc.logger.Error("Failed to find certification reviewers", zap.Int64("campaign_id", campaignId), zap.Error(err))
+		return SummaryResponse{}, fmt.Errorf("error finding reviewers of certification campaign %d: %w", campaignId, err)
+	}
+
+	summary := SummaryResponse{
+		Campaign:      campaign.toResponse(),
+		Total:         counts.Total,
+		Pending:       counts.Pending,
+		Certified:     counts.Certified,
+		Revoked:       counts.Revoked,
+		AutoCertified: counts.AutoCertified,
+		AutoRevoked:   counts.AutoRevoked,
+		Reviewers:     reviewers,
+	}
+	if summary.Reviewers == nil {
+		summary.Reviewers = []ReviewerSummary{}
+	}
+	if counts.Total > 0 {
+		summary.Progress = (counts.Total - counts.Pending) * 100 / counts.Total
+	}
+	return summary, nil
+}
+
+// CloseDue завершает кампании, срок которых истёк: по непересмотренным назначениям принимается
+// решение auto_action кампании. Кампания, по назначениям которой решение принять не удалось,
+// остаётся активной и завершается на следующем проходе. Вызывается периодически (см. Closer)
+func (svc *Service) CloseDue(ctx context.Context) (int, error) {
+	ids, err := svc.repo.FindDueCampaignIds(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error finding due certification campaigns: %w", err)
+	}
+
+	completed := 0
+	var errs []error
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return completed, ctx.Err()
+		}
+		done, err := svc.closeCampaign(ctx, id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if done {
+			completed++
+			svc.logger.Info("Certification campaign completed", zap.Int64("id", id))
+		}
+	}
+	return completed, errors.Join(errs...)
+}
+
+func (svc *Service) closeCampaign(ctx context.Context, id int64) (bool, error) {
+	campaign, err := svc.findCampaign(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	decision := DecisionRevoked
+	if campaign.AutoAction == AutoActionCertify {
+		decision = DecisionCertified
+	}
+
+	itemIds, err := svc.repo.FindPendingItemIds(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("error finding pending items of certification campaign %d: %w", id, err)
+	}
+	var errs []error
+	for _, itemId := range itemIds {
+		if err := svc.decide(ctx, itemId, decision, "", nil); err != nil {
+			errs = append(errs, fmt.Errorf("certification item %d: %w", itemId, err))
+		}
+	}
+	if len(errs) > 0 {
+		return false, fmt.Errorf("error closing certification campaign %d: %w", id, errors.Join(errs...))
+	}
+
+	done, err := svc.repo.CompleteCampaign(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("error completing certification campaign %d: %w", id, err)
+	}
+	return done, nil
+}
+
+// определяет пользователя по инициатору запроса; сотрудник пользователя ищется по адресу почты
+func (svc *Service) resolveCaller(ctx context.Context, admin bool) (caller, error) {
+	actor := common.ActorFromContext(ctx)
+	c := caller{sub: actor.Subject, username: actor.Username, admin: admin}
+	if actor.Email == "" {
+		return c, nil
+	}
+
+	id, err := svc.repo.FindEmployeeIdByEmail(ctx, actor.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, nil
+	}
+	if err != nil {
+		svc.logger.Error("Failed to find employee of user", zap.String("username", actor.Username), zap.Error(err))
+		return caller{}, fmt.Errorf("error finding employee of user %s: %w", actor.Username, err)
+	}
+	c.employeeId = id
+	return c, nil
+}
+
+func (svc *Service) findCampaign(ctx context.Context, id int64) (CampaignEntity, error) {
+	campaign, err := svc.repo.FindCampaignById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return CampaignEntity{}, common.NotFoundError{Message: fmt.Sprintf("certification campaign with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find certification campaign", zap.Int64("id", id), zap.Error(err))
+		return CampaignEntity{}, fmt.Errorf("error finding certification campaign with id %d: %w", id, err)
+	}
+	return campaign, nil
+}
+
+// валидация запросов сервиса
+func (svc *Service) validateRequest(request any) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Certification request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return nil
+}
+
+func (svc *Service) finishTransaction(tx *sqlx.Tx, err error, id int64) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			svc.logger.Error("Failed to rollback transaction",
+				zap.Int64("id", id),
+				zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		svc.logger.Error("Failed to commit transaction",
+			zap.Int64("id", id),
+			zap.Error(commitErr))
+		return commitErr
+	}
+	return nil
+}
+
+func toItemResponses(items []ItemEntity) []ItemResponse {
+	responses := make([]ItemResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.toResponse()
+	}
+	return responses
+}
+
+func itemNotFound(id int64) error {
+	return common.NotFoundError{Message: fmt.Sprintf("certification item with id %d not found", id)}
+}
+
+func nullable(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,469 @@
+package certification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/validator"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// мок репозитория; транзакции открываются в sqlmock, ожидания Begin/Commit/Rollback задаются в тесте
+type MockRepo struct {
+	mock.Mock
+	db *sqlx.DB
+}
+
+func (m *MockRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	return m.db.Beginx()
+}
+
+func (m *MockRepo) FindEmployeeIdByEmail(ctx context.Context, email string) (int64, error) {
+	args := m.Called(email)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) CreateCampaignTx(ctx context.Context, tx *sqlx.Tx, campaign CampaignEntity) (int64, error) {
+	args := m.Called(campaign)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) SnapshotItemsTx(ctx context.Context, tx *sqlx.Tx, campaign CampaignEntity) (int64, error) {
+	args := m.Called(campaign)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindCampaignById(ctx context.Context, id int64) (CampaignEntity, error) {
+	args := m.Called(id)
+	return args.Get(0).(CampaignEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindCampaigns(ctx context.Context, status string) ([]CampaignEntity, error) {
+	args := m.Called(status)
+	return args.Get(0).([]CampaignEntity), args.Error(1)
+}
+
+func (m *MockRepo) CancelCampaign(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindItems(ctx context.Context, campaignId int64, decision string) ([]ItemEntity, error) {
+	args := m.Called(campaignId, decision)
+	return args.Get(0).([]ItemEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindReviewItems(ctx context.Context, employeeId int64, admin bool, decision string) ([]ItemEntity, error) {
+	args := m.Called(employeeId, admin, decision)
+	return args.Get(0).([]ItemEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindItemById(ctx context.Context, id int64) (ItemEntity, error) {
+	args := m.Called(id)
+	return args.Get(0).(ItemEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindItemForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (ItemEntity, error) {
+	args := m.Called(id)
+	return args.Get(0).(ItemEntity), args.Error(1)
+}
+
+func (m *MockRepo) DecideItemTx(ctx context.Context, tx *sqlx.Tx, item ItemEntity) error {
+	return m.Called(item).Error(0)
+}
+
+func (m *MockRepo) CountDecisions(ctx context.Context, campaignId int64) (decisionCounts, error) {
+	args := m.Called(campaignId)
+	return args.Get(0).(decisionCounts), args.Error(1)
+}
+
+func (m *MockRepo) FindReviewerSummaries(ctx context.Context, campaignId int64) ([]ReviewerSummary, error) {
+	args := m.Called(campaignId)
+	return args.Get(0).([]ReviewerSummary), args.Error(1)
+}
+
+func (m *MockRepo) FindDueCampaignIds(ctx context.Context) ([]int64, error) {
+	args := m.Called()
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) FindPendingItemIds(ctx context.Context, campaignId int64) ([]int64, error) {
+	args := m.Called(campaignId)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) CompleteCampaign(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockRevoker struct {
+	mock.Mock
+}
+
+func (m *MockRevoker) RevokeRoleAssignment(ctx context.Context, employeeId, assignmentId int64) error {
+	return m.Called(employeeId, assignmentId).Error(0)
+}
+
+func createTestLogger() *common.Logger {
+	cfg := common.Config{
+		DbDriverName:   "postgres",
+		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
+		AppName:        "test_app",
+		AppVersion:     "1.0.0",
+		LogLevel:       "DEBUG",
+		LogDevelopMode: true,
+	}
+	return common.NewLogger(cfg)
+}
+
+func newTestService(t *testing.T) (*Service, *MockRepo, *MockRevoker, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	repo := &MockRepo{db: sqlx.NewDb(db, "postgres")}
+	revoker := new(MockRevoker)
+	return NewService(repo, revoker, validator.New(), createTestLogger()), repo, revoker, sqlMock
+}
+
+func actorContext(sub, email string) context.Context {
+	return common.WithActor(context.Background(), common.Actor{Subject: sub, Username: sub, Email: email})
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// назначение активной кампании на пересмотре у руководителя 2
+func pendingItem() ItemEntity {
+	return ItemEntity{
+		Id:                 5,
+		CampaignId:         1,
+		AssignmentId:       ptr(int64(40)),
+		EmployeeId:         7,
+		RoleId:             3,
+		ReviewerEmployeeId: ptr(int64(2)),
+		Decision:           DecisionPending,
+		CampaignStatus:     StatusActive,
+		CampaignDeadline:   time.Now().Add(time.Hour),
+	}
+}
+
+func TestService_Create(t *testing.T) {
+	t.Run("Campaign snapshots assignments with default policy", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		deadline := time.Now().Add(24 * time.Hour)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("CreateCampaignTx", mock.MatchedBy(func(campaign CampaignEntity) bool {
+			return campaign.Reviewer == ReviewerManager && campaign.AutoAction == AutoActionRevoke &&
+				campaign.RoleIds != nil && campaign.DepartmentIds != nil && *campaign.CreatedBySub == "sub-admin"
+		})).Return(int64(1), nil)
+		repo.On("SnapshotItemsTx", mock.MatchedBy(func(campaign CampaignEntity) bool {
+			return campaign.Id == 1
+		})).Return(int64(12), nil)
+		repo.On("FindCampaignById", int64(1)).Return(CampaignEntity{Id: 1, Status: StatusActive, ItemCount: 12}, nil)
+
+		response, err := svc.Create(actorContext("sub-admin", ""), CreateRequest{Name: "Q3 review", Deadline: deadline})
+
+		require.NoError(t, err)
+		assert.Equal(t, 12, response.ItemCount)
+		repo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Empty scope is rejected", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("CreateCampaignTx", mock.Anything).Return(int64(1), nil)
+		repo.On("SnapshotItemsTx", mock.Anything).Return(int64(0), nil)
+
+		_, err := svc.Create(context.Background(), CreateRequest{
+			Name: "Q3 review", RoleIds: []int64{99}, Deadline: time.Now().Add(time.Hour),
+		})
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Deadline must be in the future", func(t *testing.T) {
+		svc, _, _, sqlMock := newTestService(t)
+
+		_, err := svc.Create(context.Background(), CreateRequest{Name: "Q3 review", Deadline: time.Now().Add(-time.Hour)})
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Review(t *testing.T) {
+	t.Run("Manager certifies the assignment", func(t *testing.T) {
+		svc, repo, revoker, sqlMock := newTestService(t)
+		ctx := actorContext("sub-2", "boss@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindEmployeeIdByEmail", "boss@example.com").Return(int64(2), nil)
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+		repo.On("DecideItemTx", mock.MatchedBy(func(item ItemEntity) bool {
+			return item.Decision == DecisionCertified && !item.Auto && *item.DecidedBySub == "sub-2"
+		})).Return(nil)
+		repo.On("FindItemById", int64(5)).Return(ItemEntity{Id: 5, Decision: DecisionCertified}, nil)
+
+		response, err := svc.Certify(ctx, DecisionRequest{ItemId: 5}, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, DecisionCertified, response.Decision)
+		revoker.AssertNotCalled(t, "RevokeRoleAssignment", mock.Anything, mock.Anything)
+		repo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Revocation revokes the role", func(t *testing.T) {
+		svc, repo, revoker, sqlMock := newTestService(t)
+		ctx := actorContext("sub-admin", "")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+		revoker.On("RevokeRoleAssignment", int64(7), int64(40)).Return(nil)
+		repo.On("DecideItemTx", mock.MatchedBy(func(item ItemEntity) bool {
+			return item.Decision == DecisionRevoked && *item.Comment == "Moved to sales"
+		})).Return(nil)
+		repo.On("FindItemById", int64(5)).Return(ItemEntity{Id: 5, Decision: DecisionRevoked}, nil)
+
+		_, err := svc.Revoke(ctx, DecisionRequest{ItemId: 5, Comment: "Moved to sales"}, true)
+
+		require.NoError(t, err)
+		revoker.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Failed revocation keeps the item pending", func(t *testing.T) {
+		svc, repo, revoker, sqlMock := newTestService(t)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+		revoker.On("RevokeRoleAssignment", int64(7), int64(40)).Return(errors.New("database is down"))
+
+		_, err := svc.Revoke(actorContext("sub-admin", ""), DecisionRequest{ItemId: 5, Comment: "Not needed"}, true)
+
+		assert.Error(t, err)
+		repo.AssertNotCalled(t, "DecideItemTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Revocation requires a reason", func(t *testing.T) {
+		svc, _, _, sqlMock := newTestService(t)
+
+		_, err := svc.Revoke(actorContext("sub-admin", ""), DecisionRequest{ItemId: 5}, true)
+
+		assert.True(t, errors.As(err, &common.RequestValidationError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Other user does not see the item", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-9", "peer@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "peer@example.com").Return(int64(9), nil)
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+
+		_, err := svc.Certify(ctx, DecisionRequest{ItemId: 5}, false)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Administrator cannot certify own assignment", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		ctx := actorContext("sub-7", "john@example.com")
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindEmployeeIdByEmail", "john@example.com").Return(int64(7), nil)
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+
+		_, err := svc.Certify(ctx, DecisionRequest{ItemId: 5}, true)
+
+		assert.True(t, errors.As(err, &common.ForbiddenError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Reviewed item cannot be reviewed again", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		item := pendingItem()
+		item.Decision = DecisionCertified
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindItemForUpdateTx", int64(5)).Return(item, nil)
+
+		_, err := svc.Certify(actorContext("sub-admin", ""), DecisionRequest{ItemId: 5}, true)
+
+		assert.True(t, errors.As(err, &common.ConflictError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Item cannot be reviewed after the deadline", func(t *testing.T) {
+		svc, repo, _, sqlMock := newTestService(t)
+		item := pendingItem()
+		item.CampaignDeadline = time.Now().Add(-time.Minute)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindItemForUpdateTx", int64(5)).Return(item, nil)
+
+		_, err := svc.Certify(actorContext("sub-admin", ""), DecisionRequest{ItemId: 5}, true)
+
+		assert.True(t, errors.As(err, &common.ConflictError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_CloseDue(t *testing.T) {
+	t.Run("Unreviewed items are revoked and the campaign completed", func(t *testing.T) {
+		svc, repo, revoker, sqlMock := newTestService(t)
+		reviewed := pendingItem()
+		reviewed.Id, reviewed.Decision = 6, DecisionCertified
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindDueCampaignIds").Return([]int64{1}, nil)
+		repo.On("FindCampaignById", int64(1)).Return(CampaignEntity{Id: 1, AutoAction: AutoActionRevoke}, nil)
+		repo.On("FindPendingItemIds", int64(1)).Return([]int64{5, 6}, nil)
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+		// назначение 6 пересмотрели, пока кампания ждала завершения
+		repo.On("FindItemForUpdateTx", int64(6)).Return(reviewed, nil)
+		revoker.On("RevokeRoleAssignment", int64(7), int64(40)).Return(nil)
+		repo.On("DecideItemTx", mock.MatchedBy(func(item ItemEntity) bool {
+			return item.Id == 5 && item.Decision == DecisionRevoked && item.Auto && item.DecidedBySub == nil
+		})).Return(nil).Once()
+		repo.On("CompleteCampaign", int64(1)).Return(true, nil)
+
+		completed, err := svc.CloseDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, completed)
+		repo.AssertExpectations(t)
+		revoker.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Ended assignment is revoked without error", func(t *testing.T) {
+		svc, repo, revoker, sqlMock := newTestService(t)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindDueCampaignIds").Return([]int64{1}, nil)
+		repo.On("FindCampaignById", int64(1)).Return(CampaignEntity{Id: 1, AutoAction: AutoActionRevoke}, nil)
+		repo.On("FindPendingItemIds", int64(1)).Return([]int64{5}, nil)
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+		revoker.On("RevokeRoleAssignment", int64(7), int64(40)).
+			Return(common.NotFoundError{Message: "active role assignment 40 of employee 7 not found"})
+		repo.On("DecideItemTx", mock.Anything).Return(nil)
+		repo.On("CompleteCampaign", int64(1)).Return(true, nil)
+
+		completed, err := svc.CloseDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, completed)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Campaign with a failed item stays active", func(t *testing.T) {
+		svc, repo, revoker, sqlMock := newTestService(t)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		repo.On("FindDueCampaignIds").Return([]int64{1}, nil)
+		repo.On("FindCampaignById", int64(1)).Return(CampaignEntity{Id: 1, AutoAction: AutoActionRevoke}, nil)
+		repo.On("FindPendingItemIds", int64(1)).Return([]int64{5}, nil)
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+		revoker.On("RevokeRoleAssignment", int64(7), int64(40)).Return(errors.New("database is down"))
+
+		completed, err := svc.CloseDue(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, completed)
+		repo.AssertNotCalled(t, "CompleteCampaign", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Certify policy keeps the roles", func(t *testing.T) {
+		svc, repo, revoker, sqlMock := newTestService(t)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectCommit()
+		repo.On("FindDueCampaignIds").Return([]int64{1}, nil)
+		repo.On("FindCampaignById", int64(1)).Return(CampaignEntity{Id: 1, AutoAction: AutoActionCertify}, nil)
+		repo.On("FindPendingItemIds", int64(1)).Return([]int64{5}, nil)
+		repo.On("FindItemForUpdateTx", int64(5)).Return(pendingItem(), nil)
+		repo.On("DecideItemTx", mock.MatchedBy(func(item ItemEntity) bool {
+			return item.Decision == DecisionCertified && item.Auto
+		})).Return(nil)
+		repo.On("CompleteCampaign", int64(1)).Return(true, nil)
+
+		_, err := svc.CloseDue(context.Background())
+
+		require.NoError(t, err)
+		revoker.AssertNotCalled(t, "RevokeRoleAssignment", mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Summary(t *testing.T) {
+	t.Run("Progress of the campaign", func(t *testing.T) {
+		svc, repo, _, _ := newTestService(t)
+		repo.On("FindCampaignById", int64(1)).Return(CampaignEntity{Id: 1, Status: StatusActive}, nil)
+		repo.On("CountDecisions", int64(1)).
+			Return(decisionCounts{Total: 8, Pending: 2, Certified: 4, Revoked: 2, AutoRevoked: 1}, nil)
+		repo.On("FindReviewerSummaries", int64(1)).
+			Return([]ReviewerSummary{{ReviewerEmployeeId: ptr(int64(2)), Total: 8, Pending: 2}}, nil)
+
+		summary, err := svc.Summary(context.Background(), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, 75, summary.Progress)
+		assert.Equal(t, 1, summary.AutoRevoked)
+		assert.Len(t, summary.Reviewers, 1)
+	})
+
+	t.Run("Campaign not found", func(t *testing.T) {
+		svc, repo, _, _ := newTestService(t)
+		repo.On("FindCampaignById", int64(1)).Return(CampaignEntity{}, sql.ErrNoRows)
+
+		_, err := svc.Summary(context.Background(), 1)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+	})
+}
+
+func TestService_Cancel_AlreadyCompleted(t *testing.T) {
+	svc, repo, _, _ := newTestService(t)
+	repo.On("CancelCampaign", int64(1)).Return(false, nil)
+	repo.On("FindCampaignById", int64(1)).Return(CampaignEntity{Id: 1, Status: StatusCompleted}, nil)
+
+	_, err := svc.Cancel(context.Background(), 1)
+
+	assert.True(t, errors.As(err, &common.ConflictError{}))
+}
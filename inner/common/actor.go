@@ -0,0 +1,29 @@
+package common
+
+import "context"
+
+// Actor инициатор запроса, от имени которого выполняется изменение
+type Actor struct {
+	// идентификатор пользователя (JWT sub)
+	Subject string
+	// имя пользователя (JWT preferred_username)
+	Username string
+	// адрес почты пользователя (JWT email); по нему пользователь сопоставляется с сотрудником
+	Email     string
+	RequestId string
+	Ip        string
+}
+
+type actorKey struct{}
+
+// WithActor возвращает контекст, содержащий инициатора запроса
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext извлекает инициатора запроса из контекста.
+// Для внутренних вызовов без HTTP-запроса возвращается пустой Actor
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
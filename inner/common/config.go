@@ -22,6 +22,8 @@ type Config struct {
 	SslSert        string `validate:"required"`
 	SslKey         string `validate:"required"`
 	KeycloakJwkUrl string `validate:"required"`
+	// ключ подписи курсоров постраничного вывода; если не задан, используется случайный
+	CursorSecret string
 }
 
 // Получение конфигурации из .env файла или переменных окружения
@@ -42,6 +44,7 @@ func GetConfig(envFile string) Config {
 		SslSert:        os.Getenv("SSL_SERT"),
 		SslKey:         os.Getenv("SSL_KEY"),
 		KeycloakJwkUrl: os.Getenv("KEYCLOAK_JWK_URL"),
+		CursorSecret:   os.Getenv("CURSOR_SECRET"),
 	}
 	err = validator.New().Struct(cfg)
 	if err != nil {
@@ -0,0 +1,137 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Cursor позиция в выборке при постраничном выводе по ключу (keyset pagination):
+// значение поля сортировки и id строки на границе страницы
+type Cursor struct {
+	// сортировка, для которой выдан курсор; с другой сортировкой курсор недействителен
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Id    int64  `json:"i"`
+	// курсор prev: выбираются строки перед позицией
+	Backward bool `json:"b,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorCodec превращает курсор в непрозрачную строку, подписанную HMAC-SHA256,
+// чтобы клиент не мог подделать или изменить позицию
+type CursorCodec struct {
+	secret []byte
+}
+
+// функция-конструктор. Пустой secret заменяется случайным:
+// такие курсоры перестают действовать после перезапуска приложения
+func NewCursorCodec(secret string) *CursorCodec {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	return &CursorCodec{secret: key}
+}
+
+func (c *CursorCodec) Encode(cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload))
+}
+
+// Decode проверяет подпись и восстанавливает курсор; любая ошибка - ErrInvalidCursor
+func (c *CursorCodec) Decode(value string) (Cursor, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(value, ".")
+	if !found {
+		return Cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, c.sign(payload)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// EncodeOptional кодирует необязательный курсор; nil остаётся nil
+func (c *CursorCodec) EncodeOptional(cursor *Cursor) *string {
+	if cursor == nil {
+		return nil
+	}
+	value := c.Encode(*cursor)
+	return &value
+}
+
+// DecodeOptional разбирает курсор из необязательного параметра запроса; пустая строка - nil
+func (c *CursorCodec) DecodeOptional(value string) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	cursor, err := c.Decode(value)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// CursorWindow разбирает строки, выбранные от позиции position с запасом в одну строку (limit+1),
+// и возвращает страницу в порядке сортировки вместе с позициями соседних страниц.
+// При движении назад строки выбраны в обратном порядке. key возвращает значение поля сортировки и id строки.
+// nil вместо позиции - соседней страницы нет
+func CursorWindow[T any](
+	rows []T,
+	limit int,
+	sort string,
+	position *Cursor,
+	key func(T) (string, int64),
+) (page []T, next, prev *Cursor) {
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if len(rows) == 0 {
+		return rows, nil, nil
+	}
+
+	at := func(row T, backward bool) *Cursor {
+		value, id := key(row)
+		return &Cursor{Sort: sort, Value: value, Id: id, Backward: backward}
+	}
+
+	if position != nil && position.Backward {
+		slices.Reverse(rows)
+		if hasMore {
+			prev = at(rows[0], true)
+		}
+		return rows, at(rows[len(rows)-1], false), prev
+	}
+
+	if hasMore {
+		next = at(rows[len(rows)-1], false)
+	}
+	if position != nil {
+		prev = at(rows[0], true)
+	}
+	return rows, next, prev
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewCursorCodec("secret")
+	cursor := Cursor{Sort: "-created_at", Value: "2025-07-01T10:00:00.123456Z", Id: 42, Backward: true}
+
+	decoded, err := codec.Decode(codec.Encode(cursor))
+	require.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	// курсор, подписанный другим ключом или изменённый клиентом, отклоняется
+	_, err = NewCursorCodec("other").Decode(codec.Encode(cursor))
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	forged := NewCursorCodec("other").Encode(Cursor{Sort: "-created_at", Id: 1})
+	_, err = codec.Decode(forged)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	for _, value := range []string{"garbage", "a.b", "."} {
+		_, err = codec.Decode(value)
+		assert.ErrorIs(t, err, ErrInvalidCursor, value)
+	}
+
+	empty, err := codec.DecodeOptional("")
+	assert.NoError(t, err)
+	assert.Nil(t, empty)
+	assert.Nil(t, codec.EncodeOptional(nil))
+}
+
+func TestCursorWindow(t *testing.T) {
+	key := func(id int64) (string, int64) { return "", id }
+
+	t.Run("first page", func(t *testing.T) {
+		page, next, prev := CursorWindow([]int64{1, 2, 3}, 2, "id", nil, key)
+		assert.Equal(t, []int64{1, 2}, page)
+		assert.Equal(t, &Cursor{Sort: "id", Id: 2}, next)
+		assert.Nil(t, prev)
+	})
+
+	t.Run("last page after cursor", func(t *testing.T) {
+		page, next, prev := CursorWindow([]int64{3, 4}, 2, "id", &Cursor{Sort: "id", Id: 2}, key)
+		assert.Equal(t, []int64{3, 4}, page)
+		assert.Nil(t, next)
+		assert.Equal(t, &Cursor{Sort: "id", Id: 3, Backward: true}, prev)
+	})
+
+	t.Run("backward page", func(t *testing.T) {
+		// строки перед позицией 5 приходят в обратном порядке
+		page, next, prev := CursorWindow([]int64{4, 3, 2}, 2, "id", &Cursor{Sort: "id", Id: 5, Backward: true}, key)
+		assert.Equal(t, []int64{3, 4}, page)
+		assert.Equal(t, &Cursor{Sort: "id", Id: 4}, next)
+		assert.Equal(t, &Cursor{Sort: "id", Id: 3, Backward: true}, prev)
+	})
+
+	t.Run("empty page", func(t *testing.T) {
+		page, next, prev := CursorWindow([]int64{}, 2, "id", &Cursor{Sort: "id", Id: 9}, key)
+		assert.Empty(t, page)
+		assert.Nil(t, next)
+		assert.Nil(t, prev)
+	})
+}
@@ -0,0 +1,107 @@
+package common
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"iter"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// форматы выгрузки
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+)
+
+var exportContentTypes = map[string]string{
+	ExportFormatCSV:   "text/csv; charset=utf-8",
+	ExportFormatJSONL: "application/x-ndjson",
+}
+
+// через сколько записей выгрузка отправляется клиенту, не дожидаясь заполнения буфера
+const exportFlushRows = 500
+
+// ExportRecord запись выгрузки: в JSON Lines пишется сама структура,
+// в CSV - значения CSVValues в порядке колонок заголовка
+type ExportRecord interface {
+	CSVValues() []string
+}
+
+// ParseExportFormat проверяет формат выгрузки из параметра запроса; по умолчанию - CSV
+func ParseExportFormat(format string) (string, error) {
+	if format == "" {
+		return ExportFormatCSV, nil
+	}
+	if _, ok := exportContentTypes[format]; !ok {
+		return "", fmt.Errorf("unsupported export format %q", format)
+	}
+	return format, nil
+}
+
+// ExportContentType тип содержимого выгрузки в формате format
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// StreamExport отправляет выгрузку потоком: записи пишутся в ответ по мере чтения из records.
+// Статус и заголовки к этому моменту уже отправлены, поэтому ошибка чтения обрывает выгрузку
+// и только передаётся в onError. records проходится в отдельной горутине после возврата из обработчика,
+// поэтому не должен зависеть от *fiber.Ctx
+func StreamExport[T ExportRecord](
+	ctx *fiber.Ctx,
+	format, filename string,
+	header []string,
+	records iter.Seq2[T, error],
+	onError func(error),
+) error {
+	ctx.Set(fiber.HeaderContentType, ExportContentType(format))
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := WriteExport(w, format, header, records); err != nil {
+			onError(err)
+		}
+	})
+	return nil
+}
+
+// WriteExport записывает выгрузку в w в формате format
+func WriteExport[T ExportRecord](w *bufio.Writer, format string, header []string, records iter.Seq2[T, error]) error {
+	csvWriter := csv.NewWriter(w)
+	encoder := json.NewEncoder(w)
+	flush := func() error {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	if format == ExportFormatCSV {
+		if err := csvWriter.Write(header); err != nil {
+			return err
+		}
+	}
+	count := 0
+	for record, err := range records {
+		if err != nil {
+			return err
+		}
+		if format == ExportFormatCSV {
+			err = csvWriter.Write(record.CSVValues())
+		} else {
+			err = encoder.Encode(record)
+		}
+		if err != nil {
+			return err
+		}
+		if count++; count%exportFlushRows == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
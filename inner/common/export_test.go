@@ -0,0 +1,75 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testExportRecord struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (r testExportRecord) CSVValues() []string {
+	return []string{strings.Repeat("x", r.Id), r.Name}
+}
+
+func testExportRecords(err error, records ...testExportRecord) func(yield func(testExportRecord, error) bool) {
+	return func(yield func(testExportRecord, error) bool) {
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(testExportRecord{}, err)
+		}
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	format, err := ParseExportFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatCSV, format)
+
+	format, err = ParseExportFormat("jsonl")
+	require.NoError(t, err)
+	assert.Equal(t, ExportFormatJSONL, format)
+
+	_, err = ParseExportFormat("xlsx")
+	assert.Error(t, err)
+}
+
+func TestWriteExport(t *testing.T) {
+	records := []testExportRecord{{Id: 1, Name: "Doe, John"}, {Id: 2, Name: `"Jane"`}}
+
+	t.Run("CSV with header", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteExport(bufio.NewWriter(&buf), ExportFormatCSV, []string{"id", "name"}, testExportRecords(nil, records...))
+
+		require.NoError(t, err)
+		assert.Equal(t, "id,name\nx,\"Doe, John\"\nxx,\"\"\"Jane\"\"\"\n", buf.String())
+	})
+
+	t.Run("JSON Lines without header", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteExport(bufio.NewWriter(&buf), ExportFormatJSONL, []string{"id", "name"}, testExportRecords(nil, records...))
+
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":1,"name":"Doe, John"}`+"\n"+`{"id":2,"name":"\"Jane\""}`+"\n", buf.String())
+	})
+
+	t.Run("Read error stops export", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteExport(bufio.NewWriter(&buf), ExportFormatCSV, []string{"id", "name"},
+			testExportRecords(errors.New("connection reset"), records[0]))
+
+		assert.EqualError(t, err, "connection reset")
+	})
+}
@@ -25,3 +25,24 @@ type NotFoundError struct {
 func (err NotFoundError) Error() string {
 	return err.Message
 }
+
+// ForbiddenError представляет ошибку, когда у пользователя нет права на действие с сущностью
+type ForbiddenError struct {
+	Message string `json:"message"`
+}
+
+func (err ForbiddenError) Error() string {
+	return err.Message
+}
+
+// ConflictError представляет ошибку конкурентного изменения сущности
+// (например, версия записи устарела к моменту сохранения).
+// Data - необязательные подробности конфликта для клиента
+type ConflictError struct {
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (err ConflictError) Error() string {
+	return err.Message
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"iter"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// StructRows построчно читает результат запроса в структуры T, не загружая его в память целиком.
+// Итератор закрывает rows по завершении, поэтому его нужно пройти: иначе соединение не вернётся в пул.
+// Ошибка чтения возвращается последним элементом
+func StructRows[T any](rows *sqlx.Rows) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var item T
+			if err := rows.StructScan(&item); err != nil {
+				yield(item, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
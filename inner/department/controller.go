@@ -0,0 +1,360 @@
+package department
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server            *web.Server
+	departmentService Svc
+	logger            *common.Logger
+}
+
+// интерфейс сервиса department.Service
+type Svc interface {
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	FindTree(ctx context.Context) ([]TreeNode, error)
+	FindDescendants(ctx context.Context, id int64) ([]HierarchyResponse, error)
+	CreateDepartment(ctx context.Context, request CreateRequest) (Response, error)
+	UpdateDepartment(ctx context.Context, request UpdateRequest) (Response, error)
+	DeleteById(ctx context.Context, id int64) error
+}
+
+func NewController(server *web.Server, departmentService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:            server,
+		departmentService: departmentService,
+		logger:            logger,
+	}
+}
+
+// функция для регистрации маршрутов
+func (c *Controller) RegisterRoutes() {
+	c.logger.Info("Registering department routes")
+	// Маршруты для чтения (доступны пользователям с ролью IDM_ADMIN или IDM_USER)
+	// статические маршруты регистрируются раньше "/departments/:id", иначе он их перехватит
+	c.server.GroupApiV1User.Get("/departments/tree", c.FindDepartmentTree)
+	c.server.GroupApiV1User.Get("/departments/:id/descendants", c.FindDepartmentDescendants)
+	c.server.GroupApiV1User.Get("/departments/:id", c.GetDepartment)
+	c.server.GroupApiV1User.Get("/departments", c.FindAllDepartments)
+
+	// Маршруты для создания, изменения и удаления (доступны только администраторам)
+	c.server.GroupApiV1Admin.Post("/departments", c.CreateDepartment)
+	c.server.GroupApiV1Admin.Put("/departments/:id", c.UpdateDepartment)
+	c.server.GroupApiV1Admin.Delete("/departments/:id", c.DeleteDepartment)
+
+	c.logger.Info("Department routes registered successfully")
+}
+
+// CreateDepartment создаёт отдел
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Create a department
+//	@Description	Create a new department. Names are unique case-insensitively
+//	@Tags			departments
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		department.CreateRequest				true	"create department request"
+//	@Success		200		{object}	common.Response[department.Response]	"Created department"
+//	@Failure		400		{object}	common.Response[any]					"Incorrect data format in request"
+//	@Failure		409		{object}	common.Response[any]					"Department name already exists"
+//	@Failure		500		{object}	common.Response[any]					"Internal server error"
+//	@Router			/admin/departments [post]
+func (c *Controller) CreateDepartment(ctx *fiber.Ctx) error {
+	c.logger.Info("Received create department request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse create department request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	department, err := c.departmentService.CreateDepartment(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleDepartmentError(ctx, err, 0)
+	}
+
+	c.logger.Info("Department created successfully",
+		zap.Int64("id", department.Id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, department)
+}
+
+// UpdateDepartment обновляет отдел
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Update a department
+//	@Description	Replace name, parent and head of a department. A department cannot be moved under its own subtree
+//	@Tags			departments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int										true	"Department ID"
+//	@Param			request	body		department.UpdateRequest				true	"update department request"
+//	@Success		200		{object}	common.Response[department.Response]	"Updated department"
+//	@Failure		400		{object}	common.Response[any]					"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]					"Department not found"
+//	@Failure		409		{object}	common.Response[any]					"Name already exists or hierarchy would form a cycle"
+//	@Failure		500		{object}	common.Response[any]					"Internal server error"
+//	@Router			/admin/departments/{id} [put]
+func (c *Controller) UpdateDepartment(ctx *fiber.Ctx) error {
+	c.logger.Info("Received update department request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseDepartmentId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid department ID format")
+	}
+
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse update department request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.Id = id
+
+	department, err := c.departmentService.UpdateDepartment(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleDepartmentError(ctx, err, id)
+	}
+
+	c.logger.Info("Department updated successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, department)
+}
+
+// DeleteDepartment удаляет отдел
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Delete a department
+//	@Description	Delete a department without employees and child departments. Otherwise 409 lists the dependents
+//	@Tags			departments
+//	@Produce		json
+//	@Param			id	path		int										true	"Department ID"
+//	@Success		200	{object}	common.Response[any]					"Department deleted successfully"
+//	@Failure		400	{object}	common.Response[any]					"Invalid department ID format"
+//	@Failure		404	{object}	common.Response[any]					"Department not found"
+//	@Failure		409	{object}	common.Response[department.Dependents]	"Department has employees or child departments"
+//	@Failure		500	{object}	common.Response[any]					"Internal server error"
+//	@Router			/admin/departments/{id} [delete]
+func (c *Controller) DeleteDepartment(ctx *fiber.Ctx) error {
+	c.logger.Info("Received delete department request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseDepartmentId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid department ID format")
+	}
+
+	if err := c.departmentService.DeleteById(ctx.UserContext(), id); err != nil {
+		return c.handleDepartmentError(ctx, err, id)
+	}
+
+	c.logger.Info("Department deleted successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, fiber.Map{"message": "Department deleted successfully"})
+}
+
+// GetDepartment возвращает отдел по ID
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get department by ID
+//	@Tags			departments
+//	@Produce		json
+//	@Param			id	path		int										true	"Department ID"
+//	@Success		200	{object}	common.Response[department.Response]	"Department"
+//	@Failure		400	{object}	common.Response[any]					"Invalid department ID format"
+//	@Failure		404	{object}	common.Response[any]					"Department not found"
+//	@Router			/departments/{id} [get]
+func (c *Controller) GetDepartment(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received get department request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseDepartmentId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid department ID format")
+	}
+
+	department, err := c.departmentService.FindById(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleDepartmentError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, department)
+}
+
+// FindAllDepartments возвращает все отделы
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List departments
+//	@Tags			departments
+//	@Produce		json
+//	@Success		200	{object}	common.Response[[]department.Response]	"Departments ordered by name"
+//	@Failure		500	{object}	common.Response[any]					"Internal server error"
+//	@Router			/departments [get]
+func (c *Controller) FindAllDepartments(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find all departments request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	departments, err := c.departmentService.FindAll(ctx.UserContext())
+	if err != nil {
+		c.logger.Error("Failed to find all departments",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when getting the list of departments")
+	}
+
+	return common.OkResponse(ctx, departments)
+}
+
+// FindDepartmentTree возвращает иерархию отделов в виде дерева
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Department tree
+//	@Tags			departments
+//	@Produce		json
+//	@Success		200	{object}	common.Response[[]department.TreeNode]	"Department tree"
+//	@Failure		500	{object}	common.Response[any]					"Internal server error"
+//	@Router			/departments/tree [get]
+func (c *Controller) FindDepartmentTree(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received department tree request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	tree, err := c.departmentService.FindTree(ctx.UserContext())
+	if err != nil {
+		c.logger.Error("Failed to build department tree",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when building the department tree")
+	}
+
+	return common.OkResponse(ctx, tree)
+}
+
+// FindDepartmentDescendants возвращает все подотделы отдела
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Department descendants
+//	@Description	All child departments of a department in breadth-first order with their depth
+//	@Tags			departments
+//	@Produce		json
+//	@Param			id	path		int													true	"Department ID"
+//	@Success		200	{object}	common.Response[[]department.HierarchyResponse]	"Descendants"
+//	@Failure		400	{object}	common.Response[any]								"Invalid department ID format"
+//	@Failure		404	{object}	common.Response[any]								"Department not found"
+//	@Router			/departments/{id}/descendants [get]
+func (c *Controller) FindDepartmentDescendants(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received department descendants request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseDepartmentId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid department ID format")
+	}
+
+	departments, err := c.departmentService.FindDescendants(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleDepartmentError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, departments)
+}
+
+// извлекает ID отдела из параметров пути
+func (c *Controller) parseDepartmentId(ctx *fiber.Ctx) (int64, error) {
+	idParam := ctx.Params("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Error("Invalid department ID format",
+			zap.String("id", idParam),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+	}
+	return id, err
+}
+
+// обрабатывает ошибки сервиса отделов
+func (c *Controller) handleDepartmentError(ctx *fiber.Ctx, err error, id int64) error {
+	var validationErr common.RequestValidationError
+	var conflictErr common.ConflictError
+	switch {
+	case errors.As(err, &validationErr):
+		c.logger.Warn("Department validation error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Department not found",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Department not found")
+
+	case errors.As(err, &conflictErr):
+		c.logger.Warn("Department conflict error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		if conflictErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message, conflictErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message)
+
+	case errors.As(err, &common.AlreadyExistsError{}):
+		c.logger.Warn("Department already exists",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+
+	default:
+		c.logger.Error("Department internal error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -0,0 +1,175 @@
+package department
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock для сервиса
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindAll(ctx context.Context) ([]Response, error) {
+	args := m.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (m *MockService) FindTree(ctx context.Context) ([]TreeNode, error) {
+	args := m.Called()
+	return args.Get(0).([]TreeNode), args.Error(1)
+}
+
+func (m *MockService) FindDescendants(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).([]HierarchyResponse), args.Error(1)
+}
+
+func (m *MockService) CreateDepartment(ctx context.Context, request CreateRequest) (Response, error) {
+	args := m.Called(request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) UpdateDepartment(ctx context.Context, request UpdateRequest) (Response, error) {
+	args := m.Called(request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) DeleteById(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// Вспомогательные функции для создания Fiber app
+func setupTestApp() (*fiber.App, *MockService) {
+	app := fiber.New()
+	mockService := &MockService{}
+
+	server := &web.Server{
+		GroupApiV1User:  app.Group("/api/v1"),
+		GroupApiV1Admin: app.Group("/api/v1/admin"),
+	}
+
+	controller := NewController(server, mockService, createTestLogger())
+	controller.RegisterRoutes()
+
+	return app, mockService
+}
+
+func TestController_Departments(t *testing.T) {
+	parentId := int64(1)
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:   "create department",
+			method: fiber.MethodPost,
+			path:   "/api/v1/admin/departments",
+			body:   `{"name":"Backend","parent_id":1}`,
+			mockSetup: func(m *MockService) {
+				m.On("CreateDepartment", CreateRequest{Name: "Backend", ParentId: &parentId}).
+					Return(Response{Id: 2, Name: "Backend", ParentId: &parentId}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "duplicate name returns conflict",
+			method: fiber.MethodPost,
+			path:   "/api/v1/admin/departments",
+			body:   `{"name":"it"}`,
+			mockSetup: func(m *MockService) {
+				m.On("CreateDepartment", CreateRequest{Name: "it"}).
+					Return(Response{}, common.AlreadyExistsError{Message: "department with name it already exists"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:   "cycle returns conflict",
+			method: fiber.MethodPut,
+			path:   "/api/v1/admin/departments/1",
+			body:   `{"name":"Company","parent_id":1}`,
+			mockSetup: func(m *MockService) {
+				m.On("UpdateDepartment", UpdateRequest{Id: 1, Name: "Company", ParentId: &parentId}).
+					Return(Response{}, common.ConflictError{Message: "department 1 cannot be its own parent"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:   "delete with dependents returns conflict",
+			method: fiber.MethodDelete,
+			path:   "/api/v1/admin/departments/1",
+			mockSetup: func(m *MockService) {
+				m.On("DeleteById", int64(1)).Return(common.ConflictError{
+					Message: "department 1 is referenced by 2 employees and 0 child departments",
+					Data:    Dependents{EmployeeIds: []int64{4, 5}, ChildDepartmentIds: []int64{}},
+				})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:   "tree",
+			method: fiber.MethodGet,
+			path:   "/api/v1/departments/tree",
+			mockSetup: func(m *MockService) {
+				m.On("FindTree").Return([]TreeNode{{Response: Response{Id: 1, Name: "Company"}}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "descendants of unknown department",
+			method: fiber.MethodGet,
+			path:   "/api/v1/departments/9/descendants",
+			mockSetup: func(m *MockService) {
+				m.On("FindDescendants", int64(9)).
+					Return([]HierarchyResponse(nil), common.NotFoundError{Message: "department with id 9 not found"})
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid id",
+			method:       fiber.MethodGet,
+			path:         "/api/v1/departments/abc",
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockService := setupTestApp()
+			tt.mockSetup(mockService)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			var response common.Response[any]
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			assert.Equal(t, tt.expectedCode == http.StatusOK, response.Success)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
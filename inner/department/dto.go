@@ -0,0 +1,126 @@
+package department
+
+import "time"
+
+type Entity struct {
+	Id             int64     `db:"id"`
+	Name           string    `db:"name"`
+	ParentId       *int64    `db:"parent_id"`
+	HeadEmployeeId *int64    `db:"head_employee_id"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:             e.Id,
+		Name:           e.Name,
+		ParentId:       e.ParentId,
+		HeadEmployeeId: e.HeadEmployeeId,
+		CreatedAt:      e.CreatedAt,
+		UpdatedAt:      e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id             int64     `json:"id"`
+	Name           string    `json:"name"`
+	ParentId       *int64    `json:"parent_id"`
+	HeadEmployeeId *int64    `json:"head_employee_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+} // @name DepartmentResponse
+
+// CreateRequest структура запроса на создание отдела
+type CreateRequest struct {
+	Name           string `json:"name" validate:"required,min=2,max=100" example:"Engineering"`
+	ParentId       *int64 `json:"parent_id,omitempty" validate:"omitempty,min=1" example:"1"`
+	HeadEmployeeId *int64 `json:"head_employee_id,omitempty" validate:"omitempty,min=1" example:"1"`
+} // @name DepartmentCreateRequest
+
+func (req *CreateRequest) toEntity() Entity {
+	return Entity{
+		Name:           req.Name,
+		ParentId:       req.ParentId,
+		HeadEmployeeId: req.HeadEmployeeId,
+	}
+}
+
+// UpdateRequest структура запроса на полное обновление отдела.
+// ParentId = nil делает отдел корневым, HeadEmployeeId = nil снимает руководителя
+type UpdateRequest struct {
+	Id             int64  `json:"-"`
+	Name           string `json:"name" validate:"required,min=2,max=100" example:"Engineering"`
+	ParentId       *int64 `json:"parent_id,omitempty" validate:"omitempty,min=1" example:"1"`
+	HeadEmployeeId *int64 `json:"head_employee_id,omitempty" validate:"omitempty,min=1" example:"1"`
+} // @name DepartmentUpdateRequest
+
+// применяет полное обновление к сущности
+func (req *UpdateRequest) applyTo(entity *Entity) {
+	entity.Name = req.Name
+	entity.ParentId = req.ParentId
+	entity.HeadEmployeeId = req.HeadEmployeeId
+}
+
+// TreeNode узел дерева отделов
+type TreeNode struct {
+	Response
+	Children []TreeNode `json:"children"`
+} // @name DepartmentTreeNode
+
+// строит лес отделов по parent_id; отделы, родитель которых не найден, становятся корнями
+func buildTree(departments []Entity) []TreeNode {
+	children := make(map[int64][]Entity, len(departments))
+	known := make(map[int64]bool, len(departments))
+	for _, department := range departments {
+		known[department.Id] = true
+	}
+
+	var roots []Entity
+	for _, department := range departments {
+		if department.ParentId == nil || !known[*department.ParentId] {
+			roots = append(roots, department)
+			continue
+		}
+		children[*department.ParentId] = append(children[*department.ParentId], department)
+	}
+
+	var build func(nodes []Entity) []TreeNode
+	build = func(nodes []Entity) []TreeNode {
+		result := make([]TreeNode, len(nodes))
+		for i, node := range nodes {
+			result[i] = TreeNode{
+				Response: node.toResponse(),
+				Children: build(children[node.Id]),
+			}
+		}
+		return result
+	}
+	return build(roots)
+}
+
+// отдел с расстоянием до исходного отдела в иерархии
+type hierarchyEntity struct {
+	Entity
+	Depth int `db:"depth"`
+}
+
+// HierarchyResponse подотдел исходного отдела,
+// Depth - расстояние до исходного отдела (1 - непосредственный подотдел)
+type HierarchyResponse struct {
+	Response
+	Depth int `json:"depth"`
+} // @name DepartmentHierarchyResponse
+
+func (e *hierarchyEntity) toHierarchyResponse() HierarchyResponse {
+	return HierarchyResponse{
+		Response: e.toResponse(),
+		Depth:    e.Depth,
+	}
+}
+
+// Dependents сотрудники и подотделы, ссылающиеся на удаляемый отдел
+type Dependents struct {
+	EmployeeIds        []int64 `json:"employee_ids"`
+	ChildDepartmentIds []int64 `json:"child_department_ids"`
+} // @name DepartmentDependents
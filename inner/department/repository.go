@@ -0,0 +1,156 @@
+package department
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewDepartmentRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (department Entity, err error) {
+	err = r.db.GetContext(ctx, &department, "SELECT * FROM department WHERE id = $1", id)
+	return department, err
+}
+
+func (r *Repository) FindAll(ctx context.Context) ([]Entity, error) {
+	var departments []Entity
+	err := r.db.SelectContext(ctx, &departments, "SELECT * FROM department ORDER BY name")
+	return departments, err
+}
+
+// Транзакционные методы
+func (r *Repository) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// Проверить, занято ли имя другим отделом; имена сравниваются без учёта регистра.
+// excludeId = 0 проверяет все отделы
+func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (isExists bool, err error) {
+	err = tx.GetContext(
+		ctx,
+		&isExists,
+		"select exists(select 1 from department where lower(name) = lower($1) and id <> $2)",
+		name, excludeId,
+	)
+	return isExists, err
+}
+
+// Создать новый отдел
+func (r *Repository) SaveTx(ctx context.Context, tx *sqlx.Tx, department Entity) (created Entity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&created,
+		`INSERT INTO department (name, parent_id, head_employee_id) VALUES ($1, $2, $3) RETURNING *`,
+		department.Name, department.ParentId, department.HeadEmployeeId)
+	return created, err
+}
+
+// Найти отдел по id и заблокировать строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (department Entity, err error) {
+	err = tx.GetContext(ctx, &department, "SELECT * FROM department WHERE id = $1 FOR UPDATE", id)
+	return department, err
+}
+
+// Обновить отдел
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, department Entity) (updated Entity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&updated,
+		`UPDATE department
+		SET name = $1, parent_id = $2, head_employee_id = $3, updated_at = clock_timestamp()
+		WHERE id = $4
+		RETURNING *`,
+		department.Name, department.ParentId, department.HeadEmployeeId, department.Id)
+	return updated, err
+}
+
+// Удалить отдел
+func (r *Repository) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM department WHERE id = $1", id)
+	return err
+}
+
+// Проверить существование отдела
+func (r *Repository) ExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from department where id = $1)", id)
+	return isExists, err
+}
+
+// Сериализовать изменения иерархии отделов: два параллельных переподчинения
+// не должны по отдельности пройти проверку на цикл и вместе его образовать
+func (r *Repository) LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('department_hierarchy'))")
+	return err
+}
+
+// Проверить, находится ли отдел ancestorId в цепочке родителей отдела departmentId (включая сам departmentId)
+func (r *Repository) IsInParentChainTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	departmentId, ancestorId int64,
+) (inChain bool, err error) {
+	err = tx.GetContext(
+		ctx,
+		&inChain,
+		`WITH RECURSIVE chain AS (
+			SELECT id, parent_id FROM department WHERE id = $1
+			UNION
+			SELECT d.id, d.parent_id FROM department d JOIN chain c ON d.id = c.parent_id
+		)
+		SELECT exists(SELECT 1 FROM chain WHERE id = $2)`,
+		departmentId, ancestorId)
+	return inChain, err
+}
+
+// Найти все подотделы в порядке обхода в ширину
+func (r *Repository) FindDescendants(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	var departments []hierarchyEntity
+	err := r.db.SelectContext(
+		ctx,
+		&departments,
+		`WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth, ARRAY[id] AS path FROM department WHERE id = $1
+			UNION ALL
+			SELECT d.id, s.depth + 1, s.path || d.id
+			FROM department d JOIN subtree s ON d.parent_id = s.id
+			WHERE NOT d.id = ANY (s.path)
+		)
+		SELECT d.*, s.depth FROM subtree s JOIN department d ON d.id = s.id
+		WHERE s.depth > 0
+		ORDER BY s.depth, d.id`,
+		id)
+	return departments, err
+}
+
+// Найти непосредственные подотделы
+func (r *Repository) FindChildIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error) {
+	var ids []int64
+	err := tx.SelectContext(ctx, &ids, "SELECT id FROM department WHERE parent_id = $1 ORDER BY id", id)
+	return ids, err
+}
+
+// Найти неудалённых сотрудников отдела
+func (r *Repository) FindEmployeeIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error) {
+	var ids []int64
+	err := tx.SelectContext(ctx, &ids,
+		"SELECT id FROM employee WHERE department_id = $1 AND deleted_at IS NULL ORDER BY id", id)
+	return ids, err
+}
+
+// Проверить, что сотрудник существует и может руководить отделом (не удалён и не уволен)
+func (r *Repository) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (isExists bool, err error) {
+	err = tx.GetContext(
+		ctx,
+		&isExists,
+		"select exists(select 1 from employee where id = $1 and deleted_at is null and status <> 'terminated')",
+		employeeId,
+	)
+	return isExists, err
+}
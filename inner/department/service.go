@@ -0,0 +1,458 @@
+package department
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/validator"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo      Repo
+	auditor   Auditor
+	validator Validator
+	logger    *common.Logger
+}
+
+type Repo interface {
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (bool, error)
+	SaveTx(ctx context.Context, tx *sqlx.Tx, department Entity) (Entity, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, department Entity) (Entity, error)
+	DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+	ExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error
+	IsInParentChainTx(ctx context.Context, tx *sqlx.Tx, departmentId, ancestorId int64) (bool, error)
+	FindDescendants(ctx context.Context, id int64) ([]hierarchyEntity, error)
+	FindChildIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error)
+	FindEmployeeIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error)
+	EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error)
+}
+
+// интерфейс журнала аудита: событие пишется в транзакции изменения
+type Auditor interface {
+	Record(ctx context.Context, tx *sqlx.Tx, event audit.Event) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// функция-конструктор
+func NewService(repo Repo, auditor Auditor, validator Validator, logger *common.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		auditor:   auditor,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// Метод для создания нового отдела
+func (svc *Service) CreateDepartment(ctx context.Context, request CreateRequest) (response Response, err error) {
+	svc.logger.Info("Creating new department", zap.String("name", request.Name))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for department creation",
+			zap.String("name", request.Name),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error create department: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, 0)
+	}()
+
+	entity := request.toEntity()
+	if err = svc.checkDepartment(ctx, tx, entity); err != nil {
+		return Response{}, err
+	}
+
+	created, err := svc.repo.SaveTx(ctx, tx, entity)
+	if err != nil {
+		svc.logger.Error("Failed to save department",
+			zap.String("name", request.Name),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error creating department with name: %s %w", request.Name, err)
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionCreate,
+		EntityType: audit.EntityDepartment,
+		EntityId:   created.Id,
+		After:      created.toResponse(),
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Department created successfully",
+		zap.String("name", created.Name),
+		zap.Int64("id", created.Id))
+	return created.toResponse(), nil
+}
+
+// Метод для полного обновления отдела, включая смену родителя и руководителя
+func (svc *Service) UpdateDepartment(ctx context.Context, request UpdateRequest) (response Response, err error) {
+	svc.logger.Info("Updating department", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for department update",
+			zap.Int64("id", request.Id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error update department: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, request.Id)
+	}()
+
+	entity, err := svc.findForUpdate(ctx, tx, request.Id)
+	if err != nil {
+		return Response{}, err
+	}
+
+	before := entity.toResponse()
+	request.applyTo(&entity)
+
+	if err = svc.checkDepartment(ctx, tx, entity); err != nil {
+		return Response{}, err
+	}
+
+	updated, err := svc.repo.UpdateTx(ctx, tx, entity)
+	if err != nil {
+		svc.logger.Error("Failed to update department",
+			zap.Int64("id", request.Id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error updating department with id %d: %w", request.Id, err)
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionUpdate,
+		EntityType: audit.EntityDepartment,
+		EntityId:   request.Id,
+		Before:     before,
+		After:      updated.toResponse(),
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Department updated successfully", zap.Int64("id", request.Id))
+	return updated.toResponse(), nil
+}
+
+// Метод для удаления отдела. Отдел, в котором работают сотрудники или есть подотделы,
+// не удаляется: ConflictError содержит список зависимых
+func (svc *Service) DeleteById(ctx context.Context, id int64) (err error) {
+	svc.logger.Info("Deleting department by ID", zap.Int64("id", id))
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for department deletion",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error delete department: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	// параллельно созданный подотдел не должен ссылаться на удаляемый отдел
+	if err = svc.repo.LockHierarchyTx(ctx, tx); err != nil {
+		return fmt.Errorf("error locking department hierarchy: %w", err)
+	}
+
+	entity, err := svc.findForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	dependents, err := svc.findDependents(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if len(dependents.EmployeeIds) > 0 || len(dependents.ChildDepartmentIds) > 0 {
+		svc.logger.Warn("Department has dependents, deletion rejected",
+			zap.Int64("id", id),
+			zap.Int64s("employee_ids", dependents.EmployeeIds),
+			zap.Int64s("child_department_ids", dependents.ChildDepartmentIds))
+		return common.ConflictError{
+			Message: fmt.Sprintf("department %d is referenced by %d employees and %d child departments",
+				id, len(dependents.EmployeeIds), len(dependents.ChildDepartmentIds)),
+			Data: dependents,
+		}
+	}
+
+	if err = svc.repo.DeleteTx(ctx, tx, id); err != nil {
+		svc.logger.Error("Failed to delete department",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error deleting department with id %d: %w", id, err)
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionDelete,
+		EntityType: audit.EntityDepartment,
+		EntityId:   id,
+		Before:     entity.toResponse(),
+	})
+	if err != nil {
+		return err
+	}
+
+	svc.logger.Info("Department deleted successfully", zap.Int64("id", id))
+	return nil
+}
+
+// сотрудники и подотделы, ссылающиеся на отдел
+func (svc *Service) findDependents(ctx context.Context, tx *sqlx.Tx, id int64) (Dependents, error) {
+	employeeIds, err := svc.repo.FindEmployeeIdsTx(ctx, tx, id)
+	if err != nil {
+		return Dependents{}, fmt.Errorf("error finding employees of department %d: %w", id, err)
+	}
+	childIds, err := svc.repo.FindChildIdsTx(ctx, tx, id)
+	if err != nil {
+		return Dependents{}, fmt.Errorf("error finding child departments of department %d: %w", id, err)
+	}
+	if employeeIds == nil {
+		employeeIds = []int64{}
+	}
+	if childIds == nil {
+		childIds = []int64{}
+	}
+	return Dependents{EmployeeIds: employeeIds, ChildDepartmentIds: childIds}, nil
+}
+
+// находит отдел и блокирует его строку; NotFoundError, если отдела нет
+func (svc *Service) findForUpdate(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			svc.logger.Warn("Department not found", zap.Int64("id", id))
+			return Entity{}, common.NotFoundError{Message: fmt.Sprintf("department with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find department",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Entity{}, fmt.Errorf("error finding department with id %d: %w", id, err)
+	}
+	return entity, nil
+}
+
+// проверяет создаваемый или изменённый отдел: имя уникально без учёта регистра,
+// родитель существует и не образует цикл, руководитель - действующий сотрудник
+func (svc *Service) checkDepartment(ctx context.Context, tx *sqlx.Tx, entity Entity) error {
+	isExist, err := svc.repo.FindByNameTx(ctx, tx, entity.Name, entity.Id)
+	if err != nil {
+		svc.logger.Error("Failed to check department existence",
+			zap.String("name", entity.Name),
+			zap.Error(err))
+		return fmt.Errorf("error finding department by name: %s, %w", entity.Name, err)
+	}
+	if isExist {
+		svc.logger.Warn("Department already exists", zap.String("name", entity.Name))
+		return common.AlreadyExistsError{Message: fmt.Sprintf("department with name %s already exists", entity.Name)}
+	}
+
+	if entity.ParentId != nil {
+		if err := svc.checkParent(ctx, tx, entity.Id, *entity.ParentId); err != nil {
+			return err
+		}
+	}
+
+	if entity.HeadEmployeeId != nil {
+		isExist, err := svc.repo.EmployeeExistsTx(ctx, tx, *entity.HeadEmployeeId)
+		if err != nil {
+			svc.logger.Error("Failed to check head employee existence",
+				zap.Int64("head_employee_id", *entity.HeadEmployeeId),
+				zap.Error(err))
+			return fmt.Errorf("error finding employee with id %d: %w", *entity.HeadEmployeeId, err)
+		}
+		if !isExist {
+			return common.RequestValidationError{
+				Message: fmt.Sprintf("head employee with id %d does not exist or is terminated", *entity.HeadEmployeeId),
+			}
+		}
+	}
+
+	return nil
+}
+
+// проверяет, что отдел parentId может стать родителем отдела departmentId:
+// родитель существует и не является самим отделом или его подотделом.
+// departmentId = 0 означает создаваемый отдел, у которого ещё нет подотделов
+func (svc *Service) checkParent(ctx context.Context, tx *sqlx.Tx, departmentId, parentId int64) error {
+	if parentId == departmentId {
+		return common.ConflictError{Message: fmt.Sprintf("department %d cannot be its own parent", departmentId)}
+	}
+
+	if err := svc.repo.LockHierarchyTx(ctx, tx); err != nil {
+		svc.logger.Error("Failed to lock department hierarchy", zap.Error(err))
+		return fmt.Errorf("error locking department hierarchy: %w", err)
+	}
+
+	isExist, err := svc.repo.ExistsTx(ctx, tx, parentId)
+	if err != nil {
+		svc.logger.Error("Failed to check parent department existence",
+			zap.Int64("parent_id", parentId),
+			zap.Error(err))
+		return fmt.Errorf("error finding parent department with id %d: %w", parentId, err)
+	}
+	if !isExist {
+		return common.RequestValidationError{Message: fmt.Sprintf("parent department with id %d does not exist", parentId)}
+	}
+
+	if departmentId == 0 {
+		return nil
+	}
+
+	// если отдел уже есть в цепочке родителей нового родителя, то новый родитель - его подотдел
+	isCycle, err := svc.repo.IsInParentChainTx(ctx, tx, parentId, departmentId)
+	if err != nil {
+		svc.logger.Error("Failed to check department hierarchy for cycles",
+			zap.Int64("id", departmentId),
+			zap.Int64("parent_id", parentId),
+			zap.Error(err))
+		return fmt.Errorf("error checking department hierarchy: %w", err)
+	}
+	if isCycle {
+		svc.logger.Warn("Department hierarchy cycle rejected",
+			zap.Int64("id", departmentId),
+			zap.Int64("parent_id", parentId))
+		return common.ConflictError{
+			Message: fmt.Sprintf("department %d cannot have parent %d: department %d is a descendant of department %d, this would create a cycle",
+				departmentId, parentId, parentId, departmentId),
+		}
+	}
+
+	return nil
+}
+
+// валидация запроса на создание или изменение отдела
+func (svc *Service) validateRequest(request any) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Department request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return nil
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	svc.logger.Debug("Finding department by ID", zap.Int64("id", id))
+
+	department, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("department with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find department by ID",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding department with id %d: %w", id, err)
+	}
+
+	return department.toResponse(), nil
+}
+
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	svc.logger.Debug("Fetching all departments")
+
+	departments, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to fetch all departments", zap.Error(err))
+		return nil, fmt.Errorf("error finding all departments: %w", err)
+	}
+
+	responses := make([]Response, len(departments))
+	for i, entity := range departments {
+		responses[i] = entity.toResponse()
+	}
+	return responses, nil
+}
+
+// Метод для получения всей иерархии отделов в виде дерева
+func (svc *Service) FindTree(ctx context.Context) ([]TreeNode, error) {
+	svc.logger.Debug("Building department tree")
+
+	departments, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to fetch departments for tree", zap.Error(err))
+		return nil, fmt.Errorf("error building department tree: %w", err)
+	}
+
+	tree := buildTree(departments)
+	svc.logger.Debug("Department tree built successfully",
+		zap.Int("departments_count", len(departments)),
+		zap.Int("roots_count", len(tree)))
+	return tree, nil
+}
+
+// Метод для получения всех подотделов отдела
+func (svc *Service) FindDescendants(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	svc.logger.Debug("Finding department descendants", zap.Int64("id", id))
+
+	if _, err := svc.FindById(ctx, id); err != nil {
+		return nil, err
+	}
+
+	departments, err := svc.repo.FindDescendants(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to find department descendants",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding descendants of department %d: %w", id, err)
+	}
+
+	responses := make([]HierarchyResponse, len(departments))
+	for i, department := range departments {
+		responses[i] = department.toHierarchyResponse()
+	}
+	return responses, nil
+}
+
+// завершает транзакцию: откатывает при ошибке, иначе фиксирует.
+// Возвращает исходную ошибку или ошибку фиксации
+func (svc *Service) finishTransaction(tx *sqlx.Tx, err error, id int64) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			svc.logger.Error("Failed to rollback transaction",
+				zap.Int64("id", id),
+				zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		svc.logger.Error("Failed to commit transaction",
+			zap.Int64("id", id),
+			zap.Error(commitErr))
+		return commitErr
+	}
+	return nil
+}
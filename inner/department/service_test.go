@@ -0,0 +1,358 @@
+package department
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+type MockValidator struct {
+	mock.Mock
+}
+
+// журнал аудита для тестов: запоминает записанные события
+type StubAuditor struct {
+	events []audit.Event
+}
+
+func (a *StubAuditor) Record(ctx context.Context, tx *sqlx.Tx, event audit.Event) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func (m *MockValidator) Validate(request any) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called()
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	args := m.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockRepo) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (bool, error) {
+	args := m.Called(name, excludeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, department Entity) (Entity, error) {
+	args := m.Called(department)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, department Entity) (Entity, error) {
+	args := m.Called(department)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepo) ExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockRepo) IsInParentChainTx(ctx context.Context, tx *sqlx.Tx, departmentId, ancestorId int64) (bool, error) {
+	args := m.Called(departmentId, ancestorId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindDescendants(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	args := m.Called(id)
+	return args.Get(0).([]hierarchyEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindChildIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error) {
+	args := m.Called(id)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) FindEmployeeIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error) {
+	args := m.Called(id)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error) {
+	args := m.Called(employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func createTestLogger() *common.Logger {
+	cfg := common.Config{
+		DbDriverName:   "postgres",
+		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
+		AppName:        "test_app",
+		AppVersion:     "1.0.0",
+		LogLevel:       "DEBUG",
+		LogDevelopMode: true,
+	}
+	return common.NewLogger(cfg)
+}
+
+func newMockTx(t *testing.T, commit bool) (*sqlx.Tx, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	sqlMock.ExpectBegin()
+	if commit {
+		sqlMock.ExpectCommit()
+	} else {
+		sqlMock.ExpectRollback()
+	}
+
+	tx, err := sqlx.NewDb(db, "postgres").Beginx()
+	require.NoError(t, err)
+	return tx, sqlMock
+}
+
+func TestService_CreateDepartment(t *testing.T) {
+	parentId := int64(1)
+	headId := int64(7)
+
+	t.Run("Successful creation", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		request := CreateRequest{Name: "Backend", ParentId: &parentId, HeadEmployeeId: &headId}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByNameTx", "Backend", int64(0)).Return(false, nil)
+		mockRepo.On("LockHierarchyTx").Return(nil)
+		mockRepo.On("ExistsTx", parentId).Return(true, nil)
+		mockRepo.On("EmployeeExistsTx", headId).Return(true, nil)
+		mockRepo.On("SaveTx", Entity{Name: "Backend", ParentId: &parentId, HeadEmployeeId: &headId}).
+			Return(Entity{Id: 2, Name: "Backend", ParentId: &parentId, HeadEmployeeId: &headId}, nil)
+
+		response, err := svc.CreateDepartment(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), response.Id)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionCreate, auditor.events[0].Action)
+			assert.Equal(t, audit.EntityDepartment, auditor.events[0].EntityType)
+		}
+	})
+
+	t.Run("Duplicate name", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := CreateRequest{Name: "it"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByNameTx", "it", int64(0)).Return(true, nil)
+
+		_, err := svc.CreateDepartment(context.Background(), request)
+
+		var alreadyExistsErr common.AlreadyExistsError
+		assert.True(t, errors.As(err, &alreadyExistsErr))
+		mockRepo.AssertNotCalled(t, "SaveTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Terminated head employee", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := CreateRequest{Name: "Backend", HeadEmployeeId: &headId}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByNameTx", "Backend", int64(0)).Return(false, nil)
+		mockRepo.On("EmployeeExistsTx", headId).Return(false, nil)
+
+		_, err := svc.CreateDepartment(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_UpdateDepartment(t *testing.T) {
+	t.Run("Cycle is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		// Company(1) <- Engineering(2) <- Backend(3): Company не может стать подотделом Backend
+		backendId := int64(3)
+		request := UpdateRequest{Id: 1, Name: "Company", ParentId: &backendId}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(1)).Return(Entity{Id: 1, Name: "Company"}, nil)
+		mockRepo.On("FindByNameTx", "Company", int64(1)).Return(false, nil)
+		mockRepo.On("LockHierarchyTx").Return(nil)
+		mockRepo.On("ExistsTx", backendId).Return(true, nil)
+		mockRepo.On("IsInParentChainTx", backendId, int64(1)).Return(true, nil)
+
+		_, err := svc.UpdateDepartment(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		mockRepo.AssertNotCalled(t, "UpdateTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Own parent is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		selfId := int64(1)
+		request := UpdateRequest{Id: 1, Name: "Company", ParentId: &selfId}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(1)).Return(Entity{Id: 1, Name: "Company"}, nil)
+		mockRepo.On("FindByNameTx", "Company", int64(1)).Return(false, nil)
+
+		_, err := svc.UpdateDepartment(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := UpdateRequest{Id: 9, Name: "Company"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(9)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.UpdateDepartment(context.Background(), request)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_DeleteById(t *testing.T) {
+	t.Run("Department with dependents is not deleted", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx").Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(1)).Return(Entity{Id: 1, Name: "IT"}, nil)
+		mockRepo.On("FindEmployeeIdsTx", int64(1)).Return([]int64{4, 5}, nil)
+		mockRepo.On("FindChildIdsTx", int64(1)).Return([]int64(nil), nil)
+
+		err := svc.DeleteById(context.Background(), 1)
+
+		var conflictErr common.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		assert.Equal(t, Dependents{EmployeeIds: []int64{4, 5}, ChildDepartmentIds: []int64{}}, conflictErr.Data)
+		mockRepo.AssertNotCalled(t, "DeleteTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Successful deletion", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx").Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(1)).Return(Entity{Id: 1, Name: "IT"}, nil)
+		mockRepo.On("FindEmployeeIdsTx", int64(1)).Return([]int64{}, nil)
+		mockRepo.On("FindChildIdsTx", int64(1)).Return([]int64{}, nil)
+		mockRepo.On("DeleteTx", int64(1)).Return(nil)
+
+		err := svc.DeleteById(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionDelete, auditor.events[0].Action)
+		}
+	})
+}
+
+func TestService_FindDescendants_NotFound(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+	mockRepo.On("FindById", int64(9)).Return(Entity{}, sql.ErrNoRows)
+
+	_, err := svc.FindDescendants(context.Background(), 9)
+
+	var notFoundErr common.NotFoundError
+	assert.True(t, errors.As(err, &notFoundErr))
+	mockRepo.AssertNotCalled(t, "FindDescendants", mock.Anything)
+}
+
+func TestBuildTree(t *testing.T) {
+	companyId, engineeringId, unknownId := int64(1), int64(2), int64(99)
+	departments := []Entity{
+		{Id: 1, Name: "Company"},
+		{Id: 2, Name: "Engineering", ParentId: &companyId},
+		{Id: 3, Name: "Backend", ParentId: &engineeringId},
+		{Id: 4, Name: "Orphan", ParentId: &unknownId},
+	}
+
+	tree := buildTree(departments)
+
+	require.Len(t, tree, 2)
+	assert.Equal(t, "Company", tree[0].Name)
+	require.Len(t, tree[0].Children, 1)
+	require.Len(t, tree[0].Children[0].Children, 1)
+	assert.Equal(t, "Backend", tree[0].Children[0].Children[0].Name)
+	assert.Equal(t, "Orphan", tree[1].Name)
+}
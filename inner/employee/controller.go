@@ -1,11 +1,14 @@
 package employee
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"idm/inner/common"
 	"idm/inner/web"
+	"iter"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -22,11 +25,36 @@ type Controller struct {
 type Svc interface {
 	FindById(ctx context.Context, id int64) (Response, error)
 	CreateEmployee(ctx context.Context, request CreateRequest) (int64, error)
+	Import(ctx context.Context, request ImportRequest) (ImportReport, error)
 	DeleteById(ctx context.Context, id int64) error
-	FindAll(ctx context.Context) ([]Response, error)
+	FindWithCursor(ctx context.Context, request CursorRequest) (CursorPage, error)
+	Search(ctx context.Context, request SearchRequest) ([]SearchResponse, error)
+	Export(ctx context.Context, filter PageRequest) (iter.Seq2[ExportRecord, error], error)
 	FindByIds(ctx context.Context, ids []int64) ([]Response, error)
 	DeleteByIds(ctx context.Context, ids []int64) error
 	FindWithPagination(ctx context.Context, request PageRequest) (PageResponse, error)
+	UpdateEmployee(ctx context.Context, request UpdateRequest) (Response, error)
+	PatchEmployee(ctx context.Context, request PatchRequest) (Response, error)
+	AssignRole(ctx context.Context, request AssignRoleRequest) (RoleAssignmentResponse, error)
+	FindRoleAssignments(ctx context.Context, employeeId int64, activeOnly bool) ([]RoleAssignmentResponse, error)
+	RevokeRoleAssignment(ctx context.Context, employeeId, assignmentId int64) error
+	Restore(ctx context.Context, id int64) (Response, error)
+	Purge(ctx context.Context, request PurgeRequest) (PurgeResponse, error)
+	Hire(ctx context.Context, request HireRequest) (Response, error)
+	Suspend(ctx context.Context, request TransitionRequest) (Response, error)
+	Resume(ctx context.Context, request TransitionRequest) (Response, error)
+	Transfer(ctx context.Context, request TransferRequest) (Response, error)
+	Terminate(ctx context.Context, request TransitionRequest) (Response, error)
+	FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryResponse, error)
+	ScheduleChange(ctx context.Context, request ScheduledChangeRequest) (ScheduledChangeResponse, error)
+	FindScheduledChanges(ctx context.Context, filter ScheduledChangeFilter) ([]ScheduledChangeResponse, error)
+	UpdateScheduledChange(ctx context.Context, request ScheduledChangeRequest) (ScheduledChangeResponse, error)
+	CancelScheduledChange(ctx context.Context, id int64) (ScheduledChangeResponse, error)
+	SetManager(ctx context.Context, request SetManagerRequest) (Response, error)
+	FindDirectReports(ctx context.Context, id int64) ([]Response, error)
+	FindSubordinates(ctx context.Context, id int64) ([]HierarchyResponse, error)
+	FindManagementChain(ctx context.Context, id int64) ([]HierarchyResponse, error)
+	FindOrgChart(ctx context.Context) ([]OrgChartNode, error)
 }
 
 func NewController(server *web.Server, employeeService Svc, logger *common.Logger) *Controller {
@@ -43,15 +71,40 @@ func (c *Controller) RegisterRoutes() {
 	// полный маршрут получится "/api/v1/admin/employees"
 	// Маршруты для чтения (доступны пользователям с ролью IDM_ADMIN или IDM_USER)
 	c.server.GroupApiV1User.Get("/employees/page", c.FindEmployeesWithPagination)
+	c.server.GroupApiV1User.Get("/employees/org-chart", c.GetOrgChart)
+	c.server.GroupApiV1User.Get("/employees/search", c.SearchEmployees)
+	c.server.GroupApiV1User.Get("/employees/export", c.ExportEmployees)
 	c.server.GroupApiV1User.Get("/employees/:id", c.GetEmployee)
+	c.server.GroupApiV1User.Get("/employees/:id/roles", c.FindEmployeeRoles)
+	c.server.GroupApiV1User.Get("/employees/:id/history", c.FindEmployeeStatusHistory)
+	c.server.GroupApiV1User.Get("/employees/:id/reports", c.FindEmployeeDirectReports)
+	c.server.GroupApiV1User.Get("/employees/:id/subordinates", c.FindEmployeeSubordinates)
+	c.server.GroupApiV1User.Get("/employees/:id/chain", c.FindEmployeeManagementChain)
 	c.server.GroupApiV1User.Get("/employees", c.FindAllEmployee)
 	c.server.GroupApiV1User.Post("/employees/ids", c.FindEmployeeByIds)
 
 	// полный маршрут получится "/api/v1/employees"
 	// Маршруты для создания, изменения и удаления (доступны только администраторам)
 	c.server.GroupApiV1Admin.Post("/employees", c.CreateEmployee)
+	c.server.GroupApiV1Admin.Put("/employees/:id", c.UpdateEmployee)
+	c.server.GroupApiV1Admin.Patch("/employees/:id", c.PatchEmployee)
 	c.server.GroupApiV1Admin.Delete("/employees/:id", c.DeleteEmployee)
 	c.server.GroupApiV1Admin.Delete("/employees", c.DeleteEmployeeByIds)
+	c.server.GroupApiV1Admin.Post("/employees/:id/roles", c.AssignEmployeeRole)
+	c.server.GroupApiV1Admin.Delete("/employees/:id/roles/:assignmentId", c.RevokeEmployeeRole)
+	c.server.GroupApiV1Admin.Post("/employees/purge", c.PurgeEmployees)
+	c.server.GroupApiV1Admin.Post("/employees/import", c.ImportEmployees)
+	c.server.GroupApiV1Admin.Post("/employees/:id/restore", c.RestoreEmployee)
+	c.server.GroupApiV1Admin.Post("/employees/:id/hire", c.HireEmployee)
+	c.server.GroupApiV1Admin.Post("/employees/:id/suspend", c.SuspendEmployee)
+	c.server.GroupApiV1Admin.Post("/employees/:id/resume", c.ResumeEmployee)
+	c.server.GroupApiV1Admin.Post("/employees/:id/transfer", c.TransferEmployee)
+	c.server.GroupApiV1Admin.Post("/employees/:id/terminate", c.TerminateEmployee)
+	c.server.GroupApiV1Admin.Put("/employees/:id/manager", c.SetEmployeeManager)
+	c.server.GroupApiV1Admin.Post("/employees/:id/scheduled-changes", c.ScheduleEmployeeChange)
+	c.server.GroupApiV1Admin.Get("/scheduled-changes", c.FindScheduledChanges)
+	c.server.GroupApiV1Admin.Put("/scheduled-changes/:changeId", c.UpdateScheduledChange)
+	c.server.GroupApiV1Admin.Post("/scheduled-changes/:changeId/cancel", c.CancelScheduledChange)
 
 	c.logger.Info("Employee routes registered successfully")
 }
@@ -94,7 +147,7 @@ func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 	c.logger.Debug("create employee: received request", zap.Any("request", request))
 
 	// context.Context нужен для поддержки отмены, дедлайнов и трейсинга запросов к БД.
-	newEmployeeId, err := c.employeeService.CreateEmployee(ctx.Context(), request)
+	newEmployeeId, err := c.employeeService.CreateEmployee(ctx.UserContext(), request)
 	if err != nil {
 		return c.handleCreateEmployeeError(ctx, err, request)
 	}
@@ -147,8 +200,7 @@ func (c *Controller) GetEmployee(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID")
 	}
 
-	// context.Context нужен для поддержки отмены, дедлайнов и трейсинга запросов к БД.
-	employee, err := c.employeeService.FindById(ctx.Context(), id)
+	employee, err := c.employeeService.FindById(ctx.UserContext(), id)
 	if err != nil {
 		return c.handleFindEmployeeError(ctx, err, id)
 	}
@@ -157,6 +209,8 @@ func (c *Controller) GetEmployee(ctx *fiber.Ctx) error {
 		zap.Int64("id", id),
 		zap.String("ip", ctx.IP()))
 
+	// версия записи нужна клиенту для последующего обновления (If-Match)
+	ctx.Set(fiber.HeaderETag, versionETag(employee.UpdatedAt))
 	return common.OkResponse(ctx, employee)
 }
 
@@ -196,8 +250,7 @@ func (c *Controller) DeleteEmployee(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
 	}
 
-	// context.Context нужен для поддержки отмены, дедлайнов и трейсинга запросов к БД.
-	err = c.employeeService.DeleteById(ctx.Context(), id)
+	err = c.employeeService.DeleteById(ctx.UserContext(), id)
 	if err != nil {
 		return c.handleDeleteEmployeeError(ctx, err, id)
 	}
@@ -209,16 +262,20 @@ func (c *Controller) DeleteEmployee(ctx *fiber.Ctx) error {
 	return common.OkResponse(ctx, fiber.Map{"message": "Employee deleted successfully"})
 }
 
-// FindAllEmployee получает всех сотрудников
+// FindAllEmployee получает сотрудников постранично по курсору
 //
 // @Security		OAuth2AccessCode[read]
 //
-//	@Summary		Get all employees
-//	@Description	Obtain a list of all employees.
+//	@Summary		Get employees with cursor pagination
+//	@Description	Obtain a list of employees page by page. Pass next or prev from the response as cursor to get the adjacent page; a cursor is valid only with the sort it was issued for
 //	@Tags			employees
 //	@Produce		json
-//	@Success		200	{array}		common.Response[any]	"List of employees"
-//	@Failure		500	{object}	common.Response[any]	"Error when getting the list of employees"
+//	@Param			limit	query		int											false	"Number of items on page"	default(50)
+//	@Param			sort	query		string										false	"One of id, name, email, status, created_at, updated_at; '-' for descending"	example(-created_at)
+//	@Param			cursor	query		string										false	"Cursor from the previous response"
+//	@Success		200		{object}	common.Response[CursorPageResponse]	"Page of employees"
+//	@Failure		400		{object}	common.Response[any]						"Invalid limit, sort or cursor"
+//	@Failure		500		{object}	common.Response[any]						"Error when getting the list of employees"
 //	@Router			/employees [get]
 func (c *Controller) FindAllEmployee(ctx *fiber.Ctx) error {
 	c.logger.Debug("Received find all employees request",
@@ -226,23 +283,96 @@ func (c *Controller) FindAllEmployee(ctx *fiber.Ctx) error {
 		zap.String("path", ctx.Path()),
 		zap.String("ip", ctx.IP()))
 
-	userRoles := web.GetUserRoles(ctx)
-	c.logger.Debug("User roles", zap.Strings("roles", userRoles))
+	limit, err := strconv.Atoi(ctx.Query("limit", "50"))
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid limit parameter")
+	}
+	cursor, err := c.server.Cursors.DecodeOptional(ctx.Query("cursor"))
+	if err != nil {
+		c.logger.Warn("Invalid employees cursor",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid cursor parameter")
+	}
 
-	// context.Context нужен для поддержки отмены, дедлайнов и трейсинга запросов к БД.
-	employees, err := c.employeeService.FindAll(ctx.Context())
+	page, err := c.employeeService.FindWithCursor(ctx.UserContext(), CursorRequest{
+		Limit:  limit,
+		Sort:   ctx.Query("sort"),
+		Cursor: cursor,
+	})
 	if err != nil {
+		var validationErr common.RequestValidationError
+		if errors.As(err, &validationErr) {
+			if validationErr.Data != nil {
+				return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message, validationErr.Data)
+			}
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+		}
 		c.logger.Error("Failed to find all employees",
 			zap.Error(err),
 			zap.String("ip", ctx.IP()))
 		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when getting the list of employees")
 	}
 
-	c.logger.Debug("All employees retrieved successfully",
-		zap.Int("count", len(employees)),
+	c.logger.Debug("Employees page retrieved successfully",
+		zap.Int("count", len(page.Data)),
 		zap.String("ip", ctx.IP()))
 
-	return common.OkResponse(ctx, employees)
+	return common.OkResponse(ctx, CursorPageResponse{
+		Data: page.Data,
+		Next: c.server.Cursors.EncodeOptional(page.Next),
+		Prev: c.server.Cursors.EncodeOptional(page.Prev),
+	})
+}
+
+// SearchEmployees ищет сотрудников по имени, email, должности и отделу
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Search employees
+//	@Description	Full-text and fuzzy search by name, email, position and department. Words are matched by prefix and by similarity, Cyrillic and Latin spellings find each other. Results are ordered by relevance
+//	@Tags			employees
+//	@Produce		json
+//	@Param			q		query		string									true	"Search query"	example(иванов)
+//	@Param			limit	query		int										false	"Maximum number of results"	default(20)
+//	@Success		200		{object}	common.Response[[]SearchResponse]	"Found employees"
+//	@Failure		400		{object}	common.Response[any]					"Invalid query or limit"
+//	@Failure		500		{object}	common.Response[any]					"Error when searching employees"
+//	@Router			/employees/search [get]
+func (c *Controller) SearchEmployees(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received search employees request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	limit, err := strconv.Atoi(ctx.Query("limit", "20"))
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid limit parameter")
+	}
+
+	results, err := c.employeeService.Search(ctx.UserContext(), SearchRequest{
+		Query: ctx.Query("q"),
+		Limit: limit,
+	})
+	if err != nil {
+		var validationErr common.RequestValidationError
+		if errors.As(err, &validationErr) {
+			if validationErr.Data != nil {
+				return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message, validationErr.Data)
+			}
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+		}
+		c.logger.Error("Failed to search employees",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when searching employees")
+	}
+
+	c.logger.Debug("Employees search completed",
+		zap.Int("count", len(results)),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, results)
 }
 
 // FindEmployeeByIds получает сотрудников по списку ID
@@ -287,8 +417,7 @@ func (c *Controller) FindEmployeeByIds(ctx *fiber.Ctx) error {
 		zap.Int64s("ids", request.Ids),
 		zap.String("ip", ctx.IP()))
 
-	// context.Context нужен для поддержки отмены, дедлайнов и трейсинга запросов к БД.
-	employees, err := c.employeeService.FindByIds(ctx.Context(), request.Ids)
+	employees, err := c.employeeService.FindByIds(ctx.UserContext(), request.Ids)
 	if err != nil {
 		c.logger.Error("Failed to find employees by IDs",
 			zap.Int64s("ids", request.Ids),
@@ -316,6 +445,14 @@ func (c *Controller) FindEmployeeByIds(ctx *fiber.Ctx) error {
 //	@Param			pageNumber	query		int						false	"Page number"				default(1)
 //	@Param			pageSize	query		int						false	"Number of items on page"	default(10)
 //	@Param			textFilter	query		string					false	"Text filter (name, email)"	example("John")
+//	@Param			departmentId	query	[]int					false	"Department IDs, subdepartments included"	collectionFormat(multi)
+//	@Param			email		query		string					false	"Email substring, case-insensitive"	example("@example.com")
+//	@Param			positionId	query		[]int					false	"Position IDs"	collectionFormat(multi)
+//	@Param			roleId		query		[]int					false	"Role IDs, only active assignments match"	collectionFormat(multi)
+//	@Param			status		query		[]string				false	"Statuses"	Enums(pending, active, suspended, terminated)	collectionFormat(multi)
+//	@Param			createdFrom	query		string					false	"Created at or after, RFC 3339"	example(2025-01-01T00:00:00Z)
+//	@Param			createdTo	query		string					false	"Created before, RFC 3339"	example(2026-01-01T00:00:00Z)
+//	@Param			sort		query		string					false	"Sort fields: id, name, email, status, hire_date, start_date, created_at, updated_at; '-' for descending"	example(name,-created_at)
 //	@Success		200			{object}	PageResponse			"List of employees with pagination"
 //	@Failure		400			{object}	common.Response[any]	"Error when getting paginated employees"
 //	@Router			/employees/page [get]
@@ -332,7 +469,14 @@ func (c *Controller) FindEmployeesWithPagination(ctx *fiber.Ctx) error {
 	// Получение параметров из query string
 	pageNumberStr := ctx.Query("pageNumber", "1")
 	pageSizeStr := ctx.Query("pageSize", "10")
-	textFilter := ctx.Query("textFilter", "")
+
+	pageRequest, param, err := parsePageFilter(ctx)
+	if err != nil {
+		c.logger.Error("Invalid "+param+" parameter",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid "+param+" parameter")
+	}
 
 	// Конвертация в числа
 	pageNumber, err := strconv.Atoi(pageNumberStr)
@@ -354,11 +498,8 @@ func (c *Controller) FindEmployeesWithPagination(ctx *fiber.Ctx) error {
 	}
 
 	// запрос пагинации с фильтром
-	pageRequest := PageRequest{
-		PageNumber: pageNumber,
-		PageSize:   pageSize,
-		TextFilter: textFilter,
-	}
+	pageRequest.PageNumber = pageNumber
+	pageRequest.PageSize = pageSize
 
 	// ВАЖНО: Создание нового контекса для работы с БД
 	// Это предотвращает проблему с отмененным контекстом в тестах
@@ -367,11 +508,18 @@ func (c *Controller) FindEmployeesWithPagination(ctx *fiber.Ctx) error {
 
 	pageResponse, err := c.employeeService.FindWithPagination(dbCtx, pageRequest)
 	if err != nil {
+		var validationErr common.RequestValidationError
+		if errors.As(err, &validationErr) {
+			if validationErr.Data != nil {
+				return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message, validationErr.Data)
+			}
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+		}
 		c.logger.Error("Failed to find employees with pagination",
 			zap.Error(err),
 			zap.Int("pageNumber", pageNumber),
 			zap.Int("pageSize", pageSize),
-			zap.String("textFilter", textFilter),
+			zap.String("textFilter", pageRequest.TextFilter),
 			zap.String("ip", ctx.IP()))
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Error when getting paginated employees")
 	}
@@ -379,7 +527,7 @@ func (c *Controller) FindEmployeesWithPagination(ctx *fiber.Ctx) error {
 	c.logger.Debug("Paginated employees retrieved successfully",
 		zap.Int("pageNumber", pageResponse.PageNumber),
 		zap.Int("pageSize", pageResponse.PageSize),
-		zap.String("textFilter", textFilter),
+		zap.String("textFilter", pageRequest.TextFilter),
 		zap.Int64("totalCount", pageResponse.TotalCount),
 		zap.Int("totalPages", pageResponse.TotalPages),
 		zap.Int("dataCount", len(pageResponse.Data)),
@@ -388,6 +536,109 @@ func (c *Controller) FindEmployeesWithPagination(ctx *fiber.Ctx) error {
 	return common.OkResponse(ctx, pageResponse)
 }
 
+// разбирает фильтры и сортировку страницы сотрудников из query string.
+// При ошибке возвращает название параметра с неверным значением
+func parsePageFilter(ctx *fiber.Ctx) (PageRequest, string, error) {
+	departmentIds, err := parseIdList(ctx, "departmentId")
+	if err != nil {
+		return PageRequest{}, "departmentId", err
+	}
+	positionIds, err := parseIdList(ctx, "positionId")
+	if err != nil {
+		return PageRequest{}, "positionId", err
+	}
+	roleIds, err := parseIdList(ctx, "roleId")
+	if err != nil {
+		return PageRequest{}, "roleId", err
+	}
+	createdFrom, err := parseTimeQuery(ctx, "createdFrom")
+	if err != nil {
+		return PageRequest{}, "createdFrom", err
+	}
+	createdTo, err := parseTimeQuery(ctx, "createdTo")
+	if err != nil {
+		return PageRequest{}, "createdTo", err
+	}
+
+	return PageRequest{
+		TextFilter:    ctx.Query("textFilter", ""),
+		Email:         ctx.Query("email"),
+		DepartmentIds: departmentIds,
+		PositionIds:   positionIds,
+		RoleIds:       roleIds,
+		Statuses:      parseStringList(ctx, "status"),
+		CreatedFrom:   createdFrom,
+		CreatedTo:     createdTo,
+		Sort:          ctx.Query("sort"),
+	}, "", nil
+}
+
+// ExportEmployees выгружает сотрудников в CSV или JSON Lines
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Export employees
+//	@Description	Stream all employees matching the filters of the page endpoint, in its sort order. Position, department, manager and active roles are exported by name
+//	@Tags			employees
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Param			format		query		string					false	"Export format"	Enums(csv, jsonl)	default(csv)
+//	@Param			textFilter	query		string					false	"Text filter (name, email)"	example("John")
+//	@Param			departmentId	query	[]int					false	"Department IDs, subdepartments included"	collectionFormat(multi)
+//	@Param			email		query		string					false	"Email substring, case-insensitive"	example("@example.com")
+//	@Param			positionId	query		[]int					false	"Position IDs"	collectionFormat(multi)
+//	@Param			roleId		query		[]int					false	"Role IDs, only active assignments match"	collectionFormat(multi)
+//	@Param			status		query		[]string				false	"Statuses"	Enums(pending, active, suspended, terminated)	collectionFormat(multi)
+//	@Param			createdFrom	query		string					false	"Created at or after, RFC 3339"	example(2025-01-01T00:00:00Z)
+//	@Param			createdTo	query		string					false	"Created before, RFC 3339"	example(2026-01-01T00:00:00Z)
+//	@Param			sort		query		string					false	"Sort fields: id, name, email, status, hire_date, start_date, created_at, updated_at; '-' for descending"	example(name,-created_at)
+//	@Success		200			{array}		ExportRecord			"Employees, one per line"
+//	@Failure		400			{object}	common.Response[any]	"Invalid format or filter"
+//	@Failure		500			{object}	common.Response[any]	"Error when exporting employees"
+//	@Router			/employees/export [get]
+func (c *Controller) ExportEmployees(ctx *fiber.Ctx) error {
+	c.logger.Info("Received export employees request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("query", ctx.OriginalURL()),
+		zap.String("ip", ctx.IP()))
+
+	format, err := common.ParseExportFormat(ctx.Query("format"))
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid format parameter")
+	}
+	filter, param, err := parsePageFilter(ctx)
+	if err != nil {
+		c.logger.Error("Invalid "+param+" parameter",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid "+param+" parameter")
+	}
+
+	// контекст запроса к базе должен жить, пока выгрузка пишется в ответ после возврата из обработчика
+	records, err := c.employeeService.Export(ctx.UserContext(), filter)
+	if err != nil {
+		var validationErr common.RequestValidationError
+		if errors.As(err, &validationErr) {
+			if validationErr.Data != nil {
+				return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message, validationErr.Data)
+			}
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+		}
+		c.logger.Error("Failed to export employees",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when exporting employees")
+	}
+
+	ip := ctx.IP()
+	return common.StreamExport(ctx, format, "employees", ExportHeader, records, func(err error) {
+		c.logger.Error("Employees export interrupted",
+			zap.Error(err),
+			zap.String("ip", ip))
+	})
+}
+
 // DeleteEmployeeByIds удаляет сотрудников по списку ID
 //
 // @Security		OAuth2AccessCode[write]
@@ -431,8 +682,7 @@ func (c *Controller) DeleteEmployeeByIds(ctx *fiber.Ctx) error {
 		zap.Int64s("ids", request.Ids),
 		zap.String("ip", ctx.IP()))
 
-	// context.Context нужен для поддержки отмены, дедлайнов и трейсинга запросов к БД.
-	err := c.employeeService.DeleteByIds(ctx.Context(), request.Ids)
+	err := c.employeeService.DeleteByIds(ctx.UserContext(), request.Ids)
 	if err != nil {
 		c.logger.Error("Failed to delete employees by IDs",
 			zap.Int64s("ids", request.Ids),
@@ -448,77 +698,1255 @@ func (c *Controller) DeleteEmployeeByIds(ctx *fiber.Ctx) error {
 	return common.OkResponse(ctx, fiber.Map{"message": "Employees deleted successfully"})
 }
 
-// обрабатывает ошибки при создании сотрудника
-func (c *Controller) handleCreateEmployeeError(ctx *fiber.Ctx, err error, request CreateRequest) error {
-	switch {
-	// Обработка ошибок валидации
-	case errors.As(err, &common.RequestValidationError{}):
-		c.logger.Warn("Create employee validation error",
-			zap.String("name", request.Name),
+// UpdateEmployee полностью обновляет сотрудника
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Update employee
+//	@Description	Full update of an employee. The version (updated_at) must be passed in the If-Match header or the version field
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int						true	"Employee ID"
+//	@Param			If-Match	header		string					false	"Employee version (updated_at) received on read"
+//	@Param			request		body		employee.UpdateRequest	true	"update employee request"
+//	@Success		200			{object}	common.Response[any]	"Updated employee"
+//	@Failure		400			{object}	common.Response[any]	"Incorrect data format in request"
+//	@Failure		404			{object}	common.Response[any]	"Employee not found"
+//	@Failure		409			{object}	common.Response[any]	"Employee was modified by another request"
+//	@Failure		500			{object}	common.Response[any]	"Internal server error"
+//	@Router			/employees/{id} [put]
+func (c *Controller) UpdateEmployee(ctx *fiber.Ctx) error {
+	c.logger.Info("Received update employee request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse update employee request body",
 			zap.Error(err),
 			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.Id = id
 
-		// Получаем детали ошибки валидации
-		var validationErr common.RequestValidationError
-		errors.As(err, &validationErr)
+	version, err := c.versionFromRequest(ctx, request.Version)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid If-Match header")
+	}
+	request.Version = version
 
-		if validationErr.Data != nil {
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
-		}
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+	updated, err := c.employeeService.UpdateEmployee(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, id)
+	}
 
-	// Обработка ошибок существования
-	case errors.As(err, &common.AlreadyExistsError{}):
-		c.logger.Warn("Create employee conflict error",
-			zap.String("name", request.Name),
-			zap.Error(err),
-			zap.String("ip", ctx.IP()))
-		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+	c.logger.Info("Employee updated successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
 
-	// Обработка других ошибок
-	default:
-		c.logger.Error("Create employee internal error",
-			zap.String("name", request.Name),
+	ctx.Set(fiber.HeaderETag, versionETag(updated.UpdatedAt))
+	return common.OkResponse(ctx, updated)
+}
+
+// PatchEmployee частично обновляет сотрудника
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Patch employee
+//	@Description	Partial update of an employee. The version (updated_at) must be passed in the If-Match header or the version field
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int						true	"Employee ID"
+//	@Param			If-Match	header		string					false	"Employee version (updated_at) received on read"
+//	@Param			request		body		employee.PatchRequest	true	"patch employee request"
+//	@Success		200			{object}	common.Response[any]	"Updated employee"
+//	@Failure		400			{object}	common.Response[any]	"Incorrect data format in request"
+//	@Failure		404			{object}	common.Response[any]	"Employee not found"
+//	@Failure		409			{object}	common.Response[any]	"Employee was modified by another request"
+//	@Failure		500			{object}	common.Response[any]	"Internal server error"
+//	@Router			/employees/{id} [patch]
+func (c *Controller) PatchEmployee(ctx *fiber.Ctx) error {
+	c.logger.Info("Received patch employee request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	var request PatchRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse patch employee request body",
 			zap.Error(err),
 			zap.String("ip", ctx.IP()))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.Id = id
+
+	version, err := c.versionFromRequest(ctx, request.Version)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid If-Match header")
+	}
+	request.Version = version
+
+	updated, err := c.employeeService.PatchEmployee(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, id)
 	}
+
+	c.logger.Info("Employee patched successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	ctx.Set(fiber.HeaderETag, versionETag(updated.UpdatedAt))
+	return common.OkResponse(ctx, updated)
 }
 
-// обрабатывает ошибки при поиске сотрудника
-func (c *Controller) handleFindEmployeeError(ctx *fiber.Ctx, err error, id int64) error {
-	switch {
-	case errors.As(err, &common.NotFoundError{}):
-		c.logger.Warn("Employee not found",
-			zap.Int64("id", id),
-			zap.Error(err),
-			zap.String("ip", ctx.IP()))
-		return common.ErrResponse(ctx, fiber.StatusNotFound, "Employee not found")
+// FindEmployeeRoles возвращает назначения ролей сотрудника
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get employee role assignments
+//	@Description	Role assignments of an employee. By default the whole history is returned, active=true returns only assignments valid now
+//	@Tags			employees
+//	@Produce		json
+//	@Param			id		path		int						true	"Employee ID"
+//	@Param			active	query		bool					false	"Only active assignments"
+//	@Success		200		{object}	common.Response[any]	"Role assignments"
+//	@Failure		400		{object}	common.Response[any]	"Invalid employee ID format"
+//	@Failure		404		{object}	common.Response[any]	"Employee not found"
+//	@Failure		500		{object}	common.Response[any]	"Internal server error"
+//	@Router			/employees/{id}/roles [get]
+func (c *Controller) FindEmployeeRoles(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find employee roles request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
 
-	default:
-		c.logger.Error("Find employee internal error",
-			zap.Int64("id", id),
-			zap.Error(err),
-			zap.String("ip", ctx.IP()))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	assignments, err := c.employeeService.FindRoleAssignments(ctx.UserContext(), id, ctx.QueryBool("active"))
+	if err != nil {
+		return c.handleRoleAssignmentError(ctx, err, id)
 	}
+
+	return common.OkResponse(ctx, assignments)
 }
 
-// обрабатывает ошибки при удалении сотрудника
-func (c *Controller) handleDeleteEmployeeError(ctx *fiber.Ctx, err error, id int64) error {
-	switch {
-	case errors.As(err, &common.NotFoundError{}):
-		c.logger.Warn("Employee not found for deletion",
-			zap.Int64("id", id),
+// AssignEmployeeRole назначает сотруднику роль
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Assign role to employee
+//	@Description	Assign a role for a period. Without valid_from the assignment starts now, without valid_to it is open-ended
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Employee ID"
+//	@Param			request	body		employee.AssignRoleRequest	true	"assign role request"
+//	@Success		200		{object}	common.Response[any]		"Created assignment"
+//	@Failure		400		{object}	common.Response[any]		"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]		"Employee not found"
+//	@Failure		409		{object}	common.Response[any]		"Role is already assigned for an overlapping period"
+//	@Failure		500		{object}	common.Response[any]		"Internal server error"
+//	@Router			/admin/employees/{id}/roles [post]
+func (c *Controller) AssignEmployeeRole(ctx *fiber.Ctx) error {
+	c.logger.Info("Received assign employee role request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	var request AssignRoleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse assign role request body",
 			zap.Error(err),
 			zap.String("ip", ctx.IP()))
-		return common.ErrResponse(ctx, fiber.StatusNotFound, "Employee not found")
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.EmployeeId = id
 
-	default:
-		c.logger.Error("Delete employee internal error",
-			zap.Int64("id", id),
+	assignment, err := c.employeeService.AssignRole(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleRoleAssignmentError(ctx, err, id)
+	}
+
+	c.logger.Info("Role assigned to employee successfully",
+		zap.Int64("id", id),
+		zap.Int64("role_id", request.RoleId),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, assignment)
+}
+
+// RevokeEmployeeRole отзывает назначение роли у сотрудника
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Revoke employee role assignment
+//	@Description	An active assignment is closed at the current moment, a future one is removed
+//	@Tags			employees
+//	@Produce		json
+//	@Param			id				path		int						true	"Employee ID"
+//	@Param			assignmentId	path		int						true	"Assignment ID"
+//	@Success		200				{object}	common.Response[any]	"Assignment revoked"
+//	@Failure		400				{object}	common.Response[any]	"Invalid ID format"
+//	@Failure		404				{object}	common.Response[any]	"Employee or active assignment not found"
+//	@Failure		500				{object}	common.Response[any]	"Internal server error"
+//	@Router			/admin/employees/{id}/roles/{assignmentId} [delete]
+func (c *Controller) RevokeEmployeeRole(ctx *fiber.Ctx) error {
+	c.logger.Info("Received revoke employee role request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	assignmentParam := ctx.Params("assignmentId")
+	assignmentId, err := strconv.ParseInt(assignmentParam, 10, 64)
+	if err != nil {
+		c.logger.Error("Invalid assignment ID format",
+			zap.String("assignment_id", assignmentParam),
 			zap.Error(err),
 			zap.String("ip", ctx.IP()))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid assignment ID format")
+	}
+
+	if err := c.employeeService.RevokeRoleAssignment(ctx.UserContext(), id, assignmentId); err != nil {
+		return c.handleRoleAssignmentError(ctx, err, id)
+	}
+
+	c.logger.Info("Employee role assignment revoked successfully",
+		zap.Int64("id", id),
+		zap.Int64("assignment_id", assignmentId),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, fiber.Map{"message": "Role assignment revoked successfully"})
+}
+
+// RestoreEmployee восстанавливает мягко удалённого сотрудника
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Restore deleted employee
+//	@Description	Restore a soft-deleted employee. Fails if the name or email is already taken by an active employee
+//	@Tags			employees
+//	@Produce		json
+//	@Param			id	path		int						true	"Employee ID"
+//	@Success		200	{object}	common.Response[any]	"Restored employee"
+//	@Failure		400	{object}	common.Response[any]	"Invalid employee ID format"
+//	@Failure		404	{object}	common.Response[any]	"Deleted employee not found"
+//	@Failure		409	{object}	common.Response[any]	"Name or email already taken"
+//	@Failure		500	{object}	common.Response[any]	"Internal server error"
+//	@Router			/admin/employees/{id}/restore [post]
+func (c *Controller) RestoreEmployee(ctx *fiber.Ctx) error {
+	c.logger.Info("Received restore employee request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	employee, err := c.employeeService.Restore(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, id)
+	}
+
+	c.logger.Info("Employee restored successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	ctx.Set(fiber.HeaderETag, versionETag(employee.UpdatedAt))
+	return common.OkResponse(ctx, employee)
+}
+
+// PurgeEmployees окончательно удаляет сотрудников, мягко удалённых раньше срока хранения
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Purge deleted employees
+//	@Description	Permanently remove employees soft-deleted more than retention_days days ago
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		employee.PurgeRequest	true	"purge request"
+//	@Success		200		{object}	common.Response[any]	"Ids of purged employees"
+//	@Failure		400		{object}	common.Response[any]	"Incorrect data format in request"
+//	@Failure		500		{object}	common.Response[any]	"Internal server error"
+//	@Router			/admin/employees/purge [post]
+func (c *Controller) PurgeEmployees(ctx *fiber.Ctx) error {
+	c.logger.Info("Received purge employees request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request PurgeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse purge request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	response, err := c.employeeService.Purge(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, 0)
+	}
+
+	c.logger.Info("Deleted employees purged successfully",
+		zap.Int("count", len(response.PurgedIds)),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, response)
+}
+
+// режимы фиксации импорта
+const (
+	importModeAllOrNothing = "all_or_nothing"
+	importModeBestEffort   = "best_effort"
+)
+
+// ImportEmployees массово создаёт сотрудников из файла CSV или JSON Lines
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Import employees
+//	@Description	Create employees from a CSV file with a header row or from JSON Lines. Columns and fields: name, email, position_id, department_id, role_id or role (role name), hire_date, start_date.
+//	@Description	Every row is validated and reported as created, skipped (employee with the same name or email exists) or failed.
+//	@Description	In all_or_nothing mode any failed row cancels the whole import, in best_effort mode valid rows are created. With dryRun=true nothing is saved
+//	@Tags			employees
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			dryRun	query		bool								false	"Only validate rows"	default(false)
+//	@Param			mode	query		string								false	"Commit mode"	Enums(all_or_nothing, best_effort)	default(all_or_nothing)
+//	@Param			file	body		string								true	"CSV or JSON Lines content"
+//	@Success		200		{object}	common.Response[ImportReport]	"Per-row import report"
+//	@Failure		400		{object}	common.Response[any]				"Invalid file or parameters"
+//	@Failure		415		{object}	common.Response[any]				"Unsupported content type"
+//	@Failure		500		{object}	common.Response[any]				"Internal server error"
+//	@Router			/admin/employees/import [post]
+func (c *Controller) ImportEmployees(ctx *fiber.Ctx) error {
+	c.logger.Info("Received import employees request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	format := ImportFormat(ctx.Get(fiber.HeaderContentType))
+	if format == "" {
+		return common.ErrResponse(ctx, fiber.StatusUnsupportedMediaType,
+			"Content type must be text/csv or application/x-ndjson")
+	}
+	mode := ctx.Query("mode", importModeAllOrNothing)
+	if mode != importModeAllOrNothing && mode != importModeBestEffort {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid mode parameter")
+	}
+
+	rows, err := ParseImport(format, bytes.NewReader(ctx.Body()))
+	if err != nil {
+		c.logger.Warn("Failed to parse import file",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid import file: "+err.Error())
+	}
+
+	report, err := c.employeeService.Import(ctx.UserContext(), ImportRequest{
+		Rows:       rows,
+		DryRun:     ctx.QueryBool("dryRun"),
+		BestEffort: mode == importModeBestEffort,
+	})
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, 0)
+	}
+
+	c.logger.Info("Employees import processed",
+		zap.Int("created", report.Created),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("failed", report.Failed),
+		zap.Bool("committed", report.Committed),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, report)
+}
+
+// HireEmployee оформляет сотрудника, ожидающего выхода на работу
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Hire employee
+//	@Description	Transition pending -> active. Dates not passed in the request are taken from the employee card
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Employee ID"
+//	@Param			request	body		employee.HireRequest	true	"hire request"
+//	@Success		200		{object}	common.Response[any]	"Hired employee"
+//	@Failure		400		{object}	common.Response[any]	"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]	"Employee not found"
+//	@Failure		409		{object}	common.Response[any]	"Transition is not allowed in the current status"
+//	@Failure		500		{object}	common.Response[any]	"Internal server error"
+//	@Router			/admin/employees/{id}/hire [post]
+func (c *Controller) HireEmployee(ctx *fiber.Ctx) error {
+	c.logger.Info("Received hire employee request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	var request HireRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse hire request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.EmployeeId = id
+
+	employee, err := c.employeeService.Hire(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, id)
+	}
+
+	c.logger.Info("Employee hired successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	ctx.Set(fiber.HeaderETag, versionETag(employee.UpdatedAt))
+	return common.OkResponse(ctx, employee)
+}
+
+// SuspendEmployee приостанавливает работу сотрудника
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Suspend employee
+//	@Description	Transition active -> suspended. Role assignments are kept but grant nothing until the employee is resumed
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Employee ID"
+//	@Param			request	body		employee.TransitionRequest	true	"transition request"
+//	@Success		200		{object}	common.Response[any]		"Suspended employee"
+//	@Failure		400		{object}	common.Response[any]		"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]		"Employee not found"
+//	@Failure		409		{object}	common.Response[any]		"Transition is not allowed in the current status"
+//	@Failure		500		{object}	common.Response[any]		"Internal server error"
+//	@Router			/admin/employees/{id}/suspend [post]
+func (c *Controller) SuspendEmployee(ctx *fiber.Ctx) error {
+	return c.changeEmployeeStatus(ctx, TransitionSuspend, c.employeeService.Suspend)
+}
+
+// ResumeEmployee возобновляет работу приостановленного сотрудника
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Resume employee
+//	@Description	Transition suspended -> active
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Employee ID"
+//	@Param			request	body		employee.TransitionRequest	true	"transition request"
+//	@Success		200		{object}	common.Response[any]		"Resumed employee"
+//	@Failure		400		{object}	common.Response[any]		"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]		"Employee not found"
+//	@Failure		409		{object}	common.Response[any]		"Transition is not allowed in the current status"
+//	@Failure		500		{object}	common.Response[any]		"Internal server error"
+//	@Router			/admin/employees/{id}/resume [post]
+func (c *Controller) ResumeEmployee(ctx *fiber.Ctx) error {
+	return c.changeEmployeeStatus(ctx, TransitionResume, c.employeeService.Resume)
+}
+
+// TerminateEmployee увольняет сотрудника и отзывает все его роли
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Terminate employee
+//	@Description	Transition to terminated. All role assignments are revoked, terminated is a final status
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Employee ID"
+//	@Param			request	body		employee.TransitionRequest	true	"transition request"
+//	@Success		200		{object}	common.Response[any]		"Terminated employee"
+//	@Failure		400		{object}	common.Response[any]		"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]		"Employee not found"
+//	@Failure		409		{object}	common.Response[any]		"Transition is not allowed in the current status"
+//	@Failure		500		{object}	common.Response[any]		"Internal server error"
+//	@Router			/admin/employees/{id}/terminate [post]
+func (c *Controller) TerminateEmployee(ctx *fiber.Ctx) error {
+	return c.changeEmployeeStatus(ctx, TransitionTerminate, c.employeeService.Terminate)
+}
+
+// TransferEmployee переводит сотрудника на другую должность или в другой отдел
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Transfer employee
+//	@Description	Change position and/or department of an active or suspended employee. Only passed fields are changed
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Employee ID"
+//	@Param			request	body		employee.TransferRequest	true	"transfer request"
+//	@Success		200		{object}	common.Response[any]		"Transferred employee"
+//	@Failure		400		{object}	common.Response[any]		"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]		"Employee not found"
+//	@Failure		409		{object}	common.Response[any]		"Transition is not allowed in the current status"
+//	@Failure		500		{object}	common.Response[any]		"Internal server error"
+//	@Router			/admin/employees/{id}/transfer [post]
+func (c *Controller) TransferEmployee(ctx *fiber.Ctx) error {
+	c.logger.Info("Received transfer employee request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	var request TransferRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse transfer request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.EmployeeId = id
+
+	employee, err := c.employeeService.Transfer(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, id)
+	}
+
+	c.logger.Info("Employee transferred successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	ctx.Set(fiber.HeaderETag, versionETag(employee.UpdatedAt))
+	return common.OkResponse(ctx, employee)
+}
+
+// FindEmployeeStatusHistory возвращает историю переходов сотрудника между состояниями
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get employee status history
+//	@Description	Lifecycle transitions of an employee with reason and actor, oldest first
+//	@Tags			employees
+//	@Produce		json
+//	@Param			id	path		int						true	"Employee ID"
+//	@Success		200	{object}	common.Response[any]	"Status history"
+//	@Failure		400	{object}	common.Response[any]	"Invalid employee ID format"
+//	@Failure		404	{object}	common.Response[any]	"Employee not found"
+//	@Failure		500	{object}	common.Response[any]	"Internal server error"
+//	@Router			/employees/{id}/history [get]
+func (c *Controller) FindEmployeeStatusHistory(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find employee status history request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	history, err := c.employeeService.FindStatusHistory(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleFindEmployeeError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, history)
+}
+
+// SetEmployeeManager назначает сотруднику руководителя
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Set employee manager
+//	@Description	Set the direct manager of an employee. manager_id 0 removes the manager. A manager cannot report to the employee, directly or indirectly
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int							true	"Employee ID"
+//	@Param			request	body		employee.SetManagerRequest	true	"set manager request"
+//	@Success		200		{object}	common.Response[any]		"Updated employee"
+//	@Failure		400		{object}	common.Response[any]		"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]		"Employee not found"
+//	@Failure		409		{object}	common.Response[any]		"Reporting line would form a cycle or manager is terminated"
+//	@Failure		500		{object}	common.Response[any]		"Internal server error"
+//	@Router			/admin/employees/{id}/manager [put]
+func (c *Controller) SetEmployeeManager(ctx *fiber.Ctx) error {
+	c.logger.Info("Received set employee manager request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	var request SetManagerRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse set manager request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.EmployeeId = id
+
+	employee, err := c.employeeService.SetManager(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, id)
+	}
+
+	c.logger.Info("Employee manager set successfully",
+		zap.Int64("id", id),
+		zap.Int64("manager_id", request.ManagerId),
+		zap.String("ip", ctx.IP()))
+
+	ctx.Set(fiber.HeaderETag, versionETag(employee.UpdatedAt))
+	return common.OkResponse(ctx, employee)
+}
+
+// FindEmployeeDirectReports возвращает непосредственных подчинённых сотрудника
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get direct reports
+//	@Description	Employees whose direct manager is the given employee
+//	@Tags			employees
+//	@Produce		json
+//	@Param			id	path		int						true	"Employee ID"
+//	@Success		200	{object}	common.Response[any]	"Direct reports"
+//	@Failure		400	{object}	common.Response[any]	"Invalid employee ID format"
+//	@Failure		404	{object}	common.Response[any]	"Employee not found"
+//	@Failure		500	{object}	common.Response[any]	"Internal server error"
+//	@Router			/employees/{id}/reports [get]
+func (c *Controller) FindEmployeeDirectReports(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find direct reports request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	reports, err := c.employeeService.FindDirectReports(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleFindEmployeeError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, reports)
+}
+
+// FindEmployeeSubordinates возвращает всех подчинённых сотрудника
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get subordinate tree
+//	@Description	All direct and indirect reports of the employee, depth 1 is a direct report
+//	@Tags			employees
+//	@Produce		json
+//	@Param			id	path		int						true	"Employee ID"
+//	@Success		200	{object}	common.Response[any]	"Subordinates with depth"
+//	@Failure		400	{object}	common.Response[any]	"Invalid employee ID format"
+//	@Failure		404	{object}	common.Response[any]	"Employee not found"
+//	@Failure		500	{object}	common.Response[any]	"Internal server error"
+//	@Router			/employees/{id}/subordinates [get]
+func (c *Controller) FindEmployeeSubordinates(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find subordinates request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	subordinates, err := c.employeeService.FindSubordinates(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleFindEmployeeError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, subordinates)
+}
+
+// FindEmployeeManagementChain возвращает цепочку руководителей сотрудника
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get management chain
+//	@Description	Managers of the employee from the direct manager (depth 1) up to the top of the organization
+//	@Tags			employees
+//	@Produce		json
+//	@Param			id	path		int						true	"Employee ID"
+//	@Success		200	{object}	common.Response[any]	"Management chain with depth"
+//	@Failure		400	{object}	common.Response[any]	"Invalid employee ID format"
+//	@Failure		404	{object}	common.Response[any]	"Employee not found"
+//	@Failure		500	{object}	common.Response[any]	"Internal server error"
+//	@Router			/employees/{id}/chain [get]
+func (c *Controller) FindEmployeeManagementChain(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find management chain request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	chain, err := c.employeeService.FindManagementChain(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleFindEmployeeError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, chain)
+}
+
+// GetOrgChart возвращает оргструктуру в виде дерева
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Export org chart
+//	@Description	Org chart of non-terminated employees as a JSON tree built from reporting lines
+//	@Tags			employees
+//	@Produce		json
+//	@Success		200	{object}	common.Response[[]employee.OrgChartNode]	"Org chart"
+//	@Failure		500	{object}	common.Response[any]						"Internal server error"
+//	@Router			/employees/org-chart [get]
+func (c *Controller) GetOrgChart(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received org chart request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	chart, err := c.employeeService.FindOrgChart(ctx.UserContext())
+	if err != nil {
+		c.logger.Error("Failed to build org chart",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when building the org chart")
+	}
+
+	return common.OkResponse(ctx, chart)
+}
+
+// общий обработчик переходов, принимающих только причину (suspend, resume, terminate)
+func (c *Controller) changeEmployeeStatus(
+	ctx *fiber.Ctx,
+	transition string,
+	change func(ctx context.Context, request TransitionRequest) (Response, error),
+) error {
+	c.logger.Info("Received employee transition request",
+		zap.String("transition", transition),
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	var request TransitionRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse transition request body",
+			zap.String("transition", transition),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.EmployeeId = id
+
+	employee, err := change(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, id)
+	}
+
+	c.logger.Info("Employee transition completed",
+		zap.String("transition", transition),
+		zap.Int64("id", id),
+		zap.String("status", employee.Status),
+		zap.String("ip", ctx.IP()))
+
+	ctx.Set(fiber.HeaderETag, versionETag(employee.UpdatedAt))
+	return common.OkResponse(ctx, employee)
+}
+
+// ScheduleEmployeeChange создаёт отложенное изменение сотрудника
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Schedule employee change
+//	@Description	Schedule a change of position, department and/or role that takes effect at effective_at
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int								true	"Employee ID"
+//	@Param			request	body		employee.ScheduledChangeRequest	true	"scheduled change request"
+//	@Success		200		{object}	common.Response[any]			"Created scheduled change"
+//	@Failure		400		{object}	common.Response[any]			"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]			"Employee not found"
+//	@Failure		409		{object}	common.Response[any]			"Employee is terminated"
+//	@Failure		500		{object}	common.Response[any]			"Internal server error"
+//	@Router			/admin/employees/{id}/scheduled-changes [post]
+func (c *Controller) ScheduleEmployeeChange(ctx *fiber.Ctx) error {
+	c.logger.Info("Received schedule employee change request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseEmployeeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	var request ScheduledChangeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse scheduled change request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.EmployeeId = id
+
+	change, err := c.employeeService.ScheduleChange(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, id)
+	}
+
+	c.logger.Info("Employee change scheduled successfully",
+		zap.Int64("id", id),
+		zap.Int64("change_id", change.Id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, change)
+}
+
+// FindScheduledChanges возвращает отложенные изменения сотрудников
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get scheduled employee changes
+//	@Description	Scheduled changes ordered by effective date, optionally filtered by employee and status
+//	@Tags			employees
+//	@Produce		json
+//	@Param			employeeId	query		int						false	"Employee ID"
+//	@Param			status		query		string					false	"Change status"	Enums(pending, applied, failed, cancelled)
+//	@Success		200			{object}	common.Response[any]	"Scheduled changes"
+//	@Failure		400			{object}	common.Response[any]	"Invalid filter"
+//	@Failure		500			{object}	common.Response[any]	"Internal server error"
+//	@Router			/admin/scheduled-changes [get]
+func (c *Controller) FindScheduledChanges(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find scheduled changes request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	filter := ScheduledChangeFilter{Status: ctx.Query("status")}
+	if employeeId := ctx.Query("employeeId"); employeeId != "" {
+		id, err := strconv.ParseInt(employeeId, 10, 64)
+		if err != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+		}
+		filter.EmployeeId = id
+	}
+
+	changes, err := c.employeeService.FindScheduledChanges(ctx.UserContext(), filter)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, filter.EmployeeId)
+	}
+
+	return common.OkResponse(ctx, changes)
+}
+
+// UpdateScheduledChange изменяет ещё не применённое отложенное изменение
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Update scheduled employee change
+//	@Description	Replace fields and effective date of a pending scheduled change
+//	@Tags			employees
+//	@Accept			json
+//	@Produce		json
+//	@Param			changeId	path		int								true	"Scheduled change ID"
+//	@Param			request		body		employee.ScheduledChangeRequest	true	"scheduled change request"
+//	@Success		200			{object}	common.Response[any]			"Updated scheduled change"
+//	@Failure		400			{object}	common.Response[any]			"Incorrect data format in request"
+//	@Failure		404			{object}	common.Response[any]			"Scheduled change not found"
+//	@Failure		409			{object}	common.Response[any]			"Scheduled change is not pending"
+//	@Failure		500			{object}	common.Response[any]			"Internal server error"
+//	@Router			/admin/scheduled-changes/{changeId} [put]
+func (c *Controller) UpdateScheduledChange(ctx *fiber.Ctx) error {
+	c.logger.Info("Received update scheduled change request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	changeId, err := c.parseChangeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid scheduled change ID format")
+	}
+
+	var request ScheduledChangeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse scheduled change request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.Id = changeId
+
+	change, err := c.employeeService.UpdateScheduledChange(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, 0)
+	}
+
+	c.logger.Info("Scheduled change updated successfully",
+		zap.Int64("change_id", changeId),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, change)
+}
+
+// CancelScheduledChange отменяет ещё не применённое отложенное изменение
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Cancel scheduled employee change
+//	@Description	Cancel a pending scheduled change. The change is kept with status cancelled
+//	@Tags			employees
+//	@Produce		json
+//	@Param			changeId	path		int						true	"Scheduled change ID"
+//	@Success		200			{object}	common.Response[any]	"Cancelled scheduled change"
+//	@Failure		400			{object}	common.Response[any]	"Invalid scheduled change ID format"
+//	@Failure		404			{object}	common.Response[any]	"Scheduled change not found"
+//	@Failure		409			{object}	common.Response[any]	"Scheduled change is not pending"
+//	@Failure		500			{object}	common.Response[any]	"Internal server error"
+//	@Router			/admin/scheduled-changes/{changeId}/cancel [post]
+func (c *Controller) CancelScheduledChange(ctx *fiber.Ctx) error {
+	c.logger.Info("Received cancel scheduled change request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	changeId, err := c.parseChangeId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid scheduled change ID format")
+	}
+
+	change, err := c.employeeService.CancelScheduledChange(ctx.UserContext(), changeId)
+	if err != nil {
+		return c.handleUpdateEmployeeError(ctx, err, 0)
+	}
+
+	c.logger.Info("Scheduled change cancelled successfully",
+		zap.Int64("change_id", changeId),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, change)
+}
+
+// извлекает ID сотрудника из параметров пути
+func (c *Controller) parseEmployeeId(ctx *fiber.Ctx) (int64, error) {
+	idParam := ctx.Params("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Error("Invalid employee ID format",
+			zap.String("id_param", idParam),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+	}
+	return id, err
+}
+
+// извлекает ID отложенного изменения из параметров пути
+// читает список id из query-параметра: повторяющегося (?id=1&id=2) или через запятую (?id=1,2)
+// разбирает список идентификаторов из повторяющегося параметра запроса или значений через запятую
+func parseIdList(ctx *fiber.Ctx, name string) ([]int64, error) {
+	var ids []int64
+	for _, value := range ctx.Context().QueryArgs().PeekMulti(name) {
+		for _, part := range strings.Split(string(value), ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// значения параметра: повторяющиеся и/или через запятую; пустые значения пропускаются
+func parseStringList(ctx *fiber.Ctx, name string) []string {
+	var values []string
+	for _, value := range ctx.Context().QueryArgs().PeekMulti(name) {
+		for _, part := range strings.Split(string(value), ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+// время из параметра в формате RFC 3339; nil, если параметр не передан
+func parseTimeQuery(ctx *fiber.Ctx, name string) (*time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func (c *Controller) parseChangeId(ctx *fiber.Ctx) (int64, error) {
+	idParam := ctx.Params("changeId")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Error("Invalid scheduled change ID format",
+			zap.String("change_id", idParam),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+	}
+	return id, err
+}
+
+// определяет версию записи: заголовок If-Match имеет приоритет над полем version в теле запроса
+func (c *Controller) versionFromRequest(ctx *fiber.Ctx, bodyVersion time.Time) (time.Time, error) {
+	ifMatch := ctx.Get(fiber.HeaderIfMatch)
+	if ifMatch == "" {
+		return bodyVersion, nil
+	}
+
+	version, err := parseETag(ifMatch)
+	if err != nil {
+		c.logger.Warn("Invalid If-Match header",
+			zap.String("if_match", ifMatch),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+	}
+	return version, err
+}
+
+// формирует ETag из версии записи
+func versionETag(version time.Time) string {
+	return `"` + version.UTC().Format(time.RFC3339Nano) + `"`
+}
+
+// разбирает ETag (в том числе слабый, W/"...") обратно в версию записи
+func parseETag(etag string) (time.Time, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	value = strings.Trim(value, `"`)
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// обрабатывает ошибки при создании сотрудника
+func (c *Controller) handleCreateEmployeeError(ctx *fiber.Ctx, err error, request CreateRequest) error {
+	switch {
+	// Обработка ошибок валидации
+	case errors.As(err, &common.RequestValidationError{}):
+		c.logger.Warn("Create employee validation error",
+			zap.String("name", request.Name),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+
+		// Получаем детали ошибки валидации
+		var validationErr common.RequestValidationError
+		errors.As(err, &validationErr)
+
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	// Обработка ошибок существования
+	case errors.As(err, &common.AlreadyExistsError{}):
+		c.logger.Warn("Create employee conflict error",
+			zap.String("name", request.Name),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+
+	// Обработка конфликтов, например нарушения правила разделения обязанностей
+	case errors.As(err, &common.ConflictError{}):
+		c.logger.Warn("Create employee conflict error",
+			zap.String("name", request.Name),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return conflictResponse(ctx, err)
+
+	// Обработка других ошибок
+	default:
+		c.logger.Error("Create employee internal error",
+			zap.String("name", request.Name),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// обрабатывает ошибки при поиске сотрудника
+func (c *Controller) handleFindEmployeeError(ctx *fiber.Ctx, err error, id int64) error {
+	switch {
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Employee not found",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Employee not found")
+
+	default:
+		c.logger.Error("Find employee internal error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// обрабатывает ошибки при удалении сотрудника
+func (c *Controller) handleDeleteEmployeeError(ctx *fiber.Ctx, err error, id int64) error {
+	switch {
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Employee not found for deletion",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Employee not found")
+
+	default:
+		c.logger.Error("Delete employee internal error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// обрабатывает ошибки при обновлении сотрудника
+func (c *Controller) handleUpdateEmployeeError(ctx *fiber.Ctx, err error, id int64) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		c.logger.Warn("Update employee validation error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+
+		var validationErr common.RequestValidationError
+		errors.As(err, &validationErr)
+
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Employee for update not found",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Employee not found")
+
+	case errors.As(err, &common.AlreadyExistsError{}), errors.As(err, &common.ConflictError{}):
+		c.logger.Warn("Update employee conflict error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return conflictResponse(ctx, err)
+
+	default:
+		c.logger.Error("Update employee internal error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// обрабатывает ошибки при работе с назначениями ролей сотрудника
+func (c *Controller) handleRoleAssignmentError(ctx *fiber.Ctx, err error, id int64) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		c.logger.Warn("Role assignment validation error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+
+		var validationErr common.RequestValidationError
+		errors.As(err, &validationErr)
+
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Role assignment target not found",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+
+	case errors.As(err, &common.ConflictError{}):
+		c.logger.Warn("Role assignment conflict error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return conflictResponse(ctx, err)
+
+	default:
+		c.logger.Error("Role assignment internal error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// ответ 409; подробности конфликта (например, нарушения правил разделения обязанностей) передаются в data
+func conflictResponse(ctx *fiber.Ctx, err error) error {
+	var conflictErr common.ConflictError
+	if errors.As(err, &conflictErr) && conflictErr.Data != nil {
+		return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message, conflictErr.Data)
 	}
+	return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
 }
@@ -15,6 +15,7 @@ import (
 	"idm/inner/testutils"
 	"idm/inner/web"
 	"io"
+	"iter"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -53,9 +54,24 @@ func (m *MockService) DeleteByIds(ctx context.Context, ids []int64) error {
 	return args.Error(0)
 }
 
-func (m *MockService) FindAll(ctx context.Context) ([]Response, error) {
-	args := m.Called(ctx)
-	return args.Get(0).([]Response), args.Error(1)
+func (m *MockService) FindWithCursor(ctx context.Context, request CursorRequest) (CursorPage, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(CursorPage), args.Error(1)
+}
+
+func (m *MockService) Import(ctx context.Context, request ImportRequest) (ImportReport, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(ImportReport), args.Error(1)
+}
+
+func (m *MockService) Search(ctx context.Context, request SearchRequest) ([]SearchResponse, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).([]SearchResponse), args.Error(1)
+}
+
+func (m *MockService) Export(ctx context.Context, filter PageRequest) (iter.Seq2[ExportRecord, error], error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(iter.Seq2[ExportRecord, error]), args.Error(1)
 }
 
 func (m *MockService) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
@@ -68,6 +84,115 @@ func (m *MockService) FindWithPagination(ctx context.Context, request PageReques
 	return args.Get(0).(PageResponse), args.Error(1)
 }
 
+func (m *MockService) UpdateEmployee(ctx context.Context, request UpdateRequest) (Response, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) PatchEmployee(ctx context.Context, request PatchRequest) (Response, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) AssignRole(ctx context.Context, request AssignRoleRequest) (RoleAssignmentResponse, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(RoleAssignmentResponse), args.Error(1)
+}
+
+func (m *MockService) FindRoleAssignments(ctx context.Context, employeeId int64, activeOnly bool) ([]RoleAssignmentResponse, error) {
+	args := m.Called(ctx, employeeId, activeOnly)
+	return args.Get(0).([]RoleAssignmentResponse), args.Error(1)
+}
+
+func (m *MockService) RevokeRoleAssignment(ctx context.Context, employeeId, assignmentId int64) error {
+	return m.Called(ctx, employeeId, assignmentId).Error(0)
+}
+
+func (m *MockService) Restore(ctx context.Context, id int64) (Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Purge(ctx context.Context, request PurgeRequest) (PurgeResponse, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(PurgeResponse), args.Error(1)
+}
+
+func (m *MockService) Hire(ctx context.Context, request HireRequest) (Response, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Suspend(ctx context.Context, request TransitionRequest) (Response, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Resume(ctx context.Context, request TransitionRequest) (Response, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Transfer(ctx context.Context, request TransferRequest) (Response, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Terminate(ctx context.Context, request TransitionRequest) (Response, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryResponse, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]StatusHistoryResponse), args.Error(1)
+}
+
+func (m *MockService) ScheduleChange(ctx context.Context, request ScheduledChangeRequest) (ScheduledChangeResponse, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(ScheduledChangeResponse), args.Error(1)
+}
+
+func (m *MockService) FindScheduledChanges(ctx context.Context, filter ScheduledChangeFilter) ([]ScheduledChangeResponse, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]ScheduledChangeResponse), args.Error(1)
+}
+
+func (m *MockService) UpdateScheduledChange(ctx context.Context, request ScheduledChangeRequest) (ScheduledChangeResponse, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(ScheduledChangeResponse), args.Error(1)
+}
+
+func (m *MockService) CancelScheduledChange(ctx context.Context, id int64) (ScheduledChangeResponse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(ScheduledChangeResponse), args.Error(1)
+}
+
+func (m *MockService) SetManager(ctx context.Context, request SetManagerRequest) (Response, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindDirectReports(ctx context.Context, id int64) ([]Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (m *MockService) FindSubordinates(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]HierarchyResponse), args.Error(1)
+}
+
+func (m *MockService) FindManagementChain(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]HierarchyResponse), args.Error(1)
+}
+
+func (m *MockService) FindOrgChart(ctx context.Context) ([]OrgChartNode, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]OrgChartNode), args.Error(1)
+}
+
 // setupTestServer создает тестовый сервер с настроенной аутентификацией
 func setupTestServer(t *testing.T) (*MockService, *fiber.App) {
 
@@ -192,7 +317,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "successful creation with admin role",
 			userRoles:   []string{web.IdmAdmin},
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 1},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1},
 			mockSetup: func(m *MockService) {
 				m.On("CreateEmployee", mock.Anything, mock.AnythingOfType("CreateRequest")).
 					Return(int64(123), nil)
@@ -204,7 +329,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "forbidden access with user role",
 			userRoles:   []string{web.IdmUser},
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 2},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 2},
 			mockSetup: func(m *MockService) {
 				// Сервис не должен вызываться при отсутствии прав
 			},
@@ -214,7 +339,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "validation error",
 			userRoles:   []string{web.IdmAdmin},
-			requestBody: CreateRequest{Name: "", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 2}, // пустое имя
+			requestBody: CreateRequest{Name: "", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 2}, // пустое имя
 			mockSetup: func(m *MockService) {
 				validationErr := common.RequestValidationError{
 					Message: "Name is required",
@@ -229,7 +354,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "already exists error",
 			userRoles:   []string{web.IdmAdmin},
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 2},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 2},
 			mockSetup: func(m *MockService) {
 				existsErr := common.AlreadyExistsError{Message: "Employee already exists"}
 				m.On("CreateEmployee", mock.Anything, mock.AnythingOfType("CreateRequest")).
@@ -241,7 +366,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "internal server error",
 			userRoles:   []string{web.IdmAdmin},
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 2},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 2},
 			mockSetup: func(m *MockService) {
 				m.On("CreateEmployee", mock.Anything, mock.AnythingOfType("CreateRequest")).
 					Return(int64(0), errors.New("database connection error"))
@@ -252,7 +377,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "unauthorized missing token",
 			userRoles:   nil,
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 1},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1},
 			mockSetup: func(m *MockService) {
 				// Сервис не должен вызываться
 			},
@@ -262,7 +387,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "unauthorized malformed token",
 			userRoles:   nil,
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 1},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1},
 			mockSetup: func(m *MockService) {
 				// Сервис не должен вызываться
 			},
@@ -272,7 +397,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "unauthorized malformed token",
 			userRoles:   []string{web.IdmAdmin},
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 1},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1},
 			mockSetup: func(m *MockService) {
 				// Сервис не должен вызываться
 			},
@@ -282,7 +407,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "unauthorized expired token",
 			userRoles:   []string{web.IdmAdmin},
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 1},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1},
 			mockSetup: func(m *MockService) {
 				// Сервис не должен вызываться
 			},
@@ -292,7 +417,7 @@ func TestController_CreateEmployee(t *testing.T) {
 		{
 			name:        "forbidden access with user role",
 			userRoles:   []string{web.IdmUser}, // нет прав на /admin
-			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", Position: "Developer", Department: "IT", RoleId: 2},
+			requestBody: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 2},
 			mockSetup: func(m *MockService) {
 				// Сервис не должен вызываться
 			},
@@ -724,11 +849,11 @@ func TestController_CreateEmployee_Success(t *testing.T) {
 	mockService, app := setupTestServer(t)
 
 	createRequest := CreateRequest{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     1,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       1,
 	}
 
 	expectedEmployeeId := int64(123)
@@ -780,11 +905,11 @@ func TestController_CreateEmployee_ValidationError(t *testing.T) {
 	mockService, app := setupTestServer(t)
 
 	createRequest := CreateRequest{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     1,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       1,
 	}
 
 	validationError := common.RequestValidationError{Message: "validation failed"}
@@ -811,11 +936,11 @@ func TestController_CreateEmployee_AlreadyExistsError(t *testing.T) {
 	mockService, app := setupTestServer(t)
 
 	createRequest := CreateRequest{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     1,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       1,
 	}
 
 	alreadyExistsError := common.AlreadyExistsError{Message: "employee already exists"}
@@ -843,11 +968,11 @@ func TestController_CreateEmployee_InternalServerError(t *testing.T) {
 	mockService, app := setupTestServer(t)
 
 	createRequest := CreateRequest{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     1,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       1,
 	}
 
 	internalError := errors.New("Internal server error")
@@ -875,11 +1000,11 @@ func TestController_CreateEmployee_InvalidData_ReturnsValidationError(t *testing
 
 	// Невалидные данные (пустое имя)
 	createRequest := CreateRequest{
-		Name:       "",
-		Email:      "test@example.com",
-		Position:   "Dev",
-		Department: "IT",
-		RoleId:     1,
+		Name:         "",
+		Email:        "test@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       1,
 	}
 	validationError := common.RequestValidationError{Message: "validation failed"}
 	mockService.On("CreateEmployee", mock.Anything, createRequest).Return(int64(0), validationError)
@@ -993,9 +1118,9 @@ func TestFindEmployeesWithPagination_Success(t *testing.T) {
 
 	expectedResponse := PageResponse{
 		Data: []Response{
-			{Id: 4, Name: "Rick Sanchez", Email: "rick@example.com", Position: "Developer", Department: "Engineering", RoleId: 3, CreatedAt: time.Now(), UpdatedAt: time.Now()},
-			{Id: 2, Name: "Jane Smith", Email: "jane@example.com", Position: "Developer", Department: "IT", RoleId: 3, CreatedAt: time.Now(), UpdatedAt: time.Now()},
-			{Id: 5, Name: "John Doe", Email: "john@example.com", Position: "CTO", Department: "IT", RoleId: 2, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{Id: 4, Name: "Rick Sanchez", Email: "rick@example.com", Position: "Developer", DepartmentId: 2, Department: "Engineering", RoleId: 3, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{Id: 2, Name: "Jane Smith", Email: "jane@example.com", Position: "Developer", DepartmentId: 1, Department: "IT", RoleId: 3, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			{Id: 5, Name: "John Doe", Email: "john@example.com", Position: "CTO", DepartmentId: 1, Department: "IT", RoleId: 2, CreatedAt: time.Now(), UpdatedAt: time.Now()},
 		},
 		PageNumber: 1,
 		PageSize:   10,
@@ -1085,6 +1210,303 @@ func TestFindEmployeesWithPagination_DefaultValues(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+// Тестирует фильтр по отделам
+func TestFindEmployeesWithPagination_DepartmentFilter(t *testing.T) {
+	t.Run("repeated and comma separated ids", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		expectedRequest := PageRequest{PageNumber: 1, PageSize: 10, DepartmentIds: []int64{1, 2, 3}}
+		mockService.On("FindWithPagination", mock.Anything, expectedRequest).
+			Return(PageResponse{Data: []Response{}, PageNumber: 1, PageSize: 10, TotalPages: 1}, nil).
+			Once()
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet,
+			"/api/v1/employees/page?departmentId=1&departmentId=2,3", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet,
+			"/api/v1/employees/page?departmentId=abc", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "FindWithPagination", mock.Anything, mock.Anything)
+	})
+}
+
+// Тестирует фильтры и сортировку страницы
+func TestFindEmployeesWithPagination_Filters(t *testing.T) {
+	t.Run("all filters are passed to the service", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		createdFrom := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		expectedRequest := PageRequest{
+			PageNumber:  1,
+			PageSize:    10,
+			Email:       "@example.com",
+			PositionIds: []int64{3},
+			RoleIds:     []int64{5, 6},
+			Statuses:    []string{"active", "suspended"},
+			CreatedFrom: &createdFrom,
+			Sort:        "name,-created_at",
+		}
+		mockService.On("FindWithPagination", mock.Anything, expectedRequest).
+			Return(PageResponse{Data: []Response{}, PageNumber: 1, PageSize: 10, TotalPages: 1}, nil).
+			Once()
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet,
+			"/api/v1/employees/page?email=@example.com&positionId=3&roleId=5,6&status=active&status=suspended"+
+				"&createdFrom=2025-01-01T00:00:00Z&sort=name,-created_at", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid createdTo", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet,
+			"/api/v1/employees/page?createdTo=yesterday", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "FindWithPagination", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unsupported sort field", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		mockService.On("FindWithPagination", mock.Anything, mock.Anything).
+			Return(PageResponse{}, common.RequestValidationError{Message: `unsupported sort field "password"`}).
+			Once()
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet,
+			"/api/v1/employees/page?sort=password", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		var responseBody common.Response[any]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&responseBody))
+		assert.Contains(t, responseBody.Message, "password")
+	})
+}
+
+// Тестирует вывод сотрудников по курсору
+func TestFindAllEmployee_Cursor(t *testing.T) {
+	t.Run("default limit and signed next cursor", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		next := &common.Cursor{Sort: "", Value: "2", Id: 2}
+		mockService.On("FindWithCursor", mock.Anything, CursorRequest{Limit: 50}).
+			Return(CursorPage{Data: []Response{{Id: 1}, {Id: 2}}, Next: next}, nil).
+			Once()
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet, "/api/v1/employees", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var responseBody common.Response[CursorPageResponse]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&responseBody))
+		assert.Len(t, responseBody.Data.Data, 2)
+		assert.NotNil(t, responseBody.Data.Next)
+		assert.Nil(t, responseBody.Data.Prev)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("forged cursor", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		forged := common.NewCursorCodec("forged").Encode(common.Cursor{Id: 1})
+		req := createAuthenticatedRequest(t, fiber.MethodGet, "/api/v1/employees?cursor="+forged, nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "FindWithCursor", mock.Anything, mock.Anything)
+	})
+}
+
+// Тестирует поиск сотрудников
+func TestSearchEmployees(t *testing.T) {
+	t.Run("search route is not taken for employee id", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		mockService.On("Search", mock.Anything, SearchRequest{Query: "ivanov", Limit: 20}).
+			Return([]SearchResponse{{Response: Response{Id: 1, Name: "Иванов Иван"}, Rank: 0.9}}, nil).
+			Once()
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet, "/api/v1/employees/search?q=ivanov", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var responseBody common.Response[[]SearchResponse]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&responseBody))
+		require.Len(t, responseBody.Data, 1)
+		assert.Equal(t, 0.9, responseBody.Data[0].Rank)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		mockService.On("Search", mock.Anything, SearchRequest{Limit: 20}).
+			Return([]SearchResponse(nil), common.RequestValidationError{Message: "Invalid search request"}).
+			Once()
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet, "/api/v1/employees/search", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+}
+
+// Тестирует выгрузку сотрудников
+func TestExportEmployees(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	hireDate := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	records := iter.Seq2[ExportRecord, error](func(yield func(ExportRecord, error) bool) {
+		yield(ExportRecord{
+			Id: 1, Name: "John Doe", Email: "john@example.com", Status: StatusActive, Position: "Developer",
+			Department: "IT", Roles: []string{"Admin", "User"}, HireDate: &hireDate, CreatedAt: created, UpdatedAt: created,
+		}, nil)
+	})
+
+	t.Run("csv with page filters", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		mockService.On("Export", mock.Anything, PageRequest{
+			DepartmentIds: []int64{2}, Statuses: []string{StatusActive}, Sort: "-name",
+		}).Return(records, nil).Once()
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet,
+			"/api/v1/employees/export?departmentId=2&status=active&sort=-name", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, `attachment; filename="employees.csv"`, resp.Header.Get(fiber.HeaderContentDisposition))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "id,name,email,status,position,department,manager,roles,hire_date,start_date,created_at,updated_at\n"+
+			"1,John Doe,john@example.com,active,Developer,IT,,Admin; User,2025-06-30,,2025-07-01T09:00:00Z,2025-07-01T09:00:00Z\n",
+			string(body))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("json lines", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		mockService.On("Export", mock.Anything, PageRequest{}).Return(records, nil).Once()
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet, "/api/v1/employees/export?format=jsonl", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get(fiber.HeaderContentType))
+		var record ExportRecord
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&record))
+		assert.Equal(t, []string{"Admin", "User"}, record.Roles)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		req := createAuthenticatedRequest(t, fiber.MethodGet, "/api/v1/employees/export?roleId=x", nil, []string{web.IdmUser})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Export", mock.Anything, mock.Anything)
+	})
+}
+
+// Тестирует импорт сотрудников из файла
+func TestImportEmployees(t *testing.T) {
+	t.Run("csv best effort dry run", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		expected := ImportRequest{
+			Rows: []ImportRow{{
+				Line:    2,
+				Request: CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1},
+				Role:    "Developer",
+			}},
+			DryRun:     true,
+			BestEffort: true,
+		}
+		mockService.On("Import", mock.Anything, expected).
+			Return(ImportReport{DryRun: true, BestEffort: true, Created: 1, Rows: []ImportRowResult{{Line: 2, Status: ImportCreated}}}, nil).
+			Once()
+
+		body := strings.NewReader("name,email,position_id,department_id,role\nJohn Doe,john@example.com,1,1,Developer\n")
+		req := createAuthenticatedRequest(t, fiber.MethodPost, "/api/v1/admin/employees/import?dryRun=true&mode=best_effort", body, []string{web.IdmAdmin})
+		req.Header.Set("Content-Type", "text/csv")
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var responseBody common.Response[ImportReport]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&responseBody))
+		assert.Equal(t, 1, responseBody.Data.Created)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		req := createAuthenticatedRequest(t, fiber.MethodPost, "/api/v1/admin/employees/import", strings.NewReader("{}"), []string{web.IdmAdmin})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusUnsupportedMediaType, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Import", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown csv column", func(t *testing.T) {
+		mockService, app := setupTestServer(t)
+
+		req := createAuthenticatedRequest(t, fiber.MethodPost, "/api/v1/admin/employees/import", strings.NewReader("name,salary\n"), []string{web.IdmAdmin})
+		req.Header.Set("Content-Type", "text/csv")
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Import", mock.Anything, mock.Anything)
+	})
+}
+
 // Тестирует обработку ошибок сервиса
 func TestFindEmployeesWithPagination_ServiceError(t *testing.T) {
 	mockService, app := setupTestServer(t)
@@ -1165,3 +1587,574 @@ func TestController_RegisterRoutes(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEqual(t, fiber.StatusNotFound, resp.StatusCode)
 }
+
+func TestController_UpdateEmployee(t *testing.T) {
+	version := time.Date(2025, 6, 10, 12, 0, 0, 123456000, time.UTC)
+	body := `{"name":"John Doe","email":"john@example.com","position_id":1,"department_id":1,"role_id":1}`
+
+	tests := []struct {
+		name         string
+		method       string
+		ifMatch      string
+		userRoles    []string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:      "successful update with If-Match header",
+			method:    fiber.MethodPut,
+			ifMatch:   versionETag(version),
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("UpdateEmployee", mock.Anything, mock.MatchedBy(func(r UpdateRequest) bool {
+					return r.Id == 123 && r.Version.Equal(version)
+				})).Return(Response{Id: 123, UpdatedAt: version.Add(time.Second)}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "stale version returns conflict",
+			method:    fiber.MethodPatch,
+			ifMatch:   versionETag(version),
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("PatchEmployee", mock.Anything, mock.AnythingOfType("PatchRequest")).
+					Return(Response{}, common.ConflictError{Message: "employee was modified"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "malformed If-Match header",
+			method:       fiber.MethodPut,
+			ifMatch:      `"not-a-version"`,
+			userRoles:    []string{web.IdmAdmin},
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "forbidden access with user role",
+			method:       fiber.MethodPut,
+			ifMatch:      versionETag(version),
+			userRoles:    []string{web.IdmUser},
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, app := setupTestServer(t)
+			tt.mockSetup(mockService)
+
+			req := createAuthenticatedRequest(t, tt.method, "/api/v1/admin/employees/123", strings.NewReader(body), tt.userRoles)
+			req.Header.Set(fiber.HeaderIfMatch, tt.ifMatch)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, versionETag(version.Add(time.Second)), resp.Header.Get(fiber.HeaderETag))
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestController_EmployeeRoles(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		userRoles    []string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:      "list active assignments",
+			method:    fiber.MethodGet,
+			path:      "/api/v1/employees/123/roles?active=true",
+			userRoles: []string{web.IdmUser},
+			mockSetup: func(m *MockService) {
+				m.On("FindRoleAssignments", mock.Anything, int64(123), true).
+					Return([]RoleAssignmentResponse{{Id: 1, RoleId: 2, Active: true}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "assign role",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/123/roles",
+			body:      `{"role_id":2,"valid_to":"2030-01-01T00:00:00Z"}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("AssignRole", mock.Anything, mock.MatchedBy(func(r AssignRoleRequest) bool {
+					return r.EmployeeId == 123 && r.RoleId == 2 && r.ValidFrom == nil && r.ValidTo != nil
+				})).Return(RoleAssignmentResponse{Id: 1, RoleId: 2}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "overlapping assignment returns conflict",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/123/roles",
+			body:      `{"role_id":2}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("AssignRole", mock.Anything, mock.AnythingOfType("AssignRoleRequest")).
+					Return(RoleAssignmentResponse{}, common.ConflictError{Message: "role 2 is already assigned"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:      "segregation of duties violation returns conflict",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/123/roles",
+			body:      `{"role_id":2}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("AssignRole", mock.Anything, mock.AnythingOfType("AssignRoleRequest")).
+					Return(RoleAssignmentResponse{}, common.ConflictError{
+						Message: `role "Payment creator" conflicts with role "Payment approver" held by employee 123 under SoD rule "Payments" (id 1)`,
+						Data:    []map[string]int64{{"rule_id": 1, "role_id": 2, "conflicting_role_id": 3}},
+					})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:      "revoke unknown assignment",
+			method:    fiber.MethodDelete,
+			path:      "/api/v1/admin/employees/123/roles/7",
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("RevokeRoleAssignment", mock.Anything, int64(123), int64(7)).
+					Return(common.NotFoundError{Message: "active role assignment 7 of employee 123 not found"})
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "forbidden assignment with user role",
+			method:       fiber.MethodPost,
+			path:         "/api/v1/admin/employees/123/roles",
+			body:         `{"role_id":2}`,
+			userRoles:    []string{web.IdmUser},
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, app := setupTestServer(t)
+			tt.mockSetup(mockService)
+
+			req := createAuthenticatedRequest(t, tt.method, tt.path, strings.NewReader(tt.body), tt.userRoles)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestController_RestoreAndPurge(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		userRoles    []string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:      "restore deleted employee",
+			path:      "/api/v1/admin/employees/123/restore",
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Restore", mock.Anything, int64(123)).Return(Response{Id: 123}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "restore with email taken returns conflict",
+			path:      "/api/v1/admin/employees/123/restore",
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Restore", mock.Anything, int64(123)).
+					Return(Response{}, common.AlreadyExistsError{Message: "employee with email john@example.com already exists"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:      "restore not deleted employee",
+			path:      "/api/v1/admin/employees/123/restore",
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Restore", mock.Anything, int64(123)).
+					Return(Response{}, common.NotFoundError{Message: "deleted employee with id 123 not found"})
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:      "purge deleted employees",
+			path:      "/api/v1/admin/employees/purge",
+			body:      `{"retention_days":30}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Purge", mock.Anything, PurgeRequest{RetentionDays: 30}).
+					Return(PurgeResponse{PurgedIds: []int64{1, 2}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "forbidden purge with user role",
+			path:         "/api/v1/admin/employees/purge",
+			body:         `{"retention_days":30}`,
+			userRoles:    []string{web.IdmUser},
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, app := setupTestServer(t)
+			tt.mockSetup(mockService)
+
+			req := createAuthenticatedRequest(t, fiber.MethodPost, tt.path, strings.NewReader(tt.body), tt.userRoles)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestController_Lifecycle(t *testing.T) {
+	departmentId := int64(2)
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		userRoles    []string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:      "suspend employee",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/123/suspend",
+			body:      `{"reason":"Long-term leave"}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Suspend", mock.Anything, TransitionRequest{EmployeeId: 123, Reason: "Long-term leave"}).
+					Return(Response{Id: 123, Status: StatusSuspended}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "resume active employee returns conflict",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/123/resume",
+			body:      `{"reason":"Back from leave"}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Resume", mock.Anything, TransitionRequest{EmployeeId: 123, Reason: "Back from leave"}).
+					Return(Response{}, common.ConflictError{Message: "transition resume is not allowed for employee in status active"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:      "terminate employee",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/123/terminate",
+			body:      `{"reason":"Resigned"}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Terminate", mock.Anything, TransitionRequest{EmployeeId: 123, Reason: "Resigned"}).
+					Return(Response{Id: 123, Status: StatusTerminated}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "transfer employee",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/123/transfer",
+			body:      `{"department_id":2,"reason":"Reorganization"}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Transfer", mock.Anything, TransferRequest{EmployeeId: 123, DepartmentId: &departmentId, Reason: "Reorganization"}).
+					Return(Response{Id: 123, DepartmentId: departmentId}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "hire without reason",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/123/hire",
+			body:      `{}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("Hire", mock.Anything, HireRequest{EmployeeId: 123}).
+					Return(Response{}, common.RequestValidationError{Message: "Data validation error"})
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "forbidden terminate with user role",
+			method:       fiber.MethodPost,
+			path:         "/api/v1/admin/employees/123/terminate",
+			body:         `{"reason":"Resigned"}`,
+			userRoles:    []string{web.IdmUser},
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:      "status history",
+			method:    fiber.MethodGet,
+			path:      "/api/v1/employees/123/history",
+			userRoles: []string{web.IdmUser},
+			mockSetup: func(m *MockService) {
+				m.On("FindStatusHistory", mock.Anything, int64(123)).Return([]StatusHistoryResponse{
+					{Id: 1, Transition: TransitionCreate, ToStatus: StatusActive},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "status history of unknown employee",
+			method:    fiber.MethodGet,
+			path:      "/api/v1/employees/999/history",
+			userRoles: []string{web.IdmUser},
+			mockSetup: func(m *MockService) {
+				m.On("FindStatusHistory", mock.Anything, int64(999)).
+					Return([]StatusHistoryResponse(nil), common.NotFoundError{Message: "employee with id 999 not found"})
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, app := setupTestServer(t)
+			tt.mockSetup(mockService)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := createAuthenticatedRequest(t, tt.method, tt.path, body, tt.userRoles)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestController_ScheduledChanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		userRoles    []string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:      "schedule change",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/employees/1/scheduled-changes",
+			body:      `{"department_id":2,"effective_at":"2030-01-01T00:00:00Z","reason":"Promotion"}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("ScheduleChange", mock.Anything, mock.MatchedBy(func(request ScheduledChangeRequest) bool {
+					return request.EmployeeId == 1 && *request.DepartmentId == 2
+				})).Return(ScheduledChangeResponse{Id: 7, Status: ChangeStatusPending}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "list pending changes",
+			method:    fiber.MethodGet,
+			path:      "/api/v1/admin/scheduled-changes?employeeId=1&status=pending",
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("FindScheduledChanges", mock.Anything, ScheduledChangeFilter{EmployeeId: 1, Status: ChangeStatusPending}).
+					Return([]ScheduledChangeResponse{{Id: 7}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "edit applied change returns conflict",
+			method:    fiber.MethodPut,
+			path:      "/api/v1/admin/scheduled-changes/7",
+			body:      `{"department_id":2,"effective_at":"2030-01-01T00:00:00Z","reason":"Promotion"}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("UpdateScheduledChange", mock.Anything, mock.MatchedBy(func(request ScheduledChangeRequest) bool {
+					return request.Id == 7
+				})).Return(ScheduledChangeResponse{}, common.ConflictError{Message: "scheduled change with id 7 is applied and cannot be modified"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:      "cancel change",
+			method:    fiber.MethodPost,
+			path:      "/api/v1/admin/scheduled-changes/7/cancel",
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("CancelScheduledChange", mock.Anything, int64(7)).
+					Return(ScheduledChangeResponse{Id: 7, Status: ChangeStatusCancelled}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "forbidden for user role",
+			method:       fiber.MethodGet,
+			path:         "/api/v1/admin/scheduled-changes",
+			userRoles:    []string{web.IdmUser},
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, app := setupTestServer(t)
+			tt.mockSetup(mockService)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := createAuthenticatedRequest(t, tt.method, tt.path, body, tt.userRoles)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestController_Hierarchy(t *testing.T) {
+	managerId := int64(7)
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		userRoles    []string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:      "set manager",
+			method:    fiber.MethodPut,
+			path:      "/api/v1/admin/employees/123/manager",
+			body:      `{"manager_id":7}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("SetManager", mock.Anything, SetManagerRequest{EmployeeId: 123, ManagerId: 7}).
+					Return(Response{Id: 123, ManagerId: &managerId}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "set manager forming a cycle",
+			method:    fiber.MethodPut,
+			path:      "/api/v1/admin/employees/123/manager",
+			body:      `{"manager_id":7}`,
+			userRoles: []string{web.IdmAdmin},
+			mockSetup: func(m *MockService) {
+				m.On("SetManager", mock.Anything, SetManagerRequest{EmployeeId: 123, ManagerId: 7}).
+					Return(Response{}, common.ConflictError{Message: "employee 7 reports to employee 123, reporting line would form a cycle"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "forbidden set manager with user role",
+			method:       fiber.MethodPut,
+			path:         "/api/v1/admin/employees/123/manager",
+			body:         `{"manager_id":7}`,
+			userRoles:    []string{web.IdmUser},
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:      "direct reports",
+			method:    fiber.MethodGet,
+			path:      "/api/v1/employees/7/reports",
+			userRoles: []string{web.IdmUser},
+			mockSetup: func(m *MockService) {
+				m.On("FindDirectReports", mock.Anything, int64(7)).
+					Return([]Response{{Id: 123, ManagerId: &managerId}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "subordinates of unknown employee",
+			method:    fiber.MethodGet,
+			path:      "/api/v1/employees/999/subordinates",
+			userRoles: []string{web.IdmUser},
+			mockSetup: func(m *MockService) {
+				m.On("FindSubordinates", mock.Anything, int64(999)).
+					Return([]HierarchyResponse(nil), common.NotFoundError{Message: "employee with id 999 not found"})
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:      "management chain",
+			method:    fiber.MethodGet,
+			path:      "/api/v1/employees/123/chain",
+			userRoles: []string{web.IdmUser},
+			mockSetup: func(m *MockService) {
+				m.On("FindManagementChain", mock.Anything, int64(123)).
+					Return([]HierarchyResponse{{Response: Response{Id: 7}, Depth: 1}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:      "org chart",
+			method:    fiber.MethodGet,
+			path:      "/api/v1/employees/org-chart",
+			userRoles: []string{web.IdmUser},
+			mockSetup: func(m *MockService) {
+				m.On("FindOrgChart", mock.Anything).Return([]OrgChartNode{
+					{Id: 7, Name: "Manager", Reports: []OrgChartNode{{Id: 123, Name: "Developer"}}},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, app := setupTestServer(t)
+			tt.mockSetup(mockService)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := createAuthenticatedRequest(t, tt.method, tt.path, body, tt.userRoles)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
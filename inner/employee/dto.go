@@ -1,67 +1,480 @@
 package employee
 
-import "time"
+import (
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/validator"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
 
 type Entity struct {
-	Id         int64     `db:"id"`
-	Name       string    `db:"name"`
-	Email      string    `db:"email"`
-	Position   string    `db:"position"`
-	Department string    `db:"department"`
-	RoleId     int64     `db:"role_id"`
-	CreatedAt  time.Time `db:"created_at"`
-	UpdatedAt  time.Time `db:"updated_at"`
+	Id         int64  `db:"id"`
+	Name       string `db:"name"`
+	Email      string `db:"email"`
+	PositionId int64  `db:"position_id"`
+	// название должности, только для чтения (вычисляется по position_id)
+	Position     string `db:"position"`
+	DepartmentId int64  `db:"department_id"`
+	// название отдела, только для чтения (вычисляется по department_id)
+	Department string     `db:"department"`
+	RoleId     int64      `db:"role_id"`
+	Status     string     `db:"status"`
+	HireDate   *time.Time `db:"hire_date"`
+	StartDate  *time.Time `db:"start_date"`
+	ManagerId  *int64     `db:"manager_id"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at"`
+	DeletedAt  *time.Time `db:"deleted_at"`
 }
 
 func (e *Entity) toResponse() Response {
 	return Response{
-		Id:         e.Id,
-		Name:       e.Name,
-		Email:      e.Email,
-		Position:   e.Position,
-		Department: e.Department,
-		RoleId:     e.RoleId,
-		CreatedAt:  e.CreatedAt,
-		UpdatedAt:  e.UpdatedAt,
+		Id:           e.Id,
+		Name:         e.Name,
+		Email:        e.Email,
+		PositionId:   e.PositionId,
+		Position:     e.Position,
+		DepartmentId: e.DepartmentId,
+		Department:   e.Department,
+		RoleId:       e.RoleId,
+		Status:       e.Status,
+		HireDate:     e.HireDate,
+		StartDate:    e.StartDate,
+		ManagerId:    e.ManagerId,
+		CreatedAt:    e.CreatedAt,
+		UpdatedAt:    e.UpdatedAt,
+		DeletedAt:    e.DeletedAt,
 	}
 }
 
 type Response struct {
-	Id         int64     `json:"id"`
-	Name       string    `json:"name"`
-	Email      string    `json:"email"`
-	Position   string    `json:"position"`
-	Department string    `json:"department"`
-	RoleId     int64     `json:"role_id"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id           int64      `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	PositionId   int64      `json:"position_id"`
+	Position     string     `json:"position"`
+	DepartmentId int64      `json:"department_id"`
+	Department   string     `json:"department"`
+	RoleId       int64      `json:"role_id"`
+	Status       string     `json:"status"`
+	HireDate     *time.Time `json:"hire_date,omitempty"`
+	StartDate    *time.Time `json:"start_date,omitempty"`
+	ManagerId    *int64     `json:"manager_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	// действующие назначения ролей; заполняется только при получении сотрудника по id
+	Roles []RoleAssignmentResponse `json:"roles,omitempty"`
 } // @name Response
 
+// CreateRequest структура запроса на создание сотрудника.
+// Сотрудник с датой выхода в будущем создаётся в состоянии pending и ожидает оформления (hire).
+// Кроме RoleId сотруднику назначаются роли по умолчанию его должности
 type CreateRequest struct {
-	Name       string `json:"name" validate:"required,min=2,max=155" example:"Ivan Ivanov"`
-	Email      string `json:"email" validate:"required,email" example:"ivan.ivanov@company.com"`
-	Position   string `json:"position" validate:"required,min=2,max=100" example:"Developer"`
-	Department string `json:"department" validate:"required,min=2,max=100" example:"IT"`
-	RoleId     int64  `json:"role_id" validate:"required" example:"1"`
+	Name         string     `json:"name" validate:"required,min=2,max=155" example:"Ivan Ivanov"`
+	Email        string     `json:"email" validate:"required,email" example:"ivan.ivanov@company.com"`
+	PositionId   int64      `json:"position_id" validate:"required,min=1" example:"1"`
+	DepartmentId int64      `json:"department_id" validate:"required,min=1" example:"1"`
+	RoleId       int64      `json:"role_id" validate:"required" example:"1"`
+	HireDate     *time.Time `json:"hire_date,omitempty" example:"2025-07-01T00:00:00Z"`
+	StartDate    *time.Time `json:"start_date,omitempty" example:"2025-07-15T00:00:00Z"`
 } // @name CreateRequest
 
 func (req *CreateRequest) ToEntity() Entity {
 	return Entity{
-		Name:       req.Name,
-		Email:      req.Email,
-		Position:   req.Position,
-		Department: req.Department,
-		RoleId:     req.RoleId,
+		Name:         req.Name,
+		Email:        req.Email,
+		PositionId:   req.PositionId,
+		DepartmentId: req.DepartmentId,
+		RoleId:       req.RoleId,
+		Status:       initialStatus(req.StartDate, time.Now()),
+		HireDate:     req.HireDate,
+		StartDate:    req.StartDate,
+	}
+}
+
+// UpdateRequest структура запроса на полное обновление сотрудника.
+// Version - значение updated_at, полученное клиентом при чтении записи
+type UpdateRequest struct {
+	Id           int64     `json:"-"`
+	Name         string    `json:"name" validate:"required,min=2,max=155" example:"Ivan Ivanov"`
+	Email        string    `json:"email" validate:"required,email" example:"ivan.ivanov@company.com"`
+	PositionId   int64     `json:"position_id" validate:"required,min=1" example:"1"`
+	DepartmentId int64     `json:"department_id" validate:"required,min=1" example:"1"`
+	RoleId       int64     `json:"role_id" validate:"required" example:"1"`
+	Version      time.Time `json:"version" example:"2025-06-10T12:00:00.123456Z"`
+} // @name UpdateRequest
+
+// применяет полное обновление к сущности
+func (req *UpdateRequest) applyTo(entity *Entity) {
+	entity.Name = req.Name
+	entity.Email = req.Email
+	entity.PositionId = req.PositionId
+	entity.DepartmentId = req.DepartmentId
+	entity.RoleId = req.RoleId
+}
+
+// PatchRequest структура запроса на частичное обновление сотрудника.
+// Изменяются только переданные (не nil) поля
+type PatchRequest struct {
+	Id           int64     `json:"-"`
+	Name         *string   `json:"name,omitempty" validate:"omitempty,min=2,max=155" example:"Ivan Ivanov"`
+	Email        *string   `json:"email,omitempty" validate:"omitempty,email" example:"ivan.ivanov@company.com"`
+	PositionId   *int64    `json:"position_id,omitempty" validate:"omitempty,min=1" example:"1"`
+	DepartmentId *int64    `json:"department_id,omitempty" validate:"omitempty,min=1" example:"1"`
+	RoleId       *int64    `json:"role_id,omitempty" validate:"omitempty,min=1" example:"1"`
+	Version      time.Time `json:"version" example:"2025-06-10T12:00:00.123456Z"`
+} // @name PatchRequest
+
+// применяет частичное обновление к сущности
+func (req *PatchRequest) applyTo(entity *Entity) {
+	if req.Name != nil {
+		entity.Name = *req.Name
+	}
+	if req.Email != nil {
+		entity.Email = *req.Email
+	}
+	if req.PositionId != nil {
+		entity.PositionId = *req.PositionId
+	}
+	if req.DepartmentId != nil {
+		entity.DepartmentId = *req.DepartmentId
+	}
+	if req.RoleId != nil {
+		entity.RoleId = *req.RoleId
+	}
+}
+
+// RoleAssignmentEntity назначение роли сотруднику на период [ValidFrom, ValidTo)
+type RoleAssignmentEntity struct {
+	Id         int64      `db:"id"`
+	EmployeeId int64      `db:"employee_id"`
+	RoleId     int64      `db:"role_id"`
+	RoleName   string     `db:"role_name"`
+	ValidFrom  time.Time  `db:"valid_from"`
+	ValidTo    *time.Time `db:"valid_to"`
+	Active     bool       `db:"active"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+func (e *RoleAssignmentEntity) toResponse() RoleAssignmentResponse {
+	return RoleAssignmentResponse{
+		Id:        e.Id,
+		RoleId:    e.RoleId,
+		RoleName:  e.RoleName,
+		ValidFrom: e.ValidFrom,
+		ValidTo:   e.ValidTo,
+		Active:    e.Active,
+		CreatedAt: e.CreatedAt,
 	}
 }
 
-// PageRequest структура для запроса пагинации
+type RoleAssignmentResponse struct {
+	Id        int64      `json:"id"`
+	RoleId    int64      `json:"role_id"`
+	RoleName  string     `json:"role_name"`
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+} // @name RoleAssignmentResponse
+
+// AssignRoleRequest структура запроса на назначение роли сотруднику.
+// ValidFrom = nil - с текущего момента, ValidTo = nil - бессрочно
+type AssignRoleRequest struct {
+	EmployeeId int64      `json:"-"`
+	RoleId     int64      `json:"role_id" validate:"required,min=1" example:"1"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty" example:"2025-07-01T00:00:00Z"`
+	ValidTo    *time.Time `json:"valid_to,omitempty" example:"2025-12-31T23:59:59Z"`
+} // @name AssignRoleRequest
+
+// PurgeRequest структура запроса на окончательное удаление сотрудников,
+// мягко удалённых более RetentionDays дней назад
+type PurgeRequest struct {
+	RetentionDays int `json:"retention_days" validate:"required,min=1" example:"30"`
+} // @name PurgeRequest
+
+// PurgeResponse результат очистки
+type PurgeResponse struct {
+	PurgedIds []int64 `json:"purged_ids"`
+} // @name PurgeResponse
+
+// TransitionRequest структура запроса на смену состояния сотрудника (suspend, resume, terminate)
+type TransitionRequest struct {
+	EmployeeId int64  `json:"-"`
+	Reason     string `json:"reason" validate:"required,min=2,max=500" example:"Long-term leave"`
+} // @name TransitionRequest
+
+// HireRequest структура запроса на оформление сотрудника (pending -> active).
+// Без дат используются ранее сохранённые, а при их отсутствии - текущая дата
+type HireRequest struct {
+	EmployeeId int64      `json:"-"`
+	HireDate   *time.Time `json:"hire_date,omitempty" example:"2025-07-01T00:00:00Z"`
+	StartDate  *time.Time `json:"start_date,omitempty" example:"2025-07-15T00:00:00Z"`
+	Reason     string     `json:"reason" validate:"required,min=2,max=500" example:"Onboarding completed"`
+} // @name HireRequest
+
+// TransferRequest структура запроса на перевод сотрудника; изменяются только переданные поля
+type TransferRequest struct {
+	EmployeeId   int64  `json:"-"`
+	PositionId   *int64 `json:"position_id,omitempty" validate:"omitempty,min=1" example:"2"`
+	DepartmentId *int64 `json:"department_id,omitempty" validate:"omitempty,min=1" example:"2"`
+	Reason       string `json:"reason" validate:"required,min=2,max=500" example:"Promotion"`
+} // @name TransferRequest
+
+// StatusHistoryEntity запись истории переходов сотрудника между состояниями
+type StatusHistoryEntity struct {
+	Id            int64     `db:"id"`
+	EmployeeId    int64     `db:"employee_id"`
+	Transition    string    `db:"transition"`
+	FromStatus    *string   `db:"from_status"`
+	ToStatus      string    `db:"to_status"`
+	Reason        *string   `db:"reason"`
+	Details       *string   `db:"details"`
+	ActorSub      *string   `db:"actor_sub"`
+	ActorUsername *string   `db:"actor_username"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+func (e *StatusHistoryEntity) toResponse() StatusHistoryResponse {
+	response := StatusHistoryResponse{
+		Id:            e.Id,
+		Transition:    e.Transition,
+		FromStatus:    e.FromStatus,
+		ToStatus:      e.ToStatus,
+		Reason:        e.Reason,
+		ActorSub:      e.ActorSub,
+		ActorUsername: e.ActorUsername,
+		CreatedAt:     e.CreatedAt,
+	}
+	if e.Details != nil {
+		response.Details = json.RawMessage(*e.Details)
+	}
+	return response
+}
+
+type StatusHistoryResponse struct {
+	Id            int64           `json:"id"`
+	Transition    string          `json:"transition"`
+	FromStatus    *string         `json:"from_status"`
+	ToStatus      string          `json:"to_status"`
+	Reason        *string         `json:"reason"`
+	Details       json.RawMessage `json:"details,omitempty"`
+	ActorSub      *string         `json:"actor_sub"`
+	ActorUsername *string         `json:"actor_username"`
+	CreatedAt     time.Time       `json:"created_at"`
+} // @name StatusHistoryResponse
+
+// ScheduledChangeEntity отложенное изменение сотрудника, вступающее в силу в EffectiveAt.
+// Nil-поля не изменяются
+type ScheduledChangeEntity struct {
+	Id                int64      `db:"id"`
+	EmployeeId        int64      `db:"employee_id"`
+	PositionId        *int64     `db:"position_id"`
+	DepartmentId      *int64     `db:"department_id"`
+	RoleId            *int64     `db:"role_id"`
+	EffectiveAt       time.Time  `db:"effective_at"`
+	Status            string     `db:"status"`
+	Reason            *string    `db:"reason"`
+	Result            *string    `db:"result"`
+	CreatedBySub      *string    `db:"created_by_sub"`
+	CreatedByUsername *string    `db:"created_by_username"`
+	CreatedAt         time.Time  `db:"created_at"`
+	UpdatedAt         time.Time  `db:"updated_at"`
+	AppliedAt         *time.Time `db:"applied_at"`
+}
+
+func (e *ScheduledChangeEntity) toResponse() ScheduledChangeResponse {
+	return ScheduledChangeResponse{
+		Id:                e.Id,
+		EmployeeId:        e.EmployeeId,
+		PositionId:        e.PositionId,
+		DepartmentId:      e.DepartmentId,
+		RoleId:            e.RoleId,
+		EffectiveAt:       e.EffectiveAt,
+		Status:            e.Status,
+		Reason:            e.Reason,
+		Result:            e.Result,
+		CreatedBySub:      e.CreatedBySub,
+		CreatedByUsername: e.CreatedByUsername,
+		CreatedAt:         e.CreatedAt,
+		UpdatedAt:         e.UpdatedAt,
+		AppliedAt:         e.AppliedAt,
+	}
+}
+
+type ScheduledChangeResponse struct {
+	Id                int64      `json:"id"`
+	EmployeeId        int64      `json:"employee_id"`
+	PositionId        *int64     `json:"position_id,omitempty"`
+	DepartmentId      *int64     `json:"department_id,omitempty"`
+	RoleId            *int64     `json:"role_id,omitempty"`
+	EffectiveAt       time.Time  `json:"effective_at"`
+	Status            string     `json:"status"`
+	Reason            *string    `json:"reason"`
+	Result            *string    `json:"result"`
+	CreatedBySub      *string    `json:"created_by_sub"`
+	CreatedByUsername *string    `json:"created_by_username"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	AppliedAt         *time.Time `json:"applied_at,omitempty"`
+} // @name ScheduledChangeResponse
+
+// ScheduledChangeRequest структура запроса на создание или изменение отложенного изменения.
+// Должно быть передано хотя бы одно из полей position_id, department_id, role_id
+type ScheduledChangeRequest struct {
+	Id           int64     `json:"-"`
+	EmployeeId   int64     `json:"-"`
+	PositionId   *int64    `json:"position_id,omitempty" validate:"omitempty,min=1" example:"2"`
+	DepartmentId *int64    `json:"department_id,omitempty" validate:"omitempty,min=1" example:"2"`
+	RoleId       *int64    `json:"role_id,omitempty" validate:"omitempty,min=1" example:"2"`
+	EffectiveAt  time.Time `json:"effective_at" validate:"required" example:"2025-08-01T00:00:00Z"`
+	Reason       string    `json:"reason" validate:"required,min=2,max=500" example:"Promotion agreed with HR"`
+} // @name ScheduledChangeRequest
+
+// применяет запрос к отложенному изменению
+func (req *ScheduledChangeRequest) applyTo(entity *ScheduledChangeEntity) {
+	entity.PositionId = req.PositionId
+	entity.DepartmentId = req.DepartmentId
+	entity.RoleId = req.RoleId
+	entity.EffectiveAt = req.EffectiveAt
+	entity.Reason = &req.Reason
+}
+
+// ScheduledChangeFilter фильтр списка отложенных изменений; нулевые значения не фильтруют
+type ScheduledChangeFilter struct {
+	EmployeeId int64  `json:"employeeId" validate:"min=0"`
+	Status     string `json:"status" validate:"omitempty,oneof=pending applied failed cancelled"`
+}
+
+// SetManagerRequest структура запроса на назначение руководителя; ManagerId = 0 убирает руководителя
+type SetManagerRequest struct {
+	EmployeeId int64 `json:"-"`
+	ManagerId  int64 `json:"manager_id" validate:"min=0,nefield=EmployeeId" example:"1"`
+} // @name SetManagerRequest
+
+// сотрудник с расстоянием до исходного сотрудника в линии подчинения
+type hierarchyEntity struct {
+	Entity
+	Depth int `db:"depth"`
+}
+
+// HierarchyResponse сотрудник из цепочки руководителей или подчинённых,
+// Depth - расстояние до исходного сотрудника (1 - непосредственный руководитель или подчинённый)
+type HierarchyResponse struct {
+	Response
+	Depth int `json:"depth"`
+} // @name EmployeeHierarchyResponse
+
+func (e *hierarchyEntity) toHierarchyResponse() HierarchyResponse {
+	return HierarchyResponse{
+		Response: e.toResponse(),
+		Depth:    e.Depth,
+	}
+}
+
+func toHierarchyResponses(entities []hierarchyEntity) []HierarchyResponse {
+	responses := make([]HierarchyResponse, len(entities))
+	for i, entity := range entities {
+		responses[i] = entity.toHierarchyResponse()
+	}
+	return responses
+}
+
+// OrgChartNode узел оргструктуры: сотрудник и его непосредственные подчинённые
+type OrgChartNode struct {
+	Id         int64          `json:"id"`
+	Name       string         `json:"name"`
+	Email      string         `json:"email"`
+	Position   string         `json:"position"`
+	Department string         `json:"department"`
+	Status     string         `json:"status"`
+	Reports    []OrgChartNode `json:"reports"`
+} // @name OrgChartNode
+
+// строит лес оргструктуры по manager_id; сотрудники, руководитель которых не найден, становятся корнями
+func buildOrgChart(employees []Entity) []OrgChartNode {
+	reports := make(map[int64][]Entity, len(employees))
+	known := make(map[int64]bool, len(employees))
+	for _, employee := range employees {
+		known[employee.Id] = true
+	}
+
+	var roots []Entity
+	for _, employee := range employees {
+		if employee.ManagerId == nil || !known[*employee.ManagerId] {
+			roots = append(roots, employee)
+			continue
+		}
+		reports[*employee.ManagerId] = append(reports[*employee.ManagerId], employee)
+	}
+
+	var build func(nodes []Entity) []OrgChartNode
+	build = func(nodes []Entity) []OrgChartNode {
+		result := make([]OrgChartNode, len(nodes))
+		for i, node := range nodes {
+			result[i] = OrgChartNode{
+				Id:         node.Id,
+				Name:       node.Name,
+				Email:      node.Email,
+				Position:   node.Position,
+				Department: node.Department,
+				Status:     node.Status,
+				Reports:    build(reports[node.Id]),
+			}
+		}
+		return result
+	}
+	return build(roots)
+}
+
+// PageRequest структура для запроса пагинации.
+// DepartmentIds отбирает сотрудников указанных отделов вместе со всеми их подотделами,
+// RoleIds - сотрудников с активным назначением любой из ролей,
+// CreatedFrom включается в интервал, CreatedTo - нет.
+// Sort - поля через запятую, "-" перед полем задаёт обратный порядок: "name,-created_at"
 type PageRequest struct {
-	PageNumber int    `json:"pageNumber" validate:"min=1"`
-	PageSize   int    `json:"pageSize" validate:"min=1,max=100"`
-	TextFilter string `json:"textFilter"`
+	PageNumber    int        `json:"pageNumber" validate:"min=1"`
+	PageSize      int        `json:"pageSize" validate:"min=1,max=100"`
+	TextFilter    string     `json:"textFilter"`
+	Email         string     `json:"email" validate:"max=255"`
+	DepartmentIds []int64    `json:"departmentIds" validate:"max=50,dive,min=1"`
+	PositionIds   []int64    `json:"positionIds" validate:"max=50,dive,min=1"`
+	RoleIds       []int64    `json:"roleIds" validate:"max=50,dive,min=1"`
+	Statuses      []string   `json:"statuses" validate:"max=4,dive,oneof=pending active suspended terminated"`
+	CreatedFrom   *time.Time `json:"createdFrom"`
+	CreatedTo     *time.Time `json:"createdTo"`
+	Sort          string     `json:"sort" validate:"max=200" example:"name,-created_at"`
 } // @name PageRequest
 
+// CursorRequest структура запроса страницы сотрудников по курсору.
+// Sort - одно поле из списка полей сортировки PageRequest, кроме hire_date и start_date;
+// "-" перед полем задаёт обратный порядок, по умолчанию - по id
+type CursorRequest struct {
+	Limit  int            `json:"limit" validate:"min=1,max=100"`
+	Sort   string         `json:"sort" validate:"max=50" example:"-created_at"`
+	Cursor *common.Cursor `json:"-"`
+}
+
+// CursorPage страница сотрудников и позиции соседних страниц; nil - соседней страницы нет
+type CursorPage struct {
+	Data []Response
+	Next *common.Cursor
+	Prev *common.Cursor
+}
+
+// CursorPageResponse структура ответа с постраничным выводом по курсору.
+// Next и Prev передаются в параметре cursor следующего запроса
+type CursorPageResponse struct {
+	Data []Response `json:"data"`
+	Next *string    `json:"next"`
+	Prev *string    `json:"prev"`
+} // @name EmployeeCursorPageResponse
+
 // PageResponse структура для ответа с пагинацией
 type PageResponse struct {
 	Data       []Response `json:"data"`
@@ -70,3 +483,146 @@ type PageResponse struct {
 	TotalCount int64      `json:"totalCount"`
 	TotalPages int        `json:"totalPages"`
 } // @name PageResponse
+
+// SearchRequest структура запроса поиска сотрудников по имени, email, должности и отделу
+type SearchRequest struct {
+	Query string `json:"q" validate:"required,max=200" example:"иванов"`
+	Limit int    `json:"limit" validate:"min=1,max=100"`
+}
+
+// найденный сотрудник и его релевантность запросу
+type searchEntity struct {
+	Entity
+	Rank float64 `db:"rank"`
+}
+
+// SearchResponse найденный сотрудник; Rank - релевантность, чем больше, тем точнее совпадение
+type SearchResponse struct {
+	Response
+	Rank float64 `json:"rank"`
+} // @name EmployeeSearchResponse
+
+func (e *searchEntity) toSearchResponse() SearchResponse {
+	return SearchResponse{
+		Response: e.toResponse(),
+		Rank:     e.Rank,
+	}
+}
+
+// ImportRequest запрос на массовое создание сотрудников из файла.
+// DryRun - только проверить строки, ничего не создавая.
+// BestEffort - создать все корректные строки; без него любая ошибочная строка отменяет весь импорт
+type ImportRequest struct {
+	Rows       []ImportRow
+	DryRun     bool
+	BestEffort bool
+	// вызывается после обработки каждой строки с числом обработанных строк; может быть nil
+	Progress func(done int)
+}
+
+// roleNameEntity id роли и её название в нижнем регистре для поиска роли по названию при импорте
+type roleNameEntity struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+// ImportRowResult результат импорта строки: created, skipped (сотрудник уже существует) или failed.
+// Line - номер строки в файле, Id - id созданного сотрудника, если импорт зафиксирован
+type ImportRowResult struct {
+	Line    int                         `json:"line"`
+	Status  string                      `json:"status" example:"created"`
+	Id      int64                       `json:"id,omitempty"`
+	Name    string                      `json:"name,omitempty"`
+	Email   string                      `json:"email,omitempty"`
+	Message string                      `json:"message,omitempty"`
+	Errors  []validator.ValidationError `json:"errors,omitempty"`
+} // @name ImportRowResult
+
+// ImportReport отчёт об импорте. Committed - изменения сохранены; при dryRun или отменённом импорте
+// статус created означает, что строка была бы создана
+type ImportReport struct {
+	DryRun     bool              `json:"dryRun"`
+	BestEffort bool              `json:"bestEffort"`
+	Committed  bool              `json:"committed"`
+	Created    int               `json:"created"`
+	Skipped    int               `json:"skipped"`
+	Failed     int               `json:"failed"`
+	Rows       []ImportRowResult `json:"rows"`
+} // @name ImportReport
+
+func (report *ImportReport) add(result ImportRowResult) {
+	switch result.Status {
+	case ImportCreated:
+		report.Created++
+	case ImportSkipped:
+		report.Skipped++
+	case ImportFailed:
+		report.Failed++
+	}
+	report.Rows = append(report.Rows, result)
+}
+
+// сотрудник для выгрузки: названия активных ролей и имя руководителя вместо id
+type exportEntity struct {
+	Entity
+	Roles   pq.StringArray `db:"roles"`
+	Manager string         `db:"manager"`
+}
+
+// ExportHeader колонки CSV выгрузки сотрудников, в порядке ExportRecord.CSVValues
+var ExportHeader = []string{
+	"id", "name", "email", "status", "position", "department", "manager", "roles",
+	"hire_date", "start_date", "created_at", "updated_at",
+}
+
+// ExportRecord запись выгрузки сотрудников
+type ExportRecord struct {
+	Id         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Email      string     `json:"email"`
+	Status     string     `json:"status"`
+	Position   string     `json:"position"`
+	Department string     `json:"department"`
+	Manager    string     `json:"manager"`
+	Roles      []string   `json:"roles"`
+	HireDate   *time.Time `json:"hire_date"`
+	StartDate  *time.Time `json:"start_date"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+} // @name EmployeeExportRecord
+
+func (e *exportEntity) toExportRecord() ExportRecord {
+	roles := []string(e.Roles)
+	if roles == nil {
+		roles = []string{}
+	}
+	return ExportRecord{
+		Id:         e.Id,
+		Name:       e.Name,
+		Email:      e.Email,
+		Status:     e.Status,
+		Position:   e.Position,
+		Department: e.Department,
+		Manager:    e.Manager,
+		Roles:      roles,
+		HireDate:   e.HireDate,
+		StartDate:  e.StartDate,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}
+}
+
+// значения колонок CSV; роли перечисляются через "; ", даты без времени - в формате 2006-01-02
+func (r ExportRecord) CSVValues() []string {
+	date := func(value *time.Time) string {
+		if value == nil {
+			return ""
+		}
+		return value.Format(time.DateOnly)
+	}
+	return []string{
+		strconv.FormatInt(r.Id, 10), r.Name, r.Email, r.Status, r.Position, r.Department, r.Manager,
+		strings.Join(r.Roles, "; "), date(r.HireDate), date(r.StartDate),
+		r.CreatedAt.Format(time.RFC3339), r.UpdatedAt.Format(time.RFC3339),
+	}
+}
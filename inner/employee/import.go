@@ -0,0 +1,216 @@
+package employee
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// результат импорта строки
+const (
+	ImportCreated = "created"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// форматы файла импорта
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+// максимальное число строк в одном импорте: все строки создаются в одной транзакции
+const maxImportRows = 5000
+
+// ImportRow строка файла импорта. Роль задаётся id (role_id) или названием (role).
+// Error - ошибка разбора строки: такая строка не создаётся и попадает в отчёт как failed
+type ImportRow struct {
+	Line    int
+	Request CreateRequest
+	Role    string
+	Error   string
+}
+
+// запись JSON Lines; набор полей совпадает с колонками CSV
+type importRecord struct {
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	PositionId   int64      `json:"position_id"`
+	DepartmentId int64      `json:"department_id"`
+	RoleId       int64      `json:"role_id"`
+	Role         string     `json:"role"`
+	HireDate     *time.Time `json:"hire_date"`
+	StartDate    *time.Time `json:"start_date"`
+}
+
+// колонки CSV; первая строка файла - заголовок с названиями колонок в любом порядке
+var importColumns = map[string]func(row *ImportRow, value string) error{
+	"name":  func(row *ImportRow, value string) error { row.Request.Name = value; return nil },
+	"email": func(row *ImportRow, value string) error { row.Request.Email = value; return nil },
+	"role":  func(row *ImportRow, value string) error { row.Role = value; return nil },
+	"position_id": func(row *ImportRow, value string) error {
+		return parseImportId(value, &row.Request.PositionId)
+	},
+	"department_id": func(row *ImportRow, value string) error {
+		return parseImportId(value, &row.Request.DepartmentId)
+	},
+	"role_id": func(row *ImportRow, value string) error {
+		return parseImportId(value, &row.Request.RoleId)
+	},
+	"hire_date": func(row *ImportRow, value string) (err error) {
+		row.Request.HireDate, err = parseImportDate(value)
+		return err
+	},
+	"start_date": func(row *ImportRow, value string) (err error) {
+		row.Request.StartDate, err = parseImportDate(value)
+		return err
+	},
+}
+
+// ImportFormat определяет формат файла импорта по заголовку Content-Type; пустая строка - формат не поддерживается
+func ImportFormat(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "text/csv", "application/csv":
+		return ImportFormatCSV
+	case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+		return ImportFormatJSONL
+	}
+	return ""
+}
+
+// ParseImport разбирает файл импорта. Ошибки отдельных строк записываются в строки,
+// ошибка возвращается, только если файл нельзя разобрать целиком
+func ParseImport(format string, body io.Reader) ([]ImportRow, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseImportCSV(body)
+	case ImportFormatJSONL:
+		return parseImportJSONL(body)
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+func parseImportCSV(body io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	// число колонок проверяется по заголовку для каждой строки отдельно
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv header: %w", err)
+	}
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := importColumns[name]; !ok {
+			return nil, fmt.Errorf("unknown csv column %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate csv column %q", name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			// после ошибки разбора чтение продолжается со следующей записи
+			rows = append(rows, ImportRow{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		row := ImportRow{Line: line}
+		if len(record) != len(columns) {
+			row.Error = fmt.Sprintf("expected %d columns, got %d", len(columns), len(record))
+			rows = append(rows, row)
+			continue
+		}
+		for i, value := range record {
+			if err := importColumns[columns[i]](&row, strings.TrimSpace(value)); err != nil {
+				row.Error = fmt.Sprintf("column %s: %v", columns[i], err)
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseImportJSONL(body io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var rows []ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		row := ImportRow{Line: line}
+		var record importRecord
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			row.Error = err.Error()
+		} else {
+			row.Role = strings.TrimSpace(record.Role)
+			row.Request = CreateRequest{
+				Name:         strings.TrimSpace(record.Name),
+				Email:        strings.TrimSpace(record.Email),
+				PositionId:   record.PositionId,
+				DepartmentId: record.DepartmentId,
+				RoleId:       record.RoleId,
+				HireDate:     record.HireDate,
+				StartDate:    record.StartDate,
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+// пустое значение - 0, т.е. поле не задано
+func parseImportId(value string, target *int64) error {
+	if value == "" {
+		return nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", value)
+	}
+	*target = id
+	return nil
+}
+
+// дата в формате 2006-01-02 или RFC3339; пустое значение - nil
+func parseImportDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q", value)
+}
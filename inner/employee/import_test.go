@@ -0,0 +1,66 @@
+package employee
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportFormat(t *testing.T) {
+	assert.Equal(t, ImportFormatCSV, ImportFormat("text/csv; charset=utf-8"))
+	assert.Equal(t, ImportFormatJSONL, ImportFormat("application/x-ndjson"))
+	assert.Equal(t, "", ImportFormat("application/json"))
+}
+
+func TestParseImport_CSV(t *testing.T) {
+	body := "\ufeffName,email,role,position_id,department_id,hire_date\n" +
+		"John Doe,john@example.com,Developer,1,2,2025-07-01\n" +
+		"Jane Doe,jane@example.com,,x,2,\n" +
+		"Rick,rick@example.com\n" +
+		`"Bad "quote",bad@example.com,,1,1,` + "\n"
+
+	rows, err := ParseImport(ImportFormatCSV, strings.NewReader(body))
+
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	hireDate := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, ImportRow{
+		Line: 2,
+		Request: CreateRequest{
+			Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 2, HireDate: &hireDate,
+		},
+		Role: "Developer",
+	}, rows[0])
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Contains(t, rows[1].Error, "column position_id")
+	assert.Equal(t, "expected 6 columns, got 2", rows[2].Error)
+	assert.Equal(t, 5, rows[3].Line)
+	assert.NotEmpty(t, rows[3].Error)
+}
+
+func TestParseImport_CSVUnknownColumn(t *testing.T) {
+	_, err := ParseImport(ImportFormatCSV, strings.NewReader("name,salary\nJohn,100\n"))
+
+	assert.ErrorContains(t, err, `unknown csv column "salary"`)
+}
+
+func TestParseImport_JSONL(t *testing.T) {
+	body := `{"name":"John Doe","email":"john@example.com","role_id":3,"position_id":1,"department_id":2}` + "\n" +
+		"\n" +
+		`{"name":"Jane","salary":100}` + "\n"
+
+	rows, err := ParseImport(ImportFormatJSONL, strings.NewReader(body))
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, ImportRow{
+		Line:    1,
+		Request: CreateRequest{Name: "John Doe", Email: "john@example.com", RoleId: 3, PositionId: 1, DepartmentId: 2},
+	}, rows[0])
+	// пустые строки пропускаются, но нумерация соответствует файлу
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Contains(t, rows[1].Error, "salary")
+}
@@ -0,0 +1,68 @@
+package employee
+
+import (
+	"fmt"
+	"idm/inner/common"
+	"time"
+)
+
+// состояния жизненного цикла сотрудника
+const (
+	StatusPending    = "pending"
+	StatusActive     = "active"
+	StatusSuspended  = "suspended"
+	StatusTerminated = "terminated"
+)
+
+// переходы между состояниями (joiner / mover / leaver)
+const (
+	TransitionCreate    = "create"
+	TransitionHire      = "hire"
+	TransitionSuspend   = "suspend"
+	TransitionResume    = "resume"
+	TransitionTransfer  = "transfer"
+	TransitionTerminate = "terminate"
+)
+
+// transition описывает допустимый переход: из каких состояний он возможен и в какое переводит.
+// Пустой target означает, что состояние не меняется (перевод в другой отдел)
+type transition struct {
+	from   []string
+	target string
+}
+
+var transitions = map[string]transition{
+	TransitionHire:      {from: []string{StatusPending}, target: StatusActive},
+	TransitionSuspend:   {from: []string{StatusActive}, target: StatusSuspended},
+	TransitionResume:    {from: []string{StatusSuspended}, target: StatusActive},
+	TransitionTransfer:  {from: []string{StatusActive, StatusSuspended}},
+	TransitionTerminate: {from: []string{StatusPending, StatusActive, StatusSuspended}, target: StatusTerminated},
+}
+
+// nextStatus возвращает состояние после перехода name из состояния current
+// или ConflictError, если переход из текущего состояния недопустим
+func nextStatus(name, current string) (string, error) {
+	t, ok := transitions[name]
+	if !ok {
+		return "", fmt.Errorf("unknown employee transition %q", name)
+	}
+	for _, from := range t.from {
+		if from == current {
+			if t.target == "" {
+				return current, nil
+			}
+			return t.target, nil
+		}
+	}
+	return "", common.ConflictError{
+		Message: fmt.Sprintf("transition %s is not allowed for employee in status %s", name, current),
+	}
+}
+
+// initialStatus состояние нового сотрудника: до даты выхода он ожидает оформления
+func initialStatus(startDate *time.Time, now time.Time) string {
+	if startDate != nil && startDate.After(now) {
+		return StatusPending
+	}
+	return StatusActive
+}
@@ -5,9 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"iter"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
+	"idm/inner/common"
+	"idm/inner/database"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
@@ -16,71 +22,346 @@ type Repository struct {
 	db *sqlx.DB
 }
 
+// Источник истины для ролей сотрудника - таблица employee_role.
+// role_id в ответах вычисляется как последняя начавшаяся активная роль,
+// колонка employee.role_id поддерживается только для обратной совместимости
+const activeRoleIdColumn = `COALESCE((
+		SELECT er.role_id FROM employee_role er
+		WHERE er.employee_id = employee.id
+			AND er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+		ORDER BY er.valid_from DESC, er.id DESC
+		LIMIT 1
+	), 0) AS role_id`
+
+// название отдела вычисляется по department_id; у сотрудника без отдела - пустая строка
+const departmentNameColumn = `COALESCE((
+		SELECT d.name FROM department d WHERE d.id = employee.department_id
+	), '') AS department`
+
+// название должности вычисляется по position_id; у сотрудника без должности - пустая строка
+const positionNameColumn = `COALESCE((
+		SELECT p.name FROM position p WHERE p.id = employee.position_id
+	), '') AS position`
+
+const employeeColumns = `employee.id, employee.name, employee.email,
+	COALESCE(employee.position_id, 0) AS position_id, ` + positionNameColumn + `,
+	COALESCE(employee.department_id, 0) AS department_id, ` + departmentNameColumn + `,
+	` + activeRoleIdColumn + `, employee.status, employee.hire_date, employee.start_date, employee.manager_id,
+	employee.created_at, employee.updated_at, employee.deleted_at`
+
+const selectEmployee = `SELECT ` + employeeColumns + ` FROM employee`
+
+// удалённые (мягко) сотрудники исключаются из всех выборок, кроме восстановления и очистки
+const notDeleted = `employee.deleted_at IS NULL`
+
+// создаёт сотрудника вместе с назначением его начальной роли
+const insertEmployee = `WITH created AS (
+		INSERT INTO employee (name, email, position_id, department_id, role_id, status, hire_date, start_date)
+		VALUES ($1, $2, NULLIF($3::bigint, 0), NULLIF($4::bigint, 0), NULLIF($5::bigint, 0), COALESCE(NULLIF($6, ''), 'active'), $7, $8)
+		RETURNING id, role_id
+	), assigned AS (
+		INSERT INTO employee_role (employee_id, role_id)
+		SELECT id, role_id FROM created WHERE role_id IS NOT NULL
+	)
+	SELECT id FROM created`
+
+// условие активности назначения роли на текущий момент
+const activeAssignmentCondition = `er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())`
+
 func NewEmployeeRepository(database *sqlx.DB) *Repository {
 	return &Repository{db: database}
 }
 
 func (r *Repository) FindById(ctx context.Context, id int64) (employee Entity, err error) {
-	err = r.db.GetContext(ctx, &employee, "SELECT * FROM employee WHERE id = $1", id)
+	err = r.db.GetContext(ctx, &employee, selectEmployee+" WHERE id = $1 AND "+notDeleted, id)
 	return employee, err
 }
 
 func (r *Repository) Add(ctx context.Context, employee *Entity) error {
 	err := r.db.QueryRowContext(
 		ctx,
-		"INSERT INTO employee (name, email, position, department, role_id) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		employee.Name, employee.Email, employee.Position, employee.Department, employee.RoleId,
+		insertEmployee,
+		employee.Name, employee.Email, employee.PositionId, employee.DepartmentId, employee.RoleId,
+		employee.Status, employee.HireDate, employee.StartDate,
 	).Scan(&employee.Id)
 	return err
 }
 
-func (r *Repository) FindAll(ctx context.Context) ([]Entity, error) {
-	var employees []Entity
-	err := r.db.SelectContext(ctx, &employees, "SELECT * FROM employee")
-	return employees, err
-}
-
 func (r *Repository) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
 	var employees []Entity
 	if len(ids) == 0 {
 		return employees, nil
 	}
-	err := r.db.SelectContext(ctx, &employees, "SELECT * FROM employee WHERE id = ANY ($1)", pq.Array(ids))
+	err := r.db.SelectContext(ctx, &employees, selectEmployee+" WHERE id = ANY ($1) AND "+notDeleted, pq.Array(ids))
 	return employees, err
 }
 
-func (r *Repository) FindWithPagination(ctx context.Context, limit, offset int, textFilter string) ([]Entity, error) {
-	var employees []Entity
-	query := `SELECT * FROM employee WHERE 1 = 1`
-	args := []any{limit, offset}
-
-	// Добавляем фильтр по имени только если textFilter содержит не менее 3 не пробельных символов
-	if isValidTextFilter(textFilter) {
-		query += ` AND name ILIKE $3`
-		args = append(args, "%"+textFilter+"%")
+func (r *Repository) FindWithPagination(ctx context.Context, limit, offset int, filter PageRequest) ([]Entity, error) {
+	fields, err := parseSort(filter.Sort)
+	if err != nil {
+		return nil, err
 	}
 
-	query += ` ORDER BY id LIMIT $1 OFFSET $2`
+	var employees []Entity
+	condition, args := pageFilterCondition(filter, []any{limit, offset})
+	query := selectEmployee + ` WHERE ` + condition + ` ORDER BY ` + pageOrderBy(fields) + ` LIMIT $1 OFFSET $2`
 
-	err := r.db.SelectContext(ctx, &employees, query, args...)
+	err = r.db.SelectContext(ctx, &employees, query, args...)
 	return employees, err
 }
 
-func (r *Repository) CountWithFilter(ctx context.Context, textFilter string) (int64, error) {
+func (r *Repository) CountWithFilter(ctx context.Context, filter PageRequest) (int64, error) {
 	var count int64
-	query := `SELECT COUNT(*) FROM employee WHERE 1 = 1`
-	args := []any{}
-
-	// Фильтр по имени только если textFilter содержит не менее 3 не пробельных символов
-	if isValidTextFilter(textFilter) {
-		query += ` AND name ILIKE $1`
-		args = append(args, "%"+textFilter+"%")
-	}
+	condition, args := pageFilterCondition(filter, nil)
+	query := `SELECT COUNT(*) FROM employee WHERE ` + condition
 
 	err := r.db.GetContext(ctx, &count, query, args...)
 	return count, err
 }
 
+// id отделов из параметра вместе со всеми их подотделами
+const departmentSubtreeIds = `WITH RECURSIVE subtree AS (
+		SELECT id, ARRAY[id] AS path FROM department WHERE id = ANY ($%d)
+		UNION ALL
+		SELECT d.id, s.path || d.id
+		FROM department d JOIN subtree s ON d.parent_id = s.id
+		WHERE NOT d.id = ANY (s.path)
+	)
+	SELECT id FROM subtree`
+
+// условие отбора страницы сотрудников, общее для выборки и подсчёта, чтобы totalCount
+// всегда совпадал с содержимым страниц. Параметры фильтров добавляются к args,
+// условие ссылается на них по номерам
+func pageFilterCondition(filter PageRequest, args []any) (string, []any) {
+	condition := notDeleted
+	add := func(format string, arg any) {
+		args = append(args, arg)
+		condition += ` AND ` + fmt.Sprintf(format, len(args))
+	}
+
+	// фильтр по имени только если textFilter содержит не менее 3 не пробельных символов
+	if isValidTextFilter(filter.TextFilter) {
+		add(`employee.name ILIKE $%d`, "%"+filter.TextFilter+"%")
+	}
+	if email := strings.TrimSpace(filter.Email); email != "" {
+		add(`employee.email ILIKE $%d`, "%"+email+"%")
+	}
+
+	// сотрудники отделов и всех их подотделов
+	if len(filter.DepartmentIds) > 0 {
+		add(`employee.department_id IN (`+departmentSubtreeIds+`)`, pq.Array(filter.DepartmentIds))
+	}
+	if len(filter.PositionIds) > 0 {
+		add(`employee.position_id = ANY ($%d)`, pq.Array(filter.PositionIds))
+	}
+	// учитываются только активные на текущий момент назначения
+	if len(filter.RoleIds) > 0 {
+		add(`EXISTS (SELECT 1 FROM employee_role er WHERE er.employee_id = employee.id
+			AND er.role_id = ANY ($%d) AND `+activeAssignmentCondition+`)`, pq.Array(filter.RoleIds))
+	}
+	if len(filter.Statuses) > 0 {
+		add(`employee.status = ANY ($%d)`, pq.Array(filter.Statuses))
+	}
+	if filter.CreatedFrom != nil {
+		add(`employee.created_at >= $%d`, *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		add(`employee.created_at < $%d`, *filter.CreatedTo)
+	}
+
+	return condition, args
+}
+
+// поля, по которым разрешена сортировка страницы сотрудников, и их колонки
+var sortColumns = map[string]string{
+	"id":         "employee.id",
+	"name":       "employee.name",
+	"email":      "employee.email",
+	"status":     "employee.status",
+	"hire_date":  "employee.hire_date",
+	"start_date": "employee.start_date",
+	"created_at": "employee.created_at",
+	"updated_at": "employee.updated_at",
+}
+
+type sortField struct {
+	column string
+	desc   bool
+}
+
+// разбирает параметр сортировки вида "name,-created_at".
+// Поле вне списка sortColumns или повтор поля - ошибка
+func parseSort(sort string) ([]sortField, error) {
+	if strings.TrimSpace(sort) == "" {
+		return nil, nil
+	}
+
+	var fields []sortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(sort, ",") {
+		name := strings.TrimSpace(part)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+
+		column, ok := sortColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported sort field %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate sort field %q", name)
+		}
+		seen[name] = true
+		fields = append(fields, sortField{column: column, desc: desc})
+	}
+	return fields, nil
+}
+
+// ORDER BY по полям сортировки; id в конце делает порядок однозначным между страницами
+func pageOrderBy(fields []sortField) string {
+	var parts []string
+	hasId := false
+	for _, field := range fields {
+		direction := "ASC"
+		if field.desc {
+			direction = "DESC"
+		}
+		parts = append(parts, field.column+" "+direction)
+		hasId = hasId || field.column == sortColumns["id"]
+	}
+	if !hasId {
+		parts = append(parts, sortColumns["id"]+" ASC")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// колонки выгрузки: кроме полей сотрудника - названия активных ролей и имя руководителя
+const exportColumns = employeeColumns + `,
+	ARRAY(
+		SELECT r.name FROM employee_role er JOIN role r ON r.id = er.role_id
+		WHERE er.employee_id = employee.id AND ` + activeAssignmentCondition + `
+		ORDER BY r.name
+	) AS roles,
+	COALESCE((SELECT m.name FROM employee m WHERE m.id = employee.manager_id), '') AS manager`
+
+// ExportEmployees выбирает сотрудников по фильтру и сортировке страницы без ограничения количества.
+// Строки читаются из базы по мере прохода итератора
+func (r *Repository) ExportEmployees(ctx context.Context, filter PageRequest) (iter.Seq2[exportEntity, error], error) {
+	fields, err := parseSort(filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	condition, args := pageFilterCondition(filter, nil)
+	query := `SELECT ` + exportColumns + ` FROM employee WHERE ` + condition + ` ORDER BY ` + pageOrderBy(fields)
+
+	rows, err := r.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return database.StructRows[exportEntity](rows), nil
+}
+
+// колонка вывода по курсору: значения не бывают NULL, тип нужен для сравнения
+// со строковым значением из курсора, value получает это значение из строки выборки
+type cursorColumn struct {
+	column  string
+	sqlType string
+	value   func(Entity) string
+}
+
+// поля, по которым возможен вывод по курсору
+var cursorColumns = map[string]cursorColumn{
+	"id":         {"employee.id", "bigint", func(e Entity) string { return strconv.FormatInt(e.Id, 10) }},
+	"name":       {"employee.name", "text", func(e Entity) string { return e.Name }},
+	"email":      {"employee.email", "text", func(e Entity) string { return e.Email }},
+	"status":     {"employee.status", "text", func(e Entity) string { return e.Status }},
+	"created_at": {"employee.created_at", "timestamptz", func(e Entity) string { return e.CreatedAt.Format(time.RFC3339Nano) }},
+	"updated_at": {"employee.updated_at", "timestamptz", func(e Entity) string { return e.UpdatedAt.Format(time.RFC3339Nano) }},
+}
+
+// разбирает сортировку вывода по курсору: одно поле, "-" - по убыванию, по умолчанию id
+func parseCursorSort(sort string) (cursorColumn, bool, error) {
+	name := strings.TrimSpace(sort)
+	if name == "" {
+		name = "id"
+	}
+	desc := strings.HasPrefix(name, "-")
+	column, ok := cursorColumns[strings.TrimPrefix(name, "-")]
+	if !ok {
+		return cursorColumn{}, false, fmt.Errorf("unsupported cursor sort %q", sort)
+	}
+	return column, desc, nil
+}
+
+// FindWithCursor выбирает до limit сотрудников после позиции position в порядке сортировки sort;
+// id - второй ключ в том же направлении. Для position.Backward выбираются строки перед позицией
+// в обратном порядке. Без позиции выбирается начало списка
+func (r *Repository) FindWithCursor(ctx context.Context, sort string, position *common.Cursor, limit int) ([]Entity, error) {
+	column, desc, err := parseCursorSort(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// направление выборки: по убыванию при обратной сортировке или движении назад, но не при обоих
+	reverse := desc != (position != nil && position.Backward)
+	operator, direction := ">", "ASC"
+	if reverse {
+		operator, direction = "<", "DESC"
+	}
+
+	condition := notDeleted
+	args := []any{limit}
+	if position != nil {
+		args = append(args, position.Value, position.Id)
+		condition += fmt.Sprintf(` AND (%s, employee.id) %s ($2::%s, $3)`, column.column, operator, column.sqlType)
+	}
+	query := selectEmployee + ` WHERE ` + condition +
+		fmt.Sprintf(` ORDER BY %s %s, employee.id %s LIMIT $1`, column.column, direction, direction)
+
+	var employees []Entity
+	err = r.db.SelectContext(ctx, &employees, query, args...)
+	return employees, err
+}
+
+// Search ищет сотрудников по имени, email, должности и отделу: полнотекстово по префиксам слов запроса
+// и по триграммному сходству слов, чтобы находились опечатки. Запрос дополнительно транслитерируется,
+// кириллица и латиница находят друг друга. Результат упорядочен по убыванию релевантности
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]searchEntity, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	text := strings.Join(terms, " ")
+	prefixQuery := strings.Join(terms, ":* & ") + ":*"
+
+	var employees []searchEntity
+	err := r.db.SelectContext(
+		ctx,
+		&employees,
+		`WITH q AS (
+			SELECT to_tsquery('simple', $3) || to_tsquery('simple', translit_ru($3)) AS ts,
+				$2::text AS text, translit_ru($2) AS latin
+		)
+		SELECT `+employeeColumns+`,
+			ts_rank(employee.search_vector, q.ts)
+				+ GREATEST(word_similarity(q.text, employee.search_text), word_similarity(q.latin, employee.search_text)) AS rank
+		FROM employee CROSS JOIN q
+		WHERE `+notDeleted+` AND (employee.search_vector @@ q.ts
+			OR q.text <% employee.search_text OR q.latin <% employee.search_text)
+		ORDER BY rank DESC, employee.id
+		LIMIT $1`,
+		limit, text, prefixQuery)
+	return employees, err
+}
+
+// слова поискового запроса в нижнем регистре: только буквы и цифры,
+// поэтому их можно без экранирования подставить в tsquery
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
 // Вспомогательная функция для проверки валидности текстового фильтра
 func isValidTextFilter(textFilter string) bool {
 	if textFilter == "" {
@@ -102,13 +383,14 @@ func isValidTextFilter(textFilter string) bool {
 
 func (r *Repository) CountAll(ctx context.Context) (int64, error) {
 	var count int64
-	query := `SELECT COUNT(*) FROM employee`
+	query := `SELECT COUNT(*) FROM employee WHERE ` + notDeleted
 	err := r.db.GetContext(ctx, &count, query)
 	return count, err
 }
 
+// Мягко удалить сотрудника: строка помечается deleted_at и может быть восстановлена
 func (r *Repository) DeleteById(ctx context.Context, id int64) error {
-	result, err := r.db.ExecContext(ctx, "DELETE FROM employee WHERE id = $1", id)
+	result, err := r.db.ExecContext(ctx, "UPDATE employee SET deleted_at = now() WHERE id = $1 AND "+notDeleted, id)
 	if err != nil {
 		return err
 	}
@@ -122,7 +404,8 @@ func (r *Repository) DeleteById(ctx context.Context, id int64) error {
 }
 
 func (r *Repository) DeleteByIds(ctx context.Context, ids []int64) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM employee WHERE id = ANY ($1)", pq.Array(ids))
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE employee SET deleted_at = now() WHERE id = ANY ($1) AND "+notDeleted, pq.Array(ids))
 	return err
 }
 
@@ -137,7 +420,7 @@ func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string)
 	err = tx.GetContext(
 		ctx,
 		&isExists,
-		"select exists(select 1 from employee where name = $1)",
+		"select exists(select 1 from employee where name = $1 and "+notDeleted+")",
 		name,
 	)
 	return isExists, err
@@ -148,15 +431,16 @@ func (r *Repository) SaveTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (
 	err = tx.GetContext(
 		ctx,
 		&employeeId,
-		`insert into employee (name, email, position, department, role_id) values ($1, $2, $3, $4, $5) returning id`,
-		employee.Name, employee.Email, employee.Position, employee.Department, employee.RoleId)
+		insertEmployee,
+		employee.Name, employee.Email, employee.PositionId, employee.DepartmentId, employee.RoleId,
+		employee.Status, employee.HireDate, employee.StartDate)
 	return employeeId, err
 }
 
 // Добавить нового сотрудника
 func (r *Repository) AddWithTransaction(ctx context.Context, tx *sqlx.Tx, employee *Entity) error {
 	var existing *Entity
-	err := tx.GetContext(ctx, &existing, "SELECT id FROM employee WHERE name = $1", employee.Name)
+	err := tx.GetContext(ctx, &existing, "SELECT id FROM employee WHERE name = $1 AND "+notDeleted, employee.Name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 		} else {
@@ -168,9 +452,504 @@ func (r *Repository) AddWithTransaction(ctx context.Context, tx *sqlx.Tx, employ
 
 	err = tx.QueryRowContext(
 		ctx,
-		"INSERT INTO employee (name, email, position, department, role_id) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		employee.Name, employee.Email, employee.Position, employee.Department, employee.RoleId,
+		insertEmployee,
+		employee.Name, employee.Email, employee.PositionId, employee.DepartmentId, employee.RoleId,
+		employee.Status, employee.HireDate, employee.StartDate,
 	).Scan(&employee.Id)
 
 	return err
 }
+
+// Найти сотрудника по id и заблокировать строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (employee Entity, err error) {
+	err = tx.GetContext(ctx, &employee, selectEmployee+" WHERE id = $1 AND "+notDeleted+" FOR UPDATE OF employee", id)
+	return employee, err
+}
+
+// Найти сотрудников по списку id и заблокировать их строки до конца транзакции
+func (r *Repository) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	var employees []Entity
+	err := tx.SelectContext(ctx, &employees,
+		selectEmployee+" WHERE id = ANY ($1) AND "+notDeleted+" ORDER BY id FOR UPDATE OF employee", pq.Array(ids))
+	return employees, err
+}
+
+func (r *Repository) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE employee SET deleted_at = now() WHERE id = $1 AND "+notDeleted, id)
+	return err
+}
+
+func (r *Repository) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE employee SET deleted_at = now() WHERE id = ANY ($1) AND "+notDeleted, pq.Array(ids))
+	return err
+}
+
+// Найти удалённого сотрудника по id и заблокировать строку до конца транзакции
+func (r *Repository) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (employee Entity, err error) {
+	err = tx.GetContext(ctx, &employee,
+		selectEmployee+" WHERE id = $1 AND employee.deleted_at IS NOT NULL FOR UPDATE OF employee", id)
+	return employee, err
+}
+
+// Проверить, занят ли email неудалённым сотрудником
+func (r *Repository) EmailExistsTx(ctx context.Context, tx *sqlx.Tx, email string) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from employee where email = $1 and "+notDeleted+")", email)
+	return isExists, err
+}
+
+// Найти id действующих ролей по названиям без учёта регистра; одному названию может соответствовать несколько ролей
+func (r *Repository) FindRoleIdsByNamesTx(ctx context.Context, tx *sqlx.Tx, names []string) ([]roleNameEntity, error) {
+	var roles []roleNameEntity
+	err := tx.SelectContext(ctx, &roles,
+		"SELECT id, lower(name) AS name FROM role WHERE lower(name) = ANY ($1) AND deleted_at IS NULL ORDER BY id",
+		pq.Array(names))
+	return roles, err
+}
+
+// Точка сохранения внутри транзакции: откат к ней отменяет только изменения, сделанные после неё
+func (r *Repository) SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+func (r *Repository) RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+func (r *Repository) ReleaseSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+// Восстановить удалённого сотрудника
+func (r *Repository) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (restored Entity, err error) {
+	err = tx.GetContext(ctx, &restored,
+		"UPDATE employee SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+employeeColumns, id)
+	return restored, err
+}
+
+// Окончательно удалить сотрудников, удалённых раньше указанного момента.
+// Возвращает снимки удалённых строк
+func (r *Repository) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, deletedBefore time.Time) ([]Entity, error) {
+	var purged []Entity
+	err := tx.SelectContext(ctx, &purged,
+		"DELETE FROM employee WHERE deleted_at < $1 RETURNING "+employeeColumns, deletedBefore)
+	return purged, err
+}
+
+// Обновить сотрудника, если его версия (updated_at) совпадает с переданной.
+// Если версия изменилась, возвращается sql.ErrNoRows
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity, version time.Time) (updated Entity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&updated,
+		`UPDATE employee
+		SET name = $1, email = $2, position_id = NULLIF($3::bigint, 0), department_id = NULLIF($4::bigint, 0),
+			role_id = NULLIF($5::bigint, 0), updated_at = clock_timestamp()
+		WHERE id = $6 AND updated_at = $7 AND `+notDeleted+`
+		RETURNING `+employeeColumns,
+		employee.Name, employee.Email, employee.PositionId, employee.DepartmentId, employee.RoleId,
+		employee.Id, version)
+	return updated, err
+}
+
+// Найти назначения ролей сотрудника; activeOnly = true - только действующие на текущий момент
+func (r *Repository) FindRoleAssignments(ctx context.Context, employeeId int64, activeOnly bool) ([]RoleAssignmentEntity, error) {
+	var assignments []RoleAssignmentEntity
+	query := `SELECT er.id, er.employee_id, er.role_id, r.name AS role_name, er.valid_from, er.valid_to, er.created_at,
+		` + activeAssignmentCondition + ` AS active
+		FROM employee_role er JOIN role r ON r.id = er.role_id
+		WHERE er.employee_id = $1`
+	if activeOnly {
+		query += ` AND ` + activeAssignmentCondition
+	}
+	query += ` ORDER BY er.valid_from, er.id`
+
+	err := r.db.SelectContext(ctx, &assignments, query, employeeId)
+	return assignments, err
+}
+
+// Проверить существование отдела
+func (r *Repository) DepartmentExistsTx(ctx context.Context, tx *sqlx.Tx, departmentId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from department where id = $1)", departmentId)
+	return isExists, err
+}
+
+// Проверить существование должности
+func (r *Repository) PositionExistsTx(ctx context.Context, tx *sqlx.Tx, positionId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from position where id = $1)", positionId)
+	return isExists, err
+}
+
+// Найти неудалённые активные роли по умолчанию должности; неактивные роли не назначаются
+func (r *Repository) FindPositionRoleIdsTx(ctx context.Context, tx *sqlx.Tx, positionId int64) ([]int64, error) {
+	var roleIds []int64
+	err := tx.SelectContext(
+		ctx,
+		&roleIds,
+		`SELECT pr.role_id FROM position_role pr JOIN role r ON r.id = pr.role_id
+		WHERE pr.position_id = $1 AND r.deleted_at IS NULL AND r.status
+		ORDER BY pr.role_id`,
+		positionId)
+	return roleIds, err
+}
+
+// Найти статус неудалённой роли (true - активна). Если роли нет, возвращается sql.ErrNoRows
+func (r *Repository) FindRoleStatusTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (status bool, err error) {
+	err = tx.GetContext(ctx, &status, "select status from role where id = $1 and deleted_at is null", roleId)
+	return status, err
+}
+
+// Проверить, пересекается ли период [validFrom, validTo) с уже существующим назначением той же роли.
+// validFrom = nil означает текущий момент, validTo = nil - бессрочно
+func (r *Repository) HasOverlappingAssignmentTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	employeeId, roleId int64,
+	validFrom, validTo *time.Time,
+) (overlaps bool, err error) {
+	err = tx.GetContext(
+		ctx,
+		&overlaps,
+		`select exists(
+			select 1 from employee_role er
+			where er.employee_id = $1 and er.role_id = $2
+				and er.valid_from < COALESCE($4, 'infinity'::timestamptz)
+				and COALESCE(er.valid_to, 'infinity'::timestamptz) > COALESCE($3, now())
+		)`,
+		employeeId, roleId, validFrom, validTo)
+	return overlaps, err
+}
+
+// Назначить роль сотруднику. validFrom = nil означает текущий момент
+func (r *Repository) AssignRoleTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	employeeId, roleId int64,
+	validFrom, validTo *time.Time,
+) (assignment RoleAssignmentEntity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&assignment,
+		`WITH created AS (
+			INSERT INTO employee_role (employee_id, role_id, valid_from, valid_to)
+			VALUES ($1, $2, COALESCE($3, now()), $4)
+			RETURNING *
+		)
+		SELECT er.id, er.employee_id, er.role_id, r.name AS role_name, er.valid_from, er.valid_to, er.created_at,
+			`+activeAssignmentCondition+` AS active
+		FROM created er JOIN role r ON r.id = er.role_id`,
+		employeeId, roleId, validFrom, validTo)
+	return assignment, err
+}
+
+// Отозвать назначение роли: действующее назначение закрывается текущим моментом,
+// ещё не начавшееся - удаляется. Возвращает false, если отзывать нечего
+func (r *Repository) RevokeRoleAssignmentTx(ctx context.Context, tx *sqlx.Tx, employeeId, assignmentId int64) (bool, error) {
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE employee_role SET valid_to = now()
+		WHERE id = $1 AND employee_id = $2
+			AND valid_from <= now() AND (valid_to IS NULL OR valid_to > now())`,
+		assignmentId, employeeId)
+	if err != nil {
+		return false, err
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows > 0 {
+		return rows > 0, err
+	}
+
+	result, err = tx.ExecContext(
+		ctx,
+		"DELETE FROM employee_role WHERE id = $1 AND employee_id = $2 AND valid_from > now()",
+		assignmentId, employeeId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// Закрыть все действующие назначения роли сотрудника текущим моментом
+func (r *Repository) EndActiveAssignmentsTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE employee_role er SET valid_to = now()
+		WHERE er.employee_id = $1 AND er.role_id = $2 AND `+activeAssignmentCondition,
+		employeeId, roleId)
+	return err
+}
+
+// Синхронизировать колонку employee.role_id с назначениями (для обратной совместимости)
+func (r *Repository) SyncLegacyRoleIdTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE employee SET role_id = NULLIF(active.role_id, 0)
+		FROM (SELECT `+activeRoleIdColumn+` FROM employee WHERE employee.id = $1) AS active
+		WHERE employee.id = $1 AND employee.role_id IS DISTINCT FROM NULLIF(active.role_id, 0)`,
+		employeeId)
+	return err
+}
+
+// Сохранить состояние жизненного цикла сотрудника вместе с датами и должностью
+func (r *Repository) UpdateLifecycleTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (updated Entity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&updated,
+		`UPDATE employee
+		SET status = $1, hire_date = $2, start_date = $3, position_id = NULLIF($4::bigint, 0), department_id = NULLIF($5::bigint, 0),
+			updated_at = clock_timestamp()
+		WHERE id = $6 AND `+notDeleted+`
+		RETURNING `+employeeColumns,
+		employee.Status, employee.HireDate, employee.StartDate, employee.PositionId, employee.DepartmentId, employee.Id)
+	return updated, err
+}
+
+// Записать переход сотрудника в историю состояний
+func (r *Repository) SaveStatusHistoryTx(ctx context.Context, tx *sqlx.Tx, history StatusHistoryEntity) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO employee_status_history
+			(employee_id, transition, from_status, to_status, reason, details, actor_sub, actor_username)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		history.EmployeeId, history.Transition, history.FromStatus, history.ToStatus, history.Reason,
+		history.Details, history.ActorSub, history.ActorUsername)
+	return err
+}
+
+// Найти историю переходов сотрудника, от старых к новым
+func (r *Repository) FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryEntity, error) {
+	var history []StatusHistoryEntity
+	err := r.db.SelectContext(
+		ctx,
+		&history,
+		`SELECT id, employee_id, transition, from_status, to_status, reason, details, actor_sub, actor_username, created_at
+		FROM employee_status_history
+		WHERE employee_id = $1
+		ORDER BY created_at, id`,
+		employeeId)
+	return history, err
+}
+
+// Отозвать все назначения ролей сотрудника: действующие закрываются текущим моментом,
+// ещё не начавшиеся - удаляются. Возвращает id затронутых назначений
+func (r *Repository) RevokeAllAssignmentsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) ([]int64, error) {
+	var ended []int64
+	err := tx.SelectContext(
+		ctx,
+		&ended,
+		`UPDATE employee_role er SET valid_to = now()
+		WHERE er.employee_id = $1 AND `+activeAssignmentCondition+`
+		RETURNING er.id`,
+		employeeId)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []int64
+	err = tx.SelectContext(
+		ctx,
+		&removed,
+		"DELETE FROM employee_role WHERE employee_id = $1 AND valid_from > now() RETURNING id",
+		employeeId)
+	if err != nil {
+		return nil, err
+	}
+	return append(ended, removed...), nil
+}
+
+const scheduledChangeColumns = `id, employee_id, position_id, department_id, role_id, effective_at, status, reason, result,
+	created_by_sub, created_by_username, created_at, updated_at, applied_at`
+
+// Создать отложенное изменение сотрудника
+func (r *Repository) SaveScheduledChangeTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	change ScheduledChangeEntity,
+) (created ScheduledChangeEntity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&created,
+		`INSERT INTO employee_scheduled_change
+			(employee_id, position_id, department_id, role_id, effective_at, reason, created_by_sub, created_by_username)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+scheduledChangeColumns,
+		change.EmployeeId, change.PositionId, change.DepartmentId, change.RoleId, change.EffectiveAt, change.Reason,
+		change.CreatedBySub, change.CreatedByUsername)
+	return created, err
+}
+
+// Найти отложенные изменения по фильтру, ближайшие первыми
+func (r *Repository) FindScheduledChanges(ctx context.Context, filter ScheduledChangeFilter) ([]ScheduledChangeEntity, error) {
+	var changes []ScheduledChangeEntity
+	query := `SELECT ` + scheduledChangeColumns + ` FROM employee_scheduled_change WHERE true`
+	var args []any
+
+	if filter.EmployeeId > 0 {
+		args = append(args, filter.EmployeeId)
+		query += fmt.Sprintf(" AND employee_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	query += ` ORDER BY effective_at, id`
+
+	err := r.db.SelectContext(ctx, &changes, query, args...)
+	return changes, err
+}
+
+// Найти отложенное изменение по id и заблокировать строку до конца транзакции
+func (r *Repository) FindScheduledChangeForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (change ScheduledChangeEntity, err error) {
+	err = tx.GetContext(ctx, &change,
+		"SELECT "+scheduledChangeColumns+" FROM employee_scheduled_change WHERE id = $1 FOR UPDATE", id)
+	return change, err
+}
+
+// Сохранить изменяемые поля отложенного изменения
+func (r *Repository) UpdateScheduledChangeTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	change ScheduledChangeEntity,
+) (updated ScheduledChangeEntity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&updated,
+		`UPDATE employee_scheduled_change
+		SET position_id = $1, department_id = $2, role_id = $3, effective_at = $4, reason = $5,
+			status = $6, result = $7, applied_at = $8, updated_at = now()
+		WHERE id = $9
+		RETURNING `+scheduledChangeColumns,
+		change.PositionId, change.DepartmentId, change.RoleId, change.EffectiveAt, change.Reason,
+		change.Status, change.Result, change.AppliedAt, change.Id)
+	return updated, err
+}
+
+// Найти id наступивших, но ещё не применённых изменений
+func (r *Repository) FindDueScheduledChangeIds(ctx context.Context, limit int) ([]int64, error) {
+	var ids []int64
+	err := r.db.SelectContext(
+		ctx,
+		&ids,
+		`SELECT id FROM employee_scheduled_change
+		WHERE status = 'pending' AND effective_at <= now()
+		ORDER BY effective_at, id
+		LIMIT $1`,
+		limit)
+	return ids, err
+}
+
+// Заблокировать наступившее изменение для применения. Если изменение уже применяется
+// другим экземпляром приложения, отменено или ещё не наступило, возвращается sql.ErrNoRows
+func (r *Repository) LockDueScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, id int64) (change ScheduledChangeEntity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&change,
+		`SELECT `+scheduledChangeColumns+` FROM employee_scheduled_change
+		WHERE id = $1 AND status = 'pending' AND effective_at <= now()
+		FOR UPDATE SKIP LOCKED`,
+		id)
+	return change, err
+}
+
+// Отметить изменение как не применённое, сохранив причину
+func (r *Repository) MarkScheduledChangeFailed(ctx context.Context, id int64, result string) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE employee_scheduled_change SET status = 'failed', result = $2, updated_at = now()
+		WHERE id = $1 AND status = 'pending'`,
+		id, result)
+	return err
+}
+
+// Заблокировать линию подчинения до конца транзакции: изменения руководителей выполняются последовательно,
+// чтобы параллельные назначения не образовали цикл
+func (r *Repository) LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('employee_hierarchy'))")
+	return err
+}
+
+// Проверить, находится ли сотрудник ancestorId в цепочке руководителей сотрудника employeeId (включая его самого)
+func (r *Repository) IsInManagementChainTx(ctx context.Context, tx *sqlx.Tx, employeeId, ancestorId int64) (inChain bool, err error) {
+	err = tx.GetContext(
+		ctx,
+		&inChain,
+		`WITH RECURSIVE chain AS (
+			SELECT id, manager_id FROM employee WHERE id = $1
+			UNION
+			SELECT e.id, e.manager_id FROM employee e JOIN chain c ON e.id = c.manager_id
+		)
+		SELECT exists(SELECT 1 FROM chain WHERE id = $2)`,
+		employeeId, ancestorId)
+	return inChain, err
+}
+
+// Назначить сотруднику руководителя; managerId = nil убирает руководителя
+func (r *Repository) UpdateManagerTx(ctx context.Context, tx *sqlx.Tx, id int64, managerId *int64) (updated Entity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&updated,
+		`UPDATE employee SET manager_id = $1, updated_at = clock_timestamp()
+		WHERE id = $2 AND `+notDeleted+`
+		RETURNING `+employeeColumns,
+		managerId, id)
+	return updated, err
+}
+
+// Найти непосредственных подчинённых сотрудника
+func (r *Repository) FindDirectReports(ctx context.Context, managerId int64) ([]Entity, error) {
+	var employees []Entity
+	err := r.db.SelectContext(ctx, &employees,
+		selectEmployee+" WHERE manager_id = $1 AND "+notDeleted+" ORDER BY name, id", managerId)
+	return employees, err
+}
+
+// Найти всех подчинённых сотрудника в порядке обхода в ширину
+func (r *Repository) FindSubordinates(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	var employees []hierarchyEntity
+	err := r.db.SelectContext(
+		ctx,
+		&employees,
+		`WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth, ARRAY[id] AS path FROM employee WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT e.id, s.depth + 1, s.path || e.id
+			FROM employee e JOIN subtree s ON e.manager_id = s.id
+			WHERE NOT e.id = ANY (s.path) AND e.deleted_at IS NULL
+		)
+		SELECT `+employeeColumns+`, s.depth FROM subtree s JOIN employee ON employee.id = s.id
+		WHERE s.depth > 0
+		ORDER BY s.depth, employee.id`,
+		id)
+	return employees, err
+}
+
+// Найти цепочку руководителей сотрудника: от непосредственного руководителя до верха
+func (r *Repository) FindManagementChain(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	var employees []hierarchyEntity
+	err := r.db.SelectContext(
+		ctx,
+		&employees,
+		`WITH RECURSIVE chain AS (
+			SELECT id, manager_id, 0 AS depth, ARRAY[id] AS path FROM employee WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT e.id, e.manager_id, c.depth + 1, c.path || e.id
+			FROM employee e JOIN chain c ON e.id = c.manager_id
+			WHERE NOT e.id = ANY (c.path) AND e.deleted_at IS NULL
+		)
+		SELECT `+employeeColumns+`, c.depth FROM chain c JOIN employee ON employee.id = c.id
+		WHERE c.depth > 0
+		ORDER BY c.depth`,
+		id)
+	return employees, err
+}
+
+// Найти сотрудников для построения оргструктуры; уволенные в неё не входят
+func (r *Repository) FindOrgChart(ctx context.Context) ([]Entity, error) {
+	var employees []Entity
+	err := r.db.SelectContext(ctx, &employees,
+		selectEmployee+" WHERE "+notDeleted+" AND employee.status <> 'terminated' ORDER BY name, id")
+	return employees, err
+}
@@ -0,0 +1,101 @@
+package employee
+
+import (
+	"context"
+	"idm/inner/common"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// состояния отложенного изменения
+const (
+	ChangeStatusPending   = "pending"
+	ChangeStatusApplied   = "applied"
+	ChangeStatusFailed    = "failed"
+	ChangeStatusCancelled = "cancelled"
+)
+
+// интерфейс применения наступивших изменений (реализуется employee.Service)
+type ChangeApplier interface {
+	ApplyDueChanges(ctx context.Context) (int, error)
+}
+
+// Scheduler фоновая горутина, периодически применяющая наступившие отложенные изменения сотрудников
+type Scheduler struct {
+	applier  ChangeApplier
+	interval time.Duration
+	logger   *common.Logger
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// функция-конструктор
+func NewScheduler(applier ChangeApplier, interval time.Duration, logger *common.Logger) *Scheduler {
+	return &Scheduler{
+		applier:  applier,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start запускает планировщик; первая проверка выполняется сразу
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go s.run(ctx)
+	s.logger.Info("Scheduled changes scheduler started", zap.Duration("interval", s.interval))
+}
+
+// Stop останавливает планировщик и ждёт завершения текущего прохода или отмены ctx
+func (s *Scheduler) Stop(ctx context.Context) {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.logger.Info("Scheduled changes scheduler stopped")
+	case <-ctx.Done():
+		s.logger.Warn("Scheduled changes scheduler stop timeout exceeded")
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// один проход планировщика; ошибки только логируются, изменения будут повторены на следующем проходе
+func (s *Scheduler) tick(ctx context.Context) {
+	applied, err := s.applier.ApplyDueChanges(ctx)
+	if err != nil && ctx.Err() == nil {
+		s.logger.Error("Failed to apply scheduled changes",
+			zap.Int("applied", applied),
+			zap.Error(err))
+		return
+	}
+	if applied > 0 {
+		s.logger.Info("Scheduled changes applied", zap.Int("applied", applied))
+	}
+}
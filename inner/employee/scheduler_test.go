@@ -0,0 +1,42 @@
+package employee
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// применяет изменения без базы данных, считая вызовы
+type countingApplier struct {
+	calls atomic.Int32
+}
+
+func (a *countingApplier) ApplyDueChanges(ctx context.Context) (int, error) {
+	a.calls.Add(1)
+	return 0, nil
+}
+
+func TestScheduler_StartStop(t *testing.T) {
+	applier := &countingApplier{}
+	scheduler := NewScheduler(applier, 10*time.Millisecond, createTestLogger())
+
+	scheduler.Start()
+	assert.Eventually(t, func() bool { return applier.calls.Load() >= 2 }, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	scheduler.Stop(ctx)
+
+	// после остановки проходов больше нет
+	calls := applier.calls.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, calls, applier.calls.Load())
+}
+
+func TestScheduler_StopWithoutStart(t *testing.T) {
+	scheduler := NewScheduler(&countingApplier{}, time.Minute, createTestLogger())
+	scheduler.Stop(context.Background())
+}
@@ -2,8 +2,15 @@ package employee
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
+	"strings"
+	"time"
 
+	"idm/inner/audit"
 	"idm/inner/common"
 	"idm/inner/validator"
 
@@ -13,6 +20,8 @@ import (
 
 type Service struct {
 	repo      Repo
+	auditor   Auditor
+	sod       SodChecker
 	validator Validator
 	logger    *common.Logger
 }
@@ -21,16 +30,68 @@ type Repo interface {
 	FindById(ctx context.Context, id int64) (Entity, error)
 	Add(ctx context.Context, employee *Entity) error
 	AddWithTransaction(ctx context.Context, tx *sqlx.Tx, employee *Entity) error
-	FindAll(ctx context.Context) ([]Entity, error)
+	FindWithCursor(ctx context.Context, sort string, position *common.Cursor, limit int) ([]Entity, error)
+	Search(ctx context.Context, query string, limit int) ([]searchEntity, error)
+	ExportEmployees(ctx context.Context, filter PageRequest) (iter.Seq2[exportEntity, error], error)
 	FindByIds(ctx context.Context, ids []int64) ([]Entity, error)
-	DeleteById(ctx context.Context, id int64) error
-	DeleteByIds(ctx context.Context, ids []int64) error
 	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
 	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
 	SaveTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (int64, error)
-	FindWithPagination(ctx context.Context, limit, offset int, textFilter string) ([]Entity, error)
+	FindWithPagination(ctx context.Context, limit, offset int, filter PageRequest) ([]Entity, error)
 	CountAll(ctx context.Context) (int64, error)
-	CountWithFilter(ctx context.Context, textFilter string) (int64, error)
+	CountWithFilter(ctx context.Context, filter PageRequest) (int64, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error)
+	DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+	DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error
+	FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	EmailExistsTx(ctx context.Context, tx *sqlx.Tx, email string) (bool, error)
+	RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindRoleIdsByNamesTx(ctx context.Context, tx *sqlx.Tx, names []string) ([]roleNameEntity, error)
+	SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error
+	RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error
+	ReleaseSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error
+	PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, deletedBefore time.Time) ([]Entity, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity, version time.Time) (Entity, error)
+	FindRoleAssignments(ctx context.Context, employeeId int64, activeOnly bool) ([]RoleAssignmentEntity, error)
+	FindRoleStatusTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (bool, error)
+	DepartmentExistsTx(ctx context.Context, tx *sqlx.Tx, departmentId int64) (bool, error)
+	PositionExistsTx(ctx context.Context, tx *sqlx.Tx, positionId int64) (bool, error)
+	FindPositionRoleIdsTx(ctx context.Context, tx *sqlx.Tx, positionId int64) ([]int64, error)
+	HasOverlappingAssignmentTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64, validFrom, validTo *time.Time) (bool, error)
+	AssignRoleTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64, validFrom, validTo *time.Time) (RoleAssignmentEntity, error)
+	RevokeRoleAssignmentTx(ctx context.Context, tx *sqlx.Tx, employeeId, assignmentId int64) (bool, error)
+	EndActiveAssignmentsTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) error
+	SyncLegacyRoleIdTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error
+	UpdateLifecycleTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (Entity, error)
+	SaveStatusHistoryTx(ctx context.Context, tx *sqlx.Tx, history StatusHistoryEntity) error
+	FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryEntity, error)
+	RevokeAllAssignmentsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) ([]int64, error)
+	SaveScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, change ScheduledChangeEntity) (ScheduledChangeEntity, error)
+	FindScheduledChanges(ctx context.Context, filter ScheduledChangeFilter) ([]ScheduledChangeEntity, error)
+	FindScheduledChangeForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (ScheduledChangeEntity, error)
+	UpdateScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, change ScheduledChangeEntity) (ScheduledChangeEntity, error)
+	FindDueScheduledChangeIds(ctx context.Context, limit int) ([]int64, error)
+	LockDueScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, id int64) (ScheduledChangeEntity, error)
+	MarkScheduledChangeFailed(ctx context.Context, id int64, result string) error
+	LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error
+	IsInManagementChainTx(ctx context.Context, tx *sqlx.Tx, employeeId, ancestorId int64) (bool, error)
+	UpdateManagerTx(ctx context.Context, tx *sqlx.Tx, id int64, managerId *int64) (Entity, error)
+	FindDirectReports(ctx context.Context, managerId int64) ([]Entity, error)
+	FindSubordinates(ctx context.Context, id int64) ([]hierarchyEntity, error)
+	FindManagementChain(ctx context.Context, id int64) ([]hierarchyEntity, error)
+	FindOrgChart(ctx context.Context) ([]Entity, error)
+}
+
+// интерфейс журнала аудита: событие пишется в транзакции изменения
+type Auditor interface {
+	Record(ctx context.Context, tx *sqlx.Tx, event audit.Event) error
+}
+
+// интерфейс проверки правил разделения обязанностей (role.Service): роли roleIds,
+// назначенные сотруднику в транзакции tx, не должны конфликтовать с другими его ролями
+type SodChecker interface {
+	CheckSodTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) error
 }
 
 type Validator interface {
@@ -38,9 +99,11 @@ type Validator interface {
 }
 
 // функция-конструктор
-func NewService(repo Repo, validator Validator, logger *common.Logger) *Service {
+func NewService(repo Repo, auditor Auditor, sod SodChecker, validator Validator, logger *common.Logger) *Service {
 	return &Service{
 		repo:      repo,
+		auditor:   auditor,
+		sod:       sod,
 		validator: validator,
 		logger:    logger,
 	}
@@ -49,7 +112,6 @@ func NewService(repo Repo, validator Validator, logger *common.Logger) *Service
 // Метод для создания нового сотрудника
 // принимает на вход CreateRequest - структура запроса на создание сотрудника
 func (svc *Service) CreateEmployee(ctx context.Context, request CreateRequest) (int64, error) {
-	// context.Context нужен для поддержки отмены, дедлайнов и трейсинга запросов к БД.
 	svc.logger.Info("Creating new employee", zap.String("name", request.Name))
 
 	if err := svc.validateCreateRequest(request); err != nil {
@@ -81,6 +143,20 @@ func (svc *Service) CreateEmployee(ctx context.Context, request CreateRequest) (
 		return 0, fmt.Errorf("error create employee: error creating transaction: %w", err)
 	}
 
+	newEmployeeId, err := svc.createTx(ctx, tx, request)
+	if err != nil {
+		return newEmployeeId, err
+	}
+
+	svc.logger.Info("Employee created successfully",
+		zap.String("name", request.Name),
+		zap.Int64("id", newEmployeeId))
+	return newEmployeeId, nil
+}
+
+// создаёт сотрудника в транзакции tx: проверки, сохранение, история состояний, аудит и роли должности.
+// Запрос должен быть уже провалидирован
+func (svc *Service) createTx(ctx context.Context, tx *sqlx.Tx, request CreateRequest) (int64, error) {
 	// в рамках транзакции проверяем наличие в базе данных работника с таким же именем
 	isExist, err := svc.repo.FindByNameTx(ctx, tx, request.Name)
 	if err != nil {
@@ -95,20 +171,59 @@ func (svc *Service) CreateEmployee(ctx context.Context, request CreateRequest) (
 		return 0, common.AlreadyExistsError{Message: fmt.Sprintf("employee with name %s already exists", request.Name)}
 	}
 
+	if err := svc.checkDepartment(ctx, tx, request.DepartmentId); err != nil {
+		return 0, err
+	}
+	if err := svc.checkPosition(ctx, tx, request.PositionId); err != nil {
+		return 0, err
+	}
+	if request.RoleId != 0 {
+		if err := svc.checkAssignableRole(ctx, tx, request.RoleId); err != nil {
+			return 0, err
+		}
+	}
+
 	// в случае отсутствия сотрудника с таким же именем - в рамках этой же транзакции вызываем метод репозитория,
 	// который должен будет создать нового сотрудника
-	newEmployeeId, err := svc.repo.SaveTx(ctx, tx, request.ToEntity())
+	entity := request.ToEntity()
+	newEmployeeId, err := svc.repo.SaveTx(ctx, tx, entity)
 	if err != nil {
 		svc.logger.Error("Failed to save new employee",
 			zap.String("name", request.Name),
 			zap.Error(err))
 		err = fmt.Errorf("error creating employee with name: %s %v", request.Name, err)
-	} else {
-		svc.logger.Info("Employee created successfully",
-			zap.String("name", request.Name),
-			zap.Int64("id", newEmployeeId))
+		return newEmployeeId, err
+	}
+
+	// история состояний начинается с создания сотрудника
+	err = svc.recordTransition(ctx, tx, newEmployeeId, TransitionCreate, "", entity.Status, "", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionCreate,
+		EntityType: audit.EntityEmployee,
+		EntityId:   newEmployeeId,
+		After:      request,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// кроме роли из запроса сотрудник получает роли по умолчанию своей должности
+	granted, err := svc.applyPositionRoles(ctx, tx, newEmployeeId, request.PositionId)
+	if err != nil {
+		return 0, err
+	}
+	if request.RoleId != 0 {
+		granted = append(granted, request.RoleId)
+	}
+	if err := svc.checkSod(ctx, tx, newEmployeeId, granted); err != nil {
+		return 0, err
 	}
-	return newEmployeeId, err
+
+	return newEmployeeId, nil
 }
 
 // валидация запроса на создание сотрудника
@@ -135,6 +250,206 @@ func (svc *Service) validateCreateRequest(request CreateRequest) error {
 	return nil
 }
 
+// точка сохранения строки импорта: ошибка в строке откатывает только изменения этой строки
+const importSavepoint = "employee_import_row"
+
+// Метод для массового создания сотрудников из файла импорта.
+// Все строки обрабатываются в одной транзакции, каждая - под своей точкой сохранения.
+// Транзакция фиксируется, если это не dryRun и нет ошибочных строк либо выбран режим best-effort
+func (svc *Service) Import(ctx context.Context, request ImportRequest) (report ImportReport, err error) {
+	svc.logger.Info("Importing employees",
+		zap.Int("rows", len(request.Rows)),
+		zap.Bool("dry_run", request.DryRun),
+		zap.Bool("best_effort", request.BestEffort))
+
+	if len(request.Rows) == 0 {
+		return ImportReport{}, common.RequestValidationError{Message: "import file contains no rows"}
+	}
+	if len(request.Rows) > maxImportRows {
+		return ImportReport{}, common.RequestValidationError{
+			Message: fmt.Sprintf("import file contains %d rows, at most %d are allowed", len(request.Rows), maxImportRows),
+		}
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for employee import", zap.Error(err))
+		return ImportReport{}, fmt.Errorf("error import employees: error creating transaction: %w", err)
+	}
+	commit := false
+	defer func() {
+		if err == nil && commit {
+			if commitErr := tx.Commit(); commitErr != nil {
+				svc.logger.Error("Failed to commit employee import", zap.Error(commitErr))
+				report, err = ImportReport{}, commitErr
+			}
+			return
+		}
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			svc.logger.Error("Failed to rollback employee import", zap.Error(rollbackErr))
+		}
+	}()
+
+	roleIds, err := svc.findImportRoles(ctx, tx, request.Rows)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	report = ImportReport{
+		DryRun:     request.DryRun,
+		BestEffort: request.BestEffort,
+		Rows:       make([]ImportRowResult, 0, len(request.Rows)),
+	}
+	for i, row := range request.Rows {
+		// отменённый импорт прерывается, а не записывает ошибку в каждую оставшуюся строку
+		if err := ctx.Err(); err != nil {
+			return ImportReport{}, err
+		}
+		result, err := svc.importRow(ctx, tx, row, roleIds)
+		if err != nil {
+			return ImportReport{}, err
+		}
+		report.add(result)
+		if request.Progress != nil {
+			request.Progress(i + 1)
+		}
+	}
+
+	commit = !request.DryRun && (request.BestEffort || report.Failed == 0)
+	report.Committed = commit
+	if !commit {
+		// после отката id созданных сотрудников недействительны
+		for i := range report.Rows {
+			report.Rows[i].Id = 0
+		}
+	}
+
+	svc.logger.Info("Employees import processed",
+		zap.Int("created", report.Created),
+		zap.Int("skipped", report.Skipped),
+		zap.Int("failed", report.Failed),
+		zap.Bool("committed", commit))
+	return report, nil
+}
+
+// находит id ролей, указанных в строках импорта по названию; ключ - название в нижнем регистре
+func (svc *Service) findImportRoles(ctx context.Context, tx *sqlx.Tx, rows []ImportRow) (map[string][]int64, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		name := strings.ToLower(row.Role)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	roleIds := make(map[string][]int64, len(names))
+	if len(names) == 0 {
+		return roleIds, nil
+	}
+	roles, err := svc.repo.FindRoleIdsByNamesTx(ctx, tx, names)
+	if err != nil {
+		svc.logger.Error("Failed to find import roles by names", zap.Error(err))
+		return nil, fmt.Errorf("error finding roles by names: %w", err)
+	}
+	for _, role := range roles {
+		roleIds[role.Name] = append(roleIds[role.Name], role.Id)
+	}
+	return roleIds, nil
+}
+
+// импортирует строку под точкой сохранения. Ошибки строки попадают в результат,
+// ошибка возвращается, только если продолжать импорт нельзя
+func (svc *Service) importRow(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	row ImportRow,
+	roleIds map[string][]int64,
+) (ImportRowResult, error) {
+	result := ImportRowResult{Line: row.Line, Name: row.Request.Name, Email: row.Request.Email}
+	failed := func(message string) (ImportRowResult, error) {
+		result.Status = ImportFailed
+		result.Message = message
+		return result, nil
+	}
+	if row.Error != "" {
+		return failed(row.Error)
+	}
+
+	request := row.Request
+	if row.Role != "" {
+		ids := roleIds[strings.ToLower(row.Role)]
+		switch {
+		case len(ids) == 0:
+			return failed(fmt.Sprintf("role %s does not exist", row.Role))
+		case len(ids) > 1:
+			return failed(fmt.Sprintf("role name %s is ambiguous, use role_id", row.Role))
+		case request.RoleId != 0 && request.RoleId != ids[0]:
+			return failed(fmt.Sprintf("role %s does not match role_id %d", row.Role, request.RoleId))
+		}
+		request.RoleId = ids[0]
+	}
+
+	if err := svc.validator.Validate(request); err != nil {
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			result.Errors = validationErr.Errors
+			return failed("Data validation error")
+		}
+		return failed(err.Error())
+	}
+
+	if err := svc.repo.SavepointTx(ctx, tx, importSavepoint); err != nil {
+		return ImportRowResult{}, fmt.Errorf("error import employees: error creating savepoint: %w", err)
+	}
+	id, err := svc.importRowTx(ctx, tx, request)
+	if err != nil {
+		if rollbackErr := svc.repo.RollbackToSavepointTx(ctx, tx, importSavepoint); rollbackErr != nil {
+			return ImportRowResult{}, fmt.Errorf("error import employees: error rolling back row %d: %w", row.Line, rollbackErr)
+		}
+
+		var existsErr common.AlreadyExistsError
+		var validationErr common.RequestValidationError
+		var conflictErr common.ConflictError
+		switch {
+		case errors.As(err, &existsErr):
+			result.Status = ImportSkipped
+			result.Message = existsErr.Message
+			return result, nil
+		case errors.As(err, &validationErr):
+			return failed(validationErr.Message)
+		case errors.As(err, &conflictErr):
+			// например, роли строки нарушают правило разделения обязанностей
+			return failed(conflictErr.Message)
+		}
+		svc.logger.Error("Failed to import employee",
+			zap.Int("line", row.Line),
+			zap.String("name", request.Name),
+			zap.Error(err))
+		return failed("error creating employee")
+	}
+	if err := svc.repo.ReleaseSavepointTx(ctx, tx, importSavepoint); err != nil {
+		return ImportRowResult{}, fmt.Errorf("error import employees: error releasing savepoint: %w", err)
+	}
+
+	result.Status = ImportCreated
+	result.Id = id
+	return result, nil
+}
+
+// создаёт сотрудника из строки импорта; сотрудник с тем же email считается уже импортированным
+func (svc *Service) importRowTx(ctx context.Context, tx *sqlx.Tx, request CreateRequest) (int64, error) {
+	emailTaken, err := svc.repo.EmailExistsTx(ctx, tx, request.Email)
+	if err != nil {
+		return 0, fmt.Errorf("error finding employee by email: %s, %w", request.Email, err)
+	}
+	if emailTaken {
+		return 0, common.AlreadyExistsError{Message: fmt.Sprintf("employee with email %s already exists", request.Email)}
+	}
+
+	return svc.createTx(ctx, tx, request)
+}
+
 func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
 	svc.logger.Debug("Finding employee by ID", zap.Int64("id", id))
 
@@ -146,8 +461,21 @@ func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
 		return Response{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
 	}
 
+	assignments, err := svc.repo.FindRoleAssignments(ctx, id, true)
+	if err != nil {
+		svc.logger.Error("Failed to find employee role assignments",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding roles of employee with id %d: %w", id, err)
+	}
+
+	response := entity.toResponse()
+	for _, assignment := range assignments {
+		response.Roles = append(response.Roles, assignment.toResponse())
+	}
+
 	svc.logger.Debug("Employee found successfully", zap.Int64("id", id))
-	return entity.toResponse(), nil
+	return response, nil
 }
 
 func (svc *Service) Add(ctx context.Context, employee *Entity) (Response, error) {
@@ -216,6 +544,12 @@ func (svc *Service) AddWithTransaction(ctx context.Context, employee *Entity) (R
 		}
 	}()
 
+	if employee.RoleId != 0 {
+		if err = svc.checkAssignableRole(ctx, tx, employee.RoleId); err != nil {
+			return Response{}, err
+		}
+	}
+
 	err = svc.repo.AddWithTransaction(ctx, tx, employee)
 	if err != nil {
 		svc.logger.Error("Transaction failed while adding employee",
@@ -223,28 +557,145 @@ func (svc *Service) AddWithTransaction(ctx context.Context, employee *Entity) (R
 			zap.Error(err))
 		return Response{}, fmt.Errorf("transaction failed: %w", err)
 	}
+	if employee.RoleId != 0 {
+		if err = svc.checkSod(ctx, tx, employee.Id, []int64{employee.RoleId}); err != nil {
+			return Response{}, err
+		}
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionCreate,
+		EntityType: audit.EntityEmployee,
+		EntityId:   employee.Id,
+		After:      employee.toResponse(),
+	})
+	if err != nil {
+		return Response{}, err
+	}
 
 	svc.logger.Info("Employee added with transaction successfully", zap.String("name", employee.Name))
 	return employee.toResponse(), nil
 }
 
-func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
-	svc.logger.Debug("Finding all employees")
+// Метод для постраничного вывода сотрудников по курсору. Курсор действителен только
+// для той сортировки, с которой он выдан
+func (svc *Service) FindWithCursor(ctx context.Context, request CursorRequest) (CursorPage, error) {
+	svc.logger.Debug("Finding employees with cursor",
+		zap.Int("limit", request.Limit),
+		zap.String("sort", request.Sort))
+
+	if err := svc.validator.Validate(request); err != nil {
+		svc.logger.Error("Validation failed for cursor request", zap.Error(err))
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return CursorPage{}, common.RequestValidationError{
+				Message: "Invalid cursor request",
+				Data:    validationErr.Errors,
+			}
+		}
+		return CursorPage{}, common.RequestValidationError{Message: err.Error()}
+	}
+	column, _, err := parseCursorSort(request.Sort)
+	if err != nil {
+		return CursorPage{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if request.Cursor != nil && request.Cursor.Sort != request.Sort {
+		return CursorPage{}, common.RequestValidationError{Message: "cursor was issued for another sort"}
+	}
 
-	entities, err := svc.repo.FindAll(ctx)
+	// на одну строку больше, чтобы узнать, есть ли следующая страница
+	entities, err := svc.repo.FindWithCursor(ctx, request.Sort, request.Cursor, request.Limit+1)
 	if err != nil {
-		svc.logger.Error("Failed to find all employees", zap.Error(err))
-		return nil, fmt.Errorf("error finding all employees: %w", err)
+		svc.logger.Error("Failed to find employees with cursor", zap.Error(err))
+		return CursorPage{}, fmt.Errorf("error finding employees with cursor: %w", err)
 	}
 
+	entities, next, prev := common.CursorWindow(entities, request.Limit, request.Sort, request.Cursor,
+		func(e Entity) (string, int64) { return column.value(e), e.Id })
+
 	responses := make([]Response, len(entities))
 	for i, entity := range entities {
 		responses[i] = entity.toResponse()
 	}
-	svc.logger.Debug("Found all employees", zap.Int("count", len(responses)))
+	svc.logger.Debug("Found employees with cursor", zap.Int("count", len(responses)))
+	return CursorPage{Data: responses, Next: next, Prev: prev}, nil
+}
+
+// Метод для поиска сотрудников по имени, email, должности и отделу с упорядочиванием по релевантности
+func (svc *Service) Search(ctx context.Context, request SearchRequest) ([]SearchResponse, error) {
+	svc.logger.Debug("Searching employees",
+		zap.String("query", request.Query),
+		zap.Int("limit", request.Limit))
+
+	if err := svc.validator.Validate(request); err != nil {
+		svc.logger.Error("Validation failed for search request", zap.Error(err))
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return nil, common.RequestValidationError{
+				Message: "Invalid search request",
+				Data:    validationErr.Errors,
+			}
+		}
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	if len(searchTerms(request.Query)) == 0 {
+		return nil, common.RequestValidationError{Message: "search query must contain letters or digits"}
+	}
+
+	entities, err := svc.repo.Search(ctx, request.Query, request.Limit)
+	if err != nil {
+		svc.logger.Error("Failed to search employees", zap.Error(err))
+		return nil, fmt.Errorf("error searching employees: %w", err)
+	}
+
+	responses := make([]SearchResponse, len(entities))
+	for i, entity := range entities {
+		responses[i] = entity.toSearchResponse()
+	}
+	svc.logger.Debug("Found employees by search", zap.Int("count", len(responses)))
 	return responses, nil
 }
 
+// Метод для выгрузки сотрудников с фильтрами и сортировкой страницы.
+// Записи читаются из базы по мере прохода итератора; итератор нужно пройти до конца или прервать
+func (svc *Service) Export(ctx context.Context, filter PageRequest) (iter.Seq2[ExportRecord, error], error) {
+	svc.logger.Debug("Exporting employees", zap.String("sort", filter.Sort))
+
+	// выгрузка не делится на страницы, номер и размер страницы не используются
+	filter.PageNumber, filter.PageSize = 1, 1
+	if err := svc.validator.Validate(filter); err != nil {
+		svc.logger.Error("Validation failed for export request", zap.Error(err))
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return nil, common.RequestValidationError{
+				Message: "Invalid export request",
+				Data:    validationErr.Errors,
+			}
+		}
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return nil, common.RequestValidationError{Message: "createdFrom must be before createdTo"}
+	}
+	if _, err := parseSort(filter.Sort); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+
+	entities, err := svc.repo.ExportEmployees(ctx, filter)
+	if err != nil {
+		svc.logger.Error("Failed to export employees", zap.Error(err))
+		return nil, fmt.Errorf("error exporting employees: %w", err)
+	}
+	return func(yield func(ExportRecord, error) bool) {
+		for entity, err := range entities {
+			if err != nil {
+				yield(ExportRecord{}, fmt.Errorf("error exporting employees: %w", err))
+				return
+			}
+			if !yield(entity.toExportRecord(), nil) {
+				return
+			}
+		}
+	}, nil
+}
+
 func (svc *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
 	svc.logger.Debug("Finding employees by IDs", zap.Int64s("ids", ids))
 
@@ -302,9 +753,22 @@ func (svc *Service) FindWithPagination(ctx context.Context, request PageRequest)
 		return PageResponse{}, common.RequestValidationError{Message: "pageSize must be between 1 and 100"}
 	}
 
+	if request.CreatedFrom != nil && request.CreatedTo != nil && !request.CreatedFrom.Before(*request.CreatedTo) {
+		svc.logger.Error("Invalid created_at range",
+			zap.Time("createdFrom", *request.CreatedFrom),
+			zap.Time("createdTo", *request.CreatedTo))
+		return PageResponse{}, common.RequestValidationError{Message: "createdFrom must be before createdTo"}
+	}
+
+	// сортировка только по полям из белого списка
+	if _, err := parseSort(request.Sort); err != nil {
+		svc.logger.Error("Invalid sort", zap.String("sort", request.Sort), zap.Error(err))
+		return PageResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+
 	offset := (request.PageNumber - 1) * request.PageSize
 
-	entities, err := svc.repo.FindWithPagination(ctx, request.PageSize, offset, request.TextFilter)
+	entities, err := svc.repo.FindWithPagination(ctx, request.PageSize, offset, request)
 	if err != nil {
 		svc.logger.Error("Failed to find employees with pagination",
 			zap.Int("pageSize", request.PageSize),
@@ -315,7 +779,7 @@ func (svc *Service) FindWithPagination(ctx context.Context, request PageRequest)
 	}
 
 	// проверка на валидность фильтра
-	totalCount, err := svc.repo.CountWithFilter(ctx, request.TextFilter)
+	totalCount, err := svc.repo.CountWithFilter(ctx, request)
 	if err != nil {
 		svc.logger.Error("Failed to count total employees",
 			zap.String("textFilter", request.TextFilter),
@@ -351,32 +815,1664 @@ func (svc *Service) FindWithPagination(ctx context.Context, request PageRequest)
 	return pageResponse, nil
 }
 
-func (svc *Service) DeleteById(ctx context.Context, id int64) error {
+// Метод для мягкого удаления сотрудника; удаление и запись в журнал выполняются в одной транзакции
+func (svc *Service) DeleteById(ctx context.Context, id int64) (err error) {
 	svc.logger.Info("Deleting employee by ID", zap.Int64("id", id))
 
-	err := svc.repo.DeleteById(ctx, id)
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for employee deletion",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error delete employee: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find employee for deletion",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+
+	if err = svc.repo.DeleteByIdTx(ctx, tx, id); err != nil {
 		svc.logger.Error("Failed to delete employee by ID",
 			zap.Int64("id", id),
 			zap.Error(err))
 		return fmt.Errorf("error deleting employee with id %d: %w", id, err)
 	}
 
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionDelete,
+		EntityType: audit.EntityEmployee,
+		EntityId:   id,
+		Before:     entity.toResponse(),
+	})
+	if err != nil {
+		return err
+	}
+
 	svc.logger.Info("Employee deleted successfully", zap.Int64("id", id))
 	return nil
 }
 
-func (svc *Service) DeleteByIds(ctx context.Context, ids []int64) error {
+// Метод для удаления сотрудников по списку id; в журнал пишется событие на каждого удалённого
+func (svc *Service) DeleteByIds(ctx context.Context, ids []int64) (err error) {
 	svc.logger.Info("Deleting employees by IDs", zap.Int64s("ids", ids))
 
-	err := svc.repo.DeleteByIds(ctx, ids)
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for employees deletion",
+			zap.Int64s("ids", ids),
+			zap.Error(err))
+		return fmt.Errorf("error delete employees: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, 0)
+	}()
+
+	entities, err := svc.repo.FindByIdsForUpdateTx(ctx, tx, ids)
 	if err != nil {
+		svc.logger.Error("Failed to find employees for deletion",
+			zap.Int64s("ids", ids),
+			zap.Error(err))
+		return fmt.Errorf("error finding employees with ids: %w", err)
+	}
+
+	if err = svc.repo.DeleteByIdsTx(ctx, tx, ids); err != nil {
 		svc.logger.Error("Failed to delete employees by IDs",
 			zap.Int64s("ids", ids),
 			zap.Error(err))
 		return fmt.Errorf("error deleting employees with ids: %w", err)
 	}
 
+	for _, entity := range entities {
+		err = svc.auditor.Record(ctx, tx, audit.Event{
+			Action:     audit.ActionDelete,
+			EntityType: audit.EntityEmployee,
+			EntityId:   entity.Id,
+			Before:     entity.toResponse(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	svc.logger.Info("Employees deleted successfully", zap.Int64s("ids", ids))
 	return nil
 }
+
+// Метод для восстановления мягко удалённого сотрудника.
+// Восстановление невозможно, если имя или email уже заняты действующим сотрудником
+func (svc *Service) Restore(ctx context.Context, id int64) (response Response, err error) {
+	svc.logger.Info("Restoring employee", zap.Int64("id", id))
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for employee restore",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error restore employee: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	deleted, err := svc.repo.FindDeletedByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("deleted employee with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find deleted employee",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding deleted employee with id %d: %w", id, err)
+	}
+
+	nameTaken, err := svc.repo.FindByNameTx(ctx, tx, deleted.Name)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee by name: %s, %w", deleted.Name, err)
+	}
+	if nameTaken {
+		return Response{}, common.AlreadyExistsError{
+			Message: fmt.Sprintf("employee with name %s already exists", deleted.Name),
+		}
+	}
+
+	emailTaken, err := svc.repo.EmailExistsTx(ctx, tx, deleted.Email)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee by email: %s, %w", deleted.Email, err)
+	}
+	if emailTaken {
+		return Response{}, common.AlreadyExistsError{
+			Message: fmt.Sprintf("employee with email %s already exists", deleted.Email),
+		}
+	}
+
+	restored, err := svc.repo.RestoreTx(ctx, tx, id)
+	if err != nil {
+		svc.logger.Error("Failed to restore employee",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error restoring employee with id %d: %w", id, err)
+	}
+
+	response = restored.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionRestore,
+		EntityType: audit.EntityEmployee,
+		EntityId:   id,
+		Before:     deleted.toResponse(),
+		After:      response,
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Employee restored successfully", zap.Int64("id", id))
+	return response, nil
+}
+
+// Метод для окончательного удаления сотрудников, мягко удалённых раньше срока хранения.
+// В журнал пишется событие на каждого удалённого
+func (svc *Service) Purge(ctx context.Context, request PurgeRequest) (response PurgeResponse, err error) {
+	svc.logger.Info("Purging deleted employees", zap.Int("retention_days", request.RetentionDays))
+
+	if err := svc.validator.Validate(request); err != nil {
+		svc.logger.Error("Employee purge request validation failed", zap.Error(err))
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return PurgeResponse{}, common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return PurgeResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for employees purge", zap.Error(err))
+		return PurgeResponse{}, fmt.Errorf("error purge employees: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, 0)
+	}()
+
+	deletedBefore := time.Now().AddDate(0, 0, -request.RetentionDays)
+	purged, err := svc.repo.PurgeDeletedTx(ctx, tx, deletedBefore)
+	if err != nil {
+		svc.logger.Error("Failed to purge deleted employees",
+			zap.Time("deleted_before", deletedBefore),
+			zap.Error(err))
+		return PurgeResponse{}, fmt.Errorf("error purging deleted employees: %w", err)
+	}
+
+	response.PurgedIds = make([]int64, 0, len(purged))
+	for _, entity := range purged {
+		err = svc.auditor.Record(ctx, tx, audit.Event{
+			Action:     audit.ActionPurge,
+			EntityType: audit.EntityEmployee,
+			EntityId:   entity.Id,
+			Before:     entity.toResponse(),
+		})
+		if err != nil {
+			return PurgeResponse{}, err
+		}
+		response.PurgedIds = append(response.PurgedIds, entity.Id)
+	}
+
+	svc.logger.Info("Deleted employees purged successfully", zap.Int64s("ids", response.PurgedIds))
+	return response, nil
+}
+
+// Метод для полного обновления сотрудника
+// принимает на вход UpdateRequest, в котором Version - значение updated_at, известное клиенту
+func (svc *Service) UpdateEmployee(ctx context.Context, request UpdateRequest) (Response, error) {
+	svc.logger.Info("Updating employee", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request, request.Version); err != nil {
+		return Response{}, err
+	}
+
+	return svc.update(ctx, request.Id, request.Version, request.applyTo)
+}
+
+// Метод для частичного обновления сотрудника
+// принимает на вход PatchRequest, в котором изменяются только переданные поля
+func (svc *Service) PatchEmployee(ctx context.Context, request PatchRequest) (Response, error) {
+	svc.logger.Info("Patching employee", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request, request.Version); err != nil {
+		return Response{}, err
+	}
+
+	return svc.update(ctx, request.Id, request.Version, request.applyTo)
+}
+
+// валидация запроса на изменение сотрудника
+func (svc *Service) validateRequest(request any, version time.Time) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Employee update request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+
+	if version.IsZero() {
+		return common.RequestValidationError{
+			Message: "employee version is required: pass If-Match header or version field",
+		}
+	}
+
+	return nil
+}
+
+// обновляет сотрудника в транзакции с проверкой версии записи (оптимистичная блокировка)
+func (svc *Service) update(
+	ctx context.Context,
+	id int64,
+	version time.Time,
+	apply func(entity *Entity),
+) (response Response, err error) {
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for employee update",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error update employee: error creating transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				svc.logger.Error("Failed to rollback transaction",
+					zap.Int64("id", id),
+					zap.Error(rollbackErr))
+			}
+		} else {
+			if commitErr := tx.Commit(); commitErr != nil {
+				svc.logger.Error("Failed to commit transaction",
+					zap.Int64("id", id),
+					zap.Error(commitErr))
+				err = commitErr
+			}
+		}
+	}()
+
+	// блокируем строку, чтобы параллельное обновление дождалось окончания текущего
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			svc.logger.Warn("Employee for update not found", zap.Int64("id", id))
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find employee for update",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+
+	if !entity.UpdatedAt.Equal(version) {
+		svc.logger.Warn("Employee version mismatch",
+			zap.Int64("id", id),
+			zap.Time("expected", version),
+			zap.Time("actual", entity.UpdatedAt))
+		return Response{}, versionConflictError(id)
+	}
+
+	before := entity.toResponse()
+	currentName := entity.Name
+	currentRoleId := entity.RoleId
+	currentDepartmentId := entity.DepartmentId
+	currentPositionId := entity.PositionId
+	apply(&entity)
+
+	// имя сотрудника должно оставаться уникальным, как и при создании
+	if entity.Name != currentName {
+		isExist, err := svc.repo.FindByNameTx(ctx, tx, entity.Name)
+		if err != nil {
+			svc.logger.Error("Failed to check if employee exists",
+				zap.String("name", entity.Name),
+				zap.Error(err))
+			return Response{}, fmt.Errorf("error finding employee by name: %s, %w", entity.Name, err)
+		}
+		if isExist {
+			svc.logger.Warn("Employee with this name already exists",
+				zap.String("name", entity.Name))
+			return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf("employee with name %s already exists", entity.Name)}
+		}
+	}
+
+	if entity.DepartmentId != currentDepartmentId {
+		if err = svc.checkDepartment(ctx, tx, entity.DepartmentId); err != nil {
+			return Response{}, err
+		}
+	}
+
+	// роли новой должности назначаются раньше замены role_id, чтобы role_id из запроса
+	// остался последней назначенной ролью. Уволенному сотруднику роли не назначаются
+	var granted []int64
+	if entity.PositionId != currentPositionId {
+		if err = svc.checkPosition(ctx, tx, entity.PositionId); err != nil {
+			return Response{}, err
+		}
+		if entity.Status != StatusTerminated {
+			if granted, err = svc.applyPositionRoles(ctx, tx, id, entity.PositionId); err != nil {
+				return Response{}, err
+			}
+		}
+	}
+
+	// role_id в запросе сохраняет прежний смысл единственной роли: старая роль закрывается, новая назначается
+	if entity.RoleId != currentRoleId {
+		if entity.Status == StatusTerminated {
+			return Response{}, terminatedConflictError(id)
+		}
+		if err = svc.replaceRole(ctx, tx, id, currentRoleId, entity.RoleId); err != nil {
+			return Response{}, err
+		}
+		granted = append(granted, entity.RoleId)
+	}
+	// правила проверяются после всех замен: роль, которую запрос снимает, не считается конфликтующей
+	if err = svc.checkSod(ctx, tx, id, granted); err != nil {
+		return Response{}, err
+	}
+
+	updated, err := svc.repo.UpdateTx(ctx, tx, entity, version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, versionConflictError(id)
+		}
+		svc.logger.Error("Failed to update employee",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error updating employee with id %d: %w", id, err)
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionUpdate,
+		EntityType: audit.EntityEmployee,
+		EntityId:   id,
+		Before:     before,
+		After:      updated.toResponse(),
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Employee updated successfully",
+		zap.Int64("id", id),
+		zap.Time("version", updated.UpdatedAt))
+	return updated.toResponse(), nil
+}
+
+// ошибка устаревшей версии записи сотрудника
+func versionConflictError(id int64) error {
+	return common.ConflictError{
+		Message: fmt.Sprintf("employee with id %d was modified by another request, reload it and retry", id),
+	}
+}
+
+// ошибка назначения роли уволенному сотруднику
+func terminatedConflictError(id int64) error {
+	return common.ConflictError{
+		Message: fmt.Sprintf("employee with id %d is terminated, roles cannot be assigned", id),
+	}
+}
+
+// заменяет действующую роль сотрудника на новую в рамках транзакции обновления
+func (svc *Service) replaceRole(ctx context.Context, tx *sqlx.Tx, employeeId, oldRoleId, newRoleId int64) error {
+	if err := svc.checkAssignableRole(ctx, tx, newRoleId); err != nil {
+		return err
+	}
+
+	if oldRoleId != 0 {
+		if err := svc.repo.EndActiveAssignmentsTx(ctx, tx, employeeId, oldRoleId); err != nil {
+			svc.logger.Error("Failed to end previous role assignment",
+				zap.Int64("id", employeeId),
+				zap.Int64("role_id", oldRoleId),
+				zap.Error(err))
+			return fmt.Errorf("error revoking role %d from employee %d: %w", oldRoleId, employeeId, err)
+		}
+	}
+
+	// новая роль уже может быть назначена отдельным бессрочным назначением
+	overlaps, err := svc.repo.HasOverlappingAssignmentTx(ctx, tx, employeeId, newRoleId, nil, nil)
+	if err != nil {
+		svc.logger.Error("Failed to check role assignment overlap",
+			zap.Int64("id", employeeId),
+			zap.Int64("role_id", newRoleId),
+			zap.Error(err))
+		return fmt.Errorf("error checking role assignments of employee %d: %w", employeeId, err)
+	}
+	if overlaps {
+		return nil
+	}
+
+	if _, err := svc.repo.AssignRoleTx(ctx, tx, employeeId, newRoleId, nil, nil); err != nil {
+		svc.logger.Error("Failed to assign role",
+			zap.Int64("id", employeeId),
+			zap.Int64("role_id", newRoleId),
+			zap.Error(err))
+		return fmt.Errorf("error assigning role %d to employee %d: %w", newRoleId, employeeId, err)
+	}
+	return nil
+}
+
+// Метод для назначения сотруднику роли на период
+func (svc *Service) AssignRole(ctx context.Context, request AssignRoleRequest) (response RoleAssignmentResponse, err error) {
+	svc.logger.Info("Assigning role to employee",
+		zap.Int64("id", request.EmployeeId),
+		zap.Int64("role_id", request.RoleId))
+
+	if err = svc.validateAssignRoleRequest(request); err != nil {
+		return RoleAssignmentResponse{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for role assignment",
+			zap.Int64("id", request.EmployeeId),
+			zap.Error(err))
+		return RoleAssignmentResponse{}, fmt.Errorf("error assign role: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, request.EmployeeId)
+	}()
+
+	// блокируем сотрудника, чтобы параллельные назначения одной роли не пересеклись
+	employee, err := svc.lockEmployee(ctx, tx, request.EmployeeId)
+	if err != nil {
+		return RoleAssignmentResponse{}, err
+	}
+	if employee.Status == StatusTerminated {
+		return RoleAssignmentResponse{}, terminatedConflictError(request.EmployeeId)
+	}
+
+	if err = svc.checkAssignableRole(ctx, tx, request.RoleId); err != nil {
+		return RoleAssignmentResponse{}, err
+	}
+
+	overlaps, err := svc.repo.HasOverlappingAssignmentTx(
+		ctx, tx, request.EmployeeId, request.RoleId, request.ValidFrom, request.ValidTo)
+	if err != nil {
+		svc.logger.Error("Failed to check role assignment overlap",
+			zap.Int64("id", request.EmployeeId),
+			zap.Int64("role_id", request.RoleId),
+			zap.Error(err))
+		return RoleAssignmentResponse{}, fmt.Errorf("error checking role assignments of employee %d: %w", request.EmployeeId, err)
+	}
+	if overlaps {
+		return RoleAssignmentResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("role %d is already assigned to employee %d for an overlapping period",
+				request.RoleId, request.EmployeeId),
+		}
+	}
+
+	assignment, err := svc.repo.AssignRoleTx(
+		ctx, tx, request.EmployeeId, request.RoleId, request.ValidFrom, request.ValidTo)
+	if err != nil {
+		svc.logger.Error("Failed to assign role",
+			zap.Int64("id", request.EmployeeId),
+			zap.Int64("role_id", request.RoleId),
+			zap.Error(err))
+		return RoleAssignmentResponse{}, fmt.Errorf("error assigning role %d to employee %d: %w",
+			request.RoleId, request.EmployeeId, err)
+	}
+	if err = svc.checkSod(ctx, tx, request.EmployeeId, []int64{request.RoleId}); err != nil {
+		return RoleAssignmentResponse{}, err
+	}
+
+	if err = svc.syncLegacyRoleId(ctx, tx, request.EmployeeId); err != nil {
+		return RoleAssignmentResponse{}, err
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionAssignRole,
+		EntityType: audit.EntityEmployee,
+		EntityId:   request.EmployeeId,
+		After:      assignment.toResponse(),
+	})
+	if err != nil {
+		return RoleAssignmentResponse{}, err
+	}
+
+	svc.logger.Info("Role assigned successfully",
+		zap.Int64("id", request.EmployeeId),
+		zap.Int64("role_id", request.RoleId),
+		zap.Int64("assignment_id", assignment.Id))
+	return assignment.toResponse(), nil
+}
+
+// Метод для получения назначений ролей сотрудника; activeOnly = false возвращает и историю
+func (svc *Service) FindRoleAssignments(ctx context.Context, employeeId int64, activeOnly bool) ([]RoleAssignmentResponse, error) {
+	svc.logger.Debug("Finding employee role assignments",
+		zap.Int64("id", employeeId),
+		zap.Bool("active_only", activeOnly))
+
+	if _, err := svc.repo.FindById(ctx, employeeId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", employeeId)}
+		}
+		svc.logger.Error("Failed to find employee by ID",
+			zap.Int64("id", employeeId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+	}
+
+	assignments, err := svc.repo.FindRoleAssignments(ctx, employeeId, activeOnly)
+	if err != nil {
+		svc.logger.Error("Failed to find employee role assignments",
+			zap.Int64("id", employeeId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding roles of employee with id %d: %w", employeeId, err)
+	}
+
+	responses := make([]RoleAssignmentResponse, len(assignments))
+	for i, assignment := range assignments {
+		responses[i] = assignment.toResponse()
+	}
+	return responses, nil
+}
+
+// Метод для отзыва назначения роли у сотрудника
+func (svc *Service) RevokeRoleAssignment(ctx context.Context, employeeId, assignmentId int64) (err error) {
+	svc.logger.Info("Revoking employee role assignment",
+		zap.Int64("id", employeeId),
+		zap.Int64("assignment_id", assignmentId))
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for role revocation",
+			zap.Int64("id", employeeId),
+			zap.Error(err))
+		return fmt.Errorf("error revoke role: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, employeeId)
+	}()
+
+	if _, err = svc.lockEmployee(ctx, tx, employeeId); err != nil {
+		return err
+	}
+
+	revoked, err := svc.repo.RevokeRoleAssignmentTx(ctx, tx, employeeId, assignmentId)
+	if err != nil {
+		svc.logger.Error("Failed to revoke role assignment",
+			zap.Int64("id", employeeId),
+			zap.Int64("assignment_id", assignmentId),
+			zap.Error(err))
+		return fmt.Errorf("error revoking role assignment %d: %w", assignmentId, err)
+	}
+	if !revoked {
+		return common.NotFoundError{
+			Message: fmt.Sprintf("active role assignment %d of employee %d not found", assignmentId, employeeId),
+		}
+	}
+
+	if err = svc.syncLegacyRoleId(ctx, tx, employeeId); err != nil {
+		return err
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionRevokeRole,
+		EntityType: audit.EntityEmployee,
+		EntityId:   employeeId,
+		Before:     map[string]int64{"assignment_id": assignmentId},
+	})
+	if err != nil {
+		return err
+	}
+
+	svc.logger.Info("Role assignment revoked successfully",
+		zap.Int64("id", employeeId),
+		zap.Int64("assignment_id", assignmentId))
+	return nil
+}
+
+// Метод для оформления сотрудника (pending -> active).
+// Даты, не переданные в запросе, берутся из карточки сотрудника, а при их отсутствии - текущая дата
+func (svc *Service) Hire(ctx context.Context, request HireRequest) (Response, error) {
+	svc.logger.Info("Hiring employee", zap.Int64("id", request.EmployeeId))
+
+	if err := svc.validateLifecycleRequest(request); err != nil {
+		return Response{}, err
+	}
+	if request.HireDate != nil && request.StartDate != nil && request.StartDate.Before(*request.HireDate) {
+		return Response{}, common.RequestValidationError{Message: "start_date must not be before hire_date"}
+	}
+
+	return svc.transition(ctx, request.EmployeeId, TransitionHire, request.Reason,
+		func(ctx context.Context, tx *sqlx.Tx, entity *Entity) (any, error) {
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			entity.HireDate = firstDate(request.HireDate, entity.HireDate, &today)
+			entity.StartDate = firstDate(request.StartDate, entity.StartDate, entity.HireDate)
+			return map[string]*time.Time{"hire_date": entity.HireDate, "start_date": entity.StartDate}, nil
+		})
+}
+
+// Метод для приостановки работы сотрудника (active -> suspended).
+// Назначения ролей сохраняются, но не действуют, пока сотрудник приостановлен
+func (svc *Service) Suspend(ctx context.Context, request TransitionRequest) (Response, error) {
+	svc.logger.Info("Suspending employee", zap.Int64("id", request.EmployeeId))
+
+	if err := svc.validateLifecycleRequest(request); err != nil {
+		return Response{}, err
+	}
+	return svc.transition(ctx, request.EmployeeId, TransitionSuspend, request.Reason, nil)
+}
+
+// Метод для возобновления работы сотрудника (suspended -> active)
+func (svc *Service) Resume(ctx context.Context, request TransitionRequest) (Response, error) {
+	svc.logger.Info("Resuming employee", zap.Int64("id", request.EmployeeId))
+
+	if err := svc.validateLifecycleRequest(request); err != nil {
+		return Response{}, err
+	}
+	return svc.transition(ctx, request.EmployeeId, TransitionResume, request.Reason, nil)
+}
+
+// Метод для перевода сотрудника на другую должность или в другой отдел; состояние не меняется
+func (svc *Service) Transfer(ctx context.Context, request TransferRequest) (Response, error) {
+	svc.logger.Info("Transferring employee", zap.Int64("id", request.EmployeeId))
+
+	if err := svc.validateLifecycleRequest(request); err != nil {
+		return Response{}, err
+	}
+	if request.PositionId == nil && request.DepartmentId == nil {
+		return Response{}, common.RequestValidationError{Message: "position_id or department_id is required"}
+	}
+
+	return svc.transition(ctx, request.EmployeeId, TransitionTransfer, request.Reason,
+		func(ctx context.Context, tx *sqlx.Tx, entity *Entity) (any, error) {
+			changes := map[string]any{}
+			if request.PositionId != nil && *request.PositionId != entity.PositionId {
+				if err := svc.checkPosition(ctx, tx, *request.PositionId); err != nil {
+					return nil, err
+				}
+				granted, err := svc.applyPositionRoles(ctx, tx, entity.Id, *request.PositionId)
+				if err != nil {
+					return nil, err
+				}
+				if err := svc.checkSod(ctx, tx, entity.Id, granted); err != nil {
+					return nil, err
+				}
+				changes["position_id"] = map[string]int64{"from": entity.PositionId, "to": *request.PositionId}
+				entity.PositionId = *request.PositionId
+			}
+			if request.DepartmentId != nil {
+				if err := svc.checkDepartment(ctx, tx, *request.DepartmentId); err != nil {
+					return nil, err
+				}
+				changes["department_id"] = map[string]int64{"from": entity.DepartmentId, "to": *request.DepartmentId}
+				entity.DepartmentId = *request.DepartmentId
+			}
+			return changes, nil
+		})
+}
+
+// Метод для увольнения сотрудника: все его назначения ролей отзываются в той же транзакции.
+// Из состояния terminated переходов нет
+func (svc *Service) Terminate(ctx context.Context, request TransitionRequest) (Response, error) {
+	svc.logger.Info("Terminating employee", zap.Int64("id", request.EmployeeId))
+
+	if err := svc.validateLifecycleRequest(request); err != nil {
+		return Response{}, err
+	}
+
+	return svc.transition(ctx, request.EmployeeId, TransitionTerminate, request.Reason,
+		func(ctx context.Context, tx *sqlx.Tx, entity *Entity) (any, error) {
+			revoked, err := svc.repo.RevokeAllAssignmentsTx(ctx, tx, entity.Id)
+			if err != nil {
+				svc.logger.Error("Failed to revoke role assignments of terminated employee",
+					zap.Int64("id", entity.Id),
+					zap.Error(err))
+				return nil, fmt.Errorf("error revoking roles of employee %d: %w", entity.Id, err)
+			}
+			if err = svc.syncLegacyRoleId(ctx, tx, entity.Id); err != nil {
+				return nil, err
+			}
+
+			for _, assignmentId := range revoked {
+				err = svc.auditor.Record(ctx, tx, audit.Event{
+					Action:     audit.ActionRevokeRole,
+					EntityType: audit.EntityEmployee,
+					EntityId:   entity.Id,
+					Before:     map[string]int64{"assignment_id": assignmentId},
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			return map[string][]int64{"revoked_assignment_ids": revoked}, nil
+		})
+}
+
+// Метод для получения истории переходов сотрудника между состояниями
+func (svc *Service) FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryResponse, error) {
+	svc.logger.Debug("Finding employee status history", zap.Int64("id", employeeId))
+
+	if _, err := svc.repo.FindById(ctx, employeeId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", employeeId)}
+		}
+		svc.logger.Error("Failed to find employee by ID",
+			zap.Int64("id", employeeId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+	}
+
+	history, err := svc.repo.FindStatusHistory(ctx, employeeId)
+	if err != nil {
+		svc.logger.Error("Failed to find employee status history",
+			zap.Int64("id", employeeId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding status history of employee with id %d: %w", employeeId, err)
+	}
+
+	responses := make([]StatusHistoryResponse, len(history))
+	for i, entry := range history {
+		responses[i] = entry.toResponse()
+	}
+	return responses, nil
+}
+
+// дополнительные изменения, выполняемые при переходе; возвращают подробности для истории
+type transitionEffect func(ctx context.Context, tx *sqlx.Tx, entity *Entity) (any, error)
+
+// выполняет переход сотрудника в одной транзакции: блокировка, проверка допустимости перехода,
+// дополнительные изменения, сохранение, запись в историю состояний и в журнал аудита
+func (svc *Service) transition(
+	ctx context.Context,
+	id int64,
+	name string,
+	reason string,
+	effect transitionEffect,
+) (response Response, err error) {
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for employee transition",
+			zap.Int64("id", id),
+			zap.String("transition", name),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error %s employee: error creating transaction: %w", name, err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	entity, err := svc.lockEmployee(ctx, tx, id)
+	if err != nil {
+		return Response{}, err
+	}
+
+	fromStatus := entity.Status
+	toStatus, err := nextStatus(name, fromStatus)
+	if err != nil {
+		svc.logger.Warn("Employee transition is not allowed",
+			zap.Int64("id", id),
+			zap.String("transition", name),
+			zap.String("status", fromStatus))
+		return Response{}, err
+	}
+
+	before := entity.toResponse()
+	entity.Status = toStatus
+
+	var details any
+	if effect != nil {
+		if details, err = effect(ctx, tx, &entity); err != nil {
+			return Response{}, err
+		}
+	}
+
+	updated, err := svc.repo.UpdateLifecycleTx(ctx, tx, entity)
+	if err != nil {
+		svc.logger.Error("Failed to update employee status",
+			zap.Int64("id", id),
+			zap.String("transition", name),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error updating status of employee with id %d: %w", id, err)
+	}
+
+	if err = svc.recordTransition(ctx, tx, id, name, fromStatus, toStatus, reason, details); err != nil {
+		return Response{}, err
+	}
+
+	response = updated.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionTransition,
+		EntityType: audit.EntityEmployee,
+		EntityId:   id,
+		Before:     before,
+		After:      response,
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Employee transition completed",
+		zap.Int64("id", id),
+		zap.String("transition", name),
+		zap.String("from", fromStatus),
+		zap.String("to", toStatus))
+	return response, nil
+}
+
+// записывает переход в историю состояний; инициатор берётся из контекста запроса
+func (svc *Service) recordTransition(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	id int64,
+	name, fromStatus, toStatus, reason string,
+	details any,
+) error {
+	actor := common.ActorFromContext(ctx)
+	history := StatusHistoryEntity{
+		EmployeeId:    id,
+		Transition:    name,
+		FromStatus:    nullable(fromStatus),
+		ToStatus:      toStatus,
+		Reason:        nullable(reason),
+		ActorSub:      nullable(actor.Subject),
+		ActorUsername: nullable(actor.Username),
+	}
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			return fmt.Errorf("error recording transition of employee %d: %w", id, err)
+		}
+		history.Details = nullable(string(data))
+	}
+
+	if err := svc.repo.SaveStatusHistoryTx(ctx, tx, history); err != nil {
+		svc.logger.Error("Failed to save employee status history",
+			zap.Int64("id", id),
+			zap.String("transition", name),
+			zap.Error(err))
+		return fmt.Errorf("error recording transition of employee %d: %w", id, err)
+	}
+	return nil
+}
+
+// валидация запроса на смену состояния сотрудника
+func (svc *Service) validateLifecycleRequest(request any) error {
+	if err := svc.validator.Validate(request); err != nil {
+		svc.logger.Error("Employee lifecycle request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return nil
+}
+
+// возвращает первую заданную дату
+func firstDate(dates ...*time.Time) *time.Time {
+	for _, date := range dates {
+		if date != nil {
+			return date
+		}
+	}
+	return nil
+}
+
+func nullable(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// Метод для назначения сотруднику руководителя. Руководитель не может быть подчинённым сотрудника
+// (прямо или косвенно), иначе линия подчинения образует цикл
+func (svc *Service) SetManager(ctx context.Context, request SetManagerRequest) (response Response, err error) {
+	svc.logger.Info("Setting employee manager",
+		zap.Int64("id", request.EmployeeId),
+		zap.Int64("manager_id", request.ManagerId))
+
+	if err = svc.validateLifecycleRequest(request); err != nil {
+		return Response{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for setting manager",
+			zap.Int64("id", request.EmployeeId),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error set manager: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, request.EmployeeId)
+	}()
+
+	if err = svc.repo.LockHierarchyTx(ctx, tx); err != nil {
+		svc.logger.Error("Failed to lock employee hierarchy", zap.Error(err))
+		return Response{}, fmt.Errorf("error locking employee hierarchy: %w", err)
+	}
+
+	entity, err := svc.lockEmployee(ctx, tx, request.EmployeeId)
+	if err != nil {
+		return Response{}, err
+	}
+
+	var managerId *int64
+	if request.ManagerId != 0 {
+		manager, err := svc.repo.FindByIdForUpdateTx(ctx, tx, request.ManagerId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return Response{}, common.RequestValidationError{
+					Message: fmt.Sprintf("manager with id %d does not exist", request.ManagerId),
+				}
+			}
+			return Response{}, fmt.Errorf("error finding employee with id %d: %w", request.ManagerId, err)
+		}
+		if manager.Status == StatusTerminated {
+			return Response{}, common.ConflictError{
+				Message: fmt.Sprintf("employee with id %d is terminated and cannot be a manager", request.ManagerId),
+			}
+		}
+
+		inChain, err := svc.repo.IsInManagementChainTx(ctx, tx, request.ManagerId, request.EmployeeId)
+		if err != nil {
+			svc.logger.Error("Failed to check management chain",
+				zap.Int64("id", request.EmployeeId),
+				zap.Int64("manager_id", request.ManagerId),
+				zap.Error(err))
+			return Response{}, fmt.Errorf("error checking management chain of employee %d: %w", request.ManagerId, err)
+		}
+		if inChain {
+			return Response{}, common.ConflictError{
+				Message: fmt.Sprintf("employee %d reports to employee %d, reporting line would form a cycle",
+					request.ManagerId, request.EmployeeId),
+			}
+		}
+		managerId = &request.ManagerId
+	}
+
+	updated, err := svc.repo.UpdateManagerTx(ctx, tx, request.EmployeeId, managerId)
+	if err != nil {
+		svc.logger.Error("Failed to update employee manager",
+			zap.Int64("id", request.EmployeeId),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error updating manager of employee %d: %w", request.EmployeeId, err)
+	}
+
+	response = updated.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionUpdate,
+		EntityType: audit.EntityEmployee,
+		EntityId:   request.EmployeeId,
+		Before:     entity.toResponse(),
+		After:      response,
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Employee manager set successfully",
+		zap.Int64("id", request.EmployeeId),
+		zap.Int64("manager_id", request.ManagerId))
+	return response, nil
+}
+
+// Метод для получения непосредственных подчинённых сотрудника
+func (svc *Service) FindDirectReports(ctx context.Context, id int64) ([]Response, error) {
+	svc.logger.Debug("Finding employee direct reports", zap.Int64("id", id))
+
+	if err := svc.ensureEmployeeExists(ctx, id); err != nil {
+		return nil, err
+	}
+
+	entities, err := svc.repo.FindDirectReports(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to find direct reports",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding direct reports of employee %d: %w", id, err)
+	}
+
+	responses := make([]Response, len(entities))
+	for i, entity := range entities {
+		responses[i] = entity.toResponse()
+	}
+	return responses, nil
+}
+
+// Метод для получения всех подчинённых сотрудника (прямых и косвенных)
+func (svc *Service) FindSubordinates(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	svc.logger.Debug("Finding employee subordinates", zap.Int64("id", id))
+
+	if err := svc.ensureEmployeeExists(ctx, id); err != nil {
+		return nil, err
+	}
+
+	entities, err := svc.repo.FindSubordinates(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to find subordinates",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding subordinates of employee %d: %w", id, err)
+	}
+	return toHierarchyResponses(entities), nil
+}
+
+// Метод для получения цепочки руководителей сотрудника (от непосредственного руководителя к верху)
+func (svc *Service) FindManagementChain(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	svc.logger.Debug("Finding employee management chain", zap.Int64("id", id))
+
+	if err := svc.ensureEmployeeExists(ctx, id); err != nil {
+		return nil, err
+	}
+
+	entities, err := svc.repo.FindManagementChain(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to find management chain",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding management chain of employee %d: %w", id, err)
+	}
+	return toHierarchyResponses(entities), nil
+}
+
+// Метод для построения оргструктуры по линиям подчинения
+func (svc *Service) FindOrgChart(ctx context.Context) ([]OrgChartNode, error) {
+	svc.logger.Debug("Building org chart")
+
+	entities, err := svc.repo.FindOrgChart(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to fetch employees for org chart", zap.Error(err))
+		return nil, fmt.Errorf("error building org chart: %w", err)
+	}
+
+	chart := buildOrgChart(entities)
+	svc.logger.Debug("Org chart built successfully",
+		zap.Int("employees_count", len(entities)),
+		zap.Int("roots_count", len(chart)))
+	return chart, nil
+}
+
+// проверяет существование сотрудника
+func (svc *Service) ensureEmployeeExists(ctx context.Context, id int64) error {
+	if _, err := svc.repo.FindById(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find employee by ID",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	return nil
+}
+
+// количество изменений, применяемых за один проход планировщика
+const dueChangesBatchSize = 100
+
+// Метод для создания отложенного изменения сотрудника (перевод или смена роли в будущем)
+func (svc *Service) ScheduleChange(ctx context.Context, request ScheduledChangeRequest) (response ScheduledChangeResponse, err error) {
+	svc.logger.Info("Scheduling employee change",
+		zap.Int64("id", request.EmployeeId),
+		zap.Time("effective_at", request.EffectiveAt))
+
+	if err = svc.validateScheduledChangeRequest(request); err != nil {
+		return ScheduledChangeResponse{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for scheduling employee change",
+			zap.Int64("id", request.EmployeeId),
+			zap.Error(err))
+		return ScheduledChangeResponse{}, fmt.Errorf("error schedule change: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, request.EmployeeId)
+	}()
+
+	employee, err := svc.lockEmployee(ctx, tx, request.EmployeeId)
+	if err != nil {
+		return ScheduledChangeResponse{}, err
+	}
+	if employee.Status == StatusTerminated {
+		return ScheduledChangeResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("employee with id %d is terminated, changes cannot be scheduled", request.EmployeeId),
+		}
+	}
+	if err = svc.checkScheduledRole(ctx, tx, request.RoleId); err != nil {
+		return ScheduledChangeResponse{}, err
+	}
+	if request.DepartmentId != nil {
+		if err = svc.checkDepartment(ctx, tx, *request.DepartmentId); err != nil {
+			return ScheduledChangeResponse{}, err
+		}
+	}
+	if request.PositionId != nil {
+		if err = svc.checkPosition(ctx, tx, *request.PositionId); err != nil {
+			return ScheduledChangeResponse{}, err
+		}
+	}
+
+	actor := common.ActorFromContext(ctx)
+	change := ScheduledChangeEntity{
+		EmployeeId:        request.EmployeeId,
+		CreatedBySub:      nullable(actor.Subject),
+		CreatedByUsername: nullable(actor.Username),
+	}
+	request.applyTo(&change)
+
+	created, err := svc.repo.SaveScheduledChangeTx(ctx, tx, change)
+	if err != nil {
+		svc.logger.Error("Failed to save scheduled change",
+			zap.Int64("id", request.EmployeeId),
+			zap.Error(err))
+		return ScheduledChangeResponse{}, fmt.Errorf("error scheduling change of employee %d: %w", request.EmployeeId, err)
+	}
+
+	svc.logger.Info("Employee change scheduled successfully",
+		zap.Int64("id", request.EmployeeId),
+		zap.Int64("change_id", created.Id))
+	return created.toResponse(), nil
+}
+
+// Метод для получения отложенных изменений по фильтру
+func (svc *Service) FindScheduledChanges(ctx context.Context, filter ScheduledChangeFilter) ([]ScheduledChangeResponse, error) {
+	svc.logger.Debug("Finding scheduled employee changes",
+		zap.Int64("id", filter.EmployeeId),
+		zap.String("status", filter.Status))
+
+	if err := svc.validator.Validate(filter); err != nil {
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return nil, common.RequestValidationError{
+				Message: "Invalid scheduled changes filter",
+				Data:    validationErr.Errors,
+			}
+		}
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+
+	changes, err := svc.repo.FindScheduledChanges(ctx, filter)
+	if err != nil {
+		svc.logger.Error("Failed to find scheduled changes", zap.Error(err))
+		return nil, fmt.Errorf("error finding scheduled changes: %w", err)
+	}
+
+	responses := make([]ScheduledChangeResponse, len(changes))
+	for i, change := range changes {
+		responses[i] = change.toResponse()
+	}
+	return responses, nil
+}
+
+// Метод для изменения ещё не применённого отложенного изменения
+func (svc *Service) UpdateScheduledChange(ctx context.Context, request ScheduledChangeRequest) (ScheduledChangeResponse, error) {
+	svc.logger.Info("Updating scheduled employee change", zap.Int64("change_id", request.Id))
+
+	if err := svc.validateScheduledChangeRequest(request); err != nil {
+		return ScheduledChangeResponse{}, err
+	}
+
+	return svc.changePendingChange(ctx, request.Id, func(ctx context.Context, tx *sqlx.Tx, change *ScheduledChangeEntity) error {
+		if err := svc.checkScheduledRole(ctx, tx, request.RoleId); err != nil {
+			return err
+		}
+		if request.DepartmentId != nil {
+			if err := svc.checkDepartment(ctx, tx, *request.DepartmentId); err != nil {
+				return err
+			}
+		}
+		if request.PositionId != nil {
+			if err := svc.checkPosition(ctx, tx, *request.PositionId); err != nil {
+				return err
+			}
+		}
+		request.applyTo(change)
+		return nil
+	})
+}
+
+// Метод для отмены ещё не применённого отложенного изменения
+func (svc *Service) CancelScheduledChange(ctx context.Context, id int64) (ScheduledChangeResponse, error) {
+	svc.logger.Info("Cancelling scheduled employee change", zap.Int64("change_id", id))
+
+	return svc.changePendingChange(ctx, id, func(ctx context.Context, tx *sqlx.Tx, change *ScheduledChangeEntity) error {
+		change.Status = ChangeStatusCancelled
+		return nil
+	})
+}
+
+// изменяет отложенное изменение в транзакции; применённые, отменённые и неудачные изменения не редактируются
+func (svc *Service) changePendingChange(
+	ctx context.Context,
+	id int64,
+	apply func(ctx context.Context, tx *sqlx.Tx, change *ScheduledChangeEntity) error,
+) (response ScheduledChangeResponse, err error) {
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for scheduled change update",
+			zap.Int64("change_id", id),
+			zap.Error(err))
+		return ScheduledChangeResponse{}, fmt.Errorf("error update scheduled change: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	change, err := svc.repo.FindScheduledChangeForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ScheduledChangeResponse{}, common.NotFoundError{Message: fmt.Sprintf("scheduled change with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find scheduled change",
+			zap.Int64("change_id", id),
+			zap.Error(err))
+		return ScheduledChangeResponse{}, fmt.Errorf("error finding scheduled change with id %d: %w", id, err)
+	}
+	if change.Status != ChangeStatusPending {
+		return ScheduledChangeResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("scheduled change with id %d is %s and cannot be modified", id, change.Status),
+		}
+	}
+
+	if err = apply(ctx, tx, &change); err != nil {
+		return ScheduledChangeResponse{}, err
+	}
+
+	updated, err := svc.repo.UpdateScheduledChangeTx(ctx, tx, change)
+	if err != nil {
+		svc.logger.Error("Failed to update scheduled change",
+			zap.Int64("change_id", id),
+			zap.Error(err))
+		return ScheduledChangeResponse{}, fmt.Errorf("error updating scheduled change with id %d: %w", id, err)
+	}
+
+	svc.logger.Info("Scheduled employee change updated successfully",
+		zap.Int64("change_id", id),
+		zap.String("status", updated.Status))
+	return updated.toResponse(), nil
+}
+
+// ApplyDueChanges применяет наступившие отложенные изменения; вызывается планировщиком.
+// Каждое изменение применяется в отдельной транзакции, возвращается количество применённых
+func (svc *Service) ApplyDueChanges(ctx context.Context) (int, error) {
+	ids, err := svc.repo.FindDueScheduledChangeIds(ctx, dueChangesBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("error finding due scheduled changes: %w", err)
+	}
+
+	applied := 0
+	var errs []error
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		ok, err := svc.applyScheduledChange(ctx, id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			applied++
+		}
+	}
+	return applied, errors.Join(errs...)
+}
+
+// применяет одно отложенное изменение. Если изменение неприменимо (сотрудник уволен или удалён,
+// роль удалена), оно помечается как failed с описанием причины и больше не повторяется
+func (svc *Service) applyScheduledChange(ctx context.Context, id int64) (bool, error) {
+	applied, err := svc.applyScheduledChangeTx(ctx, id)
+	if err == nil {
+		return applied, nil
+	}
+
+	if !isBusinessError(err) {
+		svc.logger.Error("Failed to apply scheduled change",
+			zap.Int64("change_id", id),
+			zap.Error(err))
+		return false, err
+	}
+
+	svc.logger.Warn("Scheduled change cannot be applied",
+		zap.Int64("change_id", id),
+		zap.Error(err))
+	if markErr := svc.repo.MarkScheduledChangeFailed(ctx, id, err.Error()); markErr != nil {
+		return false, fmt.Errorf("error marking scheduled change %d as failed: %w", id, markErr)
+	}
+	return false, nil
+}
+
+func (svc *Service) applyScheduledChangeTx(ctx context.Context, id int64) (applied bool, err error) {
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error apply scheduled change: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	change, err := svc.repo.LockDueScheduledChangeTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// изменение уже применено другим экземпляром или отменено
+			return false, nil
+		}
+		return false, fmt.Errorf("error locking scheduled change with id %d: %w", id, err)
+	}
+
+	// изменение выполняется от имени того, кто его запланировал
+	ctx = common.WithActor(ctx, common.Actor{
+		Subject:  valueOf(change.CreatedBySub),
+		Username: valueOf(change.CreatedByUsername),
+	})
+
+	entity, err := svc.lockEmployee(ctx, tx, change.EmployeeId)
+	if err != nil {
+		return false, err
+	}
+	if entity.Status == StatusTerminated {
+		return false, common.ConflictError{
+			Message: fmt.Sprintf("employee with id %d is terminated", change.EmployeeId),
+		}
+	}
+
+	before := entity.toResponse()
+	changes := map[string]any{}
+	var granted []int64
+	// должность, удалённая после планирования, обнуляет position_id изменения (ON DELETE SET NULL)
+	if change.PositionId != nil && *change.PositionId != entity.PositionId {
+		changes["position_id"] = map[string]int64{"from": entity.PositionId, "to": *change.PositionId}
+		if granted, err = svc.applyPositionRoles(ctx, tx, entity.Id, *change.PositionId); err != nil {
+			return false, err
+		}
+		entity.PositionId = *change.PositionId
+	}
+	if change.DepartmentId != nil && *change.DepartmentId != entity.DepartmentId {
+		changes["department_id"] = map[string]int64{"from": entity.DepartmentId, "to": *change.DepartmentId}
+		entity.DepartmentId = *change.DepartmentId
+	}
+	if change.RoleId != nil && *change.RoleId != entity.RoleId {
+		changes["role_id"] = map[string]int64{"from": entity.RoleId, "to": *change.RoleId}
+		if err = svc.replaceRole(ctx, tx, entity.Id, entity.RoleId, *change.RoleId); err != nil {
+			return false, err
+		}
+		if err = svc.syncLegacyRoleId(ctx, tx, entity.Id); err != nil {
+			return false, err
+		}
+		granted = append(granted, *change.RoleId)
+	}
+	// нарушение правила - бизнес-ошибка: изменение помечается неудавшимся
+	if err = svc.checkSod(ctx, tx, entity.Id, granted); err != nil {
+		return false, err
+	}
+
+	updated, err := svc.repo.UpdateLifecycleTx(ctx, tx, entity)
+	if err != nil {
+		return false, fmt.Errorf("error updating employee with id %d: %w", entity.Id, err)
+	}
+
+	err = svc.recordTransition(ctx, tx, entity.Id, TransitionTransfer, entity.Status, entity.Status,
+		valueOf(change.Reason), map[string]any{"scheduled_change_id": change.Id, "changes": changes})
+	if err != nil {
+		return false, err
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionUpdate,
+		EntityType: audit.EntityEmployee,
+		EntityId:   entity.Id,
+		Before:     before,
+		After:      updated.toResponse(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	change.Status = ChangeStatusApplied
+	change.AppliedAt = &now
+	if _, err = svc.repo.UpdateScheduledChangeTx(ctx, tx, change); err != nil {
+		return false, fmt.Errorf("error updating scheduled change with id %d: %w", id, err)
+	}
+
+	svc.logger.Info("Scheduled change applied",
+		zap.Int64("change_id", id),
+		zap.Int64("id", entity.Id))
+	return true, nil
+}
+
+// валидация запроса на отложенное изменение
+func (svc *Service) validateScheduledChangeRequest(request ScheduledChangeRequest) error {
+	if err := svc.validateLifecycleRequest(request); err != nil {
+		return err
+	}
+	if request.PositionId == nil && request.DepartmentId == nil && request.RoleId == nil {
+		return common.RequestValidationError{Message: "position_id, department_id or role_id is required"}
+	}
+	if !request.EffectiveAt.After(time.Now()) {
+		return common.RequestValidationError{Message: "effective_at must be in the future"}
+	}
+	return nil
+}
+
+// проверяет роль, назначаемую отложенным изменением; при применении изменения роль проверяется ещё раз
+func (svc *Service) checkScheduledRole(ctx context.Context, tx *sqlx.Tx, roleId *int64) error {
+	if roleId == nil {
+		return nil
+	}
+	return svc.checkAssignableRole(ctx, tx, *roleId)
+}
+
+// проверяет, что роль можно назначить: она существует и активна
+func (svc *Service) checkAssignableRole(ctx context.Context, tx *sqlx.Tx, roleId int64) error {
+	status, err := svc.repo.FindRoleStatusTx(ctx, tx, roleId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return common.RequestValidationError{Message: fmt.Sprintf("role with id %d does not exist", roleId)}
+		}
+		svc.logger.Error("Failed to check role existence",
+			zap.Int64("role_id", roleId),
+			zap.Error(err))
+		return fmt.Errorf("error finding role with id %d: %w", roleId, err)
+	}
+	if !status {
+		return common.RequestValidationError{Message: fmt.Sprintf("role with id %d is inactive and cannot be assigned", roleId)}
+	}
+	return nil
+}
+
+// проверяет существование отдела, в который переводится сотрудник
+func (svc *Service) checkDepartment(ctx context.Context, tx *sqlx.Tx, departmentId int64) error {
+	isExist, err := svc.repo.DepartmentExistsTx(ctx, tx, departmentId)
+	if err != nil {
+		svc.logger.Error("Failed to check department existence",
+			zap.Int64("department_id", departmentId),
+			zap.Error(err))
+		return fmt.Errorf("error finding department with id %d: %w", departmentId, err)
+	}
+	if !isExist {
+		return common.RequestValidationError{Message: fmt.Sprintf("department with id %d does not exist", departmentId)}
+	}
+	return nil
+}
+
+// проверяет существование должности, на которую принимается или переводится сотрудник
+func (svc *Service) checkPosition(ctx context.Context, tx *sqlx.Tx, positionId int64) error {
+	isExist, err := svc.repo.PositionExistsTx(ctx, tx, positionId)
+	if err != nil {
+		svc.logger.Error("Failed to check position existence",
+			zap.Int64("position_id", positionId),
+			zap.Error(err))
+		return fmt.Errorf("error finding position with id %d: %w", positionId, err)
+	}
+	if !isExist {
+		return common.RequestValidationError{Message: fmt.Sprintf("position with id %d does not exist", positionId)}
+	}
+	return nil
+}
+
+// назначает сотруднику бессрочно роли по умолчанию должности, которых у него ещё нет,
+// и возвращает id назначенных ролей для проверки правил разделения обязанностей.
+// Роли прежней должности не отзываются: доступ, выданный отдельно, не должен пропасть при переводе
+func (svc *Service) applyPositionRoles(ctx context.Context, tx *sqlx.Tx, employeeId, positionId int64) ([]int64, error) {
+	roleIds, err := svc.repo.FindPositionRoleIdsTx(ctx, tx, positionId)
+	if err != nil {
+		svc.logger.Error("Failed to find position default roles",
+			zap.Int64("position_id", positionId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding default roles of position %d: %w", positionId, err)
+	}
+
+	var assigned []int64
+	for _, roleId := range roleIds {
+		overlaps, err := svc.repo.HasOverlappingAssignmentTx(ctx, tx, employeeId, roleId, nil, nil)
+		if err != nil {
+			svc.logger.Error("Failed to check role assignment overlap",
+				zap.Int64("id", employeeId),
+				zap.Int64("role_id", roleId),
+				zap.Error(err))
+			return nil, fmt.Errorf("error checking role assignments of employee %d: %w", employeeId, err)
+		}
+		if overlaps {
+			continue
+		}
+
+		assignment, err := svc.repo.AssignRoleTx(ctx, tx, employeeId, roleId, nil, nil)
+		if err != nil {
+			svc.logger.Error("Failed to assign position default role",
+				zap.Int64("id", employeeId),
+				zap.Int64("role_id", roleId),
+				zap.Error(err))
+			return nil, fmt.Errorf("error assigning role %d to employee %d: %w", roleId, employeeId, err)
+		}
+		err = svc.auditor.Record(ctx, tx, audit.Event{
+			Action:     audit.ActionAssignRole,
+			EntityType: audit.EntityEmployee,
+			EntityId:   employeeId,
+			After:      assignment.toResponse(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		assigned = append(assigned, roleId)
+	}
+
+	if len(assigned) == 0 {
+		return nil, nil
+	}
+	svc.logger.Info("Position default roles assigned",
+		zap.Int64("id", employeeId),
+		zap.Int64("position_id", positionId),
+		zap.Int64s("role_ids", assigned))
+	if err := svc.syncLegacyRoleId(ctx, tx, employeeId); err != nil {
+		return nil, err
+	}
+	return assigned, nil
+}
+
+// проверяет правила разделения обязанностей для ролей, назначенных сотруднику в транзакции tx
+func (svc *Service) checkSod(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) error {
+	if len(roleIds) == 0 {
+		return nil
+	}
+	return svc.sod.CheckSodTx(ctx, tx, employeeId, roleIds)
+}
+
+// ошибки, означающие, что операция неприменима по бизнес-правилам (повтор не поможет)
+func isBusinessError(err error) bool {
+	return errors.As(err, &common.NotFoundError{}) ||
+		errors.As(err, &common.ConflictError{}) ||
+		errors.As(err, &common.RequestValidationError{})
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// валидация запроса на назначение роли
+func (svc *Service) validateAssignRoleRequest(request AssignRoleRequest) error {
+	if err := svc.validator.Validate(request); err != nil {
+		svc.logger.Error("Role assignment request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+
+	validFrom := time.Now()
+	if request.ValidFrom != nil {
+		validFrom = *request.ValidFrom
+	}
+	if request.ValidTo != nil && !request.ValidTo.After(validFrom) {
+		return common.RequestValidationError{Message: "valid_to must be after valid_from"}
+	}
+	return nil
+}
+
+// блокирует строку сотрудника до конца транзакции
+func (svc *Service) lockEmployee(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Entity{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to lock employee",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Entity{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	return entity, nil
+}
+
+// поддерживает колонку employee.role_id в соответствии с назначениями
+func (svc *Service) syncLegacyRoleId(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	if err := svc.repo.SyncLegacyRoleIdTx(ctx, tx, id); err != nil {
+		svc.logger.Error("Failed to sync employee role_id",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error syncing role_id of employee %d: %w", id, err)
+	}
+	return nil
+}
+
+// завершает транзакцию: откатывает при ошибке, иначе фиксирует.
+// Возвращает исходную ошибку или ошибку фиксации
+func (svc *Service) finishTransaction(tx *sqlx.Tx, err error, id int64) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			svc.logger.Error("Failed to rollback transaction",
+				zap.Int64("id", id),
+				zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		svc.logger.Error("Failed to commit transaction",
+			zap.Int64("id", id),
+			zap.Error(commitErr))
+		return commitErr
+	}
+	return nil
+}
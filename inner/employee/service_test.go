@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"idm/inner/audit"
 	"idm/inner/common"
+	idmvalidator "idm/inner/validator"
+	"iter"
 	"testing"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	mock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // объявляем структуру мок-репозитория
@@ -33,6 +37,28 @@ type MockValidator struct {
 	mock.Mock
 }
 
+// журнал аудита для тестов: запоминает записанные события
+type StubAuditor struct {
+	events []audit.Event
+}
+
+func (a *StubAuditor) Record(ctx context.Context, tx *sqlx.Tx, event audit.Event) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+// проверка правил разделения обязанностей для тестов: запоминает проверенные роли
+// и возвращает err, если он задан
+type StubSodChecker struct {
+	checked [][]int64
+	err     error
+}
+
+func (c *StubSodChecker) CheckSodTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) error {
+	c.checked = append(c.checked, roleIds)
+	return c.err
+}
+
 func (m *MockValidator) Validate(request any) error {
 	args := m.Called(request)
 	return args.Error(0)
@@ -77,15 +103,33 @@ func (s *StubRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
 	return &sqlx.Tx{}, nil
 }
 
-func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
-	args := m.Called(ctx)
+func (m *MockRepo) FindWithCursor(ctx context.Context, sort string, position *common.Cursor, limit int) ([]Entity, error) {
+	args := m.Called(ctx, sort, position, limit)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (s *StubRepo) FindAll(ctx context.Context) ([]Entity, error) {
+func (s *StubRepo) FindWithCursor(ctx context.Context, sort string, position *common.Cursor, limit int) ([]Entity, error) {
 	return []Entity{s.entity}, nil
 }
 
+func (m *MockRepo) Search(ctx context.Context, query string, limit int) ([]searchEntity, error) {
+	args := m.Called(ctx, query, limit)
+	return args.Get(0).([]searchEntity), args.Error(1)
+}
+
+func (s *StubRepo) Search(ctx context.Context, query string, limit int) ([]searchEntity, error) {
+	return []searchEntity{{Entity: s.entity}}, nil
+}
+
+func (m *MockRepo) ExportEmployees(ctx context.Context, filter PageRequest) (iter.Seq2[exportEntity, error], error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(iter.Seq2[exportEntity, error]), args.Error(1)
+}
+
+func (s *StubRepo) ExportEmployees(ctx context.Context, filter PageRequest) (iter.Seq2[exportEntity, error], error) {
+	panic("unimplemented")
+}
+
 func (m *MockRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
 	args := m.Called(ctx, ids)
 	return args.Get(0).([]Entity), args.Error(1)
@@ -95,22 +139,31 @@ func (s *StubRepo) FindByIds(ctx context.Context, id []int64) ([]Entity, error)
 	return []Entity{s.entity}, nil
 }
 
-func (m *MockRepo) DeleteById(ctx context.Context, id int64) error {
-	args := m.Called(ctx, id)
+func (m *MockRepo) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	args := m.Called(ctx, tx, ids)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (s *StubRepo) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	args := m.Called(ctx, tx, id)
 	return args.Error(0)
 }
 
-func (s *StubRepo) DeleteById(ctx context.Context, id int64) error {
-	return nil
+func (s *StubRepo) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	panic("unimplemented")
 }
 
-func (m *MockRepo) DeleteByIds(ctx context.Context, ids []int64) error {
-	args := m.Called(ctx, ids)
+func (m *MockRepo) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	args := m.Called(ctx, tx, ids)
 	return args.Error(0)
 }
 
-func (s *StubRepo) DeleteByIds(ctx context.Context, id []int64) error {
-	return nil
+func (s *StubRepo) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	panic("unimplemented")
 }
 
 func (m *MockRepo) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
@@ -136,16 +189,16 @@ func (m *MockRepo) CountAll(ctx context.Context) (int64, error) {
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockRepo) FindWithPagination(ctx context.Context, limit, offset int, textFilter string) ([]Entity, error) {
-	args := m.Called(ctx, limit, offset, textFilter)
+func (m *MockRepo) FindWithPagination(ctx context.Context, limit, offset int, filter PageRequest) ([]Entity, error) {
+	args := m.Called(ctx, limit, offset, filter)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) CountWithFilter(ctx context.Context, textFilter string) (int64, error) {
-	args := m.Called(ctx, textFilter)
+func (m *MockRepo) CountWithFilter(ctx context.Context, filter PageRequest) (int64, error) {
+	args := m.Called(ctx, filter)
 	return args.Get(0).(int64), args.Error(1)
 }
 
@@ -153,11 +206,368 @@ func (s *StubRepo) CountAll(ctx context.Context) (int64, error) {
 	panic("unimplemented")
 }
 
-func (s *StubRepo) FindWithPagination(ctx context.Context, limit int, offset int, textFilter string) ([]Entity, error) {
+func (s *StubRepo) FindWithPagination(ctx context.Context, limit, offset int, filter PageRequest) ([]Entity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) CountWithFilter(ctx context.Context, filter PageRequest) (int64, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity, version time.Time) (Entity, error) {
+	args := m.Called(ctx, tx, employee, version)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (s *StubRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
 	panic("unimplemented")
 }
 
-func (s *StubRepo) CountWithFilter(ctx context.Context, textFilter string) (int64, error) {
+func (s *StubRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity, version time.Time) (Entity, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) FindRoleAssignments(ctx context.Context, employeeId int64, activeOnly bool) ([]RoleAssignmentEntity, error) {
+	args := m.Called(ctx, employeeId, activeOnly)
+	return args.Get(0).([]RoleAssignmentEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindRoleStatusTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (bool, error) {
+	args := m.Called(ctx, tx, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) HasOverlappingAssignmentTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	employeeId, roleId int64,
+	validFrom, validTo *time.Time,
+) (bool, error) {
+	args := m.Called(ctx, tx, employeeId, roleId, validFrom, validTo)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) AssignRoleTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	employeeId, roleId int64,
+	validFrom, validTo *time.Time,
+) (RoleAssignmentEntity, error) {
+	args := m.Called(ctx, tx, employeeId, roleId, validFrom, validTo)
+	return args.Get(0).(RoleAssignmentEntity), args.Error(1)
+}
+
+func (m *MockRepo) RevokeRoleAssignmentTx(ctx context.Context, tx *sqlx.Tx, employeeId, assignmentId int64) (bool, error) {
+	args := m.Called(ctx, tx, employeeId, assignmentId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) EndActiveAssignmentsTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) error {
+	return m.Called(ctx, tx, employeeId, roleId).Error(0)
+}
+
+func (m *MockRepo) SyncLegacyRoleIdTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	return m.Called(ctx, tx, employeeId).Error(0)
+}
+
+func (s *StubRepo) FindRoleAssignments(ctx context.Context, employeeId int64, activeOnly bool) ([]RoleAssignmentEntity, error) {
+	return nil, nil
+}
+
+func (s *StubRepo) FindRoleStatusTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (bool, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) HasOverlappingAssignmentTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	employeeId, roleId int64,
+	validFrom, validTo *time.Time,
+) (bool, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) AssignRoleTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	employeeId, roleId int64,
+	validFrom, validTo *time.Time,
+) (RoleAssignmentEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) RevokeRoleAssignmentTx(ctx context.Context, tx *sqlx.Tx, employeeId, assignmentId int64) (bool, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) EndActiveAssignmentsTx(ctx context.Context, tx *sqlx.Tx, employeeId, roleId int64) error {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (s *StubRepo) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) EmailExistsTx(ctx context.Context, tx *sqlx.Tx, email string) (bool, error) {
+	args := m.Called(ctx, tx, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (s *StubRepo) EmailExistsTx(ctx context.Context, tx *sqlx.Tx, email string) (bool, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) DepartmentExistsTx(ctx context.Context, tx *sqlx.Tx, departmentId int64) (bool, error) {
+	args := m.Called(ctx, tx, departmentId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (s *StubRepo) DepartmentExistsTx(ctx context.Context, tx *sqlx.Tx, departmentId int64) (bool, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) PositionExistsTx(ctx context.Context, tx *sqlx.Tx, positionId int64) (bool, error) {
+	args := m.Called(ctx, tx, positionId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (s *StubRepo) PositionExistsTx(ctx context.Context, tx *sqlx.Tx, positionId int64) (bool, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) FindPositionRoleIdsTx(ctx context.Context, tx *sqlx.Tx, positionId int64) ([]int64, error) {
+	args := m.Called(ctx, tx, positionId)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (s *StubRepo) FindPositionRoleIdsTx(ctx context.Context, tx *sqlx.Tx, positionId int64) ([]int64, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (s *StubRepo) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) FindRoleIdsByNamesTx(ctx context.Context, tx *sqlx.Tx, names []string) ([]roleNameEntity, error) {
+	args := m.Called(ctx, tx, names)
+	return args.Get(0).([]roleNameEntity), args.Error(1)
+}
+
+func (s *StubRepo) FindRoleIdsByNamesTx(ctx context.Context, tx *sqlx.Tx, names []string) ([]roleNameEntity, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	args := m.Called(ctx, tx, name)
+	return args.Error(0)
+}
+
+func (s *StubRepo) SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	args := m.Called(ctx, tx, name)
+	return args.Error(0)
+}
+
+func (s *StubRepo) RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) ReleaseSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	args := m.Called(ctx, tx, name)
+	return args.Error(0)
+}
+
+func (s *StubRepo) ReleaseSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, deletedBefore time.Time) ([]Entity, error) {
+	args := m.Called(ctx, tx, deletedBefore)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (s *StubRepo) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, deletedBefore time.Time) ([]Entity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) SyncLegacyRoleIdTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) UpdateLifecycleTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (Entity, error) {
+	args := m.Called(ctx, tx, employee)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) SaveStatusHistoryTx(ctx context.Context, tx *sqlx.Tx, history StatusHistoryEntity) error {
+	return m.Called(ctx, tx, history).Error(0)
+}
+
+func (m *MockRepo) FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryEntity, error) {
+	args := m.Called(ctx, employeeId)
+	return args.Get(0).([]StatusHistoryEntity), args.Error(1)
+}
+
+func (m *MockRepo) RevokeAllAssignmentsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) ([]int64, error) {
+	args := m.Called(ctx, tx, employeeId)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (s *StubRepo) UpdateLifecycleTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (Entity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) SaveStatusHistoryTx(ctx context.Context, tx *sqlx.Tx, history StatusHistoryEntity) error {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) FindStatusHistory(ctx context.Context, employeeId int64) ([]StatusHistoryEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) RevokeAllAssignmentsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) ([]int64, error) {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) SaveScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, change ScheduledChangeEntity) (ScheduledChangeEntity, error) {
+	args := m.Called(ctx, tx, change)
+	return args.Get(0).(ScheduledChangeEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindScheduledChanges(ctx context.Context, filter ScheduledChangeFilter) ([]ScheduledChangeEntity, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]ScheduledChangeEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindScheduledChangeForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (ScheduledChangeEntity, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(ScheduledChangeEntity), args.Error(1)
+}
+
+func (m *MockRepo) UpdateScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, change ScheduledChangeEntity) (ScheduledChangeEntity, error) {
+	args := m.Called(ctx, tx, change)
+	return args.Get(0).(ScheduledChangeEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindDueScheduledChangeIds(ctx context.Context, limit int) ([]int64, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) LockDueScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, id int64) (ScheduledChangeEntity, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Get(0).(ScheduledChangeEntity), args.Error(1)
+}
+
+func (m *MockRepo) MarkScheduledChangeFailed(ctx context.Context, id int64, result string) error {
+	return m.Called(ctx, id, result).Error(0)
+}
+
+func (s *StubRepo) SaveScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, change ScheduledChangeEntity) (ScheduledChangeEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) FindScheduledChanges(ctx context.Context, filter ScheduledChangeFilter) ([]ScheduledChangeEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) FindScheduledChangeForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (ScheduledChangeEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) UpdateScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, change ScheduledChangeEntity) (ScheduledChangeEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) FindDueScheduledChangeIds(ctx context.Context, limit int) ([]int64, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) LockDueScheduledChangeTx(ctx context.Context, tx *sqlx.Tx, id int64) (ScheduledChangeEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) MarkScheduledChangeFailed(ctx context.Context, id int64, result string) error {
+	panic("unimplemented")
+}
+
+func (m *MockRepo) LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error {
+	return m.Called(ctx, tx).Error(0)
+}
+
+func (m *MockRepo) IsInManagementChainTx(ctx context.Context, tx *sqlx.Tx, employeeId, ancestorId int64) (bool, error) {
+	args := m.Called(ctx, tx, employeeId, ancestorId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) UpdateManagerTx(ctx context.Context, tx *sqlx.Tx, id int64, managerId *int64) (Entity, error) {
+	args := m.Called(ctx, tx, id, managerId)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindDirectReports(ctx context.Context, managerId int64) ([]Entity, error) {
+	args := m.Called(ctx, managerId)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindSubordinates(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]hierarchyEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindManagementChain(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]hierarchyEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindOrgChart(ctx context.Context) ([]Entity, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (s *StubRepo) LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) IsInManagementChainTx(ctx context.Context, tx *sqlx.Tx, employeeId, ancestorId int64) (bool, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) UpdateManagerTx(ctx context.Context, tx *sqlx.Tx, id int64, managerId *int64) (Entity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) FindDirectReports(ctx context.Context, managerId int64) ([]Entity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) FindSubordinates(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) FindManagementChain(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	panic("unimplemented")
+}
+
+func (s *StubRepo) FindOrgChart(ctx context.Context) ([]Entity, error) {
 	panic("unimplemented")
 }
 
@@ -189,8 +599,9 @@ func TestService_FindById_Mock(t *testing.T) {
 		UpdatedAt:  time.Now(),
 	}
 	mockRepo.On("FindById", mock.Anything, int64(1)).Return(entity, nil)
+	mockRepo.On("FindRoleAssignments", mock.Anything, int64(1), true).Return([]RoleAssignmentEntity{}, nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	result, err := svc.FindById(context.Background(), 1)
 
@@ -215,7 +626,7 @@ func TestService_FindById_Stub(t *testing.T) {
 	}
 	validator := new(MockValidator)
 
-	svc := NewService(stubRepo, validator, logger)
+	svc := NewService(stubRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	result, err := svc.FindById(context.Background(), 1)
 
@@ -229,7 +640,7 @@ func TestService_FindById_Error(t *testing.T) {
 	logger := createTestLogger()
 	mockRepo.On("FindById", mock.Anything, int64(1)).Return(Entity{}, errors.New("db error"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	result, err := svc.FindById(context.Background(), 1)
 
@@ -251,7 +662,7 @@ func TestService_Add(t *testing.T) {
 	}
 	mockRepo.On("Add", mock.Anything, entity).Return(nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	validator.On("Validate", entity).Return(nil)
 
@@ -268,7 +679,7 @@ func TestService_Add_Error(t *testing.T) {
 	logger := createTestLogger()
 	mockRepo.On("Add", mock.Anything, mock.Anything).Return(errors.New("db error"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	validator.On("Validate", mock.Anything).Return(nil)
 
@@ -279,58 +690,220 @@ func TestService_Add_Error(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestService_FindAll(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
+func TestService_FindWithCursor(t *testing.T) {
+	createdAt := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
 	entities := []Entity{
-		{
-			Id:         1,
-			Name:       "John",
-			Email:      "john@example.com",
-			Position:   "Developer",
-			Department: "Engineering",
-			RoleId:     2,
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
-		},
-		{
-			Id:         2,
-			Name:       "Jane",
-			Email:      "jane@example.com",
-			Position:   "Designer",
-			Department: "Design",
-			RoleId:     3,
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
-		},
+		{Id: 1, Name: "John", Email: "john@example.com", CreatedAt: createdAt},
+		{Id: 2, Name: "Jane", Email: "jane@example.com", CreatedAt: createdAt},
+		{Id: 3, Name: "Rick", Email: "rick@example.com", CreatedAt: createdAt},
 	}
-	mockRepo.On("FindAll", mock.Anything).Return(entities, nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	t.Run("First page has next but no prev", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := CursorRequest{Limit: 2, Sort: "-created_at"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("FindWithCursor", mock.Anything, "-created_at", (*common.Cursor)(nil), 3).Return(entities, nil)
+
+		page, err := svc.FindWithCursor(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Data, 2)
+		assert.Nil(t, page.Prev)
+		assert.Equal(t, &common.Cursor{Sort: "-created_at", Value: createdAt.Format(time.RFC3339Nano), Id: 2}, page.Next)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Backward page is returned in sort order", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		position := &common.Cursor{Sort: "name", Value: "Rick", Id: 3, Backward: true}
+		request := CursorRequest{Limit: 2, Sort: "name", Cursor: position}
+
+		mockValidator.On("Validate", request).Return(nil)
+		// строки перед позицией приходят в обратном порядке
+		mockRepo.On("FindWithCursor", mock.Anything, "name", position, 3).
+			Return([]Entity{entities[0], entities[1]}, nil)
+
+		page, err := svc.FindWithCursor(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{2, 1}, []int64{page.Data[0].Id, page.Data[1].Id})
+		assert.Nil(t, page.Prev)
+		assert.Equal(t, &common.Cursor{Sort: "name", Value: "John", Id: 1}, page.Next)
+	})
+
+	t.Run("Cursor of another sort is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := CursorRequest{Limit: 2, Sort: "email", Cursor: &common.Cursor{Sort: "name", Value: "Jane", Id: 2}}
 
-	result, err := svc.FindAll(context.Background())
+		mockValidator.On("Validate", request).Return(nil)
 
-	assert.NoError(t, err)
-	assert.Len(t, result, 2)
-	assert.Equal(t, entities[0].toResponse(), result[0])
-	assert.Equal(t, entities[1].toResponse(), result[1])
-	mockRepo.AssertExpectations(t)
+		_, err := svc.FindWithCursor(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "FindWithCursor", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Sort by nullable column is rejected", func(t *testing.T) {
+		mockValidator := new(MockValidator)
+		svc := NewService(new(MockRepo), &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := CursorRequest{Limit: 2, Sort: "hire_date"}
+
+		mockValidator.On("Validate", request).Return(nil)
+
+		_, err := svc.FindWithCursor(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := CursorRequest{Limit: 2}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("FindWithCursor", mock.Anything, "", (*common.Cursor)(nil), 3).
+			Return([]Entity{}, errors.New("db error"))
+
+		_, err := svc.FindWithCursor(context.Background(), request)
+
+		assert.Error(t, err)
+	})
 }
 
-func TestService_FindAll_Error(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("FindAll", mock.Anything).Return([]Entity{}, errors.New("db error"))
+func TestService_Export(t *testing.T) {
+	t.Run("Maps entities to records", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		filter := PageRequest{PageNumber: 1, PageSize: 1, Statuses: []string{StatusActive}, Sort: "name"}
+		entities := []exportEntity{
+			{Entity: Entity{Id: 1, Name: "John Doe", Position: "Developer"}, Roles: []string{"Admin", "User"}, Manager: "Jane Roe"},
+			{Entity: Entity{Id: 2, Name: "Jane Roe"}},
+		}
+
+		mockValidator.On("Validate", filter).Return(nil)
+		mockRepo.On("ExportEmployees", mock.Anything, filter).Return(iter.Seq2[exportEntity, error](
+			func(yield func(exportEntity, error) bool) {
+				for _, entity := range entities {
+					if !yield(entity, nil) {
+						return
+					}
+				}
+			}), nil)
+
+		// номер и размер страницы для выгрузки не нужны
+		records, err := svc.Export(context.Background(), PageRequest{Statuses: []string{StatusActive}, Sort: "name"})
+
+		require.NoError(t, err)
+		var exported []ExportRecord
+		for record, err := range records {
+			require.NoError(t, err)
+			exported = append(exported, record)
+		}
+		require.Len(t, exported, 2)
+		assert.Equal(t, []string{"Admin", "User"}, exported[0].Roles)
+		assert.Equal(t, "Jane Roe", exported[0].Manager)
+		assert.Equal(t, []string{}, exported[1].Roles)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unsupported sort", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		filter := PageRequest{PageNumber: 1, PageSize: 1, Sort: "salary"}
+
+		mockValidator.On("Validate", filter).Return(nil)
+
+		_, err := svc.Export(context.Background(), PageRequest{Sort: "salary"})
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "ExportEmployees", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		filter := PageRequest{PageNumber: 1, PageSize: 1}
+
+		mockValidator.On("Validate", filter).Return(nil)
+		mockRepo.On("ExportEmployees", mock.Anything, filter).
+			Return(iter.Seq2[exportEntity, error](nil), errors.New("db error"))
+
+		_, err := svc.Export(context.Background(), PageRequest{})
+
+		assert.ErrorContains(t, err, "error exporting employees")
+	})
+}
 
-	svc := NewService(mockRepo, validator, logger)
+func TestService_Search(t *testing.T) {
+	t.Run("Results keep repository order", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := SearchRequest{Query: "иванов", Limit: 20}
 
-	result, err := svc.FindAll(context.Background())
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("Search", mock.Anything, "иванов", 20).Return([]searchEntity{
+			{Entity: Entity{Id: 2, Name: "Иванов Иван"}, Rank: 1.1},
+			{Entity: Entity{Id: 1, Name: "Ivanova Anna"}, Rank: 0.7},
+		}, nil)
 
-	assert.Error(t, err)
-	assert.Equal(t, 0, len(result))
-	mockRepo.AssertExpectations(t)
+		results, err := svc.Search(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{2, 1}, []int64{results[0].Id, results[1].Id})
+		assert.Equal(t, 1.1, results[0].Rank)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Query without letters or digits is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := SearchRequest{Query: " &|!:* ", Limit: 20}
+
+		mockValidator.On("Validate", request).Return(nil)
+
+		_, err := svc.Search(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := SearchRequest{Query: "john", Limit: 20}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("Search", mock.Anything, "john", 20).Return([]searchEntity{}, errors.New("db error"))
+
+		_, err := svc.Search(context.Background(), request)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestSearchTerms(t *testing.T) {
+	// операторы tsquery отбрасываются, email делится на слова
+	assert.Equal(t, []string{"john", "doe", "example", "com"}, searchTerms("John & (doe@example.com):*"))
+	assert.Equal(t, []string{"иванов", "qa"}, searchTerms("  Иванов!  QA "))
+	assert.Empty(t, searchTerms("!&|"))
 }
 
 func TestService_FindByIds(t *testing.T) {
@@ -358,7 +931,7 @@ func TestService_FindByIds(t *testing.T) {
 	}
 	mockRepo.On("FindByIds", mock.Anything, ids).Return(entities, nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	result, err := svc.FindByIds(context.Background(), ids)
 
@@ -375,7 +948,7 @@ func TestService_FindByIds_Error(t *testing.T) {
 	logger := createTestLogger()
 	mockRepo.On("FindByIds", mock.Anything, []int64{1, 2}).Return([]Entity{}, errors.New("db error"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	result, err := svc.FindByIds(context.Background(), []int64{1, 2})
 
@@ -385,59 +958,97 @@ func TestService_FindByIds_Error(t *testing.T) {
 }
 
 func TestService_DeleteById(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("DeleteById", mock.Anything, int64(1)).Return(nil)
-
-	svc := NewService(mockRepo, validator, logger)
-
-	err := svc.DeleteById(context.Background(), 1)
-
-	assert.NoError(t, err)
-	mockRepo.AssertExpectations(t)
+	t.Run("Deletes and records audit event", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1, Name: "John Doe"}, nil)
+		mockRepo.On("DeleteByIdTx", mock.Anything, tx, int64(1)).Return(nil)
+
+		svc := NewService(mockRepo, auditor, &StubSodChecker{}, new(MockValidator), createTestLogger())
+
+		err := svc.DeleteById(context.Background(), 1)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionDelete, auditor.events[0].Action)
+			assert.Equal(t, audit.EntityEmployee, auditor.events[0].EntityType)
+			assert.Equal(t, int64(1), auditor.events[0].EntityId)
+			assert.Equal(t, "John Doe", auditor.events[0].Before.(Response).Name)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{}, sql.ErrNoRows)
+
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+
+		err := svc.DeleteById(context.Background(), 1)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		mockRepo.AssertNotCalled(t, "DeleteByIdTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
 }
 
 func TestService_DeleteById_Error(t *testing.T) {
 	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("DeleteById", mock.Anything, int64(1)).Return(errors.New("db error"))
+	auditor := &StubAuditor{}
+	tx, sqlMock := newMockTx(t, false)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1}, nil)
+	mockRepo.On("DeleteByIdTx", mock.Anything, tx, int64(1)).Return(errors.New("db error"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, auditor, &StubSodChecker{}, new(MockValidator), createTestLogger())
 
 	err := svc.DeleteById(context.Background(), 1)
 
 	assert.Error(t, err)
+	assert.Empty(t, auditor.events)
 	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestService_DeleteByIds(t *testing.T) {
 	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("DeleteByIds", mock.Anything, []int64{1, 2}).Return(nil)
+	auditor := &StubAuditor{}
+	tx, sqlMock := newMockTx(t, true)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdsForUpdateTx", mock.Anything, tx, []int64{1, 2}).Return([]Entity{{Id: 1}, {Id: 2}}, nil)
+	mockRepo.On("DeleteByIdsTx", mock.Anything, tx, []int64{1, 2}).Return(nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, auditor, &StubSodChecker{}, new(MockValidator), createTestLogger())
 
 	err := svc.DeleteByIds(context.Background(), []int64{1, 2})
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+	// событие пишется на каждого удалённого сотрудника
+	assert.Len(t, auditor.events, 2)
 }
 
 func TestService_DeleteByIds_Error(t *testing.T) {
 	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("DeleteByIds", mock.Anything, []int64{1, 2}).Return(errors.New("db error"))
+	tx, sqlMock := newMockTx(t, false)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdsForUpdateTx", mock.Anything, tx, []int64{1, 2}).Return([]Entity{{Id: 1}, {Id: 2}}, nil)
+	mockRepo.On("DeleteByIdsTx", mock.Anything, tx, []int64{1, 2}).Return(errors.New("db error"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
 
 	err := svc.DeleteByIds(context.Background(), []int64{1, 2})
 
 	assert.Error(t, err)
 	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestService_AddWithTransaction_BeginError(t *testing.T) {
@@ -446,7 +1057,7 @@ func TestService_AddWithTransaction_BeginError(t *testing.T) {
 	logger := createTestLogger()
 	mockRepo.On("BeginTransaction", mock.Anything).Return(nil, errors.New("failed to begin transaction"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	entity := &Entity{
 		Name:       "Test User",
@@ -479,7 +1090,7 @@ func TestService_AddWithTransaction_ExistingEmployeeCheckError(t *testing.T) {
 
 	mockRepo.On("BeginTransaction", mock.Anything).Return((*sqlx.Tx)(nil), errors.New("transaction failed"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	result, err := svc.AddWithTransaction(context.Background(), entity)
 
@@ -503,7 +1114,7 @@ func TestService_AddWithTransaction_EmployeeAlreadyExists(t *testing.T) {
 
 	mockRepo.On("BeginTransaction", mock.Anything).Return((*sqlx.Tx)(nil), errors.New("failed to begin transaction"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	result, err := svc.AddWithTransaction(context.Background(), entity)
 
@@ -527,7 +1138,7 @@ func TestService_AddWithTransaction_InsertError(t *testing.T) {
 
 	mockRepo.On("BeginTransaction", mock.Anything).Return((*sqlx.Tx)(nil), errors.New("insert failed"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	result, err := svc.AddWithTransaction(context.Background(), entity)
 
@@ -551,14 +1162,19 @@ func TestService_AddWithTransaction_Success(t *testing.T) {
 
 	sqlMock.ExpectBegin()
 
+	// назначаемая роль существует и активна
+	sqlMock.ExpectQuery(`select status from role where id = \$1`).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(true))
+
 	// Запрос для проверки существования сотрудника --> возвращает пустой результат
 	sqlMock.ExpectQuery(`SELECT id FROM employee WHERE name = \$1`).
 		WithArgs("Jack Black").
 		WillReturnError(sql.ErrNoRows)
 
 	// INSERT запрос с возвратом ID
-	sqlMock.ExpectQuery(`INSERT INTO employee \(name, email, position, department, role_id\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id`).
-		WithArgs("Jack Black", "jack.black@example.com", "Developer", "IT", int64(2)).
+	sqlMock.ExpectQuery(`INSERT INTO employee \(name, email, position_id, department_id, role_id, status, hire_date, start_date\) VALUES \(\$1, \$2, NULLIF\(\$3::bigint, 0\), NULLIF\(\$4::bigint, 0\), NULLIF\(\$5::bigint, 0\), .* INSERT INTO employee_role`).
+		WithArgs("Jack Black", "jack.black@example.com", int64(1), int64(3), int64(2), "", nil, nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
 
 	sqlMock.ExpectCommit()
@@ -567,16 +1183,18 @@ func TestService_AddWithTransaction_Success(t *testing.T) {
 	validator := new(MockValidator)
 	logger := createTestLogger()
 
-	service := NewService(repo, validator, logger)
+	service := NewService(repo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	employee := &Entity{
-		Name:       "Jack Black",
-		Email:      "jack.black@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     2,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		Name:         "Jack Black",
+		Email:        "jack.black@example.com",
+		PositionId:   1,
+		Position:     "Developer",
+		DepartmentId: 3,
+		Department:   "IT",
+		RoleId:       2,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 
 	response, err := service.AddWithTransaction(context.Background(), employee)
@@ -585,7 +1203,9 @@ func TestService_AddWithTransaction_Success(t *testing.T) {
 	assert.Equal(t, int64(123), response.Id)
 	assert.Equal(t, "Jack Black", response.Name)
 	assert.Equal(t, "jack.black@example.com", response.Email)
+	assert.Equal(t, int64(1), response.PositionId)
 	assert.Equal(t, "Developer", response.Position)
+	assert.Equal(t, int64(3), response.DepartmentId)
 	assert.Equal(t, "IT", response.Department)
 	assert.Equal(t, int64(2), response.RoleId)
 
@@ -613,22 +1233,22 @@ func TestCreateEmployee_Success(t *testing.T) {
 	tx, err := sqlxDB.Beginx()
 	assert.NoError(t, err)
 
-	service := NewService(mockRepo, mockValidator, logger)
+	service := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, logger)
 
 	employee := &Entity{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     2,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       2,
 	}
 
 	request := CreateRequest{
-		Name:       employee.Name,
-		Email:      employee.Email,
-		Position:   employee.Position,
-		Department: employee.Department,
-		RoleId:     employee.RoleId,
+		Name:         employee.Name,
+		Email:        employee.Email,
+		PositionId:   employee.PositionId,
+		DepartmentId: employee.DepartmentId,
+		RoleId:       employee.RoleId,
 	}
 
 	expectedId := int64(123)
@@ -636,12 +1256,30 @@ func TestCreateEmployee_Success(t *testing.T) {
 	mockValidator.On("Validate", request).Return(nil)
 	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
 	mockRepo.On("FindByNameTx", mock.Anything, tx, "John Doe").Return(false, nil)
+	mockRepo.On("DepartmentExistsTx", mock.Anything, tx, int64(1)).Return(true, nil)
+	mockRepo.On("PositionExistsTx", mock.Anything, tx, int64(1)).Return(true, nil)
+	mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(2)).Return(true, nil)
 	mockRepo.On("SaveTx", mock.Anything, tx, request.ToEntity()).Return(expectedId, nil)
+	mockRepo.On("SaveStatusHistoryTx", mock.Anything, tx, StatusHistoryEntity{
+		EmployeeId: expectedId,
+		Transition: TransitionCreate,
+		ToStatus:   StatusActive,
+	}).Return(nil)
+	// роль 2 уже назначена из запроса, назначается только недостающая роль 3 должности
+	mockRepo.On("FindPositionRoleIdsTx", mock.Anything, tx, int64(1)).Return([]int64{2, 3}, nil)
+	mockRepo.On("HasOverlappingAssignmentTx", mock.Anything, tx, expectedId, int64(2), (*time.Time)(nil), (*time.Time)(nil)).
+		Return(true, nil)
+	mockRepo.On("HasOverlappingAssignmentTx", mock.Anything, tx, expectedId, int64(3), (*time.Time)(nil), (*time.Time)(nil)).
+		Return(false, nil)
+	mockRepo.On("AssignRoleTx", mock.Anything, tx, expectedId, int64(3), (*time.Time)(nil), (*time.Time)(nil)).
+		Return(RoleAssignmentEntity{Id: 7, EmployeeId: expectedId, RoleId: 3, Active: true}, nil)
+	mockRepo.On("SyncLegacyRoleIdTx", mock.Anything, tx, expectedId).Return(nil)
 
 	result, err := service.CreateEmployee(context.Background(), request)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedId, result)
+	mockRepo.AssertNumberOfCalls(t, "AssignRoleTx", 1)
 	mockValidator.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 	assert.NoError(t, sqlMock.ExpectationsWereMet())
@@ -652,7 +1290,7 @@ func TestCreateEmployee_ValidationError(t *testing.T) {
 	mockValidator := new(MockValidator)
 	logger := createTestLogger()
 
-	service := NewService(mockRepo, mockValidator, logger)
+	service := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, logger)
 	request := CreateRequest{Name: "A"} // слишком короткое имя
 
 	validationErr := validator.ValidationErrors{}
@@ -690,19 +1328,19 @@ func TestCreateEmployee_TransactionCreationError(t *testing.T) {
 	assert.NoError(t, err)
 
 	employee := &Entity{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     2,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       2,
 	}
 
 	request := CreateRequest{
-		Name:       employee.Name,
-		Email:      employee.Email,
-		Position:   employee.Position,
-		Department: employee.Department,
-		RoleId:     employee.RoleId,
+		Name:         employee.Name,
+		Email:        employee.Email,
+		PositionId:   employee.PositionId,
+		DepartmentId: employee.DepartmentId,
+		RoleId:       employee.RoleId,
 	}
 
 	txErr := errors.New("insert failed")
@@ -710,7 +1348,7 @@ func TestCreateEmployee_TransactionCreationError(t *testing.T) {
 	mockValidator.On("Validate", request).Return(nil)
 	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, txErr)
 
-	service := NewService(mockRepo, mockValidator, logger)
+	service := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, logger)
 
 	result, err := service.CreateEmployee(context.Background(), request)
 
@@ -741,22 +1379,22 @@ func TestCreateEmployee_FindByNameError(t *testing.T) {
 	tx, err := sqlxDB.Beginx()
 	assert.NoError(t, err)
 
-	service := NewService(mockRepo, mockValidator, logger)
+	service := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, logger)
 
 	employee := &Entity{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     2,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       2,
 	}
 
 	request := CreateRequest{
-		Name:       employee.Name,
-		Email:      employee.Email,
-		Position:   employee.Position,
-		Department: employee.Department,
-		RoleId:     employee.RoleId,
+		Name:         employee.Name,
+		Email:        employee.Email,
+		PositionId:   employee.PositionId,
+		DepartmentId: employee.DepartmentId,
+		RoleId:       employee.RoleId,
 	}
 
 	findErr := errors.New("database error")
@@ -795,22 +1433,22 @@ func TestCreateEmployee_EmployeeAlreadyExists(t *testing.T) {
 	tx, err := sqlxDB.Beginx()
 	assert.NoError(t, err)
 
-	service := NewService(mockRepo, mockValidator, logger)
+	service := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, logger)
 
 	employee := &Entity{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     2,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       2,
 	}
 
 	request := CreateRequest{
-		Name:       employee.Name,
-		Email:      employee.Email,
-		Position:   employee.Position,
-		Department: employee.Department,
-		RoleId:     employee.RoleId,
+		Name:         employee.Name,
+		Email:        employee.Email,
+		PositionId:   employee.PositionId,
+		DepartmentId: employee.DepartmentId,
+		RoleId:       employee.RoleId,
 	}
 
 	mockValidator.On("Validate", request).Return(nil)
@@ -830,7 +1468,8 @@ func TestCreateEmployee_EmployeeAlreadyExists(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 	// SaveTx не должен вызываться, если сотрудник уже существует
 	mockRepo.AssertNotCalled(t, "SaveTx")
-	assert.Error(t, sqlMock.ExpectationsWereMet())
+	// транзакция откатывается
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
 func TestCreateEmployee_SaveError(t *testing.T) {
@@ -851,22 +1490,22 @@ func TestCreateEmployee_SaveError(t *testing.T) {
 	tx, err := sqlxDB.Beginx()
 	assert.NoError(t, err)
 
-	service := NewService(mockRepo, mockValidator, logger)
+	service := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, logger)
 
 	employee := &Entity{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     2,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       2,
 	}
 
 	request := CreateRequest{
-		Name:       employee.Name,
-		Email:      employee.Email,
-		Position:   employee.Position,
-		Department: employee.Department,
-		RoleId:     employee.RoleId,
+		Name:         employee.Name,
+		Email:        employee.Email,
+		PositionId:   employee.PositionId,
+		DepartmentId: employee.DepartmentId,
+		RoleId:       employee.RoleId,
 	}
 
 	saveErr := errors.New("save failed")
@@ -874,6 +1513,9 @@ func TestCreateEmployee_SaveError(t *testing.T) {
 	mockValidator.On("Validate", request).Return(nil)
 	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
 	mockRepo.On("FindByNameTx", mock.Anything, tx, "John Doe").Return(false, nil)
+	mockRepo.On("DepartmentExistsTx", mock.Anything, tx, int64(1)).Return(true, nil)
+	mockRepo.On("PositionExistsTx", mock.Anything, tx, int64(1)).Return(true, nil)
+	mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(2)).Return(true, nil)
 	mockRepo.On("SaveTx", mock.Anything, tx, request.ToEntity()).Return(int64(0), saveErr)
 
 	result, err := service.CreateEmployee(context.Background(), request)
@@ -903,22 +1545,22 @@ func BenchmarkCreateEmployee_Success(b *testing.B) {
 
 	sqlxDB := sqlx.NewDb(db, "postgres")
 
-	service := NewService(mockRepo, mockValidator, logger)
+	service := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, logger)
 
 	employee := &Entity{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     2,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       2,
 	}
 
 	request := CreateRequest{
-		Name:       employee.Name,
-		Email:      employee.Email,
-		Position:   employee.Position,
-		Department: employee.Department,
-		RoleId:     employee.RoleId,
+		Name:         employee.Name,
+		Email:        employee.Email,
+		PositionId:   employee.PositionId,
+		DepartmentId: employee.DepartmentId,
+		RoleId:       employee.RoleId,
 	}
 
 	for b.Loop() {
@@ -930,7 +1572,11 @@ func BenchmarkCreateEmployee_Success(b *testing.B) {
 		mockValidator.On("Validate", request).Return(nil).Once()
 		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil).Once()
 		mockRepo.On("FindByNameTx", mock.Anything, tx, "John Doe").Return(false, nil).Once()
+		mockRepo.On("DepartmentExistsTx", mock.Anything, tx, int64(1)).Return(true, nil).Once()
+		mockRepo.On("PositionExistsTx", mock.Anything, tx, int64(1)).Return(true, nil).Once()
 		mockRepo.On("SaveTx", mock.Anything, tx, request.ToEntity()).Return(int64(123), nil).Once()
+		mockRepo.On("SaveStatusHistoryTx", mock.Anything, tx, mock.Anything).Return(nil).Once()
+		mockRepo.On("FindPositionRoleIdsTx", mock.Anything, tx, int64(1)).Return([]int64{}, nil).Once()
 
 		_, _ = service.CreateEmployee(context.Background(), request)
 	}
@@ -940,14 +1586,14 @@ func TestService_validateCreateRequest_Success(t *testing.T) {
 	mockRepo := new(MockRepo)
 	validator := new(MockValidator)
 	logger := createTestLogger()
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	request := CreateRequest{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     1,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       1,
 	}
 	validator.On("Validate", request).Return(nil)
 
@@ -960,7 +1606,7 @@ func TestService_validateCreateRequest_CustomError(t *testing.T) {
 	mockRepo := new(MockRepo)
 	validator := new(MockValidator)
 	logger := createTestLogger()
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	request := CreateRequest{Name: ""} // невалидные данные
 	customErr := errors.New("custom validation error")
@@ -980,7 +1626,7 @@ func TestService_validateCreateRequest_ValidationErrorsType(t *testing.T) {
 	mockRepo := new(MockRepo)
 	validator := new(MockValidator)
 	logger := createTestLogger()
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, validator, logger)
 
 	var validationErrs = mockValidationErrors{}
 
@@ -992,3 +1638,1228 @@ func TestService_validateCreateRequest_ValidationErrorsType(t *testing.T) {
 	assert.Contains(t, err.Error(), "Data validation error")
 	validator.AssertExpectations(t)
 }
+
+// создаёт транзакцию на sqlmock, ожидающую завершения commit или rollback
+func newMockTx(t *testing.T, commit bool) (*sqlx.Tx, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	sqlMock.ExpectBegin()
+	if commit {
+		sqlMock.ExpectCommit()
+	} else {
+		sqlMock.ExpectRollback()
+	}
+
+	tx, err := sqlx.NewDb(db, "postgres").Beginx()
+	assert.NoError(t, err)
+	return tx, sqlMock
+}
+
+func TestUpdateEmployee_Success(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	auditor := &StubAuditor{}
+	svc := NewService(mockRepo, auditor, &StubSodChecker{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, true)
+
+	version := time.Date(2025, 6, 10, 12, 0, 0, 123456000, time.UTC)
+	current := Entity{Id: 1, Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1, UpdatedAt: version}
+	request := UpdateRequest{Id: 1, Name: "John Doe", Email: "john.doe@example.com", PositionId: 2, DepartmentId: 1, RoleId: 2, Version: version}
+
+	expected := current
+	request.applyTo(&expected)
+	saved := expected
+	saved.UpdatedAt = version.Add(time.Second)
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(current, nil)
+	// у новой должности нет ролей по умолчанию
+	mockRepo.On("PositionExistsTx", mock.Anything, tx, int64(2)).Return(true, nil)
+	mockRepo.On("FindPositionRoleIdsTx", mock.Anything, tx, int64(2)).Return([]int64{}, nil)
+	// смена role_id закрывает назначение старой роли и назначает новую
+	mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(2)).Return(true, nil)
+	mockRepo.On("EndActiveAssignmentsTx", mock.Anything, tx, int64(1), int64(1)).Return(nil)
+	mockRepo.On("HasOverlappingAssignmentTx", mock.Anything, tx, int64(1), int64(2), (*time.Time)(nil), (*time.Time)(nil)).
+		Return(false, nil)
+	mockRepo.On("AssignRoleTx", mock.Anything, tx, int64(1), int64(2), (*time.Time)(nil), (*time.Time)(nil)).
+		Return(RoleAssignmentEntity{Id: 10, EmployeeId: 1, RoleId: 2, Active: true}, nil)
+	mockRepo.On("UpdateTx", mock.Anything, tx, expected, version).Return(saved, nil)
+
+	result, err := svc.UpdateEmployee(context.Background(), request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.PositionId)
+	assert.Equal(t, int64(2), result.RoleId)
+	assert.True(t, result.UpdatedAt.After(version))
+	// имя не менялось - проверка уникальности не нужна
+	mockRepo.AssertNotCalled(t, "FindByNameTx", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+	// в журнал попадают снимки до и после изменения
+	if assert.Len(t, auditor.events, 1) {
+		assert.Equal(t, audit.ActionUpdate, auditor.events[0].Action)
+		assert.Equal(t, int64(1), auditor.events[0].Before.(Response).PositionId)
+		assert.Equal(t, int64(2), auditor.events[0].After.(Response).PositionId)
+	}
+}
+
+func TestUpdateEmployee_VersionConflict(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, false)
+
+	staleVersion := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	current := Entity{Id: 1, Name: "John Doe", UpdatedAt: staleVersion.Add(time.Minute)}
+	request := UpdateRequest{Id: 1, Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1, Version: staleVersion}
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(current, nil)
+
+	_, err := svc.UpdateEmployee(context.Background(), request)
+
+	var conflictErr common.ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	mockRepo.AssertNotCalled(t, "UpdateTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUpdateEmployee_ConcurrentWriteDetectedOnSave(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, false)
+
+	version := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	current := Entity{Id: 1, Name: "John Doe", PositionId: 1, DepartmentId: 1, RoleId: 1, UpdatedAt: version}
+	request := UpdateRequest{Id: 1, Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1, Version: version}
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(current, nil)
+	mockRepo.On("UpdateTx", mock.Anything, tx, mock.Anything, version).Return(Entity{}, sql.ErrNoRows)
+
+	_, err := svc.UpdateEmployee(context.Background(), request)
+
+	var conflictErr common.ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUpdateEmployee_NotFound(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, false)
+
+	version := time.Now()
+	request := UpdateRequest{Id: 42, Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1, Version: version}
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(42)).Return(Entity{}, sql.ErrNoRows)
+
+	_, err := svc.UpdateEmployee(context.Background(), request)
+
+	var notFoundErr common.NotFoundError
+	assert.True(t, errors.As(err, &notFoundErr))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUpdateEmployee_MissingVersion(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+
+	request := UpdateRequest{Id: 1, Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1}
+	mockValidator.On("Validate", request).Return(nil)
+
+	_, err := svc.UpdateEmployee(context.Background(), request)
+
+	var validationErr common.RequestValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	mockRepo.AssertNotCalled(t, "BeginTransaction", mock.Anything)
+}
+
+func TestUpdateEmployee_NameAlreadyTaken(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, false)
+
+	version := time.Now()
+	current := Entity{Id: 1, Name: "John Doe", UpdatedAt: version}
+	request := UpdateRequest{Id: 1, Name: "Jane Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 1, Version: version}
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(current, nil)
+	mockRepo.On("FindByNameTx", mock.Anything, tx, "Jane Doe").Return(true, nil)
+
+	_, err := svc.UpdateEmployee(context.Background(), request)
+
+	var existsErr common.AlreadyExistsError
+	assert.True(t, errors.As(err, &existsErr))
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestPatchEmployee_ChangesOnlyProvidedFields(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, true)
+
+	version := time.Now()
+	current := Entity{Id: 1, Name: "John Doe", Email: "john@example.com", PositionId: 1, Position: "Developer", DepartmentId: 1, RoleId: 1, UpdatedAt: version}
+	departmentId := int64(2)
+	request := PatchRequest{Id: 1, DepartmentId: &departmentId, Version: version}
+
+	expected := current
+	expected.DepartmentId = 2
+	saved := expected
+	saved.Department = "Finance"
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(current, nil)
+	mockRepo.On("DepartmentExistsTx", mock.Anything, tx, int64(2)).Return(true, nil)
+	mockRepo.On("UpdateTx", mock.Anything, tx, expected, version).Return(saved, nil)
+
+	result, err := svc.PatchEmployee(context.Background(), request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.DepartmentId)
+	assert.Equal(t, "Finance", result.Department)
+	assert.Equal(t, "Developer", result.Position)
+	assert.Equal(t, "john@example.com", result.Email)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestService_FindById_ReturnsActiveRoles(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+
+	entity := Entity{Id: 1, Name: "John", RoleId: 3}
+	mockRepo.On("FindById", mock.Anything, int64(1)).Return(entity, nil)
+	mockRepo.On("FindRoleAssignments", mock.Anything, int64(1), true).Return([]RoleAssignmentEntity{
+		{Id: 10, EmployeeId: 1, RoleId: 2, RoleName: "Developer", Active: true},
+		{Id: 11, EmployeeId: 1, RoleId: 3, RoleName: "On-call", Active: true},
+	}, nil)
+
+	result, err := svc.FindById(context.Background(), 1)
+
+	assert.NoError(t, err)
+	// role_id остаётся доступным для обратной совместимости
+	assert.Equal(t, int64(3), result.RoleId)
+	assert.Len(t, result.Roles, 2)
+	assert.Equal(t, "Developer", result.Roles[0].RoleName)
+}
+
+func TestUpdateEmployee_UnknownRole(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, false)
+
+	version := time.Now()
+	current := Entity{Id: 1, Name: "John Doe", PositionId: 1, DepartmentId: 1, RoleId: 1, UpdatedAt: version}
+	request := UpdateRequest{Id: 1, Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 99, Version: version}
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(current, nil)
+	mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(99)).Return(false, sql.ErrNoRows)
+
+	_, err := svc.UpdateEmployee(context.Background(), request)
+
+	var validationErr common.RequestValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	mockRepo.AssertNotCalled(t, "EndActiveAssignmentsTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUpdateEmployee_UnknownPosition(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, false)
+
+	version := time.Now()
+	current := Entity{Id: 1, Name: "John Doe", PositionId: 1, DepartmentId: 1, RoleId: 1, UpdatedAt: version}
+	request := UpdateRequest{Id: 1, Name: "John Doe", Email: "john@example.com", PositionId: 99, DepartmentId: 1, RoleId: 1, Version: version}
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(current, nil)
+	mockRepo.On("PositionExistsTx", mock.Anything, tx, int64(99)).Return(false, nil)
+
+	_, err := svc.UpdateEmployee(context.Background(), request)
+
+	var validationErr common.RequestValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	mockRepo.AssertNotCalled(t, "FindPositionRoleIdsTx", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAssignRole(t *testing.T) {
+	validFrom := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	validTo := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+
+		request := AssignRoleRequest{EmployeeId: 1, RoleId: 2, ValidFrom: &validFrom, ValidTo: &validTo}
+		created := RoleAssignmentEntity{Id: 10, EmployeeId: 1, RoleId: 2, RoleName: "Auditor", ValidFrom: validFrom, ValidTo: &validTo}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1}, nil)
+		mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(2)).Return(true, nil)
+		mockRepo.On("HasOverlappingAssignmentTx", mock.Anything, tx, int64(1), int64(2), &validFrom, &validTo).Return(false, nil)
+		mockRepo.On("AssignRoleTx", mock.Anything, tx, int64(1), int64(2), &validFrom, &validTo).Return(created, nil)
+		mockRepo.On("SyncLegacyRoleIdTx", mock.Anything, tx, int64(1)).Return(nil)
+
+		result, err := svc.AssignRole(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), result.Id)
+		assert.Equal(t, "Auditor", result.RoleName)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Overlapping period", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		request := AssignRoleRequest{EmployeeId: 1, RoleId: 2}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1}, nil)
+		mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(2)).Return(true, nil)
+		mockRepo.On("HasOverlappingAssignmentTx", mock.Anything, tx, int64(1), int64(2), (*time.Time)(nil), (*time.Time)(nil)).
+			Return(true, nil)
+
+		_, err := svc.AssignRole(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		mockRepo.AssertNotCalled(t, "AssignRoleTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Inactive role", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		request := AssignRoleRequest{EmployeeId: 1, RoleId: 2}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1}, nil)
+		mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(2)).Return(false, nil)
+
+		_, err := svc.AssignRole(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Contains(t, err.Error(), "inactive")
+		mockRepo.AssertNotCalled(t, "AssignRoleTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Segregation of duties violation", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		sod := &StubSodChecker{err: common.ConflictError{
+			Message: `role "Payment creator" conflicts with role "Payment approver" held by employee 1 under SoD rule "Payments" (id 1)`,
+		}}
+		svc := NewService(mockRepo, auditor, sod, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		request := AssignRoleRequest{EmployeeId: 1, RoleId: 2}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1}, nil)
+		mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(2)).Return(true, nil)
+		mockRepo.On("HasOverlappingAssignmentTx", mock.Anything, tx, int64(1), int64(2), (*time.Time)(nil), (*time.Time)(nil)).
+			Return(false, nil)
+		mockRepo.On("AssignRoleTx", mock.Anything, tx, int64(1), int64(2), (*time.Time)(nil), (*time.Time)(nil)).
+			Return(RoleAssignmentEntity{Id: 10, EmployeeId: 1, RoleId: 2}, nil)
+
+		_, err := svc.AssignRole(context.Background(), request)
+
+		// назначение откатывается вместе с транзакцией
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		assert.Equal(t, [][]int64{{2}}, sod.checked)
+		mockRepo.AssertNotCalled(t, "SyncLegacyRoleIdTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.Empty(t, auditor.events)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Period ends before it starts", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+
+		request := AssignRoleRequest{EmployeeId: 1, RoleId: 2, ValidFrom: &validTo, ValidTo: &validFrom}
+		mockValidator.On("Validate", request).Return(nil)
+
+		_, err := svc.AssignRole(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "BeginTransaction", mock.Anything)
+	})
+
+	t.Run("Unknown employee", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		request := AssignRoleRequest{EmployeeId: 404, RoleId: 2}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(404)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.AssignRole(context.Background(), request)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestRevokeRoleAssignment(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1}, nil)
+		mockRepo.On("RevokeRoleAssignmentTx", mock.Anything, tx, int64(1), int64(10)).Return(true, nil)
+		mockRepo.On("SyncLegacyRoleIdTx", mock.Anything, tx, int64(1)).Return(nil)
+
+		err := svc.RevokeRoleAssignment(context.Background(), 1, 10)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Nothing to revoke", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1}, nil)
+		mockRepo.On("RevokeRoleAssignmentTx", mock.Anything, tx, int64(1), int64(10)).Return(false, nil)
+
+		err := svc.RevokeRoleAssignment(context.Background(), 1, 10)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		mockRepo.AssertNotCalled(t, "SyncLegacyRoleIdTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Import(t *testing.T) {
+	request := CreateRequest{Name: "John Doe", Email: "john@example.com", PositionId: 1, DepartmentId: 1, RoleId: 3}
+	rows := []ImportRow{
+		{Line: 2, Request: CreateRequest{Name: request.Name, Email: request.Email, PositionId: 1, DepartmentId: 1}, Role: "Developer"},
+		{Line: 3, Request: CreateRequest{Name: "Jane Doe", Email: "jane@example.com", PositionId: 1, DepartmentId: 1, RoleId: 3}},
+		{Line: 4, Error: "column position_id: invalid number \"x\""},
+	}
+
+	// сервис с транзакцией, которая должна быть зафиксирована (commit) или откачена
+	setup := func(t *testing.T, commit bool) (*Service, *MockRepo, *MockValidator, *sqlx.Tx) {
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		sqlMock.ExpectBegin()
+		if commit {
+			sqlMock.ExpectCommit()
+		} else {
+			sqlMock.ExpectRollback()
+		}
+		tx, err := sqlx.NewDb(db, "postgres").Beginx()
+		assert.NoError(t, err)
+		t.Cleanup(func() {
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+			_ = db.Close()
+		})
+
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindRoleIdsByNamesTx", mock.Anything, tx, []string{"developer"}).
+			Return([]roleNameEntity{{Id: 3, Name: "developer"}}, nil)
+		mockValidator.On("Validate", mock.Anything).Return(nil)
+		mockRepo.On("SavepointTx", mock.Anything, tx, importSavepoint).Return(nil)
+		mockRepo.On("FindRoleStatusTx", mock.Anything, tx, int64(3)).Return(true, nil)
+
+		// первая строка создаётся, вторая - сотрудник с таким email уже есть
+		mockRepo.On("EmailExistsTx", mock.Anything, tx, "john@example.com").Return(false, nil)
+		mockRepo.On("FindByNameTx", mock.Anything, tx, "John Doe").Return(false, nil)
+		mockRepo.On("DepartmentExistsTx", mock.Anything, tx, int64(1)).Return(true, nil)
+		mockRepo.On("PositionExistsTx", mock.Anything, tx, int64(1)).Return(true, nil)
+		mockRepo.On("SaveTx", mock.Anything, tx, request.ToEntity()).Return(int64(10), nil)
+		mockRepo.On("SaveStatusHistoryTx", mock.Anything, tx, mock.Anything).Return(nil)
+		mockRepo.On("FindPositionRoleIdsTx", mock.Anything, tx, int64(1)).Return([]int64{}, nil)
+		mockRepo.On("ReleaseSavepointTx", mock.Anything, tx, importSavepoint).Return(nil)
+		mockRepo.On("EmailExistsTx", mock.Anything, tx, "jane@example.com").Return(true, nil)
+		mockRepo.On("RollbackToSavepointTx", mock.Anything, tx, importSavepoint).Return(nil)
+
+		return NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger()), mockRepo, mockValidator, tx
+	}
+
+	statuses := func(report ImportReport) []string {
+		var result []string
+		for _, row := range report.Rows {
+			result = append(result, row.Status)
+		}
+		return result
+	}
+
+	t.Run("Best effort commits valid rows", func(t *testing.T) {
+		svc, mockRepo, _, _ := setup(t, true)
+
+		report, err := svc.Import(context.Background(), ImportRequest{Rows: rows, BestEffort: true})
+
+		assert.NoError(t, err)
+		assert.True(t, report.Committed)
+		assert.Equal(t, []string{ImportCreated, ImportSkipped, ImportFailed}, statuses(report))
+		assert.Equal(t, int64(10), report.Rows[0].Id)
+		assert.Equal(t, "employee with email jane@example.com already exists", report.Rows[1].Message)
+		assert.Equal(t, []int{1, 1, 1}, []int{report.Created, report.Skipped, report.Failed})
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("All or nothing is rolled back on a failed row", func(t *testing.T) {
+		svc, _, _, _ := setup(t, false)
+
+		report, err := svc.Import(context.Background(), ImportRequest{Rows: rows})
+
+		assert.NoError(t, err)
+		assert.False(t, report.Committed)
+		assert.Equal(t, []string{ImportCreated, ImportSkipped, ImportFailed}, statuses(report))
+		assert.Zero(t, report.Rows[0].Id)
+	})
+
+	t.Run("Dry run is rolled back", func(t *testing.T) {
+		svc, _, _, _ := setup(t, false)
+
+		report, err := svc.Import(context.Background(), ImportRequest{Rows: rows[:2], DryRun: true})
+
+		assert.NoError(t, err)
+		assert.False(t, report.Committed)
+		assert.Equal(t, []string{ImportCreated, ImportSkipped}, statuses(report))
+	})
+
+	t.Run("Unknown role name and validation errors fail the row", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() { _ = db.Close() }()
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+		tx, err := sqlx.NewDb(db, "postgres").Beginx()
+		assert.NoError(t, err)
+
+		invalid := CreateRequest{Name: "A", Email: "a@example.com", PositionId: 1, DepartmentId: 1, RoleId: 3}
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindRoleIdsByNamesTx", mock.Anything, tx, []string{"tester"}).Return([]roleNameEntity{}, nil)
+		mockValidator.On("Validate", invalid).Return(idmvalidator.ValidationErrors{
+			Errors: []idmvalidator.ValidationError{{Field: "Name", Tag: "min"}},
+		})
+
+		report, err := svc.Import(context.Background(), ImportRequest{Rows: []ImportRow{
+			{Line: 2, Request: CreateRequest{Name: "Jane"}, Role: "Tester"},
+			{Line: 3, Request: invalid},
+		}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "role Tester does not exist", report.Rows[0].Message)
+		assert.Equal(t, "Name", report.Rows[1].Errors[0].Field)
+		assert.Equal(t, 2, report.Failed)
+		mockRepo.AssertNotCalled(t, "SavepointTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Empty file", func(t *testing.T) {
+		svc := NewService(new(MockRepo), &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+
+		_, err := svc.Import(context.Background(), ImportRequest{})
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+	})
+}
+
+func TestRestore(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Hour)
+	deleted := Entity{Id: 1, Name: "John Doe", Email: "john@example.com", DeletedAt: &deletedAt}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, &StubSodChecker{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindDeletedByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(deleted, nil)
+		mockRepo.On("FindByNameTx", mock.Anything, tx, "John Doe").Return(false, nil)
+		mockRepo.On("EmailExistsTx", mock.Anything, tx, "john@example.com").Return(false, nil)
+		mockRepo.On("RestoreTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Name: "John Doe", Email: "john@example.com"}, nil)
+
+		response, err := svc.Restore(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Nil(t, response.DeletedAt)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionRestore, auditor.events[0].Action)
+			assert.NotNil(t, auditor.events[0].Before.(Response).DeletedAt)
+		}
+	})
+
+	t.Run("Not deleted", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindDeletedByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.Restore(context.Background(), 1)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Email taken by active employee", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindDeletedByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(deleted, nil)
+		mockRepo.On("FindByNameTx", mock.Anything, tx, "John Doe").Return(false, nil)
+		mockRepo.On("EmailExistsTx", mock.Anything, tx, "john@example.com").Return(true, nil)
+
+		_, err := svc.Restore(context.Background(), 1)
+
+		var alreadyExistsErr common.AlreadyExistsError
+		assert.True(t, errors.As(err, &alreadyExistsErr))
+		mockRepo.AssertNotCalled(t, "RestoreTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestPurge(t *testing.T) {
+	t.Run("Purges and records audit events", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		request := PurgeRequest{RetentionDays: 30}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("PurgeDeletedTx", mock.Anything, tx, mock.MatchedBy(func(deletedBefore time.Time) bool {
+			return deletedBefore.Before(time.Now().AddDate(0, 0, -29))
+		})).Return([]Entity{{Id: 1}, {Id: 2}}, nil)
+
+		response, err := svc.Purge(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1, 2}, response.PurgedIds)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 2) {
+			assert.Equal(t, audit.ActionPurge, auditor.events[0].Action)
+		}
+	})
+
+	t.Run("Validation error", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := PurgeRequest{RetentionDays: 0}
+
+		mockValidator.On("Validate", request).Return(errors.New("retention_days is required"))
+
+		_, err := svc.Purge(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "BeginTransaction", mock.Anything)
+	})
+}
+
+func TestTransitions(t *testing.T) {
+	t.Run("Terminate revokes role assignments", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		request := TransitionRequest{EmployeeId: 1, Reason: "Resigned"}
+		actorCtx := common.WithActor(context.Background(), common.Actor{Subject: "sub-1", Username: "hr"})
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, RoleId: 2, Status: StatusActive}, nil)
+		mockRepo.On("RevokeAllAssignmentsTx", mock.Anything, tx, int64(1)).Return([]int64{10, 11}, nil)
+		mockRepo.On("SyncLegacyRoleIdTx", mock.Anything, tx, int64(1)).Return(nil)
+		mockRepo.On("UpdateLifecycleTx", mock.Anything, tx, Entity{Id: 1, RoleId: 2, Status: StatusTerminated}).
+			Return(Entity{Id: 1, Status: StatusTerminated}, nil)
+		mockRepo.On("SaveStatusHistoryTx", mock.Anything, tx, mock.MatchedBy(func(history StatusHistoryEntity) bool {
+			return history.Transition == TransitionTerminate &&
+				*history.FromStatus == StatusActive && history.ToStatus == StatusTerminated &&
+				*history.Reason == "Resigned" && *history.ActorSub == "sub-1" &&
+				*history.Details == `{"revoked_assignment_ids":[10,11]}`
+		})).Return(nil)
+
+		response, err := svc.Terminate(actorCtx, request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusTerminated, response.Status)
+		assert.Equal(t, int64(0), response.RoleId)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 3) {
+			assert.Equal(t, audit.ActionRevokeRole, auditor.events[0].Action)
+			assert.Equal(t, audit.ActionRevokeRole, auditor.events[1].Action)
+			assert.Equal(t, audit.ActionTransition, auditor.events[2].Action)
+		}
+	})
+
+	t.Run("Illegal transition", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := TransitionRequest{EmployeeId: 1, Reason: "Back from leave"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Status: StatusActive}, nil)
+
+		_, err := svc.Resume(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		mockRepo.AssertNotCalled(t, "UpdateLifecycleTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Transfer records changed fields", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		departmentId := int64(2)
+		request := TransferRequest{EmployeeId: 1, DepartmentId: &departmentId, Reason: "Reorganization"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Position: "Developer", DepartmentId: 1, Status: StatusSuspended}, nil)
+		mockRepo.On("DepartmentExistsTx", mock.Anything, tx, int64(2)).Return(true, nil)
+		mockRepo.On("UpdateLifecycleTx", mock.Anything, tx,
+			Entity{Id: 1, Position: "Developer", DepartmentId: 2, Status: StatusSuspended}).
+			Return(Entity{Id: 1, Position: "Developer", DepartmentId: 2, Department: "Sales", Status: StatusSuspended}, nil)
+		mockRepo.On("SaveStatusHistoryTx", mock.Anything, tx, mock.MatchedBy(func(history StatusHistoryEntity) bool {
+			return *history.FromStatus == StatusSuspended && history.ToStatus == StatusSuspended &&
+				*history.Details == `{"department_id":{"from":1,"to":2}}`
+		})).Return(nil)
+
+		response, err := svc.Transfer(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), response.DepartmentId)
+		assert.Equal(t, "Sales", response.Department)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Transfer to another position assigns its default roles", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		positionId := int64(5)
+		request := TransferRequest{EmployeeId: 1, PositionId: &positionId, Reason: "Promotion"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, PositionId: 1, DepartmentId: 1, RoleId: 2, Status: StatusActive}, nil)
+		mockRepo.On("PositionExistsTx", mock.Anything, tx, int64(5)).Return(true, nil)
+		mockRepo.On("FindPositionRoleIdsTx", mock.Anything, tx, int64(5)).Return([]int64{4}, nil)
+		mockRepo.On("HasOverlappingAssignmentTx", mock.Anything, tx, int64(1), int64(4), (*time.Time)(nil), (*time.Time)(nil)).
+			Return(false, nil)
+		mockRepo.On("AssignRoleTx", mock.Anything, tx, int64(1), int64(4), (*time.Time)(nil), (*time.Time)(nil)).
+			Return(RoleAssignmentEntity{Id: 9, EmployeeId: 1, RoleId: 4, Active: true}, nil)
+		mockRepo.On("SyncLegacyRoleIdTx", mock.Anything, tx, int64(1)).Return(nil)
+		mockRepo.On("UpdateLifecycleTx", mock.Anything, tx,
+			Entity{Id: 1, PositionId: 5, DepartmentId: 1, RoleId: 2, Status: StatusActive}).
+			Return(Entity{Id: 1, PositionId: 5, Position: "Team Lead", DepartmentId: 1, RoleId: 4, Status: StatusActive}, nil)
+		mockRepo.On("SaveStatusHistoryTx", mock.Anything, tx, mock.MatchedBy(func(history StatusHistoryEntity) bool {
+			return *history.Details == `{"position_id":{"from":1,"to":5}}`
+		})).Return(nil)
+
+		response, err := svc.Transfer(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Team Lead", response.Position)
+		// роли прежней должности не отзываются
+		mockRepo.AssertNotCalled(t, "EndActiveAssignmentsTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 2) {
+			assert.Equal(t, audit.ActionAssignRole, auditor.events[0].Action)
+			assert.Equal(t, audit.ActionTransition, auditor.events[1].Action)
+		}
+	})
+
+	t.Run("Transfer without changes", func(t *testing.T) {
+		mockValidator := new(MockValidator)
+		svc := NewService(new(MockRepo), &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := TransferRequest{EmployeeId: 1, Reason: "Reorganization"}
+		mockValidator.On("Validate", request).Return(nil)
+
+		_, err := svc.Transfer(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+	})
+
+	t.Run("Assign role to terminated employee", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := AssignRoleRequest{EmployeeId: 1, RoleId: 2}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Status: StatusTerminated}, nil)
+
+		_, err := svc.AssignRole(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		mockRepo.AssertNotCalled(t, "AssignRoleTx",
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestNextStatus(t *testing.T) {
+	tests := []struct {
+		transition string
+		from       string
+		expected   string
+		allowed    bool
+	}{
+		{TransitionHire, StatusPending, StatusActive, true},
+		{TransitionHire, StatusActive, "", false},
+		{TransitionSuspend, StatusActive, StatusSuspended, true},
+		{TransitionSuspend, StatusPending, "", false},
+		{TransitionResume, StatusSuspended, StatusActive, true},
+		{TransitionTransfer, StatusActive, StatusActive, true},
+		{TransitionTransfer, StatusPending, "", false},
+		{TransitionTerminate, StatusPending, StatusTerminated, true},
+		{TransitionTerminate, StatusTerminated, "", false},
+		{TransitionResume, StatusTerminated, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.transition+" from "+tt.from, func(t *testing.T) {
+			status, err := nextStatus(tt.transition, tt.from)
+			if tt.allowed {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, status)
+			} else {
+				var conflictErr common.ConflictError
+				assert.True(t, errors.As(err, &conflictErr))
+			}
+		})
+	}
+}
+
+func TestScheduledChanges(t *testing.T) {
+	departmentId := int64(2)
+	reason := "Promotion"
+
+	t.Run("Schedule in the past", func(t *testing.T) {
+		mockValidator := new(MockValidator)
+		svc := NewService(new(MockRepo), &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := ScheduledChangeRequest{
+			EmployeeId: 1, DepartmentId: &departmentId, Reason: reason, EffectiveAt: time.Now().Add(-time.Hour),
+		}
+		mockValidator.On("Validate", request).Return(nil)
+
+		_, err := svc.ScheduleChange(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+	})
+
+	t.Run("Schedule records creator", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		effectiveAt := time.Now().Add(24 * time.Hour)
+		request := ScheduledChangeRequest{EmployeeId: 1, DepartmentId: &departmentId, Reason: reason, EffectiveAt: effectiveAt}
+		actorCtx := common.WithActor(context.Background(), common.Actor{Subject: "sub-1", Username: "hr"})
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).Return(Entity{Id: 1, Status: StatusActive}, nil)
+		mockRepo.On("DepartmentExistsTx", mock.Anything, tx, int64(2)).Return(true, nil)
+		mockRepo.On("SaveScheduledChangeTx", mock.Anything, tx, mock.MatchedBy(func(change ScheduledChangeEntity) bool {
+			return change.EmployeeId == 1 && *change.DepartmentId == departmentId &&
+				change.EffectiveAt.Equal(effectiveAt) && *change.CreatedBySub == "sub-1"
+		})).Return(ScheduledChangeEntity{Id: 7, EmployeeId: 1, Status: ChangeStatusPending}, nil)
+
+		response, err := svc.ScheduleChange(actorCtx, request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), response.Id)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Cancel applied change", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("FindScheduledChangeForUpdateTx", mock.Anything, tx, int64(7)).
+			Return(ScheduledChangeEntity{Id: 7, Status: ChangeStatusApplied}, nil)
+
+		_, err := svc.CancelScheduledChange(context.Background(), 7)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Apply due change", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, &StubSodChecker{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		creator := "sub-1"
+		change := ScheduledChangeEntity{
+			Id: 7, EmployeeId: 1, DepartmentId: &departmentId, Reason: &reason,
+			Status: ChangeStatusPending, CreatedBySub: &creator,
+		}
+
+		mockRepo.On("FindDueScheduledChangeIds", mock.Anything, dueChangesBatchSize).Return([]int64{7}, nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("LockDueScheduledChangeTx", mock.Anything, tx, int64(7)).Return(change, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, DepartmentId: 1, Status: StatusActive}, nil)
+		mockRepo.On("UpdateLifecycleTx", mock.Anything, tx, Entity{Id: 1, DepartmentId: 2, Status: StatusActive}).
+			Return(Entity{Id: 1, DepartmentId: 2, Department: "Sales", Status: StatusActive}, nil)
+		mockRepo.On("SaveStatusHistoryTx", mock.Anything, tx, mock.MatchedBy(func(history StatusHistoryEntity) bool {
+			return history.Transition == TransitionTransfer && *history.Reason == reason && *history.ActorSub == creator
+		})).Return(nil)
+		mockRepo.On("UpdateScheduledChangeTx", mock.Anything, tx, mock.MatchedBy(func(updated ScheduledChangeEntity) bool {
+			return updated.Status == ChangeStatusApplied && updated.AppliedAt != nil
+		})).Return(ScheduledChangeEntity{}, nil)
+
+		applied, err := svc.ApplyDueChanges(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, applied)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionUpdate, auditor.events[0].Action)
+		}
+	})
+
+	t.Run("Change of terminated employee is marked failed", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		change := ScheduledChangeEntity{Id: 7, EmployeeId: 1, DepartmentId: &departmentId, Status: ChangeStatusPending}
+
+		mockRepo.On("FindDueScheduledChangeIds", mock.Anything, dueChangesBatchSize).Return([]int64{7}, nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("LockDueScheduledChangeTx", mock.Anything, tx, int64(7)).Return(change, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Status: StatusTerminated}, nil)
+		mockRepo.On("MarkScheduledChangeFailed", mock.Anything, int64(7), "employee with id 1 is terminated").Return(nil)
+
+		applied, err := svc.ApplyDueChanges(context.Background())
+
+		assert.NoError(t, err)
+		assert.Zero(t, applied)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestSetManager(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		request := SetManagerRequest{EmployeeId: 1, ManagerId: 2}
+		managerId := int64(2)
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", mock.Anything, tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Status: StatusActive}, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(2)).
+			Return(Entity{Id: 2, Status: StatusActive}, nil)
+		mockRepo.On("IsInManagementChainTx", mock.Anything, tx, int64(2), int64(1)).Return(false, nil)
+		mockRepo.On("UpdateManagerTx", mock.Anything, tx, int64(1), &managerId).
+			Return(Entity{Id: 1, Status: StatusActive, ManagerId: &managerId}, nil)
+
+		response, err := svc.SetManager(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &managerId, response.ManagerId)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionUpdate, auditor.events[0].Action)
+		}
+	})
+
+	t.Run("Cycle is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := SetManagerRequest{EmployeeId: 1, ManagerId: 3}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", mock.Anything, tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Status: StatusActive}, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(3)).
+			Return(Entity{Id: 3, Status: StatusActive}, nil)
+		mockRepo.On("IsInManagementChainTx", mock.Anything, tx, int64(3), int64(1)).Return(true, nil)
+
+		_, err := svc.SetManager(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		mockRepo.AssertNotCalled(t, "UpdateManagerTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown manager", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := SetManagerRequest{EmployeeId: 1, ManagerId: 99}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", mock.Anything, tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Status: StatusActive}, nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(99)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.SetManager(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Remove manager", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		request := SetManagerRequest{EmployeeId: 1}
+		managerId := int64(2)
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction", mock.Anything).Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", mock.Anything, tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", mock.Anything, tx, int64(1)).
+			Return(Entity{Id: 1, Status: StatusActive, ManagerId: &managerId}, nil)
+		mockRepo.On("UpdateManagerTx", mock.Anything, tx, int64(1), (*int64)(nil)).
+			Return(Entity{Id: 1, Status: StatusActive}, nil)
+
+		response, err := svc.SetManager(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Nil(t, response.ManagerId)
+		mockRepo.AssertNotCalled(t, "IsInManagementChainTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestBuildOrgChart(t *testing.T) {
+	ceo, cto, unknown := int64(1), int64(2), int64(42)
+	chart := buildOrgChart([]Entity{
+		{Id: 1, Name: "CEO"},
+		{Id: 2, Name: "CTO", ManagerId: &ceo},
+		{Id: 3, Name: "Developer", ManagerId: &cto},
+		{Id: 4, Name: "Designer", ManagerId: &cto},
+		{Id: 5, Name: "Contractor", ManagerId: &unknown},
+	})
+
+	if assert.Len(t, chart, 2) {
+		assert.Equal(t, "CEO", chart[0].Name)
+		assert.Equal(t, "Contractor", chart[1].Name)
+		assert.Empty(t, chart[1].Reports)
+		if assert.Len(t, chart[0].Reports, 1) {
+			cto := chart[0].Reports[0]
+			assert.Equal(t, "CTO", cto.Name)
+			if assert.Len(t, cto.Reports, 2) {
+				assert.Equal(t, "Developer", cto.Reports[0].Name)
+				assert.Equal(t, "Designer", cto.Reports[1].Name)
+			}
+		}
+	}
+}
+
+func TestFindWithPagination_Filters(t *testing.T) {
+	t.Run("Page and count use the same filter", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := PageRequest{
+			PageNumber: 2, PageSize: 10,
+			Email: "@example.com", PositionIds: []int64{3}, RoleIds: []int64{5},
+			Statuses: []string{StatusActive}, Sort: "name,-created_at",
+		}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("FindWithPagination", mock.Anything, 10, 10, request).Return([]Entity{{Id: 11}}, nil)
+		mockRepo.On("CountWithFilter", mock.Anything, request).Return(int64(11), nil)
+
+		response, err := svc.FindWithPagination(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), response.TotalCount)
+		assert.Equal(t, 2, response.TotalPages)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unknown sort field", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		request := PageRequest{PageNumber: 1, PageSize: 10, Sort: "name,password"}
+
+		mockValidator.On("Validate", request).Return(nil)
+
+		_, err := svc.FindWithPagination(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Contains(t, validationErr.Message, "password")
+		mockRepo.AssertNotCalled(t, "FindWithPagination", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Empty created_at range", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, &StubSodChecker{}, mockValidator, createTestLogger())
+		from := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		to := from.Add(-time.Hour)
+		request := PageRequest{PageNumber: 1, PageSize: 10, CreatedFrom: &from, CreatedTo: &to}
+
+		mockValidator.On("Validate", request).Return(nil)
+
+		_, err := svc.FindWithPagination(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "CountWithFilter", mock.Anything, mock.Anything)
+	})
+}
+
+func TestPageFilterCondition(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	condition, args := pageFilterCondition(PageRequest{
+		TextFilter:  "jo",
+		Email:       " @example.com ",
+		PositionIds: []int64{3},
+		Statuses:    []string{StatusActive, StatusSuspended},
+		CreatedFrom: &from,
+	}, []any{10, 0})
+
+	// короткий textFilter не участвует в отборе, номера параметров идут после limit и offset
+	assert.NotContains(t, condition, "employee.name")
+	assert.Contains(t, condition, "employee.email ILIKE $3")
+	assert.Contains(t, condition, "employee.position_id = ANY ($4)")
+	assert.Contains(t, condition, "employee.status = ANY ($5)")
+	assert.Contains(t, condition, "employee.created_at >= $6")
+	assert.Len(t, args, 6)
+	assert.Equal(t, "%@example.com%", args[2])
+}
+
+func TestParseSort(t *testing.T) {
+	fields, err := parseSort("name, -created_at")
+	assert.NoError(t, err)
+	assert.Equal(t, "employee.name ASC, employee.created_at DESC, employee.id ASC", pageOrderBy(fields))
+
+	fields, err = parseSort("-id,name")
+	assert.NoError(t, err)
+	assert.Equal(t, "employee.id DESC, employee.name ASC", pageOrderBy(fields))
+
+	fields, err = parseSort("")
+	assert.NoError(t, err)
+	assert.Equal(t, "employee.id ASC", pageOrderBy(fields))
+
+	for _, sort := range []string{"name,name", "name;drop", "name,", "-"} {
+		_, err = parseSort(sort)
+		assert.Error(t, err, sort)
+	}
+}
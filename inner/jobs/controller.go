@@ -0,0 +1,346 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// режимы импорта: любая ошибочная строка отменяет импорт или создаются все корректные строки
+const (
+	importModeAllOrNothing = "all_or_nothing"
+	importModeBestEffort   = "best_effort"
+)
+
+type Controller struct {
+	server     *web.Server
+	jobService Svc
+	logger     *common.Logger
+}
+
+// интерфейс сервиса jobs.Service
+type Svc interface {
+	Submit(ctx context.Context, request SubmitRequest) (SubmitResponse, error)
+	FindById(ctx context.Context, id int64, all bool) (Response, error)
+	FindResultFile(ctx context.Context, id int64, all bool) (ResultFile, error)
+	Cancel(ctx context.Context, id int64, all bool) (Response, error)
+}
+
+func NewController(server *web.Server, jobService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:     server,
+		jobService: jobService,
+		logger:     logger,
+	}
+}
+
+// функция для регистрации маршрутов
+func (c *Controller) RegisterRoutes() {
+	c.logger.Info("Registering job routes")
+	// полный маршрут получится "/api/v1/jobs"
+	// статические маршруты регистрируются раньше "/jobs/:id", иначе он их перехватит
+	c.server.GroupApiV1User.Post("/jobs/employees/export", c.SubmitEmployeeExport)
+	c.server.GroupApiV1User.Post("/jobs/roles/export", c.SubmitRoleExport)
+	c.server.GroupApiV1User.Get("/jobs/:id", c.FindJobById)
+	c.server.GroupApiV1User.Get("/jobs/:id/result", c.DownloadJobResult)
+	c.server.GroupApiV1User.Post("/jobs/:id/cancel", c.CancelJob)
+	// изменяющие задания доступны только администраторам: "/api/v1/admin/jobs/..."
+	c.server.GroupApiV1Admin.Post("/jobs/employees/import", c.SubmitEmployeeImport)
+	c.server.GroupApiV1Admin.Post("/jobs/employees/delete", c.SubmitEmployeeDelete)
+	c.server.GroupApiV1Admin.Post("/jobs/roles/delete", c.SubmitRoleDelete)
+	c.logger.Info("Job routes registered successfully")
+}
+
+// SubmitEmployeeImport ставит в очередь импорт сотрудников из файла
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Submit employee import job
+//	@Description	Queue the import of a CSV or JSON Lines file in the format of the synchronous import. The import report is the result of the job
+//	@Tags			jobs
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			mode	query		string					false	"Import mode"	Enums(all_or_nothing, best_effort)	default(all_or_nothing)
+//	@Param			dryRun	query		bool					false	"Validate rows without creating employees"
+//	@Success		202		{object}	JobSubmitResponse		"Queued job"
+//	@Failure		400		{object}	common.Response[any]	"Invalid parameters or empty file"
+//	@Failure		415		{object}	common.Response[any]	"Unsupported content type"
+//	@Failure		500		{object}	common.Response[any]	"Error when creating the job"
+//	@Router			/admin/jobs/employees/import [post]
+func (c *Controller) SubmitEmployeeImport(ctx *fiber.Ctx) error {
+	c.logger.Info("Received employee import job request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	format := employee.ImportFormat(ctx.Get(fiber.HeaderContentType))
+	if format == "" {
+		return common.ErrResponse(ctx, fiber.StatusUnsupportedMediaType,
+			"Content type must be text/csv or application/x-ndjson")
+	}
+	mode := ctx.Query("mode", importModeAllOrNothing)
+	if mode != importModeAllOrNothing && mode != importModeBestEffort {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid mode parameter")
+	}
+	if len(ctx.Body()) == 0 {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Import file is empty")
+	}
+
+	return c.submit(ctx, SubmitRequest{
+		Type: TypeEmployeeImport,
+		Params: ImportParams{
+			Format:     format,
+			DryRun:     ctx.QueryBool("dryRun"),
+			BestEffort: mode == importModeBestEffort,
+		},
+		// тело запроса принадлежит fiber и переиспользуется после ответа
+		Input: bytes.Clone(ctx.Body()),
+	})
+}
+
+// SubmitEmployeeExport ставит в очередь выгрузку сотрудников
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Submit employee export job
+//	@Description	Queue the export of employees matching the filters of the page endpoint. The file is downloaded from /jobs/{id}/result
+//	@Tags			jobs
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		JobEmployeeExportRequest	true	"Export format and filters"
+//	@Success		202		{object}	JobSubmitResponse			"Queued job"
+//	@Failure		400		{object}	common.Response[any]		"Invalid request"
+//	@Failure		500		{object}	common.Response[any]		"Error when creating the job"
+//	@Router			/jobs/employees/export [post]
+func (c *Controller) SubmitEmployeeExport(ctx *fiber.Ctx) error {
+	c.logger.Info("Received employee export job request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request EmployeeExportRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	return c.submit(ctx, SubmitRequest{Type: TypeEmployeeExport, Params: request})
+}
+
+// SubmitRoleExport ставит в очередь выгрузку ролей
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Submit role export job
+//	@Description	Queue the export of all roles. The file is downloaded from /jobs/{id}/result
+//	@Tags			jobs
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		JobRoleExportRequest	true	"Export format and sort"
+//	@Success		202		{object}	JobSubmitResponse		"Queued job"
+//	@Failure		400		{object}	common.Response[any]	"Invalid request"
+//	@Failure		500		{object}	common.Response[any]	"Error when creating the job"
+//	@Router			/jobs/roles/export [post]
+func (c *Controller) SubmitRoleExport(ctx *fiber.Ctx) error {
+	c.logger.Info("Received role export job request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request RoleExportRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	return c.submit(ctx, SubmitRequest{Type: TypeRoleExport, Params: request})
+}
+
+// SubmitEmployeeDelete ставит в очередь удаление сотрудников по списку id
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Submit employee delete job
+//	@Description	Queue the deletion of employees by IDs in a single transaction
+//	@Tags			jobs
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		JobDeleteRequest		true	"Employee IDs"
+//	@Success		202		{object}	JobSubmitResponse		"Queued job"
+//	@Failure		400		{object}	common.Response[any]	"Invalid request"
+//	@Failure		500		{object}	common.Response[any]	"Error when creating the job"
+//	@Router			/admin/jobs/employees/delete [post]
+func (c *Controller) SubmitEmployeeDelete(ctx *fiber.Ctx) error {
+	return c.submitDelete(ctx, TypeEmployeeDelete)
+}
+
+// SubmitRoleDelete ставит в очередь удаление ролей по списку id
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Submit role delete job
+//	@Description	Queue the deletion of roles by IDs in a single transaction. Roles with dependents are not deleted
+//	@Tags			jobs
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		JobDeleteRequest		true	"Role IDs"
+//	@Success		202		{object}	JobSubmitResponse		"Queued job"
+//	@Failure		400		{object}	common.Response[any]	"Invalid request"
+//	@Failure		500		{object}	common.Response[any]	"Error when creating the job"
+//	@Router			/admin/jobs/roles/delete [post]
+func (c *Controller) SubmitRoleDelete(ctx *fiber.Ctx) error {
+	return c.submitDelete(ctx, TypeRoleDelete)
+}
+
+func (c *Controller) submitDelete(ctx *fiber.Ctx, jobType string) error {
+	c.logger.Info("Received delete job request",
+		zap.String("type", jobType),
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request DeleteRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	return c.submit(ctx, SubmitRequest{Type: jobType, Params: request})
+}
+
+// ставит задание в очередь и отвечает 202 с id задания
+func (c *Controller) submit(ctx *fiber.Ctx, request SubmitRequest) error {
+	response, err := c.jobService.Submit(ctx.UserContext(), request)
+	if err != nil {
+		var validationErr common.RequestValidationError
+		if errors.As(err, &validationErr) {
+			if validationErr.Data != nil {
+				return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message, validationErr.Data)
+			}
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+		}
+		c.logger.Error("Failed to submit job",
+			zap.String("type", request.Type),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when creating the job")
+	}
+
+	ctx.Location(fmt.Sprintf("/api/v1/jobs/%d", response.Id))
+	ctx.Status(fiber.StatusAccepted)
+	return common.OkResponse(ctx, response)
+}
+
+// FindJobById получает состояние задания
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get job
+//	@Description	Status, progress and result of a job. Users see only their own jobs, administrators see all jobs
+//	@Tags			jobs
+//	@Produce		json
+//	@Param			id	path		int						true	"Job ID"
+//	@Success		200	{object}	JobResponse				"Job"
+//	@Failure		400	{object}	common.Response[any]	"Invalid job ID"
+//	@Failure		404	{object}	common.Response[any]	"Job not found"
+//	@Failure		500	{object}	common.Response[any]	"Error when getting the job"
+//	@Router			/jobs/{id} [get]
+func (c *Controller) FindJobById(ctx *fiber.Ctx) error {
+	id, err := c.parseJobId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid job ID format")
+	}
+
+	response, err := c.jobService.FindById(ctx.UserContext(), id, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleJobError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// DownloadJobResult отдаёт файл результата задания
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Download job result
+//	@Description	Download the file produced by a succeeded job, e.g. an export
+//	@Tags			jobs
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Param			id	path		int						true	"Job ID"
+//	@Success		200	{file}		file					"Result file"
+//	@Failure		400	{object}	common.Response[any]	"Invalid job ID"
+//	@Failure		404	{object}	common.Response[any]	"Job not found or has no result file"
+//	@Failure		500	{object}	common.Response[any]	"Error when getting the result"
+//	@Router			/jobs/{id}/result [get]
+func (c *Controller) DownloadJobResult(ctx *fiber.Ctx) error {
+	id, err := c.parseJobId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid job ID format")
+	}
+
+	file, err := c.jobService.FindResultFile(ctx.UserContext(), id, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleJobError(ctx, err, id)
+	}
+
+	ctx.Set(fiber.HeaderContentType, file.ContentType)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, file.Filename))
+	return ctx.Send(file.Content)
+}
+
+// CancelJob отменяет задание
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Cancel job
+//	@Description	A queued job is cancelled immediately, a running job stops at its next progress update. Work committed by the job before cancellation is kept
+//	@Tags			jobs
+//	@Produce		json
+//	@Param			id	path		int						true	"Job ID"
+//	@Success		200	{object}	JobResponse				"Job after the cancellation request"
+//	@Failure		400	{object}	common.Response[any]	"Invalid job ID"
+//	@Failure		404	{object}	common.Response[any]	"Job not found"
+//	@Failure		409	{object}	common.Response[any]	"Job is already finished"
+//	@Failure		500	{object}	common.Response[any]	"Error when cancelling the job"
+//	@Router			/jobs/{id}/cancel [post]
+func (c *Controller) CancelJob(ctx *fiber.Ctx) error {
+	c.logger.Info("Received cancel job request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseJobId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid job ID format")
+	}
+
+	response, err := c.jobService.Cancel(ctx.UserContext(), id, web.HasRole(ctx, web.IdmAdmin))
+	if err != nil {
+		return c.handleJobError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, response)
+}
+
+func (c *Controller) parseJobId(ctx *fiber.Ctx) (int64, error) {
+	return strconv.ParseInt(ctx.Params("id"), 10, 64)
+}
+
+func (c *Controller) handleJobError(ctx *fiber.Ctx, err error, id int64) error {
+	var notFoundErr common.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		return common.ErrResponse(ctx, fiber.StatusNotFound, notFoundErr.Message)
+	}
+	var conflictErr common.ConflictError
+	if errors.As(err, &conflictErr) {
+		return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message)
+	}
+	c.logger.Error("Job request failed",
+		zap.Int64("id", id),
+		zap.Error(err),
+		zap.String("ip", ctx.IP()))
+	return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when processing the job request")
+}
@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock для сервиса
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) Submit(ctx context.Context, request SubmitRequest) (SubmitResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(SubmitResponse), args.Error(1)
+}
+
+func (m *MockService) FindById(ctx context.Context, id int64, all bool) (Response, error) {
+	args := m.Called(id, all)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindResultFile(ctx context.Context, id int64, all bool) (ResultFile, error) {
+	args := m.Called(id, all)
+	return args.Get(0).(ResultFile), args.Error(1)
+}
+
+func (m *MockService) Cancel(ctx context.Context, id int64, all bool) (Response, error) {
+	args := m.Called(id, all)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+// Вспомогательная функция для создания Fiber app; токен с ролями roles подставляется вместо Keycloak
+func setupTestApp(roles ...string) (*fiber.App, *MockService) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{
+			RealmAccess:       web.RealmAccessClaims{Roles: roles},
+			PreferredUsername: "user",
+			RegisteredClaims:  jwt.RegisteredClaims{Subject: "sub-1"},
+		}})
+		return c.Next()
+	})
+	app.Use(web.ActorMiddleware())
+	mockService := &MockService{}
+
+	server := &web.Server{
+		GroupApiV1:      app.Group("/api/v1"),
+		GroupApiV1User:  app.Group("/api/v1"),
+		GroupApiV1Admin: app.Group("/api/v1/admin"),
+	}
+
+	controller := NewController(server, mockService, createTestLogger())
+	controller.RegisterRoutes()
+
+	return app, mockService
+}
+
+func TestController_SubmitEmployeeImport(t *testing.T) {
+	t.Run("Accepted", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		body := "name,email\nJohn,john@example.com\n"
+		mockService.On("Submit", SubmitRequest{
+			Type:   TypeEmployeeImport,
+			Params: ImportParams{Format: "csv", DryRun: true, BestEffort: true},
+			Input:  []byte(body),
+		}).Return(SubmitResponse{Id: 5, Status: StatusQueued}, nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/jobs/employees/import?mode=best_effort&dryRun=true", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "text/csv")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "/api/v1/jobs/5", resp.Header.Get("Location"))
+		var response common.Response[SubmitResponse]
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		assert.Equal(t, SubmitResponse{Id: 5, Status: StatusQueued}, response.Data)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Unsupported content type", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/jobs/employees/import", bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Submit", mock.Anything)
+	})
+
+	t.Run("Empty file", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/jobs/employees/import", nil)
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Submit", mock.Anything)
+	})
+}
+
+func TestController_SubmitRoleDelete_ValidationError(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmAdmin)
+	mockService.On("Submit", SubmitRequest{Type: TypeRoleDelete, Params: DeleteRequest{Ids: []int64{}}}).
+		Return(SubmitResponse{}, common.RequestValidationError{Message: "Invalid job request"})
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/jobs/roles/delete", bytes.NewBufferString(`{"ids":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestController_FindJobById(t *testing.T) {
+	t.Run("User sees own jobs", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("FindById", int64(5), false).Return(Response{Id: 5, Status: StatusRunning, Progress: 10, Total: 20}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/jobs/5", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var response common.Response[Response]
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		assert.Equal(t, 10, response.Data.Progress)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Admin sees all jobs", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmAdmin)
+		mockService.On("FindById", int64(5), true).Return(Response{}, common.NotFoundError{Message: "job with id 5 not found"})
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/jobs/5", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid id", func(t *testing.T) {
+		app, _ := setupTestApp(web.IdmUser)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/jobs/abc", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestController_DownloadJobResult(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmUser)
+	mockService.On("FindResultFile", int64(5), false).
+		Return(ResultFile{Content: []byte("id\n1\n"), ContentType: "text/csv; charset=utf-8", Filename: "roles.csv"}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/jobs/5/result", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="roles.csv"`, resp.Header.Get("Content-Disposition"))
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "id\n1\n", string(body))
+}
+
+func TestController_CancelJob_Finished(t *testing.T) {
+	app, mockService := setupTestApp(web.IdmUser)
+	mockService.On("Cancel", int64(5), false).Return(Response{}, common.ConflictError{Message: "job with id 5 is already finished"})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/jobs/5/cancel", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
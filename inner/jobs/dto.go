@@ -0,0 +1,182 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// состояния задания
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// типы заданий
+const (
+	TypeEmployeeImport = "employee_import"
+	TypeEmployeeExport = "employee_export"
+	TypeEmployeeDelete = "employee_delete"
+	TypeRoleExport     = "role_export"
+	TypeRoleDelete     = "role_delete"
+)
+
+type Entity struct {
+	Id                int64      `db:"id"`
+	Type              string     `db:"type"`
+	Status            string     `db:"status"`
+	Params            string     `db:"params"`
+	Input             []byte     `db:"input"`
+	Progress          int        `db:"progress"`
+	Total             int        `db:"total"`
+	Attempts          int        `db:"attempts"`
+	CancelRequested   bool       `db:"cancel_requested"`
+	Result            *string    `db:"result"`
+	ResultContentType *string    `db:"result_content_type"`
+	ResultFilename    *string    `db:"result_filename"`
+	Error             *string    `db:"error"`
+	CreatedBySub      *string    `db:"created_by_sub"`
+	CreatedByUsername *string    `db:"created_by_username"`
+	RequestId         *string    `db:"request_id"`
+	Ip                *string    `db:"ip"`
+	CreatedAt         time.Time  `db:"created_at"`
+	UpdatedAt         time.Time  `db:"updated_at"`
+	StartedAt         *time.Time `db:"started_at"`
+	HeartbeatAt       *time.Time `db:"heartbeat_at"`
+	FinishedAt        *time.Time `db:"finished_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	response := Response{
+		Id:                e.Id,
+		Type:              e.Type,
+		Status:            e.Status,
+		Progress:          e.Progress,
+		Total:             e.Total,
+		CancelRequested:   e.CancelRequested,
+		HasFile:           e.ResultContentType != nil,
+		Error:             e.Error,
+		CreatedByUsername: e.CreatedByUsername,
+		CreatedAt:         e.CreatedAt,
+		StartedAt:         e.StartedAt,
+		FinishedAt:        e.FinishedAt,
+	}
+	if e.Result != nil {
+		response.Result = json.RawMessage(*e.Result)
+	}
+	return response
+}
+
+// Response состояние задания. Result - итог задания (например, отчёт импорта),
+// файл результата (выгрузка) скачивается отдельно, если HasFile
+type Response struct {
+	Id                int64           `json:"id"`
+	Type              string          `json:"type"`
+	Status            string          `json:"status"`
+	Progress          int             `json:"progress"`
+	Total             int             `json:"total"`
+	CancelRequested   bool            `json:"cancel_requested"`
+	Result            json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	HasFile           bool            `json:"has_file"`
+	Error             *string         `json:"error,omitempty"`
+	CreatedByUsername *string         `json:"created_by_username"`
+	CreatedAt         time.Time       `json:"created_at"`
+	StartedAt         *time.Time      `json:"started_at"`
+	FinishedAt        *time.Time      `json:"finished_at"`
+} // @name JobResponse
+
+// SubmitResponse ответ на постановку задания в очередь
+type SubmitResponse struct {
+	Id     int64  `json:"id"`
+	Status string `json:"status"`
+} // @name JobSubmitResponse
+
+// SubmitRequest задание, которое ставится в очередь. Params сериализуются в JSON
+// и передаются обработчику вместе с Input
+type SubmitRequest struct {
+	Type   string
+	Params any
+	Input  []byte
+}
+
+// ResultFile файл результата задания
+type ResultFile struct {
+	Content     []byte `db:"result_file"`
+	ContentType string `db:"result_content_type"`
+	Filename    string `db:"result_filename"`
+}
+
+// Outcome итог выполнения задания для сохранения
+type Outcome struct {
+	Status   string
+	Progress int
+	Total    int
+	Result   *string
+	File     *ResultFile
+	Error    *string
+}
+
+// Job задание, переданное обработчику
+type Job struct {
+	Id     int64
+	Type   string
+	Params json.RawMessage
+	Input  []byte
+}
+
+// Result итог выполнения задания. Data сохраняется как JSON,
+// File (если задан) отдаётся для скачивания с типом ContentType и именем Filename
+type Result struct {
+	Data        any
+	File        []byte
+	ContentType string
+	Filename    string
+}
+
+// Progress сообщает пулу, сколько единиц работы выполнено из total; 0 - общий объём неизвестен.
+// Вызов дешёвый: в базу прогресс сохраняется периодически
+type Progress func(done, total int)
+
+// Handler выполняет задание одного типа. Обработчик должен прекращать работу при отмене ctx:
+// это отмена пользователем или остановка приложения
+type Handler func(ctx context.Context, job Job, progress Progress) (Result, error)
+
+// ImportParams параметры импорта сотрудников; файл импорта передаётся во входных данных задания
+type ImportParams struct {
+	Format     string `json:"format" validate:"oneof=csv jsonl"`
+	DryRun     bool   `json:"dry_run"`
+	BestEffort bool   `json:"best_effort"`
+}
+
+// EmployeeExportRequest запрос выгрузки сотрудников; фильтры те же, что у страницы сотрудников
+type EmployeeExportRequest struct {
+	Format        string     `json:"format" validate:"omitempty,oneof=csv jsonl" example:"csv"`
+	TextFilter    string     `json:"textFilter"`
+	Email         string     `json:"email" validate:"max=255"`
+	DepartmentIds []int64    `json:"departmentIds" validate:"max=50,dive,min=1"`
+	PositionIds   []int64    `json:"positionIds" validate:"max=50,dive,min=1"`
+	RoleIds       []int64    `json:"roleIds" validate:"max=50,dive,min=1"`
+	Statuses      []string   `json:"statuses" validate:"max=4,dive,oneof=pending active suspended terminated"`
+	CreatedFrom   *time.Time `json:"createdFrom"`
+	CreatedTo     *time.Time `json:"createdTo"`
+	Sort          string     `json:"sort" validate:"max=200" example:"name,-created_at"`
+} // @name JobEmployeeExportRequest
+
+// RoleExportRequest запрос выгрузки ролей в порядке сортировки вывода по курсору
+type RoleExportRequest struct {
+	Format string `json:"format" validate:"omitempty,oneof=csv jsonl" example:"csv"`
+	Sort   string `json:"sort" example:"name"`
+} // @name JobRoleExportRequest
+
+// DeleteRequest запрос массового удаления по списку id
+type DeleteRequest struct {
+	Ids []int64 `json:"ids" validate:"required,min=1,max=10000,dive,min=1"`
+} // @name JobDeleteRequest
+
+// DeleteResult итог массового удаления: число переданных id; несуществующие id пропускаются
+type DeleteResult struct {
+	Requested int `json:"requested"`
+}
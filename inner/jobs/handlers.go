@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+
+	"idm/inner/common"
+	"idm/inner/employee"
+	"idm/inner/role"
+)
+
+// интерфейс сервиса сотрудников для заданий (реализуется employee.Service)
+type EmployeeService interface {
+	Import(ctx context.Context, request employee.ImportRequest) (employee.ImportReport, error)
+	Export(ctx context.Context, filter employee.PageRequest) (iter.Seq2[employee.ExportRecord, error], error)
+	DeleteByIds(ctx context.Context, ids []int64) error
+}
+
+// интерфейс сервиса ролей для заданий (реализуется role.Service)
+type RoleService interface {
+	Export(ctx context.Context, sort string) (iter.Seq2[role.ExportRecord, error], error)
+	DeleteByIds(ctx context.Context, ids []int64) error
+}
+
+// ExportResult итог выгрузки; сам файл скачивается отдельно
+type ExportResult struct {
+	Rows int `json:"rows"`
+}
+
+// RegisterHandlers регистрирует в пуле обработчики заданий сотрудников и ролей
+func RegisterHandlers(pool *Pool, employees EmployeeService, roles RoleService) {
+	pool.Register(TypeEmployeeImport, employeeImportHandler(employees))
+	pool.Register(TypeEmployeeExport, employeeExportHandler(employees))
+	pool.Register(TypeEmployeeDelete, deleteHandler(employees.DeleteByIds))
+	pool.Register(TypeRoleExport, roleExportHandler(roles))
+	pool.Register(TypeRoleDelete, deleteHandler(roles.DeleteByIds))
+}
+
+// импорт сотрудников: файл разбирается при выполнении задания, отчёт импорта - итог задания
+func employeeImportHandler(employees EmployeeService) Handler {
+	return func(ctx context.Context, job Job, progress Progress) (Result, error) {
+		var params ImportParams
+		if err := json.Unmarshal(job.Params, &params); err != nil {
+			return Result{}, fmt.Errorf("invalid job params: %w", err)
+		}
+		rows, err := employee.ParseImport(params.Format, bytes.NewReader(job.Input))
+		if err != nil {
+			return Result{}, fmt.Errorf("invalid import file: %w", err)
+		}
+
+		progress(0, len(rows))
+		report, err := employees.Import(ctx, employee.ImportRequest{
+			Rows:       rows,
+			DryRun:     params.DryRun,
+			BestEffort: params.BestEffort,
+			Progress:   func(done int) { progress(done, len(rows)) },
+		})
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Data: report}, nil
+	}
+}
+
+func employeeExportHandler(employees EmployeeService) Handler {
+	return func(ctx context.Context, job Job, progress Progress) (Result, error) {
+		var request EmployeeExportRequest
+		if err := json.Unmarshal(job.Params, &request); err != nil {
+			return Result{}, fmt.Errorf("invalid job params: %w", err)
+		}
+		// формат проверяется до запроса: начатую выгрузку нужно пройти до конца, чтобы освободить соединение
+		format, err := common.ParseExportFormat(request.Format)
+		if err != nil {
+			return Result{}, err
+		}
+		records, err := employees.Export(ctx, employee.PageRequest{
+			TextFilter:    request.TextFilter,
+			Email:         request.Email,
+			DepartmentIds: request.DepartmentIds,
+			PositionIds:   request.PositionIds,
+			RoleIds:       request.RoleIds,
+			Statuses:      request.Statuses,
+			CreatedFrom:   request.CreatedFrom,
+			CreatedTo:     request.CreatedTo,
+			Sort:          request.Sort,
+		})
+		if err != nil {
+			return Result{}, err
+		}
+		return exportFile(format, "employees", employee.ExportHeader, records, progress)
+	}
+}
+
+func roleExportHandler(roles RoleService) Handler {
+	return func(ctx context.Context, job Job, progress Progress) (Result, error) {
+		var request RoleExportRequest
+		if err := json.Unmarshal(job.Params, &request); err != nil {
+			return Result{}, fmt.Errorf("invalid job params: %w", err)
+		}
+		format, err := common.ParseExportFormat(request.Format)
+		if err != nil {
+			return Result{}, err
+		}
+		records, err := roles.Export(ctx, request.Sort)
+		if err != nil {
+			return Result{}, err
+		}
+		return exportFile(format, "roles", role.ExportHeader, records, progress)
+	}
+}
+
+// массовое удаление выполняется одной транзакцией, поэтому прогресс известен только до и после неё
+func deleteHandler(deleteByIds func(ctx context.Context, ids []int64) error) Handler {
+	return func(ctx context.Context, job Job, progress Progress) (Result, error) {
+		var request DeleteRequest
+		if err := json.Unmarshal(job.Params, &request); err != nil {
+			return Result{}, fmt.Errorf("invalid job params: %w", err)
+		}
+
+		progress(0, len(request.Ids))
+		if err := deleteByIds(ctx, request.Ids); err != nil {
+			return Result{}, err
+		}
+		progress(len(request.Ids), len(request.Ids))
+		return Result{Data: DeleteResult{Requested: len(request.Ids)}}, nil
+	}
+}
+
+// записывает выгрузку в файл результата задания; прогресс - число выгруженных записей
+func exportFile[T common.ExportRecord](
+	format, filename string,
+	header []string,
+	records iter.Seq2[T, error],
+	progress Progress,
+) (Result, error) {
+	count := 0
+	counted := func(yield func(T, error) bool) {
+		for record, err := range records {
+			if err == nil {
+				count++
+				progress(count, 0)
+			}
+			if !yield(record, err) {
+				return
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := common.WriteExport(bufio.NewWriter(&buf), format, header, counted); err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Data:        ExportResult{Rows: count},
+		File:        buf.Bytes(),
+		ContentType: common.ExportContentType(format),
+		Filename:    filename + "." + format,
+	}, nil
+}
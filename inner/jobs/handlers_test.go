@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"idm/inner/role"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// сервис ролей для обработчиков заданий
+type stubRoleService struct {
+	records []role.ExportRecord
+	deleted []int64
+	err     error
+}
+
+func (s *stubRoleService) Export(ctx context.Context, sort string) (iter.Seq2[role.ExportRecord, error], error) {
+	return func(yield func(role.ExportRecord, error) bool) {
+		for _, record := range s.records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+func (s *stubRoleService) DeleteByIds(ctx context.Context, ids []int64) error {
+	s.deleted = ids
+	return s.err
+}
+
+func TestRoleExportHandler(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	roles := &stubRoleService{records: []role.ExportRecord{
+		{Id: 1, Name: "Admin", Status: true, CreatedAt: created, UpdatedAt: created},
+		{Id: 2, Name: "User", Status: true, CreatedAt: created, UpdatedAt: created},
+	}}
+	var done int
+	progress := func(d, total int) { done = d }
+
+	t.Run("CSV file", func(t *testing.T) {
+		result, err := roleExportHandler(roles)(context.Background(), Job{Params: json.RawMessage(`{"format":"csv"}`)}, progress)
+
+		require.NoError(t, err)
+		assert.Equal(t, ExportResult{Rows: 2}, result.Data)
+		assert.Equal(t, 2, done)
+		assert.Equal(t, "roles.csv", result.Filename)
+		assert.Equal(t, "text/csv; charset=utf-8", result.ContentType)
+		assert.Equal(t, "id,name,description,status,parent,permissions,holders,created_at,updated_at\n"+
+			"1,Admin,,true,,,0,2025-07-01T09:00:00Z,2025-07-01T09:00:00Z\n"+
+			"2,User,,true,,,0,2025-07-01T09:00:00Z,2025-07-01T09:00:00Z\n",
+			string(result.File))
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		_, err := roleExportHandler(roles)(context.Background(), Job{Params: json.RawMessage(`{"format":"xlsx"}`)}, progress)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestDeleteHandler(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		roles := &stubRoleService{}
+		var done, total int
+
+		result, err := deleteHandler(roles.DeleteByIds)(context.Background(),
+			Job{Params: json.RawMessage(`{"ids":[3,4]}`)},
+			func(d, t int) { done, total = d, t })
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{3, 4}, roles.deleted)
+		assert.Equal(t, DeleteResult{Requested: 2}, result.Data)
+		assert.Equal(t, 2, done)
+		assert.Equal(t, 2, total)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		roles := &stubRoleService{err: errors.New("role has dependents")}
+
+		_, err := deleteHandler(roles.DeleteByIds)(context.Background(),
+			Job{Params: json.RawMessage(`{"ids":[3]}`)},
+			func(d, t int) {})
+
+		assert.EqualError(t, err, "role has dependents")
+	})
+}
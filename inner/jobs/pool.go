@@ -0,0 +1,337 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idm/inner/common"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// задание, не сохранявшее прогресс дольше staleAfter интервалов опроса, считается брошенным
+// (экземпляр приложения, выполнявший его, аварийно остановлен) и забирается повторно
+const staleAfter = 5
+
+// после maxAttempts попыток брошенное задание завершается с ошибкой, а не забирается снова
+const maxAttempts = 3
+
+// сколько ждать обработчики, прерванные при остановке, прежде чем вернуть задания в очередь
+const stopGrace = 5 * time.Second
+
+// причины отмены контекста задания
+var (
+	errCancelled = errors.New("job cancelled")
+	errShutdown  = errors.New("job interrupted by shutdown")
+)
+
+// интерфейс хранилища заданий для пула (реализуется jobs.Repository)
+type PoolRepo interface {
+	Claim(ctx context.Context, types []string, staleBefore time.Time) (Entity, bool, error)
+	Heartbeat(ctx context.Context, id int64, progress, total int) (bool, error)
+	Finish(ctx context.Context, id int64, outcome Outcome) error
+	Requeue(ctx context.Context, id int64) error
+}
+
+// Pool фиксированное число обработчиков, выполняющих задания из таблицы job.
+// Новые задания забираются по сигналу Notify или при периодическом опросе
+type Pool struct {
+	repo     PoolRepo
+	workers  int
+	interval time.Duration
+	logger   *common.Logger
+	handlers map[string]Handler
+	wake     chan struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	// контексты выполняемых заданий для отмены и остановки
+	mu      sync.Mutex
+	running map[int64]context.CancelCauseFunc
+}
+
+// функция-конструктор; interval - период опроса очереди и сохранения прогресса
+func NewPool(repo PoolRepo, workers int, interval time.Duration, logger *common.Logger) *Pool {
+	return &Pool{
+		repo:     repo,
+		workers:  workers,
+		interval: interval,
+		logger:   logger,
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+		running:  make(map[int64]context.CancelCauseFunc),
+	}
+}
+
+// Register задаёт обработчик заданий типа jobType; вызывается до Start
+func (p *Pool) Register(jobType string, handler Handler) {
+	p.handlers[jobType] = handler
+}
+
+// Start запускает обработчики
+func (p *Pool) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	for range p.workers {
+		p.wg.Add(1)
+		go p.work(ctx)
+	}
+	p.logger.Info("Job pool started",
+		zap.Int("workers", p.workers),
+		zap.Duration("interval", p.interval))
+}
+
+// Stop прекращает забирать новые задания и ждёт завершения выполняемых до отмены ctx.
+// Задания, не успевшие завершиться, прерываются и возвращаются в очередь
+func (p *Pool) Stop(ctx context.Context) {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.logger.Info("Job pool stopped")
+		return
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	interrupted := len(p.running)
+	for _, cancel := range p.running {
+		cancel(errShutdown)
+	}
+	p.mu.Unlock()
+	p.logger.Warn("Job pool stop timeout exceeded, interrupting jobs", zap.Int("jobs", interrupted))
+
+	select {
+	case <-done:
+		p.logger.Info("Job pool stopped")
+	case <-time.After(stopGrace):
+		p.logger.Warn("Job handlers did not stop after interruption")
+	}
+}
+
+// Notify будит свободный обработчик, не дожидаясь очередного опроса
+func (p *Pool) Notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Cancel прерывает задание, если оно выполняется в этом экземпляре приложения
+func (p *Pool) Cancel(id int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.running[id]; ok {
+		cancel(errCancelled)
+	}
+}
+
+func (p *Pool) types() []string {
+	types := make([]string, 0, len(p.handlers))
+	for jobType := range p.handlers {
+		types = append(types, jobType)
+	}
+	return types
+}
+
+func (p *Pool) work(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	types := p.types()
+	for {
+		// задания забираются подряд, пока очередь не опустеет
+		for ctx.Err() == nil {
+			job, found, err := p.repo.Claim(ctx, types, time.Now().Add(-staleAfter*p.interval))
+			if err != nil {
+				if ctx.Err() == nil {
+					p.logger.Error("Failed to claim job", zap.Error(err))
+				}
+				break
+			}
+			if !found {
+				break
+			}
+			p.run(job)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// выполняет задание. Контекст задания не зависит от контекста пула, чтобы остановка
+// дожидалась выполняемых заданий, а не прерывала их сразу
+func (p *Pool) run(job Entity) {
+	logger := p.logger.With(zap.Int64("job_id", job.Id), zap.String("type", job.Type))
+
+	if job.CancelRequested {
+		p.finish(job.Id, Outcome{Status: StatusCancelled}, Result{}, nil, logger)
+		return
+	}
+	if job.Attempts > maxAttempts {
+		p.finish(job.Id, Outcome{Status: StatusFailed}, Result{}, errors.New("job was interrupted too many times"), logger)
+		return
+	}
+
+	// изменения от имени задания записываются в журнал аудита с инициатором исходного запроса
+	ctx := common.WithActor(context.Background(), common.Actor{
+		Subject:   deref(job.CreatedBySub),
+		Username:  deref(job.CreatedByUsername),
+		RequestId: deref(job.RequestId),
+		Ip:        deref(job.Ip),
+	})
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	p.mu.Lock()
+	p.running[job.Id] = cancel
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.running, job.Id)
+		p.mu.Unlock()
+	}()
+
+	var done, total atomic.Int64
+	progress := func(d, t int) {
+		done.Store(int64(d))
+		total.Store(int64(t))
+	}
+	stopHeartbeat := p.heartbeat(ctx, cancel, job.Id, &done, &total, logger)
+
+	logger.Info("Job started", zap.Int("attempt", job.Attempts))
+	result, err := p.handle(ctx, job, progress)
+	stopHeartbeat()
+
+	// задание, завершённое обработчиком, считается выполненным, даже если отмена пришла после этого
+	cause := context.Cause(ctx)
+	outcome := Outcome{Status: StatusSucceeded, Progress: int(done.Load()), Total: int(total.Load())}
+	switch {
+	case err == nil:
+	case errors.Is(cause, errShutdown):
+		if requeueErr := p.repo.Requeue(context.Background(), job.Id); requeueErr != nil {
+			logger.Error("Failed to requeue interrupted job", zap.Error(requeueErr))
+			return
+		}
+		logger.Warn("Job interrupted by shutdown and requeued")
+		return
+	case errors.Is(cause, errCancelled):
+		outcome.Status = StatusCancelled
+	default:
+		outcome.Status = StatusFailed
+	}
+	p.finish(job.Id, outcome, result, err, logger)
+}
+
+// вызывает обработчик; паника обработчика завершает задание с ошибкой, а не весь пул
+func (p *Pool) handle(ctx context.Context, job Entity, progress Progress) (result Result, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job handler panicked: %v", recovered)
+		}
+	}()
+
+	handler, ok := p.handlers[job.Type]
+	if !ok {
+		return Result{}, fmt.Errorf("unknown job type %q", job.Type)
+	}
+	return handler(ctx, Job{Id: job.Id, Type: job.Type, Params: json.RawMessage(job.Params), Input: job.Input}, progress)
+}
+
+// периодически сохраняет прогресс задания и отменяет его контекст, если отмену запросили
+// через другой экземпляр приложения. Возвращает функцию остановки
+func (p *Pool) heartbeat(
+	ctx context.Context,
+	cancel context.CancelCauseFunc,
+	id int64,
+	done, total *atomic.Int64,
+	logger *zap.Logger,
+) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			cancelRequested, err := p.repo.Heartbeat(context.Background(), id, int(done.Load()), int(total.Load()))
+			if err != nil {
+				logger.Error("Failed to save job progress", zap.Error(err))
+				continue
+			}
+			if cancelRequested {
+				cancel(errCancelled)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// записывает итог задания. Итог записывается без контекста задания: он может быть уже отменён.
+// Результат сохраняется только у выполненного задания, ошибка - у завершённого с ошибкой
+func (p *Pool) finish(id int64, outcome Outcome, result Result, jobErr error, logger *zap.Logger) {
+	if outcome.Status == StatusSucceeded {
+		if result.Data != nil {
+			encoded, err := json.Marshal(result.Data)
+			if err != nil {
+				outcome.Status, jobErr = StatusFailed, fmt.Errorf("error encoding job result: %w", err)
+			} else {
+				value := string(encoded)
+				outcome.Result = &value
+			}
+		}
+		if result.ContentType != "" && outcome.Status == StatusSucceeded {
+			outcome.File = &ResultFile{Content: result.File, ContentType: result.ContentType, Filename: result.Filename}
+		}
+	}
+	if outcome.Status == StatusFailed && jobErr != nil {
+		value := jobErr.Error()
+		outcome.Error = &value
+	}
+
+	if err := p.repo.Finish(context.Background(), id, outcome); err != nil {
+		logger.Error("Failed to save job result", zap.String("status", outcome.Status), zap.Error(err))
+		return
+	}
+	if outcome.Status == StatusFailed {
+		logger.Error("Job failed", zap.Error(jobErr))
+		return
+	}
+	logger.Info("Job finished", zap.String("status", outcome.Status))
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
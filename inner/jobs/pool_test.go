@@ -0,0 +1,271 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// хранилище заданий в памяти
+type fakePoolRepo struct {
+	mu              sync.Mutex
+	queue           []Entity
+	cancelRequested map[int64]bool
+	finished        chan finishedJob
+	requeued        chan int64
+}
+
+type finishedJob struct {
+	id      int64
+	outcome Outcome
+}
+
+func newFakePoolRepo(jobs ...Entity) *fakePoolRepo {
+	return &fakePoolRepo{
+		queue:           jobs,
+		cancelRequested: make(map[int64]bool),
+		finished:        make(chan finishedJob, 10),
+		requeued:        make(chan int64, 10),
+	}
+}
+
+func (r *fakePoolRepo) Claim(ctx context.Context, types []string, staleBefore time.Time) (Entity, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.queue) == 0 {
+		return Entity{}, false, nil
+	}
+	job := r.queue[0]
+	r.queue = r.queue[1:]
+	job.Status = StatusRunning
+	job.Attempts++
+	return job, true, nil
+}
+
+func (r *fakePoolRepo) Heartbeat(ctx context.Context, id int64, progress, total int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancelRequested[id], nil
+}
+
+func (r *fakePoolRepo) Finish(ctx context.Context, id int64, outcome Outcome) error {
+	r.finished <- finishedJob{id: id, outcome: outcome}
+	return nil
+}
+
+func (r *fakePoolRepo) Requeue(ctx context.Context, id int64) error {
+	r.requeued <- id
+	return nil
+}
+
+func (r *fakePoolRepo) requestCancel(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancelRequested[id] = true
+}
+
+func waitFinished(t *testing.T, repo *fakePoolRepo) finishedJob {
+	t.Helper()
+	select {
+	case job := <-repo.finished:
+		return job
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not finished")
+		return finishedJob{}
+	}
+}
+
+// обработчик, ожидающий отмены контекста
+func blockingHandler(started chan<- struct{}) Handler {
+	return func(ctx context.Context, job Job, progress Progress) (Result, error) {
+		close(started)
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	}
+}
+
+func TestPool_Run(t *testing.T) {
+	t.Run("Succeeded job saves result, file and progress", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeRoleExport, Params: `{"format":"csv"}`, CreatedBySub: ptr("sub-1")})
+		pool := NewPool(repo, 1, 10*time.Millisecond, createTestLogger())
+		var actor common.Actor
+		pool.Register(TypeRoleExport, func(ctx context.Context, job Job, progress Progress) (Result, error) {
+			actor = common.ActorFromContext(ctx)
+			progress(2, 2)
+			return Result{Data: ExportResult{Rows: 2}, File: []byte("a\nb\n"), ContentType: "text/csv", Filename: "roles.csv"}, nil
+		})
+
+		pool.Start()
+		job := waitFinished(t, repo)
+		pool.Stop(context.Background())
+
+		assert.Equal(t, int64(1), job.id)
+		assert.Equal(t, StatusSucceeded, job.outcome.Status)
+		assert.Equal(t, 2, job.outcome.Progress)
+		assert.Equal(t, 2, job.outcome.Total)
+		require.NotNil(t, job.outcome.Result)
+		assert.JSONEq(t, `{"rows":2}`, *job.outcome.Result)
+		require.NotNil(t, job.outcome.File)
+		assert.Equal(t, "roles.csv", job.outcome.File.Filename)
+		assert.Equal(t, "sub-1", actor.Subject)
+	})
+
+	t.Run("Failed job saves error", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeRoleDelete})
+		pool := NewPool(repo, 1, 10*time.Millisecond, createTestLogger())
+		pool.Register(TypeRoleDelete, func(ctx context.Context, job Job, progress Progress) (Result, error) {
+			return Result{Data: "ignored"}, errors.New("delete failed")
+		})
+
+		pool.Start()
+		job := waitFinished(t, repo)
+		pool.Stop(context.Background())
+
+		assert.Equal(t, StatusFailed, job.outcome.Status)
+		assert.Nil(t, job.outcome.Result)
+		require.NotNil(t, job.outcome.Error)
+		assert.Equal(t, "delete failed", *job.outcome.Error)
+	})
+
+	t.Run("Panic and unknown type fail the job", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeRoleDelete}, Entity{Id: 2, Type: "unknown"})
+		pool := NewPool(repo, 1, 10*time.Millisecond, createTestLogger())
+		pool.Register(TypeRoleDelete, func(ctx context.Context, job Job, progress Progress) (Result, error) {
+			panic("boom")
+		})
+
+		pool.Start()
+		first := waitFinished(t, repo)
+		second := waitFinished(t, repo)
+		pool.Stop(context.Background())
+
+		assert.Equal(t, StatusFailed, first.outcome.Status)
+		assert.Contains(t, *first.outcome.Error, "panicked")
+		assert.Equal(t, StatusFailed, second.outcome.Status)
+		assert.Contains(t, *second.outcome.Error, "unknown job type")
+	})
+
+	t.Run("Job interrupted too many times fails", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeRoleDelete, Attempts: maxAttempts})
+		pool := NewPool(repo, 1, 10*time.Millisecond, createTestLogger())
+		called := false
+		pool.Register(TypeRoleDelete, func(ctx context.Context, job Job, progress Progress) (Result, error) {
+			called = true
+			return Result{}, nil
+		})
+
+		pool.Start()
+		job := waitFinished(t, repo)
+		pool.Stop(context.Background())
+
+		assert.Equal(t, StatusFailed, job.outcome.Status)
+		assert.False(t, called)
+	})
+}
+
+func TestPool_Cancel(t *testing.T) {
+	t.Run("Cancel in this instance", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeEmployeeImport})
+		pool := NewPool(repo, 1, time.Hour, createTestLogger())
+		started := make(chan struct{})
+		pool.Register(TypeEmployeeImport, blockingHandler(started))
+
+		pool.Start()
+		<-started
+		pool.Cancel(1)
+		job := waitFinished(t, repo)
+		pool.Stop(context.Background())
+
+		assert.Equal(t, StatusCancelled, job.outcome.Status)
+		assert.Nil(t, job.outcome.Error)
+	})
+
+	t.Run("Cancel requested through another instance", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeEmployeeImport})
+		pool := NewPool(repo, 1, 10*time.Millisecond, createTestLogger())
+		started := make(chan struct{})
+		pool.Register(TypeEmployeeImport, blockingHandler(started))
+
+		pool.Start()
+		<-started
+		repo.requestCancel(1)
+		job := waitFinished(t, repo)
+		pool.Stop(context.Background())
+
+		assert.Equal(t, StatusCancelled, job.outcome.Status)
+	})
+
+	t.Run("Claimed job with cancel request is not run", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeEmployeeImport, CancelRequested: true})
+		pool := NewPool(repo, 1, 10*time.Millisecond, createTestLogger())
+		called := false
+		pool.Register(TypeEmployeeImport, func(ctx context.Context, job Job, progress Progress) (Result, error) {
+			called = true
+			return Result{}, nil
+		})
+
+		pool.Start()
+		job := waitFinished(t, repo)
+		pool.Stop(context.Background())
+
+		assert.Equal(t, StatusCancelled, job.outcome.Status)
+		assert.False(t, called)
+	})
+}
+
+func TestPool_Stop(t *testing.T) {
+	t.Run("Stop waits for running job", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeRoleDelete})
+		pool := NewPool(repo, 1, time.Hour, createTestLogger())
+		started := make(chan struct{})
+		release := make(chan struct{})
+		pool.Register(TypeRoleDelete, func(ctx context.Context, job Job, progress Progress) (Result, error) {
+			close(started)
+			<-release
+			return Result{}, nil
+		})
+
+		pool.Start()
+		<-started
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+		}()
+		pool.Stop(context.Background())
+
+		job := waitFinished(t, repo)
+		assert.Equal(t, StatusSucceeded, job.outcome.Status)
+	})
+
+	t.Run("Job interrupted by stop timeout is requeued", func(t *testing.T) {
+		repo := newFakePoolRepo(Entity{Id: 1, Type: TypeEmployeeImport})
+		pool := NewPool(repo, 1, time.Hour, createTestLogger())
+		started := make(chan struct{})
+		pool.Register(TypeEmployeeImport, blockingHandler(started))
+
+		pool.Start()
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		pool.Stop(ctx)
+
+		select {
+		case id := <-repo.requeued:
+			assert.Equal(t, int64(1), id)
+		default:
+			t.Fatal("job was not requeued")
+		}
+		assert.Empty(t, repo.finished)
+	})
+
+	t.Run("Stop without start", func(t *testing.T) {
+		pool := NewPool(newFakePoolRepo(), 1, time.Hour, createTestLogger())
+		pool.Stop(context.Background())
+	})
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+// колонки задания без входных данных и файла результата: они могут быть большими
+// и читаются только обработчиком и при скачивании результата
+const jobColumns = `id, type, status, params, progress, total, attempts, cancel_requested,
+	result, result_content_type, result_filename, error, created_by_sub, created_by_username,
+	request_id, ip, created_at, updated_at, started_at, heartbeat_at, finished_at`
+
+func NewJobRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) Create(ctx context.Context, job Entity) (id int64, err error) {
+	err = r.db.GetContext(ctx, &id,
+		`INSERT INTO job (type, params, input, created_by_sub, created_by_username, request_id, ip)
+		VALUES ($1, $2::jsonb, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		job.Type, job.Params, job.Input, job.CreatedBySub, job.CreatedByUsername, job.RequestId, job.Ip)
+	return id, err
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (job Entity, err error) {
+	err = r.db.GetContext(ctx, &job, `SELECT `+jobColumns+` FROM job WHERE id = $1`, id)
+	return job, err
+}
+
+func (r *Repository) FindResultFile(ctx context.Context, id int64) (file ResultFile, err error) {
+	err = r.db.GetContext(ctx, &file,
+		`SELECT result_file, result_content_type, result_filename FROM job
+		WHERE id = $1 AND result_content_type IS NOT NULL`,
+		id)
+	return file, err
+}
+
+// Claim забирает следующее задание одного из типов types: ожидающее в очереди или выполняемое,
+// но брошенное (heartbeat_at раньше staleBefore). SKIP LOCKED позволяет нескольким экземплярам
+// приложения забирать задания одновременно. found = false - подходящих заданий нет
+func (r *Repository) Claim(ctx context.Context, types []string, staleBefore time.Time) (job Entity, found bool, err error) {
+	err = r.db.GetContext(ctx, &job,
+		`UPDATE job SET status = 'running', attempts = attempts + 1, progress = 0, total = 0,
+			started_at = now(), heartbeat_at = now()
+		WHERE id = (
+			SELECT id FROM job
+			WHERE type = ANY ($1) AND (status = 'queued' OR (status = 'running' AND heartbeat_at < $2))
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns+`, input`,
+		pq.Array(types), staleBefore)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entity{}, false, nil
+	}
+	return job, err == nil, err
+}
+
+// Heartbeat сохраняет прогресс выполняемого задания и возвращает, запрошена ли его отмена.
+// Если задание уже не выполняется (например, его забрал другой экземпляр), возвращается sql.ErrNoRows
+func (r *Repository) Heartbeat(ctx context.Context, id int64, progress, total int) (cancelRequested bool, err error) {
+	err = r.db.GetContext(ctx, &cancelRequested,
+		`UPDATE job SET progress = $2, total = $3, heartbeat_at = now()
+		WHERE id = $1 AND status = 'running'
+		RETURNING cancel_requested`,
+		id, progress, total)
+	return cancelRequested, err
+}
+
+// Finish записывает итог выполняемого задания
+func (r *Repository) Finish(ctx context.Context, id int64, outcome Outcome) error {
+	var content []byte
+	var contentType, filename *string
+	if outcome.File != nil {
+		content, contentType, filename = outcome.File.Content, &outcome.File.ContentType, &outcome.File.Filename
+	}
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE job SET status = $2, progress = $3, total = $4, result = $5::jsonb, result_file = $6,
+			result_content_type = $7, result_filename = $8, error = $9, finished_at = now()
+		WHERE id = $1 AND status = 'running'`,
+		id, outcome.Status, outcome.Progress, outcome.Total, outcome.Result, content, contentType, filename, outcome.Error)
+	return err
+}
+
+// Requeue возвращает прерванное при остановке приложения задание в очередь
+func (r *Repository) Requeue(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE job SET status = 'queued', progress = 0, total = 0, started_at = NULL, heartbeat_at = NULL,
+			attempts = GREATEST(attempts - 1, 0)
+		WHERE id = $1 AND status = 'running'`,
+		id)
+	return err
+}
+
+// RequestCancel отменяет задание в очереди сразу, а для выполняемого задания запрашивает отмену:
+// её выполнит обработчик при следующем сохранении прогресса.
+// found = false - задание уже завершено
+func (r *Repository) RequestCancel(ctx context.Context, id int64) (job Entity, found bool, err error) {
+	err = r.db.GetContext(ctx, &job,
+		`UPDATE job SET cancel_requested = true,
+			status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+			finished_at = CASE WHEN status = 'queued' THEN now() ELSE finished_at END
+		WHERE id = $1 AND status IN ('queued', 'running')
+		RETURNING `+jobColumns,
+		id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entity{}, false, nil
+	}
+	return job, err == nil, err
+}
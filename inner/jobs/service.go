@@ -0,0 +1,160 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"idm/inner/common"
+	"idm/inner/validator"
+
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo      Repo
+	runner    Runner
+	validator Validator
+	logger    *common.Logger
+}
+
+type Repo interface {
+	Create(ctx context.Context, job Entity) (int64, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindResultFile(ctx context.Context, id int64) (ResultFile, error)
+	RequestCancel(ctx context.Context, id int64) (Entity, bool, error)
+}
+
+// интерфейс пула обработчиков (реализуется jobs.Pool)
+type Runner interface {
+	Notify()
+	Cancel(id int64)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// функция-конструктор
+func NewService(repo Repo, runner Runner, validator Validator, logger *common.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		runner:    runner,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// Метод для постановки задания в очередь. Инициатор запроса сохраняется в задании:
+// от его имени задание записывает изменения в журнал аудита
+func (svc *Service) Submit(ctx context.Context, request SubmitRequest) (SubmitResponse, error) {
+	// параметры проверяются при постановке в очередь, чтобы не создавать заведомо неудачное задание
+	if err := svc.validator.Validate(request.Params); err != nil {
+		svc.logger.Error("Validation failed for job params", zap.String("type", request.Type), zap.Error(err))
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return SubmitResponse{}, common.RequestValidationError{
+				Message: "Invalid job request",
+				Data:    validationErr.Errors,
+			}
+		}
+		return SubmitResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+
+	params, err := json.Marshal(request.Params)
+	if err != nil {
+		return SubmitResponse{}, fmt.Errorf("error encoding job params: %w", err)
+	}
+
+	actor := common.ActorFromContext(ctx)
+	id, err := svc.repo.Create(ctx, Entity{
+		Type:              request.Type,
+		Params:            string(params),
+		Input:             request.Input,
+		CreatedBySub:      nullable(actor.Subject),
+		CreatedByUsername: nullable(actor.Username),
+		RequestId:         nullable(actor.RequestId),
+		Ip:                nullable(actor.Ip),
+	})
+	if err != nil {
+		svc.logger.Error("Failed to create job", zap.String("type", request.Type), zap.Error(err))
+		return SubmitResponse{}, fmt.Errorf("error creating job: %w", err)
+	}
+	svc.runner.Notify()
+
+	svc.logger.Info("Job submitted", zap.Int64("id", id), zap.String("type", request.Type))
+	return SubmitResponse{Id: id, Status: StatusQueued}, nil
+}
+
+// Метод для получения состояния задания. Пользователю доступны только его задания,
+// администратору (all = true) - все
+func (svc *Service) FindById(ctx context.Context, id int64, all bool) (Response, error) {
+	entity, err := svc.findAccessible(ctx, id, all)
+	if err != nil {
+		return Response{}, err
+	}
+	return entity.toResponse(), nil
+}
+
+// Метод для получения файла результата выполненного задания
+func (svc *Service) FindResultFile(ctx context.Context, id int64, all bool) (ResultFile, error) {
+	entity, err := svc.findAccessible(ctx, id, all)
+	if err != nil {
+		return ResultFile{}, err
+	}
+	if entity.Status != StatusSucceeded || entity.ResultContentType == nil {
+		return ResultFile{}, common.NotFoundError{Message: fmt.Sprintf("job with id %d has no result file", id)}
+	}
+
+	file, err := svc.repo.FindResultFile(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to find job result file", zap.Int64("id", id), zap.Error(err))
+		return ResultFile{}, fmt.Errorf("error finding job result file: %w", err)
+	}
+	return file, nil
+}
+
+// Метод для отмены задания. Задание в очереди отменяется сразу, выполняемое -
+// как только обработчик заметит отмену; завершённое задание отменить нельзя
+func (svc *Service) Cancel(ctx context.Context, id int64, all bool) (Response, error) {
+	if _, err := svc.findAccessible(ctx, id, all); err != nil {
+		return Response{}, err
+	}
+
+	entity, found, err := svc.repo.RequestCancel(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to cancel job", zap.Int64("id", id), zap.Error(err))
+		return Response{}, fmt.Errorf("error cancelling job: %w", err)
+	}
+	if !found {
+		return Response{}, common.ConflictError{Message: fmt.Sprintf("job with id %d is already finished", id)}
+	}
+	svc.runner.Cancel(id)
+
+	svc.logger.Info("Job cancellation requested", zap.Int64("id", id), zap.String("status", entity.Status))
+	return entity.toResponse(), nil
+}
+
+// находит задание, доступное инициатору запроса. Чужое задание не отличается от несуществующего
+func (svc *Service) findAccessible(ctx context.Context, id int64, all bool) (Entity, error) {
+	entity, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Entity{}, common.NotFoundError{Message: fmt.Sprintf("job with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find job", zap.Int64("id", id), zap.Error(err))
+		return Entity{}, fmt.Errorf("error finding job with id %d: %w", id, err)
+	}
+	if !all && deref(entity.CreatedBySub) != common.ActorFromContext(ctx).Subject {
+		return Entity{}, common.NotFoundError{Message: fmt.Sprintf("job with id %d not found", id)}
+	}
+	return entity, nil
+}
+
+func nullable(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
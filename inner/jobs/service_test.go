@@ -0,0 +1,216 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/validator"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) Create(ctx context.Context, job Entity) (int64, error) {
+	args := m.Called(job)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindResultFile(ctx context.Context, id int64) (ResultFile, error) {
+	args := m.Called(id)
+	return args.Get(0).(ResultFile), args.Error(1)
+}
+
+func (m *MockRepo) RequestCancel(ctx context.Context, id int64) (Entity, bool, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Bool(1), args.Error(2)
+}
+
+// пул для тестов сервиса: запоминает сигналы
+type StubRunner struct {
+	notified  int
+	cancelled []int64
+}
+
+func (r *StubRunner) Notify() {
+	r.notified++
+}
+
+func (r *StubRunner) Cancel(id int64) {
+	r.cancelled = append(r.cancelled, id)
+}
+
+func createTestLogger() *common.Logger {
+	cfg := common.Config{
+		DbDriverName:   "postgres",
+		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
+		AppName:        "test_app",
+		AppVersion:     "1.0.0",
+		LogLevel:       "DEBUG",
+		LogDevelopMode: true,
+	}
+	return common.NewLogger(cfg)
+}
+
+func ptr(value string) *string {
+	return &value
+}
+
+func TestService_Submit(t *testing.T) {
+	t.Run("Job is created on behalf of the actor", func(t *testing.T) {
+		repo := new(MockRepo)
+		runner := &StubRunner{}
+		svc := NewService(repo, runner, validator.New(), createTestLogger())
+		ctx := common.WithActor(context.Background(), common.Actor{Subject: "sub-1", Username: "admin", RequestId: "req-1"})
+
+		repo.On("Create", Entity{
+			Type:              TypeEmployeeDelete,
+			Params:            `{"ids":[1,2]}`,
+			CreatedBySub:      ptr("sub-1"),
+			CreatedByUsername: ptr("admin"),
+			RequestId:         ptr("req-1"),
+		}).Return(int64(7), nil)
+
+		response, err := svc.Submit(ctx, SubmitRequest{Type: TypeEmployeeDelete, Params: DeleteRequest{Ids: []int64{1, 2}}})
+
+		require.NoError(t, err)
+		assert.Equal(t, SubmitResponse{Id: 7, Status: StatusQueued}, response)
+		assert.Equal(t, 1, runner.notified)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid params", func(t *testing.T) {
+		repo := new(MockRepo)
+		runner := &StubRunner{}
+		svc := NewService(repo, runner, validator.New(), createTestLogger())
+
+		_, err := svc.Submit(context.Background(), SubmitRequest{Type: TypeEmployeeDelete, Params: DeleteRequest{}})
+
+		var validationErr common.RequestValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Zero(t, runner.notified)
+		repo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestService_FindById(t *testing.T) {
+	job := Entity{Id: 7, Type: TypeRoleExport, Status: StatusSucceeded, CreatedBySub: ptr("sub-1"), Result: ptr(`{"rows":3}`)}
+
+	t.Run("Own job", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubRunner{}, validator.New(), createTestLogger())
+		ctx := common.WithActor(context.Background(), common.Actor{Subject: "sub-1"})
+		repo.On("FindById", int64(7)).Return(job, nil)
+
+		response, err := svc.FindById(ctx, 7, false)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusSucceeded, response.Status)
+		assert.JSONEq(t, `{"rows":3}`, string(response.Result))
+	})
+
+	t.Run("Job of another user is not found", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubRunner{}, validator.New(), createTestLogger())
+		ctx := common.WithActor(context.Background(), common.Actor{Subject: "sub-2"})
+		repo.On("FindById", int64(7)).Return(job, nil)
+
+		_, err := svc.FindById(ctx, 7, false)
+		var notFoundErr common.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+
+		// администратору доступны все задания
+		_, err = svc.FindById(ctx, 7, true)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Missing job", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubRunner{}, validator.New(), createTestLogger())
+		repo.On("FindById", int64(8)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.FindById(context.Background(), 8, true)
+
+		var notFoundErr common.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+	})
+}
+
+func TestService_FindResultFile(t *testing.T) {
+	t.Run("Job without file", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubRunner{}, validator.New(), createTestLogger())
+		repo.On("FindById", int64(7)).Return(Entity{Id: 7, Status: StatusRunning}, nil)
+
+		_, err := svc.FindResultFile(context.Background(), 7, true)
+
+		var notFoundErr common.NotFoundError
+		assert.ErrorAs(t, err, &notFoundErr)
+		repo.AssertNotCalled(t, "FindResultFile", mock.Anything)
+	})
+
+	t.Run("Succeeded export", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubRunner{}, validator.New(), createTestLogger())
+		file := ResultFile{Content: []byte("id\n"), ContentType: "text/csv; charset=utf-8", Filename: "roles.csv"}
+		repo.On("FindById", int64(7)).Return(Entity{Id: 7, Status: StatusSucceeded, ResultContentType: ptr(file.ContentType)}, nil)
+		repo.On("FindResultFile", int64(7)).Return(file, nil)
+
+		found, err := svc.FindResultFile(context.Background(), 7, true)
+
+		require.NoError(t, err)
+		assert.Equal(t, file, found)
+	})
+}
+
+func TestService_Cancel(t *testing.T) {
+	t.Run("Running job", func(t *testing.T) {
+		repo := new(MockRepo)
+		runner := &StubRunner{}
+		svc := NewService(repo, runner, validator.New(), createTestLogger())
+		repo.On("FindById", int64(7)).Return(Entity{Id: 7, Status: StatusRunning}, nil)
+		repo.On("RequestCancel", int64(7)).Return(Entity{Id: 7, Status: StatusRunning, CancelRequested: true}, true, nil)
+
+		response, err := svc.Cancel(context.Background(), 7, true)
+
+		require.NoError(t, err)
+		assert.True(t, response.CancelRequested)
+		assert.Equal(t, []int64{7}, runner.cancelled)
+	})
+
+	t.Run("Finished job", func(t *testing.T) {
+		repo := new(MockRepo)
+		runner := &StubRunner{}
+		svc := NewService(repo, runner, validator.New(), createTestLogger())
+		repo.On("FindById", int64(7)).Return(Entity{Id: 7, Status: StatusSucceeded}, nil)
+		repo.On("RequestCancel", int64(7)).Return(Entity{}, false, nil)
+
+		_, err := svc.Cancel(context.Background(), 7, true)
+
+		var conflictErr common.ConflictError
+		assert.ErrorAs(t, err, &conflictErr)
+		assert.Empty(t, runner.cancelled)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		repo := new(MockRepo)
+		svc := NewService(repo, &StubRunner{}, validator.New(), createTestLogger())
+		repo.On("FindById", int64(7)).Return(Entity{Id: 7, Status: StatusQueued}, nil)
+		repo.On("RequestCancel", int64(7)).Return(Entity{}, false, errors.New("db error"))
+
+		_, err := svc.Cancel(context.Background(), 7, true)
+
+		assert.ErrorContains(t, err, "error cancelling job")
+	})
+}
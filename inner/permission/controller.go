@@ -0,0 +1,299 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server            *web.Server
+	permissionService Svc
+	logger            *common.Logger
+}
+
+// интерфейс сервиса permission.Service
+type Svc interface {
+	CreatePermission(ctx context.Context, request CreateRequest) (int64, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	DeleteById(ctx context.Context, id int64) error
+	FindByRoleId(ctx context.Context, roleId int64) ([]Response, error)
+	Grant(ctx context.Context, request GrantRequest) error
+	Revoke(ctx context.Context, roleId, permissionId int64) error
+	FindEffectivePermissions(ctx context.Context, employeeId int64) ([]EffectivePermissionResponse, error)
+}
+
+func NewController(server *web.Server, permissionService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:            server,
+		permissionService: permissionService,
+		logger:            logger,
+	}
+}
+
+// функция для регистрации маршрутов
+func (c *Controller) RegisterRoutes() {
+	c.logger.Info("Registering permission routes")
+	// полный маршрут получится "/api/v1/permissions"
+	api := c.server.GroupApiV1
+	api.Get("/permissions", c.FindAllPermissions)
+	api.Get("/permissions/employees/:employeeId/effective", c.FindEffectivePermissions)
+	api.Get("/permissions/:id", c.FindPermissionById)
+	api.Get("/roles/:id/permissions", c.FindRolePermissions)
+	// изменять разрешения и их выдачу ролям могут только администраторы:
+	// на результат опираются внешние сервисы через /authz/check
+	c.server.GroupApiV1Admin.Post("/permissions", c.CreatePermission)
+	c.server.GroupApiV1Admin.Delete("/permissions/:id", c.DeletePermissionById)
+	c.server.GroupApiV1Admin.Post("/roles/:id/permissions", c.GrantPermissions)
+	c.server.GroupApiV1Admin.Delete("/roles/:id/permissions/:permissionId", c.RevokePermission)
+	c.logger.Info("Permission routes registered successfully")
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/admin/permissions"
+func (c *Controller) CreatePermission(ctx *fiber.Ctx) error {
+	c.logger.Info("Received create permission request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse create permission request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	newPermissionId, err := c.permissionService.CreatePermission(ctx.Context(), request)
+	if err != nil {
+		return c.handleError(ctx, err, "Permission not found")
+	}
+
+	c.logger.Info("Permission created successfully",
+		zap.String("code", request.Code),
+		zap.Int64("id", newPermissionId),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, fiber.Map{
+		"id":      newPermissionId,
+		"message": "Permission successfully created",
+	})
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/permissions"
+func (c *Controller) FindAllPermissions(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find all permissions request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	permissions, err := c.permissionService.FindAll(ctx.Context())
+	if err != nil {
+		c.logger.Error("Failed to find all permissions",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when getting the list of permissions")
+	}
+
+	return common.OkResponse(ctx, permissions)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/permissions/:id"
+func (c *Controller) FindPermissionById(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find permission by ID request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseIdParam(ctx, "id")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid permission ID format")
+	}
+
+	permission, err := c.permissionService.FindById(ctx.Context(), id)
+	if err != nil {
+		return c.handleError(ctx, err, "Permission not found")
+	}
+
+	return common.OkResponse(ctx, permission)
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/admin/permissions/:id".
+// Разрешение автоматически отзывается у всех ролей
+func (c *Controller) DeletePermissionById(ctx *fiber.Ctx) error {
+	c.logger.Info("Received delete permission request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseIdParam(ctx, "id")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid permission ID format")
+	}
+
+	if err := c.permissionService.DeleteById(ctx.Context(), id); err != nil {
+		return c.handleError(ctx, err, "Permission not found")
+	}
+
+	c.logger.Info("Permission deleted successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, fiber.Map{"message": "Permission deleted successfully"})
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles/:id/permissions"
+func (c *Controller) FindRolePermissions(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received role permissions request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	roleId, err := c.parseIdParam(ctx, "id")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	permissions, err := c.permissionService.FindByRoleId(ctx.Context(), roleId)
+	if err != nil {
+		return c.handleError(ctx, err, "Role not found")
+	}
+
+	return common.OkResponse(ctx, permissions)
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/admin/roles/:id/permissions"
+func (c *Controller) GrantPermissions(ctx *fiber.Ctx) error {
+	c.logger.Info("Received grant permissions request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	roleId, err := c.parseIdParam(ctx, "id")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	var request GrantRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse grant permissions request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.RoleId = roleId
+
+	if err := c.permissionService.Grant(ctx.Context(), request); err != nil {
+		return c.handleError(ctx, err, "Role not found")
+	}
+
+	c.logger.Info("Permissions granted successfully",
+		zap.Int64("role_id", roleId),
+		zap.Int64s("permission_ids", request.PermissionIds),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, fiber.Map{"message": "Permissions granted successfully"})
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/admin/roles/:id/permissions/:permissionId"
+func (c *Controller) RevokePermission(ctx *fiber.Ctx) error {
+	c.logger.Info("Received revoke permission request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	roleId, err := c.parseIdParam(ctx, "id")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+	permissionId, err := c.parseIdParam(ctx, "permissionId")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid permission ID format")
+	}
+
+	if err := c.permissionService.Revoke(ctx.Context(), roleId, permissionId); err != nil {
+		return c.handleError(ctx, err, "Permission is not granted to the role")
+	}
+
+	c.logger.Info("Permission revoked successfully",
+		zap.Int64("role_id", roleId),
+		zap.Int64("permission_id", permissionId),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, fiber.Map{"message": "Permission revoked successfully"})
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/permissions/employees/:employeeId/effective"
+func (c *Controller) FindEffectivePermissions(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received effective permissions request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	employeeId, err := c.parseIdParam(ctx, "employeeId")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	permissions, err := c.permissionService.FindEffectivePermissions(ctx.Context(), employeeId)
+	if err != nil {
+		return c.handleError(ctx, err, "Employee not found")
+	}
+
+	return common.OkResponse(ctx, permissions)
+}
+
+// извлекает числовой параметр пути
+func (c *Controller) parseIdParam(ctx *fiber.Ctx, name string) (int64, error) {
+	idParam := ctx.Params(name)
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Error("Invalid ID format",
+			zap.String(name, idParam),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+	}
+	return id, err
+}
+
+// преобразует ошибки сервиса в HTTP-ответ
+func (c *Controller) handleError(ctx *fiber.Ctx, err error, notFoundMessage string) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		c.logger.Warn("Permission request validation error",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+
+		var validationErr common.RequestValidationError
+		errors.As(err, &validationErr)
+
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Permission request target not found",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, notFoundMessage)
+
+	case errors.As(err, &common.AlreadyExistsError{}):
+		c.logger.Warn("Permission request conflict error",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+
+	default:
+		c.logger.Error("Permission request internal error",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
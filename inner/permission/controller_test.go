@@ -0,0 +1,210 @@
+package permission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock для сервиса
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) CreatePermission(ctx context.Context, request CreateRequest) (int64, error) {
+	args := m.Called(request)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindAll(ctx context.Context) ([]Response, error) {
+	args := m.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (m *MockService) DeleteById(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockService) FindByRoleId(ctx context.Context, roleId int64) ([]Response, error) {
+	args := m.Called(roleId)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (m *MockService) Grant(ctx context.Context, request GrantRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *MockService) Revoke(ctx context.Context, roleId, permissionId int64) error {
+	args := m.Called(roleId, permissionId)
+	return args.Error(0)
+}
+
+func (m *MockService) FindEffectivePermissions(ctx context.Context, employeeId int64) ([]EffectivePermissionResponse, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).([]EffectivePermissionResponse), args.Error(1)
+}
+
+// Вспомогательные функции для создания Fiber app.
+// Без ролей запрос выполняется от администратора
+func setupTestApp(roles ...string) (*fiber.App, *MockService) {
+	if len(roles) == 0 {
+		roles = []string{web.IdmAdmin}
+	}
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{
+			RealmAccess: web.RealmAccessClaims{Roles: roles},
+		}})
+		return c.Next()
+	})
+	mockService := &MockService{}
+
+	groupApiV1Admin := app.Group("/api/v1/admin")
+	groupApiV1Admin.Use(web.RequireRole(web.IdmAdmin, createTestLogger()))
+	server := &web.Server{
+		GroupApiV1:      app.Group("/api/v1"),
+		GroupApiV1Admin: groupApiV1Admin,
+	}
+
+	controller := NewController(server, mockService, createTestLogger())
+	controller.RegisterRoutes()
+
+	return app, mockService
+}
+
+func TestController_CreatePermission_Conflict(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	request := CreateRequest{Code: "employee:read", Description: "Read employees"}
+	mockService.On("CreatePermission", request).
+		Return(int64(0), common.AlreadyExistsError{Message: "permission with code employee:read already exists"})
+
+	body, _ := json.Marshal(request)
+	req := httptest.NewRequest("POST", "/api/v1/admin/permissions", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_GrantPermissions(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("Grant", GrantRequest{RoleId: 1, PermissionIds: []int64{10, 11}}).Return(nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/roles/1/permissions",
+			bytes.NewReader([]byte(`{"permission_ids":[10,11]}`)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("Grant", mock.Anything).Return(common.NotFoundError{Message: "role with id 404 not found"})
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/roles/404/permissions",
+			bytes.NewReader([]byte(`{"permission_ids":[10]}`)))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestController_RevokePermission(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("Revoke", int64(1), int64(10)).Return(nil)
+
+	req := httptest.NewRequest("DELETE", "/api/v1/admin/roles/1/permissions/10", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_FindEffectivePermissions(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("FindEffectivePermissions", int64(7)).Return([]EffectivePermissionResponse{
+		{Response: Response{Id: 10, Code: "employee:read"}, ViaRoleIds: []int64{3}},
+		{Response: Response{Id: 12, Code: "audit:read"}, Inherited: true, ViaRoleIds: []int64{1}},
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/permissions/employees/7/effective", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	// маршрут не должен перехватываться маршрутом "/permissions/:id"
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response common.Response[[]EffectivePermissionResponse]
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 2)
+	assert.True(t, response.Data[1].Inherited)
+	mockService.AssertNotCalled(t, "FindById", mock.Anything)
+}
+
+func TestController_PermissionMutations_ForbiddenForUser(t *testing.T) {
+	requests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{"POST", "/api/v1/admin/permissions", `{"code":"employee:read"}`},
+		{"DELETE", "/api/v1/admin/permissions/10", ""},
+		{"POST", "/api/v1/admin/roles/1/permissions", `{"permission_ids":[10]}`},
+		{"DELETE", "/api/v1/admin/roles/1/permissions/10", ""},
+	}
+	for _, r := range requests {
+		t.Run(r.method+" "+r.url, func(t *testing.T) {
+			app, mockService := setupTestApp(web.IdmUser)
+
+			req := httptest.NewRequest(r.method, r.url, bytes.NewBufferString(r.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Reading is allowed", func(t *testing.T) {
+		app, mockService := setupTestApp(web.IdmUser)
+		mockService.On("FindByRoleId", int64(1)).Return([]Response{}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/roles/1/permissions", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,61 @@
+package permission
+
+import "time"
+
+type Entity struct {
+	Id        int64     `db:"id"`
+	Code      string    `db:"code"`
+	Desc      string    `db:"description"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:        e.Id,
+		Code:      e.Code,
+		Desc:      e.Desc,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id        int64     `json:"id"`
+	Code      string    `json:"code"`
+	Desc      string    `json:"description"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// разрешение вместе с ролью, которой оно выдано
+type rolePermissionEntity struct {
+	Entity
+	RoleId int64 `db:"role_id"`
+}
+
+type CreateRequest struct {
+	Code        string `json:"code" validate:"required,min=3,max=100" example:"employee:read"`
+	Description string `json:"description" validate:"max=500" example:"Read access to employee records"`
+}
+
+func (req *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Code: req.Code,
+		Desc: req.Description,
+	}
+}
+
+// GrantRequest структура запроса на выдачу разрешений роли
+type GrantRequest struct {
+	RoleId        int64   `json:"-"`
+	PermissionIds []int64 `json:"permission_ids" validate:"required,min=1,dive,min=1"`
+}
+
+// EffectivePermissionResponse разрешение сотрудника с указанием, через какие роли оно получено.
+// Inherited = true, если ни одна из этих ролей не назначена сотруднику напрямую
+type EffectivePermissionResponse struct {
+	Response
+	Inherited  bool    `json:"inherited"`
+	ViaRoleIds []int64 `json:"via_role_ids"`
+}
@@ -0,0 +1,143 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewPermissionRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (permission Entity, err error) {
+	err = r.db.GetContext(ctx, &permission, "SELECT * FROM permission WHERE id = $1", id)
+	return permission, err
+}
+
+func (r *Repository) FindAll(ctx context.Context) ([]Entity, error) {
+	var permissions []Entity
+	err := r.db.SelectContext(ctx, &permissions, "SELECT * FROM permission ORDER BY code")
+	return permissions, err
+}
+
+func (r *Repository) DeleteById(ctx context.Context, id int64) (deleted bool, err error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM permission WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// Найти разрешения, выданные роли напрямую
+func (r *Repository) FindByRoleId(ctx context.Context, roleId int64) ([]Entity, error) {
+	var permissions []Entity
+	err := r.db.SelectContext(
+		ctx,
+		&permissions,
+		`SELECT p.* FROM permission p
+		JOIN role_permission rp ON rp.permission_id = p.id
+		WHERE rp.role_id = $1
+		ORDER BY p.code`,
+		roleId)
+	return permissions, err
+}
+
+// Найти разрешения, выданные любой из ролей, по одной строке на каждую пару роль-разрешение
+func (r *Repository) FindByRoleIds(ctx context.Context, roleIds []int64) ([]rolePermissionEntity, error) {
+	var permissions []rolePermissionEntity
+	if len(roleIds) == 0 {
+		return permissions, nil
+	}
+	err := r.db.SelectContext(
+		ctx,
+		&permissions,
+		`SELECT p.*, rp.role_id FROM permission p
+		JOIN role_permission rp ON rp.permission_id = p.id
+		WHERE rp.role_id = ANY ($1)
+		ORDER BY p.code, rp.role_id`,
+		pq.Array(roleIds))
+	return permissions, err
+}
+
+// Проверить существование роли
+func (r *Repository) RoleExists(ctx context.Context, roleId int64) (isExists bool, err error) {
+	err = r.db.GetContext(ctx, &isExists, "select exists(select 1 from role where id = $1 and deleted_at is null)", roleId)
+	return isExists, err
+}
+
+// Отозвать разрешение у роли
+func (r *Repository) Revoke(ctx context.Context, roleId, permissionId int64) (revoked bool, err error) {
+	result, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM role_permission WHERE role_id = $1 AND permission_id = $2",
+		roleId, permissionId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// Транзакционные методы
+func (r *Repository) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// Найти разрешение по коду
+func (r *Repository) FindByCodeTx(ctx context.Context, tx *sqlx.Tx, code string) (isExists bool, err error) {
+	err = tx.GetContext(
+		ctx,
+		&isExists,
+		"select exists(select 1 from permission where code = $1)",
+		code,
+	)
+	return isExists, err
+}
+
+// Создать новое разрешение
+func (r *Repository) SaveTx(ctx context.Context, tx *sqlx.Tx, permission Entity) (permissionId int64, err error) {
+	err = tx.GetContext(
+		ctx,
+		&permissionId,
+		`INSERT INTO permission (code, description) VALUES ($1, $2) RETURNING id`,
+		permission.Code, permission.Desc)
+	return permissionId, err
+}
+
+// Проверить существование роли и заблокировать её от удаления до конца транзакции
+func (r *Repository) LockRoleTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (isExists bool, err error) {
+	var ids []int64
+	err = tx.SelectContext(ctx, &ids, "SELECT id FROM role WHERE id = $1 AND deleted_at IS NULL FOR SHARE", roleId)
+	return len(ids) > 0, err
+}
+
+// Найти среди переданных id те, которых нет в каталоге разрешений
+func (r *Repository) FindMissingIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]int64, error) {
+	var missing []int64
+	err := tx.SelectContext(
+		ctx,
+		&missing,
+		`SELECT DISTINCT req.id FROM unnest($1::bigint[]) AS req(id)
+		WHERE NOT exists(SELECT 1 FROM permission p WHERE p.id = req.id)
+		ORDER BY req.id`,
+		pq.Array(ids))
+	return missing, err
+}
+
+// Выдать разрешения роли; уже выданные разрешения пропускаются
+func (r *Repository) GrantTx(ctx context.Context, tx *sqlx.Tx, roleId int64, permissionIds []int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO role_permission (role_id, permission_id)
+		SELECT $1, unnest($2::bigint[])
+		ON CONFLICT (role_id, permission_id) DO NOTHING`,
+		roleId, pq.Array(permissionIds))
+	return err
+}
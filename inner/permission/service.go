@@ -0,0 +1,360 @@
+package permission
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"idm/inner/common"
+	"idm/inner/role"
+	"idm/inner/validator"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo        Repo
+	roleService RoleSvc
+	validator   Validator
+	logger      *common.Logger
+}
+
+type Repo interface {
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	DeleteById(ctx context.Context, id int64) (bool, error)
+	FindByRoleId(ctx context.Context, roleId int64) ([]Entity, error)
+	FindByRoleIds(ctx context.Context, roleIds []int64) ([]rolePermissionEntity, error)
+	RoleExists(ctx context.Context, roleId int64) (bool, error)
+	Revoke(ctx context.Context, roleId, permissionId int64) (bool, error)
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindByCodeTx(ctx context.Context, tx *sqlx.Tx, code string) (bool, error)
+	SaveTx(ctx context.Context, tx *sqlx.Tx, permission Entity) (int64, error)
+	LockRoleTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (bool, error)
+	FindMissingIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]int64, error)
+	GrantTx(ctx context.Context, tx *sqlx.Tx, roleId int64, permissionIds []int64) error
+}
+
+// интерфейс сервиса ролей: эффективные роли сотрудника вычисляются только в нём,
+// чтобы наследование через parent_id было реализовано в одном месте
+type RoleSvc interface {
+	FindEffectiveRoles(ctx context.Context, employeeId int64) ([]role.EffectiveRoleResponse, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// функция-конструктор
+func NewService(repo Repo, roleService RoleSvc, validator Validator, logger *common.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		roleService: roleService,
+		validator:   validator,
+		logger:      logger,
+	}
+}
+
+// Метод для добавления разрешения в каталог
+func (svc *Service) CreatePermission(ctx context.Context, request CreateRequest) (id int64, err error) {
+	svc.logger.Info("Creating new permission", zap.String("code", request.Code))
+
+	if err = svc.validateRequest(request); err != nil {
+		return 0, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for permission creation",
+			zap.String("code", request.Code),
+			zap.Error(err))
+		return 0, fmt.Errorf("error create permission: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err)
+	}()
+
+	isExist, err := svc.repo.FindByCodeTx(ctx, tx, request.Code)
+	if err != nil {
+		svc.logger.Error("Failed to check permission existence",
+			zap.String("code", request.Code),
+			zap.Error(err))
+		return 0, fmt.Errorf("error finding permission by code: %s, %w", request.Code, err)
+	}
+	if isExist {
+		svc.logger.Warn("Permission already exists", zap.String("code", request.Code))
+		return 0, common.AlreadyExistsError{Message: fmt.Sprintf("permission with code %s already exists", request.Code)}
+	}
+
+	id, err = svc.repo.SaveTx(ctx, tx, request.ToEntity())
+	if err != nil {
+		svc.logger.Error("Failed to save permission",
+			zap.String("code", request.Code),
+			zap.Error(err))
+		return 0, fmt.Errorf("error creating permission with code: %s %w", request.Code, err)
+	}
+
+	svc.logger.Info("Permission created successfully",
+		zap.String("code", request.Code),
+		zap.Int64("id", id))
+	return id, nil
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	svc.logger.Debug("Finding permission by ID", zap.Int64("id", id))
+
+	permission, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("permission with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find permission by ID",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding permission with id %d: %w", id, err)
+	}
+
+	return permission.toResponse(), nil
+}
+
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	svc.logger.Debug("Fetching all permissions")
+
+	permissions, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to fetch all permissions", zap.Error(err))
+		return nil, fmt.Errorf("error finding all permissions: %w", err)
+	}
+
+	return toResponses(permissions), nil
+}
+
+// Метод для удаления разрешения из каталога; выдачи этого разрешения ролям удаляются каскадно
+func (svc *Service) DeleteById(ctx context.Context, id int64) error {
+	svc.logger.Info("Deleting permission by ID", zap.Int64("id", id))
+
+	deleted, err := svc.repo.DeleteById(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to delete permission by ID",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error deleting permission with id %d: %w", id, err)
+	}
+	if !deleted {
+		return common.NotFoundError{Message: fmt.Sprintf("permission with id %d not found", id)}
+	}
+
+	svc.logger.Info("Permission deleted successfully", zap.Int64("id", id))
+	return nil
+}
+
+// Метод для получения разрешений, выданных роли напрямую
+func (svc *Service) FindByRoleId(ctx context.Context, roleId int64) ([]Response, error) {
+	svc.logger.Debug("Finding role permissions", zap.Int64("role_id", roleId))
+
+	isExist, err := svc.repo.RoleExists(ctx, roleId)
+	if err != nil {
+		svc.logger.Error("Failed to check role existence",
+			zap.Int64("role_id", roleId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding role with id %d: %w", roleId, err)
+	}
+	if !isExist {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", roleId)}
+	}
+
+	permissions, err := svc.repo.FindByRoleId(ctx, roleId)
+	if err != nil {
+		svc.logger.Error("Failed to find role permissions",
+			zap.Int64("role_id", roleId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding permissions of role %d: %w", roleId, err)
+	}
+
+	return toResponses(permissions), nil
+}
+
+// Метод для выдачи роли разрешений. Повторная выдача уже имеющегося разрешения не является ошибкой
+func (svc *Service) Grant(ctx context.Context, request GrantRequest) (err error) {
+	svc.logger.Info("Granting permissions to role",
+		zap.Int64("role_id", request.RoleId),
+		zap.Int64s("permission_ids", request.PermissionIds))
+
+	if err = svc.validateRequest(request); err != nil {
+		return err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for permission grant",
+			zap.Int64("role_id", request.RoleId),
+			zap.Error(err))
+		return fmt.Errorf("error grant permissions: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err)
+	}()
+
+	isExist, err := svc.repo.LockRoleTx(ctx, tx, request.RoleId)
+	if err != nil {
+		svc.logger.Error("Failed to lock role",
+			zap.Int64("role_id", request.RoleId),
+			zap.Error(err))
+		return fmt.Errorf("error finding role with id %d: %w", request.RoleId, err)
+	}
+	if !isExist {
+		return common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", request.RoleId)}
+	}
+
+	missing, err := svc.repo.FindMissingIdsTx(ctx, tx, request.PermissionIds)
+	if err != nil {
+		svc.logger.Error("Failed to check permissions existence",
+			zap.Int64s("permission_ids", request.PermissionIds),
+			zap.Error(err))
+		return fmt.Errorf("error finding permissions: %w", err)
+	}
+	if len(missing) > 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("permissions with ids %v do not exist", missing)}
+	}
+
+	if err = svc.repo.GrantTx(ctx, tx, request.RoleId, request.PermissionIds); err != nil {
+		svc.logger.Error("Failed to grant permissions",
+			zap.Int64("role_id", request.RoleId),
+			zap.Error(err))
+		return fmt.Errorf("error granting permissions to role %d: %w", request.RoleId, err)
+	}
+
+	svc.logger.Info("Permissions granted successfully",
+		zap.Int64("role_id", request.RoleId),
+		zap.Int64s("permission_ids", request.PermissionIds))
+	return nil
+}
+
+// Метод для отзыва разрешения у роли
+func (svc *Service) Revoke(ctx context.Context, roleId, permissionId int64) error {
+	svc.logger.Info("Revoking permission from role",
+		zap.Int64("role_id", roleId),
+		zap.Int64("permission_id", permissionId))
+
+	revoked, err := svc.repo.Revoke(ctx, roleId, permissionId)
+	if err != nil {
+		svc.logger.Error("Failed to revoke permission",
+			zap.Int64("role_id", roleId),
+			zap.Int64("permission_id", permissionId),
+			zap.Error(err))
+		return fmt.Errorf("error revoking permission %d from role %d: %w", permissionId, roleId, err)
+	}
+	if !revoked {
+		return common.NotFoundError{
+			Message: fmt.Sprintf("permission %d is not granted to role %d", permissionId, roleId),
+		}
+	}
+
+	svc.logger.Info("Permission revoked successfully",
+		zap.Int64("role_id", roleId),
+		zap.Int64("permission_id", permissionId))
+	return nil
+}
+
+// Метод для вычисления эффективных разрешений сотрудника: разрешения всех его эффективных ролей,
+// включая унаследованные через цепочку родителей
+func (svc *Service) FindEffectivePermissions(ctx context.Context, employeeId int64) ([]EffectivePermissionResponse, error) {
+	svc.logger.Debug("Finding effective permissions", zap.Int64("employee_id", employeeId))
+
+	roles, err := svc.roleService.FindEffectiveRoles(ctx, employeeId)
+	if err != nil {
+		return nil, err
+	}
+
+	roleIds := make([]int64, len(roles))
+	directRoles := make(map[int64]bool, len(roles))
+	for i, r := range roles {
+		roleIds[i] = r.Id
+		directRoles[r.Id] = !r.Inherited
+	}
+
+	grants, err := svc.repo.FindByRoleIds(ctx, roleIds)
+	if err != nil {
+		svc.logger.Error("Failed to find permissions of effective roles",
+			zap.Int64("employee_id", employeeId),
+			zap.Int64s("role_ids", roleIds),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding effective permissions of employee %d: %w", employeeId, err)
+	}
+
+	// одно разрешение может быть выдано нескольким ролям - схлопываем в одну запись
+	byId := make(map[int64]*EffectivePermissionResponse)
+	var order []int64
+	for _, grant := range grants {
+		effective, ok := byId[grant.Id]
+		if !ok {
+			effective = &EffectivePermissionResponse{Response: grant.toResponse(), Inherited: true}
+			byId[grant.Id] = effective
+			order = append(order, grant.Id)
+		}
+		effective.ViaRoleIds = append(effective.ViaRoleIds, grant.RoleId)
+		if directRoles[grant.RoleId] {
+			effective.Inherited = false
+		}
+	}
+
+	responses := make([]EffectivePermissionResponse, 0, len(order))
+	for _, id := range order {
+		sort.Slice(byId[id].ViaRoleIds, func(i, j int) bool {
+			return byId[id].ViaRoleIds[i] < byId[id].ViaRoleIds[j]
+		})
+		responses = append(responses, *byId[id])
+	}
+	sort.SliceStable(responses, func(i, j int) bool {
+		return responses[i].Code < responses[j].Code
+	})
+
+	svc.logger.Debug("Effective permissions found",
+		zap.Int64("employee_id", employeeId),
+		zap.Int("count", len(responses)))
+	return responses, nil
+}
+
+// валидация запросов к сервису разрешений
+func (svc *Service) validateRequest(request any) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Permission request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return nil
+}
+
+// завершает транзакцию: откатывает при ошибке, иначе фиксирует.
+// Возвращает исходную ошибку или ошибку фиксации
+func (svc *Service) finishTransaction(tx *sqlx.Tx, err error) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			svc.logger.Error("Failed to rollback transaction", zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		svc.logger.Error("Failed to commit transaction", zap.Error(commitErr))
+		return commitErr
+	}
+	return nil
+}
+
+func toResponses(permissions []Entity) []Response {
+	responses := make([]Response, len(permissions))
+	for i, permission := range permissions {
+		responses[i] = permission.toResponse()
+	}
+	return responses
+}
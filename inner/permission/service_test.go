@@ -0,0 +1,303 @@
+package permission
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/role"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Объявляем структуру мок-репозитория
+type MockRepo struct {
+	mock.Mock
+}
+
+type MockRoleService struct {
+	mock.Mock
+}
+
+type MockValidator struct {
+	mock.Mock
+}
+
+func (m *MockValidator) Validate(request any) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *MockRoleService) FindEffectiveRoles(ctx context.Context, employeeId int64) ([]role.EffectiveRoleResponse, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).([]role.EffectiveRoleResponse), args.Error(1)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called()
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) DeleteById(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindByRoleId(ctx context.Context, roleId int64) ([]Entity, error) {
+	args := m.Called(roleId)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByRoleIds(ctx context.Context, roleIds []int64) ([]rolePermissionEntity, error) {
+	args := m.Called(roleIds)
+	return args.Get(0).([]rolePermissionEntity), args.Error(1)
+}
+
+func (m *MockRepo) RoleExists(ctx context.Context, roleId int64) (bool, error) {
+	args := m.Called(roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) Revoke(ctx context.Context, roleId, permissionId int64) (bool, error) {
+	args := m.Called(roleId, permissionId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	args := m.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockRepo) FindByCodeTx(ctx context.Context, tx *sqlx.Tx, code string) (bool, error) {
+	args := m.Called(tx, code)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, permission Entity) (int64, error) {
+	args := m.Called(tx, permission)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) LockRoleTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (bool, error) {
+	args := m.Called(tx, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindMissingIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]int64, error) {
+	args := m.Called(tx, ids)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) GrantTx(ctx context.Context, tx *sqlx.Tx, roleId int64, permissionIds []int64) error {
+	args := m.Called(tx, roleId, permissionIds)
+	return args.Error(0)
+}
+
+// логгер для тестов
+func createTestLogger() *common.Logger {
+	cfg := common.Config{
+		DbDriverName:   "postgres",
+		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
+		AppName:        "test_app",
+		AppVersion:     "1.0.0",
+		LogLevel:       "DEBUG",
+		LogDevelopMode: true,
+	}
+	return common.NewLogger(cfg)
+}
+
+// создаёт транзакцию поверх sqlmock, которая ожидает commit или rollback
+func newMockTx(t *testing.T, commit bool) (*sqlx.Tx, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	sqlMock.ExpectBegin()
+	if commit {
+		sqlMock.ExpectCommit()
+	} else {
+		sqlMock.ExpectRollback()
+	}
+
+	tx, err := sqlx.NewDb(db, "postgres").Beginx()
+	assert.NoError(t, err)
+	return tx, sqlMock
+}
+
+func newTestService() (*Service, *MockRepo, *MockRoleService, *MockValidator) {
+	mockRepo := new(MockRepo)
+	mockRoleService := new(MockRoleService)
+	mockValidator := new(MockValidator)
+	service := NewService(mockRepo, mockRoleService, mockValidator, createTestLogger())
+	return service, mockRepo, mockRoleService, mockValidator
+}
+
+func TestService_CreatePermission(t *testing.T) {
+	request := CreateRequest{Code: "employee:read", Description: "Read employees"}
+
+	t.Run("Success", func(t *testing.T) {
+		service, mockRepo, _, mockValidator := newTestService()
+		tx, sqlMock := newMockTx(t, true)
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByCodeTx", tx, request.Code).Return(false, nil)
+		mockRepo.On("SaveTx", tx, request.ToEntity()).Return(int64(5), nil)
+
+		id, err := service.CreatePermission(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), id)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Code already exists", func(t *testing.T) {
+		service, mockRepo, _, mockValidator := newTestService()
+		tx, sqlMock := newMockTx(t, false)
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByCodeTx", tx, request.Code).Return(true, nil)
+
+		_, err := service.CreatePermission(context.Background(), request)
+
+		var alreadyExistsErr common.AlreadyExistsError
+		assert.True(t, errors.As(err, &alreadyExistsErr))
+		mockRepo.AssertNotCalled(t, "SaveTx", mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_FindById_NotFound(t *testing.T) {
+	service, mockRepo, _, _ := newTestService()
+
+	mockRepo.On("FindById", int64(404)).Return(Entity{}, sql.ErrNoRows)
+
+	_, err := service.FindById(context.Background(), 404)
+
+	var notFoundErr common.NotFoundError
+	assert.True(t, errors.As(err, &notFoundErr))
+}
+
+func TestService_Grant(t *testing.T) {
+	request := GrantRequest{RoleId: 1, PermissionIds: []int64{10, 11}}
+
+	t.Run("Success", func(t *testing.T) {
+		service, mockRepo, _, mockValidator := newTestService()
+		tx, sqlMock := newMockTx(t, true)
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockRoleTx", tx, int64(1)).Return(true, nil)
+		mockRepo.On("FindMissingIdsTx", tx, request.PermissionIds).Return([]int64{}, nil)
+		mockRepo.On("GrantTx", tx, int64(1), request.PermissionIds).Return(nil)
+
+		err := service.Grant(context.Background(), request)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		service, mockRepo, _, mockValidator := newTestService()
+		tx, sqlMock := newMockTx(t, false)
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockRoleTx", tx, int64(1)).Return(false, nil)
+
+		err := service.Grant(context.Background(), request)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown permission", func(t *testing.T) {
+		service, mockRepo, _, mockValidator := newTestService()
+		tx, sqlMock := newMockTx(t, false)
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockRoleTx", tx, int64(1)).Return(true, nil)
+		mockRepo.On("FindMissingIdsTx", tx, request.PermissionIds).Return([]int64{11}, nil)
+
+		err := service.Grant(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Contains(t, validationErr.Message, "11")
+		mockRepo.AssertNotCalled(t, "GrantTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Revoke_NotGranted(t *testing.T) {
+	service, mockRepo, _, _ := newTestService()
+
+	mockRepo.On("Revoke", int64(1), int64(10)).Return(false, nil)
+
+	err := service.Revoke(context.Background(), 1, 10)
+
+	var notFoundErr common.NotFoundError
+	assert.True(t, errors.As(err, &notFoundErr))
+}
+
+func TestService_FindEffectivePermissions(t *testing.T) {
+	t.Run("Merges direct and inherited grants", func(t *testing.T) {
+		service, mockRepo, mockRoleService, _ := newTestService()
+
+		// сотруднику назначена роль Guest(3), она наследует Admin(2) и Root(1)
+		mockRoleService.On("FindEffectiveRoles", int64(7)).Return([]role.EffectiveRoleResponse{
+			{Response: role.Response{Id: 3}},
+			{Response: role.Response{Id: 2}, Inherited: true, Depth: 1, SourceRoleId: 3},
+			{Response: role.Response{Id: 1}, Inherited: true, Depth: 2, SourceRoleId: 3},
+		}, nil)
+		mockRepo.On("FindByRoleIds", []int64{3, 2, 1}).Return([]rolePermissionEntity{
+			{Entity: Entity{Id: 10, Code: "employee:read"}, RoleId: 3},
+			{Entity: Entity{Id: 10, Code: "employee:read"}, RoleId: 1},
+			{Entity: Entity{Id: 11, Code: "employee:write"}, RoleId: 2},
+			{Entity: Entity{Id: 12, Code: "audit:read"}, RoleId: 1},
+		}, nil)
+
+		permissions, err := service.FindEffectivePermissions(context.Background(), 7)
+
+		assert.NoError(t, err)
+		assert.Len(t, permissions, 3)
+
+		assert.Equal(t, "audit:read", permissions[0].Code)
+		assert.True(t, permissions[0].Inherited)
+
+		assert.Equal(t, "employee:read", permissions[1].Code)
+		assert.False(t, permissions[1].Inherited)
+		assert.Equal(t, []int64{1, 3}, permissions[1].ViaRoleIds)
+
+		assert.Equal(t, "employee:write", permissions[2].Code)
+		assert.True(t, permissions[2].Inherited)
+	})
+
+	t.Run("Unknown employee", func(t *testing.T) {
+		service, mockRepo, mockRoleService, _ := newTestService()
+
+		mockRoleService.On("FindEffectiveRoles", int64(404)).
+			Return([]role.EffectiveRoleResponse(nil), common.NotFoundError{Message: "employee with id 404 not found"})
+
+		_, err := service.FindEffectivePermissions(context.Background(), 404)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		mockRepo.AssertNotCalled(t, "FindByRoleIds", mock.Anything)
+	})
+}
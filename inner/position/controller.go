@@ -0,0 +1,296 @@
+package position
+
+import (
+	"context"
+	"errors"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+type Controller struct {
+	server          *web.Server
+	positionService Svc
+	logger          *common.Logger
+}
+
+// интерфейс сервиса position.Service
+type Svc interface {
+	FindById(ctx context.Context, id int64) (Response, error)
+	FindAll(ctx context.Context) ([]Response, error)
+	CreatePosition(ctx context.Context, request CreateRequest) (Response, error)
+	UpdatePosition(ctx context.Context, request UpdateRequest) (Response, error)
+	DeleteById(ctx context.Context, id int64) error
+}
+
+func NewController(server *web.Server, positionService Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:          server,
+		positionService: positionService,
+		logger:          logger,
+	}
+}
+
+// функция для регистрации маршрутов
+func (c *Controller) RegisterRoutes() {
+	c.logger.Info("Registering position routes")
+	// Маршруты для чтения (доступны пользователям с ролью IDM_ADMIN или IDM_USER)
+	c.server.GroupApiV1User.Get("/positions/:id", c.GetPosition)
+	c.server.GroupApiV1User.Get("/positions", c.FindAllPositions)
+
+	// Маршруты для создания, изменения и удаления (доступны только администраторам)
+	c.server.GroupApiV1Admin.Post("/positions", c.CreatePosition)
+	c.server.GroupApiV1Admin.Put("/positions/:id", c.UpdatePosition)
+	c.server.GroupApiV1Admin.Delete("/positions/:id", c.DeletePosition)
+
+	c.logger.Info("Position routes registered successfully")
+}
+
+// CreatePosition создаёт должность
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Create a position
+//	@Description	Create a new position with its default roles. Names are unique case-insensitively
+//	@Tags			positions
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		position.CreateRequest				true	"create position request"
+//	@Success		200		{object}	common.Response[position.Response]	"Created position"
+//	@Failure		400		{object}	common.Response[any]					"Incorrect data format in request"
+//	@Failure		409		{object}	common.Response[any]					"Position name already exists"
+//	@Failure		500		{object}	common.Response[any]					"Internal server error"
+//	@Router			/admin/positions [post]
+func (c *Controller) CreatePosition(ctx *fiber.Ctx) error {
+	c.logger.Info("Received create position request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse create position request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	position, err := c.positionService.CreatePosition(ctx.UserContext(), request)
+	if err != nil {
+		return c.handlePositionError(ctx, err, 0)
+	}
+
+	c.logger.Info("Position created successfully",
+		zap.Int64("id", position.Id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, position)
+}
+
+// UpdatePosition обновляет должность
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Update a position
+//	@Description	Replace name, description and default roles of a position. Roles already assigned to employees are not revoked
+//	@Tags			positions
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int										true	"Position ID"
+//	@Param			request	body		position.UpdateRequest				true	"update position request"
+//	@Success		200		{object}	common.Response[position.Response]	"Updated position"
+//	@Failure		400		{object}	common.Response[any]					"Incorrect data format in request"
+//	@Failure		404		{object}	common.Response[any]					"Position not found"
+//	@Failure		409		{object}	common.Response[any]					"Position name already exists"
+//	@Failure		500		{object}	common.Response[any]					"Internal server error"
+//	@Router			/admin/positions/{id} [put]
+func (c *Controller) UpdatePosition(ctx *fiber.Ctx) error {
+	c.logger.Info("Received update position request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parsePositionId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid position ID format")
+	}
+
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse update position request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.Id = id
+
+	position, err := c.positionService.UpdatePosition(ctx.UserContext(), request)
+	if err != nil {
+		return c.handlePositionError(ctx, err, id)
+	}
+
+	c.logger.Info("Position updated successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, position)
+}
+
+// DeletePosition удаляет должность
+//
+// @Security		OAuth2AccessCode[write]
+//
+//	@Summary		Delete a position
+//	@Description	Delete a position that no employee holds. Otherwise 409 lists the employees
+//	@Tags			positions
+//	@Produce		json
+//	@Param			id	path		int										true	"Position ID"
+//	@Success		200	{object}	common.Response[any]					"Position deleted successfully"
+//	@Failure		400	{object}	common.Response[any]					"Invalid position ID format"
+//	@Failure		404	{object}	common.Response[any]					"Position not found"
+//	@Failure		409	{object}	common.Response[position.Dependents]	"Position is held by employees"
+//	@Failure		500	{object}	common.Response[any]					"Internal server error"
+//	@Router			/admin/positions/{id} [delete]
+func (c *Controller) DeletePosition(ctx *fiber.Ctx) error {
+	c.logger.Info("Received delete position request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parsePositionId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid position ID format")
+	}
+
+	if err := c.positionService.DeleteById(ctx.UserContext(), id); err != nil {
+		return c.handlePositionError(ctx, err, id)
+	}
+
+	c.logger.Info("Position deleted successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, fiber.Map{"message": "Position deleted successfully"})
+}
+
+// GetPosition возвращает должность по ID
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		Get position by ID
+//	@Tags			positions
+//	@Produce		json
+//	@Param			id	path		int										true	"Position ID"
+//	@Success		200	{object}	common.Response[position.Response]	"Position"
+//	@Failure		400	{object}	common.Response[any]					"Invalid position ID format"
+//	@Failure		404	{object}	common.Response[any]					"Position not found"
+//	@Router			/positions/{id} [get]
+func (c *Controller) GetPosition(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received get position request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parsePositionId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid position ID format")
+	}
+
+	position, err := c.positionService.FindById(ctx.UserContext(), id)
+	if err != nil {
+		return c.handlePositionError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, position)
+}
+
+// FindAllPositions возвращает все должности
+//
+// @Security		OAuth2AccessCode[read]
+//
+//	@Summary		List positions
+//	@Tags			positions
+//	@Produce		json
+//	@Success		200	{object}	common.Response[[]position.Response]	"Positions ordered by name"
+//	@Failure		500	{object}	common.Response[any]					"Internal server error"
+//	@Router			/positions [get]
+func (c *Controller) FindAllPositions(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received find all positions request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	positions, err := c.positionService.FindAll(ctx.UserContext())
+	if err != nil {
+		c.logger.Error("Failed to find all positions",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when getting the list of positions")
+	}
+
+	return common.OkResponse(ctx, positions)
+}
+
+// извлекает ID должности из параметров пути
+func (c *Controller) parsePositionId(ctx *fiber.Ctx) (int64, error) {
+	idParam := ctx.Params("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Error("Invalid position ID format",
+			zap.String("id", idParam),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+	}
+	return id, err
+}
+
+// обрабатывает ошибки сервиса должностей
+func (c *Controller) handlePositionError(ctx *fiber.Ctx, err error, id int64) error {
+	var validationErr common.RequestValidationError
+	var conflictErr common.ConflictError
+	switch {
+	case errors.As(err, &validationErr):
+		c.logger.Warn("Position validation error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Position not found",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Position not found")
+
+	case errors.As(err, &conflictErr):
+		c.logger.Warn("Position conflict error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		if conflictErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message, conflictErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message)
+
+	case errors.As(err, &common.AlreadyExistsError{}):
+		c.logger.Warn("Position already exists",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+
+	default:
+		c.logger.Error("Position internal error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
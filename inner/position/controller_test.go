@@ -0,0 +1,164 @@
+package position
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock для сервиса
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindAll(ctx context.Context) ([]Response, error) {
+	args := m.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (m *MockService) CreatePosition(ctx context.Context, request CreateRequest) (Response, error) {
+	args := m.Called(request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) UpdatePosition(ctx context.Context, request UpdateRequest) (Response, error) {
+	args := m.Called(request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) DeleteById(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// Вспомогательные функции для создания Fiber app
+func setupTestApp() (*fiber.App, *MockService) {
+	app := fiber.New()
+	mockService := &MockService{}
+
+	server := &web.Server{
+		GroupApiV1User:  app.Group("/api/v1"),
+		GroupApiV1Admin: app.Group("/api/v1/admin"),
+	}
+
+	controller := NewController(server, mockService, createTestLogger())
+	controller.RegisterRoutes()
+
+	return app, mockService
+}
+
+func TestController_Positions(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		mockSetup    func(*MockService)
+		expectedCode int
+	}{
+		{
+			name:   "create position",
+			method: fiber.MethodPost,
+			path:   "/api/v1/admin/positions",
+			body:   `{"name":"Developer","role_ids":[2,3]}`,
+			mockSetup: func(m *MockService) {
+				m.On("CreatePosition", CreateRequest{Name: "Developer", RoleIds: []int64{2, 3}}).
+					Return(Response{Id: 1, Name: "Developer", RoleIds: []int64{2, 3}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "unknown default role returns bad request",
+			method: fiber.MethodPost,
+			path:   "/api/v1/admin/positions",
+			body:   `{"name":"Developer","role_ids":[9]}`,
+			mockSetup: func(m *MockService) {
+				m.On("CreatePosition", CreateRequest{Name: "Developer", RoleIds: []int64{9}}).
+					Return(Response{}, common.RequestValidationError{Message: "roles with ids [9] do not exist"})
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:   "duplicate name returns conflict",
+			method: fiber.MethodPut,
+			path:   "/api/v1/admin/positions/1",
+			body:   `{"name":"manager"}`,
+			mockSetup: func(m *MockService) {
+				m.On("UpdatePosition", UpdateRequest{Id: 1, Name: "manager"}).
+					Return(Response{}, common.AlreadyExistsError{Message: "position with name manager already exists"})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:   "delete held position returns conflict",
+			method: fiber.MethodDelete,
+			path:   "/api/v1/admin/positions/1",
+			mockSetup: func(m *MockService) {
+				m.On("DeleteById", int64(1)).Return(common.ConflictError{
+					Message: "position 1 is held by 2 employees",
+					Data:    Dependents{EmployeeIds: []int64{4, 5}},
+				})
+			},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:   "unknown position",
+			method: fiber.MethodGet,
+			path:   "/api/v1/positions/9",
+			mockSetup: func(m *MockService) {
+				m.On("FindById", int64(9)).
+					Return(Response{}, common.NotFoundError{Message: "position with id 9 not found"})
+			},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "list positions",
+			method: fiber.MethodGet,
+			path:   "/api/v1/positions",
+			mockSetup: func(m *MockService) {
+				m.On("FindAll").Return([]Response{{Id: 1, Name: "Developer", RoleIds: []int64{}}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid id",
+			method:       fiber.MethodGet,
+			path:         "/api/v1/positions/abc",
+			mockSetup:    func(m *MockService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockService := setupTestApp()
+			tt.mockSetup(mockService)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			var response common.Response[any]
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			assert.Equal(t, tt.expectedCode == http.StatusOK, response.Success)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package position
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Entity struct {
+	Id          int64   `db:"id"`
+	Name        string  `db:"name"`
+	Description *string `db:"description"`
+	// роли по умолчанию (из position_role), только для чтения
+	RoleIds   pq.Int64Array `db:"role_ids"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	roleIds := []int64(e.RoleIds)
+	if roleIds == nil {
+		roleIds = []int64{}
+	}
+	return Response{
+		Id:          e.Id,
+		Name:        e.Name,
+		Description: e.Description,
+		RoleIds:     roleIds,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+	}
+}
+
+type Response struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	RoleIds     []int64   `json:"role_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+} // @name PositionResponse
+
+// CreateRequest структура запроса на создание должности.
+// RoleIds - роли, которые назначаются каждому сотруднику, принятому или переведённому на должность
+type CreateRequest struct {
+	Name        string  `json:"name" validate:"required,min=2,max=100" example:"Developer"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=500" example:"Backend developer"`
+	RoleIds     []int64 `json:"role_ids" validate:"max=50,dive,min=1" example:"1,2"`
+} // @name PositionCreateRequest
+
+func (req *CreateRequest) toEntity() Entity {
+	return Entity{
+		Name:        req.Name,
+		Description: req.Description,
+		RoleIds:     uniqueIds(req.RoleIds),
+	}
+}
+
+// UpdateRequest структура запроса на полное обновление должности.
+// RoleIds заменяет набор ролей по умолчанию; уже назначенные сотрудникам роли не отзываются
+type UpdateRequest struct {
+	Id          int64   `json:"-"`
+	Name        string  `json:"name" validate:"required,min=2,max=100" example:"Developer"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=500" example:"Backend developer"`
+	RoleIds     []int64 `json:"role_ids" validate:"max=50,dive,min=1" example:"1,2"`
+} // @name PositionUpdateRequest
+
+// применяет полное обновление к сущности
+func (req *UpdateRequest) applyTo(entity *Entity) {
+	entity.Name = req.Name
+	entity.Description = req.Description
+	entity.RoleIds = uniqueIds(req.RoleIds)
+}
+
+// Dependents сотрудники, занимающие удаляемую должность
+type Dependents struct {
+	EmployeeIds []int64 `json:"employee_ids"`
+} // @name PositionDependents
+
+// убирает повторы, сохраняя порядок; пустой список остаётся пустым, а не nil
+func uniqueIds(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	result := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package position
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+// роли по умолчанию собираются в массив; должность без ролей получает пустой массив
+const positionColumns = `position.id, position.name, position.description,
+	COALESCE((
+		SELECT array_agg(pr.role_id ORDER BY pr.role_id) FROM position_role pr WHERE pr.position_id = position.id
+	), '{}') AS role_ids,
+	position.created_at, position.updated_at`
+
+const selectPosition = `SELECT ` + positionColumns + ` FROM position`
+
+func NewPositionRepository(database *sqlx.DB) *Repository {
+	return &Repository{db: database}
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (position Entity, err error) {
+	err = r.db.GetContext(ctx, &position, selectPosition+" WHERE id = $1", id)
+	return position, err
+}
+
+func (r *Repository) FindAll(ctx context.Context) ([]Entity, error) {
+	var positions []Entity
+	err := r.db.SelectContext(ctx, &positions, selectPosition+" ORDER BY name")
+	return positions, err
+}
+
+// Транзакционные методы
+func (r *Repository) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	return r.db.BeginTxx(ctx, nil)
+}
+
+// Проверить, занято ли название другой должностью; названия сравниваются без учёта регистра.
+// excludeId = 0 проверяет все должности
+func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (isExists bool, err error) {
+	err = tx.GetContext(
+		ctx,
+		&isExists,
+		"select exists(select 1 from position where lower(name) = lower($1) and id <> $2)",
+		name, excludeId,
+	)
+	return isExists, err
+}
+
+// Создать новую должность; роли по умолчанию сохраняются отдельно через ReplaceRolesTx
+func (r *Repository) SaveTx(ctx context.Context, tx *sqlx.Tx, position Entity) (created Entity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&created,
+		`INSERT INTO position (name, description) VALUES ($1, $2)
+		RETURNING id, name, description, created_at, updated_at`,
+		position.Name, position.Description)
+	return created, err
+}
+
+// Найти должность по id и заблокировать строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (position Entity, err error) {
+	err = tx.GetContext(ctx, &position, selectPosition+" WHERE id = $1 FOR UPDATE OF position", id)
+	return position, err
+}
+
+// Обновить должность
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, position Entity) (updated Entity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&updated,
+		`UPDATE position SET name = $1, description = $2, updated_at = clock_timestamp()
+		WHERE id = $3
+		RETURNING id, name, description, created_at, updated_at`,
+		position.Name, position.Description, position.Id)
+	return updated, err
+}
+
+// Заменить набор ролей по умолчанию
+func (r *Repository) ReplaceRolesTx(ctx context.Context, tx *sqlx.Tx, positionId int64, roleIds []int64) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM position_role WHERE position_id = $1", positionId)
+	if err != nil {
+		return err
+	}
+	if len(roleIds) == 0 {
+		return nil
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO position_role (position_id, role_id) SELECT $1, unnest($2::bigint[])",
+		positionId, pq.Array(roleIds))
+	return err
+}
+
+// Удалить должность вместе с её ролями по умолчанию
+func (r *Repository) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM position WHERE id = $1", id)
+	return err
+}
+
+// Найти среди переданных id роли, которых нет или которые удалены
+func (r *Repository) FindMissingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error) {
+	var missing []int64
+	err := tx.SelectContext(
+		ctx,
+		&missing,
+		`SELECT requested.role_id FROM unnest($1::bigint[]) AS requested(role_id)
+		WHERE NOT EXISTS (SELECT 1 FROM role r WHERE r.id = requested.role_id AND r.deleted_at IS NULL)
+		ORDER BY requested.role_id`,
+		pq.Array(roleIds))
+	return missing, err
+}
+
+// Найти неудалённых сотрудников, занимающих должность
+func (r *Repository) FindEmployeeIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error) {
+	var ids []int64
+	err := tx.SelectContext(ctx, &ids,
+		"SELECT id FROM employee WHERE position_id = $1 AND deleted_at IS NULL ORDER BY id", id)
+	return ids, err
+}
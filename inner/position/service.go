@@ -0,0 +1,353 @@
+package position
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/validator"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Service struct {
+	repo      Repo
+	auditor   Auditor
+	validator Validator
+	logger    *common.Logger
+}
+
+type Repo interface {
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindAll(ctx context.Context) ([]Entity, error)
+	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
+	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (bool, error)
+	SaveTx(ctx context.Context, tx *sqlx.Tx, position Entity) (Entity, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, position Entity) (Entity, error)
+	ReplaceRolesTx(ctx context.Context, tx *sqlx.Tx, positionId int64, roleIds []int64) error
+	DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+	FindMissingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error)
+	FindEmployeeIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error)
+}
+
+// интерфейс журнала аудита: событие пишется в транзакции изменения
+type Auditor interface {
+	Record(ctx context.Context, tx *sqlx.Tx, event audit.Event) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// функция-конструктор
+func NewService(repo Repo, auditor Auditor, validator Validator, logger *common.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		auditor:   auditor,
+		validator: validator,
+		logger:    logger,
+	}
+}
+
+// Метод для создания новой должности вместе с набором ролей по умолчанию
+func (svc *Service) CreatePosition(ctx context.Context, request CreateRequest) (response Response, err error) {
+	svc.logger.Info("Creating new position", zap.String("name", request.Name))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for position creation",
+			zap.String("name", request.Name),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error create position: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, 0)
+	}()
+
+	entity := request.toEntity()
+	if err = svc.checkPosition(ctx, tx, entity); err != nil {
+		return Response{}, err
+	}
+
+	created, err := svc.repo.SaveTx(ctx, tx, entity)
+	if err != nil {
+		svc.logger.Error("Failed to save position",
+			zap.String("name", request.Name),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error creating position with name: %s %w", request.Name, err)
+	}
+	if err = svc.replaceRoles(ctx, tx, created.Id, entity.RoleIds); err != nil {
+		return Response{}, err
+	}
+	created.RoleIds = entity.RoleIds
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionCreate,
+		EntityType: audit.EntityPosition,
+		EntityId:   created.Id,
+		After:      created.toResponse(),
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Position created successfully",
+		zap.String("name", created.Name),
+		zap.Int64("id", created.Id))
+	return created.toResponse(), nil
+}
+
+// Метод для полного обновления должности. Новый набор ролей по умолчанию применяется
+// к сотрудникам при следующем приёме или переводе на должность
+func (svc *Service) UpdatePosition(ctx context.Context, request UpdateRequest) (response Response, err error) {
+	svc.logger.Info("Updating position", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for position update",
+			zap.Int64("id", request.Id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error update position: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, request.Id)
+	}()
+
+	entity, err := svc.findForUpdate(ctx, tx, request.Id)
+	if err != nil {
+		return Response{}, err
+	}
+
+	before := entity.toResponse()
+	request.applyTo(&entity)
+
+	if err = svc.checkPosition(ctx, tx, entity); err != nil {
+		return Response{}, err
+	}
+
+	updated, err := svc.repo.UpdateTx(ctx, tx, entity)
+	if err != nil {
+		svc.logger.Error("Failed to update position",
+			zap.Int64("id", request.Id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error updating position with id %d: %w", request.Id, err)
+	}
+	if err = svc.replaceRoles(ctx, tx, updated.Id, entity.RoleIds); err != nil {
+		return Response{}, err
+	}
+	updated.RoleIds = entity.RoleIds
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionUpdate,
+		EntityType: audit.EntityPosition,
+		EntityId:   request.Id,
+		Before:     before,
+		After:      updated.toResponse(),
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Position updated successfully", zap.Int64("id", request.Id))
+	return updated.toResponse(), nil
+}
+
+// Метод для удаления должности. Должность, которую занимают сотрудники,
+// не удаляется: ConflictError содержит список сотрудников
+func (svc *Service) DeleteById(ctx context.Context, id int64) (err error) {
+	svc.logger.Info("Deleting position by ID", zap.Int64("id", id))
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for position deletion",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error delete position: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	entity, err := svc.findForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	employeeIds, err := svc.repo.FindEmployeeIdsTx(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("error finding employees of position %d: %w", id, err)
+	}
+	if len(employeeIds) > 0 {
+		svc.logger.Warn("Position has employees, deletion rejected",
+			zap.Int64("id", id),
+			zap.Int64s("employee_ids", employeeIds))
+		return common.ConflictError{
+			Message: fmt.Sprintf("position %d is held by %d employees", id, len(employeeIds)),
+			Data:    Dependents{EmployeeIds: employeeIds},
+		}
+	}
+
+	if err = svc.repo.DeleteTx(ctx, tx, id); err != nil {
+		svc.logger.Error("Failed to delete position",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error deleting position with id %d: %w", id, err)
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionDelete,
+		EntityType: audit.EntityPosition,
+		EntityId:   id,
+		Before:     entity.toResponse(),
+	})
+	if err != nil {
+		return err
+	}
+
+	svc.logger.Info("Position deleted successfully", zap.Int64("id", id))
+	return nil
+}
+
+// находит должность и блокирует её строку; NotFoundError, если должности нет
+func (svc *Service) findForUpdate(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			svc.logger.Warn("Position not found", zap.Int64("id", id))
+			return Entity{}, common.NotFoundError{Message: fmt.Sprintf("position with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find position",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Entity{}, fmt.Errorf("error finding position with id %d: %w", id, err)
+	}
+	return entity, nil
+}
+
+// проверяет создаваемую или изменённую должность: название уникально без учёта регистра,
+// все роли по умолчанию существуют и не удалены
+func (svc *Service) checkPosition(ctx context.Context, tx *sqlx.Tx, entity Entity) error {
+	isExist, err := svc.repo.FindByNameTx(ctx, tx, entity.Name, entity.Id)
+	if err != nil {
+		svc.logger.Error("Failed to check position existence",
+			zap.String("name", entity.Name),
+			zap.Error(err))
+		return fmt.Errorf("error finding position by name: %s, %w", entity.Name, err)
+	}
+	if isExist {
+		svc.logger.Warn("Position already exists", zap.String("name", entity.Name))
+		return common.AlreadyExistsError{Message: fmt.Sprintf("position with name %s already exists", entity.Name)}
+	}
+
+	if len(entity.RoleIds) == 0 {
+		return nil
+	}
+	missing, err := svc.repo.FindMissingRoleIdsTx(ctx, tx, entity.RoleIds)
+	if err != nil {
+		svc.logger.Error("Failed to check default roles existence",
+			zap.Int64s("role_ids", entity.RoleIds),
+			zap.Error(err))
+		return fmt.Errorf("error finding roles: %w", err)
+	}
+	if len(missing) > 0 {
+		return common.RequestValidationError{
+			Message: fmt.Sprintf("roles with ids %v do not exist", missing),
+			Data:    map[string][]int64{"missing_role_ids": missing},
+		}
+	}
+	return nil
+}
+
+// сохраняет набор ролей по умолчанию
+func (svc *Service) replaceRoles(ctx context.Context, tx *sqlx.Tx, id int64, roleIds []int64) error {
+	if err := svc.repo.ReplaceRolesTx(ctx, tx, id, roleIds); err != nil {
+		svc.logger.Error("Failed to save position default roles",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error saving default roles of position %d: %w", id, err)
+	}
+	return nil
+}
+
+// валидация запроса на создание или изменение должности
+func (svc *Service) validateRequest(request any) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Position request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return nil
+}
+
+func (svc *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	svc.logger.Debug("Finding position by ID", zap.Int64("id", id))
+
+	position, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("position with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find position by ID",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding position with id %d: %w", id, err)
+	}
+
+	return position.toResponse(), nil
+}
+
+func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
+	svc.logger.Debug("Fetching all positions")
+
+	positions, err := svc.repo.FindAll(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to fetch all positions", zap.Error(err))
+		return nil, fmt.Errorf("error finding all positions: %w", err)
+	}
+
+	responses := make([]Response, len(positions))
+	for i, entity := range positions {
+		responses[i] = entity.toResponse()
+	}
+	return responses, nil
+}
+
+// завершает транзакцию: откатывает при ошибке, иначе фиксирует.
+// Возвращает исходную ошибку или ошибку фиксации
+func (svc *Service) finishTransaction(tx *sqlx.Tx, err error, id int64) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			svc.logger.Error("Failed to rollback transaction",
+				zap.Int64("id", id),
+				zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		svc.logger.Error("Failed to commit transaction",
+			zap.Int64("id", id),
+			zap.Error(commitErr))
+		return commitErr
+	}
+	return nil
+}
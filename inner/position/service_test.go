@@ -0,0 +1,294 @@
+package position
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+type MockValidator struct {
+	mock.Mock
+}
+
+// журнал аудита для тестов: запоминает записанные события
+type StubAuditor struct {
+	events []audit.Event
+}
+
+func (a *StubAuditor) Record(ctx context.Context, tx *sqlx.Tx, event audit.Event) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func (m *MockValidator) Validate(request any) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindById(ctx context.Context, id int64) (Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
+	args := m.Called()
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) BeginTransaction(ctx context.Context) (*sqlx.Tx, error) {
+	args := m.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockRepo) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (bool, error) {
+	args := m.Called(name, excludeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, position Entity) (Entity, error) {
+	args := m.Called(position)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, position Entity) (Entity, error) {
+	args := m.Called(position)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) ReplaceRolesTx(ctx context.Context, tx *sqlx.Tx, positionId int64, roleIds []int64) error {
+	args := m.Called(positionId, roleIds)
+	return args.Error(0)
+}
+
+func (m *MockRepo) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindMissingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error) {
+	args := m.Called(roleIds)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) FindEmployeeIdsTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error) {
+	args := m.Called(id)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func createTestLogger() *common.Logger {
+	cfg := common.Config{
+		DbDriverName:   "postgres",
+		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
+		AppName:        "test_app",
+		AppVersion:     "1.0.0",
+		LogLevel:       "DEBUG",
+		LogDevelopMode: true,
+	}
+	return common.NewLogger(cfg)
+}
+
+func newMockTx(t *testing.T, commit bool) (*sqlx.Tx, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	sqlMock.ExpectBegin()
+	if commit {
+		sqlMock.ExpectCommit()
+	} else {
+		sqlMock.ExpectRollback()
+	}
+
+	tx, err := sqlx.NewDb(db, "postgres").Beginx()
+	require.NoError(t, err)
+	return tx, sqlMock
+}
+
+func TestService_CreatePosition(t *testing.T) {
+	t.Run("Successful creation with default roles", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		request := CreateRequest{Name: "Developer", RoleIds: []int64{2, 3, 2}}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByNameTx", "Developer", int64(0)).Return(false, nil)
+		mockRepo.On("FindMissingRoleIdsTx", []int64{2, 3}).Return([]int64{}, nil)
+		mockRepo.On("SaveTx", mock.AnythingOfType("position.Entity")).
+			Return(Entity{Id: 5, Name: "Developer"}, nil)
+		mockRepo.On("ReplaceRolesTx", int64(5), []int64{2, 3}).Return(nil)
+
+		response, err := svc.CreatePosition(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), response.Id)
+		assert.Equal(t, []int64{2, 3}, response.RoleIds)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionCreate, auditor.events[0].Action)
+			assert.Equal(t, audit.EntityPosition, auditor.events[0].EntityType)
+		}
+	})
+
+	t.Run("Duplicate name", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := CreateRequest{Name: "developer"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByNameTx", "developer", int64(0)).Return(true, nil)
+
+		_, err := svc.CreatePosition(context.Background(), request)
+
+		var alreadyExistsErr common.AlreadyExistsError
+		assert.True(t, errors.As(err, &alreadyExistsErr))
+		mockRepo.AssertNotCalled(t, "SaveTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown default role", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := CreateRequest{Name: "Developer", RoleIds: []int64{2, 9}}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByNameTx", "Developer", int64(0)).Return(false, nil)
+		mockRepo.On("FindMissingRoleIdsTx", []int64{2, 9}).Return([]int64{9}, nil)
+
+		_, err := svc.CreatePosition(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, map[string][]int64{"missing_role_ids": {9}}, validationErr.Data)
+		mockRepo.AssertNotCalled(t, "SaveTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_UpdatePosition(t *testing.T) {
+	t.Run("Default roles are replaced", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+		request := UpdateRequest{Id: 1, Name: "Developer", RoleIds: []int64{}}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(1)).Return(Entity{Id: 1, Name: "Dev", RoleIds: []int64{2}}, nil)
+		mockRepo.On("FindByNameTx", "Developer", int64(1)).Return(false, nil)
+		mockRepo.On("UpdateTx", mock.AnythingOfType("position.Entity")).Return(Entity{Id: 1, Name: "Developer"}, nil)
+		mockRepo.On("ReplaceRolesTx", int64(1), []int64{}).Return(nil)
+
+		response, err := svc.UpdatePosition(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Developer", response.Name)
+		assert.Empty(t, response.RoleIds)
+		mockRepo.AssertNotCalled(t, "FindMissingRoleIdsTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, []int64{2}, auditor.events[0].Before.(Response).RoleIds)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+		request := UpdateRequest{Id: 9, Name: "Developer"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(9)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := svc.UpdatePosition(context.Background(), request)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_DeleteById(t *testing.T) {
+	t.Run("Position held by employees is not deleted", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(1)).Return(Entity{Id: 1, Name: "Developer"}, nil)
+		mockRepo.On("FindEmployeeIdsTx", int64(1)).Return([]int64{4, 5}, nil)
+
+		err := svc.DeleteById(context.Background(), 1)
+
+		var conflictErr common.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		assert.Equal(t, Dependents{EmployeeIds: []int64{4, 5}}, conflictErr.Data)
+		mockRepo.AssertNotCalled(t, "DeleteTx", mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Successful deletion", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		svc := NewService(mockRepo, auditor, new(MockValidator), createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", int64(1)).Return(Entity{Id: 1, Name: "Developer"}, nil)
+		mockRepo.On("FindEmployeeIdsTx", int64(1)).Return([]int64{}, nil)
+		mockRepo.On("DeleteTx", int64(1)).Return(nil)
+
+		err := svc.DeleteById(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionDelete, auditor.events[0].Action)
+		}
+	})
+}
+
+func TestService_FindById_NotFound(t *testing.T) {
+	mockRepo := new(MockRepo)
+	svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+	mockRepo.On("FindById", int64(9)).Return(Entity{}, sql.ErrNoRows)
+
+	_, err := svc.FindById(context.Background(), 9)
+
+	var notFoundErr common.NotFoundError
+	assert.True(t, errors.As(err, &notFoundErr))
+}
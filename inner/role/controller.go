@@ -5,7 +5,9 @@ import (
 	"errors"
 	"idm/inner/common"
 	"idm/inner/web"
+	"iter"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
@@ -21,10 +23,30 @@ type Controller struct {
 type Svc interface {
 	FindById(ctx context.Context, id int64) (Response, error)
 	CreateRole(ctx context.Context, request CreateRequest) (int64, error)
-	FindAll(ctx context.Context) ([]Response, error)
+	FindWithCursor(ctx context.Context, request CursorRequest) (CursorPage, error)
+	Export(ctx context.Context, sort string) (iter.Seq2[ExportRecord, error], error)
 	FindByIds(ctx context.Context, ids []int64) ([]Response, error)
-	DeleteById(ctx context.Context, id int64) error
+	DeleteById(ctx context.Context, request DeleteRequest) (DeleteResponse, error)
 	DeleteByIds(ctx context.Context, ids []int64) error
+	UpdateRole(ctx context.Context, request UpdateRequest) (Response, error)
+	PatchRole(ctx context.Context, request PatchRequest) (Response, error)
+	FindTree(ctx context.Context) ([]TreeNode, error)
+	FindAncestors(ctx context.Context, id int64) ([]HierarchyResponse, error)
+	FindDescendants(ctx context.Context, id int64) ([]HierarchyResponse, error)
+	FindEffectiveRoles(ctx context.Context, employeeId int64) ([]EffectiveRoleResponse, error)
+	Restore(ctx context.Context, id int64) (Response, error)
+	Activate(ctx context.Context, id int64) (Response, error)
+	Deactivate(ctx context.Context, id int64) (Response, error)
+	Purge(ctx context.Context, request PurgeRequest) (PurgeResponse, error)
+	FindSodRules(ctx context.Context) ([]SodRuleResponse, error)
+	FindSodRuleById(ctx context.Context, id int64) (SodRuleResponse, error)
+	CreateSodRule(ctx context.Context, request SodRuleRequest) (SodRuleResponse, error)
+	UpdateSodRule(ctx context.Context, request SodRuleRequest) (SodRuleResponse, error)
+	DeleteSodRule(ctx context.Context, id int64) error
+	CreateSodException(ctx context.Context, request SodExceptionRequest) (SodExceptionResponse, error)
+	FindSodExceptions(ctx context.Context, filter SodExceptionFilter) ([]SodExceptionResponse, error)
+	RevokeSodException(ctx context.Context, id int64) (SodExceptionResponse, error)
+	FindSodViolations(ctx context.Context, filter SodViolationFilter) ([]SodViolationResponse, error)
 }
 
 func NewController(server *web.Server, roleService Svc, logger *common.Logger) *Controller {
@@ -40,12 +62,39 @@ func (c *Controller) RegisterRoutes() {
 	c.logger.Info("Registering role routes")
 	// полный маршрут получится "/api/v1/roles"
 	api := c.server.GroupApiV1
-	api.Post("/roles", c.CreateRole)
+	// владелец роли согласует запросы доступа к ней, поэтому создавать роли могут только администраторы
+	api.Post("/roles", web.RequireRole(web.IdmAdmin, c.logger), c.CreateRole)
+	// статические маршруты регистрируются раньше "/roles/:id", иначе он их перехватит
+	api.Get("/roles/tree", c.FindRoleTree)
+	api.Get("/roles/export", c.ExportRoles)
+	api.Get("/roles/employees/:employeeId/effective", c.FindEffectiveRoles)
+	api.Get("/roles/:id/ancestors", c.FindRoleAncestors)
+	api.Get("/roles/:id/descendants", c.FindRoleDescendants)
 	api.Get("/roles/:id", c.FindRoleById)
 	api.Get("/roles", c.FindAllRoles)
 	api.Post("/roles/ids", c.FindRoleByIds)
-	api.Delete("/roles/:id", c.DeleteRoleById)
-	api.Delete("/roles", c.DeleteRoleByIds)
+	admin := c.server.GroupApiV1Admin
+	// изменение роли и её места в иерархии меняет права всех её обладателей: "/api/v1/admin/roles/:id"
+	admin.Put("/roles/:id", c.UpdateRole)
+	admin.Patch("/roles/:id", c.PatchRole)
+	admin.Post("/roles/:id/restore", c.RestoreRole)
+	admin.Post("/roles/:id/activate", c.ActivateRole)
+	admin.Post("/roles/:id/deactivate", c.DeactivateRole)
+	// удаление роли отзывает её у обладателей или переназначает их на другую роль: "/api/v1/admin/roles/:id"
+	admin.Delete("/roles/:id", c.DeleteRoleById)
+	admin.Delete("/roles", c.DeleteRoleByIds)
+	// окончательное удаление доступно только администраторам: "/api/v1/admin/roles/purge"
+	admin.Post("/roles/purge", c.PurgeRoles)
+	// правила разделения обязанностей, исключения из них и отчёт о нарушениях: "/api/v1/admin/roles/sod/..."
+	admin.Get("/roles/sod/rules", c.FindSodRules)
+	admin.Post("/roles/sod/rules", c.CreateSodRule)
+	admin.Get("/roles/sod/rules/:id", c.FindSodRuleById)
+	admin.Put("/roles/sod/rules/:id", c.UpdateSodRule)
+	admin.Delete("/roles/sod/rules/:id", c.DeleteSodRule)
+	admin.Get("/roles/sod/exceptions", c.FindSodExceptions)
+	admin.Post("/roles/sod/exceptions", c.CreateSodException)
+	admin.Delete("/roles/sod/exceptions/:id", c.RevokeSodException)
+	admin.Get("/roles/sod/violations", c.FindSodViolations)
 	c.logger.Info("Role routes registered successfully")
 }
 
@@ -69,7 +118,7 @@ func (c *Controller) CreateRole(ctx *fiber.Ctx) error {
 	c.logger.Debug("create role: received request", zap.Any("request", request))
 
 	// вызываем метод CreateRole сервиса role.Service
-	newRoleId, err := c.roleService.CreateRole(ctx.Context(), request)
+	newRoleId, err := c.roleService.CreateRole(ctx.UserContext(), request)
 	if err != nil {
 		return c.handleCreateRoleError(ctx, err, request)
 	}
@@ -135,7 +184,7 @@ func (c *Controller) FindRoleById(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
 	}
 
-	role, err := c.roleService.FindById(ctx.Context(), id)
+	role, err := c.roleService.FindById(ctx.UserContext(), id)
 	if err != nil {
 		c.logger.Error("Failed to find role by ID",
 			zap.Int64("id", id),
@@ -151,25 +200,97 @@ func (c *Controller) FindRoleById(ctx *fiber.Ctx) error {
 	return common.OkResponse(ctx, role)
 }
 
+// функция-хендлер для GET запроса по маршруту "/api/v1/roles".
+// Роли выводятся постранично по курсору: ?limit=N (по умолчанию 50), ?sort=поле и ?cursor=
+// со значением next или prev из предыдущего ответа.
+// Фильтры: ?ownerId=, ?risk= (повторяющийся или через запятую), ?category=
+// и ?label=ключ:значение (повторяющийся, роль должна иметь все метки)
 func (c *Controller) FindAllRoles(ctx *fiber.Ctx) error {
 	c.logger.Debug("Received find all roles request",
 		zap.String("method", ctx.Method()),
 		zap.String("path", ctx.Path()),
 		zap.String("ip", ctx.IP()))
 
-	roles, err := c.roleService.FindAll(ctx.Context())
+	limit, err := strconv.Atoi(ctx.Query("limit", "50"))
 	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid limit parameter")
+	}
+	cursor, err := c.server.Cursors.DecodeOptional(ctx.Query("cursor"))
+	if err != nil {
+		c.logger.Warn("Invalid roles cursor",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid cursor parameter")
+	}
+
+	filter, err := parseFilter(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	page, err := c.roleService.FindWithCursor(ctx.UserContext(), CursorRequest{
+		Limit:  limit,
+		Sort:   ctx.Query("sort"),
+		Filter: filter,
+		Cursor: cursor,
+	})
+	if err != nil {
+		var validationErr common.RequestValidationError
+		if errors.As(err, &validationErr) {
+			if validationErr.Data != nil {
+				return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message, validationErr.Data)
+			}
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+		}
 		c.logger.Error("Failed to find all roles",
 			zap.Error(err),
 			zap.String("ip", ctx.IP()))
 		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when getting the list of roles")
 	}
 
-	c.logger.Debug("All roles retrieved successfully",
-		zap.Int("count", len(roles)),
+	c.logger.Debug("Roles page retrieved successfully",
+		zap.Int("count", len(page.Data)),
 		zap.String("ip", ctx.IP()))
 
-	return common.OkResponse(ctx, roles)
+	return common.OkResponse(ctx, CursorPageResponse{
+		Data: page.Data,
+		Next: c.server.Cursors.EncodeOptional(page.Next),
+		Prev: c.server.Cursors.EncodeOptional(page.Prev),
+	})
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/roles/export".
+// Все роли выгружаются потоком в CSV или JSON Lines (?format=csv|jsonl, по умолчанию csv)
+// в порядке ?sort= вывода по курсору
+func (c *Controller) ExportRoles(ctx *fiber.Ctx) error {
+	c.logger.Info("Received export roles request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	format, err := common.ParseExportFormat(ctx.Query("format"))
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid format parameter")
+	}
+
+	records, err := c.roleService.Export(ctx.UserContext(), ctx.Query("sort"))
+	if err != nil {
+		var validationErr common.RequestValidationError
+		if errors.As(err, &validationErr) {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+		}
+		c.logger.Error("Failed to export roles",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when exporting roles")
+	}
+
+	ip := ctx.IP()
+	return common.StreamExport(ctx, format, "roles", ExportHeader, records, func(err error) {
+		c.logger.Error("Roles export interrupted",
+			zap.Error(err),
+			zap.String("ip", ip))
+	})
 }
 
 func (c *Controller) FindRoleByIds(ctx *fiber.Ctx) error {
@@ -197,7 +318,7 @@ func (c *Controller) FindRoleByIds(ctx *fiber.Ctx) error {
 		zap.Int64s("ids", request.Ids),
 		zap.String("ip", ctx.IP()))
 
-	roles, err := c.roleService.FindByIds(ctx.Context(), request.Ids)
+	roles, err := c.roleService.FindByIds(ctx.UserContext(), request.Ids)
 	if err != nil {
 		c.logger.Error("Failed to find roles by IDs",
 			zap.Int64s("ids", request.Ids),
@@ -214,6 +335,9 @@ func (c *Controller) FindRoleByIds(ctx *fiber.Ctx) error {
 	return common.OkResponse(ctx, roles)
 }
 
+// функция-хендлер для DELETE запроса по маршруту "/api/v1/admin/roles/:id".
+// Политика обработки зависимых задаётся параметрами запроса:
+// ?policy=reject|reassign|cascade (по умолчанию reject) и ?replacementRoleId=N для reassign
 func (c *Controller) DeleteRoleById(ctx *fiber.Ctx) error {
 	c.logger.Info("Received delete role by ID request",
 		zap.String("method", ctx.Method()),
@@ -230,24 +354,33 @@ func (c *Controller) DeleteRoleById(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
 	}
 
+	request := DeleteRequest{Id: id, Policy: ctx.Query("policy")}
+	if replacementParam := ctx.Query("replacementRoleId"); replacementParam != "" {
+		request.ReplacementRoleId, err = strconv.ParseInt(replacementParam, 10, 64)
+		if err != nil {
+			c.logger.Error("Invalid replacement role ID in delete request",
+				zap.String("replacement_role_id", replacementParam),
+				zap.Error(err),
+				zap.String("ip", ctx.IP()))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid replacement role ID format")
+		}
+	}
+
 	c.logger.Info("Received delete role request",
 		zap.Int64("id", id),
+		zap.String("policy", request.Policy),
 		zap.String("ip", ctx.IP()))
 
-	err = c.roleService.DeleteById(ctx.Context(), id)
+	response, err := c.roleService.DeleteById(ctx.UserContext(), request)
 	if err != nil {
-		c.logger.Error("Failed to delete role",
-			zap.Int64("id", id),
-			zap.Error(err),
-			zap.String("ip", ctx.IP()))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when deleting a role")
+		return c.handleDeleteRoleError(ctx, err, id)
 	}
 
 	c.logger.Info("Role deleted successfully",
 		zap.Int64("id", id),
 		zap.String("ip", ctx.IP()))
 
-	return common.OkResponse(ctx, "Role deleted successfully")
+	return common.OkResponse(ctx, response)
 }
 
 func (c *Controller) DeleteRoleByIds(ctx *fiber.Ctx) error {
@@ -277,8 +410,16 @@ func (c *Controller) DeleteRoleByIds(ctx *fiber.Ctx) error {
 		zap.Int64s("ids", request.Ids),
 		zap.String("ip", ctx.IP()))
 
-	err := c.roleService.DeleteByIds(ctx.Context(), request.Ids)
+	err := c.roleService.DeleteByIds(ctx.UserContext(), request.Ids)
 	if err != nil {
+		var conflictErr common.ConflictError
+		if errors.As(err, &conflictErr) {
+			c.logger.Warn("Roles for deletion have dependents",
+				zap.Int64s("ids", request.Ids),
+				zap.Error(err),
+				zap.String("ip", ctx.IP()))
+			return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message, conflictErr.Data)
+		}
 		c.logger.Error("Failed to delete roles by IDs",
 			zap.Int64s("ids", request.Ids),
 			zap.Error(err),
@@ -292,3 +433,615 @@ func (c *Controller) DeleteRoleByIds(ctx *fiber.Ctx) error {
 
 	return common.OkResponse(ctx, fiber.Map{"message": "Roles deleted successfully"})
 }
+
+// функция-хендлер для PUT запроса по маршруту "/api/v1/admin/roles/:id"
+func (c *Controller) UpdateRole(ctx *fiber.Ctx) error {
+	c.logger.Info("Received update role request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse update role request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.Id = id
+
+	role, err := c.roleService.UpdateRole(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateRoleError(ctx, err, id)
+	}
+
+	c.logger.Info("Role updated successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, role)
+}
+
+// функция-хендлер для PATCH запроса по маршруту "/api/v1/admin/roles/:id"
+func (c *Controller) PatchRole(ctx *fiber.Ctx) error {
+	c.logger.Info("Received patch role request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	var request PatchRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse patch role request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.Id = id
+
+	role, err := c.roleService.PatchRole(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateRoleError(ctx, err, id)
+	}
+
+	c.logger.Info("Role patched successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, role)
+}
+
+// функция-хендлер для POST запроса по маршруту "/api/v1/admin/roles/:id/restore"
+func (c *Controller) RestoreRole(ctx *fiber.Ctx) error {
+	c.logger.Info("Received restore role request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	role, err := c.roleService.Restore(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleUpdateRoleError(ctx, err, id)
+	}
+
+	c.logger.Info("Role restored successfully",
+		zap.Int64("id", id),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, role)
+}
+
+// функция-хендлер для POST запроса по маршруту "/api/v1/admin/roles/:id/activate"
+func (c *Controller) ActivateRole(ctx *fiber.Ctx) error {
+	return c.changeRoleStatus(ctx, c.roleService.Activate)
+}
+
+// функция-хендлер для POST запроса по маршруту "/api/v1/admin/roles/:id/deactivate".
+// Назначения роли сохраняются, но перестают действовать до активации
+func (c *Controller) DeactivateRole(ctx *fiber.Ctx) error {
+	return c.changeRoleStatus(ctx, c.roleService.Deactivate)
+}
+
+// общая часть хендлеров активации и деактивации роли
+func (c *Controller) changeRoleStatus(ctx *fiber.Ctx, change func(ctx context.Context, id int64) (Response, error)) error {
+	c.logger.Info("Received change role status request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	role, err := change(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleUpdateRoleError(ctx, err, id)
+	}
+
+	c.logger.Info("Role status changed successfully",
+		zap.Int64("id", id),
+		zap.Bool("status", role.Status),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, role)
+}
+
+// функция-хендлер для POST запроса по маршруту "/api/v1/admin/roles/purge"
+func (c *Controller) PurgeRoles(ctx *fiber.Ctx) error {
+	c.logger.Info("Received purge roles request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request PurgeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse purge roles request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	response, err := c.roleService.Purge(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleUpdateRoleError(ctx, err, 0)
+	}
+
+	c.logger.Info("Deleted roles purged successfully",
+		zap.Int("count", len(response.PurgedIds)),
+		zap.String("ip", ctx.IP()))
+
+	return common.OkResponse(ctx, response)
+}
+
+// извлекает ID роли из параметров пути
+func (c *Controller) parseRoleId(ctx *fiber.Ctx) (int64, error) {
+	idParam := ctx.Params("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Error("Invalid role ID format",
+			zap.String("id", idParam),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+	}
+	return id, err
+}
+
+// обрабатывает ошибки при обновлении роли
+func (c *Controller) handleUpdateRoleError(ctx *fiber.Ctx, err error, id int64) error {
+	switch {
+	case errors.As(err, &common.RequestValidationError{}):
+		c.logger.Warn("Update role validation error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+
+		var validationErr common.RequestValidationError
+		errors.As(err, &validationErr)
+
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Role for update not found",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Role not found")
+
+	case errors.As(err, &common.AlreadyExistsError{}), errors.As(err, &common.ConflictError{}):
+		c.logger.Warn("Update role conflict error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+
+	default:
+		c.logger.Error("Update role internal error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// обрабатывает ошибки при удалении роли
+func (c *Controller) handleDeleteRoleError(ctx *fiber.Ctx, err error, id int64) error {
+	var validationErr common.RequestValidationError
+	var conflictErr common.ConflictError
+	switch {
+	case errors.As(err, &validationErr):
+		c.logger.Warn("Delete role validation error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("Role for deletion not found",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Role not found")
+
+	case errors.As(err, &conflictErr):
+		c.logger.Warn("Delete role conflict error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		if conflictErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message, conflictErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message)
+
+	default:
+		c.logger.Error("Failed to delete role",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when deleting a role")
+	}
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/roles/tree"
+func (c *Controller) FindRoleTree(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received role tree request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	tree, err := c.roleService.FindTree(ctx.UserContext())
+	if err != nil {
+		c.logger.Error("Failed to build role tree",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when building the role tree")
+	}
+
+	return common.OkResponse(ctx, tree)
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/roles/:id/ancestors"
+func (c *Controller) FindRoleAncestors(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received role ancestors request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	roles, err := c.roleService.FindAncestors(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleHierarchyError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, roles)
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/roles/:id/descendants"
+func (c *Controller) FindRoleDescendants(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received role descendants request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid role ID format")
+	}
+
+	roles, err := c.roleService.FindDescendants(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleHierarchyError(ctx, err, id)
+	}
+
+	return common.OkResponse(ctx, roles)
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/roles/employees/:employeeId/effective"
+func (c *Controller) FindEffectiveRoles(ctx *fiber.Ctx) error {
+	c.logger.Debug("Received effective roles request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	idParam := ctx.Params("employeeId")
+	employeeId, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.Error("Invalid employee ID format",
+			zap.String("employee_id", idParam),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID format")
+	}
+
+	roles, err := c.roleService.FindEffectiveRoles(ctx.UserContext(), employeeId)
+	if err != nil {
+		if errors.As(err, &common.NotFoundError{}) {
+			return common.ErrResponse(ctx, fiber.StatusNotFound, "Employee not found")
+		}
+		c.logger.Error("Failed to find effective roles",
+			zap.Int64("employee_id", employeeId),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Error when computing effective roles")
+	}
+
+	return common.OkResponse(ctx, roles)
+}
+
+// обрабатывает ошибки при запросах иерархии роли
+func (c *Controller) handleHierarchyError(ctx *fiber.Ctx, err error, id int64) error {
+	if errors.As(err, &common.NotFoundError{}) {
+		c.logger.Warn("Role not found",
+			zap.Int64("id", id),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, "Role not found")
+	}
+
+	c.logger.Error("Role hierarchy internal error",
+		zap.Int64("id", id),
+		zap.Error(err),
+		zap.String("ip", ctx.IP()))
+	return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/admin/roles/sod/rules"
+func (c *Controller) FindSodRules(ctx *fiber.Ctx) error {
+	rules, err := c.roleService.FindSodRules(ctx.UserContext())
+	if err != nil {
+		return c.handleSodError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, rules)
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/admin/roles/sod/rules/:id"
+func (c *Controller) FindSodRuleById(ctx *fiber.Ctx) error {
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid SoD rule ID format")
+	}
+
+	rule, err := c.roleService.FindSodRuleById(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleSodError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, rule)
+}
+
+// функция-хендлер для POST запроса по маршруту "/api/v1/admin/roles/sod/rules"
+func (c *Controller) CreateSodRule(ctx *fiber.Ctx) error {
+	c.logger.Info("Received create SoD rule request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request SodRuleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse create SoD rule request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	rule, err := c.roleService.CreateSodRule(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleSodError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, rule)
+}
+
+// функция-хендлер для PUT запроса по маршруту "/api/v1/admin/roles/sod/rules/:id"
+func (c *Controller) UpdateSodRule(ctx *fiber.Ctx) error {
+	c.logger.Info("Received update SoD rule request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid SoD rule ID format")
+	}
+
+	var request SodRuleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse update SoD rule request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+	request.Id = id
+
+	rule, err := c.roleService.UpdateSodRule(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleSodError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, rule)
+}
+
+// функция-хендлер для DELETE запроса по маршруту "/api/v1/admin/roles/sod/rules/:id"
+func (c *Controller) DeleteSodRule(ctx *fiber.Ctx) error {
+	c.logger.Info("Received delete SoD rule request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid SoD rule ID format")
+	}
+
+	if err := c.roleService.DeleteSodRule(ctx.UserContext(), id); err != nil {
+		return c.handleSodError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, fiber.Map{
+		"message": "SoD rule successfully deleted",
+	})
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/admin/roles/sod/exceptions".
+// Фильтры: ruleId, employeeId и active=true - только действующие исключения
+func (c *Controller) FindSodExceptions(ctx *fiber.Ctx) error {
+	var filter SodExceptionFilter
+	var err error
+	if filter.RuleId, filter.EmployeeId, err = parseSodQuery(ctx); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	filter.ActiveOnly = ctx.QueryBool("active")
+
+	exceptions, err := c.roleService.FindSodExceptions(ctx.UserContext(), filter)
+	if err != nil {
+		return c.handleSodError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, exceptions)
+}
+
+// функция-хендлер для POST запроса по маршруту "/api/v1/admin/roles/sod/exceptions"
+func (c *Controller) CreateSodException(ctx *fiber.Ctx) error {
+	c.logger.Info("Received create SoD exception request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	var request SodExceptionRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse create SoD exception request body",
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Incorrect data format in request")
+	}
+
+	exception, err := c.roleService.CreateSodException(ctx.UserContext(), request)
+	if err != nil {
+		return c.handleSodError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, exception)
+}
+
+// функция-хендлер для DELETE запроса по маршруту "/api/v1/admin/roles/sod/exceptions/:id"
+func (c *Controller) RevokeSodException(ctx *fiber.Ctx) error {
+	c.logger.Info("Received revoke SoD exception request",
+		zap.String("method", ctx.Method()),
+		zap.String("path", ctx.Path()),
+		zap.String("ip", ctx.IP()))
+
+	id, err := c.parseRoleId(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "Invalid SoD exception ID format")
+	}
+
+	exception, err := c.roleService.RevokeSodException(ctx.UserContext(), id)
+	if err != nil {
+		return c.handleSodError(ctx, err, id)
+	}
+	return common.OkResponse(ctx, exception)
+}
+
+// функция-хендлер для GET запроса по маршруту "/api/v1/admin/roles/sod/violations".
+// Фильтры: ruleId, employeeId и includeExcepted=true - вместе с нарушениями, разрешёнными исключениями
+func (c *Controller) FindSodViolations(ctx *fiber.Ctx) error {
+	var filter SodViolationFilter
+	var err error
+	if filter.RuleId, filter.EmployeeId, err = parseSodQuery(ctx); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	filter.IncludeExcepted = ctx.QueryBool("includeExcepted")
+
+	violations, err := c.roleService.FindSodViolations(ctx.UserContext(), filter)
+	if err != nil {
+		return c.handleSodError(ctx, err, 0)
+	}
+	return common.OkResponse(ctx, violations)
+}
+
+// разбирает фильтры ruleId и employeeId из query string; отсутствующий фильтр равен 0
+func parseSodQuery(ctx *fiber.Ctx) (ruleId, employeeId int64, err error) {
+	if value := ctx.Query("ruleId"); value != "" {
+		if ruleId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return 0, 0, errors.New("invalid ruleId parameter")
+		}
+	}
+	if value := ctx.Query("employeeId"); value != "" {
+		if employeeId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return 0, 0, errors.New("invalid employeeId parameter")
+		}
+	}
+	return ruleId, employeeId, nil
+}
+
+// разбирает фильтры списка ролей из параметров запроса
+func parseFilter(ctx *fiber.Ctx) (filter Filter, err error) {
+	if value := ctx.Query("ownerId"); value != "" {
+		if filter.OwnerId, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return Filter{}, errors.New("invalid ownerId parameter")
+		}
+	}
+	for _, value := range ctx.Context().QueryArgs().PeekMulti("risk") {
+		for _, part := range strings.Split(string(value), ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				filter.RiskLevels = append(filter.RiskLevels, part)
+			}
+		}
+	}
+	filter.Category = ctx.Query("category")
+	for _, value := range ctx.Context().QueryArgs().PeekMulti("label") {
+		key, labelValue, ok := strings.Cut(string(value), ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return Filter{}, errors.New("invalid label parameter, expected key:value")
+		}
+		if filter.Labels == nil {
+			filter.Labels = map[string]string{}
+		}
+		filter.Labels[strings.TrimSpace(key)] = strings.TrimSpace(labelValue)
+	}
+	return filter, nil
+}
+
+// обрабатывает ошибки запросов правил разделения обязанностей
+func (c *Controller) handleSodError(ctx *fiber.Ctx, err error, id int64) error {
+	var validationErr common.RequestValidationError
+	var conflictErr common.ConflictError
+	switch {
+	case errors.As(err, &validationErr):
+		c.logger.Warn("SoD request validation error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		if validationErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "Data validation error", validationErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, validationErr.Message)
+
+	case errors.As(err, &common.NotFoundError{}):
+		c.logger.Warn("SoD request target not found",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
+
+	case errors.As(err, &common.AlreadyExistsError{}):
+		c.logger.Warn("SoD request conflict error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusConflict, err.Error())
+
+	case errors.As(err, &conflictErr):
+		c.logger.Warn("SoD request conflict error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		if conflictErr.Data != nil {
+			return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message, conflictErr.Data)
+		}
+		return common.ErrResponse(ctx, fiber.StatusConflict, conflictErr.Message)
+
+	default:
+		c.logger.Error("SoD request internal error",
+			zap.Int64("id", id),
+			zap.Error(err),
+			zap.String("ip", ctx.IP()))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
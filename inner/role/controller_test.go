@@ -7,11 +7,15 @@ import (
 	"errors"
 	"idm/inner/common"
 	"idm/inner/web"
+	"io"
+	"iter"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -31,9 +35,9 @@ func (m *MockService) CreateRole(ctx context.Context, request CreateRequest) (in
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockService) DeleteById(ctx context.Context, id int64) error {
-	args := m.Called(id)
-	return args.Error(0)
+func (m *MockService) DeleteById(ctx context.Context, request DeleteRequest) (DeleteResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(DeleteResponse), args.Error(1)
 }
 
 func (m *MockService) DeleteByIds(ctx context.Context, ids []int64) error {
@@ -41,9 +45,14 @@ func (m *MockService) DeleteByIds(ctx context.Context, ids []int64) error {
 	return args.Error(0)
 }
 
-func (m *MockService) FindAll(ctx context.Context) ([]Response, error) {
-	args := m.Called()
-	return args.Get(0).([]Response), args.Error(1)
+func (m *MockService) FindWithCursor(ctx context.Context, request CursorRequest) (CursorPage, error) {
+	args := m.Called(request)
+	return args.Get(0).(CursorPage), args.Error(1)
+}
+
+func (m *MockService) Export(ctx context.Context, sort string) (iter.Seq2[ExportRecord, error], error) {
+	args := m.Called(sort)
+	return args.Get(0).(iter.Seq2[ExportRecord, error]), args.Error(1)
 }
 
 func (m *MockService) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
@@ -51,16 +60,116 @@ func (m *MockService) FindByIds(ctx context.Context, ids []int64) ([]Response, e
 	return args.Get(0).([]Response), args.Error(1)
 }
 
+func (m *MockService) UpdateRole(ctx context.Context, request UpdateRequest) (Response, error) {
+	args := m.Called(request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) PatchRole(ctx context.Context, request PatchRequest) (Response, error) {
+	args := m.Called(request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) FindTree(ctx context.Context) ([]TreeNode, error) {
+	args := m.Called()
+	return args.Get(0).([]TreeNode), args.Error(1)
+}
+
+func (m *MockService) FindAncestors(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).([]HierarchyResponse), args.Error(1)
+}
+
+func (m *MockService) FindDescendants(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).([]HierarchyResponse), args.Error(1)
+}
+
+func (m *MockService) FindEffectiveRoles(ctx context.Context, employeeId int64) ([]EffectiveRoleResponse, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).([]EffectiveRoleResponse), args.Error(1)
+}
+
+func (m *MockService) Restore(ctx context.Context, id int64) (Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Activate(ctx context.Context, id int64) (Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Deactivate(ctx context.Context, id int64) (Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (m *MockService) Purge(ctx context.Context, request PurgeRequest) (PurgeResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(PurgeResponse), args.Error(1)
+}
+
+func (m *MockService) FindSodRules(ctx context.Context) ([]SodRuleResponse, error) {
+	args := m.Called()
+	return args.Get(0).([]SodRuleResponse), args.Error(1)
+}
+
+func (m *MockService) FindSodRuleById(ctx context.Context, id int64) (SodRuleResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).(SodRuleResponse), args.Error(1)
+}
+
+func (m *MockService) CreateSodRule(ctx context.Context, request SodRuleRequest) (SodRuleResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(SodRuleResponse), args.Error(1)
+}
+
+func (m *MockService) UpdateSodRule(ctx context.Context, request SodRuleRequest) (SodRuleResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(SodRuleResponse), args.Error(1)
+}
+
+func (m *MockService) DeleteSodRule(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockService) CreateSodException(ctx context.Context, request SodExceptionRequest) (SodExceptionResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(SodExceptionResponse), args.Error(1)
+}
+
+func (m *MockService) FindSodExceptions(ctx context.Context, filter SodExceptionFilter) ([]SodExceptionResponse, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]SodExceptionResponse), args.Error(1)
+}
+
+func (m *MockService) RevokeSodException(ctx context.Context, id int64) (SodExceptionResponse, error) {
+	args := m.Called(id)
+	return args.Get(0).(SodExceptionResponse), args.Error(1)
+}
+
+func (m *MockService) FindSodViolations(ctx context.Context, filter SodViolationFilter) ([]SodViolationResponse, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]SodViolationResponse), args.Error(1)
+}
+
 // Вспомогательные функции для создания Fiber app
-func setupTestApp() (*fiber.App, *MockService) {
+// без ролей запрос выполняется от администратора
+func setupTestApp(roles ...string) (*fiber.App, *MockService) {
+	if len(roles) == 0 {
+		roles = []string{web.IdmAdmin}
+	}
 	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{
+			RealmAccess: web.RealmAccessClaims{Roles: roles},
+		}})
+		return c.Next()
+	})
 	mockService := &MockService{}
 
-	// Создаем mock web.Server
-	server := &web.Server{
-		GroupApiV1: app.Group("/api/v1"),
-	}
-
 	cfg := common.Config{
 		DbDriverName:   "postgres",
 		Dsn:            "localhost port=5432 user=wronguser password=wrongpass dbname=postgres sslmode=disable",
@@ -72,6 +181,15 @@ func setupTestApp() (*fiber.App, *MockService) {
 
 	logger := common.NewLogger(cfg)
 
+	// Создаем mock web.Server; группа администраторов проверяет роль так же, как web.NewServer
+	groupApiV1Admin := app.Group("/api/v1/admin")
+	groupApiV1Admin.Use(web.RequireRole(web.IdmAdmin, logger))
+	server := &web.Server{
+		GroupApiV1:      app.Group("/api/v1"),
+		GroupApiV1Admin: groupApiV1Admin,
+		Cursors:         common.NewCursorCodec("test-secret"),
+	}
+
 	controller := NewController(server, mockService, logger)
 	controller.RegisterRoutes()
 
@@ -355,3 +473,507 @@ func TestController_CreateRole_InvalidData_ReturnsValidationError(t *testing.T)
 
 	mockService.AssertExpectations(t)
 }
+
+func TestController_UpdateRole_Success(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	parentId := int64(1)
+	requestBody := UpdateRequest{Name: "Developer", Description: "Developers", Status: true, ParentId: &parentId}
+	expectedRequest := requestBody
+	expectedRequest.Id = 3
+
+	mockService.On("UpdateRole", expectedRequest).Return(Response{Id: 3, Name: "Developer", ParentId: &parentId}, nil)
+
+	jsonBody, _ := json.Marshal(requestBody)
+	req := httptest.NewRequest("PUT", "/api/v1/admin/roles/3", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_PatchRole_CycleConflict(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	cycleErr := common.ConflictError{Message: "role 3 cannot have parent 5: this would create a cycle"}
+	mockService.On("PatchRole", mock.AnythingOfType("PatchRequest")).Return(Response{}, cycleErr)
+
+	req := httptest.NewRequest("PATCH", "/api/v1/admin/roles/3", bytes.NewBufferString(`{"parent_id": 5}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	var response common.Response[any]
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, cycleErr.Message, response.Message)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_PatchRole_InvalidId(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	req := httptest.NewRequest("PATCH", "/api/v1/admin/roles/abc", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	mockService.AssertNotCalled(t, "PatchRole", mock.Anything)
+}
+
+func TestController_RoleMutations_ForbiddenForUser(t *testing.T) {
+	requests := []struct {
+		method string
+		url    string
+	}{
+		{"POST", "/api/v1/roles"},
+		{"PUT", "/api/v1/admin/roles/3"},
+		{"PATCH", "/api/v1/admin/roles/3"},
+		{"POST", "/api/v1/admin/roles/3/restore"},
+		{"POST", "/api/v1/admin/roles/3/activate"},
+		{"POST", "/api/v1/admin/roles/3/deactivate"},
+		{"DELETE", "/api/v1/admin/roles"},
+	}
+	for _, r := range requests {
+		t.Run(r.method+" "+r.url, func(t *testing.T) {
+			app, mockService := setupTestApp(web.IdmUser)
+
+			req := httptest.NewRequest(r.method, r.url, bytes.NewBufferString(`{"name":"Developer"}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestController_FindRoleTree(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	tree := []TreeNode{{
+		Response: Response{Id: 1, Name: "Root"},
+		Children: []TreeNode{{Response: Response{Id: 2, Name: "Admin"}, Children: []TreeNode{}}},
+	}}
+	mockService.On("FindTree").Return(tree, nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/roles/tree", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	// маршрут "/roles/tree" не должен перехватываться маршрутом "/roles/:id"
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response common.Response[[]TreeNode]
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Admin", response.Data[0].Children[0].Name)
+	mockService.AssertNotCalled(t, "FindById", mock.Anything)
+}
+
+func TestController_FindAllRoles_Cursor(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	next := &common.Cursor{Sort: "name", Value: "Admin", Id: 2}
+	mockService.On("FindWithCursor", CursorRequest{Limit: 1, Sort: "name"}).
+		Return(CursorPage{Data: []Response{{Id: 2, Name: "Admin"}}, Next: next}, nil).Once()
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/roles?limit=1&sort=name", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response common.Response[CursorPageResponse]
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Nil(t, response.Data.Prev)
+	if !assert.NotNil(t, response.Data.Next) {
+		return
+	}
+
+	// курсор из ответа возвращается сервису без изменений
+	mockService.On("FindWithCursor", CursorRequest{Limit: 1, Sort: "name", Cursor: next}).
+		Return(CursorPage{Data: []Response{}}, nil).Once()
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/roles?limit=1&sort=name&cursor="+*response.Data.Next, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_FindAllRoles_Filter(t *testing.T) {
+	t.Run("Owner, risk, category and labels", func(t *testing.T) {
+		app, mockService := setupTestApp()
+		mockService.On("FindWithCursor", CursorRequest{Limit: 50, Filter: Filter{
+			OwnerId:    7,
+			RiskLevels: []string{RiskHigh, RiskCritical},
+			Category:   "Billing",
+			Labels:     map[string]string{"app": "billing", "env": "prod"},
+		}}).Return(CursorPage{Data: []Response{}}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET",
+			"/api/v1/roles?ownerId=7&risk=high,critical&category=Billing&label=app:billing&label=env:prod", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid label", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/roles?label=billing", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "FindWithCursor", mock.Anything)
+	})
+}
+
+func TestController_FindAllRoles_TamperedCursor(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	forged := common.NewCursorCodec("another-secret").Encode(common.Cursor{Sort: "name", Value: "Admin", Id: 2})
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/roles?sort=name&cursor="+forged, nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	mockService.AssertNotCalled(t, "FindWithCursor", mock.Anything)
+}
+
+func TestController_ExportRoles(t *testing.T) {
+	created := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	records := func(yield func(ExportRecord, error) bool) {
+		yield(ExportRecord{
+			Id: 2, Name: "Admin", Desc: "Administrator, full access", Status: true, Parent: "Root",
+			Permissions: []string{"roles.read", "roles.write"}, Holders: 3, CreatedAt: created, UpdatedAt: created,
+		}, nil)
+	}
+
+	t.Run("CSV by default", func(t *testing.T) {
+		app, mockService := setupTestApp()
+		mockService.On("Export", "name").Return(iter.Seq2[ExportRecord, error](records), nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/roles/export?sort=name", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="roles.csv"`, resp.Header.Get("Content-Disposition"))
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "id,name,description,status,parent,permissions,holders,created_at,updated_at\n"+
+			`2,Admin,"Administrator, full access",true,Root,roles.read; roles.write,3,2025-07-01T09:00:00Z,2025-07-01T09:00:00Z`+"\n",
+			string(body))
+	})
+
+	t.Run("JSON Lines", func(t *testing.T) {
+		app, mockService := setupTestApp()
+		mockService.On("Export", "").Return(iter.Seq2[ExportRecord, error](records), nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/roles/export?format=jsonl", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+		var record ExportRecord
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&record))
+		assert.Equal(t, []string{"roles.read", "roles.write"}, record.Permissions)
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/roles/export?format=xlsx", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Export", mock.Anything)
+	})
+
+	t.Run("Invalid sort", func(t *testing.T) {
+		app, mockService := setupTestApp()
+		mockService.On("Export", "description").
+			Return(iter.Seq2[ExportRecord, error](nil), common.RequestValidationError{Message: "unsupported cursor sort"})
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/roles/export?sort=description", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestController_FindRoleAncestors_NotFound(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("FindAncestors", int64(404)).Return([]HierarchyResponse(nil), common.NotFoundError{Message: "role with id 404 not found"})
+
+	req := httptest.NewRequest("GET", "/api/v1/roles/404/ancestors", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_FindEffectiveRoles(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("FindEffectiveRoles", int64(7)).Return([]EffectiveRoleResponse{
+		{Response: Response{Id: 3, Name: "Guest"}},
+		{Response: Response{Id: 2, Name: "Admin"}, Inherited: true, Depth: 1, SourceRoleId: 3},
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/roles/employees/7/effective", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response common.Response[[]EffectiveRoleResponse]
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Len(t, response.Data, 2)
+	assert.True(t, response.Data[1].Inherited)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_RestoreRole(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("Restore", int64(3)).Return(Response{Id: 3, Name: "Admin"}, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/roles/3/restore", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_RestoreRole_NameTaken(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("Restore", int64(3)).Return(Response{}, common.AlreadyExistsError{Message: "role with name Admin already exists"})
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/roles/3/restore", nil)
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_ChangeRoleStatus(t *testing.T) {
+	t.Run("Deactivated", func(t *testing.T) {
+		app, mockService := setupTestApp()
+		mockService.On("Deactivate", int64(3)).Return(Response{Id: 3, Name: "Admin"}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/admin/roles/3/deactivate", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var response common.Response[Response]
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		assert.False(t, response.Data.Status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Activated role not found", func(t *testing.T) {
+		app, mockService := setupTestApp()
+		mockService.On("Activate", int64(3)).Return(Response{}, common.NotFoundError{Message: "role with id 3 not found"})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/admin/roles/3/activate", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Invalid ID", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/admin/roles/abc/activate", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "Activate", mock.Anything)
+	})
+}
+
+func TestController_PurgeRoles(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("Purge", PurgeRequest{RetentionDays: 30}).Return(PurgeResponse{PurgedIds: []int64{4}}, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/roles/purge", bytes.NewBufferString(`{"retention_days": 30}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response common.Response[PurgeResponse]
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4}, response.Data.PurgedIds)
+	mockService.AssertExpectations(t)
+}
+
+func TestController_DeleteRoleById(t *testing.T) {
+	t.Run("Passes policy from query", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("DeleteById", DeleteRequest{Id: 2, Policy: DeletePolicyReassign, ReplacementRoleId: 5}).
+			Return(DeleteResponse{DeletedRoleIds: []int64{2}, ReparentedRoleIds: []int64{3}, AffectedEmployeeIds: []int64{7}}, nil)
+
+		req := httptest.NewRequest("DELETE", "/api/v1/admin/roles/2?policy=reassign&replacementRoleId=5", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var response common.Response[DeleteResponse]
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{7}, response.Data.AffectedEmployeeIds)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Conflict lists dependents", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("DeleteById", DeleteRequest{Id: 2}).Return(DeleteResponse{}, common.ConflictError{
+			Message: "role 2 is referenced by 1 employees and 0 child roles",
+			Data:    Dependents{EmployeeIds: []int64{7}, ChildRoleIds: []int64{}},
+		})
+
+		req := httptest.NewRequest("DELETE", "/api/v1/admin/roles/2", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		var response common.Response[Dependents]
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{7}, response.Data.EmployeeIds)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid replacement role id", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		req := httptest.NewRequest("DELETE", "/api/v1/admin/roles/2?policy=reassign&replacementRoleId=abc", nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "DeleteById", mock.Anything)
+	})
+
+	t.Run("Forbidden for user with any policy", func(t *testing.T) {
+		for _, query := range []string{"", "?policy=cascade", "?policy=reassign&replacementRoleId=5"} {
+			app, mockService := setupTestApp(web.IdmUser)
+
+			resp, err := app.Test(httptest.NewRequest("DELETE", "/api/v1/admin/roles/2"+query, nil))
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode, query)
+			mockService.AssertNotCalled(t, "DeleteById", mock.Anything)
+		}
+	})
+}
+
+func TestController_CreateSodRule(t *testing.T) {
+	t.Run("Created", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("CreateSodRule", SodRuleRequest{Name: "Payments", RoleIds: []int64{3, 4}}).
+			Return(SodRuleResponse{Id: 1, Name: "Payments", RoleIds: []int64{3, 4}}, nil)
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/roles/sod/rules",
+			bytes.NewBufferString(`{"name":"Payments","role_ids":[3,4]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Name taken", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("CreateSodRule", mock.Anything).
+			Return(SodRuleResponse{}, common.AlreadyExistsError{Message: "SoD rule with name Payments already exists"})
+
+		req := httptest.NewRequest("POST", "/api/v1/admin/roles/sod/rules",
+			bytes.NewBufferString(`{"name":"Payments","role_ids":[3,4]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+}
+
+func TestController_DeleteSodRule_NotFound(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("DeleteSodRule", int64(9)).Return(common.NotFoundError{Message: "SoD rule with id 9 not found"})
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/api/v1/admin/roles/sod/rules/9", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestController_FindSodExceptions(t *testing.T) {
+	t.Run("Filtered", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		mockService.On("FindSodExceptions", SodExceptionFilter{RuleId: 1, EmployeeId: 7, ActiveOnly: true}).
+			Return([]SodExceptionResponse{{Id: 3}}, nil)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/admin/roles/sod/exceptions?ruleId=1&employeeId=7&active=true", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid rule id", func(t *testing.T) {
+		app, mockService := setupTestApp()
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/admin/roles/sod/exceptions?ruleId=abc", nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		mockService.AssertNotCalled(t, "FindSodExceptions", mock.Anything)
+	})
+}
+
+func TestController_FindSodViolations(t *testing.T) {
+	app, mockService := setupTestApp()
+
+	mockService.On("FindSodViolations", SodViolationFilter{RuleId: 1, IncludeExcepted: true}).
+		Return([]SodViolationResponse{{EmployeeId: 7, RuleId: 1, RoleId: 3, ConflictingRoleId: 4}}, nil)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/admin/roles/sod/violations?ruleId=1&includeExcepted=true", nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response common.Response[[]SodViolationResponse]
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	assert.NoError(t, err)
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, int64(4), response.Data[0].ConflictingRoleId)
+	}
+	mockService.AssertExpectations(t)
+}
@@ -1,15 +1,37 @@
 package role
 
-import "time"
+import (
+	"encoding/json"
+	"idm/inner/common"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// уровни риска роли
+const (
+	RiskLow      = "low"
+	RiskMedium   = "medium"
+	RiskHigh     = "high"
+	RiskCritical = "critical"
+)
 
 type Entity struct {
-	Id        int64     `db:"id"`
-	Name      string    `db:"name"`
-	Desc      string    `db:"description"`
-	Status    bool      `db:"status"`
-	ParentId  *int64    `db:"parent_id"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id        int64   `db:"id"`
+	Name      string  `db:"name"`
+	Desc      string  `db:"description"`
+	Status    bool    `db:"status"`
+	ParentId  *int64  `db:"parent_id"`
+	OwnerId   *int64  `db:"owner_id"`
+	RiskLevel string  `db:"risk_level"`
+	Category  *string `db:"category"`
+	// метки роли: JSON объект из строковых ключей и значений
+	Labels    string     `db:"labels"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at"`
 }
 
 func (e *Entity) toResponse() Response {
@@ -19,33 +41,550 @@ func (e *Entity) toResponse() Response {
 		Desc:      e.Desc,
 		Status:    e.Status,
 		ParentId:  e.ParentId,
+		OwnerId:   e.OwnerId,
+		RiskLevel: e.RiskLevel,
+		Category:  e.Category,
+		Labels:    decodeLabels(e.Labels),
 		CreatedAt: e.CreatedAt,
 		UpdatedAt: e.UpdatedAt,
+		DeletedAt: e.DeletedAt,
 	}
 }
 
+// Response роль. OwnerId - сотрудник-владелец роли, согласующий запросы доступа к ней
 type Response struct {
-	Id        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Desc      string    `json:"description"`
-	Status    bool      `json:"status"`
-	ParentId  *int64    `json:"parent_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Id        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Desc      string            `json:"description"`
+	Status    bool              `json:"status"`
+	ParentId  *int64            `json:"parent_id"`
+	OwnerId   *int64            `json:"owner_id"`
+	RiskLevel string            `json:"risk_level"`
+	Category  *string           `json:"category"`
+	Labels    map[string]string `json:"labels"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty"`
+}
+
+// метки из колонки labels; пустой объект, если меток нет
+func decodeLabels(raw string) map[string]string {
+	labels := map[string]string{}
+	if raw != "" {
+		_ = json.Unmarshal([]byte(raw), &labels)
+	}
+	return labels
+}
+
+// метки для записи в колонку labels
+func encodeLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "{}"
+	}
+	raw, _ := json.Marshal(labels)
+	return string(raw)
 }
 
+// уровень риска запроса; не указанный уровень - RiskLow
+func riskLevelOrDefault(riskLevel string) string {
+	if riskLevel == "" {
+		return RiskLow
+	}
+	return riskLevel
+}
+
+// заполняет не заданные уровень риска и метки значениями по умолчанию перед записью роли
+func (e *Entity) applyMetadataDefaults() {
+	e.RiskLevel = riskLevelOrDefault(e.RiskLevel)
+	if e.Labels == "" {
+		e.Labels = "{}"
+	}
+}
+
+// CreateRequest структура запроса на создание роли.
+// RiskLevel по умолчанию low, Labels - произвольные метки "ключ: значение"
 type CreateRequest struct {
-	Name        string `json:"name" validate:"required,min=2,max=100" example:"Administrator"`
-	Description string `json:"description" validate:"required,min=5,max=500" example:"Full access rights to the system"`
-	Status      bool   `json:"status" validate:"required"`
-	ParentId    *int64 `json:"parent_id,omitempty" validate:"omitempty"`
+	Name        string            `json:"name" validate:"required,min=2,max=100" example:"Administrator"`
+	Description string            `json:"description" validate:"required,min=5,max=500" example:"Full access rights to the system"`
+	Status      bool              `json:"status" validate:"required"`
+	ParentId    *int64            `json:"parent_id,omitempty" validate:"omitempty"`
+	OwnerId     *int64            `json:"owner_id,omitempty" validate:"omitempty,min=1" example:"7"`
+	RiskLevel   string            `json:"risk_level,omitempty" validate:"omitempty,oneof=low medium high critical" example:"high"`
+	Category    *string           `json:"category,omitempty" validate:"omitempty,min=1,max=100" example:"Billing"`
+	Labels      map[string]string `json:"labels,omitempty" validate:"max=50,dive,keys,min=1,max=100,endkeys,max=500"`
 }
 
 func (req *CreateRequest) ToEntity() Entity {
 	return Entity{
-		Name:     req.Name,
-		Desc:     req.Description,
-		Status:   req.Status,
-		ParentId: req.ParentId,
+		Name:      req.Name,
+		Desc:      req.Description,
+		Status:    req.Status,
+		ParentId:  req.ParentId,
+		OwnerId:   req.OwnerId,
+		RiskLevel: riskLevelOrDefault(req.RiskLevel),
+		Category:  req.Category,
+		Labels:    encodeLabels(req.Labels),
+	}
+}
+
+// UpdateRequest структура запроса на полное обновление роли.
+// ParentId = nil делает роль корневой, OwnerId = nil снимает владельца,
+// не указанные RiskLevel и Labels сбрасываются к low и пустому набору меток
+type UpdateRequest struct {
+	Id          int64             `json:"-"`
+	Name        string            `json:"name" validate:"required,min=2,max=100" example:"Administrator"`
+	Description string            `json:"description" validate:"required,min=5,max=500" example:"Full access rights to the system"`
+	Status      bool              `json:"status"`
+	ParentId    *int64            `json:"parent_id,omitempty" validate:"omitempty,min=1"`
+	OwnerId     *int64            `json:"owner_id,omitempty" validate:"omitempty,min=1" example:"7"`
+	RiskLevel   string            `json:"risk_level,omitempty" validate:"omitempty,oneof=low medium high critical" example:"high"`
+	Category    *string           `json:"category,omitempty" validate:"omitempty,min=1,max=100" example:"Billing"`
+	Labels      map[string]string `json:"labels,omitempty" validate:"max=50,dive,keys,min=1,max=100,endkeys,max=500"`
+}
+
+// применяет полное обновление к сущности
+func (req *UpdateRequest) applyTo(entity *Entity) {
+	entity.Name = req.Name
+	entity.Desc = req.Description
+	entity.Status = req.Status
+	entity.ParentId = req.ParentId
+	entity.OwnerId = req.OwnerId
+	entity.RiskLevel = riskLevelOrDefault(req.RiskLevel)
+	entity.Category = req.Category
+	entity.Labels = encodeLabels(req.Labels)
+}
+
+// PatchRequest структура запроса на частичное обновление роли.
+// Изменяются только переданные поля, ParentId = 0 отвязывает роль от родителя,
+// OwnerId = 0 снимает владельца, пустая Category убирает категорию.
+// Labels заменяет все метки роли, пустой объект удаляет их
+type PatchRequest struct {
+	Id          int64             `json:"-"`
+	Name        *string           `json:"name,omitempty" validate:"omitempty,min=2,max=100" example:"Administrator"`
+	Description *string           `json:"description,omitempty" validate:"omitempty,min=5,max=500" example:"Full access rights to the system"`
+	Status      *bool             `json:"status,omitempty"`
+	ParentId    *int64            `json:"parent_id,omitempty" validate:"omitempty,min=0"`
+	OwnerId     *int64            `json:"owner_id,omitempty" validate:"omitempty,min=0" example:"7"`
+	RiskLevel   *string           `json:"risk_level,omitempty" validate:"omitempty,oneof=low medium high critical" example:"high"`
+	Category    *string           `json:"category,omitempty" validate:"omitempty,max=100" example:"Billing"`
+	Labels      map[string]string `json:"labels,omitempty" validate:"omitempty,max=50,dive,keys,min=1,max=100,endkeys,max=500"`
+}
+
+// применяет частичное обновление к сущности
+func (req *PatchRequest) applyTo(entity *Entity) {
+	if req.Name != nil {
+		entity.Name = *req.Name
+	}
+	if req.Description != nil {
+		entity.Desc = *req.Description
+	}
+	if req.Status != nil {
+		entity.Status = *req.Status
+	}
+	if req.ParentId != nil {
+		if *req.ParentId == 0 {
+			entity.ParentId = nil
+		} else {
+			parentId := *req.ParentId
+			entity.ParentId = &parentId
+		}
+	}
+	if req.OwnerId != nil {
+		if *req.OwnerId == 0 {
+			entity.OwnerId = nil
+		} else {
+			ownerId := *req.OwnerId
+			entity.OwnerId = &ownerId
+		}
+	}
+	if req.RiskLevel != nil {
+		entity.RiskLevel = *req.RiskLevel
+	}
+	if req.Category != nil {
+		if *req.Category == "" {
+			entity.Category = nil
+		} else {
+			category := *req.Category
+			entity.Category = &category
+		}
+	}
+	if req.Labels != nil {
+		entity.Labels = encodeLabels(req.Labels)
+	}
+}
+
+// TreeNode узел дерева ролей
+type TreeNode struct {
+	Response
+	Children []TreeNode `json:"children"`
+}
+
+// роль дерева: depth - расстояние от корня, path - id ролей от корня до неё самой
+type treeEntity struct {
+	Entity
+	Depth int           `db:"depth"`
+	Path  pq.Int64Array `db:"path"`
+}
+
+// строит лес ролей из обхода в глубину: потомки роли идут сразу за ней с большей глубиной
+func buildTree(roles []treeEntity) []TreeNode {
+	next := 0
+	var build func(depth int) []TreeNode
+	build = func(depth int) []TreeNode {
+		nodes := make([]TreeNode, 0)
+		for next < len(roles) && roles[next].Depth == depth {
+			role := roles[next]
+			next++
+			nodes = append(nodes, TreeNode{
+				Response: role.toResponse(),
+				Children: build(depth + 1),
+			})
+		}
+		return nodes
+	}
+	return build(0)
+}
+
+// роль с расстоянием до исходной роли в иерархии
+type hierarchyEntity struct {
+	Entity
+	Depth int `db:"depth"`
+}
+
+// HierarchyResponse роль из цепочки предков или потомков,
+// Depth - расстояние до исходной роли (1 - непосредственный родитель или потомок)
+type HierarchyResponse struct {
+	Response
+	Depth int `json:"depth"`
+}
+
+func (e *hierarchyEntity) toHierarchyResponse() HierarchyResponse {
+	return HierarchyResponse{
+		Response: e.toResponse(),
+		Depth:    e.Depth,
+	}
+}
+
+// эффективная роль сотрудника
+type effectiveEntity struct {
+	Entity
+	Depth        int   `db:"depth"`
+	SourceRoleId int64 `db:"source_role_id"`
+}
+
+// EffectiveRoleResponse роль, которой сотрудник обладает напрямую или через наследование.
+// SourceRoleId - назначенная сотруднику роль, от которой унаследована данная
+type EffectiveRoleResponse struct {
+	Response
+	Inherited    bool  `json:"inherited"`
+	Depth        int   `json:"depth"`
+	SourceRoleId int64 `json:"source_role_id"`
+}
+
+func (e *effectiveEntity) toEffectiveResponse() EffectiveRoleResponse {
+	return EffectiveRoleResponse{
+		Response:     e.toResponse(),
+		Inherited:    e.Depth > 0,
+		Depth:        e.Depth,
+		SourceRoleId: e.SourceRoleId,
+	}
+}
+
+// PurgeRequest структура запроса на окончательное удаление ролей,
+// мягко удалённых более RetentionDays дней назад
+type PurgeRequest struct {
+	RetentionDays int `json:"retention_days" validate:"required,min=1" example:"30"`
+} // @name RolePurgeRequest
+
+// PurgeResponse результат очистки
+type PurgeResponse struct {
+	PurgedIds []int64 `json:"purged_ids"`
+} // @name RolePurgeResponse
+
+// политики удаления роли, на которую ссылаются сотрудники или дочерние роли
+const (
+	// отказать в удалении (409) со списком зависимых
+	DeletePolicyReject = "reject"
+	// переназначить сотрудников и дочерние роли на роль-замену
+	DeletePolicyReassign = "reassign"
+	// удалить роль вместе с потомками и отозвать их назначения
+	DeletePolicyCascade = "cascade"
+)
+
+// CursorRequest структура запроса страницы ролей по курсору.
+// Sort - одно поле: id, name, created_at или updated_at; "-" перед полем задаёт обратный порядок
+type CursorRequest struct {
+	Limit  int            `json:"limit" validate:"min=1,max=100"`
+	Sort   string         `json:"sort" validate:"max=50"`
+	Filter Filter         `json:"-"`
+	Cursor *common.Cursor `json:"-"`
+}
+
+// Filter фильтр списка ролей; нулевые значения не ограничивают выборку.
+// RiskLevels отбирает роли любого из уровней, Category сравнивается без учёта регистра,
+// Labels - метки, которые должны быть у роли одновременно
+type Filter struct {
+	OwnerId    int64             `validate:"min=0"`
+	RiskLevels []string          `validate:"max=4,dive,oneof=low medium high critical"`
+	Category   string            `validate:"max=100"`
+	Labels     map[string]string `validate:"max=20,dive,keys,min=1,max=100,endkeys,max=500"`
+}
+
+// CursorPage страница ролей и позиции соседних страниц; nil - соседней страницы нет
+type CursorPage struct {
+	Data []Response
+	Next *common.Cursor
+	Prev *common.Cursor
+}
+
+// CursorPageResponse структура ответа с постраничным выводом ролей по курсору
+type CursorPageResponse struct {
+	Data []Response `json:"data"`
+	Next *string    `json:"next"`
+	Prev *string    `json:"prev"`
+} // @name RoleCursorPageResponse
+
+// DeleteRequest структура запроса на удаление роли.
+// Policy = "" равносильно DeletePolicyReject
+type DeleteRequest struct {
+	Id                int64  `validate:"required,min=1"`
+	Policy            string `validate:"omitempty,oneof=reject reassign cascade"`
+	ReplacementRoleId int64  `validate:"required_if=Policy reassign,omitempty,min=1,nefield=Id"`
+}
+
+// DeleteResponse результат удаления роли
+type DeleteResponse struct {
+	DeletedRoleIds      []int64 `json:"deleted_role_ids"`
+	ReparentedRoleIds   []int64 `json:"reparented_role_ids"`
+	AffectedEmployeeIds []int64 `json:"affected_employee_ids"`
+}
+
+// Dependents сотрудники и дочерние роли, ссылающиеся на удаляемую роль
+type Dependents struct {
+	EmployeeIds  []int64 `json:"employee_ids"`
+	ChildRoleIds []int64 `json:"child_role_ids"`
+}
+
+// назначение роли сотруднику, из-за которого роль нельзя удалить без последствий
+type holderEntity struct {
+	EmployeeId int64 `db:"employee_id"`
+	RoleId     int64 `db:"role_id"`
+}
+
+// роль для выгрузки: название родителя, коды разрешений и число сотрудников с ролью
+type exportEntity struct {
+	Entity
+	Parent      string         `db:"parent"`
+	Permissions pq.StringArray `db:"permissions"`
+	Holders     int64          `db:"holders"`
+}
+
+// ExportHeader колонки CSV выгрузки ролей, в порядке ExportRecord.CSVValues
+var ExportHeader = []string{
+	"id", "name", "description", "status", "parent", "permissions", "holders", "created_at", "updated_at",
+}
+
+// ExportRecord запись выгрузки ролей
+type ExportRecord struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Desc        string    `json:"description"`
+	Status      bool      `json:"status"`
+	Parent      string    `json:"parent"`
+	Permissions []string  `json:"permissions"`
+	Holders     int64     `json:"holders"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+} // @name RoleExportRecord
+
+func (e *exportEntity) toExportRecord() ExportRecord {
+	permissions := []string(e.Permissions)
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return ExportRecord{
+		Id:          e.Id,
+		Name:        e.Name,
+		Desc:        e.Desc,
+		Status:      e.Status,
+		Parent:      e.Parent,
+		Permissions: permissions,
+		Holders:     e.Holders,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+	}
+}
+
+// значения колонок CSV; разрешения перечисляются через "; "
+func (r ExportRecord) CSVValues() []string {
+	return []string{
+		strconv.FormatInt(r.Id, 10), r.Name, r.Desc, strconv.FormatBool(r.Status), r.Parent,
+		strings.Join(r.Permissions, "; "), strconv.FormatInt(r.Holders, 10),
+		r.CreatedAt.Format(time.RFC3339), r.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// правило разделения обязанностей (SoD) с ролями
+type sodRuleEntity struct {
+	Id          int64         `db:"id"`
+	Name        string        `db:"name"`
+	Description *string       `db:"description"`
+	RoleIds     pq.Int64Array `db:"role_ids"`
+	CreatedAt   time.Time     `db:"created_at"`
+	UpdatedAt   time.Time     `db:"updated_at"`
+}
+
+func (e *sodRuleEntity) toResponse() SodRuleResponse {
+	return SodRuleResponse{
+		Id:          e.Id,
+		Name:        e.Name,
+		Description: e.Description,
+		RoleIds:     e.RoleIds,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
 	}
 }
+
+// SodRuleResponse правило разделения обязанностей: сотрудник может обладать
+// не более чем одной из ролей RoleIds, в том числе через наследование
+type SodRuleResponse struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description,omitempty"`
+	RoleIds     []int64   `json:"role_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SodRuleRequest структура запроса на создание и изменение правила; пара ролей - правило из двух ролей
+type SodRuleRequest struct {
+	Id          int64   `json:"-"`
+	Name        string  `json:"name" validate:"required,min=2,max=255" example:"Payments"`
+	Description string  `json:"description" validate:"max=1000" example:"Payment creator cannot approve payments"`
+	RoleIds     []int64 `json:"role_ids" validate:"required,min=2,max=100,unique,dive,min=1" example:"3,4"`
+}
+
+func (req *SodRuleRequest) toEntity() sodRuleEntity {
+	rule := sodRuleEntity{
+		Id:      req.Id,
+		Name:    req.Name,
+		RoleIds: append([]int64{}, req.RoleIds...),
+	}
+	if req.Description != "" {
+		rule.Description = &req.Description
+	}
+	return rule
+}
+
+// исключение из правила для сотрудника
+type sodExceptionEntity struct {
+	Id                 int64      `db:"id"`
+	RuleId             int64      `db:"rule_id"`
+	EmployeeId         int64      `db:"employee_id"`
+	Justification      string     `db:"justification"`
+	ExpiresAt          time.Time  `db:"expires_at"`
+	ApprovedBySub      *string    `db:"approved_by_sub"`
+	ApprovedByUsername *string    `db:"approved_by_username"`
+	CreatedAt          time.Time  `db:"created_at"`
+	RevokedAt          *time.Time `db:"revoked_at"`
+}
+
+func (e *sodExceptionEntity) toResponse() SodExceptionResponse {
+	return SodExceptionResponse{
+		Id:            e.Id,
+		RuleId:        e.RuleId,
+		EmployeeId:    e.EmployeeId,
+		Justification: e.Justification,
+		ExpiresAt:     e.ExpiresAt,
+		ApprovedBy:    e.ApprovedByUsername,
+		CreatedAt:     e.CreatedAt,
+		RevokedAt:     e.RevokedAt,
+	}
+}
+
+// SodExceptionResponse согласованное исключение: до ExpiresAt сотрудник может обладать ролями правила одновременно
+type SodExceptionResponse struct {
+	Id            int64      `json:"id"`
+	RuleId        int64      `json:"rule_id"`
+	EmployeeId    int64      `json:"employee_id"`
+	Justification string     `json:"justification"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	ApprovedBy    *string    `json:"approved_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
+
+// SodExceptionRequest структура запроса на исключение из правила
+type SodExceptionRequest struct {
+	RuleId        int64     `json:"rule_id" validate:"required,min=1" example:"1"`
+	EmployeeId    int64     `json:"employee_id" validate:"required,min=1" example:"7"`
+	Justification string    `json:"justification" validate:"required,min=5,max=1000" example:"Sole accountant of the branch"`
+	ExpiresAt     time.Time `json:"expires_at" validate:"required" example:"2025-12-31T23:59:59Z"`
+}
+
+// SodExceptionFilter фильтр исключений; нулевые значения не ограничивают выборку,
+// ActiveOnly оставляет неотозванные исключения, срок которых не истёк
+type SodExceptionFilter struct {
+	RuleId     int64
+	EmployeeId int64
+	ActiveOnly bool
+}
+
+// нарушение правила: сотрудник обладает двумя ролями правила. SourceRoleId - назначенная роль,
+// через которую сотрудник обладает ролью правила (совпадает с ней, если роль назначена напрямую)
+type sodViolationEntity struct {
+	EmployeeId              int64      `db:"employee_id"`
+	EmployeeName            string     `db:"employee_name"`
+	RuleId                  int64      `db:"rule_id"`
+	RuleName                string     `db:"rule_name"`
+	RoleId                  int64      `db:"role_id"`
+	RoleName                string     `db:"role_name"`
+	SourceRoleId            int64      `db:"source_role_id"`
+	ConflictingRoleId       int64      `db:"conflicting_role_id"`
+	ConflictingRoleName     string     `db:"conflicting_role_name"`
+	ConflictingSourceRoleId int64      `db:"conflicting_source_role_id"`
+	ExceptionId             *int64     `db:"exception_id"`
+	ExceptionExpiresAt      *time.Time `db:"exception_expires_at"`
+}
+
+func (e *sodViolationEntity) toResponse() SodViolationResponse {
+	return SodViolationResponse{
+		EmployeeId:              e.EmployeeId,
+		EmployeeName:            e.EmployeeName,
+		RuleId:                  e.RuleId,
+		RuleName:                e.RuleName,
+		RoleId:                  e.RoleId,
+		RoleName:                e.RoleName,
+		SourceRoleId:            e.SourceRoleId,
+		ConflictingRoleId:       e.ConflictingRoleId,
+		ConflictingRoleName:     e.ConflictingRoleName,
+		ConflictingSourceRoleId: e.ConflictingSourceRoleId,
+		ExceptionId:             e.ExceptionId,
+		ExceptionExpiresAt:      e.ExceptionExpiresAt,
+	}
+}
+
+// SodViolationResponse нарушение правила разделения обязанностей. ExceptionId заполнен,
+// если нарушение разрешено действующим исключением
+type SodViolationResponse struct {
+	EmployeeId              int64      `json:"employee_id"`
+	EmployeeName            string     `json:"employee_name"`
+	RuleId                  int64      `json:"rule_id"`
+	RuleName                string     `json:"rule_name"`
+	RoleId                  int64      `json:"role_id"`
+	RoleName                string     `json:"role_name"`
+	SourceRoleId            int64      `json:"source_role_id"`
+	ConflictingRoleId       int64      `json:"conflicting_role_id"`
+	ConflictingRoleName     string     `json:"conflicting_role_name"`
+	ConflictingSourceRoleId int64      `json:"conflicting_source_role_id"`
+	ExceptionId             *int64     `json:"exception_id,omitempty"`
+	ExceptionExpiresAt      *time.Time `json:"exception_expires_at,omitempty"`
+}
+
+// SodViolationFilter фильтр отчёта о нарушениях; IncludeExcepted добавляет нарушения,
+// разрешённые действующими исключениями
+type SodViolationFilter struct {
+	RuleId          int64
+	EmployeeId      int64
+	IncludeExcepted bool
+}
@@ -2,6 +2,14 @@ package role
 
 import (
 	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+
+	"idm/inner/common"
+	"idm/inner/database"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
@@ -11,20 +19,25 @@ type Repository struct {
 	db *sqlx.DB
 }
 
+// удалённые (мягко) роли исключаются из всех выборок, кроме восстановления и очистки
+const notDeleted = `deleted_at IS NULL`
+
 func NewRoleRepository(database *sqlx.DB) *Repository {
 	return &Repository{db: database}
 }
 
 func (r *Repository) FindById(ctx context.Context, id int64) (role Entity, err error) {
-	err = r.db.GetContext(ctx, &role, "SELECT * FROM role WHERE id = $1", id)
+	err = r.db.GetContext(ctx, &role, "SELECT * FROM role WHERE id = $1 AND "+notDeleted, id)
 	return role, err
 }
 
 func (r *Repository) Add(ctx context.Context, role *Entity) error {
+	role.applyMetadataDefaults()
 	err := r.db.QueryRowContext(
 		ctx,
-		"INSERT INTO role (name, description, status, parent_id) VALUES ($1, $2, $3, $4) RETURNING id",
-		role.Name, role.Desc, role.Status, role.ParentId,
+		`INSERT INTO role (name, description, status, parent_id, owner_id, risk_level, category, labels)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		role.Name, role.Desc, role.Status, role.ParentId, role.OwnerId, role.RiskLevel, role.Category, role.Labels,
 	).Scan(&role.Id)
 	if err != nil {
 		return err
@@ -34,26 +47,148 @@ func (r *Repository) Add(ctx context.Context, role *Entity) error {
 
 func (r *Repository) FindAll(ctx context.Context) ([]Entity, error) {
 	var roles []Entity
-	err := r.db.SelectContext(ctx, &roles, "SELECT * FROM role")
+	err := r.db.SelectContext(ctx, &roles, "SELECT * FROM role WHERE "+notDeleted)
+	return roles, err
+}
+
+// колонка вывода по курсору: тип нужен для сравнения со строковым значением из курсора,
+// value получает это значение из строки выборки
+type cursorColumn struct {
+	column  string
+	sqlType string
+	value   func(Entity) string
+}
+
+// поля, по которым возможен вывод ролей по курсору
+var cursorColumns = map[string]cursorColumn{
+	"id":         {"id", "bigint", func(e Entity) string { return strconv.FormatInt(e.Id, 10) }},
+	"name":       {"name", "text", func(e Entity) string { return e.Name }},
+	"created_at": {"created_at", "timestamptz", func(e Entity) string { return e.CreatedAt.Format(time.RFC3339Nano) }},
+	"updated_at": {"updated_at", "timestamptz", func(e Entity) string { return e.UpdatedAt.Format(time.RFC3339Nano) }},
+}
+
+// разбирает сортировку вывода по курсору: одно поле, "-" - по убыванию, по умолчанию id
+func parseCursorSort(sort string) (cursorColumn, bool, error) {
+	name := strings.TrimSpace(sort)
+	if name == "" {
+		name = "id"
+	}
+	desc := strings.HasPrefix(name, "-")
+	column, ok := cursorColumns[strings.TrimPrefix(name, "-")]
+	if !ok {
+		return cursorColumn{}, false, fmt.Errorf("unsupported cursor sort %q", sort)
+	}
+	return column, desc, nil
+}
+
+// условие отбора ролей по фильтру списка. Параметры фильтра добавляются к args,
+// условие ссылается на них по номерам
+func filterCondition(filter Filter, args []any) (string, []any) {
+	condition := notDeleted
+	add := func(format string, arg any) {
+		args = append(args, arg)
+		condition += ` AND ` + fmt.Sprintf(format, len(args))
+	}
+
+	if filter.OwnerId != 0 {
+		add(`owner_id = $%d`, filter.OwnerId)
+	}
+	if len(filter.RiskLevels) > 0 {
+		add(`risk_level = ANY ($%d)`, pq.Array(filter.RiskLevels))
+	}
+	if category := strings.TrimSpace(filter.Category); category != "" {
+		add(`lower(category) = lower($%d)`, category)
+	}
+	// роль должна содержать все метки фильтра
+	if len(filter.Labels) > 0 {
+		add(`labels @> $%d::jsonb`, encodeLabels(filter.Labels))
+	}
+
+	return condition, args
+}
+
+// FindWithCursor выбирает до limit ролей, подходящих под filter, после позиции position
+// в порядке сортировки sort; id - второй ключ в том же направлении.
+// Для position.Backward выбираются строки перед позицией в обратном порядке
+func (r *Repository) FindWithCursor(ctx context.Context, filter Filter, sort string, position *common.Cursor, limit int) ([]Entity, error) {
+	column, desc, err := parseCursorSort(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// по убыванию при обратной сортировке или движении назад, но не при обоих
+	reverse := desc != (position != nil && position.Backward)
+	operator, direction := ">", "ASC"
+	if reverse {
+		operator, direction = "<", "DESC"
+	}
+
+	args := []any{limit}
+	if position != nil {
+		args = append(args, position.Value, position.Id)
+	}
+	condition, args := filterCondition(filter, args)
+	if position != nil {
+		condition += fmt.Sprintf(` AND (%s, id) %s ($2::%s, $3)`, column.column, operator, column.sqlType)
+	}
+	query := `SELECT * FROM role WHERE ` + condition +
+		fmt.Sprintf(` ORDER BY %s %s, id %s LIMIT $1`, column.column, direction, direction)
+
+	var roles []Entity
+	err = r.db.SelectContext(ctx, &roles, query, args...)
 	return roles, err
 }
 
+// колонки выгрузки: родитель и разрешения по названию, число сотрудников с действующим назначением роли
+const exportColumns = `role.*,
+	COALESCE((SELECT p.name FROM role p WHERE p.id = role.parent_id), '') AS parent,
+	ARRAY(
+		SELECT p.code FROM role_permission rp JOIN permission p ON p.id = rp.permission_id
+		WHERE rp.role_id = role.id
+		ORDER BY p.code
+	) AS permissions,
+	(SELECT count(DISTINCT er.employee_id) FROM employee_role er
+		WHERE er.role_id = role.id AND er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+	) AS holders`
+
+// ExportRoles выбирает все неудалённые роли в порядке сортировки вывода по курсору.
+// Строки читаются из базы по мере прохода итератора
+func (r *Repository) ExportRoles(ctx context.Context, sort string) (iter.Seq2[exportEntity, error], error) {
+	column, desc, err := parseCursorSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	query := `SELECT ` + exportColumns + ` FROM role WHERE ` + notDeleted +
+		fmt.Sprintf(` ORDER BY %s %s, id %s`, column.column, direction, direction)
+
+	rows, err := r.db.QueryxContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return database.StructRows[exportEntity](rows), nil
+}
+
 func (r *Repository) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
 	var roles []Entity
 	if len(ids) == 0 {
 		return roles, nil
 	}
-	err := r.db.SelectContext(ctx, &roles, "SELECT * FROM role WHERE id = ANY ($1)", pq.Array(ids))
+	err := r.db.SelectContext(ctx, &roles, "SELECT * FROM role WHERE id = ANY ($1) AND "+notDeleted, pq.Array(ids))
 	return roles, err
 }
 
+// Мягко удалить роль: строка помечается deleted_at и может быть восстановлена
 func (r *Repository) DeleteById(ctx context.Context, id int64) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM role WHERE id = $1", id)
+	_, err := r.db.ExecContext(ctx, "UPDATE role SET deleted_at = now() WHERE id = $1 AND "+notDeleted, id)
 	return err
 }
 
 func (r *Repository) DeleteByIds(ctx context.Context, ids []int64) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM role WHERE id = ANY ($1)", pq.Array(ids))
+	_, err := r.db.ExecContext(ctx, "UPDATE role SET deleted_at = now() WHERE id = ANY ($1) AND "+notDeleted, pq.Array(ids))
 	return err
 }
 
@@ -67,7 +202,7 @@ func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string)
 	err = tx.GetContext(
 		ctx,
 		&isExists,
-		"select exists(select 1 from role where name = $1)",
+		"select exists(select 1 from role where name = $1 and "+notDeleted+")",
 		name,
 	)
 	return isExists, err
@@ -75,10 +210,544 @@ func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string)
 
 // Создать новую роль
 func (r *Repository) SaveTx(ctx context.Context, tx *sqlx.Tx, role Entity) (roleId int64, err error) {
+	role.applyMetadataDefaults()
 	err = tx.GetContext(
 		ctx,
 		&roleId,
-		`INSERT INTO role (name, description, status, parent_id) VALUES ($1, $2, $3, $4) RETURNING id`,
-		role.Name, role.Desc, role.Status, role.ParentId)
+		`INSERT INTO role (name, description, status, parent_id, owner_id, risk_level, category, labels)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		role.Name, role.Desc, role.Status, role.ParentId, role.OwnerId, role.RiskLevel, role.Category, role.Labels)
 	return roleId, err
 }
+
+// Найти роль по id и заблокировать строку до конца транзакции
+func (r *Repository) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (role Entity, err error) {
+	err = tx.GetContext(ctx, &role, "SELECT * FROM role WHERE id = $1 AND "+notDeleted+" FOR UPDATE", id)
+	return role, err
+}
+
+// Найти роли по списку id и заблокировать их строки до конца транзакции
+func (r *Repository) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	var roles []Entity
+	err := tx.SelectContext(ctx, &roles,
+		"SELECT * FROM role WHERE id = ANY ($1) AND "+notDeleted+" ORDER BY id FOR UPDATE", pq.Array(ids))
+	return roles, err
+}
+
+func (r *Repository) DeleteByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE role SET deleted_at = now() WHERE id = $1 AND "+notDeleted, id)
+	return err
+}
+
+func (r *Repository) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE role SET deleted_at = now() WHERE id = ANY ($1) AND "+notDeleted, pq.Array(ids))
+	return err
+}
+
+// Найти удалённую роль по id и заблокировать строку до конца транзакции
+func (r *Repository) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (role Entity, err error) {
+	err = tx.GetContext(ctx, &role, "SELECT * FROM role WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE", id)
+	return role, err
+}
+
+// Восстановить удалённую роль
+func (r *Repository) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (restored Entity, err error) {
+	err = tx.GetContext(ctx, &restored,
+		"UPDATE role SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING *", id)
+	return restored, err
+}
+
+// Окончательно удалить роли, удалённые раньше указанного момента.
+// Роли, на которые ещё ссылаются назначения сотрудников, ожидающие отложенные изменения, запросы доступа
+// или пересмотры доступа, пропускаются; в истории отложенных изменений ссылка на роль обнуляется.
+// Возвращает снимки удалённых строк
+func (r *Repository) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, deletedBefore time.Time) ([]Entity, error) {
+	var purged []Entity
+	err := tx.SelectContext(ctx, &purged,
+		`DELETE FROM role
+		WHERE deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM employee_role er WHERE er.role_id = role.id)
+			AND NOT EXISTS (SELECT 1 FROM employee e WHERE e.role_id = role.id)
+			AND NOT EXISTS (
+				SELECT 1 FROM employee_scheduled_change c WHERE c.role_id = role.id AND c.status = 'pending'
+			)
+			AND NOT EXISTS (SELECT 1 FROM access_request ar WHERE ar.role_id = role.id)
+			AND NOT EXISTS (SELECT 1 FROM certification_item ci WHERE ci.role_id = role.id)
+		RETURNING *`,
+		deletedBefore)
+	return purged, err
+}
+
+// Найти неудалённых сотрудников, у которых есть действующие или будущие назначения любой из ролей
+func (r *Repository) FindHoldersTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]holderEntity, error) {
+	var holders []holderEntity
+	err := tx.SelectContext(
+		ctx,
+		&holders,
+		`SELECT DISTINCT er.employee_id, er.role_id
+		FROM employee_role er JOIN employee e ON e.id = er.employee_id
+		WHERE er.role_id = ANY ($1) AND (er.valid_to IS NULL OR er.valid_to > now())
+			AND e.deleted_at IS NULL
+		ORDER BY er.employee_id, er.role_id`,
+		pq.Array(roleIds))
+	return holders, err
+}
+
+// Найти неудалённые дочерние роли и заблокировать их строки до конца транзакции
+func (r *Repository) FindChildrenForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]Entity, error) {
+	var children []Entity
+	err := tx.SelectContext(ctx, &children,
+		"SELECT * FROM role WHERE parent_id = $1 AND "+notDeleted+" ORDER BY id FOR UPDATE", id)
+	return children, err
+}
+
+// Найти роль вместе со всеми неудалёнными потомками и заблокировать их строки до конца транзакции
+func (r *Repository) FindSubtreeForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]Entity, error) {
+	var roles []Entity
+	err := tx.SelectContext(
+		ctx,
+		&roles,
+		`WITH RECURSIVE subtree AS (
+			SELECT id, ARRAY[id] AS path FROM role WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT r.id, s.path || r.id
+			FROM role r JOIN subtree s ON r.parent_id = s.id
+			WHERE NOT r.id = ANY (s.path) AND r.deleted_at IS NULL
+		)
+		SELECT r.* FROM role r WHERE r.id IN (SELECT id FROM subtree)
+		ORDER BY r.id
+		FOR UPDATE`,
+		id)
+	return roles, err
+}
+
+// Переподчинить неудалённые дочерние роли новому родителю
+func (r *Repository) ReparentChildrenTx(ctx context.Context, tx *sqlx.Tx, id, newParentId int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"UPDATE role SET parent_id = $2, updated_at = clock_timestamp() WHERE parent_id = $1 AND "+notDeleted,
+		id, newParentId)
+	return err
+}
+
+// Выдать роль toRoleId на оставшийся период каждого действующего или будущего назначения роли fromRoleId.
+// Если у сотрудника уже есть пересекающееся назначение toRoleId, новое не создаётся
+func (r *Repository) ReassignHoldersTx(ctx context.Context, tx *sqlx.Tx, fromRoleId, toRoleId int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO employee_role (employee_id, role_id, valid_from, valid_to)
+		SELECT er.employee_id, $2, GREATEST(er.valid_from, now()), er.valid_to
+		FROM employee_role er
+		WHERE er.role_id = $1 AND (er.valid_to IS NULL OR er.valid_to > now())
+			AND NOT exists(
+				SELECT 1 FROM employee_role o
+				WHERE o.employee_id = er.employee_id AND o.role_id = $2
+					AND o.valid_from < COALESCE(er.valid_to, 'infinity'::timestamptz)
+					AND COALESCE(o.valid_to, 'infinity'::timestamptz) > GREATEST(er.valid_from, now())
+			)`,
+		fromRoleId, toRoleId)
+	return err
+}
+
+// Отозвать все назначения ролей: действующие закрываются текущим моментом, будущие - удаляются.
+// Возвращает id сотрудников, у которых были отозваны назначения
+func (r *Repository) RevokeAssignmentsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error) {
+	var employeeIds []int64
+	err := tx.SelectContext(
+		ctx,
+		&employeeIds,
+		`WITH ended AS (
+			UPDATE employee_role SET valid_to = now()
+			WHERE role_id = ANY ($1) AND valid_from <= now() AND (valid_to IS NULL OR valid_to > now())
+			RETURNING employee_id
+		), removed AS (
+			DELETE FROM employee_role
+			WHERE role_id = ANY ($1) AND valid_from > now()
+			RETURNING employee_id
+		)
+		SELECT DISTINCT employee_id FROM (
+			SELECT employee_id FROM ended UNION ALL SELECT employee_id FROM removed
+		) affected
+		ORDER BY employee_id`,
+		pq.Array(roleIds))
+	return employeeIds, err
+}
+
+// Синхронизировать колонку employee.role_id с назначениями сотрудников (для обратной совместимости)
+func (r *Repository) SyncEmployeesRoleIdTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`UPDATE employee e SET role_id = active.role_id
+		FROM (
+			SELECT employee.id, (
+				SELECT er.role_id FROM employee_role er
+				WHERE er.employee_id = employee.id
+					AND er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+				ORDER BY er.valid_from DESC, er.id DESC
+				LIMIT 1
+			) AS role_id
+			FROM employee WHERE employee.id = ANY ($1)
+		) AS active
+		WHERE e.id = active.id AND e.role_id IS DISTINCT FROM active.role_id`,
+		pq.Array(employeeIds))
+	return err
+}
+
+// Проверить существование роли
+func (r *Repository) ExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from role where id = $1 and "+notDeleted+")", id)
+	return isExists, err
+}
+
+// Сериализовать изменения иерархии ролей: два параллельных переподчинения
+// не должны по отдельности пройти проверку на цикл и вместе его образовать
+func (r *Repository) LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('role_hierarchy'))")
+	return err
+}
+
+// Проверить, находится ли роль ancestorId в цепочке родителей роли roleId (включая саму roleId)
+func (r *Repository) IsInParentChainTx(ctx context.Context, tx *sqlx.Tx, roleId, ancestorId int64) (inChain bool, err error) {
+	err = tx.GetContext(
+		ctx,
+		&inChain,
+		`WITH RECURSIVE chain AS (
+			SELECT id, parent_id FROM role WHERE id = $1
+			UNION
+			SELECT r.id, r.parent_id FROM role r JOIN chain c ON r.id = c.parent_id
+		)
+		SELECT exists(SELECT 1 FROM chain WHERE id = $2)`,
+		roleId, ancestorId)
+	return inChain, err
+}
+
+// Обновить роль
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, role Entity) (updated Entity, err error) {
+	err = tx.GetContext(
+		ctx,
+		&updated,
+		`UPDATE role
+		SET name = $1, description = $2, status = $3, parent_id = $4, owner_id = $5, risk_level = $6,
+			category = $7, labels = $8, updated_at = clock_timestamp()
+		WHERE id = $9 AND `+notDeleted+`
+		RETURNING *`,
+		role.Name, role.Desc, role.Status, role.ParentId, role.OwnerId, role.RiskLevel, role.Category, role.Labels, role.Id)
+	return updated, err
+}
+
+// Активировать или деактивировать роль
+func (r *Repository) SetStatusTx(ctx context.Context, tx *sqlx.Tx, id int64, status bool) (updated Entity, err error) {
+	err = tx.GetContext(ctx, &updated,
+		`UPDATE role SET status = $2, updated_at = clock_timestamp()
+		WHERE id = $1 AND `+notDeleted+`
+		RETURNING *`,
+		id, status)
+	return updated, err
+}
+
+// Найти всех предков роли, начиная с непосредственного родителя
+func (r *Repository) FindAncestors(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	var roles []hierarchyEntity
+	err := r.db.SelectContext(
+		ctx,
+		&roles,
+		`WITH RECURSIVE chain AS (
+			SELECT id, parent_id, 0 AS depth, ARRAY[id] AS path FROM role WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT r.id, r.parent_id, c.depth + 1, c.path || r.id
+			FROM role r JOIN chain c ON r.id = c.parent_id
+			WHERE NOT r.id = ANY (c.path) AND r.deleted_at IS NULL
+		)
+		SELECT r.*, c.depth FROM chain c JOIN role r ON r.id = c.id
+		WHERE c.depth > 0
+		ORDER BY c.depth`,
+		id)
+	return roles, err
+}
+
+// Найти всех потомков роли в порядке обхода в ширину
+func (r *Repository) FindDescendants(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	var roles []hierarchyEntity
+	err := r.db.SelectContext(
+		ctx,
+		&roles,
+		`WITH RECURSIVE subtree AS (
+			SELECT id, 0 AS depth, ARRAY[id] AS path FROM role WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT r.id, s.depth + 1, s.path || r.id
+			FROM role r JOIN subtree s ON r.parent_id = s.id
+			WHERE NOT r.id = ANY (s.path) AND r.deleted_at IS NULL
+		)
+		SELECT r.*, s.depth FROM subtree s JOIN role r ON r.id = s.id
+		WHERE s.depth > 0
+		ORDER BY s.depth, r.id`,
+		id)
+	return roles, err
+}
+
+// Найти все неудалённые роли дерева в порядке обхода в глубину: каждая роль следует за своим родителем,
+// братья упорядочены по id. Корнями становятся роли без родителя и роли, родитель которых удалён
+func (r *Repository) FindTree(ctx context.Context) ([]treeEntity, error) {
+	var roles []treeEntity
+	err := r.db.SelectContext(
+		ctx,
+		&roles,
+		`WITH RECURSIVE tree AS (
+			SELECT id, 0 AS depth, ARRAY[id] AS path FROM role
+			WHERE deleted_at IS NULL AND (parent_id IS NULL OR NOT EXISTS (
+				SELECT 1 FROM role p WHERE p.id = role.parent_id AND p.deleted_at IS NULL
+			))
+			UNION ALL
+			SELECT r.id, t.depth + 1, t.path || r.id
+			FROM role r JOIN tree t ON r.parent_id = t.id
+			WHERE NOT r.id = ANY (t.path) AND r.deleted_at IS NULL
+		)
+		SELECT r.*, t.depth, t.path FROM tree t JOIN role r ON r.id = t.id
+		ORDER BY t.path`)
+	return roles, err
+}
+
+// Проверить существование сотрудника
+func (r *Repository) EmployeeExists(ctx context.Context, employeeId int64) (isExists bool, err error) {
+	err = r.db.GetContext(ctx, &isExists, "select exists(select 1 from employee where id = $1 and deleted_at is null)", employeeId)
+	return isExists, err
+}
+
+// Проверить в транзакции существование неудалённого сотрудника - владельца роли
+func (r *Repository) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "select exists(select 1 from employee where id = $1 and deleted_at is null)", employeeId)
+	return isExists, err
+}
+
+// Найти эффективные роли сотрудника: действующие назначенные роли и всех их предков.
+// Если роль унаследована по нескольким путям, возвращается ближайший.
+// Роли действуют только для работающих (active) сотрудников. Неактивная роль не действует
+// и обрывает наследование: её предки через неё не достаются
+func (r *Repository) FindEffectiveByEmployeeId(ctx context.Context, employeeId int64) ([]effectiveEntity, error) {
+	var roles []effectiveEntity
+	err := r.db.SelectContext(
+		ctx,
+		&roles,
+		`WITH RECURSIVE effective AS (
+			SELECT r.id, r.parent_id, 0 AS depth, r.id AS source_role_id, ARRAY[r.id] AS path
+			FROM employee_role er
+				JOIN role r ON r.id = er.role_id
+				JOIN employee e ON e.id = er.employee_id AND e.status = 'active'
+			WHERE er.employee_id = $1 AND r.deleted_at IS NULL AND r.status
+				AND er.valid_from <= now() AND (er.valid_to IS NULL OR er.valid_to > now())
+			UNION ALL
+			SELECT r.id, r.parent_id, ef.depth + 1, ef.source_role_id, ef.path || r.id
+			FROM role r JOIN effective ef ON r.id = ef.parent_id
+			WHERE NOT r.id = ANY (ef.path) AND r.deleted_at IS NULL AND r.status
+		)
+		SELECT DISTINCT ON (r.id) r.*, ef.depth, ef.source_role_id
+		FROM effective ef JOIN role r ON r.id = ef.id
+		ORDER BY r.id, ef.depth`,
+		employeeId)
+	return roles, err
+}
+
+// правило с ролями
+const selectSodRule = `SELECT s.*,
+		ARRAY(SELECT rr.role_id FROM sod_rule_role rr WHERE rr.rule_id = s.id ORDER BY rr.role_id) AS role_ids
+	FROM sod_rule s`
+
+// роли, которыми обладают сотрудники ($1 = 0 - все сотрудники): назначенные роли и их предки
+// с периодом назначения. $2 = TRUE учитывает и будущие назначения, иначе только действующие.
+// Неактивные роли, как и при вычислении эффективных ролей, не действуют и обрывают наследование
+const sodHeldRoles = `WITH RECURSIVE held AS (
+		SELECT er.employee_id, er.role_id AS source_role_id, r.id AS role_id, r.parent_id,
+			er.valid_from, er.valid_to, ARRAY[r.id] AS path
+		FROM employee_role er
+			JOIN role r ON r.id = er.role_id AND r.deleted_at IS NULL AND r.status
+			JOIN employee e ON e.id = er.employee_id AND e.deleted_at IS NULL
+		WHERE ($1::bigint = 0 OR er.employee_id = $1)
+			AND (er.valid_to IS NULL OR er.valid_to > now()) AND ($2 OR er.valid_from <= now())
+		UNION ALL
+		SELECT h.employee_id, h.source_role_id, r.id, r.parent_id, h.valid_from, h.valid_to, h.path || r.id
+		FROM role r JOIN held h ON r.id = h.parent_id
+		WHERE NOT r.id = ANY (h.path) AND r.deleted_at IS NULL AND r.status
+	)`
+
+// пары ролей одного правила, которыми сотрудник обладает в пересекающиеся периоды
+const sodConflictPairs = `SELECT a.employee_id, rr.rule_id, a.role_id, a.source_role_id,
+			b.role_id AS conflicting_role_id, b.source_role_id AS conflicting_source_role_id
+		FROM held a
+			JOIN held b ON b.employee_id = a.employee_id AND b.role_id <> a.role_id
+				AND a.valid_from < COALESCE(b.valid_to, 'infinity'::timestamptz)
+				AND b.valid_from < COALESCE(a.valid_to, 'infinity'::timestamptz)
+			JOIN sod_rule_role rr ON rr.role_id = a.role_id
+			JOIN sod_rule_role rb ON rb.rule_id = rr.rule_id AND rb.role_id = b.role_id`
+
+// нарушения с именами и действующим исключением
+const selectSodViolation = `SELECT c.*, e.name AS employee_name, s.name AS rule_name,
+		ra.name AS role_name, rb.name AS conflicting_role_name,
+		x.id AS exception_id, x.expires_at AS exception_expires_at
+	FROM conflicts c
+		JOIN employee e ON e.id = c.employee_id
+		JOIN sod_rule s ON s.id = c.rule_id
+		JOIN role ra ON ra.id = c.role_id
+		JOIN role rb ON rb.id = c.conflicting_role_id
+		LEFT JOIN LATERAL (
+			SELECT id, expires_at FROM sod_exception
+			WHERE rule_id = c.rule_id AND employee_id = c.employee_id AND revoked_at IS NULL AND expires_at > now()
+			ORDER BY expires_at DESC
+			LIMIT 1
+		) x ON TRUE`
+
+func (r *Repository) FindSodRules(ctx context.Context) ([]sodRuleEntity, error) {
+	var rules []sodRuleEntity
+	err := r.db.SelectContext(ctx, &rules, selectSodRule+" ORDER BY s.id")
+	return rules, err
+}
+
+func (r *Repository) FindSodRuleById(ctx context.Context, id int64) (rule sodRuleEntity, err error) {
+	err = r.db.GetContext(ctx, &rule, selectSodRule+" WHERE s.id = $1", id)
+	return rule, err
+}
+
+// Найти правило по id и заблокировать его строку до конца транзакции
+func (r *Repository) FindSodRuleForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (rule sodRuleEntity, err error) {
+	err = tx.GetContext(ctx, &rule, selectSodRule+" WHERE s.id = $1 FOR UPDATE OF s", id)
+	return rule, err
+}
+
+// Проверить, есть ли другое правило с таким именем; имена сравниваются без учёта регистра
+func (r *Repository) SodRuleNameExistsTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists,
+		"SELECT exists(SELECT 1 FROM sod_rule WHERE lower(name) = lower($1) AND id <> $2)", name, excludeId)
+	return isExists, err
+}
+
+// Найти id ролей из списка, которых нет среди неудалённых ролей
+func (r *Repository) FindMissingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]int64, error) {
+	var missing []int64
+	err := tx.SelectContext(ctx, &missing,
+		`SELECT ids.role_id FROM unnest($1::bigint[]) AS ids(role_id)
+		WHERE NOT exists(SELECT 1 FROM role r WHERE r.id = ids.role_id AND r.deleted_at IS NULL)
+		ORDER BY ids.role_id`,
+		pq.Array(ids))
+	return missing, err
+}
+
+func (r *Repository) SaveSodRuleTx(ctx context.Context, tx *sqlx.Tx, rule sodRuleEntity) (id int64, err error) {
+	err = tx.GetContext(ctx, &id,
+		`WITH created AS (
+			INSERT INTO sod_rule (name, description) VALUES ($1, $2) RETURNING id
+		), roles AS (
+			INSERT INTO sod_rule_role (rule_id, role_id)
+			SELECT created.id, role_id FROM created, unnest($3::bigint[]) AS role_id
+		)
+		SELECT id FROM created`,
+		rule.Name, rule.Description, rule.RoleIds)
+	return id, err
+}
+
+// Обновить правило и заменить его роли
+func (r *Repository) UpdateSodRuleTx(ctx context.Context, tx *sqlx.Tx, rule sodRuleEntity) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE sod_rule SET name = $2, description = $3 WHERE id = $1", rule.Id, rule.Name, rule.Description)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM sod_rule_role WHERE rule_id = $1 AND NOT role_id = ANY ($2)", rule.Id, rule.RoleIds)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO sod_rule_role (rule_id, role_id)
+		SELECT $1, role_id FROM unnest($2::bigint[]) AS role_id
+		ON CONFLICT DO NOTHING`,
+		rule.Id, rule.RoleIds)
+	return err
+}
+
+// Удалить правило; его роли и исключения удаляются каскадно
+func (r *Repository) DeleteSodRuleTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM sod_rule WHERE id = $1", id)
+	return err
+}
+
+func (r *Repository) SaveSodExceptionTx(ctx context.Context, tx *sqlx.Tx, exception sodExceptionEntity) (saved sodExceptionEntity, err error) {
+	err = tx.GetContext(ctx, &saved,
+		`INSERT INTO sod_exception (rule_id, employee_id, justification, expires_at, approved_by_sub, approved_by_username)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING *`,
+		exception.RuleId, exception.EmployeeId, exception.Justification, exception.ExpiresAt,
+		exception.ApprovedBySub, exception.ApprovedByUsername)
+	return saved, err
+}
+
+// Проверить, есть ли у сотрудника действующее исключение из правила
+func (r *Repository) HasActiveSodExceptionTx(ctx context.Context, tx *sqlx.Tx, ruleId, employeeId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists,
+		`SELECT exists(
+			SELECT 1 FROM sod_exception
+			WHERE rule_id = $1 AND employee_id = $2 AND revoked_at IS NULL AND expires_at > now()
+		)`,
+		ruleId, employeeId)
+	return isExists, err
+}
+
+// Найти исключения, новые первыми
+func (r *Repository) FindSodExceptions(ctx context.Context, filter SodExceptionFilter) ([]sodExceptionEntity, error) {
+	var exceptions []sodExceptionEntity
+	err := r.db.SelectContext(ctx, &exceptions,
+		`SELECT * FROM sod_exception
+		WHERE ($1::bigint = 0 OR rule_id = $1) AND ($2::bigint = 0 OR employee_id = $2)
+			AND (NOT $3 OR (revoked_at IS NULL AND expires_at > now()))
+		ORDER BY id DESC`,
+		filter.RuleId, filter.EmployeeId, filter.ActiveOnly)
+	return exceptions, err
+}
+
+// Найти исключение по id и заблокировать его строку до конца транзакции
+func (r *Repository) FindSodExceptionForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (exception sodExceptionEntity, err error) {
+	err = tx.GetContext(ctx, &exception, "SELECT * FROM sod_exception WHERE id = $1 FOR UPDATE", id)
+	return exception, err
+}
+
+func (r *Repository) RevokeSodExceptionTx(ctx context.Context, tx *sqlx.Tx, id int64) (revoked sodExceptionEntity, err error) {
+	err = tx.GetContext(ctx, &revoked,
+		"UPDATE sod_exception SET revoked_at = now() WHERE id = $1 RETURNING *", id)
+	return revoked, err
+}
+
+// Найти нарушения правил, которые дают сотруднику роли roleIds вместе с другими его ролями.
+// Учитываются действующие и будущие назначения, в том числе ещё не зафиксированные в транзакции tx.
+// Нарушения, разрешённые действующими исключениями, не возвращаются
+func (r *Repository) FindSodConflictsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) ([]sodViolationEntity, error) {
+	var conflicts []sodViolationEntity
+	err := tx.SelectContext(ctx, &conflicts,
+		sodHeldRoles+`, conflicts AS (
+			SELECT DISTINCT ON (p.rule_id, LEAST(p.role_id, p.conflicting_role_id), GREATEST(p.role_id, p.conflicting_role_id)) p.*
+			FROM (`+sodConflictPairs+`
+				WHERE a.source_role_id = ANY ($3)
+			) p
+			ORDER BY p.rule_id, LEAST(p.role_id, p.conflicting_role_id), GREATEST(p.role_id, p.conflicting_role_id)
+		)
+		`+selectSodViolation+`
+		WHERE x.id IS NULL
+		ORDER BY c.rule_id, c.role_id, c.conflicting_role_id`,
+		employeeId, true, pq.Array(roleIds))
+	return conflicts, err
+}
+
+// Найти действующие нарушения правил; каждая пара ролей сотрудника возвращается один раз
+func (r *Repository) FindSodViolations(ctx context.Context, filter SodViolationFilter) ([]sodViolationEntity, error) {
+	var violations []sodViolationEntity
+	err := r.db.SelectContext(ctx, &violations,
+		sodHeldRoles+`, conflicts AS (
+			SELECT DISTINCT ON (p.employee_id, p.rule_id, p.role_id, p.conflicting_role_id) p.*
+			FROM (`+sodConflictPairs+`
+				WHERE a.role_id < b.role_id AND ($3::bigint = 0 OR rr.rule_id = $3)
+			) p
+			ORDER BY p.employee_id, p.rule_id, p.role_id, p.conflicting_role_id, p.source_role_id, p.conflicting_source_role_id
+		)
+		`+selectSodViolation+`
+		WHERE $4 OR x.id IS NULL
+		ORDER BY c.employee_id, c.rule_id, c.role_id, c.conflicting_role_id`,
+		filter.EmployeeId, false, filter.RuleId, filter.IncludeExcepted)
+	return violations, err
+}
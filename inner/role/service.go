@@ -2,8 +2,15 @@ package role
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"iter"
+	"slices"
+	"sort"
+	"time"
 
+	"idm/inner/audit"
 	"idm/inner/common"
 	"idm/inner/validator"
 
@@ -13,6 +20,7 @@ import (
 
 type Service struct {
 	repo      Repo
+	auditor   Auditor
 	validator Validator
 	logger    *common.Logger
 }
@@ -21,12 +29,56 @@ type Repo interface {
 	FindById(ctx context.Context, id int64) (Entity, error)
 	Add(ctx context.Context, role *Entity) error
 	FindAll(ctx context.Context) ([]Entity, error)
+	FindWithCursor(ctx context.Context, filter Filter, sort string, position *common.Cursor, limit int) ([]Entity, error)
+	ExportRoles(ctx context.Context, sort string) (iter.Seq2[exportEntity, error], error)
 	FindByIds(ctx context.Context, ids []int64) ([]Entity, error)
-	DeleteById(ctx context.Context, id int64) error
-	DeleteByIds(ctx context.Context, ids []int64) error
 	BeginTransaction(ctx context.Context) (*sqlx.Tx, error)
 	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
 	SaveTx(ctx context.Context, tx *sqlx.Tx, role Entity) (int64, error)
+	FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error)
+	DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error
+	FindHoldersTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]holderEntity, error)
+	FindChildrenForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]Entity, error)
+	FindSubtreeForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]Entity, error)
+	ReparentChildrenTx(ctx context.Context, tx *sqlx.Tx, id, newParentId int64) error
+	ReassignHoldersTx(ctx context.Context, tx *sqlx.Tx, fromRoleId, toRoleId int64) error
+	RevokeAssignmentsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error)
+	SyncEmployeesRoleIdTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error
+	FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, deletedBefore time.Time) ([]Entity, error)
+	ExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error
+	IsInParentChainTx(ctx context.Context, tx *sqlx.Tx, roleId, ancestorId int64) (bool, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, role Entity) (Entity, error)
+	SetStatusTx(ctx context.Context, tx *sqlx.Tx, id int64, status bool) (Entity, error)
+	FindAncestors(ctx context.Context, id int64) ([]hierarchyEntity, error)
+	FindDescendants(ctx context.Context, id int64) ([]hierarchyEntity, error)
+	FindTree(ctx context.Context) ([]treeEntity, error)
+	EmployeeExists(ctx context.Context, employeeId int64) (bool, error)
+	EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error)
+	FindEffectiveByEmployeeId(ctx context.Context, employeeId int64) ([]effectiveEntity, error)
+	FindSodRules(ctx context.Context) ([]sodRuleEntity, error)
+	FindSodRuleById(ctx context.Context, id int64) (sodRuleEntity, error)
+	FindSodRuleForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (sodRuleEntity, error)
+	SodRuleNameExistsTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (bool, error)
+	FindMissingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]int64, error)
+	SaveSodRuleTx(ctx context.Context, tx *sqlx.Tx, rule sodRuleEntity) (int64, error)
+	UpdateSodRuleTx(ctx context.Context, tx *sqlx.Tx, rule sodRuleEntity) error
+	DeleteSodRuleTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+	SaveSodExceptionTx(ctx context.Context, tx *sqlx.Tx, exception sodExceptionEntity) (sodExceptionEntity, error)
+	HasActiveSodExceptionTx(ctx context.Context, tx *sqlx.Tx, ruleId, employeeId int64) (bool, error)
+	FindSodExceptions(ctx context.Context, filter SodExceptionFilter) ([]sodExceptionEntity, error)
+	FindSodExceptionForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (sodExceptionEntity, error)
+	RevokeSodExceptionTx(ctx context.Context, tx *sqlx.Tx, id int64) (sodExceptionEntity, error)
+	FindSodConflictsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) ([]sodViolationEntity, error)
+	FindSodViolations(ctx context.Context, filter SodViolationFilter) ([]sodViolationEntity, error)
+}
+
+// интерфейс журнала аудита: событие пишется в транзакции изменения
+type Auditor interface {
+	Record(ctx context.Context, tx *sqlx.Tx, event audit.Event) error
 }
 
 type Validator interface {
@@ -34,9 +86,10 @@ type Validator interface {
 }
 
 // функция-конструктор
-func NewService(repo Repo, validator Validator, logger *common.Logger) *Service {
+func NewService(repo Repo, auditor Auditor, validator Validator, logger *common.Logger) *Service {
 	return &Service{
 		repo:      repo,
+		auditor:   auditor,
 		validator: validator,
 		logger:    logger,
 	}
@@ -90,6 +143,18 @@ func (svc *Service) CreateRole(ctx context.Context, request CreateRequest) (int6
 		return 0, common.AlreadyExistsError{Message: fmt.Sprintf("role with name %s already exists", request.Name)}
 	}
 
+	// родительская роль должна существовать, иначе вместо понятной ошибки получим нарушение FK
+	if request.ParentId != nil {
+		if err = svc.checkParent(ctx, tx, 0, *request.ParentId); err != nil {
+			return 0, err
+		}
+	}
+	if request.OwnerId != nil {
+		if err = svc.checkOwner(ctx, tx, *request.OwnerId); err != nil {
+			return 0, err
+		}
+	}
+
 	// в случае отсутствия роли с таким же именем - в рамках этой же транзакции вызываем метод репозитория,
 	// который должен будет создать новую роль
 	newRoleId, err := svc.repo.SaveTx(ctx, tx, request.ToEntity())
@@ -98,13 +163,23 @@ func (svc *Service) CreateRole(ctx context.Context, request CreateRequest) (int6
 			zap.String("name", request.Name),
 			zap.Error(err))
 		err = fmt.Errorf("error creating role with name: %s %v", request.Name, err)
-	} else {
-		svc.logger.Info("Role created successfully",
-			zap.String("name", request.Name),
-			zap.Int64("id", newRoleId))
+		return newRoleId, err
 	}
 
-	return newRoleId, err
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionCreate,
+		EntityType: audit.EntityRole,
+		EntityId:   newRoleId,
+		After:      request,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	svc.logger.Info("Role created successfully",
+		zap.String("name", request.Name),
+		zap.Int64("id", newRoleId))
+	return newRoleId, nil
 }
 
 // валидация запроса на создание роли
@@ -175,22 +250,67 @@ func (svc *Service) Add(ctx context.Context, role *Entity) (Response, error) {
 	return role.toResponse(), nil
 }
 
-func (svc *Service) FindAll(ctx context.Context) ([]Response, error) {
-	svc.logger.Debug("Fetching all roles")
+// Метод для постраничного вывода ролей по курсору. Курсор действителен только
+// для той сортировки, с которой он выдан
+func (svc *Service) FindWithCursor(ctx context.Context, request CursorRequest) (CursorPage, error) {
+	svc.logger.Debug("Fetching roles with cursor",
+		zap.Int("limit", request.Limit),
+		zap.String("sort", request.Sort))
+
+	if err := svc.validateRequest(request); err != nil {
+		return CursorPage{}, err
+	}
+	column, _, err := parseCursorSort(request.Sort)
+	if err != nil {
+		return CursorPage{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if request.Cursor != nil && request.Cursor.Sort != request.Sort {
+		return CursorPage{}, common.RequestValidationError{Message: "cursor was issued for another sort"}
+	}
 
-	roles, err := svc.repo.FindAll(ctx)
+	// на одну строку больше, чтобы узнать, есть ли следующая страница
+	roles, err := svc.repo.FindWithCursor(ctx, request.Filter, request.Sort, request.Cursor, request.Limit+1)
 	if err != nil {
-		svc.logger.Error("Failed to fetch all roles", zap.Error(err))
-		return nil, fmt.Errorf("error finding all roles: %w", err)
+		svc.logger.Error("Failed to fetch roles with cursor", zap.Error(err))
+		return CursorPage{}, fmt.Errorf("error finding roles with cursor: %w", err)
 	}
 
+	roles, next, prev := common.CursorWindow(roles, request.Limit, request.Sort, request.Cursor,
+		func(e Entity) (string, int64) { return column.value(e), e.Id })
+
 	responses := make([]Response, len(roles))
 	for i, entity := range roles {
 		responses[i] = entity.toResponse()
 	}
 
-	svc.logger.Debug("Found all roles successfully", zap.Int("count", len(responses)))
-	return responses, nil
+	svc.logger.Debug("Found roles with cursor successfully", zap.Int("count", len(responses)))
+	return CursorPage{Data: responses, Next: next, Prev: prev}, nil
+}
+
+// Метод для выгрузки всех ролей в порядке сортировки вывода по курсору
+func (svc *Service) Export(ctx context.Context, sort string) (iter.Seq2[ExportRecord, error], error) {
+	svc.logger.Debug("Exporting roles", zap.String("sort", sort))
+
+	if _, _, err := parseCursorSort(sort); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+
+	entities, err := svc.repo.ExportRoles(ctx, sort)
+	if err != nil {
+		svc.logger.Error("Failed to export roles", zap.Error(err))
+		return nil, fmt.Errorf("error exporting roles: %w", err)
+	}
+	return func(yield func(ExportRecord, error) bool) {
+		for entity, err := range entities {
+			if err != nil {
+				yield(ExportRecord{}, fmt.Errorf("error exporting roles: %w", err))
+				return
+			}
+			if !yield(entity.toExportRecord(), nil) {
+				return
+			}
+		}
+	}, nil
 }
 
 func (svc *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
@@ -220,21 +340,312 @@ func (svc *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, err
 	return responses, nil
 }
 
-func (svc *Service) DeleteById(ctx context.Context, id int64) error {
-	svc.logger.Info("Deleting role by ID", zap.Int64("id", id))
+// Метод для мягкого удаления роли. Если на роль ссылаются сотрудники или дочерние роли,
+// поведение определяется политикой запроса: отказ, переназначение на роль-замену или каскадное удаление.
+// Удаление, обработка зависимых и запись в журнал выполняются в одной транзакции
+func (svc *Service) DeleteById(ctx context.Context, request DeleteRequest) (response DeleteResponse, err error) {
+	svc.logger.Info("Deleting role by ID",
+		zap.Int64("id", request.Id),
+		zap.String("policy", request.Policy))
+
+	if err := svc.validateRequest(request); err != nil {
+		return DeleteResponse{}, err
+	}
+	if request.Policy == "" {
+		request.Policy = DeletePolicyReject
+	}
+	id := request.Id
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for role deletion",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return DeleteResponse{}, fmt.Errorf("error delete role: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	// переподчинение и каскадное удаление меняют иерархию
+	if err = svc.repo.LockHierarchyTx(ctx, tx); err != nil {
+		return DeleteResponse{}, fmt.Errorf("error locking role hierarchy: %w", err)
+	}
 
-	err := svc.repo.DeleteById(ctx, id)
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
 	if err != nil {
-		svc.logger.Error("Failed to delete role by ID",
+		if errors.Is(err, sql.ErrNoRows) {
+			return DeleteResponse{}, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find role for deletion",
 			zap.Int64("id", id),
 			zap.Error(err))
-		return fmt.Errorf("error deleting role with id %d: %w", id, err)
+		return DeleteResponse{}, fmt.Errorf("error finding role with id %d: %w", id, err)
+	}
+
+	switch request.Policy {
+	case DeletePolicyCascade:
+		response, err = svc.deleteCascade(ctx, tx, id)
+	case DeletePolicyReassign:
+		response, err = svc.deleteReassign(ctx, tx, entity, request.ReplacementRoleId)
+	default:
+		response, err = svc.deleteReject(ctx, tx, entity)
+	}
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	svc.logger.Info("Role deleted successfully",
+		zap.Int64("id", id),
+		zap.String("policy", request.Policy),
+		zap.Int64s("deleted_role_ids", response.DeletedRoleIds),
+		zap.Int64s("affected_employee_ids", response.AffectedEmployeeIds))
+	return response, nil
+}
+
+// удаляет роль, только если на неё никто не ссылается
+func (svc *Service) deleteReject(ctx context.Context, tx *sqlx.Tx, entity Entity) (DeleteResponse, error) {
+	dependents, err := svc.findDependents(ctx, tx, entity.Id)
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+	if len(dependents.EmployeeIds) > 0 || len(dependents.ChildRoleIds) > 0 {
+		svc.logger.Warn("Role has dependents, deletion rejected",
+			zap.Int64("id", entity.Id),
+			zap.Int64s("employee_ids", dependents.EmployeeIds),
+			zap.Int64s("child_role_ids", dependents.ChildRoleIds))
+		return DeleteResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("role %d is referenced by %d employees and %d child roles",
+				entity.Id, len(dependents.EmployeeIds), len(dependents.ChildRoleIds)),
+			Data: dependents,
+		}
+	}
+
+	if err := svc.softDelete(ctx, tx, []Entity{entity}); err != nil {
+		return DeleteResponse{}, err
+	}
+	return DeleteResponse{
+		DeletedRoleIds:      []int64{entity.Id},
+		ReparentedRoleIds:   []int64{},
+		AffectedEmployeeIds: []int64{},
+	}, nil
+}
+
+// переназначает сотрудников и дочерние роли на роль-замену, затем удаляет роль
+func (svc *Service) deleteReassign(ctx context.Context, tx *sqlx.Tx, entity Entity, replacementId int64) (DeleteResponse, error) {
+	replacement, err := svc.repo.FindByIdForUpdateTx(ctx, tx, replacementId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DeleteResponse{}, common.NotFoundError{
+				Message: fmt.Sprintf("replacement role with id %d not found", replacementId),
+			}
+		}
+		return DeleteResponse{}, fmt.Errorf("error finding replacement role with id %d: %w", replacementId, err)
+	}
+	// неактивная роль не действует: переназначенные на неё сотрудники потеряли бы доступ
+	if !replacement.Status {
+		return DeleteResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("replacement role %d is inactive and cannot be assigned", replacementId),
+		}
+	}
+
+	// роль-замена из поддерева удаляемой роли после переподчинения образовала бы цикл
+	inSubtree, err := svc.repo.IsInParentChainTx(ctx, tx, replacementId, entity.Id)
+	if err != nil {
+		return DeleteResponse{}, fmt.Errorf("error checking role hierarchy: %w", err)
+	}
+	if inSubtree {
+		return DeleteResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("replacement role %d is a descendant of role %d", replacementId, entity.Id),
+		}
+	}
+
+	holders, err := svc.repo.FindHoldersTx(ctx, tx, []int64{entity.Id})
+	if err != nil {
+		return DeleteResponse{}, fmt.Errorf("error finding employees with role %d: %w", entity.Id, err)
+	}
+	children, err := svc.repo.FindChildrenForUpdateTx(ctx, tx, entity.Id)
+	if err != nil {
+		return DeleteResponse{}, fmt.Errorf("error finding child roles of role %d: %w", entity.Id, err)
+	}
+
+	if err := svc.repo.ReassignHoldersTx(ctx, tx, entity.Id, replacementId); err != nil {
+		return DeleteResponse{}, fmt.Errorf("error reassigning employees to role %d: %w", replacementId, err)
+	}
+	affected, err := svc.revokeAssignments(ctx, tx, []int64{entity.Id}, holders)
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+	for _, holder := range holders {
+		err = svc.auditor.Record(ctx, tx, audit.Event{
+			Action:     audit.ActionAssignRole,
+			EntityType: audit.EntityEmployee,
+			EntityId:   holder.EmployeeId,
+			After:      map[string]int64{"role_id": replacementId, "replaced_role_id": holder.RoleId},
+		})
+		if err != nil {
+			return DeleteResponse{}, err
+		}
+	}
+
+	reparented := make([]int64, 0, len(children))
+	if len(children) > 0 {
+		if err := svc.repo.ReparentChildrenTx(ctx, tx, entity.Id, replacementId); err != nil {
+			return DeleteResponse{}, fmt.Errorf("error reparenting child roles of role %d: %w", entity.Id, err)
+		}
+		for _, child := range children {
+			before := child.toResponse()
+			child.ParentId = &replacementId
+			err = svc.auditor.Record(ctx, tx, audit.Event{
+				Action:     audit.ActionUpdate,
+				EntityType: audit.EntityRole,
+				EntityId:   child.Id,
+				Before:     before,
+				After:      child.toResponse(),
+			})
+			if err != nil {
+				return DeleteResponse{}, err
+			}
+			reparented = append(reparented, child.Id)
+		}
+	}
+
+	if err := svc.softDelete(ctx, tx, []Entity{entity}); err != nil {
+		return DeleteResponse{}, err
+	}
+
+	// роль-замена не должна нарушать правил разделения обязанностей у получивших её сотрудников
+	checked := make(map[int64]bool, len(holders))
+	for _, holder := range holders {
+		if checked[holder.EmployeeId] {
+			continue
+		}
+		checked[holder.EmployeeId] = true
+		if err := svc.CheckSodTx(ctx, tx, holder.EmployeeId, []int64{replacementId}); err != nil {
+			return DeleteResponse{}, err
+		}
+	}
+	return DeleteResponse{
+		DeletedRoleIds:      []int64{entity.Id},
+		ReparentedRoleIds:   reparented,
+		AffectedEmployeeIds: affected,
+	}, nil
+}
+
+// удаляет роль вместе со всеми потомками и отзывает их назначения
+func (svc *Service) deleteCascade(ctx context.Context, tx *sqlx.Tx, id int64) (DeleteResponse, error) {
+	subtree, err := svc.repo.FindSubtreeForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return DeleteResponse{}, fmt.Errorf("error finding subtree of role %d: %w", id, err)
+	}
+	ids := make([]int64, len(subtree))
+	for i, entity := range subtree {
+		ids[i] = entity.Id
+	}
+
+	holders, err := svc.repo.FindHoldersTx(ctx, tx, ids)
+	if err != nil {
+		return DeleteResponse{}, fmt.Errorf("error finding employees with roles: %w", err)
+	}
+	affected, err := svc.revokeAssignments(ctx, tx, ids, holders)
+	if err != nil {
+		return DeleteResponse{}, err
+	}
+
+	if err := svc.softDelete(ctx, tx, subtree); err != nil {
+		return DeleteResponse{}, err
+	}
+	return DeleteResponse{
+		DeletedRoleIds:      ids,
+		ReparentedRoleIds:   []int64{},
+		AffectedEmployeeIds: affected,
+	}, nil
+}
+
+// сотрудники и дочерние роли, ссылающиеся на роль
+func (svc *Service) findDependents(ctx context.Context, tx *sqlx.Tx, id int64) (Dependents, error) {
+	holders, err := svc.repo.FindHoldersTx(ctx, tx, []int64{id})
+	if err != nil {
+		return Dependents{}, fmt.Errorf("error finding employees with role %d: %w", id, err)
+	}
+	children, err := svc.repo.FindChildrenForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return Dependents{}, fmt.Errorf("error finding child roles of role %d: %w", id, err)
+	}
+
+	dependents := Dependents{
+		EmployeeIds:  make([]int64, 0, len(holders)),
+		ChildRoleIds: make([]int64, 0, len(children)),
+	}
+	for _, holder := range holders {
+		dependents.EmployeeIds = append(dependents.EmployeeIds, holder.EmployeeId)
+	}
+	for _, child := range children {
+		dependents.ChildRoleIds = append(dependents.ChildRoleIds, child.Id)
+	}
+	return dependents, nil
+}
+
+// отзывает назначения ролей, синхронизирует employee.role_id и пишет в журнал событие на каждое назначение
+func (svc *Service) revokeAssignments(ctx context.Context, tx *sqlx.Tx, roleIds []int64, holders []holderEntity) ([]int64, error) {
+	affected, err := svc.repo.RevokeAssignmentsTx(ctx, tx, roleIds)
+	if err != nil {
+		return nil, fmt.Errorf("error revoking assignments of roles: %w", err)
+	}
+	if len(affected) > 0 {
+		if err := svc.repo.SyncEmployeesRoleIdTx(ctx, tx, affected); err != nil {
+			return nil, fmt.Errorf("error syncing employees role_id: %w", err)
+		}
+	}
+
+	for _, holder := range holders {
+		err = svc.auditor.Record(ctx, tx, audit.Event{
+			Action:     audit.ActionRevokeRole,
+			EntityType: audit.EntityEmployee,
+			EntityId:   holder.EmployeeId,
+			Before:     map[string]int64{"role_id": holder.RoleId},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if affected == nil {
+		affected = []int64{}
+	}
+	return affected, nil
+}
+
+// мягко удаляет роли и пишет в журнал событие на каждую
+func (svc *Service) softDelete(ctx context.Context, tx *sqlx.Tx, entities []Entity) error {
+	ids := make([]int64, len(entities))
+	for i, entity := range entities {
+		ids[i] = entity.Id
+	}
+	if err := svc.repo.DeleteByIdsTx(ctx, tx, ids); err != nil {
+		svc.logger.Error("Failed to delete roles by IDs",
+			zap.Int64s("ids", ids),
+			zap.Error(err))
+		return fmt.Errorf("error deleting roles with ids: %w", err)
+	}
+
+	for _, entity := range entities {
+		err := svc.auditor.Record(ctx, tx, audit.Event{
+			Action:     audit.ActionDelete,
+			EntityType: audit.EntityRole,
+			EntityId:   entity.Id,
+			Before:     entity.toResponse(),
+		})
+		if err != nil {
+			return err
+		}
 	}
-	svc.logger.Info("Role deleted successfully", zap.Int64("id", id))
 	return nil
 }
 
-func (svc *Service) DeleteByIds(ctx context.Context, ids []int64) error {
+// Метод для мягкого удаления ролей по списку id; в журнал пишется событие на каждую удалённую роль.
+// Массовое удаление всегда работает по политике DeletePolicyReject: роли, на которые ссылаются
+// сотрудники или не входящие в список дочерние роли, не удаляются
+func (svc *Service) DeleteByIds(ctx context.Context, ids []int64) (err error) {
 	svc.logger.Info("Deleting roles by IDs", zap.Int64s("ids", ids))
 
 	if len(ids) == 0 {
@@ -242,14 +653,1028 @@ func (svc *Service) DeleteByIds(ctx context.Context, ids []int64) error {
 		return nil
 	}
 
-	err := svc.repo.DeleteByIds(ctx, ids)
+	tx, err := svc.repo.BeginTransaction(ctx)
 	if err != nil {
-		svc.logger.Error("Failed to delete roles by IDs",
+		svc.logger.Error("Failed to begin transaction for roles deletion",
 			zap.Int64s("ids", ids),
 			zap.Error(err))
-		return fmt.Errorf("error deleting roles with ids: %w", err)
+		return fmt.Errorf("error delete roles: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, 0)
+	}()
+
+	if err = svc.repo.LockHierarchyTx(ctx, tx); err != nil {
+		return fmt.Errorf("error locking role hierarchy: %w", err)
+	}
+
+	entities, err := svc.repo.FindByIdsForUpdateTx(ctx, tx, ids)
+	if err != nil {
+		svc.logger.Error("Failed to find roles for deletion",
+			zap.Int64s("ids", ids),
+			zap.Error(err))
+		return fmt.Errorf("error finding roles with ids: %w", err)
+	}
+	if len(entities) == 0 {
+		return nil
+	}
+
+	deleting := make(map[int64]bool, len(entities))
+	for _, entity := range entities {
+		deleting[entity.Id] = true
+	}
+	dependents := Dependents{EmployeeIds: []int64{}, ChildRoleIds: []int64{}}
+	for _, entity := range entities {
+		found, err := svc.findDependents(ctx, tx, entity.Id)
+		if err != nil {
+			return err
+		}
+		dependents.EmployeeIds = append(dependents.EmployeeIds, found.EmployeeIds...)
+		for _, childId := range found.ChildRoleIds {
+			if !deleting[childId] {
+				dependents.ChildRoleIds = append(dependents.ChildRoleIds, childId)
+			}
+		}
+	}
+	if len(dependents.EmployeeIds) > 0 || len(dependents.ChildRoleIds) > 0 {
+		slices.Sort(dependents.EmployeeIds)
+		dependents.EmployeeIds = slices.Compact(dependents.EmployeeIds)
+		return common.ConflictError{
+			Message: fmt.Sprintf("roles are referenced by %d employees and %d child roles",
+				len(dependents.EmployeeIds), len(dependents.ChildRoleIds)),
+			Data: dependents,
+		}
+	}
+
+	if err = svc.softDelete(ctx, tx, entities); err != nil {
+		return err
 	}
 
 	svc.logger.Info("Roles deleted successfully", zap.Int64s("ids", ids))
 	return nil
 }
+
+// Метод для восстановления мягко удалённой роли.
+// Восстановление невозможно, если имя уже занято действующей ролью
+func (svc *Service) Restore(ctx context.Context, id int64) (response Response, err error) {
+	svc.logger.Info("Restoring role", zap.Int64("id", id))
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for role restore",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error restore role: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	deleted, err := svc.repo.FindDeletedByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("deleted role with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find deleted role",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding deleted role with id %d: %w", id, err)
+	}
+
+	nameTaken, err := svc.repo.FindByNameTx(ctx, tx, deleted.Name)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding role by name: %s, %w", deleted.Name, err)
+	}
+	if nameTaken {
+		return Response{}, common.AlreadyExistsError{
+			Message: fmt.Sprintf("role with name %s already exists", deleted.Name),
+		}
+	}
+
+	restored, err := svc.repo.RestoreTx(ctx, tx, id)
+	if err != nil {
+		svc.logger.Error("Failed to restore role",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error restoring role with id %d: %w", id, err)
+	}
+
+	response = restored.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionRestore,
+		EntityType: audit.EntityRole,
+		EntityId:   id,
+		Before:     deleted.toResponse(),
+		After:      response,
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Role restored successfully", zap.Int64("id", id))
+	return response, nil
+}
+
+// Метод для окончательного удаления ролей, мягко удалённых раньше срока хранения.
+// Роли, на которые ссылаются назначения сотрудников, остаются до следующей очистки,
+// роли с запросами или пересмотрами доступа не удаляются окончательно, чтобы сохранить их историю
+func (svc *Service) Purge(ctx context.Context, request PurgeRequest) (response PurgeResponse, err error) {
+	svc.logger.Info("Purging deleted roles", zap.Int("retention_days", request.RetentionDays))
+
+	if err := svc.validateRequest(request); err != nil {
+		return PurgeResponse{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for roles purge", zap.Error(err))
+		return PurgeResponse{}, fmt.Errorf("error purge roles: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, 0)
+	}()
+
+	deletedBefore := time.Now().AddDate(0, 0, -request.RetentionDays)
+	purged, err := svc.repo.PurgeDeletedTx(ctx, tx, deletedBefore)
+	if err != nil {
+		svc.logger.Error("Failed to purge deleted roles",
+			zap.Time("deleted_before", deletedBefore),
+			zap.Error(err))
+		return PurgeResponse{}, fmt.Errorf("error purging deleted roles: %w", err)
+	}
+
+	response.PurgedIds = make([]int64, 0, len(purged))
+	for _, entity := range purged {
+		err = svc.auditor.Record(ctx, tx, audit.Event{
+			Action:     audit.ActionPurge,
+			EntityType: audit.EntityRole,
+			EntityId:   entity.Id,
+			Before:     entity.toResponse(),
+		})
+		if err != nil {
+			return PurgeResponse{}, err
+		}
+		response.PurgedIds = append(response.PurgedIds, entity.Id)
+	}
+
+	svc.logger.Info("Deleted roles purged successfully", zap.Int64s("ids", response.PurgedIds))
+	return response, nil
+}
+
+// Метод для полного обновления роли, включая смену родителя
+func (svc *Service) UpdateRole(ctx context.Context, request UpdateRequest) (Response, error) {
+	svc.logger.Info("Updating role", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+
+	return svc.update(ctx, request.Id, request.applyTo)
+}
+
+// Метод для частичного обновления роли
+func (svc *Service) PatchRole(ctx context.Context, request PatchRequest) (Response, error) {
+	svc.logger.Info("Patching role", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request); err != nil {
+		return Response{}, err
+	}
+
+	return svc.update(ctx, request.Id, request.applyTo)
+}
+
+// Метод для активации роли: роль снова действует для сотрудников, которым назначена
+func (svc *Service) Activate(ctx context.Context, id int64) (Response, error) {
+	return svc.setStatus(ctx, id, true)
+}
+
+// Метод для деактивации роли. Назначения роли сохраняются, но роль и унаследованные
+// через неё роли не действуют, а новые назначения роли отклоняются
+func (svc *Service) Deactivate(ctx context.Context, id int64) (Response, error) {
+	return svc.setStatus(ctx, id, false)
+}
+
+// меняет статус роли; роль, уже находящаяся в нужном статусе, возвращается без изменений
+func (svc *Service) setStatus(ctx context.Context, id int64, status bool) (response Response, err error) {
+	svc.logger.Info("Changing role status",
+		zap.Int64("id", id),
+		zap.Bool("status", status))
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for role status change",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error change role status: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find role for status change",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding role with id %d: %w", id, err)
+	}
+	if entity.Status == status {
+		return entity.toResponse(), nil
+	}
+
+	updated, err := svc.repo.SetStatusTx(ctx, tx, id, status)
+	if err != nil {
+		svc.logger.Error("Failed to change role status",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error changing status of role with id %d: %w", id, err)
+	}
+	if status {
+		if err = svc.checkSubtreeSodTx(ctx, tx, id); err != nil {
+			return Response{}, err
+		}
+	}
+
+	response = updated.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionUpdate,
+		EntityType: audit.EntityRole,
+		EntityId:   id,
+		Before:     entity.toResponse(),
+		After:      response,
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Role status changed successfully",
+		zap.Int64("id", id),
+		zap.Bool("status", status))
+	return response, nil
+}
+
+// валидация запроса на изменение роли
+func (svc *Service) validateRequest(request any) error {
+	err := svc.validator.Validate(request)
+	if err != nil {
+		svc.logger.Error("Role update request validation failed", zap.Error(err))
+
+		if validationErr, ok := err.(validator.ValidationErrors); ok {
+			return common.RequestValidationError{
+				Message: "Data validation error",
+				Data:    validationErr.Errors,
+			}
+		}
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return nil
+}
+
+// обновляет роль в транзакции с проверкой уникальности имени и отсутствия циклов в иерархии
+func (svc *Service) update(ctx context.Context, id int64, apply func(entity *Entity)) (response Response, err error) {
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for role update",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error update role: error creating transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				svc.logger.Error("Failed to rollback transaction",
+					zap.Int64("id", id),
+					zap.Error(rollbackErr))
+			}
+		} else {
+			if commitErr := tx.Commit(); commitErr != nil {
+				svc.logger.Error("Failed to commit transaction",
+					zap.Int64("id", id),
+					zap.Error(commitErr))
+				err = commitErr
+			}
+		}
+	}()
+
+	entity, err := svc.repo.FindByIdForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			svc.logger.Warn("Role for update not found", zap.Int64("id", id))
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find role for update",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error finding role with id %d: %w", id, err)
+	}
+
+	before := entity.toResponse()
+	currentName := entity.Name
+	apply(&entity)
+
+	if entity.Name != currentName {
+		isExist, err := svc.repo.FindByNameTx(ctx, tx, entity.Name)
+		if err != nil {
+			svc.logger.Error("Failed to check role existence",
+				zap.String("name", entity.Name),
+				zap.Error(err))
+			return Response{}, fmt.Errorf("error finding role by name: %s, %w", entity.Name, err)
+		}
+		if isExist {
+			svc.logger.Warn("Role already exists", zap.String("name", entity.Name))
+			return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf("role with name %s already exists", entity.Name)}
+		}
+	}
+
+	if entity.ParentId != nil {
+		if err = svc.checkParent(ctx, tx, id, *entity.ParentId); err != nil {
+			return Response{}, err
+		}
+	}
+	// прежний владелец мог быть удалён, проверяется только смена владельца
+	if entity.OwnerId != nil && (before.OwnerId == nil || *before.OwnerId != *entity.OwnerId) {
+		if err = svc.checkOwner(ctx, tx, *entity.OwnerId); err != nil {
+			return Response{}, err
+		}
+	}
+
+	updated, err := svc.repo.UpdateTx(ctx, tx, entity)
+	if err != nil {
+		svc.logger.Error("Failed to update role",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return Response{}, fmt.Errorf("error updating role with id %d: %w", id, err)
+	}
+	// новый родитель или активация роли добавляют её обладателям унаследованные роли
+	parentChanged := entity.ParentId != nil && (before.ParentId == nil || *before.ParentId != *entity.ParentId)
+	if parentChanged || (entity.Status && !before.Status) {
+		if err = svc.checkSubtreeSodTx(ctx, tx, id); err != nil {
+			return Response{}, err
+		}
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionUpdate,
+		EntityType: audit.EntityRole,
+		EntityId:   id,
+		Before:     before,
+		After:      updated.toResponse(),
+	})
+	if err != nil {
+		return Response{}, err
+	}
+
+	svc.logger.Info("Role updated successfully", zap.Int64("id", id))
+	return updated.toResponse(), nil
+}
+
+// проверяет, что роль parentId может стать родителем роли roleId:
+// родитель существует и не является самой ролью или её потомком.
+// roleId = 0 означает создаваемую роль, у которой ещё нет потомков
+func (svc *Service) checkParent(ctx context.Context, tx *sqlx.Tx, roleId, parentId int64) error {
+	if parentId == roleId {
+		return common.ConflictError{Message: fmt.Sprintf("role %d cannot be its own parent", roleId)}
+	}
+
+	if err := svc.repo.LockHierarchyTx(ctx, tx); err != nil {
+		svc.logger.Error("Failed to lock role hierarchy", zap.Error(err))
+		return fmt.Errorf("error locking role hierarchy: %w", err)
+	}
+
+	isExist, err := svc.repo.ExistsTx(ctx, tx, parentId)
+	if err != nil {
+		svc.logger.Error("Failed to check parent role existence",
+			zap.Int64("parent_id", parentId),
+			zap.Error(err))
+		return fmt.Errorf("error finding parent role with id %d: %w", parentId, err)
+	}
+	if !isExist {
+		return common.RequestValidationError{Message: fmt.Sprintf("parent role with id %d does not exist", parentId)}
+	}
+
+	if roleId == 0 {
+		return nil
+	}
+
+	// если роль уже есть в цепочке родителей нового родителя, то новый родитель - её потомок
+	isCycle, err := svc.repo.IsInParentChainTx(ctx, tx, parentId, roleId)
+	if err != nil {
+		svc.logger.Error("Failed to check role hierarchy for cycles",
+			zap.Int64("id", roleId),
+			zap.Int64("parent_id", parentId),
+			zap.Error(err))
+		return fmt.Errorf("error checking role hierarchy: %w", err)
+	}
+	if isCycle {
+		svc.logger.Warn("Role hierarchy cycle rejected",
+			zap.Int64("id", roleId),
+			zap.Int64("parent_id", parentId))
+		return common.ConflictError{
+			Message: fmt.Sprintf("role %d cannot have parent %d: role %d is a descendant of role %d, this would create a cycle",
+				roleId, parentId, parentId, roleId),
+		}
+	}
+
+	return nil
+}
+
+// проверяет, что владельцем роли назначается существующий сотрудник
+func (svc *Service) checkOwner(ctx context.Context, tx *sqlx.Tx, ownerId int64) error {
+	isExist, err := svc.repo.EmployeeExistsTx(ctx, tx, ownerId)
+	if err != nil {
+		svc.logger.Error("Failed to check role owner existence",
+			zap.Int64("owner_id", ownerId),
+			zap.Error(err))
+		return fmt.Errorf("error finding owner employee with id %d: %w", ownerId, err)
+	}
+	if !isExist {
+		return common.RequestValidationError{Message: fmt.Sprintf("owner employee with id %d does not exist", ownerId)}
+	}
+	return nil
+}
+
+// Метод для получения всей иерархии ролей в виде дерева
+func (svc *Service) FindTree(ctx context.Context) ([]TreeNode, error) {
+	svc.logger.Debug("Building role tree")
+
+	roles, err := svc.repo.FindTree(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to fetch roles for tree", zap.Error(err))
+		return nil, fmt.Errorf("error building role tree: %w", err)
+	}
+
+	tree := buildTree(roles)
+	svc.logger.Debug("Role tree built successfully",
+		zap.Int("roles_count", len(roles)),
+		zap.Int("roots_count", len(tree)))
+	return tree, nil
+}
+
+// Метод для получения предков роли (от непосредственного родителя к корню)
+func (svc *Service) FindAncestors(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	svc.logger.Debug("Finding role ancestors", zap.Int64("id", id))
+
+	if err := svc.ensureRoleExists(ctx, id); err != nil {
+		return nil, err
+	}
+
+	roles, err := svc.repo.FindAncestors(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to find role ancestors",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding ancestors of role %d: %w", id, err)
+	}
+
+	return toHierarchyResponses(roles), nil
+}
+
+// Метод для получения всех потомков роли
+func (svc *Service) FindDescendants(ctx context.Context, id int64) ([]HierarchyResponse, error) {
+	svc.logger.Debug("Finding role descendants", zap.Int64("id", id))
+
+	if err := svc.ensureRoleExists(ctx, id); err != nil {
+		return nil, err
+	}
+
+	roles, err := svc.repo.FindDescendants(ctx, id)
+	if err != nil {
+		svc.logger.Error("Failed to find role descendants",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding descendants of role %d: %w", id, err)
+	}
+
+	return toHierarchyResponses(roles), nil
+}
+
+// Метод для вычисления эффективных ролей сотрудника:
+// назначенные роли плюс все роли, унаследованные через цепочку родителей
+func (svc *Service) FindEffectiveRoles(ctx context.Context, employeeId int64) ([]EffectiveRoleResponse, error) {
+	svc.logger.Debug("Finding effective roles", zap.Int64("employee_id", employeeId))
+
+	isExist, err := svc.repo.EmployeeExists(ctx, employeeId)
+	if err != nil {
+		svc.logger.Error("Failed to check employee existence",
+			zap.Int64("employee_id", employeeId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+	}
+	if !isExist {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", employeeId)}
+	}
+
+	roles, err := svc.repo.FindEffectiveByEmployeeId(ctx, employeeId)
+	if err != nil {
+		svc.logger.Error("Failed to find effective roles",
+			zap.Int64("employee_id", employeeId),
+			zap.Error(err))
+		return nil, fmt.Errorf("error finding effective roles of employee %d: %w", employeeId, err)
+	}
+
+	// сначала назначенные роли, затем унаследованные по мере удалённости
+	sort.SliceStable(roles, func(i, j int) bool {
+		if roles[i].Depth != roles[j].Depth {
+			return roles[i].Depth < roles[j].Depth
+		}
+		return roles[i].Id < roles[j].Id
+	})
+
+	responses := make([]EffectiveRoleResponse, len(roles))
+	for i, role := range roles {
+		responses[i] = role.toEffectiveResponse()
+	}
+
+	svc.logger.Debug("Effective roles found",
+		zap.Int64("employee_id", employeeId),
+		zap.Int("count", len(responses)))
+	return responses, nil
+}
+
+// возвращает NotFoundError, если роли с таким id нет
+func (svc *Service) ensureRoleExists(ctx context.Context, id int64) error {
+	_, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find role by ID",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error finding role with id %d: %w", id, err)
+	}
+	return nil
+}
+
+func toHierarchyResponses(roles []hierarchyEntity) []HierarchyResponse {
+	responses := make([]HierarchyResponse, len(roles))
+	for i, role := range roles {
+		responses[i] = role.toHierarchyResponse()
+	}
+	return responses
+}
+
+// завершает транзакцию: откатывает при ошибке, иначе фиксирует.
+// Возвращает исходную ошибку или ошибку фиксации
+func (svc *Service) finishTransaction(tx *sqlx.Tx, err error, id int64) error {
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			svc.logger.Error("Failed to rollback transaction",
+				zap.Int64("id", id),
+				zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		svc.logger.Error("Failed to commit transaction",
+			zap.Int64("id", id),
+			zap.Error(commitErr))
+		return commitErr
+	}
+	return nil
+}
+
+// Метод для получения всех правил разделения обязанностей
+func (svc *Service) FindSodRules(ctx context.Context) ([]SodRuleResponse, error) {
+	svc.logger.Debug("Finding SoD rules")
+
+	rules, err := svc.repo.FindSodRules(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to find SoD rules", zap.Error(err))
+		return nil, fmt.Errorf("error finding SoD rules: %w", err)
+	}
+
+	responses := make([]SodRuleResponse, len(rules))
+	for i, rule := range rules {
+		responses[i] = rule.toResponse()
+	}
+	return responses, nil
+}
+
+func (svc *Service) FindSodRuleById(ctx context.Context, id int64) (SodRuleResponse, error) {
+	svc.logger.Debug("Finding SoD rule by ID", zap.Int64("id", id))
+
+	rule, err := svc.repo.FindSodRuleById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SodRuleResponse{}, common.NotFoundError{Message: fmt.Sprintf("SoD rule with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find SoD rule by ID",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return SodRuleResponse{}, fmt.Errorf("error finding SoD rule with id %d: %w", id, err)
+	}
+	return rule.toResponse(), nil
+}
+
+// Метод для создания правила разделения обязанностей.
+// Правило не проверяет уже существующие назначения: их показывает отчёт о нарушениях
+func (svc *Service) CreateSodRule(ctx context.Context, request SodRuleRequest) (response SodRuleResponse, err error) {
+	svc.logger.Info("Creating SoD rule", zap.String("name", request.Name))
+
+	if err := svc.validateRequest(request); err != nil {
+		return SodRuleResponse{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for SoD rule creation", zap.Error(err))
+		return SodRuleResponse{}, fmt.Errorf("error create SoD rule: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, 0)
+	}()
+
+	if err = svc.checkSodRule(ctx, tx, request); err != nil {
+		return SodRuleResponse{}, err
+	}
+
+	id, err := svc.repo.SaveSodRuleTx(ctx, tx, request.toEntity())
+	if err != nil {
+		svc.logger.Error("Failed to save SoD rule",
+			zap.String("name", request.Name),
+			zap.Error(err))
+		return SodRuleResponse{}, fmt.Errorf("error creating SoD rule with name %s: %w", request.Name, err)
+	}
+	created, err := svc.repo.FindSodRuleForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return SodRuleResponse{}, fmt.Errorf("error finding SoD rule with id %d: %w", id, err)
+	}
+
+	response = created.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionCreate,
+		EntityType: audit.EntitySodRule,
+		EntityId:   id,
+		After:      response,
+	})
+	if err != nil {
+		return SodRuleResponse{}, err
+	}
+
+	svc.logger.Info("SoD rule created successfully", zap.Int64("id", id))
+	return response, nil
+}
+
+// Метод для изменения правила разделения обязанностей, включая его роли
+func (svc *Service) UpdateSodRule(ctx context.Context, request SodRuleRequest) (response SodRuleResponse, err error) {
+	svc.logger.Info("Updating SoD rule", zap.Int64("id", request.Id))
+
+	if err := svc.validateRequest(request); err != nil {
+		return SodRuleResponse{}, err
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for SoD rule update",
+			zap.Int64("id", request.Id),
+			zap.Error(err))
+		return SodRuleResponse{}, fmt.Errorf("error update SoD rule: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, request.Id)
+	}()
+
+	before, err := svc.findSodRuleForUpdate(ctx, tx, request.Id)
+	if err != nil {
+		return SodRuleResponse{}, err
+	}
+	if err = svc.checkSodRule(ctx, tx, request); err != nil {
+		return SodRuleResponse{}, err
+	}
+
+	if err = svc.repo.UpdateSodRuleTx(ctx, tx, request.toEntity()); err != nil {
+		svc.logger.Error("Failed to update SoD rule",
+			zap.Int64("id", request.Id),
+			zap.Error(err))
+		return SodRuleResponse{}, fmt.Errorf("error updating SoD rule with id %d: %w", request.Id, err)
+	}
+	updated, err := svc.repo.FindSodRuleForUpdateTx(ctx, tx, request.Id)
+	if err != nil {
+		return SodRuleResponse{}, fmt.Errorf("error finding SoD rule with id %d: %w", request.Id, err)
+	}
+
+	response = updated.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionUpdate,
+		EntityType: audit.EntitySodRule,
+		EntityId:   request.Id,
+		Before:     before.toResponse(),
+		After:      response,
+	})
+	if err != nil {
+		return SodRuleResponse{}, err
+	}
+
+	svc.logger.Info("SoD rule updated successfully", zap.Int64("id", request.Id))
+	return response, nil
+}
+
+// Метод для удаления правила вместе с его исключениями
+func (svc *Service) DeleteSodRule(ctx context.Context, id int64) (err error) {
+	svc.logger.Info("Deleting SoD rule", zap.Int64("id", id))
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for SoD rule deletion",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error delete SoD rule: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	rule, err := svc.findSodRuleForUpdate(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err = svc.repo.DeleteSodRuleTx(ctx, tx, id); err != nil {
+		svc.logger.Error("Failed to delete SoD rule",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("error deleting SoD rule with id %d: %w", id, err)
+	}
+
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionDelete,
+		EntityType: audit.EntitySodRule,
+		EntityId:   id,
+		Before:     rule.toResponse(),
+	})
+	if err != nil {
+		return err
+	}
+
+	svc.logger.Info("SoD rule deleted successfully", zap.Int64("id", id))
+	return nil
+}
+
+func (svc *Service) findSodRuleForUpdate(ctx context.Context, tx *sqlx.Tx, id int64) (sodRuleEntity, error) {
+	rule, err := svc.repo.FindSodRuleForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sodRuleEntity{}, common.NotFoundError{Message: fmt.Sprintf("SoD rule with id %d not found", id)}
+		}
+		svc.logger.Error("Failed to find SoD rule",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return sodRuleEntity{}, fmt.Errorf("error finding SoD rule with id %d: %w", id, err)
+	}
+	return rule, nil
+}
+
+// проверяет уникальность имени правила и существование его ролей
+func (svc *Service) checkSodRule(ctx context.Context, tx *sqlx.Tx, request SodRuleRequest) error {
+	isExist, err := svc.repo.SodRuleNameExistsTx(ctx, tx, request.Name, request.Id)
+	if err != nil {
+		return fmt.Errorf("error finding SoD rule by name: %s, %w", request.Name, err)
+	}
+	if isExist {
+		return common.AlreadyExistsError{Message: fmt.Sprintf("SoD rule with name %s already exists", request.Name)}
+	}
+
+	missing, err := svc.repo.FindMissingRoleIdsTx(ctx, tx, request.RoleIds)
+	if err != nil {
+		return fmt.Errorf("error finding roles of SoD rule: %w", err)
+	}
+	if len(missing) > 0 {
+		return common.RequestValidationError{Message: fmt.Sprintf("roles with ids %v do not exist", missing)}
+	}
+	return nil
+}
+
+// Метод для согласования исключения из правила: до истечения срока сотрудник может
+// обладать ролями правила одновременно. Согласующим записывается инициатор запроса
+func (svc *Service) CreateSodException(ctx context.Context, request SodExceptionRequest) (response SodExceptionResponse, err error) {
+	svc.logger.Info("Creating SoD exception",
+		zap.Int64("rule_id", request.RuleId),
+		zap.Int64("employee_id", request.EmployeeId))
+
+	if err := svc.validateRequest(request); err != nil {
+		return SodExceptionResponse{}, err
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		return SodExceptionResponse{}, common.RequestValidationError{Message: "expires_at must be in the future"}
+	}
+
+	isExist, err := svc.repo.EmployeeExists(ctx, request.EmployeeId)
+	if err != nil {
+		return SodExceptionResponse{}, fmt.Errorf("error finding employee with id %d: %w", request.EmployeeId, err)
+	}
+	if !isExist {
+		return SodExceptionResponse{}, common.NotFoundError{
+			Message: fmt.Sprintf("employee with id %d not found", request.EmployeeId),
+		}
+	}
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for SoD exception creation", zap.Error(err))
+		return SodExceptionResponse{}, fmt.Errorf("error create SoD exception: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, 0)
+	}()
+
+	// блокировка правила упорядочивает одновременные исключения из него
+	if _, err = svc.findSodRuleForUpdate(ctx, tx, request.RuleId); err != nil {
+		return SodExceptionResponse{}, err
+	}
+	hasActive, err := svc.repo.HasActiveSodExceptionTx(ctx, tx, request.RuleId, request.EmployeeId)
+	if err != nil {
+		return SodExceptionResponse{}, fmt.Errorf("error finding SoD exceptions: %w", err)
+	}
+	if hasActive {
+		return SodExceptionResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("employee %d already has an active exception from SoD rule %d",
+				request.EmployeeId, request.RuleId),
+		}
+	}
+
+	actor := common.ActorFromContext(ctx)
+	saved, err := svc.repo.SaveSodExceptionTx(ctx, tx, sodExceptionEntity{
+		RuleId:             request.RuleId,
+		EmployeeId:         request.EmployeeId,
+		Justification:      request.Justification,
+		ExpiresAt:          request.ExpiresAt,
+		ApprovedBySub:      nullable(actor.Subject),
+		ApprovedByUsername: nullable(actor.Username),
+	})
+	if err != nil {
+		svc.logger.Error("Failed to save SoD exception", zap.Error(err))
+		return SodExceptionResponse{}, fmt.Errorf("error creating SoD exception: %w", err)
+	}
+
+	response = saved.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionCreate,
+		EntityType: audit.EntitySodException,
+		EntityId:   saved.Id,
+		After:      response,
+	})
+	if err != nil {
+		return SodExceptionResponse{}, err
+	}
+
+	svc.logger.Info("SoD exception created successfully", zap.Int64("id", saved.Id))
+	return response, nil
+}
+
+// Метод для получения исключений из правил
+func (svc *Service) FindSodExceptions(ctx context.Context, filter SodExceptionFilter) ([]SodExceptionResponse, error) {
+	svc.logger.Debug("Finding SoD exceptions", zap.Any("filter", filter))
+
+	exceptions, err := svc.repo.FindSodExceptions(ctx, filter)
+	if err != nil {
+		svc.logger.Error("Failed to find SoD exceptions", zap.Error(err))
+		return nil, fmt.Errorf("error finding SoD exceptions: %w", err)
+	}
+
+	responses := make([]SodExceptionResponse, len(exceptions))
+	for i, exception := range exceptions {
+		responses[i] = exception.toResponse()
+	}
+	return responses, nil
+}
+
+// Метод для отзыва исключения; отозванное исключение остаётся в истории
+func (svc *Service) RevokeSodException(ctx context.Context, id int64) (response SodExceptionResponse, err error) {
+	svc.logger.Info("Revoking SoD exception", zap.Int64("id", id))
+
+	tx, err := svc.repo.BeginTransaction(ctx)
+	if err != nil {
+		svc.logger.Error("Failed to begin transaction for SoD exception revocation",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return SodExceptionResponse{}, fmt.Errorf("error revoke SoD exception: error creating transaction: %w", err)
+	}
+	defer func() {
+		err = svc.finishTransaction(tx, err, id)
+	}()
+
+	exception, err := svc.repo.FindSodExceptionForUpdateTx(ctx, tx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SodExceptionResponse{}, common.NotFoundError{Message: fmt.Sprintf("SoD exception with id %d not found", id)}
+		}
+		return SodExceptionResponse{}, fmt.Errorf("error finding SoD exception with id %d: %w", id, err)
+	}
+	if exception.RevokedAt != nil {
+		return SodExceptionResponse{}, common.ConflictError{Message: fmt.Sprintf("SoD exception %d is already revoked", id)}
+	}
+
+	revoked, err := svc.repo.RevokeSodExceptionTx(ctx, tx, id)
+	if err != nil {
+		svc.logger.Error("Failed to revoke SoD exception",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return SodExceptionResponse{}, fmt.Errorf("error revoking SoD exception with id %d: %w", id, err)
+	}
+
+	response = revoked.toResponse()
+	err = svc.auditor.Record(ctx, tx, audit.Event{
+		Action:     audit.ActionDelete,
+		EntityType: audit.EntitySodException,
+		EntityId:   id,
+		Before:     exception.toResponse(),
+		After:      response,
+	})
+	if err != nil {
+		return SodExceptionResponse{}, err
+	}
+
+	svc.logger.Info("SoD exception revoked successfully", zap.Int64("id", id))
+	return response, nil
+}
+
+// Метод для отчёта о действующих нарушениях правил. Нарушения возникают, если правило создано
+// после назначения ролей или иерархия ролей изменилась после назначения
+func (svc *Service) FindSodViolations(ctx context.Context, filter SodViolationFilter) ([]SodViolationResponse, error) {
+	svc.logger.Debug("Finding SoD violations", zap.Any("filter", filter))
+
+	violations, err := svc.repo.FindSodViolations(ctx, filter)
+	if err != nil {
+		svc.logger.Error("Failed to find SoD violations", zap.Error(err))
+		return nil, fmt.Errorf("error finding SoD violations: %w", err)
+	}
+
+	responses := make([]SodViolationResponse, len(violations))
+	for i, violation := range violations {
+		responses[i] = violation.toResponse()
+	}
+	return responses, nil
+}
+
+// проверяет правила разделения обязанностей у всех обладателей роли id и её потомков.
+// Вызывается после изменений, от которых зависят унаследованные роли: смены родителя роли и её активации
+func (svc *Service) checkSubtreeSodTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	subtree, err := svc.repo.FindSubtreeForUpdateTx(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("error finding subtree of role %d: %w", id, err)
+	}
+	ids := make([]int64, len(subtree))
+	for i, entity := range subtree {
+		ids[i] = entity.Id
+	}
+	holders, err := svc.repo.FindHoldersTx(ctx, tx, ids)
+	if err != nil {
+		return fmt.Errorf("error finding employees with roles: %w", err)
+	}
+
+	// обладатели упорядочены по сотруднику: роли каждого сотрудника проверяются вместе
+	for i := 0; i < len(holders); {
+		employeeId := holders[i].EmployeeId
+		var roleIds []int64
+		for ; i < len(holders) && holders[i].EmployeeId == employeeId; i++ {
+			roleIds = append(roleIds, holders[i].RoleId)
+		}
+		if err := svc.CheckSodTx(ctx, tx, employeeId, roleIds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckSodTx проверяет, что роли roleIds, уже назначенные сотруднику в транзакции tx,
+// не нарушают правил разделения обязанностей вместе с другими его ролями, включая унаследованные.
+// Нарушения, не связанные с roleIds, не мешают изменению. Возвращает ConflictError
+// со списком нарушений, если хотя бы одно не разрешено исключением
+func (svc *Service) CheckSodTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) error {
+	conflicts, err := svc.repo.FindSodConflictsTx(ctx, tx, employeeId, roleIds)
+	if err != nil {
+		svc.logger.Error("Failed to check SoD rules",
+			zap.Int64("employee_id", employeeId),
+			zap.Int64s("role_ids", roleIds),
+			zap.Error(err))
+		return fmt.Errorf("error checking SoD rules for employee %d: %w", employeeId, err)
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	violations := make([]SodViolationResponse, len(conflicts))
+	for i, conflict := range conflicts {
+		violations[i] = conflict.toResponse()
+	}
+	first := conflicts[0]
+	svc.logger.Warn("SoD rule violation rejected",
+		zap.Int64("employee_id", employeeId),
+		zap.Int64("rule_id", first.RuleId),
+		zap.Int("violations", len(conflicts)))
+	return common.ConflictError{
+		Message: fmt.Sprintf("role %q conflicts with role %q held by employee %d under SoD rule %q (id %d)",
+			first.RoleName, first.ConflictingRoleName, employeeId, first.RuleName, first.RuleId),
+		Data: violations,
+	}
+}
+
+func nullable(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
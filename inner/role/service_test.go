@@ -2,8 +2,12 @@ package role
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"idm/inner/audit"
 	"idm/inner/common"
+	"idm/inner/validator"
+	"iter"
 	"testing"
 	"time"
 
@@ -11,6 +15,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	mock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Объявляем структуру мок-репозитория
@@ -22,6 +27,16 @@ type MockValidator struct {
 	mock.Mock
 }
 
+// журнал аудита для тестов: запоминает записанные события
+type StubAuditor struct {
+	events []audit.Event
+}
+
+func (a *StubAuditor) Record(ctx context.Context, tx *sqlx.Tx, event audit.Event) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
 func (m *MockValidator) Validate(request any) error {
 	args := m.Called(request)
 	return args.Error(0)
@@ -42,18 +57,28 @@ func (m *MockRepo) FindAll(ctx context.Context) ([]Entity, error) {
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
+func (m *MockRepo) FindWithCursor(ctx context.Context, filter Filter, sort string, position *common.Cursor, limit int) ([]Entity, error) {
+	args := m.Called(filter, sort, position, limit)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) ExportRoles(ctx context.Context, sort string) (iter.Seq2[exportEntity, error], error) {
+	args := m.Called(sort)
+	return args.Get(0).(iter.Seq2[exportEntity, error]), args.Error(1)
+}
+
 func (m *MockRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
 	args := m.Called(ids)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) DeleteById(ctx context.Context, id int64) error {
-	args := m.Called(id)
-	return args.Error(0)
+func (m *MockRepo) FindByIdsForUpdateTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]Entity, error) {
+	args := m.Called(tx, ids)
+	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) DeleteByIds(ctx context.Context, ids []int64) error {
-	args := m.Called(ids)
+func (m *MockRepo) DeleteByIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) error {
+	args := m.Called(tx, ids)
 	return args.Error(0)
 }
 
@@ -76,6 +101,188 @@ func (m *MockRepo) SaveTx(ctx context.Context, tx *sqlx.Tx, role Entity) (int64,
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockRepo) FindByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) ExistsTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	args := m.Called(tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) LockHierarchyTx(ctx context.Context, tx *sqlx.Tx) error {
+	args := m.Called(tx)
+	return args.Error(0)
+}
+
+func (m *MockRepo) IsInParentChainTx(ctx context.Context, tx *sqlx.Tx, roleId, ancestorId int64) (bool, error) {
+	args := m.Called(tx, roleId, ancestorId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, role Entity) (Entity, error) {
+	args := m.Called(tx, role)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) SetStatusTx(ctx context.Context, tx *sqlx.Tx, id int64, status bool) (Entity, error) {
+	args := m.Called(tx, id, status)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindAncestors(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	args := m.Called(id)
+	return args.Get(0).([]hierarchyEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindDescendants(ctx context.Context, id int64) ([]hierarchyEntity, error) {
+	args := m.Called(id)
+	return args.Get(0).([]hierarchyEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindTree(ctx context.Context) ([]treeEntity, error) {
+	args := m.Called()
+	return args.Get(0).([]treeEntity), args.Error(1)
+}
+
+func (m *MockRepo) EmployeeExists(ctx context.Context, employeeId int64) (bool, error) {
+	args := m.Called(employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error) {
+	args := m.Called(tx, employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindEffectiveByEmployeeId(ctx context.Context, employeeId int64) ([]effectiveEntity, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).([]effectiveEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindDeletedByIdForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) RestoreTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) PurgeDeletedTx(ctx context.Context, tx *sqlx.Tx, deletedBefore time.Time) ([]Entity, error) {
+	args := m.Called(tx, deletedBefore)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindHoldersTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]holderEntity, error) {
+	args := m.Called(tx, roleIds)
+	return args.Get(0).([]holderEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindChildrenForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindSubtreeForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) ReparentChildrenTx(ctx context.Context, tx *sqlx.Tx, id, newParentId int64) error {
+	return m.Called(tx, id, newParentId).Error(0)
+}
+
+func (m *MockRepo) ReassignHoldersTx(ctx context.Context, tx *sqlx.Tx, fromRoleId, toRoleId int64) error {
+	return m.Called(tx, fromRoleId, toRoleId).Error(0)
+}
+
+func (m *MockRepo) RevokeAssignmentsTx(ctx context.Context, tx *sqlx.Tx, roleIds []int64) ([]int64, error) {
+	args := m.Called(tx, roleIds)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) SyncEmployeesRoleIdTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	return m.Called(tx, employeeIds).Error(0)
+}
+
+func (m *MockRepo) FindSodRules(ctx context.Context) ([]sodRuleEntity, error) {
+	args := m.Called()
+	return args.Get(0).([]sodRuleEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindSodRuleById(ctx context.Context, id int64) (sodRuleEntity, error) {
+	args := m.Called(id)
+	return args.Get(0).(sodRuleEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindSodRuleForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (sodRuleEntity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(sodRuleEntity), args.Error(1)
+}
+
+func (m *MockRepo) SodRuleNameExistsTx(ctx context.Context, tx *sqlx.Tx, name string, excludeId int64) (bool, error) {
+	args := m.Called(tx, name, excludeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindMissingRoleIdsTx(ctx context.Context, tx *sqlx.Tx, ids []int64) ([]int64, error) {
+	args := m.Called(tx, ids)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockRepo) SaveSodRuleTx(ctx context.Context, tx *sqlx.Tx, rule sodRuleEntity) (int64, error) {
+	args := m.Called(tx, rule)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) UpdateSodRuleTx(ctx context.Context, tx *sqlx.Tx, rule sodRuleEntity) error {
+	args := m.Called(tx, rule)
+	return args.Error(0)
+}
+
+func (m *MockRepo) DeleteSodRuleTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	args := m.Called(tx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepo) SaveSodExceptionTx(ctx context.Context, tx *sqlx.Tx, exception sodExceptionEntity) (sodExceptionEntity, error) {
+	args := m.Called(tx, exception)
+	return args.Get(0).(sodExceptionEntity), args.Error(1)
+}
+
+func (m *MockRepo) HasActiveSodExceptionTx(ctx context.Context, tx *sqlx.Tx, ruleId, employeeId int64) (bool, error) {
+	args := m.Called(tx, ruleId, employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindSodExceptions(ctx context.Context, filter SodExceptionFilter) ([]sodExceptionEntity, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]sodExceptionEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindSodExceptionForUpdateTx(ctx context.Context, tx *sqlx.Tx, id int64) (sodExceptionEntity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(sodExceptionEntity), args.Error(1)
+}
+
+func (m *MockRepo) RevokeSodExceptionTx(ctx context.Context, tx *sqlx.Tx, id int64) (sodExceptionEntity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(sodExceptionEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindSodConflictsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleIds []int64) ([]sodViolationEntity, error) {
+	args := m.Called(tx, employeeId, roleIds)
+	return args.Get(0).([]sodViolationEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindSodViolations(ctx context.Context, filter SodViolationFilter) ([]sodViolationEntity, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]sodViolationEntity), args.Error(1)
+}
+
 // логгер для тестов
 func createTestLogger() *common.Logger {
 	cfg := common.Config{
@@ -106,7 +313,7 @@ func TestService_FindById(t *testing.T) {
 	}
 	mockRepo.On("FindById", int64(2)).Return(entity, nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	result, err := svc.FindById(context.Background(), 2)
 
@@ -121,7 +328,7 @@ func TestService_FindById_Error(t *testing.T) {
 	logger := createTestLogger()
 	mockRepo.On("FindById", int64(2)).Return(Entity{}, errors.New("db error"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	result, err := svc.FindById(context.Background(), 2)
 
@@ -146,7 +353,7 @@ func TestService_Add(t *testing.T) {
 	}
 	mockRepo.On("Add", entity).Return(nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	validator.On("Validate", entity).Return(nil)
 
@@ -163,7 +370,7 @@ func TestService_Add_Error(t *testing.T) {
 	logger := createTestLogger()
 	mockRepo.On("Add", mock.Anything).Return(errors.New("db error"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	validator.On("Validate", mock.Anything).Return(nil)
 
@@ -174,59 +381,124 @@ func TestService_Add_Error(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestService_FindAll(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	rootId := int64(0)
-	adminId := rootId + 1
-	userId := adminId + 1
+func TestService_FindWithCursor(t *testing.T) {
 	entities := []Entity{
-		{
-			Id:        2,
-			Name:      "Admin",
-			Desc:      "Administrator role",
-			Status:    true,
-			ParentId:  &adminId,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
-		{
-			Id:        3,
-			Name:      "User",
-			Desc:      "Regular user role",
-			Status:    true,
-			ParentId:  &userId,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		},
+		{Id: 2, Name: "Admin", Desc: "Administrator role", Status: true},
+		{Id: 3, Name: "User", Desc: "Regular user role", Status: true},
 	}
-	mockRepo.On("FindAll", mock.Anything).Return(entities, nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	t.Run("Middle page has both cursors", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		validator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, validator, createTestLogger())
+		position := &common.Cursor{Sort: "name", Value: "Accountant", Id: 1}
+		filter := Filter{RiskLevels: []string{RiskHigh}, Labels: map[string]string{"app": "billing"}}
+		request := CursorRequest{Limit: 1, Sort: "name", Filter: filter, Cursor: position}
+
+		validator.On("Validate", request).Return(nil)
+		mockRepo.On("FindWithCursor", filter, "name", position, 2).Return(entities, nil)
 
-	result, err := svc.FindAll(context.Background())
+		page, err := svc.FindWithCursor(context.Background(), request)
 
-	assert.NoError(t, err)
-	assert.Len(t, result, 2)
-	assert.Equal(t, entities[0].toResponse(), result[0])
-	assert.Equal(t, entities[1].toResponse(), result[1])
-	mockRepo.AssertExpectations(t)
+		assert.NoError(t, err)
+		assert.Equal(t, []Response{entities[0].toResponse()}, page.Data)
+		assert.Equal(t, &common.Cursor{Sort: "name", Value: "Admin", Id: 2}, page.Next)
+		assert.Equal(t, &common.Cursor{Sort: "name", Value: "Admin", Id: 2, Backward: true}, page.Prev)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Repository error", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		validator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, validator, createTestLogger())
+		request := CursorRequest{Limit: 10}
+
+		validator.On("Validate", request).Return(nil)
+		mockRepo.On("FindWithCursor", Filter{}, "", (*common.Cursor)(nil), 11).Return([]Entity{}, errors.New("db error"))
+
+		page, err := svc.FindWithCursor(context.Background(), request)
+
+		assert.Error(t, err)
+		assert.Empty(t, page.Data)
+	})
+
+	t.Run("Unsupported sort", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		validator := new(MockValidator)
+		svc := NewService(mockRepo, &StubAuditor{}, validator, createTestLogger())
+		request := CursorRequest{Limit: 10, Sort: "description"}
+
+		validator.On("Validate", request).Return(nil)
+
+		_, err := svc.FindWithCursor(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "FindWithCursor", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
-func TestService_FindAll_Error(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("FindAll", mock.Anything).Return([]Entity{}, errors.New("db error"))
+func TestService_Export(t *testing.T) {
+	t.Run("Maps entities to records", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+		entities := []exportEntity{
+			{Entity: Entity{Id: 1, Name: "Root"}},
+			{Entity: Entity{Id: 2, Name: "Admin"}, Parent: "Root", Permissions: []string{"roles.read"}, Holders: 3},
+		}
+		mockRepo.On("ExportRoles", "-name").Return(iter.Seq2[exportEntity, error](func(yield func(exportEntity, error) bool) {
+			for _, entity := range entities {
+				if !yield(entity, nil) {
+					return
+				}
+			}
+		}), nil)
 
-	svc := NewService(mockRepo, validator, logger)
+		records, err := svc.Export(context.Background(), "-name")
 
-	result, err := svc.FindAll(context.Background())
+		require.NoError(t, err)
+		var exported []ExportRecord
+		for record, err := range records {
+			require.NoError(t, err)
+			exported = append(exported, record)
+		}
+		assert.Equal(t, []ExportRecord{
+			{Id: 1, Name: "Root", Permissions: []string{}},
+			{Id: 2, Name: "Admin", Parent: "Root", Permissions: []string{"roles.read"}, Holders: 3},
+		}, exported)
+	})
 
-	assert.Error(t, err)
-	assert.Equal(t, 0, len(result))
-	mockRepo.AssertExpectations(t)
+	t.Run("Read error ends export", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+		mockRepo.On("ExportRoles", "").Return(iter.Seq2[exportEntity, error](func(yield func(exportEntity, error) bool) {
+			if yield(exportEntity{Entity: Entity{Id: 1}}, nil) {
+				yield(exportEntity{}, errors.New("connection reset"))
+			}
+		}), nil)
+
+		records, err := svc.Export(context.Background(), "")
+
+		require.NoError(t, err)
+		var errs []error
+		for _, err := range records {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 2)
+		assert.NoError(t, errs[0])
+		assert.ErrorContains(t, errs[1], "connection reset")
+	})
+
+	t.Run("Unsupported sort", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		_, err := svc.Export(context.Background(), "description")
+
+		var validationErr common.RequestValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		mockRepo.AssertNotCalled(t, "ExportRoles", mock.Anything)
+	})
 }
 
 func TestService_FindByIds(t *testing.T) {
@@ -259,7 +531,7 @@ func TestService_FindByIds(t *testing.T) {
 	}
 	mockRepo.On("FindByIds", ids).Return(entities, nil)
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	result, err := svc.FindByIds(context.Background(), ids)
 
@@ -276,7 +548,7 @@ func TestService_FindByIds_Error(t *testing.T) {
 	logger := createTestLogger()
 	mockRepo.On("FindByIds", []int64{1, 2}).Return([]Entity{}, errors.New("db error"))
 
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	result, err := svc.FindByIds(context.Background(), []int64{1, 2})
 
@@ -285,83 +557,296 @@ func TestService_FindByIds_Error(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+// валидатор для тестов, пропускающий любой запрос
+func acceptingValidator() *MockValidator {
+	mockValidator := new(MockValidator)
+	mockValidator.On("Validate", mock.Anything).Return(nil)
+	return mockValidator
+}
+
 func TestService_DeleteById(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("DeleteById", int64(2)).Return(nil)
+	t.Run("Deletes role without dependents and records audit event", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2, Name: "Admin"}, nil)
+		mockRepo.On("FindHoldersTx", tx, []int64{2}).Return([]holderEntity(nil), nil)
+		mockRepo.On("FindChildrenForUpdateTx", tx, int64(2)).Return([]Entity(nil), nil)
+		mockRepo.On("DeleteByIdsTx", tx, []int64{2}).Return(nil)
 
-	svc := NewService(mockRepo, validator, logger)
+		svc := NewService(mockRepo, auditor, acceptingValidator(), createTestLogger())
 
-	err := svc.DeleteById(context.Background(), 2)
+		response, err := svc.DeleteById(context.Background(), DeleteRequest{Id: 2})
 
-	assert.NoError(t, err)
-	mockRepo.AssertExpectations(t)
-}
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{2}, response.DeletedRoleIds)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionDelete, auditor.events[0].Action)
+			assert.Equal(t, audit.EntityRole, auditor.events[0].EntityType)
+			assert.Equal(t, "Admin", auditor.events[0].Before.(Response).Name)
+		}
+	})
 
-func TestService_DeleteById_Error(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("DeleteById", int64(1)).Return(errors.New("db error"))
+	t.Run("Rejects role with dependents", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2, Name: "Admin"}, nil)
+		mockRepo.On("FindHoldersTx", tx, []int64{2}).
+			Return([]holderEntity{{EmployeeId: 7, RoleId: 2}}, nil)
+		mockRepo.On("FindChildrenForUpdateTx", tx, int64(2)).Return([]Entity{{Id: 3}}, nil)
 
-	svc := NewService(mockRepo, validator, logger)
+		svc := NewService(mockRepo, auditor, acceptingValidator(), createTestLogger())
 
-	err := svc.DeleteById(context.Background(), 1)
+		_, err := svc.DeleteById(context.Background(), DeleteRequest{Id: 2, Policy: DeletePolicyReject})
 
-	assert.Error(t, err)
-	mockRepo.AssertExpectations(t)
-}
+		var conflictErr common.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		assert.Equal(t, Dependents{EmployeeIds: []int64{7}, ChildRoleIds: []int64{3}}, conflictErr.Data)
+		mockRepo.AssertNotCalled(t, "DeleteByIdsTx", mock.Anything, mock.Anything)
+		assert.Empty(t, auditor.events)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
 
-func TestService_DeleteByIds(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("DeleteByIds", []int64{2, 3}).Return(nil)
+	t.Run("Reassigns dependents to replacement role", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2, Name: "Admin"}, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(5)).Return(Entity{Id: 5, Name: "Operator", Status: true}, nil)
+		mockRepo.On("IsInParentChainTx", tx, int64(5), int64(2)).Return(false, nil)
+		mockRepo.On("FindHoldersTx", tx, []int64{2}).
+			Return([]holderEntity{{EmployeeId: 7, RoleId: 2}}, nil)
+		mockRepo.On("FindChildrenForUpdateTx", tx, int64(2)).Return([]Entity{{Id: 3, Name: "Guest"}}, nil)
+		mockRepo.On("ReassignHoldersTx", tx, int64(2), int64(5)).Return(nil)
+		mockRepo.On("RevokeAssignmentsTx", tx, []int64{2}).Return([]int64{7}, nil)
+		mockRepo.On("SyncEmployeesRoleIdTx", tx, []int64{7}).Return(nil)
+		mockRepo.On("ReparentChildrenTx", tx, int64(2), int64(5)).Return(nil)
+		mockRepo.On("DeleteByIdsTx", tx, []int64{2}).Return(nil)
+		mockRepo.On("FindSodConflictsTx", tx, int64(7), []int64{5}).Return([]sodViolationEntity(nil), nil)
+
+		svc := NewService(mockRepo, auditor, acceptingValidator(), createTestLogger())
+
+		response, err := svc.DeleteById(context.Background(),
+			DeleteRequest{Id: 2, Policy: DeletePolicyReassign, ReplacementRoleId: 5})
 
-	svc := NewService(mockRepo, validator, logger)
+		assert.NoError(t, err)
+		assert.Equal(t, DeleteResponse{
+			DeletedRoleIds:      []int64{2},
+			ReparentedRoleIds:   []int64{3},
+			AffectedEmployeeIds: []int64{7},
+		}, response)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		// отзыв, назначение замены, переподчинение потомка и удаление
+		assert.Len(t, auditor.events, 4)
+	})
 
-	err := svc.DeleteByIds(context.Background(), []int64{2, 3})
+	t.Run("Rejects replacement from own subtree", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2}, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(Entity{Id: 3, Status: true}, nil)
+		mockRepo.On("IsInParentChainTx", tx, int64(3), int64(2)).Return(true, nil)
 
-	assert.NoError(t, err)
-	mockRepo.AssertExpectations(t)
-}
+		svc := NewService(mockRepo, &StubAuditor{}, acceptingValidator(), createTestLogger())
 
-func TestService_DeleteByIds_Error(t *testing.T) {
-	mockRepo := new(MockRepo)
-	validator := new(MockValidator)
-	logger := createTestLogger()
-	mockRepo.On("DeleteByIds", []int64{1, 2}).Return(errors.New("db error"))
+		_, err := svc.DeleteById(context.Background(),
+			DeleteRequest{Id: 2, Policy: DeletePolicyReassign, ReplacementRoleId: 3})
 
-	svc := NewService(mockRepo, validator, logger)
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		mockRepo.AssertNotCalled(t, "ReassignHoldersTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
 
-	err := svc.DeleteByIds(context.Background(), []int64{1, 2})
+	t.Run("Rejects inactive replacement", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2}, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(5)).Return(Entity{Id: 5, Name: "Operator"}, nil)
 
-	assert.Error(t, err)
-	mockRepo.AssertExpectations(t)
-}
+		svc := NewService(mockRepo, &StubAuditor{}, acceptingValidator(), createTestLogger())
 
-// Тесты для метода CreateRole
-func TestService_CreateRole(t *testing.T) {
-	t.Run("Successful role creation", func(t *testing.T) {
-		mockRepo := new(MockRepo)
-		mockValidator := new(MockValidator)
-		logger := createTestLogger()
+		_, err := svc.DeleteById(context.Background(),
+			DeleteRequest{Id: 2, Policy: DeletePolicyReassign, ReplacementRoleId: 5})
 
-		db, sqlMock, err := sqlmock.New()
-		assert.NoError(t, err)
-		defer func() {
-			_ = db.Close()
-		}()
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		assert.Contains(t, err.Error(), "inactive")
+		mockRepo.AssertNotCalled(t, "ReassignHoldersTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
 
-		sqlxDB := sqlx.NewDb(db, "postgres")
-		sqlMock.ExpectBegin()
-		sqlMock.ExpectRollback()
+	t.Run("Cascades to descendants", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2}, nil)
+		mockRepo.On("FindSubtreeForUpdateTx", tx, int64(2)).Return([]Entity{{Id: 2}, {Id: 3}}, nil)
+		mockRepo.On("FindHoldersTx", tx, []int64{2, 3}).
+			Return([]holderEntity{{EmployeeId: 7, RoleId: 3}}, nil)
+		mockRepo.On("RevokeAssignmentsTx", tx, []int64{2, 3}).Return([]int64{7}, nil)
+		mockRepo.On("SyncEmployeesRoleIdTx", tx, []int64{7}).Return(nil)
+		mockRepo.On("DeleteByIdsTx", tx, []int64{2, 3}).Return(nil)
 
-		tx, err := sqlxDB.Beginx()
-		assert.NoError(t, err)
+		svc := NewService(mockRepo, auditor, acceptingValidator(), createTestLogger())
 
-		service := NewService(mockRepo, mockValidator, logger)
+		response, err := svc.DeleteById(context.Background(), DeleteRequest{Id: 2, Policy: DeletePolicyCascade})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{2, 3}, response.DeletedRoleIds)
+		assert.Equal(t, []int64{7}, response.AffectedEmployeeIds)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		assert.Len(t, auditor.events, 3)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(404)).Return(Entity{}, sql.ErrNoRows)
+
+		svc := NewService(mockRepo, &StubAuditor{}, acceptingValidator(), createTestLogger())
+
+		_, err := svc.DeleteById(context.Background(), DeleteRequest{Id: 404})
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Reassign without replacement is invalid", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		svc := NewService(mockRepo, &StubAuditor{}, validator.New(), createTestLogger())
+
+		_, err := svc.DeleteById(context.Background(), DeleteRequest{Id: 2, Policy: DeletePolicyReassign})
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "BeginTransaction")
+	})
+}
+
+func TestService_DeleteById_Error(t *testing.T) {
+	mockRepo := new(MockRepo)
+	auditor := &StubAuditor{}
+	tx, sqlMock := newMockTx(t, false)
+	mockRepo.On("BeginTransaction").Return(tx, nil)
+	mockRepo.On("LockHierarchyTx", tx).Return(nil)
+	mockRepo.On("FindByIdForUpdateTx", tx, int64(1)).Return(Entity{Id: 1}, nil)
+	mockRepo.On("FindHoldersTx", tx, []int64{1}).Return([]holderEntity(nil), nil)
+	mockRepo.On("FindChildrenForUpdateTx", tx, int64(1)).Return([]Entity(nil), nil)
+	mockRepo.On("DeleteByIdsTx", tx, []int64{1}).Return(errors.New("db error"))
+
+	svc := NewService(mockRepo, auditor, acceptingValidator(), createTestLogger())
+
+	_, err := svc.DeleteById(context.Background(), DeleteRequest{Id: 1})
+
+	assert.Error(t, err)
+	assert.Empty(t, auditor.events)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestService_DeleteByIds(t *testing.T) {
+	mockRepo := new(MockRepo)
+	auditor := &StubAuditor{}
+	tx, sqlMock := newMockTx(t, true)
+	mockRepo.On("BeginTransaction").Return(tx, nil)
+	mockRepo.On("LockHierarchyTx", tx).Return(nil)
+	mockRepo.On("FindByIdsForUpdateTx", tx, []int64{2, 3}).Return([]Entity{{Id: 2}, {Id: 3}}, nil)
+	mockRepo.On("FindHoldersTx", tx, mock.Anything).Return([]holderEntity(nil), nil)
+	// дочерняя роль удаляется вместе с родителем и не мешает удалению
+	mockRepo.On("FindChildrenForUpdateTx", tx, int64(2)).Return([]Entity{{Id: 3}}, nil)
+	mockRepo.On("FindChildrenForUpdateTx", tx, int64(3)).Return([]Entity(nil), nil)
+	mockRepo.On("DeleteByIdsTx", tx, []int64{2, 3}).Return(nil)
+
+	svc := NewService(mockRepo, auditor, new(MockValidator), createTestLogger())
+
+	err := svc.DeleteByIds(context.Background(), []int64{2, 3})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+	assert.Len(t, auditor.events, 2)
+}
+
+func TestService_DeleteByIds_RejectsDependents(t *testing.T) {
+	mockRepo := new(MockRepo)
+	tx, sqlMock := newMockTx(t, false)
+	mockRepo.On("BeginTransaction").Return(tx, nil)
+	mockRepo.On("LockHierarchyTx", tx).Return(nil)
+	mockRepo.On("FindByIdsForUpdateTx", tx, []int64{2}).Return([]Entity{{Id: 2}}, nil)
+	mockRepo.On("FindHoldersTx", tx, []int64{2}).Return([]holderEntity{{EmployeeId: 7, RoleId: 2}}, nil)
+	mockRepo.On("FindChildrenForUpdateTx", tx, int64(2)).Return([]Entity(nil), nil)
+
+	svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+	err := svc.DeleteByIds(context.Background(), []int64{2})
+
+	var conflictErr common.ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	mockRepo.AssertNotCalled(t, "DeleteByIdsTx", mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestService_DeleteByIds_Error(t *testing.T) {
+	mockRepo := new(MockRepo)
+	tx, sqlMock := newMockTx(t, false)
+	mockRepo.On("BeginTransaction").Return(tx, nil)
+	mockRepo.On("LockHierarchyTx", tx).Return(nil)
+	mockRepo.On("FindByIdsForUpdateTx", tx, []int64{1, 2}).Return([]Entity{{Id: 1}, {Id: 2}}, nil)
+	mockRepo.On("FindHoldersTx", tx, mock.Anything).Return([]holderEntity(nil), nil)
+	mockRepo.On("FindChildrenForUpdateTx", tx, mock.Anything).Return([]Entity(nil), nil)
+	mockRepo.On("DeleteByIdsTx", tx, []int64{1, 2}).Return(errors.New("db error"))
+
+	svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+	err := svc.DeleteByIds(context.Background(), []int64{1, 2})
+
+	assert.Error(t, err)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+// Тесты для метода CreateRole
+func TestService_CreateRole(t *testing.T) {
+	t.Run("Successful role creation", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		logger := createTestLogger()
+
+		db, sqlMock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer func() {
+			_ = db.Close()
+		}()
+
+		sqlxDB := sqlx.NewDb(db, "postgres")
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectRollback()
+
+		tx, err := sqlxDB.Beginx()
+		assert.NoError(t, err)
+
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "TestRole",
@@ -390,7 +875,7 @@ func TestService_CreateRole(t *testing.T) {
 		mockRepo := new(MockRepo)
 		mockValidator := new(MockValidator)
 		logger := createTestLogger()
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "", // невалидное имя
@@ -431,7 +916,7 @@ func TestService_CreateRole(t *testing.T) {
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(t, err)
 
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "TestRole",
@@ -473,7 +958,7 @@ func TestService_CreateRole(t *testing.T) {
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(t, err)
 
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "ExistingRole",
@@ -518,7 +1003,7 @@ func TestService_CreateRole(t *testing.T) {
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(t, err)
 
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "TestRole",
@@ -563,7 +1048,7 @@ func TestService_CreateRole(t *testing.T) {
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(t, err)
 
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "TestRole",
@@ -607,7 +1092,7 @@ func TestService_CreateRole(t *testing.T) {
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(t, err)
 
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		parentId := int64(456)
 		request := CreateRequest{
@@ -622,6 +1107,8 @@ func TestService_CreateRole(t *testing.T) {
 		mockValidator.On("Validate", request).Return(nil)
 		mockRepo.On("BeginTransaction").Return(tx, nil)
 		mockRepo.On("FindByNameTx", tx, "ChildRole").Return(false, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("ExistsTx", tx, parentId).Return(true, nil)
 		mockRepo.On("SaveTx", tx, request.ToEntity()).Return(expectedRoleId, nil)
 
 		roleId, err := service.CreateRole(context.Background(), request)
@@ -653,7 +1140,7 @@ func BenchmarkService_CreateRole(b *testing.B) {
 
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(b, err)
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "BenchmarkRole",
@@ -685,7 +1172,7 @@ func BenchmarkService_CreateRole(b *testing.B) {
 		mockValidator := new(MockValidator)
 		logger := createTestLogger()
 
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "", // невалидное имя
@@ -723,7 +1210,7 @@ func BenchmarkService_CreateRole(b *testing.B) {
 
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(b, err)
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "ExistingBenchmarkRole",
@@ -762,7 +1249,7 @@ func BenchmarkService_CreateRole(b *testing.B) {
 
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(b, err)
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		parentId := int64(456)
 		request := CreateRequest{
@@ -777,6 +1264,8 @@ func BenchmarkService_CreateRole(b *testing.B) {
 		mockValidator.On("Validate", request).Return(nil)
 		mockRepo.On("BeginTransaction").Return(tx, nil)
 		mockRepo.On("FindByNameTx", tx, "ChildBenchmarkRole").Return(false, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("ExistsTx", tx, parentId).Return(true, nil)
 		mockRepo.On("SaveTx", tx, request.ToEntity()).Return(expectedRoleId, nil)
 
 		b.ResetTimer()
@@ -808,7 +1297,7 @@ func BenchmarkService_CreateRole_Memory(b *testing.B) {
 
 	tx, err := sqlxDB.Beginx()
 	assert.NoError(b, err)
-	service := NewService(mockRepo, mockValidator, logger)
+	service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 	request := CreateRequest{
 		Name:        "MemoryBenchmarkRole",
@@ -854,7 +1343,7 @@ func BenchmarkService_CreateRole_Parallel(b *testing.B) {
 
 		tx, err := sqlxDB.Beginx()
 		assert.NoError(b, err)
-		service := NewService(mockRepo, mockValidator, logger)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 		request := CreateRequest{
 			Name:        "ParallelBenchmarkRole",
@@ -910,7 +1399,7 @@ func BenchmarkService_CreateRole_DataSizes(b *testing.B) {
 
 			tx, err := sqlxDB.Beginx()
 			assert.NoError(b, err)
-			service := NewService(mockRepo, mockValidator, logger)
+			service := NewService(mockRepo, &StubAuditor{}, mockValidator, logger)
 
 			// Генерация строки нужной длины
 			name := generateString(size.nameLen)
@@ -957,7 +1446,7 @@ func TestService_validateCreateRequest_Success(t *testing.T) {
 	mockRepo := new(MockRepo)
 	validator := new(MockValidator)
 	logger := createTestLogger()
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	parentId := int64(1)
 	request := CreateRequest{
@@ -977,7 +1466,7 @@ func TestService_validateCreateRequest_CustomError(t *testing.T) {
 	mockRepo := new(MockRepo)
 	validator := new(MockValidator)
 	logger := createTestLogger()
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	request := CreateRequest{Name: ""} // невалидные данные
 	customErr := errors.New("custom validation error")
@@ -999,7 +1488,7 @@ func TestService_validateCreateRequest_ValidationErrorsType(t *testing.T) {
 	mockRepo := new(MockRepo)
 	validator := new(MockValidator)
 	logger := createTestLogger()
-	svc := NewService(mockRepo, validator, logger)
+	svc := NewService(mockRepo, &StubAuditor{}, validator, logger)
 
 	var validationErrs = mockValidationErrors{}
 
@@ -1011,3 +1500,710 @@ func TestService_validateCreateRequest_ValidationErrorsType(t *testing.T) {
 	assert.Contains(t, err.Error(), "Data validation error")
 	validator.AssertExpectations(t)
 }
+
+// создаёт транзакцию на sqlmock, ожидающую завершения commit или rollback
+func newMockTx(t *testing.T, commit bool) (*sqlx.Tx, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	sqlMock.ExpectBegin()
+	if commit {
+		sqlMock.ExpectCommit()
+	} else {
+		sqlMock.ExpectRollback()
+	}
+
+	tx, err := sqlx.NewDb(db, "postgres").Beginx()
+	assert.NoError(t, err)
+	return tx, sqlMock
+}
+
+func TestService_UpdateRole(t *testing.T) {
+	t.Run("Successful parent reassignment", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+
+		parentId := int64(1)
+		current := Entity{Id: 3, Name: "Developer", Desc: "Developers", Status: true, RiskLevel: RiskLow, Labels: "{}"}
+		request := UpdateRequest{Id: 3, Name: "Developer", Description: "Developers", Status: true, ParentId: &parentId}
+		expected := current
+		expected.ParentId = &parentId
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(current, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("ExistsTx", tx, int64(1)).Return(true, nil)
+		mockRepo.On("IsInParentChainTx", tx, int64(1), int64(3)).Return(false, nil)
+		mockRepo.On("UpdateTx", tx, expected).Return(expected, nil)
+		mockRepo.On("FindSubtreeForUpdateTx", tx, int64(3)).Return([]Entity{{Id: 3}}, nil)
+		mockRepo.On("FindHoldersTx", tx, []int64{3}).Return([]holderEntity{{EmployeeId: 7, RoleId: 3}}, nil)
+		mockRepo.On("FindSodConflictsTx", tx, int64(7), []int64{3}).Return([]sodViolationEntity(nil), nil)
+
+		result, err := service.UpdateRole(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &parentId, result.ParentId)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("New parent violates SoD rule of a holder", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		auditor := &StubAuditor{}
+		service := NewService(mockRepo, auditor, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		// сотрудник 7 обладает потомком роли 3 и через нового родителя получает конфликтующую роль
+		parentId := int64(1)
+		current := Entity{Id: 3, Name: "Developer", Status: true, RiskLevel: RiskLow, Labels: "{}"}
+		request := UpdateRequest{Id: 3, Name: "Developer", Status: true, ParentId: &parentId}
+		expected := current
+		expected.ParentId = &parentId
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(current, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("ExistsTx", tx, int64(1)).Return(true, nil)
+		mockRepo.On("IsInParentChainTx", tx, int64(1), int64(3)).Return(false, nil)
+		mockRepo.On("UpdateTx", tx, expected).Return(expected, nil)
+		mockRepo.On("FindSubtreeForUpdateTx", tx, int64(3)).Return([]Entity{{Id: 3}, {Id: 4}}, nil)
+		mockRepo.On("FindHoldersTx", tx, []int64{3, 4}).Return([]holderEntity{{EmployeeId: 7, RoleId: 4}}, nil)
+		mockRepo.On("FindSodConflictsTx", tx, int64(7), []int64{4}).Return([]sodViolationEntity{{
+			EmployeeId: 7, RuleId: 1, RuleName: "Payments", RoleId: 1, RoleName: "Payment approver", SourceRoleId: 4,
+			ConflictingRoleId: 2, ConflictingRoleName: "Payment creator", ConflictingSourceRoleId: 2,
+		}}, nil)
+
+		_, err := service.UpdateRole(context.Background(), request)
+
+		assert.True(t, errors.As(err, &common.ConflictError{}))
+		assert.Empty(t, auditor.events)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Cycle is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		// роль 5 - потомок роли 3, назначение её родителем роли 3 образует цикл
+		parentId := int64(5)
+		request := UpdateRequest{Id: 3, Name: "Developer", Description: "Developers", Status: true, ParentId: &parentId}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(Entity{Id: 3, Name: "Developer"}, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("ExistsTx", tx, int64(5)).Return(true, nil)
+		mockRepo.On("IsInParentChainTx", tx, int64(5), int64(3)).Return(true, nil)
+
+		_, err := service.UpdateRole(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		assert.Contains(t, err.Error(), "cycle")
+		mockRepo.AssertNotCalled(t, "UpdateTx", mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Self parent is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		parentId := int64(3)
+		request := UpdateRequest{Id: 3, Name: "Developer", Description: "Developers", Status: true, ParentId: &parentId}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(Entity{Id: 3, Name: "Developer"}, nil)
+
+		_, err := service.UpdateRole(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown parent is a validation error", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		parentId := int64(404)
+		request := UpdateRequest{Id: 3, Name: "Developer", Description: "Developers", Status: true, ParentId: &parentId}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(Entity{Id: 3, Name: "Developer"}, nil)
+		mockRepo.On("LockHierarchyTx", tx).Return(nil)
+		mockRepo.On("ExistsTx", tx, int64(404)).Return(false, nil)
+
+		_, err := service.UpdateRole(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Role not found", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		request := UpdateRequest{Id: 9, Name: "Developer", Description: "Developers"}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(9)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := service.UpdateRole(context.Background(), request)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_PatchRole_DetachFromParent(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+	tx, sqlMock := newMockTx(t, true)
+
+	oldParent := int64(1)
+	detach := int64(0)
+	current := Entity{Id: 3, Name: "Developer", Desc: "Developers", Status: true, ParentId: &oldParent}
+	request := PatchRequest{Id: 3, ParentId: &detach}
+	expected := current
+	expected.ParentId = nil
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction").Return(tx, nil)
+	mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(current, nil)
+	mockRepo.On("UpdateTx", tx, expected).Return(expected, nil)
+
+	result, err := service.PatchRole(context.Background(), request)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result.ParentId)
+	assert.Equal(t, "Developer", result.Name)
+	mockRepo.AssertNotCalled(t, "IsInParentChainTx", mock.Anything, mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestService_PatchRole_Metadata(t *testing.T) {
+	t.Run("Owner, risk and labels are changed", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, true)
+
+		ownerId, risk := int64(7), RiskHigh
+		current := Entity{Id: 3, Name: "Developer", Desc: "Developers", Status: true, RiskLevel: RiskLow,
+			Labels: `{"team": "core"}`}
+		request := PatchRequest{Id: 3, OwnerId: &ownerId, RiskLevel: &risk, Labels: map[string]string{"app": "billing"}}
+		expected := current
+		expected.OwnerId, expected.RiskLevel, expected.Labels = &ownerId, RiskHigh, `{"app":"billing"}`
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(current, nil)
+		mockRepo.On("EmployeeExistsTx", tx, int64(7)).Return(true, nil)
+		mockRepo.On("UpdateTx", tx, expected).Return(expected, nil)
+
+		result, err := service.PatchRole(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &ownerId, result.OwnerId)
+		assert.Equal(t, RiskHigh, result.RiskLevel)
+		assert.Equal(t, map[string]string{"app": "billing"}, result.Labels)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown owner", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockValidator := new(MockValidator)
+		service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+		tx, sqlMock := newMockTx(t, false)
+
+		ownerId := int64(404)
+		request := PatchRequest{Id: 3, OwnerId: &ownerId}
+
+		mockValidator.On("Validate", request).Return(nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(3)).Return(Entity{Id: 3, Name: "Developer"}, nil)
+		mockRepo.On("EmployeeExistsTx", tx, int64(404)).Return(false, nil)
+
+		_, err := service.PatchRole(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "UpdateTx", mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_CreateRole_UnknownParent(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	service := NewService(mockRepo, &StubAuditor{}, mockValidator, createTestLogger())
+	tx, _ := newMockTx(t, false)
+
+	parentId := int64(404)
+	request := CreateRequest{Name: "Developer", Description: "Developers", Status: true, ParentId: &parentId}
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction").Return(tx, nil)
+	mockRepo.On("FindByNameTx", tx, "Developer").Return(false, nil)
+	mockRepo.On("LockHierarchyTx", tx).Return(nil)
+	mockRepo.On("ExistsTx", tx, int64(404)).Return(false, nil)
+
+	_, err := service.CreateRole(context.Background(), request)
+
+	var validationErr common.RequestValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	mockRepo.AssertNotCalled(t, "SaveTx", mock.Anything, mock.Anything)
+}
+
+func TestService_FindTree(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+	rootId, adminId, missingId := int64(1), int64(2), int64(99)
+	roles := []treeEntity{
+		{Entity: Entity{Id: 1, Name: "Root"}, Depth: 0, Path: []int64{1}},
+		{Entity: Entity{Id: 2, Name: "Admin", ParentId: &rootId}, Depth: 1, Path: []int64{1, 2}},
+		{Entity: Entity{Id: 3, Name: "Guest", ParentId: &adminId}, Depth: 2, Path: []int64{1, 2, 3}},
+		{Entity: Entity{Id: 4, Name: "Auditor", ParentId: &rootId}, Depth: 1, Path: []int64{1, 4}},
+		// родитель удалён - роль становится корнем
+		{Entity: Entity{Id: 5, Name: "Orphan", ParentId: &missingId}, Depth: 0, Path: []int64{5}},
+	}
+	mockRepo.On("FindTree").Return(roles, nil)
+
+	tree, err := service.FindTree(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "Root", tree[0].Name)
+	assert.Equal(t, "Orphan", tree[1].Name)
+	assert.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "Admin", tree[0].Children[0].Name)
+	assert.Equal(t, "Guest", tree[0].Children[0].Children[0].Name)
+	assert.Empty(t, tree[0].Children[1].Children)
+}
+
+func TestService_FindAncestors(t *testing.T) {
+	t.Run("Returns chain to the root", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		mockRepo.On("FindById", int64(3)).Return(Entity{Id: 3}, nil)
+		mockRepo.On("FindAncestors", int64(3)).Return([]hierarchyEntity{
+			{Entity: Entity{Id: 2, Name: "Admin"}, Depth: 1},
+			{Entity: Entity{Id: 1, Name: "Root"}, Depth: 2},
+		}, nil)
+
+		ancestors, err := service.FindAncestors(context.Background(), 3)
+
+		assert.NoError(t, err)
+		assert.Len(t, ancestors, 2)
+		assert.Equal(t, "Admin", ancestors[0].Name)
+		assert.Equal(t, 2, ancestors[1].Depth)
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		mockRepo.On("FindById", int64(404)).Return(Entity{}, sql.ErrNoRows)
+
+		_, err := service.FindDescendants(context.Background(), 404)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		mockRepo.AssertNotCalled(t, "FindDescendants", mock.Anything)
+	})
+}
+
+func TestService_FindEffectiveRoles(t *testing.T) {
+	t.Run("Direct role first, then inherited by distance", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		mockRepo.On("EmployeeExists", int64(7)).Return(true, nil)
+		mockRepo.On("FindEffectiveByEmployeeId", int64(7)).Return([]effectiveEntity{
+			{Entity: Entity{Id: 1, Name: "Root"}, Depth: 2, SourceRoleId: 3},
+			{Entity: Entity{Id: 2, Name: "Admin"}, Depth: 1, SourceRoleId: 3},
+			{Entity: Entity{Id: 3, Name: "Guest"}, Depth: 0, SourceRoleId: 3},
+		}, nil)
+
+		roles, err := service.FindEffectiveRoles(context.Background(), 7)
+
+		assert.NoError(t, err)
+		assert.Len(t, roles, 3)
+		assert.Equal(t, "Guest", roles[0].Name)
+		assert.False(t, roles[0].Inherited)
+		assert.Equal(t, "Admin", roles[1].Name)
+		assert.True(t, roles[1].Inherited)
+		assert.Equal(t, int64(3), roles[2].SourceRoleId)
+	})
+
+	t.Run("Unknown employee", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		service := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		mockRepo.On("EmployeeExists", int64(404)).Return(false, nil)
+
+		_, err := service.FindEffectiveRoles(context.Background(), 404)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+	})
+}
+
+func TestService_Deactivate(t *testing.T) {
+	t.Run("Active role is deactivated", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2, Name: "Admin", Status: true}, nil)
+		mockRepo.On("SetStatusTx", tx, int64(2), false).Return(Entity{Id: 2, Name: "Admin"}, nil)
+
+		svc := NewService(mockRepo, auditor, new(MockValidator), createTestLogger())
+
+		response, err := svc.Deactivate(context.Background(), 2)
+
+		assert.NoError(t, err)
+		assert.False(t, response.Status)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionUpdate, auditor.events[0].Action)
+		}
+	})
+
+	t.Run("Activation violates SoD rule of a holder", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2, Name: "Payment approver"}, nil)
+		mockRepo.On("SetStatusTx", tx, int64(2), true).Return(Entity{Id: 2, Name: "Payment approver", Status: true}, nil)
+		mockRepo.On("FindSubtreeForUpdateTx", tx, int64(2)).Return([]Entity{{Id: 2}}, nil)
+		mockRepo.On("FindHoldersTx", tx, []int64{2}).
+			Return([]holderEntity{{EmployeeId: 7, RoleId: 2}, {EmployeeId: 8, RoleId: 2}}, nil)
+		mockRepo.On("FindSodConflictsTx", tx, int64(7), []int64{2}).Return([]sodViolationEntity(nil), nil)
+		mockRepo.On("FindSodConflictsTx", tx, int64(8), []int64{2}).Return([]sodViolationEntity{{
+			EmployeeId: 8, RuleId: 1, RuleName: "Payments", RoleId: 2, RoleName: "Payment approver", SourceRoleId: 2,
+			ConflictingRoleId: 3, ConflictingRoleName: "Payment creator", ConflictingSourceRoleId: 3,
+		}}, nil)
+
+		svc := NewService(mockRepo, auditor, new(MockValidator), createTestLogger())
+
+		_, err := svc.Activate(context.Background(), 2)
+
+		var conflictErr common.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		assert.Contains(t, conflictErr.Message, "employee 8")
+		assert.Empty(t, auditor.events)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Inactive role is left as is", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(2)).Return(Entity{Id: 2, Name: "Admin"}, nil)
+
+		svc := NewService(mockRepo, auditor, new(MockValidator), createTestLogger())
+
+		response, err := svc.Deactivate(context.Background(), 2)
+
+		assert.NoError(t, err)
+		assert.False(t, response.Status)
+		mockRepo.AssertNotCalled(t, "SetStatusTx", mock.Anything, mock.Anything, mock.Anything)
+		assert.Empty(t, auditor.events)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Role not found", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindByIdForUpdateTx", tx, int64(9)).Return(Entity{}, sql.ErrNoRows)
+
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		_, err := svc.Activate(context.Background(), 9)
+
+		assert.True(t, errors.As(err, &common.NotFoundError{}))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Restore(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Hour)
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindDeletedByIdForUpdateTx", tx, int64(2)).
+			Return(Entity{Id: 2, Name: "Admin", DeletedAt: &deletedAt}, nil)
+		mockRepo.On("FindByNameTx", tx, "Admin").Return(false, nil)
+		mockRepo.On("RestoreTx", tx, int64(2)).Return(Entity{Id: 2, Name: "Admin"}, nil)
+
+		svc := NewService(mockRepo, auditor, new(MockValidator), createTestLogger())
+
+		response, err := svc.Restore(context.Background(), 2)
+
+		assert.NoError(t, err)
+		assert.Nil(t, response.DeletedAt)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.ActionRestore, auditor.events[0].Action)
+			assert.Equal(t, audit.EntityRole, auditor.events[0].EntityType)
+		}
+	})
+
+	t.Run("Name taken by active role", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindDeletedByIdForUpdateTx", tx, int64(2)).
+			Return(Entity{Id: 2, Name: "Admin", DeletedAt: &deletedAt}, nil)
+		mockRepo.On("FindByNameTx", tx, "Admin").Return(true, nil)
+
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		_, err := svc.Restore(context.Background(), 2)
+
+		var alreadyExistsErr common.AlreadyExistsError
+		assert.True(t, errors.As(err, &alreadyExistsErr))
+		mockRepo.AssertNotCalled(t, "RestoreTx", mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Not deleted", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindDeletedByIdForUpdateTx", tx, int64(2)).Return(Entity{}, sql.ErrNoRows)
+
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		_, err := svc.Restore(context.Background(), 2)
+
+		var notFoundErr common.NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Purge(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockValidator := new(MockValidator)
+	auditor := &StubAuditor{}
+	tx, sqlMock := newMockTx(t, true)
+	request := PurgeRequest{RetentionDays: 7}
+
+	mockValidator.On("Validate", request).Return(nil)
+	mockRepo.On("BeginTransaction").Return(tx, nil)
+	mockRepo.On("PurgeDeletedTx", tx, mock.AnythingOfType("time.Time")).Return([]Entity{{Id: 4, Name: "Legacy"}}, nil)
+
+	svc := NewService(mockRepo, auditor, mockValidator, createTestLogger())
+
+	response, err := svc.Purge(context.Background(), request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4}, response.PurgedIds)
+	mockRepo.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+	if assert.Len(t, auditor.events, 1) {
+		assert.Equal(t, audit.ActionPurge, auditor.events[0].Action)
+		assert.Equal(t, "Legacy", auditor.events[0].Before.(Response).Name)
+	}
+}
+
+func TestService_CreateSodRule(t *testing.T) {
+	request := SodRuleRequest{Name: "Payments", RoleIds: []int64{3, 4}}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("SodRuleNameExistsTx", tx, "Payments", int64(0)).Return(false, nil)
+		mockRepo.On("FindMissingRoleIdsTx", tx, []int64{3, 4}).Return([]int64(nil), nil)
+		mockRepo.On("SaveSodRuleTx", tx, request.toEntity()).Return(int64(1), nil)
+		mockRepo.On("FindSodRuleForUpdateTx", tx, int64(1)).
+			Return(sodRuleEntity{Id: 1, Name: "Payments", RoleIds: []int64{3, 4}}, nil)
+
+		svc := NewService(mockRepo, auditor, acceptingValidator(), createTestLogger())
+
+		response, err := svc.CreateSodRule(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3, 4}, response.RoleIds)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.EntitySodRule, auditor.events[0].EntityType)
+		}
+	})
+
+	t.Run("Unknown role", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("SodRuleNameExistsTx", tx, "Payments", int64(0)).Return(false, nil)
+		mockRepo.On("FindMissingRoleIdsTx", tx, []int64{3, 4}).Return([]int64{4}, nil)
+
+		svc := NewService(mockRepo, &StubAuditor{}, acceptingValidator(), createTestLogger())
+
+		_, err := svc.CreateSodRule(context.Background(), request)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "SaveSodRuleTx", mock.Anything, mock.Anything)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_CreateSodException(t *testing.T) {
+	request := SodExceptionRequest{
+		RuleId:        1,
+		EmployeeId:    7,
+		Justification: "Sole accountant of the branch",
+		ExpiresAt:     time.Now().Add(24 * time.Hour),
+	}
+
+	t.Run("Approved by the actor", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		auditor := &StubAuditor{}
+		tx, sqlMock := newMockTx(t, true)
+		mockRepo.On("EmployeeExists", int64(7)).Return(true, nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindSodRuleForUpdateTx", tx, int64(1)).Return(sodRuleEntity{Id: 1}, nil)
+		mockRepo.On("HasActiveSodExceptionTx", tx, int64(1), int64(7)).Return(false, nil)
+		mockRepo.On("SaveSodExceptionTx", tx, mock.MatchedBy(func(e sodExceptionEntity) bool {
+			return e.ApprovedByUsername != nil && *e.ApprovedByUsername == "admin"
+		})).Return(sodExceptionEntity{Id: 3, RuleId: 1, EmployeeId: 7}, nil)
+
+		svc := NewService(mockRepo, auditor, acceptingValidator(), createTestLogger())
+		ctx := common.WithActor(context.Background(), common.Actor{Subject: "sub-1", Username: "admin"})
+
+		response, err := svc.CreateSodException(ctx, request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), response.Id)
+		mockRepo.AssertExpectations(t)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+		if assert.Len(t, auditor.events, 1) {
+			assert.Equal(t, audit.EntitySodException, auditor.events[0].EntityType)
+		}
+	})
+
+	t.Run("Active exception already exists", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		tx, sqlMock := newMockTx(t, false)
+		mockRepo.On("EmployeeExists", int64(7)).Return(true, nil)
+		mockRepo.On("BeginTransaction").Return(tx, nil)
+		mockRepo.On("FindSodRuleForUpdateTx", tx, int64(1)).Return(sodRuleEntity{Id: 1}, nil)
+		mockRepo.On("HasActiveSodExceptionTx", tx, int64(1), int64(7)).Return(true, nil)
+
+		svc := NewService(mockRepo, &StubAuditor{}, acceptingValidator(), createTestLogger())
+
+		_, err := svc.CreateSodException(context.Background(), request)
+
+		var conflictErr common.ConflictError
+		assert.True(t, errors.As(err, &conflictErr))
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Expiry in the past", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		expired := request
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+		svc := NewService(mockRepo, &StubAuditor{}, acceptingValidator(), createTestLogger())
+
+		_, err := svc.CreateSodException(context.Background(), expired)
+
+		var validationErr common.RequestValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		mockRepo.AssertNotCalled(t, "BeginTransaction")
+	})
+}
+
+func TestService_CheckSodTx(t *testing.T) {
+	t.Run("No conflicts", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockRepo.On("FindSodConflictsTx", (*sqlx.Tx)(nil), int64(7), []int64{3}).Return([]sodViolationEntity(nil), nil)
+
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		assert.NoError(t, svc.CheckSodTx(context.Background(), nil, 7, []int64{3}))
+	})
+
+	t.Run("Conflict with inherited role", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		mockRepo.On("FindSodConflictsTx", (*sqlx.Tx)(nil), int64(7), []int64{3}).Return([]sodViolationEntity{{
+			EmployeeId:              7,
+			RuleId:                  1,
+			RuleName:                "Payments",
+			RoleId:                  3,
+			RoleName:                "Payment creator",
+			SourceRoleId:            3,
+			ConflictingRoleId:       4,
+			ConflictingRoleName:     "Payment approver",
+			ConflictingSourceRoleId: 9,
+		}}, nil)
+
+		svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+		err := svc.CheckSodTx(context.Background(), nil, 7, []int64{3})
+
+		var conflictErr common.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		assert.Contains(t, conflictErr.Message, `"Payment approver"`)
+		violations := conflictErr.Data.([]SodViolationResponse)
+		if assert.Len(t, violations, 1) {
+			assert.Equal(t, int64(9), violations[0].ConflictingSourceRoleId)
+		}
+	})
+}
+
+func TestService_RevokeSodException_AlreadyRevoked(t *testing.T) {
+	mockRepo := new(MockRepo)
+	revokedAt := time.Now().Add(-time.Hour)
+	tx, sqlMock := newMockTx(t, false)
+	mockRepo.On("BeginTransaction").Return(tx, nil)
+	mockRepo.On("FindSodExceptionForUpdateTx", tx, int64(3)).
+		Return(sodExceptionEntity{Id: 3, RevokedAt: &revokedAt}, nil)
+
+	svc := NewService(mockRepo, &StubAuditor{}, new(MockValidator), createTestLogger())
+
+	_, err := svc.RevokeSodException(context.Background(), 3)
+
+	var conflictErr common.ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	mockRepo.AssertNotCalled(t, "RevokeSodExceptionTx", mock.Anything, mock.Anything)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -25,11 +25,11 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 	t.Run("Valid request - all fields correct", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "John Doe",
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -38,11 +38,11 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 	t.Run("Invalid Name - empty", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "",
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "",
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -56,11 +56,11 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 	t.Run("Invalid Name - too short (less than 2 characters)", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "J",
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "J",
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -75,11 +75,11 @@ func TestCreateRequest_Validation(t *testing.T) {
 	t.Run("Invalid Name - too long (more than 155 characters)", func(t *testing.T) {
 		longName := "John Doe with a very very very very very very very very very very very very very very very very very very very very very very very very very long name that exceeds the limit"
 		req := employee.CreateRequest{
-			Name:       longName,
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         longName,
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -93,11 +93,11 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 	t.Run("Invalid Email - empty", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "John Doe",
+			Email:        "",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -111,11 +111,11 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 	t.Run("Invalid Email - incorrect format", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "invalid-email",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "John Doe",
+			Email:        "invalid-email",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -129,11 +129,11 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 	t.Run("Invalid Email - missing @ symbol", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "john.doeexample.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "John Doe",
+			Email:        "john.doeexample.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -145,13 +145,13 @@ func TestCreateRequest_Validation(t *testing.T) {
 		assert.Equal(t, "email", validationErrors[0].Tag())
 	})
 
-	t.Run("Invalid Position - empty", func(t *testing.T) {
+	t.Run("Invalid PositionId - zero value", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "john.doe@example.com",
-			Position:   "",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "John Doe",
+			Email:        "john.doe@example.com",
+			PositionId:   0,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -159,17 +159,17 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 		validationErrors := err.(validator.ValidationErrors)
 		assert.Len(t, validationErrors, 1)
-		assert.Equal(t, "Position", validationErrors[0].Field())
+		assert.Equal(t, "PositionId", validationErrors[0].Field())
 		assert.Equal(t, "required", validationErrors[0].Tag())
 	})
 
-	t.Run("Invalid Department - empty", func(t *testing.T) {
+	t.Run("Invalid DepartmentId - zero value", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "",
-			RoleId:     1,
+			Name:         "John Doe",
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 0,
+			RoleId:       1,
 		}
 
 		err := validator_.Struct(req)
@@ -177,17 +177,17 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 		validationErrors := err.(validator.ValidationErrors)
 		assert.Len(t, validationErrors, 1)
-		assert.Equal(t, "Department", validationErrors[0].Field())
+		assert.Equal(t, "DepartmentId", validationErrors[0].Field())
 		assert.Equal(t, "required", validationErrors[0].Tag())
 	})
 
 	t.Run("Invalid RoleId - zero value", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     0,
+			Name:         "John Doe",
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       0,
 		}
 
 		err := validator_.Struct(req)
@@ -201,11 +201,11 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 	t.Run("Multiple validation errors", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "",
-			Email:      "invalid-email",
-			Position:   "",
-			Department: "",
-			RoleId:     0,
+			Name:         "",
+			Email:        "invalid-email",
+			PositionId:   0,
+			DepartmentId: 0,
+			RoleId:       0,
 		}
 
 		err := validator_.Struct(req)
@@ -222,8 +222,8 @@ func TestCreateRequest_Validation(t *testing.T) {
 
 		assert.Equal(t, "required", fieldErrors["Name"])
 		assert.Equal(t, "email", fieldErrors["Email"])
-		assert.Equal(t, "required", fieldErrors["Position"])
-		assert.Equal(t, "required", fieldErrors["Department"])
+		assert.Equal(t, "required", fieldErrors["PositionId"])
+		assert.Equal(t, "required", fieldErrors["DepartmentId"])
 		assert.Equal(t, "required", fieldErrors["RoleId"])
 	})
 }
@@ -233,11 +233,11 @@ func TestCreateRequest_WithCustomValidator(t *testing.T) {
 
 	t.Run("Valid request with custom validator", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "John Doe",
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		err := customValidator.Validate(req)
@@ -246,11 +246,11 @@ func TestCreateRequest_WithCustomValidator(t *testing.T) {
 
 	t.Run("Invalid request with custom validator", func(t *testing.T) {
 		req := employee.CreateRequest{
-			Name:       "",
-			Email:      "invalid-email",
-			Position:   "",
-			Department: "",
-			RoleId:     0,
+			Name:         "",
+			Email:        "invalid-email",
+			PositionId:   0,
+			DepartmentId: 0,
+			RoleId:       0,
 		}
 
 		err := customValidator.Validate(req)
@@ -265,8 +265,8 @@ func TestCreateRequest_WithCustomValidator(t *testing.T) {
 		}
 		assert.True(t, fields["Name"])
 		assert.True(t, fields["Email"])
-		assert.True(t, fields["Position"])
-		assert.True(t, fields["Department"])
+		assert.True(t, fields["PositionId"])
+		assert.True(t, fields["DepartmentId"])
 		assert.True(t, fields["RoleId"])
 	})
 }
@@ -276,11 +276,11 @@ func TestCreateRequest_WithMockValidator(t *testing.T) {
 	t.Run("Mock validator returns no error", func(t *testing.T) {
 		mockValidator := &MockValidator{}
 		req := employee.CreateRequest{
-			Name:       "John Doe",
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "John Doe",
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		mockValidator.On("Validate", req).Return(nil)
@@ -293,11 +293,11 @@ func TestCreateRequest_WithMockValidator(t *testing.T) {
 	t.Run("Mock validator returns validation error", func(t *testing.T) {
 		mockValidator := &MockValidator{}
 		req := employee.CreateRequest{
-			Name:       "",
-			Email:      "john.doe@example.com",
-			Position:   "Software Engineer",
-			Department: "IT",
-			RoleId:     1,
+			Name:         "",
+			Email:        "john.doe@example.com",
+			PositionId:   1,
+			DepartmentId: 1,
+			RoleId:       1,
 		}
 
 		expectedError := validator.ValidationErrors{}
@@ -314,11 +314,11 @@ func TestCreateRequest_WithMockValidator(t *testing.T) {
 func BenchmarkCreateRequest_Validation(b *testing.B) {
 	validator := validator.New()
 	req := employee.CreateRequest{
-		Name:       "John Doe",
-		Email:      "john.doe@example.com",
-		Position:   "Software Engineer",
-		Department: "IT",
-		RoleId:     1,
+		Name:         "John Doe",
+		Email:        "john.doe@example.com",
+		PositionId:   1,
+		DepartmentId: 1,
+		RoleId:       1,
 	}
 
 	for b.Loop() {
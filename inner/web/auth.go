@@ -2,7 +2,6 @@ package web
 
 import (
 	"idm/inner/common"
-	"slices"
 
 	jwtMiddleware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
@@ -17,7 +16,9 @@ const (
 )
 
 type IdmClaims struct {
-	RealmAccess RealmAccessClaims `json:"realm_access"`
+	RealmAccess       RealmAccessClaims `json:"realm_access"`
+	PreferredUsername string            `json:"preferred_username"`
+	Email             string            `json:"email"`
 	jwt.RegisteredClaims
 }
 
@@ -36,50 +37,42 @@ func AuthMiddleware(logger *common.Logger) fiber.Handler {
 	return jwtMiddleware.New(config)
 }
 
-// middleware для проверки конкретной роли
-func RequireRole(requiredRole string, logger *common.Logger) fiber.Handler {
+// middleware, сохраняющий инициатора запроса в пользовательский контекст.
+// Сервисы получают его через common.ActorFromContext для записи в журнал аудита
+func ActorMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		token := c.Locals(JwtKey).(*jwt.Token)
-		claims := token.Claims.(*IdmClaims)
-
-		if !slices.Contains(claims.RealmAccess.Roles, requiredRole) {
-			logger.Warn("Access denied: insufficient role",
-				zap.String("required_role", requiredRole),
-				zap.Strings("user_roles", claims.RealmAccess.Roles),
-				zap.String("path", c.Path()),
-				zap.String("method", c.Method()),
-				zap.String("ip", c.IP()))
-
-			return common.ErrResponse(c, fiber.StatusForbidden, "Insufficient permissions")
+		actor := common.Actor{
+			RequestId: c.GetRespHeader(fiber.HeaderXRequestID),
+			Ip:        c.IP(),
 		}
-
-		logger.Debug("Role check passed",
-			zap.String("required_role", requiredRole),
-			zap.Strings("user_roles", claims.RealmAccess.Roles),
-			zap.String("path", c.Path()))
-
+		if token, ok := c.Locals(JwtKey).(*jwt.Token); ok {
+			if claims, ok := token.Claims.(*IdmClaims); ok {
+				actor.Subject = claims.Subject
+				actor.Username = claims.PreferredUsername
+				actor.Email = claims.Email
+			}
+		}
+		c.SetUserContext(common.WithActor(c.UserContext(), actor))
 		return c.Next()
 	}
 }
 
+// middleware для проверки конкретной роли
+func RequireRole(requiredRole string, logger *common.Logger) fiber.Handler {
+	return RequireAnyRole([]string{requiredRole}, logger)
+}
+
 // middleware для проверки любой из указанных ролей
 func RequireAnyRole(requiredRoles []string, logger *common.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		token := c.Locals(JwtKey).(*jwt.Token)
-		claims := token.Claims.(*IdmClaims)
-
-		hasRole := false
-		for _, role := range requiredRoles {
-			if slices.Contains(claims.RealmAccess.Roles, role) {
-				hasRole = true
-				break
-			}
-		}
+		userRoles := GetUserRoles(c)
+		decision := EvaluateAnyRole(TokenGrants(userRoles), requiredRoles)
 
-		if !hasRole {
+		if !decision.Allowed {
 			logger.Warn("Access denied: insufficient role",
 				zap.Strings("required_roles", requiredRoles),
-				zap.Strings("user_roles", claims.RealmAccess.Roles),
+				zap.Strings("user_roles", userRoles),
+				zap.Strings("reasons", decision.Reasons),
 				zap.String("path", c.Path()),
 				zap.String("method", c.Method()),
 				zap.String("ip", c.IP()))
@@ -89,7 +82,8 @@ func RequireAnyRole(requiredRoles []string, logger *common.Logger) fiber.Handler
 
 		logger.Debug("Role check passed",
 			zap.Strings("required_roles", requiredRoles),
-			zap.Strings("user_roles", claims.RealmAccess.Roles),
+			zap.Strings("user_roles", userRoles),
+			zap.Strings("reasons", decision.Reasons),
 			zap.String("path", c.Path()))
 
 		return c.Next()
@@ -105,22 +99,12 @@ func GetUserRoles(c *fiber.Ctx) []string {
 
 // проверка, есть ли у пользователя определённая роль
 func HasRole(c *fiber.Ctx, role string) bool {
-	token := c.Locals(JwtKey).(*jwt.Token)
-	claims := token.Claims.(*IdmClaims)
-	return slices.Contains(claims.RealmAccess.Roles, role)
+	return HasAnyRole(c, []string{role})
 }
 
 // проверка, есть ли у пользователя любая из указанных ролей
 func HasAnyRole(c *fiber.Ctx, roles []string) bool {
-	token := c.Locals(JwtKey).(*jwt.Token)
-	claims := token.Claims.(*IdmClaims)
-
-	for _, role := range roles {
-		if slices.Contains(claims.RealmAccess.Roles, role) {
-			return true
-		}
-	}
-	return false
+	return EvaluateAnyRole(TokenGrants(GetUserRoles(c)), roles).Allowed
 }
 
 func createJwtErrorHandler(logger *common.Logger) fiber.ErrorHandler {
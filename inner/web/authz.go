@@ -0,0 +1,91 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+)
+
+// источник ролей, полученных из JWT токена
+const TokenSource = "token"
+
+// Grant роль или разрешение субъекта вместе с источником, через который оно получено
+type Grant struct {
+	Name   string
+	Source string
+}
+
+// Decision результат проверки доступа: разрешено ли действие и почему
+type Decision struct {
+	Allowed bool     `json:"allowed"`
+	Reasons []string `json:"reasons"`
+}
+
+// TokenGrants преобразует роли из JWT токена в права субъекта
+func TokenGrants(roles []string) []Grant {
+	grants := make([]Grant, len(roles))
+	for i, role := range roles {
+		grants[i] = Grant{Name: role, Source: TokenSource}
+	}
+	return grants
+}
+
+// EvaluateAnyRole проверяет, обладает ли субъект хотя бы одной из требуемых ролей.
+// Используется и middleware RequireRole/RequireAnyRole, и эндпоинтом /authz/check,
+// поэтому решения IDM и внешнего API всегда совпадают
+func EvaluateAnyRole(held []Grant, requiredRoles []string) Decision {
+	if len(requiredRoles) == 0 {
+		return Decision{Allowed: false, Reasons: []string{"no required roles specified"}}
+	}
+	for _, required := range requiredRoles {
+		if sources := findSources(held, required); len(sources) > 0 {
+			return Decision{
+				Allowed: true,
+				Reasons: []string{fmt.Sprintf("role %s held via %s", required, strings.Join(sources, ", "))},
+			}
+		}
+	}
+	return Decision{
+		Allowed: false,
+		Reasons: []string{fmt.Sprintf("none of required roles [%s] held", strings.Join(requiredRoles, ", "))},
+	}
+}
+
+// EvaluatePermissions проверяет, что субъекту выданы все требуемые разрешения
+func EvaluatePermissions(held []Grant, requiredPermissions []string) Decision {
+	if len(requiredPermissions) == 0 {
+		return Decision{Allowed: false, Reasons: []string{"no required permissions specified"}}
+	}
+	decision := Decision{Allowed: true}
+	for _, required := range requiredPermissions {
+		sources := findSources(held, required)
+		if len(sources) == 0 {
+			decision.Allowed = false
+			decision.Reasons = append(decision.Reasons, fmt.Sprintf("permission %s not granted", required))
+			continue
+		}
+		decision.Reasons = append(decision.Reasons,
+			fmt.Sprintf("permission %s granted via %s", required, strings.Join(sources, ", ")))
+	}
+	return decision
+}
+
+// AllOf объединяет решения: доступ разрешён, только если разрешены все проверки
+func AllOf(decisions ...Decision) Decision {
+	result := Decision{Allowed: len(decisions) > 0}
+	for _, decision := range decisions {
+		result.Allowed = result.Allowed && decision.Allowed
+		result.Reasons = append(result.Reasons, decision.Reasons...)
+	}
+	return result
+}
+
+// источники, через которые субъект получил право с указанным именем
+func findSources(held []Grant, name string) []string {
+	var sources []string
+	for _, grant := range held {
+		if grant.Name == name {
+			sources = append(sources, grant.Source)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateAnyRole(t *testing.T) {
+	held := TokenGrants([]string{IdmUser})
+
+	decision := EvaluateAnyRole(held, []string{IdmAdmin, IdmUser})
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, []string{"role IDM_USER held via token"}, decision.Reasons)
+
+	decision = EvaluateAnyRole(held, []string{IdmAdmin})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, []string{"none of required roles [IDM_ADMIN] held"}, decision.Reasons)
+
+	// пустой список требований не должен разрешать доступ
+	assert.False(t, EvaluateAnyRole(held, nil).Allowed)
+}
+
+func TestEvaluatePermissions(t *testing.T) {
+	held := []Grant{
+		{Name: "employee:read", Source: "role Guest"},
+		{Name: "employee:read", Source: "role Admin"},
+	}
+
+	decision := EvaluatePermissions(held, []string{"employee:read"})
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, []string{"permission employee:read granted via role Guest, role Admin"}, decision.Reasons)
+
+	decision = EvaluatePermissions(held, []string{"employee:read", "employee:write"})
+	assert.False(t, decision.Allowed)
+	assert.Len(t, decision.Reasons, 2)
+}
+
+func TestAllOf(t *testing.T) {
+	allowed := Decision{Allowed: true, Reasons: []string{"a"}}
+	denied := Decision{Allowed: false, Reasons: []string{"b"}}
+
+	assert.True(t, AllOf(allowed, allowed).Allowed)
+	assert.False(t, AllOf(allowed, denied).Allowed)
+	assert.Equal(t, []string{"a", "b"}, AllOf(allowed, denied).Reasons)
+	assert.False(t, AllOf().Allowed)
+}
@@ -27,6 +27,8 @@ type Server struct {
 	GroupApiV1Admin fiber.Router
 	// группа для пользователей (требует роль IDM_ADMIN или IDM_USER)
 	GroupApiV1User fiber.Router
+	// подпись курсоров постраничного вывода
+	Cursors *common.CursorCodec
 }
 
 type AuthMiddlewareInterface interface {
@@ -89,6 +91,7 @@ func NewServer(logger *common.Logger) *Server {
 		GroupApiV1Protected: groupApiV1Protected,
 		GroupApiV1Admin:     groupApiV1Admin,
 		GroupApiV1User:      groupApiV1User,
+		Cursors:             common.NewCursorCodec(""),
 	}
 }
 
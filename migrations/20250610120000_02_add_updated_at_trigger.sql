@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- функция обновляет updated_at при любом изменении строки,
+-- updated_at используется как версия записи для оптимистичной блокировки
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = clock_timestamp();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER employee_set_updated_at
+    BEFORE UPDATE ON employee
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TRIGGER role_set_updated_at
+    BEFORE UPDATE ON role
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS role_set_updated_at ON role;
+DROP TRIGGER IF EXISTS employee_set_updated_at ON employee;
+DROP FUNCTION IF EXISTS set_updated_at();
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS permission (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    code TEXT UNIQUE NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- связь многие-ко-многим: какие разрешения выданы роли напрямую
+CREATE TABLE IF NOT EXISTS role_permission (
+    role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+    permission_id BIGINT NOT NULL REFERENCES permission(id) ON DELETE CASCADE,
+    granted_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE INDEX IF NOT EXISTS role_permission_permission_id_idx ON role_permission (permission_id);
+
+CREATE TRIGGER permission_set_updated_at
+    BEFORE UPDATE ON permission
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS role_permission;
+DROP TABLE IF EXISTS permission;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- назначения ролей сотрудникам на период [valid_from, valid_to); valid_to = NULL - бессрочно.
+-- Отозванные назначения не удаляются, а закрываются, чтобы сохранялась история
+CREATE TABLE IF NOT EXISTS employee_role (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES role(id),
+    valid_from TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    valid_to TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT employee_role_valid_period CHECK (valid_to IS NULL OR valid_to >= valid_from)
+);
+
+CREATE INDEX IF NOT EXISTS employee_role_employee_id_idx ON employee_role (employee_id, valid_from);
+CREATE INDEX IF NOT EXISTS employee_role_role_id_idx ON employee_role (role_id);
+
+-- переносим существующие назначения: employee.role_id остаётся только для обратной совместимости
+INSERT INTO employee_role (employee_id, role_id, valid_from)
+SELECT id, role_id, COALESCE(created_at, NOW()) FROM employee WHERE role_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS employee_role;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- журнал изменений сотрудников и ролей; пишется в той же транзакции, что и само изменение.
+-- entity_id не ссылается на таблицы сущностей, чтобы события удалённых записей сохранялись
+CREATE TABLE IF NOT EXISTS audit_event (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    actor_sub TEXT,
+    actor_username TEXT,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id BIGINT,
+    before JSONB,
+    after JSONB,
+    request_id TEXT,
+    ip TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_created_at_idx ON audit_event (created_at);
+CREATE INDEX IF NOT EXISTS audit_event_entity_idx ON audit_event (entity_type, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_event_actor_sub_idx ON audit_event (actor_sub, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_event;
+-- +goose StatementEnd
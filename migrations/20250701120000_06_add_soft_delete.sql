@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- мягкое удаление: строка остаётся в таблице до окончательной очистки (purge)
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE role ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- email должен быть уникален только среди неудалённых сотрудников
+ALTER TABLE employee DROP CONSTRAINT IF EXISTS employee_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS employee_email_active_idx ON employee (email) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS employee_deleted_at_idx ON employee (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS role_deleted_at_idx ON role (deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM employee WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS employee_deleted_at_idx;
+DROP INDEX IF EXISTS role_deleted_at_idx;
+DROP INDEX IF EXISTS employee_email_active_idx;
+ALTER TABLE employee ADD CONSTRAINT employee_email_key UNIQUE (email);
+ALTER TABLE employee DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE role DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
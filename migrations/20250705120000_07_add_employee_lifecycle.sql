@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- жизненный цикл сотрудника: pending -> active <-> suspended -> terminated.
+-- существующие сотрудники считаются работающими
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+    CONSTRAINT employee_status_check CHECK (status IN ('pending', 'active', 'suspended', 'terminated'));
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS hire_date DATE;
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS start_date DATE;
+
+CREATE INDEX IF NOT EXISTS employee_status_idx ON employee (status);
+
+-- история переходов между состояниями: кто, когда и почему перевёл сотрудника
+CREATE TABLE IF NOT EXISTS employee_status_history (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    transition TEXT NOT NULL,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    reason TEXT,
+    details JSONB,
+    actor_sub TEXT,
+    actor_username TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS employee_status_history_employee_idx ON employee_status_history (employee_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS employee_status_history;
+DROP INDEX IF EXISTS employee_status_idx;
+ALTER TABLE employee DROP COLUMN IF EXISTS start_date;
+ALTER TABLE employee DROP COLUMN IF EXISTS hire_date;
+ALTER TABLE employee DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
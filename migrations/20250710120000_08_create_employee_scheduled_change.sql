@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- отложенные изменения сотрудника (перевод, смена роли), вступающие в силу в effective_at.
+-- Применяются фоновым планировщиком; результат применения сохраняется в status и result
+CREATE TABLE IF NOT EXISTS employee_scheduled_change (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    position TEXT,
+    department TEXT,
+    role_id BIGINT REFERENCES role(id) ON DELETE SET NULL,
+    effective_at TIMESTAMPTZ NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CONSTRAINT employee_scheduled_change_status_check CHECK (status IN ('pending', 'applied', 'failed', 'cancelled')),
+    reason TEXT,
+    result TEXT,
+    created_by_sub TEXT,
+    created_by_username TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    applied_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS employee_scheduled_change_due_idx ON employee_scheduled_change (effective_at)
+    WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS employee_scheduled_change_employee_idx ON employee_scheduled_change (employee_id, effective_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS employee_scheduled_change;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- линия подчинения: непосредственный руководитель сотрудника.
+-- Отсутствие циклов проверяется сервисом под advisory-блокировкой иерархии
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS manager_id BIGINT REFERENCES employee(id) ON DELETE SET NULL;
+ALTER TABLE employee ADD CONSTRAINT employee_manager_not_self CHECK (manager_id <> id);
+
+CREATE INDEX IF NOT EXISTS employee_manager_id_idx ON employee (manager_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS employee_manager_id_idx;
+ALTER TABLE employee DROP CONSTRAINT IF EXISTS employee_manager_not_self;
+ALTER TABLE employee DROP COLUMN IF EXISTS manager_id;
+-- +goose StatementEnd
@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+-- справочник отделов вместо свободного текста employee.department.
+-- Иерархия задаётся parent_id, отсутствие циклов проверяется сервисом под advisory-блокировкой
+CREATE TABLE IF NOT EXISTS department (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name TEXT NOT NULL,
+    parent_id BIGINT REFERENCES department(id),
+    head_employee_id BIGINT REFERENCES employee(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT department_parent_not_self CHECK (parent_id <> id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS department_name_idx ON department (lower(name));
+CREATE INDEX IF NOT EXISTS department_parent_id_idx ON department (parent_id);
+
+CREATE TRIGGER department_set_updated_at
+    BEFORE UPDATE ON department
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- нормализация существующих строк: "IT", "it" и "I.T." сводятся к одному ключу
+-- из букв и цифр в нижнем регистре, имя отдела берётся самым частым написанием
+CREATE TEMPORARY TABLE department_key ON COMMIT DROP AS
+SELECT btrim(department) AS name, lower(regexp_replace(department, '[^[:alnum:]]+', '', 'g')) AS key
+FROM (
+    SELECT department FROM employee WHERE department IS NOT NULL
+    UNION ALL
+    SELECT department FROM employee_scheduled_change WHERE department IS NOT NULL
+) source;
+
+INSERT INTO department (name)
+SELECT mode() WITHIN GROUP (ORDER BY name)
+FROM department_key
+WHERE key <> ''
+GROUP BY key
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS department_id BIGINT REFERENCES department(id) ON DELETE SET NULL;
+ALTER TABLE employee_scheduled_change
+    ADD COLUMN IF NOT EXISTS department_id BIGINT REFERENCES department(id) ON DELETE SET NULL;
+
+UPDATE employee e
+SET department_id = d.id
+FROM department d
+WHERE lower(regexp_replace(e.department, '[^[:alnum:]]+', '', 'g'))
+    = lower(regexp_replace(d.name, '[^[:alnum:]]+', '', 'g'));
+
+UPDATE employee_scheduled_change c
+SET department_id = d.id
+FROM department d
+WHERE lower(regexp_replace(c.department, '[^[:alnum:]]+', '', 'g'))
+    = lower(regexp_replace(d.name, '[^[:alnum:]]+', '', 'g'));
+
+ALTER TABLE employee DROP COLUMN IF EXISTS department;
+ALTER TABLE employee_scheduled_change DROP COLUMN IF EXISTS department;
+
+CREATE INDEX IF NOT EXISTS employee_department_id_idx ON employee (department_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS department TEXT;
+ALTER TABLE employee_scheduled_change ADD COLUMN IF NOT EXISTS department TEXT;
+
+UPDATE employee e SET department = d.name FROM department d WHERE d.id = e.department_id;
+UPDATE employee_scheduled_change c SET department = d.name FROM department d WHERE d.id = c.department_id;
+
+DROP INDEX IF EXISTS employee_department_id_idx;
+ALTER TABLE employee_scheduled_change DROP COLUMN IF EXISTS department_id;
+ALTER TABLE employee DROP COLUMN IF EXISTS department_id;
+DROP TABLE IF EXISTS department;
+-- +goose StatementEnd
@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+-- справочник должностей вместо свободного текста employee.position
+CREATE TABLE IF NOT EXISTS position (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name TEXT NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS position_name_idx ON position (lower(name));
+
+CREATE TRIGGER position_set_updated_at
+    BEFORE UPDATE ON position
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- роли по умолчанию, которые назначаются сотруднику при приёме на должность или переводе на неё
+CREATE TABLE IF NOT EXISTS position_role (
+    position_id BIGINT NOT NULL REFERENCES position(id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+    PRIMARY KEY (position_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS position_role_role_id_idx ON position_role (role_id);
+
+-- нормализация существующих строк так же, как для отделов: "Developer" и "developer"
+-- сводятся к одному ключу, название должности берётся самым частым написанием
+CREATE TEMPORARY TABLE position_key ON COMMIT DROP AS
+SELECT btrim(position) AS name, lower(regexp_replace(position, '[^[:alnum:]]+', '', 'g')) AS key
+FROM (
+    SELECT position FROM employee WHERE position IS NOT NULL
+    UNION ALL
+    SELECT position FROM employee_scheduled_change WHERE position IS NOT NULL
+) source;
+
+INSERT INTO position (name)
+SELECT mode() WITHIN GROUP (ORDER BY name)
+FROM position_key
+WHERE key <> ''
+GROUP BY key
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS position_id BIGINT REFERENCES position(id) ON DELETE SET NULL;
+ALTER TABLE employee_scheduled_change
+    ADD COLUMN IF NOT EXISTS position_id BIGINT REFERENCES position(id) ON DELETE SET NULL;
+
+UPDATE employee e
+SET position_id = p.id
+FROM position p
+WHERE lower(regexp_replace(e.position, '[^[:alnum:]]+', '', 'g'))
+    = lower(regexp_replace(p.name, '[^[:alnum:]]+', '', 'g'));
+
+UPDATE employee_scheduled_change c
+SET position_id = p.id
+FROM position p
+WHERE lower(regexp_replace(c.position, '[^[:alnum:]]+', '', 'g'))
+    = lower(regexp_replace(p.name, '[^[:alnum:]]+', '', 'g'));
+
+ALTER TABLE employee DROP COLUMN IF EXISTS position;
+ALTER TABLE employee_scheduled_change DROP COLUMN IF EXISTS position;
+
+CREATE INDEX IF NOT EXISTS employee_position_id_idx ON employee (position_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS position TEXT;
+ALTER TABLE employee_scheduled_change ADD COLUMN IF NOT EXISTS position TEXT;
+
+UPDATE employee e SET position = p.name FROM position p WHERE p.id = e.position_id;
+UPDATE employee_scheduled_change c SET position = p.name FROM position p WHERE p.id = c.position_id;
+
+DROP INDEX IF EXISTS employee_position_id_idx;
+ALTER TABLE employee_scheduled_change DROP COLUMN IF EXISTS position_id;
+ALTER TABLE employee DROP COLUMN IF EXISTS position_id;
+DROP TABLE IF EXISTS position_role;
+DROP TABLE IF EXISTS position;
+-- +goose StatementEnd
@@ -0,0 +1,120 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- транслитерация кириллицы в латиницу, чтобы "Иванов" находился по "ivanov" и наоборот
+CREATE OR REPLACE FUNCTION translit_ru(value TEXT) RETURNS TEXT AS $$
+    SELECT translate(
+        replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(
+            lower(value),
+            'щ', 'shch'), 'ж', 'zh'), 'х', 'kh'), 'ц', 'ts'), 'ч', 'ch'),
+            'ш', 'sh'), 'ю', 'yu'), 'я', 'ya'), 'ъ', ''), 'ь', ''),
+        'абвгдеёзийклмнопрстуфыэ',
+        'abvgdeeziyklmnoprstufye')
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+-- текст поиска сотрудника: имя, email, названия должности и отдела в исходном написании и в латинице
+CREATE OR REPLACE FUNCTION employee_search_text(
+    name TEXT, email TEXT, position_id BIGINT, department_id BIGINT
+) RETURNS TEXT AS $$
+    SELECT lower(source) || ' ' || translit_ru(source)
+    FROM (
+        SELECT concat_ws(' ', name, email,
+            (SELECT p.name FROM position p WHERE p.id = position_id),
+            (SELECT d.name FROM department d WHERE d.id = department_id)) AS source
+    ) s
+$$ LANGUAGE sql STABLE;
+
+-- названия должности и отдела хранятся в других таблицах, поэтому текст поиска поддерживается
+-- триггерами, а документ tsvector вычисляется из него генерируемой колонкой
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '';
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', search_text)) STORED;
+
+CREATE OR REPLACE FUNCTION employee_refresh_search_text() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_text = employee_search_text(NEW.name, NEW.email, NEW.position_id, NEW.department_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER employee_search_text
+    BEFORE INSERT OR UPDATE OF name, email, position_id, department_id, search_text ON employee
+    FOR EACH ROW EXECUTE FUNCTION employee_refresh_search_text();
+
+-- updated_at - версия сотрудника для оптимистичной блокировки: пересчёт одного текста поиска
+-- данные сотрудника не меняет и версию не увеличивает. Триггеры срабатывают по алфавиту,
+-- поэтому к этому моменту employee_search_text уже пересчитал текст
+CREATE OR REPLACE FUNCTION employee_set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.search_text IS DISTINCT FROM OLD.search_text
+        AND to_jsonb(NEW) - 'search_text' - 'search_vector' = to_jsonb(OLD) - 'search_text' - 'search_vector' THEN
+        RETURN NEW;
+    END IF;
+    NEW.updated_at = clock_timestamp();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS employee_set_updated_at ON employee;
+CREATE TRIGGER employee_set_updated_at
+    BEFORE UPDATE ON employee
+    FOR EACH ROW EXECUTE FUNCTION employee_set_updated_at();
+
+-- переименование должности или отдела обновляет текст поиска их сотрудников.
+-- Строки, текст которых не изменился, не обновляются, чтобы не увеличить их версию
+CREATE OR REPLACE FUNCTION position_refresh_employee_search() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE employee SET search_text = employee_search_text(name, email, position_id, department_id)
+    WHERE position_id = NEW.id
+        AND search_text IS DISTINCT FROM employee_search_text(name, email, position_id, department_id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER position_employee_search
+    AFTER UPDATE OF name ON position
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION position_refresh_employee_search();
+
+CREATE OR REPLACE FUNCTION department_refresh_employee_search() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE employee SET search_text = employee_search_text(name, email, position_id, department_id)
+    WHERE department_id = NEW.id
+        AND search_text IS DISTINCT FROM employee_search_text(name, email, position_id, department_id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER department_employee_search
+    AFTER UPDATE OF name ON department
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION department_refresh_employee_search();
+
+-- заполнение для существующих сотрудников
+UPDATE employee SET search_text = employee_search_text(name, email, position_id, department_id);
+
+CREATE INDEX IF NOT EXISTS employee_search_vector_idx ON employee USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS employee_search_text_trgm_idx ON employee USING GIN (search_text gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS department_employee_search ON department;
+DROP TRIGGER IF EXISTS position_employee_search ON position;
+DROP TRIGGER IF EXISTS employee_set_updated_at ON employee;
+CREATE TRIGGER employee_set_updated_at
+    BEFORE UPDATE ON employee
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+DROP FUNCTION IF EXISTS employee_set_updated_at();
+DROP TRIGGER IF EXISTS employee_search_text ON employee;
+DROP FUNCTION IF EXISTS department_refresh_employee_search();
+DROP FUNCTION IF EXISTS position_refresh_employee_search();
+DROP FUNCTION IF EXISTS employee_refresh_search_text();
+DROP INDEX IF EXISTS employee_search_text_trgm_idx;
+DROP INDEX IF EXISTS employee_search_vector_idx;
+ALTER TABLE employee DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE employee DROP COLUMN IF EXISTS search_text;
+DROP FUNCTION IF EXISTS employee_search_text(TEXT, TEXT, BIGINT, BIGINT);
+DROP FUNCTION IF EXISTS translit_ru(TEXT);
+-- +goose StatementEnd
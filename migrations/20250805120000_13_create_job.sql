@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- фоновые задания (импорт, выгрузка, массовое удаление). Задание выполняет пул обработчиков
+-- одного из экземпляров приложения; heartbeat_at обновляется во время выполнения, задание
+-- с устаревшим heartbeat_at считается брошенным и забирается повторно
+CREATE TABLE IF NOT EXISTS job (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    type TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'queued'
+        CONSTRAINT job_status_check CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+    params JSONB NOT NULL DEFAULT '{}',
+    input BYTEA,
+    progress INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    result JSONB,
+    result_file BYTEA,
+    result_content_type TEXT,
+    result_filename TEXT,
+    error TEXT,
+    created_by_sub TEXT,
+    created_by_username TEXT,
+    request_id TEXT,
+    ip TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    heartbeat_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE TRIGGER job_set_updated_at
+    BEFORE UPDATE ON job
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS job_pending_idx ON job (id) WHERE status IN ('queued', 'running');
+CREATE INDEX IF NOT EXISTS job_created_by_idx ON job (created_by_sub, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS job;
+-- +goose StatementEnd
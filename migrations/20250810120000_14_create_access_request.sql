@@ -0,0 +1,77 @@
+-- +goose Up
+-- +goose StatementBegin
+-- настройка согласования роли: шаги, которые последовательно проходит запрос доступа.
+-- Для ролей без настройки используются шаги по умолчанию
+CREATE TABLE IF NOT EXISTS access_request_policy (
+    role_id BIGINT PRIMARY KEY REFERENCES role(id) ON DELETE CASCADE,
+    steps TEXT[] NOT NULL
+        CONSTRAINT access_request_policy_steps_check
+        CHECK (cardinality(steps) BETWEEN 1 AND 5 AND steps <@ ARRAY['manager', 'role_owner', 'admin']),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER access_request_policy_set_updated_at
+    BEFORE UPDATE ON access_request_policy
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- запрос роли для сотрудника. steps - шаги согласования на момент создания запроса
+-- (изменение настройки не влияет на поданные запросы), current_step - номер текущего шага с нуля.
+-- Запрос без решения до expires_at истекает. Роль с запросами не удаляется окончательно, чтобы не потерять их историю
+CREATE TABLE IF NOT EXISTS access_request (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE RESTRICT,
+    requester_employee_id BIGINT REFERENCES employee(id) ON DELETE SET NULL,
+    justification TEXT NOT NULL,
+    valid_from TIMESTAMPTZ,
+    valid_to TIMESTAMPTZ,
+    steps TEXT[] NOT NULL,
+    current_step INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CONSTRAINT access_request_status_check
+        CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled', 'expired')),
+    assignment_id BIGINT REFERENCES employee_role(id) ON DELETE SET NULL,
+    created_by_sub TEXT,
+    created_by_username TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at TIMESTAMPTZ,
+    CONSTRAINT access_request_valid_period CHECK (valid_to IS NULL OR valid_from IS NULL OR valid_to >= valid_from)
+);
+
+CREATE TRIGGER access_request_set_updated_at
+    BEFORE UPDATE ON access_request
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- у сотрудника может быть только один рассматриваемый запрос одной роли
+CREATE UNIQUE INDEX IF NOT EXISTS access_request_pending_uidx ON access_request (employee_id, role_id)
+    WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS access_request_expires_idx ON access_request (expires_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS access_request_created_by_idx ON access_request (created_by_sub, created_at);
+
+-- история запроса: подача, решения по шагам, комментарии, отмена и истечение
+CREATE TABLE IF NOT EXISTS access_request_event (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    request_id BIGINT NOT NULL REFERENCES access_request(id) ON DELETE CASCADE,
+    action TEXT NOT NULL
+        CONSTRAINT access_request_event_action_check
+        CHECK (action IN ('submit', 'approve', 'reject', 'comment', 'cancel', 'expire')),
+    step TEXT,
+    comment TEXT,
+    actor_employee_id BIGINT REFERENCES employee(id) ON DELETE SET NULL,
+    actor_sub TEXT,
+    actor_username TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS access_request_event_request_idx ON access_request_event (request_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS access_request_event;
+DROP TABLE IF EXISTS access_request;
+DROP TABLE IF EXISTS access_request_policy;
+-- +goose StatementEnd
@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+-- кампания пересмотра доступа. Область - роли и отделы (с подотделами); пустой массив - без ограничения.
+-- reviewer - кто пересматривает назначения: руководитель сотрудника или владелец роли.
+-- auto_action - решение по непересмотренным к deadline назначениям
+CREATE TABLE IF NOT EXISTS certification_campaign (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name TEXT NOT NULL,
+    description TEXT,
+    role_ids BIGINT[] NOT NULL DEFAULT '{}',
+    department_ids BIGINT[] NOT NULL DEFAULT '{}',
+    reviewer TEXT NOT NULL DEFAULT 'manager'
+        CONSTRAINT certification_campaign_reviewer_check CHECK (reviewer IN ('manager', 'role_owner')),
+    auto_action TEXT NOT NULL DEFAULT 'revoke'
+        CONSTRAINT certification_campaign_auto_action_check CHECK (auto_action IN ('certify', 'revoke')),
+    deadline TIMESTAMPTZ NOT NULL,
+    status TEXT NOT NULL DEFAULT 'active'
+        CONSTRAINT certification_campaign_status_check CHECK (status IN ('active', 'completed', 'cancelled')),
+    created_by_sub TEXT,
+    created_by_username TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE TRIGGER certification_campaign_set_updated_at
+    BEFORE UPDATE ON certification_campaign
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS certification_campaign_deadline_idx ON certification_campaign (deadline) WHERE status = 'active';
+
+-- назначение роли, зафиксированное при запуске кампании. reviewer_employee_id - пересматривающий сотрудник;
+-- NULL - пересматривают администраторы. auto = TRUE - решение принято автоматически по истечении срока.
+-- Роль с пересмотренными назначениями не удаляется окончательно, чтобы не потерять решения по ней
+CREATE TABLE IF NOT EXISTS certification_item (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    campaign_id BIGINT NOT NULL REFERENCES certification_campaign(id) ON DELETE CASCADE,
+    assignment_id BIGINT REFERENCES employee_role(id) ON DELETE SET NULL,
+    employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE RESTRICT,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ,
+    reviewer_employee_id BIGINT REFERENCES employee(id) ON DELETE SET NULL,
+    decision TEXT NOT NULL DEFAULT 'pending'
+        CONSTRAINT certification_item_decision_check CHECK (decision IN ('pending', 'certified', 'revoked')),
+    auto BOOLEAN NOT NULL DEFAULT FALSE,
+    comment TEXT,
+    decided_by_sub TEXT,
+    decided_by_username TEXT,
+    decided_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS certification_item_campaign_idx ON certification_item (campaign_id, decision);
+CREATE INDEX IF NOT EXISTS certification_item_reviewer_idx ON certification_item (reviewer_employee_id) WHERE decision = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS certification_item;
+DROP TABLE IF EXISTS certification_campaign;
+-- +goose StatementEnd
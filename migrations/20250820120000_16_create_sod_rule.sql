@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+-- правило разделения обязанностей (SoD): сотрудник может обладать не более чем одной из ролей правила,
+-- в том числе через наследование ролей
+CREATE TABLE IF NOT EXISTS sod_rule (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name TEXT NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS sod_rule_name_idx ON sod_rule (lower(name));
+
+CREATE TRIGGER sod_rule_set_updated_at
+    BEFORE UPDATE ON sod_rule
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- взаимоисключающие роли правила; пара ролей - правило из двух ролей
+CREATE TABLE IF NOT EXISTS sod_rule_role (
+    rule_id BIGINT NOT NULL REFERENCES sod_rule(id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+    PRIMARY KEY (rule_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS sod_rule_role_role_id_idx ON sod_rule_role (role_id);
+
+-- согласованное администратором исключение: до expires_at сотрудник может обладать ролями правила одновременно.
+-- Отозванное исключение (revoked_at) остаётся в истории
+CREATE TABLE IF NOT EXISTS sod_exception (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    rule_id BIGINT NOT NULL REFERENCES sod_rule(id) ON DELETE CASCADE,
+    employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    justification TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    approved_by_sub TEXT,
+    approved_by_username TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS sod_exception_rule_employee_idx ON sod_exception (rule_id, employee_id)
+    WHERE revoked_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sod_exception;
+DROP TABLE IF EXISTS sod_rule_role;
+DROP TABLE IF EXISTS sod_rule;
+-- +goose StatementEnd
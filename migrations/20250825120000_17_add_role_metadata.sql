@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- владелец роли: согласует запросы доступа (шаг role_owner) и пересматривает назначения роли в кампаниях
+ALTER TABLE role ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES employee(id) ON DELETE SET NULL;
+ALTER TABLE role ADD COLUMN IF NOT EXISTS risk_level TEXT NOT NULL DEFAULT 'low'
+    CHECK (risk_level IN ('low', 'medium', 'high', 'critical'));
+-- категория или приложение, к которому относится роль
+ALTER TABLE role ADD COLUMN IF NOT EXISTS category TEXT;
+-- произвольные метки: объект из строковых ключей и значений
+ALTER TABLE role ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS role_owner_id_idx ON role (owner_id);
+CREATE INDEX IF NOT EXISTS role_risk_level_idx ON role (risk_level);
+CREATE INDEX IF NOT EXISTS role_category_idx ON role (lower(category));
+CREATE INDEX IF NOT EXISTS role_labels_idx ON role USING GIN (labels jsonb_path_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS role_labels_idx;
+DROP INDEX IF EXISTS role_category_idx;
+DROP INDEX IF EXISTS role_risk_level_idx;
+DROP INDEX IF EXISTS role_owner_id_idx;
+ALTER TABLE role DROP COLUMN IF EXISTS labels;
+ALTER TABLE role DROP COLUMN IF EXISTS category;
+ALTER TABLE role DROP COLUMN IF EXISTS risk_level;
+ALTER TABLE role DROP COLUMN IF EXISTS owner_id;
+-- +goose StatementEnd
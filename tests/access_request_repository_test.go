@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"idm/inner/accessrequest"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessRequestRepository(t *testing.T) {
+	repo := accessrequest.NewAccessRequestRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	var roleId, managerId, employeeId int64
+	require.NoError(t, DB.Get(&roleId, "INSERT INTO role (name) VALUES ('Reports') RETURNING id"))
+	require.NoError(t, DB.Get(&managerId,
+		"INSERT INTO employee (name, email) VALUES ('Boss', 'boss@example.com') RETURNING id"))
+	require.NoError(t, DB.Get(&employeeId,
+		"INSERT INTO employee (name, email, manager_id) VALUES ('John', 'john@example.com', $1) RETURNING id", managerId))
+
+	create := func(t *testing.T, expiresAt time.Time) int64 {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		id, err := repo.CreateTx(ctx, tx, accessrequest.Entity{
+			EmployeeId:    employeeId,
+			RoleId:        roleId,
+			Justification: "Need access",
+			Steps:         accessrequest.DefaultSteps,
+			CreatedBySub:  ptr("sub-john"),
+			ExpiresAt:     expiresAt,
+		})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		return id
+	}
+
+	t.Run("Employee is found by email", func(t *testing.T) {
+		id, err := repo.FindEmployeeIdByEmail(ctx, "JOHN@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, employeeId, id)
+
+		_, err = repo.FindEmployeeIdByEmail(ctx, "nobody@example.com")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("Only one pending request per role", func(t *testing.T) {
+		id := create(t, time.Now().Add(time.Hour))
+
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		_, err = repo.CreateTx(ctx, tx, accessrequest.Entity{
+			EmployeeId:    employeeId,
+			RoleId:        roleId,
+			Justification: "Need access again",
+			Steps:         accessrequest.DefaultSteps,
+			ExpiresAt:     time.Now().Add(time.Hour),
+		})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		request, err := repo.FindById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "John", request.EmployeeName)
+		assert.Equal(t, "Reports", request.RoleName)
+		assert.Equal(t, managerId, *request.ManagerId)
+		assert.Equal(t, accessrequest.StatusPending, request.Status)
+	})
+
+	t.Run("Pending approvals of the manager and administrators", func(t *testing.T) {
+		approvals, err := repo.FindPendingApprovals(ctx, "sub-boss", managerId, false)
+		require.NoError(t, err)
+		assert.Len(t, approvals, 1)
+
+		// шаг manager решает руководитель, у администратора его нет
+		approvals, err = repo.FindPendingApprovals(ctx, "sub-admin", 0, true)
+		require.NoError(t, err)
+		assert.Empty(t, approvals)
+
+		// свой запрос не согласуется
+		approvals, err = repo.FindPendingApprovals(ctx, "sub-john", employeeId, true)
+		require.NoError(t, err)
+		assert.Empty(t, approvals)
+
+		mine, err := repo.FindMine(ctx, "sub-other", employeeId, accessrequest.StatusPending)
+		require.NoError(t, err)
+		assert.Len(t, mine, 1)
+	})
+
+	t.Run("Pending approvals of the role owner", func(t *testing.T) {
+		var ownerId int64
+		require.NoError(t, DB.Get(&ownerId,
+			"INSERT INTO employee (name, email) VALUES ('Ann', 'ann@example.com') RETURNING id"))
+		_, err := DB.Exec("UPDATE access_request SET steps = '{role_owner}', current_step = 0")
+		require.NoError(t, err)
+
+		// без владельца шаг role_owner решают администраторы
+		approvals, err := repo.FindPendingApprovals(ctx, "sub-admin", 0, true)
+		require.NoError(t, err)
+		assert.Len(t, approvals, 1)
+
+		_, err = DB.Exec("UPDATE role SET owner_id = $1 WHERE id = $2", ownerId, roleId)
+		require.NoError(t, err)
+		approvals, err = repo.FindPendingApprovals(ctx, "sub-ann", ownerId, false)
+		require.NoError(t, err)
+		require.Len(t, approvals, 1)
+		assert.Equal(t, ownerId, *approvals[0].RoleOwnerId)
+
+		approvals, err = repo.FindPendingApprovals(ctx, "sub-admin", 0, true)
+		require.NoError(t, err)
+		assert.Empty(t, approvals)
+		approvals, err = repo.FindPendingApprovals(ctx, "sub-boss", managerId, false)
+		require.NoError(t, err)
+		assert.Empty(t, approvals)
+	})
+
+	t.Run("Stale requests expire with an event", func(t *testing.T) {
+		_, err := DB.Exec("DELETE FROM access_request")
+		require.NoError(t, err)
+		stale := create(t, time.Now().Add(-time.Minute))
+
+		expired, err := repo.ExpireStale(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{stale}, expired)
+
+		request, err := repo.FindById(ctx, stale)
+		require.NoError(t, err)
+		assert.Equal(t, accessrequest.StatusExpired, request.Status)
+		assert.NotNil(t, request.DecidedAt)
+
+		events, err := repo.FindEvents(ctx, stale)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, accessrequest.ActionExpire, events[0].Action)
+		assert.Equal(t, accessrequest.StepManager, *events[0].Step)
+	})
+
+	t.Run("Policy is saved, replaced and deleted", func(t *testing.T) {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		_, err = repo.SavePolicyTx(ctx, tx, roleId, []string{accessrequest.StepAdmin})
+		require.NoError(t, err)
+		policy, err := repo.SavePolicyTx(ctx, tx, roleId, []string{accessrequest.StepManager, accessrequest.StepRoleOwner})
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		assert.Equal(t, []string{accessrequest.StepManager, accessrequest.StepRoleOwner}, []string(policy.Steps))
+
+		deleted, err := repo.DeletePolicy(ctx, roleId)
+		require.NoError(t, err)
+		assert.True(t, deleted)
+
+		deleted, err = repo.DeletePolicy(ctx, roleId)
+		require.NoError(t, err)
+		assert.False(t, deleted)
+	})
+}
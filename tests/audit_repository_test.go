@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idm/inner/audit"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepository_SaveAndFilter(t *testing.T) {
+	repo := audit.NewAuditRepository(DB)
+
+	clearTables()
+
+	admin := "admin"
+	before := `{"name":"Guest"}`
+	events := []audit.Entity{
+		{ActorUsername: &admin, Action: audit.ActionCreate, EntityType: audit.EntityRole, EntityId: ptr(int64(1))},
+		{ActorUsername: &admin, Action: audit.ActionUpdate, EntityType: audit.EntityRole, EntityId: ptr(int64(1)), Before: &before},
+		{Action: audit.ActionDelete, EntityType: audit.EntityEmployee, EntityId: ptr(int64(7))},
+	}
+
+	tx, err := DB.Beginx()
+	require.NoError(t, err)
+	for _, event := range events {
+		require.NoError(t, repo.SaveTx(context.Background(), tx, event))
+	}
+	require.NoError(t, tx.Commit())
+
+	t.Run("By actor", func(t *testing.T) {
+		filter := audit.FilterRequest{Actor: "admin"}
+		found, err := repo.FindWithFilter(context.Background(), filter, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, found, 2)
+		// сначала новые события
+		assert.Equal(t, audit.ActionUpdate, found[0].Action)
+		assert.JSONEq(t, before, *found[0].Before)
+
+		count, err := repo.CountWithFilter(context.Background(), filter)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("By entity and period", func(t *testing.T) {
+		from := time.Now().Add(-time.Hour)
+		filter := audit.FilterRequest{EntityType: audit.EntityEmployee, EntityId: 7, From: &from}
+		found, err := repo.FindWithFilter(context.Background(), filter, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, found, 1)
+
+		to := time.Now().Add(-time.Hour)
+		count, err := repo.CountWithFilter(context.Background(), audit.FilterRequest{To: &to})
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idm/inner/certification"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificationRepository(t *testing.T) {
+	repo := certification.NewCertificationRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	var financeRoleId, salesRoleId, rootId, childId, otherId, managerId, johnId, mikeId, annId int64
+	require.NoError(t, DB.Get(&financeRoleId, "INSERT INTO role (name) VALUES ('Finance') RETURNING id"))
+	require.NoError(t, DB.Get(&salesRoleId, "INSERT INTO role (name) VALUES ('Sales') RETURNING id"))
+	require.NoError(t, DB.Get(&rootId, "INSERT INTO department (name) VALUES ('Head office') RETURNING id"))
+	require.NoError(t, DB.Get(&childId,
+		"INSERT INTO department (name, parent_id) VALUES ('Accounting', $1) RETURNING id", rootId))
+	require.NoError(t, DB.Get(&otherId, "INSERT INTO department (name) VALUES ('Warehouse') RETURNING id"))
+	require.NoError(t, DB.Get(&managerId,
+		"INSERT INTO employee (name, email, department_id) VALUES ('Boss', 'boss@example.com', $1) RETURNING id", rootId))
+	require.NoError(t, DB.Get(&johnId,
+		`INSERT INTO employee (name, email, manager_id, department_id)
+		VALUES ('John', 'john@example.com', $1, $2) RETURNING id`, managerId, childId))
+	require.NoError(t, DB.Get(&mikeId,
+		"INSERT INTO employee (name, email, department_id) VALUES ('Mike', 'mike@example.com', $1) RETURNING id", otherId))
+	require.NoError(t, DB.Get(&annId,
+		`INSERT INTO employee (name, email, manager_id, department_id)
+		VALUES ('Ann', 'ann@example.com', $1, $2) RETURNING id`, managerId, childId))
+
+	var johnFinanceId int64
+	require.NoError(t, DB.Get(&johnFinanceId,
+		"INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2) RETURNING id", johnId, financeRoleId))
+	_, err := DB.Exec("INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2), ($3, $2), ($4, $5)",
+		managerId, financeRoleId, mikeId, johnId, salesRoleId)
+	require.NoError(t, err)
+	// истёкшее и будущее назначения в кампанию не попадают
+	_, err = DB.Exec(
+		`INSERT INTO employee_role (employee_id, role_id, valid_from, valid_to)
+		VALUES ($1, $2, now() - interval '2 days', now() - interval '1 day'), ($1, $2, now() + interval '1 day', NULL)`,
+		annId, financeRoleId)
+	require.NoError(t, err)
+
+	launch := func(t *testing.T, campaign certification.CampaignEntity) (int64, int64) {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		campaign.Id, err = repo.CreateCampaignTx(ctx, tx, campaign)
+		require.NoError(t, err)
+		count, err := repo.SnapshotItemsTx(ctx, tx, campaign)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		return campaign.Id, count
+	}
+
+	campaignId, count := launch(t, certification.CampaignEntity{
+		Name:          "Finance review",
+		RoleIds:       []int64{financeRoleId},
+		DepartmentIds: []int64{rootId},
+		Reviewer:      certification.ReviewerManager,
+		AutoAction:    certification.AutoActionRevoke,
+		Deadline:      time.Now().Add(time.Hour),
+	})
+
+	t.Run("Snapshot covers active assignments of the department subtree", func(t *testing.T) {
+		assert.Equal(t, int64(2), count)
+
+		items, err := repo.FindItems(ctx, campaignId, "")
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		assert.Equal(t, johnFinanceId, *items[0].AssignmentId)
+		assert.Equal(t, "John", items[0].EmployeeName)
+		assert.Equal(t, "Finance", items[0].RoleName)
+		assert.Equal(t, managerId, *items[0].ReviewerEmployeeId)
+		assert.Equal(t, "Boss", *items[0].ReviewerName)
+		// у руководителя нет своего руководителя, его назначение пересматривают администраторы
+		assert.Equal(t, managerId, items[1].EmployeeId)
+		assert.Nil(t, items[1].ReviewerEmployeeId)
+
+		campaign, err := repo.FindCampaignById(ctx, campaignId)
+		require.NoError(t, err)
+		assert.Equal(t, 2, campaign.ItemCount)
+		assert.Equal(t, certification.StatusActive, campaign.Status)
+	})
+
+	t.Run("Empty scope covers all active assignments", func(t *testing.T) {
+		// назначения Finance пересматривает её владелец, кроме его собственного
+		_, err := DB.Exec("UPDATE role SET owner_id = $1 WHERE id = $2", managerId, financeRoleId)
+		require.NoError(t, err)
+		defer func() {
+			_, _ = DB.Exec("UPDATE role SET owner_id = NULL")
+		}()
+
+		id, count := launch(t, certification.CampaignEntity{
+			Name:          "Full review",
+			RoleIds:       []int64{},
+			DepartmentIds: []int64{},
+			Reviewer:      certification.ReviewerRoleOwner,
+			AutoAction:    certification.AutoActionCertify,
+			Deadline:      time.Now().Add(time.Hour),
+		})
+		assert.Equal(t, int64(4), count)
+
+		items, err := repo.FindItems(ctx, id, certification.DecisionPending)
+		require.NoError(t, err)
+		for _, item := range items {
+			if item.RoleId == financeRoleId && item.EmployeeId != managerId {
+				assert.Equal(t, managerId, *item.ReviewerEmployeeId)
+			} else {
+				assert.Nil(t, item.ReviewerEmployeeId)
+			}
+		}
+
+		cancelled, err := repo.CancelCampaign(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, cancelled)
+		cancelled, err = repo.CancelCampaign(ctx, id)
+		require.NoError(t, err)
+		assert.False(t, cancelled)
+	})
+
+	t.Run("Reviews of the manager and administrators", func(t *testing.T) {
+		items, err := repo.FindReviewItems(ctx, managerId, false, "")
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, johnId, items[0].EmployeeId)
+
+		// свои назначения администратор не пересматривает
+		items, err = repo.FindReviewItems(ctx, managerId, true, "")
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+
+		items, err = repo.FindReviewItems(ctx, johnId, true, "")
+		require.NoError(t, err)
+		assert.Len(t, items, 1)
+		assert.Equal(t, managerId, items[0].EmployeeId)
+	})
+
+	t.Run("Decisions are counted and the campaign completed", func(t *testing.T) {
+		items, err := repo.FindPendingItemIds(ctx, campaignId)
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+
+		completed, err := repo.CompleteCampaign(ctx, campaignId)
+		require.NoError(t, err)
+		assert.False(t, completed)
+
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		item, err := repo.FindItemForUpdateTx(ctx, tx, items[0])
+		require.NoError(t, err)
+		item.Decision, item.DecidedBySub = certification.DecisionCertified, ptr("sub-boss")
+		require.NoError(t, repo.DecideItemTx(ctx, tx, item))
+		item, err = repo.FindItemForUpdateTx(ctx, tx, items[1])
+		require.NoError(t, err)
+		item.Decision, item.Auto = certification.DecisionRevoked, true
+		require.NoError(t, repo.DecideItemTx(ctx, tx, item))
+		require.NoError(t, tx.Commit())
+
+		counts, err := repo.FindReviewerSummaries(ctx, campaignId)
+		require.NoError(t, err)
+		require.Len(t, counts, 2)
+		assert.Equal(t, managerId, *counts[0].ReviewerEmployeeId)
+		assert.Equal(t, 1, counts[0].Certified)
+		assert.Equal(t, 1, counts[1].Revoked)
+
+		completed, err = repo.CompleteCampaign(ctx, campaignId)
+		require.NoError(t, err)
+		assert.True(t, completed)
+
+		campaign, err := repo.FindCampaignById(ctx, campaignId)
+		require.NoError(t, err)
+		assert.Equal(t, certification.StatusCompleted, campaign.Status)
+		assert.NotNil(t, campaign.CompletedAt)
+	})
+
+	t.Run("Due campaigns", func(t *testing.T) {
+		id, _ := launch(t, certification.CampaignEntity{
+			Name:          "Overdue review",
+			RoleIds:       []int64{salesRoleId},
+			DepartmentIds: []int64{},
+			Reviewer:      certification.ReviewerManager,
+			AutoAction:    certification.AutoActionRevoke,
+			Deadline:      time.Now().Add(time.Hour),
+		})
+		_, err := DB.Exec("UPDATE certification_campaign SET deadline = now() - interval '1 minute' WHERE id = $1", id)
+		require.NoError(t, err)
+
+		ids, err := repo.FindDueCampaignIds(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{id}, ids)
+	})
+}
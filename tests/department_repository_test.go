@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"idm/inner/department"
+	"idm/inner/employee"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// создаёт отдел напрямую в базе и возвращает его id
+func createTestDepartment(t *testing.T, name string, parentId *int64) int64 {
+	var id int64
+	err := DB.Get(&id, "INSERT INTO department (name, parent_id) VALUES ($1, $2) RETURNING id", name, parentId)
+	require.NoError(t, err)
+	return id
+}
+
+func TestDepartmentRepository_CRUD(t *testing.T) {
+	repo := department.NewDepartmentRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	tx, err := repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	created, err := repo.SaveTx(ctx, tx, department.Entity{Name: "Engineering"})
+	require.NoError(t, err)
+	assert.NotZero(t, created.Id)
+
+	// имена уникальны без учёта регистра, сам отдел при переименовании не мешает
+	taken, err := repo.FindByNameTx(ctx, tx, "ENGINEERING", 0)
+	require.NoError(t, err)
+	assert.True(t, taken)
+	taken, err = repo.FindByNameTx(ctx, tx, "engineering", created.Id)
+	require.NoError(t, err)
+	assert.False(t, taken)
+
+	created.Name = "R&D"
+	updated, err := repo.UpdateTx(ctx, tx, created)
+	require.NoError(t, err)
+	assert.Equal(t, "R&D", updated.Name)
+	require.NoError(t, tx.Commit())
+
+	found, err := repo.FindById(ctx, created.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "R&D", found.Name)
+
+	tx, err = repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTx(ctx, tx, created.Id))
+	exists, err := repo.ExistsTx(ctx, tx, created.Id)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, tx.Commit())
+}
+
+func TestDepartmentRepository_Hierarchy(t *testing.T) {
+	repo := department.NewDepartmentRepository(DB)
+	employeeRepo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	// Company <- Engineering <- Backend
+	company := createTestDepartment(t, "Company", nil)
+	engineering := createTestDepartment(t, "Engineering", &company)
+	backend := createTestDepartment(t, "Backend", &engineering)
+	sales := createTestDepartment(t, "Sales", nil)
+
+	t.Run("FindDescendants", func(t *testing.T) {
+		descendants, err := repo.FindDescendants(ctx, company)
+		require.NoError(t, err)
+		require.Len(t, descendants, 2)
+		assert.Equal(t, engineering, descendants[0].Id)
+		assert.Equal(t, 1, descendants[0].Depth)
+		assert.Equal(t, backend, descendants[1].Id)
+		assert.Equal(t, 2, descendants[1].Depth)
+	})
+
+	t.Run("IsInParentChainTx", func(t *testing.T) {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+
+		inChain, err := repo.IsInParentChainTx(ctx, tx, backend, company)
+		require.NoError(t, err)
+		assert.True(t, inChain)
+		inChain, err = repo.IsInParentChainTx(ctx, tx, company, backend)
+		require.NoError(t, err)
+		assert.False(t, inChain)
+	})
+
+	t.Run("Employees filtered by department subtree", func(t *testing.T) {
+		add := func(name string, departmentId int64) {
+			emp := &employee.Entity{
+				Name: name, Email: strings.ToLower(name) + "@example.com", DepartmentId: departmentId,
+			}
+			require.NoError(t, employeeRepo.Add(ctx, emp))
+		}
+		add("Alice", engineering)
+		add("Bob", backend)
+		add("Carol", sales)
+
+		employees, err := employeeRepo.FindWithPagination(ctx, 10, 0, employee.PageRequest{DepartmentIds: []int64{engineering}})
+		require.NoError(t, err)
+		require.Len(t, employees, 2)
+		assert.Equal(t, "Engineering", employees[0].Department)
+		assert.Equal(t, "Backend", employees[1].Department)
+
+		count, err := employeeRepo.CountWithFilter(ctx, employee.PageRequest{DepartmentIds: []int64{company, sales}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("Dependents", func(t *testing.T) {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+
+		childIds, err := repo.FindChildIdsTx(ctx, tx, company)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{engineering}, childIds)
+		employeeIds, err := repo.FindEmployeeIdsTx(ctx, tx, engineering)
+		require.NoError(t, err)
+		assert.Len(t, employeeIds, 1)
+	})
+}
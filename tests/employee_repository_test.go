@@ -7,11 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"idm/inner/audit"
 	"idm/inner/common"
 	"idm/inner/employee"
+	"idm/inner/role"
 	"idm/inner/testutils"
 	val "idm/inner/validator"
 	"idm/inner/web"
@@ -29,7 +32,9 @@ func appLaunchKit() *fiber.App {
 	validator := val.New()
 
 	repo := employee.NewEmployeeRepository(DB)
-	service := employee.NewService(repo, validator, logger)
+	auditService := audit.NewService(audit.NewAuditRepository(DB), validator, logger)
+	roleService := role.NewService(role.NewRoleRepository(DB), auditService, validator, logger)
+	service := employee.NewService(repo, auditService, roleService, validator, logger)
 	controller := employee.NewController(server, service, logger)
 
 	app := server.App
@@ -74,22 +79,23 @@ func TestEmployeeRepository_CRUD(t *testing.T) {
 	var roleID int64 = 1
 	err := DB.QueryRow(`INSERT INTO role (name) VALUES ($1) RETURNING id`, "Test Role").Scan(&roleID)
 	assert.NoError(t, err)
+	departmentId := createTestDepartment(t, "IT", nil)
 
 	// Создаем сотрудника
 	emp := &employee.Entity{
-		Name:       "John Doe",
-		Email:      "john@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     roleID,
+		Name:         "John Doe",
+		Email:        "john@example.com",
+		PositionId:   createTestPosition(t, "Developer"),
+		DepartmentId: departmentId,
+		RoleId:       roleID,
 	}
 
 	emp2 := &employee.Entity{
-		Name:       "Rick Sanchez",
-		Email:      "rick@example.com",
-		Position:   "Manager",
-		Department: "IT",
-		RoleId:     roleID,
+		Name:         "Rick Sanchez",
+		Email:        "rick@example.com",
+		PositionId:   createTestPosition(t, "Manager"),
+		DepartmentId: departmentId,
+		RoleId:       roleID,
 	}
 
 	t.Run("Add", func(t *testing.T) {
@@ -109,8 +115,8 @@ func TestEmployeeRepository_CRUD(t *testing.T) {
 		assert.Equal(t, emp.Email, found.Email)
 	})
 
-	t.Run("FindAll", func(t *testing.T) {
-		employees, err := repo.FindAll(context.Background())
+	t.Run("FindWithCursor", func(t *testing.T) {
+		employees, err := repo.FindWithCursor(context.Background(), "", nil, 10)
 		assert.NoError(t, err)
 		assert.Len(t, employees, 2)
 		assert.Equal(t, emp.Email, employees[0].Email)
@@ -166,11 +172,11 @@ func TestAddWithTransaction_Success(t *testing.T) {
 	assert.NoError(t, err)
 
 	empl := &employee.Entity{
-		Name:       "John Doe",
-		Email:      "john@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     roleID,
+		Name:         "John Doe",
+		Email:        "john@example.com",
+		PositionId:   createTestPosition(t, "Developer"),
+		DepartmentId: createTestDepartment(t, "IT", nil),
+		RoleId:       roleID,
 	}
 
 	// Execute AddWithTransaction
@@ -212,26 +218,24 @@ func TestAddWithTransaction_Failure(t *testing.T) {
 
 	// Insert original employee inside the transaction
 	existingEmp := &employee.Entity{
-		Name:       "Jane Smith",
-		Email:      "jane@example.com",
-		Position:   "Manager",
-		Department: "HR",
-		RoleId:     roleID,
+		Name:         "Jane Smith",
+		Email:        "jane@example.com",
+		PositionId:   createTestPosition(t, "Manager"),
+		DepartmentId: createTestDepartment(t, "HR", nil),
+		RoleId:       roleID,
 	}
 
-	_, err = tx.Exec("INSERT INTO employee (name, email, position, department, role_id) VALUES ($1, $2, $3, $4, $5)",
-		existingEmp.Name, existingEmp.Email, existingEmp.Position, existingEmp.Department, existingEmp.RoleId)
+	_, err = tx.Exec("INSERT INTO employee (name, email, position_id, department_id, role_id) VALUES ($1, $2, $3, $4, $5)",
+		existingEmp.Name, existingEmp.Email, existingEmp.PositionId, existingEmp.DepartmentId, existingEmp.RoleId)
 	if err != nil {
 		t.Fatalf("Failed to insert existing employee in transaction: %v", err)
 	}
 
 	// Try to insert duplicate employee inside the same transaction
 	duplicateEmp := &employee.Entity{
-		Name:       "Jane Smith", // duplicate
-		Email:      "jane2@example.com",
-		Position:   "Sale_Manager",
-		Department: "Sales",
-		RoleId:     3,
+		Name:   "Jane Smith", // duplicate
+		Email:  "jane2@example.com",
+		RoleId: 3,
 	}
 
 	err = repo.AddWithTransaction(context.Background(), tx, duplicateEmp)
@@ -282,16 +286,16 @@ func TestFindByNameTx_Exists(t *testing.T) {
 	assert.NoError(t, err)
 
 	empl := &employee.Entity{
-		Name:       "John Doe",
-		Email:      "john@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     roleID,
+		Name:         "John Doe",
+		Email:        "john@example.com",
+		PositionId:   createTestPosition(t, "Developer"),
+		DepartmentId: createTestDepartment(t, "IT", nil),
+		RoleId:       roleID,
 	}
 
 	// Insert a test entity
-	_, err = tx.Exec("INSERT INTO employee (name, email, position, department, role_id) VALUES ($1, $2, $3, $4, $5)",
-		empl.Name, empl.Email, empl.Position, empl.Department, empl.RoleId)
+	_, err = tx.Exec("INSERT INTO employee (name, email, position_id, department_id, role_id) VALUES ($1, $2, $3, $4, $5)",
+		empl.Name, empl.Email, empl.PositionId, empl.DepartmentId, empl.RoleId)
 	assert.NoError(t, err)
 
 	exists, err := repo.FindByNameTx(context.Background(), tx, "John Doe")
@@ -329,11 +333,9 @@ func TestSaveTx(t *testing.T) {
 	assert.NoError(t, err)
 
 	employee := &employee.Entity{
-		Name:       "John Doe",
-		Email:      "john@example.com",
-		Position:   "Developer",
-		Department: "IT",
-		RoleId:     roleID,
+		Name:   "John Doe",
+		Email:  "john@example.com",
+		RoleId: roleID,
 	}
 
 	id, err := repo.SaveTx(context.Background(), tx, *employee)
@@ -955,8 +957,14 @@ func createTestEmployees(t *testing.T, count int) []int64 {
 	require.NoError(t, err)
 	require.NotEmpty(t, roleIDs, "Should have at least one active role")
 
-	departments := []string{"IT", "HR", "Finance", "Marketing", "Operations"}
-	positions := []string{"Developer", "Analyst", "Manager", "Specialist", "Coordinator"}
+	var departmentIds []int64
+	for _, name := range []string{"IT", "HR", "Finance", "Marketing", "Operations"} {
+		departmentIds = append(departmentIds, createTestDepartment(t, name, nil))
+	}
+	var positionIds []int64
+	for _, name := range []string{"Developer", "Analyst", "Manager", "Specialist", "Coordinator"} {
+		positionIds = append(positionIds, createTestPosition(t, name))
+	}
 
 	var employeeIDs []int64
 
@@ -972,20 +980,20 @@ func createTestEmployees(t *testing.T, count int) []int64 {
 		employee := struct {
 			name       string
 			email      string
-			position   string
-			department string
+			position   int64
+			department int64
 			roleID     int64
 		}{
 			// генерация реалистичных данных для тестовых сотрудников
 			name:       fmt.Sprintf("%s %s", firstName, fake.LastName()),
 			email:      fake.EmailAddress(),
-			position:   positions[i%len(positions)],
-			department: departments[i%len(departments)],
+			position:   positionIds[i%len(positionIds)],
+			department: departmentIds[i%len(departmentIds)],
 			roleID:     roleIDs[i%len(roleIDs)],
 		}
 
 		var employeeID int64
-		query := `INSERT INTO employee (name, email, position, department, role_id) 
+		query := `INSERT INTO employee (name, email, position_id, department_id, role_id) 
 				  VALUES ($1, $2, $3, $4, $5) RETURNING id`
 		err := DB.Get(&employeeID, query, employee.name, employee.email, employee.position, employee.department, employee.roleID)
 		require.NoError(t, err)
@@ -1006,12 +1014,578 @@ type PageResponse struct {
 
 // Response структура для десериализации данных сотрудника
 type Response struct {
-	Id         int64     `json:"id"`
-	Name       string    `json:"name"`
-	Email      string    `json:"email"`
-	Position   string    `json:"position"`
-	Department string    `json:"department"`
-	RoleId     int64     `json:"role_id"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Id           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	PositionId   int64     `json:"position_id"`
+	Position     string    `json:"position"`
+	DepartmentId int64     `json:"department_id"`
+	Department   string    `json:"department"`
+	RoleId       int64     `json:"role_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func TestEmployeeRepository_RoleAssignments(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	var developerRoleId, auditorRoleId int64
+	require.NoError(t, DB.QueryRow(`INSERT INTO role (name) VALUES ($1) RETURNING id`, "Developer").Scan(&developerRoleId))
+	require.NoError(t, DB.QueryRow(`INSERT INTO role (name) VALUES ($1) RETURNING id`, "Auditor").Scan(&auditorRoleId))
+
+	emp := &employee.Entity{
+		Name:   "John Doe",
+		Email:  "john.doe@example.com",
+		RoleId: developerRoleId,
+	}
+	require.NoError(t, repo.Add(ctx, emp))
+
+	// начальная роль при создании становится назначением
+	assignments, err := repo.FindRoleAssignments(ctx, emp.Id, true)
+	require.NoError(t, err)
+	require.Len(t, assignments, 1)
+	assert.Equal(t, developerRoleId, assignments[0].RoleId)
+	assert.Equal(t, "Developer", assignments[0].RoleName)
+
+	tx, err := DB.Beginx()
+	require.NoError(t, err)
+
+	overlaps, err := repo.HasOverlappingAssignmentTx(ctx, tx, emp.Id, developerRoleId, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, overlaps)
+
+	auditor, err := repo.AssignRoleTx(ctx, tx, emp.Id, auditorRoleId, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, auditor.Active)
+
+	future := time.Now().Add(24 * time.Hour)
+	temporary, err := repo.AssignRoleTx(ctx, tx, emp.Id, developerRoleId, &future, nil)
+	require.NoError(t, err)
+	assert.False(t, temporary.Active)
+	require.NoError(t, repo.SyncLegacyRoleIdTx(ctx, tx, emp.Id))
+	require.NoError(t, tx.Commit())
+
+	found, err := repo.FindById(ctx, emp.Id)
+	require.NoError(t, err)
+	// role_id - последняя начавшаяся активная роль
+	assert.Equal(t, auditorRoleId, found.RoleId)
+
+	active, err := repo.FindRoleAssignments(ctx, emp.Id, true)
+	require.NoError(t, err)
+	assert.Len(t, active, 2)
+
+	all, err := repo.FindRoleAssignments(ctx, emp.Id, false)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	tx, err = DB.Beginx()
+	require.NoError(t, err)
+	// действующее назначение закрывается, будущее - удаляется
+	revoked, err := repo.RevokeRoleAssignmentTx(ctx, tx, emp.Id, auditor.Id)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = repo.RevokeRoleAssignmentTx(ctx, tx, emp.Id, temporary.Id)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	require.NoError(t, tx.Commit())
+
+	found, err = repo.FindById(ctx, emp.Id)
+	require.NoError(t, err)
+	assert.Equal(t, developerRoleId, found.RoleId)
+
+	all, err = repo.FindRoleAssignments(ctx, emp.Id, false)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestEmployeeRepository_SoftDelete(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	emp := &employee.Entity{Name: "John Doe", Email: "john.doe@example.com"}
+	require.NoError(t, repo.Add(ctx, emp))
+	require.NoError(t, repo.DeleteById(ctx, emp.Id))
+
+	// удалённый сотрудник не виден в выборках и не занимает email
+	_, err := repo.FindById(ctx, emp.Id)
+	assert.Error(t, err)
+	count, err := repo.CountAll(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	tx, err := DB.Beginx()
+	require.NoError(t, err)
+	emailTaken, err := repo.EmailExistsTx(ctx, tx, emp.Email)
+	require.NoError(t, err)
+	assert.False(t, emailTaken)
+
+	deleted, err := repo.FindDeletedByIdForUpdateTx(ctx, tx, emp.Id)
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+
+	restored, err := repo.RestoreTx(ctx, tx, emp.Id)
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	require.NoError(t, tx.Commit())
+
+	found, err := repo.FindById(ctx, emp.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", found.Name)
+
+	// очищаются только строки, удалённые раньше срока хранения
+	require.NoError(t, repo.DeleteById(ctx, emp.Id))
+	tx, err = DB.Beginx()
+	require.NoError(t, err)
+	purged, err := repo.PurgeDeletedTx(ctx, tx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+	purged, err = repo.PurgeDeletedTx(ctx, tx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, purged, 1)
+	assert.Equal(t, emp.Id, purged[0].Id)
+	require.NoError(t, tx.Commit())
+
+	var rows int
+	require.NoError(t, DB.Get(&rows, "SELECT COUNT(*) FROM employee"))
+	assert.Zero(t, rows)
+}
+
+func TestEmployeeRepository_Lifecycle(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	var roleId int64
+	require.NoError(t, DB.Get(&roleId, "INSERT INTO role (name) VALUES ('Developer') RETURNING id"))
+
+	startDate := time.Now().AddDate(0, 0, 14).UTC().Truncate(24 * time.Hour)
+	emp := &employee.Entity{
+		Name: "John Doe", Email: "john.doe@example.com",
+		RoleId: roleId, Status: employee.StatusPending, StartDate: &startDate,
+	}
+	require.NoError(t, repo.Add(ctx, emp))
+
+	found, err := repo.FindById(ctx, emp.Id)
+	require.NoError(t, err)
+	assert.Equal(t, employee.StatusPending, found.Status)
+	require.NotNil(t, found.StartDate)
+	assert.True(t, startDate.Equal(found.StartDate.UTC()))
+
+	tx, err := DB.Beginx()
+	require.NoError(t, err)
+	found.Status = employee.StatusTerminated
+	updated, err := repo.UpdateLifecycleTx(ctx, tx, found)
+	require.NoError(t, err)
+	assert.Equal(t, employee.StatusTerminated, updated.Status)
+
+	// увольнение закрывает действующие назначения
+	revoked, err := repo.RevokeAllAssignmentsTx(ctx, tx, emp.Id)
+	require.NoError(t, err)
+	assert.Len(t, revoked, 1)
+	require.NoError(t, repo.SyncLegacyRoleIdTx(ctx, tx, emp.Id))
+
+	reason := "Resigned"
+	from := employee.StatusPending
+	require.NoError(t, repo.SaveStatusHistoryTx(ctx, tx, employee.StatusHistoryEntity{
+		EmployeeId: emp.Id,
+		Transition: employee.TransitionTerminate,
+		FromStatus: &from,
+		ToStatus:   employee.StatusTerminated,
+		Reason:     &reason,
+	}))
+	require.NoError(t, tx.Commit())
+
+	found, err = repo.FindById(ctx, emp.Id)
+	require.NoError(t, err)
+	assert.Zero(t, found.RoleId)
+
+	history, err := repo.FindStatusHistory(ctx, emp.Id)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, employee.StatusTerminated, history[0].ToStatus)
+	assert.Equal(t, "Resigned", *history[0].Reason)
+}
+
+func TestEmployeeRepository_ScheduledChanges(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	emp := &employee.Entity{Name: "John Doe", Email: "john.doe@example.com"}
+	require.NoError(t, repo.Add(ctx, emp))
+
+	departmentId := createTestDepartment(t, "Sales", nil)
+	reason := "Promotion"
+	tx, err := DB.Beginx()
+	require.NoError(t, err)
+	due, err := repo.SaveScheduledChangeTx(ctx, tx, employee.ScheduledChangeEntity{
+		EmployeeId: emp.Id, DepartmentId: &departmentId, Reason: &reason, EffectiveAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, employee.ChangeStatusPending, due.Status)
+	_, err = repo.SaveScheduledChangeTx(ctx, tx, employee.ScheduledChangeEntity{
+		EmployeeId: emp.Id, DepartmentId: &departmentId, Reason: &reason, EffectiveAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	// наступило только первое изменение
+	ids, err := repo.FindDueScheduledChangeIds(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{due.Id}, ids)
+
+	tx, err = DB.Beginx()
+	require.NoError(t, err)
+	locked, err := repo.LockDueScheduledChangeTx(ctx, tx, due.Id)
+	require.NoError(t, err)
+	now := time.Now()
+	locked.Status = employee.ChangeStatusApplied
+	locked.AppliedAt = &now
+	_, err = repo.UpdateScheduledChangeTx(ctx, tx, locked)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	pending, err := repo.FindScheduledChanges(ctx, employee.ScheduledChangeFilter{
+		EmployeeId: emp.Id, Status: employee.ChangeStatusPending,
+	})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.NotEqual(t, due.Id, pending[0].Id)
+
+	ids, err = repo.FindDueScheduledChangeIds(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestEmployeeRepository_Hierarchy(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	add := func(name string) *employee.Entity {
+		emp := &employee.Entity{
+			Name: name, Email: strings.ToLower(name) + "@example.com",
+		}
+		require.NoError(t, repo.Add(ctx, emp))
+		return emp
+	}
+	ceo, cto, dev := add("Ceo"), add("Cto"), add("Dev")
+
+	setManager := func(id int64, managerId *int64) {
+		tx, err := DB.Beginx()
+		require.NoError(t, err)
+		require.NoError(t, repo.LockHierarchyTx(ctx, tx))
+		_, err = repo.UpdateManagerTx(ctx, tx, id, managerId)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+	}
+	setManager(cto.Id, &ceo.Id)
+	setManager(dev.Id, &cto.Id)
+
+	reports, err := repo.FindDirectReports(ctx, ceo.Id)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, cto.Id, reports[0].Id)
+
+	subordinates, err := repo.FindSubordinates(ctx, ceo.Id)
+	require.NoError(t, err)
+	require.Len(t, subordinates, 2)
+	assert.Equal(t, cto.Id, subordinates[0].Id)
+	assert.Equal(t, 1, subordinates[0].Depth)
+	assert.Equal(t, dev.Id, subordinates[1].Id)
+	assert.Equal(t, 2, subordinates[1].Depth)
+
+	chain, err := repo.FindManagementChain(ctx, dev.Id)
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+	assert.Equal(t, cto.Id, chain[0].Id)
+	assert.Equal(t, ceo.Id, chain[1].Id)
+
+	// ceo входит в цепочку руководителей dev, но не наоборот
+	tx, err := DB.Beginx()
+	require.NoError(t, err)
+	inChain, err := repo.IsInManagementChainTx(ctx, tx, dev.Id, ceo.Id)
+	require.NoError(t, err)
+	assert.True(t, inChain)
+	inChain, err = repo.IsInManagementChainTx(ctx, tx, ceo.Id, dev.Id)
+	require.NoError(t, err)
+	assert.False(t, inChain)
+	require.NoError(t, tx.Rollback())
+
+	orgChart, err := repo.FindOrgChart(ctx)
+	require.NoError(t, err)
+	assert.Len(t, orgChart, 3)
+
+	setManager(dev.Id, nil)
+	found, err := repo.FindById(ctx, dev.Id)
+	require.NoError(t, err)
+	assert.Nil(t, found.ManagerId)
+}
+
+func TestEmployeeRepository_PageFilters(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	roleIds := createTestRoles(t)
+	developer := createTestPosition(t, "Developer")
+	add := func(name, email, status string, positionId, roleId int64) int64 {
+		emp := &employee.Entity{
+			Name: name, Email: email, Status: status, PositionId: positionId, RoleId: roleId,
+		}
+		require.NoError(t, repo.Add(ctx, emp))
+		return emp.Id
+	}
+	alice := add("Alice", "alice@corp.com", "active", developer, roleIds[0])
+	bob := add("Bob", "bob@example.com", "active", developer, roleIds[1])
+	carol := add("Carol", "carol@example.com", "suspended", 0, roleIds[0])
+
+	// выборка и подсчёт всегда согласованы
+	check := func(t *testing.T, filter employee.PageRequest, expected ...int64) {
+		employees, err := repo.FindWithPagination(ctx, 10, 0, filter)
+		require.NoError(t, err)
+		var ids []int64
+		for _, emp := range employees {
+			ids = append(ids, emp.Id)
+		}
+		assert.Equal(t, expected, ids)
+
+		count, err := repo.CountWithFilter(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(expected)), count)
+	}
+
+	t.Run("email", func(t *testing.T) {
+		check(t, employee.PageRequest{Email: "@EXAMPLE.com"}, bob, carol)
+	})
+	t.Run("position and role", func(t *testing.T) {
+		check(t, employee.PageRequest{PositionIds: []int64{developer}, RoleIds: []int64{roleIds[0]}}, alice)
+	})
+	t.Run("status", func(t *testing.T) {
+		check(t, employee.PageRequest{Statuses: []string{"suspended"}}, carol)
+	})
+	t.Run("created_at range", func(t *testing.T) {
+		future := time.Now().Add(time.Hour)
+		check(t, employee.PageRequest{CreatedTo: &future}, alice, bob, carol)
+		check(t, employee.PageRequest{CreatedFrom: &future})
+	})
+	t.Run("sort", func(t *testing.T) {
+		check(t, employee.PageRequest{Sort: "-name"}, carol, bob, alice)
+		check(t, employee.PageRequest{Sort: "status,-email"}, bob, alice, carol)
+	})
+}
+
+func TestEmployeeRepository_Cursor(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	// одинаковые created_at различаются вторым ключом - id
+	var ids []int64
+	for _, name := range []string{"Dave", "Alice", "Carol", "Bob"} {
+		emp := &employee.Entity{Name: name, Email: strings.ToLower(name) + "@example.com"}
+		require.NoError(t, repo.Add(ctx, emp))
+		ids = append(ids, emp.Id)
+	}
+	_, err := DB.Exec("UPDATE employee SET created_at = '2025-07-01T10:00:00Z'")
+	require.NoError(t, err)
+
+	names := func(employees []employee.Entity) []string {
+		var result []string
+		for _, emp := range employees {
+			result = append(result, emp.Name)
+		}
+		return result
+	}
+
+	t.Run("forward by name", func(t *testing.T) {
+		employees, err := repo.FindWithCursor(ctx, "name", nil, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Bob"}, names(employees))
+
+		employees, err = repo.FindWithCursor(ctx, "name", &common.Cursor{Sort: "name", Value: "Bob", Id: ids[3]}, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Carol", "Dave"}, names(employees))
+	})
+
+	t.Run("backward by name", func(t *testing.T) {
+		position := &common.Cursor{Sort: "name", Value: "Carol", Id: ids[2], Backward: true}
+		employees, err := repo.FindWithCursor(ctx, "name", position, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bob", "Alice"}, names(employees))
+	})
+
+	t.Run("descending created_at with equal values", func(t *testing.T) {
+		employees, err := repo.FindWithCursor(ctx, "-created_at", nil, 2)
+		require.NoError(t, err)
+		require.Len(t, employees, 2)
+		assert.Equal(t, ids[3], employees[0].Id)
+
+		// вставка новой строки между запросами не сдвигает следующую страницу
+		require.NoError(t, repo.Add(ctx, &employee.Entity{Name: "Eve", Email: "eve@example.com"}))
+		last := employees[1]
+		position := &common.Cursor{Sort: "-created_at", Value: last.CreatedAt.Format(time.RFC3339Nano), Id: last.Id}
+		employees, err = repo.FindWithCursor(ctx, "-created_at", position, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Dave"}, names(employees))
+	})
+}
+
+func TestEmployeeRepository_Search(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	developer := createTestPosition(t, "Разработчик")
+	add := func(name, email string, positionId int64) int64 {
+		emp := &employee.Entity{Name: name, Email: email, PositionId: positionId}
+		require.NoError(t, repo.Add(ctx, emp))
+		return emp.Id
+	}
+	ivanov := add("Иванов Иван", "ivan@example.com", developer)
+	ivanova := add("Ivanova Anna", "anna@example.com", 0)
+	petrov := add("Petrov Petr", "petr@corp.com", 0)
+
+	search := func(t *testing.T, query string) []int64 {
+		found, err := repo.Search(ctx, query, 10)
+		require.NoError(t, err)
+		var ids []int64
+		for _, emp := range found {
+			ids = append(ids, emp.Id)
+		}
+		return ids
+	}
+
+	t.Run("prefix in both alphabets, exact word ranks higher", func(t *testing.T) {
+		assert.Equal(t, []int64{ivanov, ivanova}, search(t, "иванов"))
+		assert.Equal(t, []int64{ivanov, ivanova}, search(t, "ivanov"))
+	})
+	t.Run("position and email", func(t *testing.T) {
+		assert.Equal(t, []int64{ivanov}, search(t, "razrab"))
+		assert.Equal(t, []int64{petrov}, search(t, "corp"))
+	})
+	t.Run("typo", func(t *testing.T) {
+		assert.Equal(t, []int64{petrov}, search(t, "petrof"))
+	})
+	t.Run("position rename updates search", func(t *testing.T) {
+		before, err := repo.FindById(ctx, ivanov)
+		require.NoError(t, err)
+
+		_, err = DB.Exec("UPDATE position SET name = 'Аналитик' WHERE id = $1", developer)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{ivanov}, search(t, "аналит"))
+		assert.Empty(t, search(t, "разработ"))
+
+		// пересчёт текста поиска не меняет версию сотрудника
+		after, err := repo.FindById(ctx, ivanov)
+		require.NoError(t, err)
+		assert.True(t, before.UpdatedAt.Equal(after.UpdatedAt))
+	})
+	t.Run("deleted employees are not found", func(t *testing.T) {
+		require.NoError(t, repo.DeleteById(ctx, petrov))
+		assert.Empty(t, search(t, "petrov"))
+	})
+}
+
+func TestEmployeeRepository_ExportEmployees(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	roleIds := createTestRoles(t)
+	manager := &employee.Entity{Name: "Jane Roe", Email: "jane@example.com"}
+	require.NoError(t, repo.Add(ctx, manager))
+	emp := &employee.Entity{Name: "John Doe", Email: "john@example.com"}
+	require.NoError(t, repo.Add(ctx, emp))
+	_, err := DB.Exec(`UPDATE employee SET manager_id = $1 WHERE id = $2`, manager.Id, emp.Id)
+	require.NoError(t, err)
+	_, err = DB.Exec(`INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2), ($1, $3)`,
+		emp.Id, roleIds[0], roleIds[1])
+	require.NoError(t, err)
+	// истёкшее назначение не выгружается
+	_, err = DB.Exec(`INSERT INTO employee_role (employee_id, role_id, valid_from, valid_to)
+		VALUES ($1, $2, now() - interval '2 days', now() - interval '1 day')`, emp.Id, roleIds[2])
+	require.NoError(t, err)
+
+	t.Run("sort and roles", func(t *testing.T) {
+		entities, err := repo.ExportEmployees(ctx, employee.PageRequest{Sort: "-name"})
+		require.NoError(t, err)
+		var names []string
+		for entity, err := range entities {
+			require.NoError(t, err)
+			names = append(names, entity.Name)
+			if entity.Id == emp.Id {
+				assert.Equal(t, []string{"Developer", "Manager"}, []string(entity.Roles))
+				assert.Equal(t, "Jane Roe", entity.Manager)
+			}
+		}
+		assert.Equal(t, []string{"John Doe", "Jane Roe"}, names)
+	})
+
+	t.Run("page filters", func(t *testing.T) {
+		entities, err := repo.ExportEmployees(ctx, employee.PageRequest{RoleIds: []int64{roleIds[1]}})
+		require.NoError(t, err)
+		var ids []int64
+		for entity, err := range entities {
+			require.NoError(t, err)
+			ids = append(ids, entity.Id)
+		}
+		assert.Equal(t, []int64{emp.Id}, ids)
+	})
+}
+
+func TestEmployeeRepository_Import(t *testing.T) {
+	repo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	roleIds := createTestRoles(t)
+
+	t.Run("FindRoleIdsByNamesTx", func(t *testing.T) {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+
+		roles, err := repo.FindRoleIdsByNamesTx(ctx, tx, []string{"developer", "analyst", "unknown"})
+		require.NoError(t, err)
+		require.Len(t, roles, 2)
+		assert.Equal(t, roleIds[0], roles[0].Id)
+		assert.Equal(t, roleIds[2], roles[1].Id)
+	})
+
+	t.Run("rollback to savepoint keeps earlier rows", func(t *testing.T) {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+
+		_, err = repo.SaveTx(ctx, tx, employee.Entity{Name: "John Doe", Email: "john@example.com"})
+		require.NoError(t, err)
+
+		require.NoError(t, repo.SavepointTx(ctx, tx, "import_row"))
+		_, err = repo.SaveTx(ctx, tx, employee.Entity{Name: "John Copy", Email: "john@example.com"})
+		require.Error(t, err)
+		require.NoError(t, repo.RollbackToSavepointTx(ctx, tx, "import_row"))
+
+		require.NoError(t, repo.SavepointTx(ctx, tx, "import_row"))
+		_, err = repo.SaveTx(ctx, tx, employee.Entity{Name: "Jane Doe", Email: "jane@example.com"})
+		require.NoError(t, err)
+		require.NoError(t, repo.ReleaseSavepointTx(ctx, tx, "import_row"))
+		require.NoError(t, tx.Commit())
+
+		count, err := repo.CountAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
 }
@@ -60,19 +60,346 @@ func applyMigrations() {
             status BOOLEAN DEFAULT TRUE,
             parent_id BIGINT REFERENCES role(id) ON DELETE SET NULL,
             created_at TIMESTAMPTZ DEFAULT NOW(),
-            updated_at TIMESTAMPTZ DEFAULT NOW()
+            updated_at TIMESTAMPTZ DEFAULT NOW(),
+            deleted_at TIMESTAMPTZ
         );
 
         CREATE TABLE IF NOT EXISTS employee (
             id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
             name TEXT NOT NULL,
-            email TEXT UNIQUE NOT NULL,
-            position TEXT,
-            department TEXT,
+            email TEXT NOT NULL,
             role_id BIGINT REFERENCES role(id),
+            status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('pending', 'active', 'suspended', 'terminated')),
+            hire_date DATE,
+            start_date DATE,
+            manager_id BIGINT REFERENCES employee(id) ON DELETE SET NULL CHECK (manager_id <> id),
+            created_at TIMESTAMPTZ DEFAULT NOW(),
+            updated_at TIMESTAMPTZ DEFAULT NOW(),
+            deleted_at TIMESTAMPTZ
+        );
+
+        CREATE UNIQUE INDEX IF NOT EXISTS employee_email_active_idx ON employee (email) WHERE deleted_at IS NULL;
+
+        CREATE TABLE IF NOT EXISTS department (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            name TEXT NOT NULL,
+            parent_id BIGINT REFERENCES department(id) CHECK (parent_id <> id),
+            head_employee_id BIGINT REFERENCES employee(id) ON DELETE SET NULL,
+            created_at TIMESTAMPTZ DEFAULT NOW(),
+            updated_at TIMESTAMPTZ DEFAULT NOW()
+        );
+
+        CREATE UNIQUE INDEX IF NOT EXISTS department_name_idx ON department (lower(name));
+
+        ALTER TABLE employee ADD COLUMN IF NOT EXISTS department_id BIGINT REFERENCES department(id) ON DELETE SET NULL;
+
+        CREATE TABLE IF NOT EXISTS position (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            name TEXT NOT NULL,
+            description TEXT,
             created_at TIMESTAMPTZ DEFAULT NOW(),
             updated_at TIMESTAMPTZ DEFAULT NOW()
         );
+
+        CREATE UNIQUE INDEX IF NOT EXISTS position_name_idx ON position (lower(name));
+
+        CREATE TABLE IF NOT EXISTS position_role (
+            position_id BIGINT NOT NULL REFERENCES position(id) ON DELETE CASCADE,
+            role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+            PRIMARY KEY (position_id, role_id)
+        );
+
+        ALTER TABLE employee ADD COLUMN IF NOT EXISTS position_id BIGINT REFERENCES position(id) ON DELETE SET NULL;
+
+        ALTER TABLE role ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES employee(id) ON DELETE SET NULL;
+        ALTER TABLE role ADD COLUMN IF NOT EXISTS risk_level TEXT NOT NULL DEFAULT 'low'
+            CHECK (risk_level IN ('low', 'medium', 'high', 'critical'));
+        ALTER TABLE role ADD COLUMN IF NOT EXISTS category TEXT;
+        ALTER TABLE role ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+        CREATE TABLE IF NOT EXISTS permission (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            code TEXT UNIQUE NOT NULL,
+            description TEXT,
+            created_at TIMESTAMPTZ DEFAULT NOW(),
+            updated_at TIMESTAMPTZ DEFAULT NOW()
+        );
+
+        CREATE TABLE IF NOT EXISTS role_permission (
+            role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+            permission_id BIGINT NOT NULL REFERENCES permission(id) ON DELETE CASCADE,
+            granted_at TIMESTAMPTZ DEFAULT NOW(),
+            PRIMARY KEY (role_id, permission_id)
+        );
+
+        CREATE TABLE IF NOT EXISTS employee_role (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+            role_id BIGINT NOT NULL REFERENCES role(id),
+            valid_from TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            valid_to TIMESTAMPTZ,
+            created_at TIMESTAMPTZ DEFAULT NOW(),
+            CONSTRAINT employee_role_valid_period CHECK (valid_to IS NULL OR valid_to >= valid_from)
+        );
+
+        CREATE TABLE IF NOT EXISTS audit_event (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            actor_sub TEXT,
+            actor_username TEXT,
+            action TEXT NOT NULL,
+            entity_type TEXT NOT NULL,
+            entity_id BIGINT,
+            before JSONB,
+            after JSONB,
+            request_id TEXT,
+            ip TEXT,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );
+
+        CREATE TABLE IF NOT EXISTS employee_status_history (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+            transition TEXT NOT NULL,
+            from_status TEXT,
+            to_status TEXT NOT NULL,
+            reason TEXT,
+            details JSONB,
+            actor_sub TEXT,
+            actor_username TEXT,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );
+
+        CREATE TABLE IF NOT EXISTS employee_scheduled_change (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+            position_id BIGINT REFERENCES position(id) ON DELETE SET NULL,
+            department_id BIGINT REFERENCES department(id) ON DELETE SET NULL,
+            role_id BIGINT REFERENCES role(id) ON DELETE SET NULL,
+            effective_at TIMESTAMPTZ NOT NULL,
+            status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'applied', 'failed', 'cancelled')),
+            reason TEXT,
+            result TEXT,
+            created_by_sub TEXT,
+            created_by_username TEXT,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            applied_at TIMESTAMPTZ
+        );
+
+        CREATE TABLE IF NOT EXISTS job (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            type TEXT NOT NULL,
+            status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+            params JSONB NOT NULL DEFAULT '{}',
+            input BYTEA,
+            progress INTEGER NOT NULL DEFAULT 0,
+            total INTEGER NOT NULL DEFAULT 0,
+            attempts INTEGER NOT NULL DEFAULT 0,
+            cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+            result JSONB,
+            result_file BYTEA,
+            result_content_type TEXT,
+            result_filename TEXT,
+            error TEXT,
+            created_by_sub TEXT,
+            created_by_username TEXT,
+            request_id TEXT,
+            ip TEXT,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            started_at TIMESTAMPTZ,
+            heartbeat_at TIMESTAMPTZ,
+            finished_at TIMESTAMPTZ
+        );
+
+        CREATE TABLE IF NOT EXISTS access_request_policy (
+            role_id BIGINT PRIMARY KEY REFERENCES role(id) ON DELETE CASCADE,
+            steps TEXT[] NOT NULL CHECK (cardinality(steps) BETWEEN 1 AND 5 AND steps <@ ARRAY['manager', 'role_owner', 'admin']),
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );
+
+        CREATE TABLE IF NOT EXISTS access_request (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+            role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE RESTRICT,
+            requester_employee_id BIGINT REFERENCES employee(id) ON DELETE SET NULL,
+            justification TEXT NOT NULL,
+            valid_from TIMESTAMPTZ,
+            valid_to TIMESTAMPTZ,
+            steps TEXT[] NOT NULL,
+            current_step INTEGER NOT NULL DEFAULT 0,
+            status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled', 'expired')),
+            assignment_id BIGINT REFERENCES employee_role(id) ON DELETE SET NULL,
+            created_by_sub TEXT,
+            created_by_username TEXT,
+            expires_at TIMESTAMPTZ NOT NULL,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            decided_at TIMESTAMPTZ
+        );
+
+        CREATE UNIQUE INDEX IF NOT EXISTS access_request_pending_uidx ON access_request (employee_id, role_id)
+            WHERE status = 'pending';
+
+        CREATE TABLE IF NOT EXISTS access_request_event (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            request_id BIGINT NOT NULL REFERENCES access_request(id) ON DELETE CASCADE,
+            action TEXT NOT NULL CHECK (action IN ('submit', 'approve', 'reject', 'comment', 'cancel', 'expire')),
+            step TEXT,
+            comment TEXT,
+            actor_employee_id BIGINT REFERENCES employee(id) ON DELETE SET NULL,
+            actor_sub TEXT,
+            actor_username TEXT,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );
+
+        CREATE TABLE IF NOT EXISTS certification_campaign (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            name TEXT NOT NULL,
+            description TEXT,
+            role_ids BIGINT[] NOT NULL DEFAULT '{}',
+            department_ids BIGINT[] NOT NULL DEFAULT '{}',
+            reviewer TEXT NOT NULL DEFAULT 'manager' CHECK (reviewer IN ('manager', 'role_owner')),
+            auto_action TEXT NOT NULL DEFAULT 'revoke' CHECK (auto_action IN ('certify', 'revoke')),
+            deadline TIMESTAMPTZ NOT NULL,
+            status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'cancelled')),
+            created_by_sub TEXT,
+            created_by_username TEXT,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            completed_at TIMESTAMPTZ
+        );
+
+        CREATE TABLE IF NOT EXISTS certification_item (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            campaign_id BIGINT NOT NULL REFERENCES certification_campaign(id) ON DELETE CASCADE,
+            assignment_id BIGINT REFERENCES employee_role(id) ON DELETE SET NULL,
+            employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+            role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE RESTRICT,
+            valid_from TIMESTAMPTZ NOT NULL,
+            valid_to TIMESTAMPTZ,
+            reviewer_employee_id BIGINT REFERENCES employee(id) ON DELETE SET NULL,
+            decision TEXT NOT NULL DEFAULT 'pending' CHECK (decision IN ('pending', 'certified', 'revoked')),
+            auto BOOLEAN NOT NULL DEFAULT FALSE,
+            comment TEXT,
+            decided_by_sub TEXT,
+            decided_by_username TEXT,
+            decided_at TIMESTAMPTZ,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );
+
+        CREATE TABLE IF NOT EXISTS sod_rule (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            name TEXT NOT NULL,
+            description TEXT,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );
+
+        CREATE UNIQUE INDEX IF NOT EXISTS sod_rule_name_idx ON sod_rule (lower(name));
+
+        CREATE TABLE IF NOT EXISTS sod_rule_role (
+            rule_id BIGINT NOT NULL REFERENCES sod_rule(id) ON DELETE CASCADE,
+            role_id BIGINT NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+            PRIMARY KEY (rule_id, role_id)
+        );
+
+        CREATE TABLE IF NOT EXISTS sod_exception (
+            id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+            rule_id BIGINT NOT NULL REFERENCES sod_rule(id) ON DELETE CASCADE,
+            employee_id BIGINT NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+            justification TEXT NOT NULL,
+            expires_at TIMESTAMPTZ NOT NULL,
+            approved_by_sub TEXT,
+            approved_by_username TEXT,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            revoked_at TIMESTAMPTZ
+        );
+
+        CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+        CREATE OR REPLACE FUNCTION translit_ru(value TEXT) RETURNS TEXT AS $$
+            SELECT translate(
+                replace(replace(replace(replace(replace(replace(replace(replace(replace(replace(
+                    lower(value),
+                    'щ', 'shch'), 'ж', 'zh'), 'х', 'kh'), 'ц', 'ts'), 'ч', 'ch'),
+                    'ш', 'sh'), 'ю', 'yu'), 'я', 'ya'), 'ъ', ''), 'ь', ''),
+                'абвгдеёзийклмнопрстуфыэ',
+                'abvgdeeziyklmnoprstufye')
+        $$ LANGUAGE sql IMMUTABLE PARALLEL SAFE;
+
+        CREATE OR REPLACE FUNCTION employee_search_text(
+            name TEXT, email TEXT, position_id BIGINT, department_id BIGINT
+        ) RETURNS TEXT AS $$
+            SELECT lower(source) || ' ' || translit_ru(source)
+            FROM (
+                SELECT concat_ws(' ', name, email,
+                    (SELECT p.name FROM position p WHERE p.id = position_id),
+                    (SELECT d.name FROM department d WHERE d.id = department_id)) AS source
+            ) s
+        $$ LANGUAGE sql STABLE;
+
+        ALTER TABLE employee ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '';
+        ALTER TABLE employee ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+            GENERATED ALWAYS AS (to_tsvector('simple', search_text)) STORED;
+
+        CREATE OR REPLACE FUNCTION employee_refresh_search_text() RETURNS TRIGGER AS $$
+        BEGIN
+            NEW.search_text = employee_search_text(NEW.name, NEW.email, NEW.position_id, NEW.department_id);
+            RETURN NEW;
+        END;
+        $$ LANGUAGE plpgsql;
+
+        CREATE OR REPLACE TRIGGER employee_search_text
+            BEFORE INSERT OR UPDATE OF name, email, position_id, department_id, search_text ON employee
+            FOR EACH ROW EXECUTE FUNCTION employee_refresh_search_text();
+
+        CREATE OR REPLACE FUNCTION employee_set_updated_at() RETURNS TRIGGER AS $$
+        BEGIN
+            IF NEW.search_text IS DISTINCT FROM OLD.search_text
+                AND to_jsonb(NEW) - 'search_text' - 'search_vector' = to_jsonb(OLD) - 'search_text' - 'search_vector' THEN
+                RETURN NEW;
+            END IF;
+            NEW.updated_at = clock_timestamp();
+            RETURN NEW;
+        END;
+        $$ LANGUAGE plpgsql;
+
+        CREATE OR REPLACE TRIGGER employee_set_updated_at
+            BEFORE UPDATE ON employee
+            FOR EACH ROW EXECUTE FUNCTION employee_set_updated_at();
+
+        CREATE OR REPLACE FUNCTION position_refresh_employee_search() RETURNS TRIGGER AS $$
+        BEGIN
+            UPDATE employee SET search_text = employee_search_text(name, email, position_id, department_id)
+            WHERE position_id = NEW.id
+                AND search_text IS DISTINCT FROM employee_search_text(name, email, position_id, department_id);
+            RETURN NULL;
+        END;
+        $$ LANGUAGE plpgsql;
+
+        CREATE OR REPLACE TRIGGER position_employee_search
+            AFTER UPDATE OF name ON position
+            FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+            EXECUTE FUNCTION position_refresh_employee_search();
+
+        CREATE OR REPLACE FUNCTION department_refresh_employee_search() RETURNS TRIGGER AS $$
+        BEGIN
+            UPDATE employee SET search_text = employee_search_text(name, email, position_id, department_id)
+            WHERE department_id = NEW.id
+                AND search_text IS DISTINCT FROM employee_search_text(name, email, position_id, department_id);
+            RETURN NULL;
+        END;
+        $$ LANGUAGE plpgsql;
+
+        CREATE OR REPLACE TRIGGER department_employee_search
+            AFTER UPDATE OF name ON department
+            FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+            EXECUTE FUNCTION department_refresh_employee_search();
+
+        CREATE INDEX IF NOT EXISTS employee_search_vector_idx ON employee USING GIN (search_vector);
+        CREATE INDEX IF NOT EXISTS employee_search_text_trgm_idx ON employee USING GIN (search_text gin_trgm_ops);
     `)
 	if err != nil {
 		log.Fatalf("Migration failed: %v\n", err)
@@ -83,12 +410,50 @@ func clearTables() {
 	if DB == nil {
 		log.Fatal("Database connection is nil")
 	}
-	_, err := DB.Exec("DELETE FROM employee")
+	// отложенные изменения ссылаются на роли, поэтому удаляются до них
+	_, err := DB.Exec("DELETE FROM employee_scheduled_change")
+	if err != nil {
+		log.Fatalf("Failed to clear employee_scheduled_change table: %v", err)
+	}
+	// запросы доступа удаляются каскадно вместе с сотрудниками, поэтому роли удаляются после них
+	_, err = DB.Exec("DELETE FROM employee")
 	if err != nil {
 		log.Fatalf("Failed to clear employee table: %v", err)
 	}
+	// руководители отделов обнуляются при удалении сотрудников, сами отделы удаляются после них
+	_, err = DB.Exec("DELETE FROM department")
+	if err != nil {
+		log.Fatalf("Failed to clear department table: %v", err)
+	}
+	// роли по умолчанию удаляются вместе с должностями
+	_, err = DB.Exec("DELETE FROM position")
+	if err != nil {
+		log.Fatalf("Failed to clear position table: %v", err)
+	}
 	_, err = DB.Exec("DELETE FROM role")
 	if err != nil {
 		log.Fatalf("Failed to clear role table: %v", err)
 	}
+	_, err = DB.Exec("DELETE FROM permission")
+	if err != nil {
+		log.Fatalf("Failed to clear permission table: %v", err)
+	}
+	_, err = DB.Exec("DELETE FROM audit_event")
+	if err != nil {
+		log.Fatalf("Failed to clear audit_event table: %v", err)
+	}
+	_, err = DB.Exec("DELETE FROM job")
+	if err != nil {
+		log.Fatalf("Failed to clear job table: %v", err)
+	}
+	// назначения кампаний удаляются каскадно вместе с сотрудниками, сами кампании - отдельно
+	_, err = DB.Exec("DELETE FROM certification_campaign")
+	if err != nil {
+		log.Fatalf("Failed to clear certification_campaign table: %v", err)
+	}
+	// роли правил и исключения удаляются каскадно, сами правила - отдельно
+	_, err = DB.Exec("DELETE FROM sod_rule")
+	if err != nil {
+		log.Fatalf("Failed to clear sod_rule table: %v", err)
+	}
 }
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idm/inner/jobs"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepository_Lifecycle(t *testing.T) {
+	repo := jobs.NewJobRepository(DB)
+	ctx := context.Background()
+	types := []string{jobs.TypeEmployeeImport, jobs.TypeRoleExport}
+
+	clearTables()
+
+	t.Run("Claim, heartbeat and finish", func(t *testing.T) {
+		id, err := repo.Create(ctx, jobs.Entity{
+			Type:         jobs.TypeEmployeeImport,
+			Params:       `{"format":"csv"}`,
+			Input:        []byte("name,email\n"),
+			CreatedBySub: ptr("sub-1"),
+		})
+		require.NoError(t, err)
+
+		job, found, err := repo.Claim(ctx, types, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, id, job.Id)
+		assert.Equal(t, jobs.StatusRunning, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+		assert.Equal(t, []byte("name,email\n"), job.Input)
+
+		// выполняемое задание с живым heartbeat не забирается повторно
+		_, found, err = repo.Claim(ctx, types, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		cancelRequested, err := repo.Heartbeat(ctx, id, 5, 10)
+		require.NoError(t, err)
+		assert.False(t, cancelRequested)
+
+		result := `{"rows":10}`
+		err = repo.Finish(ctx, id, jobs.Outcome{
+			Status:   jobs.StatusSucceeded,
+			Progress: 10,
+			Total:    10,
+			Result:   &result,
+			File:     &jobs.ResultFile{Content: []byte("id\n"), ContentType: "text/csv", Filename: "employees.csv"},
+		})
+		require.NoError(t, err)
+
+		finished, err := repo.FindById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, jobs.StatusSucceeded, finished.Status)
+		assert.Equal(t, 10, finished.Progress)
+		assert.JSONEq(t, result, *finished.Result)
+		assert.NotNil(t, finished.FinishedAt)
+
+		file, err := repo.FindResultFile(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, jobs.ResultFile{Content: []byte("id\n"), ContentType: "text/csv", Filename: "employees.csv"}, file)
+
+		// завершённое задание нельзя отменить
+		_, found, err = repo.RequestCancel(ctx, id)
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Stale job is claimed again", func(t *testing.T) {
+		id, err := repo.Create(ctx, jobs.Entity{Type: jobs.TypeRoleExport, Params: `{}`})
+		require.NoError(t, err)
+
+		_, found, err := repo.Claim(ctx, types, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		require.True(t, found)
+
+		job, found, err := repo.Claim(ctx, types, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, id, job.Id)
+		assert.Equal(t, 2, job.Attempts)
+
+		require.NoError(t, repo.Requeue(ctx, id))
+		requeued, err := repo.FindById(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, jobs.StatusQueued, requeued.Status)
+		assert.Equal(t, 1, requeued.Attempts)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		clearTables()
+
+		queued, err := repo.Create(ctx, jobs.Entity{Type: jobs.TypeRoleExport, Params: `{}`})
+		require.NoError(t, err)
+		job, found, err := repo.RequestCancel(ctx, queued)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, jobs.StatusCancelled, job.Status)
+
+		running, err := repo.Create(ctx, jobs.Entity{Type: jobs.TypeRoleExport, Params: `{}`})
+		require.NoError(t, err)
+		_, found, err = repo.Claim(ctx, types, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		require.True(t, found)
+
+		job, found, err = repo.RequestCancel(ctx, running)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, jobs.StatusRunning, job.Status)
+		assert.True(t, job.CancelRequested)
+
+		cancelRequested, err := repo.Heartbeat(ctx, running, 0, 0)
+		require.NoError(t, err)
+		assert.True(t, cancelRequested)
+	})
+
+	t.Run("Unknown type is not claimed", func(t *testing.T) {
+		clearTables()
+
+		_, err := repo.Create(ctx, jobs.Entity{Type: jobs.TypeEmployeeDelete, Params: `{"ids":[1]}`})
+		require.NoError(t, err)
+
+		_, found, err := repo.Claim(ctx, types, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"idm/inner/permission"
+	"idm/inner/role"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionRepository_GrantAndRevoke(t *testing.T) {
+	roleRepo := role.NewRoleRepository(DB)
+	repo := permission.NewPermissionRepository(DB)
+
+	clearTables()
+
+	adminRole := &role.Entity{Name: "Admin", Desc: "Administrator role", Status: true}
+	require.NoError(t, roleRepo.Add(context.Background(), adminRole))
+
+	tx, err := repo.BeginTransaction(context.Background())
+	require.NoError(t, err)
+	readId, err := repo.SaveTx(context.Background(), tx, permission.Entity{Code: "employee:read"})
+	require.NoError(t, err)
+	writeId, err := repo.SaveTx(context.Background(), tx, permission.Entity{Code: "employee:write"})
+	require.NoError(t, err)
+
+	missing, err := repo.FindMissingIdsTx(context.Background(), tx, []int64{readId, writeId, writeId + 100})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{writeId + 100}, missing)
+
+	require.NoError(t, repo.GrantTx(context.Background(), tx, adminRole.Id, []int64{readId, writeId}))
+	// повторная выдача не должна приводить к ошибке
+	require.NoError(t, repo.GrantTx(context.Background(), tx, adminRole.Id, []int64{readId}))
+	require.NoError(t, tx.Commit())
+
+	permissions, err := repo.FindByRoleId(context.Background(), adminRole.Id)
+	assert.NoError(t, err)
+	assert.Len(t, permissions, 2)
+
+	revoked, err := repo.Revoke(context.Background(), adminRole.Id, writeId)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = repo.Revoke(context.Background(), adminRole.Id, writeId)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	grants, err := repo.FindByRoleIds(context.Background(), []int64{adminRole.Id})
+	assert.NoError(t, err)
+	require.Len(t, grants, 1)
+	assert.Equal(t, "employee:read", grants[0].Code)
+	assert.Equal(t, adminRole.Id, grants[0].RoleId)
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"idm/inner/employee"
+	"idm/inner/position"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// создаёт должность напрямую в базе и возвращает её id
+func createTestPosition(t *testing.T, name string) int64 {
+	var id int64
+	err := DB.Get(&id, "INSERT INTO position (name) VALUES ($1) RETURNING id", name)
+	require.NoError(t, err)
+	return id
+}
+
+func TestPositionRepository_CRUD(t *testing.T) {
+	repo := position.NewPositionRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	tx, err := repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	created, err := repo.SaveTx(ctx, tx, position.Entity{Name: "Developer"})
+	require.NoError(t, err)
+	assert.NotZero(t, created.Id)
+
+	// названия уникальны без учёта регистра, сама должность при переименовании не мешает
+	taken, err := repo.FindByNameTx(ctx, tx, "DEVELOPER", 0)
+	require.NoError(t, err)
+	assert.True(t, taken)
+	taken, err = repo.FindByNameTx(ctx, tx, "developer", created.Id)
+	require.NoError(t, err)
+	assert.False(t, taken)
+
+	created.Name = "Backend Developer"
+	updated, err := repo.UpdateTx(ctx, tx, created)
+	require.NoError(t, err)
+	assert.Equal(t, "Backend Developer", updated.Name)
+	require.NoError(t, tx.Commit())
+
+	found, err := repo.FindById(ctx, created.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "Backend Developer", found.Name)
+	assert.Empty(t, found.RoleIds)
+
+	tx, err = repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTx(ctx, tx, created.Id))
+	require.NoError(t, tx.Commit())
+
+	all, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestPositionRepository_DefaultRoles(t *testing.T) {
+	repo := position.NewPositionRepository(DB)
+	employeeRepo := employee.NewEmployeeRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	roleIds := createTestRoles(t)
+	positionId := createTestPosition(t, "Developer")
+
+	tx, err := repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.ReplaceRolesTx(ctx, tx, positionId, []int64{roleIds[1], roleIds[0]}))
+	require.NoError(t, tx.Commit())
+
+	found, err := repo.FindById(ctx, positionId)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{roleIds[0], roleIds[1]}, []int64(found.RoleIds))
+
+	t.Run("FindMissingRoleIdsTx", func(t *testing.T) {
+		_, err := DB.Exec("UPDATE role SET deleted_at = NOW() WHERE id = $1", roleIds[2])
+		require.NoError(t, err)
+
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+		missing, err := repo.FindMissingRoleIdsTx(ctx, tx, []int64{roleIds[0], roleIds[2], 999999})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{roleIds[2], 999999}, missing)
+	})
+
+	t.Run("Employee sees default roles of the position", func(t *testing.T) {
+		tx, err := employeeRepo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+		exists, err := employeeRepo.PositionExistsTx(ctx, tx, positionId)
+		require.NoError(t, err)
+		assert.True(t, exists)
+		defaults, err := employeeRepo.FindPositionRoleIdsTx(ctx, tx, positionId)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{roleIds[0], roleIds[1]}, defaults)
+	})
+
+	t.Run("FindEmployeeIdsTx", func(t *testing.T) {
+		var employeeId int64
+		err := DB.Get(&employeeId,
+			"INSERT INTO employee (name, email, position_id) VALUES ('John Doe', 'john@example.com', $1) RETURNING id",
+			positionId)
+		require.NoError(t, err)
+
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+		ids, err := repo.FindEmployeeIdsTx(ctx, tx, positionId)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{employeeId}, ids)
+	})
+
+	// пустой набор очищает роли по умолчанию
+	tx, err = repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.ReplaceRolesTx(ctx, tx, positionId, nil))
+	require.NoError(t, tx.Commit())
+	found, err = repo.FindById(ctx, positionId)
+	require.NoError(t, err)
+	assert.Empty(t, found.RoleIds)
+}
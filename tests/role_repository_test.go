@@ -3,11 +3,14 @@ package tests
 import (
 	"context"
 	"testing"
+	"time"
 
+	"idm/inner/common"
 	"idm/inner/role"
 
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRoleRepository_CRUD(t *testing.T) {
@@ -66,6 +69,80 @@ func TestRoleRepository_CRUD(t *testing.T) {
 		assert.Contains(t, []string{"Root", "Admin", "Guest"}, roles[0].Name)
 	})
 
+	t.Run("FindWithCursor", func(t *testing.T) {
+		roles, err := repo.FindWithCursor(context.Background(), role.Filter{}, "-name", nil, 2)
+		assert.NoError(t, err)
+		if assert.Len(t, roles, 2) {
+			assert.Equal(t, "Root", roles[0].Name)
+			assert.Equal(t, "Guest", roles[1].Name)
+		}
+
+		position := &common.Cursor{Sort: "-name", Value: roles[1].Name, Id: roles[1].Id}
+		roles, err = repo.FindWithCursor(context.Background(), role.Filter{}, "-name", position, 2)
+		assert.NoError(t, err)
+		if assert.Len(t, roles, 1) {
+			assert.Equal(t, "Admin", roles[0].Name)
+		}
+	})
+
+	t.Run("FindWithCursor by metadata", func(t *testing.T) {
+		var ownerId int64
+		require.NoError(t, DB.Get(&ownerId,
+			"INSERT INTO employee (name, email) VALUES ('John', 'john@example.com') RETURNING id"))
+		found, err := repo.FindById(context.Background(), adminRole.Id)
+		require.NoError(t, err)
+		assert.Equal(t, role.RiskLow, found.RiskLevel)
+
+		category := "Billing"
+		found.OwnerId, found.RiskLevel, found.Category = &ownerId, role.RiskHigh, &category
+		found.Labels = `{"app": "billing", "env": "prod"}`
+		tx, err := repo.BeginTransaction(context.Background())
+		require.NoError(t, err)
+		_, err = repo.UpdateTx(context.Background(), tx, found)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+
+		filters := []role.Filter{
+			{OwnerId: ownerId},
+			{RiskLevels: []string{role.RiskHigh, role.RiskCritical}},
+			{Category: "billing"},
+			{Labels: map[string]string{"app": "billing"}},
+			{OwnerId: ownerId, Labels: map[string]string{"app": "billing", "env": "prod"}},
+		}
+		for _, filter := range filters {
+			roles, err := repo.FindWithCursor(context.Background(), filter, "", nil, 10)
+			assert.NoError(t, err)
+			if assert.Len(t, roles, 1) {
+				assert.Equal(t, adminRole.Id, roles[0].Id)
+			}
+		}
+
+		roles, err := repo.FindWithCursor(context.Background(), role.Filter{Labels: map[string]string{"app": "crm"}}, "", nil, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, roles)
+	})
+
+	t.Run("ExportRoles", func(t *testing.T) {
+		var permissionId int64
+		require.NoError(t, DB.Get(&permissionId, `INSERT INTO permission (code) VALUES ('roles.read') RETURNING id`))
+		_, err := DB.Exec(`INSERT INTO role_permission (role_id, permission_id) VALUES ($1, $2)`, adminRole.Id, permissionId)
+		require.NoError(t, err)
+
+		records, err := repo.ExportRoles(context.Background(), "name")
+		require.NoError(t, err)
+		var names, parents []string
+		for record, err := range records {
+			require.NoError(t, err)
+			names = append(names, record.Name)
+			parents = append(parents, record.Parent)
+			if record.Id == adminRole.Id {
+				assert.Equal(t, []string{"roles.read"}, []string(record.Permissions))
+			}
+		}
+		assert.Equal(t, []string{"Admin", "Guest", "Root"}, names)
+		assert.Equal(t, []string{"Root", "Admin", ""}, parents)
+	})
+
 	t.Run("FindByIds", func(t *testing.T) {
 		roles, err := repo.FindByIds(context.Background(), []int64{adminRole.Id, guestRole.Id})
 		assert.NoError(t, err)
@@ -200,3 +277,337 @@ func TestSaveRoleTx(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Administrator role", retrievedName)
 }
+
+func TestRoleRepository_IsInParentChainTx(t *testing.T) {
+	repo := role.NewRoleRepository(DB)
+
+	clearTables()
+
+	// Root <- Admin <- Guest
+	rootRole := &role.Entity{Name: "Root", Desc: "Root of all roles", Status: true}
+	require.NoError(t, repo.Add(context.Background(), rootRole))
+	adminRole := &role.Entity{Name: "Admin", Desc: "Administrator role", Status: true, ParentId: &rootRole.Id}
+	require.NoError(t, repo.Add(context.Background(), adminRole))
+	guestRole := &role.Entity{Name: "Guest", Desc: "Read-only role", Status: true, ParentId: &adminRole.Id}
+	require.NoError(t, repo.Add(context.Background(), guestRole))
+
+	tx, err := repo.BeginTransaction(context.Background())
+	require.NoError(t, err)
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Root - предок Guest: назначить Guest родителем Root нельзя
+	inChain, err := repo.IsInParentChainTx(context.Background(), tx, guestRole.Id, rootRole.Id)
+	assert.NoError(t, err)
+	assert.True(t, inChain)
+
+	// Guest не является предком Root
+	inChain, err = repo.IsInParentChainTx(context.Background(), tx, rootRole.Id, guestRole.Id)
+	assert.NoError(t, err)
+	assert.False(t, inChain)
+
+	require.NoError(t, repo.LockHierarchyTx(context.Background(), tx))
+
+	guestRole.ParentId = &rootRole.Id
+	updated, err := repo.UpdateTx(context.Background(), tx, *guestRole)
+	assert.NoError(t, err)
+	assert.Equal(t, rootRole.Id, *updated.ParentId)
+}
+
+func TestRoleRepository_Hierarchy(t *testing.T) {
+	repo := role.NewRoleRepository(DB)
+
+	clearTables()
+
+	// Root <- Admin <- Guest, Root <- Auditor
+	rootRole := &role.Entity{Name: "Root", Desc: "Root of all roles", Status: true}
+	require.NoError(t, repo.Add(context.Background(), rootRole))
+	adminRole := &role.Entity{Name: "Admin", Desc: "Administrator role", Status: true, ParentId: &rootRole.Id}
+	require.NoError(t, repo.Add(context.Background(), adminRole))
+	guestRole := &role.Entity{Name: "Guest", Desc: "Read-only role", Status: true, ParentId: &adminRole.Id}
+	require.NoError(t, repo.Add(context.Background(), guestRole))
+	auditorRole := &role.Entity{Name: "Auditor", Desc: "Audit role", Status: true, ParentId: &rootRole.Id}
+	require.NoError(t, repo.Add(context.Background(), auditorRole))
+
+	t.Run("FindAncestors", func(t *testing.T) {
+		ancestors, err := repo.FindAncestors(context.Background(), guestRole.Id)
+		assert.NoError(t, err)
+		require.Len(t, ancestors, 2)
+		assert.Equal(t, "Admin", ancestors[0].Name)
+		assert.Equal(t, "Root", ancestors[1].Name)
+	})
+
+	t.Run("FindDescendants", func(t *testing.T) {
+		descendants, err := repo.FindDescendants(context.Background(), rootRole.Id)
+		assert.NoError(t, err)
+		assert.Len(t, descendants, 3)
+	})
+
+	t.Run("FindTree", func(t *testing.T) {
+		tree, err := repo.FindTree(context.Background())
+		assert.NoError(t, err)
+		require.Len(t, tree, 4)
+		// обход в глубину: Guest идёт сразу за Admin, раньше Auditor
+		assert.Equal(t, []string{"Root", "Admin", "Guest", "Auditor"},
+			[]string{tree[0].Name, tree[1].Name, tree[2].Name, tree[3].Name})
+		assert.Equal(t, 2, tree[2].Depth)
+		assert.Equal(t, []int64{rootRole.Id, adminRole.Id, guestRole.Id}, []int64(tree[2].Path))
+	})
+
+	t.Run("FindEffectiveByEmployeeId", func(t *testing.T) {
+		var employeeId int64
+		err := DB.QueryRow(
+			`INSERT INTO employee (name, email, role_id) VALUES ($1, $2, $3) RETURNING id`,
+			"John Doe", "john@example.com", guestRole.Id,
+		).Scan(&employeeId)
+		require.NoError(t, err)
+		_, err = DB.Exec(`INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2)`, employeeId, guestRole.Id)
+		require.NoError(t, err)
+
+		effective, err := repo.FindEffectiveByEmployeeId(context.Background(), employeeId)
+		assert.NoError(t, err)
+		assert.Len(t, effective, 3)
+		for _, r := range effective {
+			assert.Equal(t, guestRole.Id, r.SourceRoleId)
+			assert.NotEqual(t, auditorRole.Id, r.Id)
+		}
+	})
+	t.Run("Inactive role cuts off its ancestors", func(t *testing.T) {
+		var employeeId int64
+		require.NoError(t, DB.Get(&employeeId,
+			"INSERT INTO employee (name, email) VALUES ('Ann', 'ann@example.com') RETURNING id"))
+		_, err := DB.Exec(`INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2)`, employeeId, guestRole.Id)
+		require.NoError(t, err)
+
+		tx, err := repo.BeginTransaction(context.Background())
+		require.NoError(t, err)
+		updated, err := repo.SetStatusTx(context.Background(), tx, adminRole.Id, false)
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit())
+		assert.False(t, updated.Status)
+
+		// Admin выключена: Guest остаётся, Root через Admin больше не наследуется
+		effective, err := repo.FindEffectiveByEmployeeId(context.Background(), employeeId)
+		assert.NoError(t, err)
+		require.Len(t, effective, 1)
+		assert.Equal(t, guestRole.Id, effective[0].Id)
+	})
+}
+
+func TestRoleRepository_SoftDelete(t *testing.T) {
+	repo := role.NewRoleRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	// Root <- Admin <- Guest
+	rootRole := &role.Entity{Name: "Root", Desc: "Root of all roles", Status: true}
+	require.NoError(t, repo.Add(ctx, rootRole))
+	adminRole := &role.Entity{Name: "Admin", Desc: "Administrator role", Status: true, ParentId: &rootRole.Id}
+	require.NoError(t, repo.Add(ctx, adminRole))
+	guestRole := &role.Entity{Name: "Guest", Desc: "Read-only role", Status: true, ParentId: &adminRole.Id}
+	require.NoError(t, repo.Add(ctx, guestRole))
+
+	require.NoError(t, repo.DeleteById(ctx, adminRole.Id))
+
+	roles, err := repo.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, roles, 2)
+
+	// удалённая роль разрывает цепочку наследования
+	ancestors, err := repo.FindAncestors(ctx, guestRole.Id)
+	require.NoError(t, err)
+	assert.Empty(t, ancestors)
+
+	tx, err := repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	nameTaken, err := repo.FindByNameTx(ctx, tx, "Admin")
+	require.NoError(t, err)
+	assert.False(t, nameTaken)
+	restored, err := repo.RestoreTx(ctx, tx, adminRole.Id)
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	require.NoError(t, tx.Commit())
+
+	ancestors, err = repo.FindAncestors(ctx, guestRole.Id)
+	require.NoError(t, err)
+	assert.Len(t, ancestors, 2)
+
+	// роль, на которую ссылается назначение сотрудника, не очищается
+	_, err = DB.Exec(`INSERT INTO employee (name, email, role_id) VALUES ($1, $2, $3)`,
+		"John Doe", "john@example.com", guestRole.Id)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteByIds(ctx, []int64{adminRole.Id, guestRole.Id}))
+
+	tx, err = repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	purged, err := repo.PurgeDeletedTx(ctx, tx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, purged, 1)
+	assert.Equal(t, adminRole.Id, purged[0].Id)
+	require.NoError(t, tx.Commit())
+}
+
+func TestRoleRepository_PurgeScheduledChangeRoles(t *testing.T) {
+	repo := role.NewRoleRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	appliedRole := &role.Entity{Name: "Accountant", Status: true}
+	require.NoError(t, repo.Add(ctx, appliedRole))
+	pendingRole := &role.Entity{Name: "Auditor", Status: true}
+	require.NoError(t, repo.Add(ctx, pendingRole))
+
+	var employeeId, appliedChangeId int64
+	require.NoError(t, DB.Get(&employeeId,
+		"INSERT INTO employee (name, email) VALUES ('John', 'john@example.com') RETURNING id"))
+	require.NoError(t, DB.Get(&appliedChangeId,
+		`INSERT INTO employee_scheduled_change (employee_id, role_id, effective_at, status)
+		VALUES ($1, $2, now() - interval '1 day', 'applied') RETURNING id`, employeeId, appliedRole.Id))
+	_, err := DB.Exec(
+		`INSERT INTO employee_scheduled_change (employee_id, role_id, effective_at, status)
+		VALUES ($1, $2, now() + interval '1 day', 'pending')`, employeeId, pendingRole.Id)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteByIds(ctx, []int64{appliedRole.Id, pendingRole.Id}))
+
+	tx, err := repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	purged, err := repo.PurgeDeletedTx(ctx, tx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	// роль ожидающего изменения остаётся, в применённом изменении ссылка обнуляется
+	require.Len(t, purged, 1)
+	assert.Equal(t, appliedRole.Id, purged[0].Id)
+	var roleId *int64
+	require.NoError(t, DB.Get(&roleId, "SELECT role_id FROM employee_scheduled_change WHERE id = $1", appliedChangeId))
+	assert.Nil(t, roleId)
+}
+
+func TestRoleRepository_PurgeAccessRequestRoles(t *testing.T) {
+	repo := role.NewRoleRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	requestedRole := &role.Entity{Name: "Accountant", Status: true}
+	require.NoError(t, repo.Add(ctx, requestedRole))
+	unusedRole := &role.Entity{Name: "Auditor", Status: true}
+	require.NoError(t, repo.Add(ctx, unusedRole))
+
+	var employeeId int64
+	require.NoError(t, DB.Get(&employeeId,
+		"INSERT INTO employee (name, email) VALUES ('John', 'john@example.com') RETURNING id"))
+	_, err := DB.Exec(
+		`INSERT INTO access_request (employee_id, role_id, justification, steps, status, expires_at)
+		VALUES ($1, $2, 'Monthly reports', ARRAY['admin'], 'rejected', now())`, employeeId, requestedRole.Id)
+	require.NoError(t, err)
+	certifiedRole := &role.Entity{Name: "Cashier", Status: true}
+	require.NoError(t, repo.Add(ctx, certifiedRole))
+	var campaignId int64
+	require.NoError(t, DB.Get(&campaignId,
+		"INSERT INTO certification_campaign (name, deadline) VALUES ('Q3 review', now()) RETURNING id"))
+	_, err = DB.Exec(
+		`INSERT INTO certification_item (campaign_id, employee_id, role_id, valid_from, decision)
+		VALUES ($1, $2, $3, now(), 'revoked')`, campaignId, employeeId, certifiedRole.Id)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteByIds(ctx, []int64{requestedRole.Id, certifiedRole.Id, unusedRole.Id}))
+
+	tx, err := repo.BeginTransaction(ctx)
+	require.NoError(t, err)
+	purged, err := repo.PurgeDeletedTx(ctx, tx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	// роли с историей запросов и пересмотров доступа остаются мягко удалёнными
+	require.Len(t, purged, 1)
+	assert.Equal(t, unusedRole.Id, purged[0].Id)
+	var requests int
+	require.NoError(t, DB.Get(&requests, "SELECT count(*) FROM access_request WHERE role_id = $1", requestedRole.Id))
+	assert.Equal(t, 1, requests)
+	var items int
+	require.NoError(t, DB.Get(&items, "SELECT count(*) FROM certification_item WHERE role_id = $1", certifiedRole.Id))
+	assert.Equal(t, 1, items)
+}
+
+func TestRoleRepository_DeletionDependents(t *testing.T) {
+	repo := role.NewRoleRepository(DB)
+	ctx := context.Background()
+
+	clearTables()
+
+	// Root <- Admin <- Guest, Operator - роль-замена
+	rootRole := &role.Entity{Name: "Root", Desc: "Root of all roles", Status: true}
+	require.NoError(t, repo.Add(ctx, rootRole))
+	adminRole := &role.Entity{Name: "Admin", Desc: "Administrator role", Status: true, ParentId: &rootRole.Id}
+	require.NoError(t, repo.Add(ctx, adminRole))
+	guestRole := &role.Entity{Name: "Guest", Desc: "Read-only role", Status: true, ParentId: &adminRole.Id}
+	require.NoError(t, repo.Add(ctx, guestRole))
+	operatorRole := &role.Entity{Name: "Operator", Desc: "Operator role", Status: true}
+	require.NoError(t, repo.Add(ctx, operatorRole))
+
+	var employeeId int64
+	require.NoError(t, DB.QueryRow(
+		`INSERT INTO employee (name, email, role_id) VALUES ($1, $2, $3) RETURNING id`,
+		"John Doe", "john@example.com", adminRole.Id,
+	).Scan(&employeeId))
+	_, err := DB.Exec(`INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2)`, employeeId, adminRole.Id)
+	require.NoError(t, err)
+
+	t.Run("Reassign", func(t *testing.T) {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		holders, err := repo.FindHoldersTx(ctx, tx, []int64{adminRole.Id})
+		require.NoError(t, err)
+		require.Len(t, holders, 1)
+		assert.Equal(t, employeeId, holders[0].EmployeeId)
+
+		children, err := repo.FindChildrenForUpdateTx(ctx, tx, adminRole.Id)
+		require.NoError(t, err)
+		require.Len(t, children, 1)
+		assert.Equal(t, guestRole.Id, children[0].Id)
+
+		require.NoError(t, repo.ReassignHoldersTx(ctx, tx, adminRole.Id, operatorRole.Id))
+		affected, err := repo.RevokeAssignmentsTx(ctx, tx, []int64{adminRole.Id})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{employeeId}, affected)
+		require.NoError(t, repo.SyncEmployeesRoleIdTx(ctx, tx, affected))
+		require.NoError(t, repo.ReparentChildrenTx(ctx, tx, adminRole.Id, operatorRole.Id))
+
+		var legacyRoleId int64
+		require.NoError(t, tx.Get(&legacyRoleId, "SELECT role_id FROM employee WHERE id = $1", employeeId))
+		assert.Equal(t, operatorRole.Id, legacyRoleId)
+
+		var parentId int64
+		require.NoError(t, tx.Get(&parentId, "SELECT parent_id FROM role WHERE id = $1", guestRole.Id))
+		assert.Equal(t, operatorRole.Id, parentId)
+	})
+
+	t.Run("Cascade", func(t *testing.T) {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		subtree, err := repo.FindSubtreeForUpdateTx(ctx, tx, adminRole.Id)
+		require.NoError(t, err)
+		require.Len(t, subtree, 2)
+		assert.Equal(t, adminRole.Id, subtree[0].Id)
+		assert.Equal(t, guestRole.Id, subtree[1].Id)
+
+		affected, err := repo.RevokeAssignmentsTx(ctx, tx, []int64{adminRole.Id, guestRole.Id})
+		require.NoError(t, err)
+		require.NoError(t, repo.SyncEmployeesRoleIdTx(ctx, tx, affected))
+
+		var legacyRoleId *int64
+		require.NoError(t, tx.Get(&legacyRoleId, "SELECT role_id FROM employee WHERE id = $1", employeeId))
+		assert.Nil(t, legacyRoleId)
+	})
+}
@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/role"
+	val "idm/inner/validator"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSodRules(t *testing.T) {
+	logger := common.NewLogger(config)
+	validator := val.New()
+	repo := role.NewRoleRepository(DB)
+	service := role.NewService(repo, audit.NewService(audit.NewAuditRepository(DB), validator, logger), validator, logger)
+	ctx := context.Background()
+
+	clearTables()
+
+	// Approver наследует Payments, поэтому сотрудник с Approver обладает и Payments
+	var creatorId, paymentsId, approverId, johnId, annId int64
+	require.NoError(t, DB.Get(&creatorId, "INSERT INTO role (name) VALUES ('Payment creator') RETURNING id"))
+	require.NoError(t, DB.Get(&paymentsId, "INSERT INTO role (name) VALUES ('Payments') RETURNING id"))
+	require.NoError(t, DB.Get(&approverId,
+		"INSERT INTO role (name, parent_id) VALUES ('Payment approver', $1) RETURNING id", paymentsId))
+	require.NoError(t, DB.Get(&johnId, "INSERT INTO employee (name, email) VALUES ('John', 'john@example.com') RETURNING id"))
+	require.NoError(t, DB.Get(&annId, "INSERT INTO employee (name, email) VALUES ('Ann', 'ann@example.com') RETURNING id"))
+	_, err := DB.Exec("INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2), ($3, $4)",
+		johnId, approverId, annId, creatorId)
+	require.NoError(t, err)
+
+	rule, err := service.CreateSodRule(ctx, role.SodRuleRequest{
+		Name:    "Create and approve payments",
+		RoleIds: []int64{creatorId, paymentsId},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{creatorId, paymentsId}, rule.RoleIds)
+
+	// назначает роль в транзакции и проверяет правила так же, как это делает employee.Service
+	assign := func(t *testing.T, employeeId, roleId int64, validFrom time.Time) error {
+		tx, err := repo.BeginTransaction(ctx)
+		require.NoError(t, err)
+		defer func() { _ = tx.Rollback() }()
+		_, err = tx.Exec("INSERT INTO employee_role (employee_id, role_id, valid_from) VALUES ($1, $2, $3)",
+			employeeId, roleId, validFrom)
+		require.NoError(t, err)
+		return service.CheckSodTx(ctx, tx, employeeId, []int64{roleId})
+	}
+
+	t.Run("Inherited role conflicts", func(t *testing.T) {
+		err := assign(t, johnId, creatorId, time.Now())
+
+		var conflictErr common.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		violations := conflictErr.Data.([]role.SodViolationResponse)
+		require.Len(t, violations, 1)
+		assert.Equal(t, paymentsId, violations[0].ConflictingRoleId)
+		assert.Equal(t, approverId, violations[0].ConflictingSourceRoleId)
+	})
+
+	t.Run("Future assignment conflicts with active one", func(t *testing.T) {
+		err := assign(t, annId, approverId, time.Now().Add(24*time.Hour))
+
+		assert.True(t, errors.As(err, &common.ConflictError{}))
+	})
+
+	t.Run("Unrelated role does not conflict", func(t *testing.T) {
+		var otherId int64
+		require.NoError(t, DB.Get(&otherId, "INSERT INTO role (name) VALUES ('Reports') RETURNING id"))
+
+		assert.NoError(t, assign(t, annId, otherId, time.Now()))
+	})
+
+	t.Run("Moving a role under a conflicting parent is rejected", func(t *testing.T) {
+		// Ann уже обладает Payment creator; через нового родителя она получила бы и Payments
+		var viewerId int64
+		require.NoError(t, DB.Get(&viewerId, "INSERT INTO role (name) VALUES ('Payment viewer') RETURNING id"))
+		_, err := DB.Exec("INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2)", annId, viewerId)
+		require.NoError(t, err)
+
+		_, err = service.PatchRole(ctx, role.PatchRequest{Id: viewerId, ParentId: &paymentsId})
+
+		var conflictErr common.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		violations := conflictErr.Data.([]role.SodViolationResponse)
+		require.NotEmpty(t, violations)
+		assert.Equal(t, viewerId, violations[0].SourceRoleId)
+		found, err := service.FindById(ctx, viewerId)
+		require.NoError(t, err)
+		assert.Nil(t, found.ParentId)
+	})
+
+	t.Run("Violations report and exceptions", func(t *testing.T) {
+		// правило появилось после назначений: нарушение видно только в отчёте
+		_, err := DB.Exec("INSERT INTO employee_role (employee_id, role_id) VALUES ($1, $2)", johnId, creatorId)
+		require.NoError(t, err)
+
+		violations, err := service.FindSodViolations(ctx, role.SodViolationFilter{})
+		require.NoError(t, err)
+		require.Len(t, violations, 1)
+		assert.Equal(t, johnId, violations[0].EmployeeId)
+		assert.Equal(t, "John", violations[0].EmployeeName)
+
+		exception, err := service.CreateSodException(ctx, role.SodExceptionRequest{
+			RuleId:        rule.Id,
+			EmployeeId:    johnId,
+			Justification: "Sole accountant of the branch",
+			ExpiresAt:     time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		violations, err = service.FindSodViolations(ctx, role.SodViolationFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, violations)
+		violations, err = service.FindSodViolations(ctx, role.SodViolationFilter{EmployeeId: johnId, IncludeExcepted: true})
+		require.NoError(t, err)
+		require.Len(t, violations, 1)
+		assert.Equal(t, exception.Id, *violations[0].ExceptionId)
+
+		_, err = service.RevokeSodException(ctx, exception.Id)
+		require.NoError(t, err)
+		exceptions, err := service.FindSodExceptions(ctx, role.SodExceptionFilter{EmployeeId: johnId, ActiveOnly: true})
+		require.NoError(t, err)
+		assert.Empty(t, exceptions)
+	})
+
+	t.Run("Rule roles are replaced on update", func(t *testing.T) {
+		updated, err := service.UpdateSodRule(ctx, role.SodRuleRequest{
+			Id:      rule.Id,
+			Name:    rule.Name,
+			RoleIds: []int64{creatorId, approverId},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{creatorId, approverId}, updated.RoleIds)
+
+		_, err = service.CreateSodRule(ctx, role.SodRuleRequest{
+			Name:    "create and approve PAYMENTS",
+			RoleIds: []int64{creatorId, paymentsId},
+		})
+		assert.True(t, errors.As(err, &common.AlreadyExistsError{}))
+	})
+
+	t.Run("Activation re-checks holders of the role", func(t *testing.T) {
+		// у John есть Payment creator и Payment approver, пока Payment approver выключена, конфликта нет
+		_, err := service.Deactivate(ctx, approverId)
+		require.NoError(t, err)
+
+		_, err = service.Activate(ctx, approverId)
+
+		var conflictErr common.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		assert.Contains(t, conflictErr.Message, "Payment")
+		found, err := service.FindById(ctx, approverId)
+		require.NoError(t, err)
+		assert.False(t, found.Status)
+	})
+}